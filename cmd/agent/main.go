@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"log-ingestion-service/internal/agent"
+	"os/signal"
+	"syscall"
+)
+
+func main() {
+	configPath := flag.String("config", "/etc/log-agent/agent.yaml", "path to the agent configuration file")
+	flag.Parse()
+
+	cfg, err := agent.LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	a, err := agent.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to start agent: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Forwarding %d sources to %s", len(cfg.Sources), cfg.Endpoint)
+	if err := a.Run(ctx); err != nil {
+		log.Fatalf("Agent stopped: %v", err)
+	}
+	log.Println("Agent stopped")
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"log-ingestion-service/internal/seed"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"os"
+)
+
+func main() {
+	opts := seed.DefaultOptions()
+	flag.Int64Var(&opts.ProjectID, "project", opts.ProjectID, "sandbox project ID to write sample data to")
+	flag.IntVar(&opts.Faults, "faults", opts.Faults, "number of faults to generate")
+	flag.IntVar(&opts.NoticesPerFault, "notices", opts.NoticesPerFault, "average notices per fault")
+	flag.IntVar(&opts.Logs, "logs", opts.Logs, "number of logs to generate")
+	flag.IntVar(&opts.Users, "users", opts.Users, "number of sandbox users")
+	flag.IntVar(&opts.Days, "days", opts.Days, "spread data over this many past days")
+	flag.Int64Var(&opts.RandomSeed, "seed", 0, "random seed for reproducible data (0 = random)")
+	flag.BoolVar(&opts.Wipe, "wipe", false, "delete existing sandbox data before seeding")
+	wipeOnly := flag.Bool("wipe-only", false, "delete sandbox data and exit without seeding")
+	flag.Parse()
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	// Initialize database connection
+	ctx := context.Background()
+	dbPool, err := storage.NewConnection(ctx, &cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer dbPool.Close()
+
+	seeder := seed.NewSeeder(storage.NewRepository(dbPool))
+
+	var result interface{}
+	if *wipeOnly {
+		result, err = seeder.Wipe(ctx, opts.ProjectID)
+	} else {
+		result, err = seeder.Run(ctx, opts)
+	}
+	if err != nil {
+		log.Fatalf("Seeding failed: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(result)
+}
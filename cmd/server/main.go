@@ -2,14 +2,39 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"log-ingestion-service/internal/api"
 	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/avatar"
 	"log-ingestion-service/internal/batch"
+	"log-ingestion-service/internal/deadletter"
+	"log-ingestion-service/internal/enrich"
+	"log-ingestion-service/internal/fault"
+	"log-ingestion-service/internal/feature"
+	"log-ingestion-service/internal/ingest/aws"
+	"log-ingestion-service/internal/ingest/syslog"
+	"log-ingestion-service/internal/jobs"
+	"log-ingestion-service/internal/listener"
+	"log-ingestion-service/internal/logschema"
+	"log-ingestion-service/internal/middleware"
+	"log-ingestion-service/internal/notify"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/pause"
+	"log-ingestion-service/internal/preflight"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/querycost"
+	"log-ingestion-service/internal/redact"
+	"log-ingestion-service/internal/rejects"
+	"log-ingestion-service/internal/sampling"
+	"log-ingestion-service/internal/sources"
 	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/internal/subscription"
+	"log-ingestion-service/internal/swr"
+	"log-ingestion-service/internal/validator"
 	"log-ingestion-service/pkg/config"
-	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -19,12 +44,22 @@ import (
 )
 
 func main() {
+	validateOnly := flag.Bool("validate-config", false, "Check the configuration and exit non-zero if it is invalid")
+	flag.Parse()
+	
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 	
+	if *validateOnly {
+		os.Exit(validateConfig(cfg))
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Failed to validate configuration: %v", err)
+	}
+	
 	// Initialize database connection
 	ctx := context.Background()
 	dbPool, err := storage.NewConnection(ctx, &cfg.Database)
@@ -36,21 +71,153 @@ func main() {
 	// Initialize repository
 	repo := storage.NewRepository(dbPool)
 	
+	// Check the database before reporting ready; failed checks are run again until they pass
+	checks := preflight.New(&cfg.Preflight, preflight.DatabaseChecks(&cfg.Preflight, repo)...)
+	if checks.Run(ctx).Passed {
+		log.Println("Preflight checks passed")
+	}
+	checks.Start()
+	defer checks.Shutdown()
+	
 	// Initialize key manager
 	keyManager := auth.NewKeyManager(repo)
 	
+	// Initialize session store; session tokens are only accepted while their session is active
+	sessions := auth.NewSessionStore(repo, cfg.Auth.JWTSecret)
+	
+	// Initialize feature flags
+	flags, err := feature.NewFlags(repo, cfg.Features)
+	if err != nil {
+		log.Fatalf("Failed to initialize feature flags: %v", err)
+	}
+	
+	// Load paused pipelines before accepting traffic
+	pipelines, err := pause.NewController(ctx, repo)
+	if err != nil {
+		log.Fatalf("Failed to load pipeline state: %v", err)
+	}
+	
+	// Load sampling rules, which drop logs before they are buffered
+	sampler, err := sampling.New(ctx, &cfg.Sampling, repo)
+	if err != nil {
+		log.Fatalf("Failed to load sampling rules: %v", err)
+	}
+	
+	// Initialize the dead-letter queue, which keeps batches that fail to flush
+	deadLetters, err := deadletter.NewStore(&cfg.DeadLetter)
+	if err != nil {
+		log.Fatalf("Failed to initialize dead-letter queue: %v", err)
+	}
+	
+	// Open the write-ahead log, which keeps buffered logs on disk until they are flushed
+	wal, err := batch.OpenWAL(&cfg.WAL)
+	if err != nil {
+		log.Fatalf("Failed to open write-ahead log: %v", err)
+	}
+	
 	// Initialize batcher
-	batcher := batch.NewBatcher(repo, &cfg.Batch)
+	batcher := batch.NewBatcher(repo, &cfg.Batch, sampler, deadLetters, wal)
 	defer batcher.Shutdown()
 	
+	// Write the logs a previous run accepted but did not flush
+	if replayed, err := batcher.ReplayWAL(); err != nil {
+		log.Printf("WARN: Replayed %d log entries from the write-ahead log, some failed: %v", replayed, err)
+	} else if replayed > 0 {
+		log.Printf("INFO: Replayed %d log entries from the write-ahead log", replayed)
+	}
+	
+	// Initialize notice batcher
+	noticeBatcher := batch.NewNoticeBatcher(repo, &cfg.NoticeBatch)
+	defer noticeBatcher.Shutdown()
+	
+	// Initialize notification dispatcher
+	notifier := notify.NewDispatcher(&cfg.Notifications)
+	notifier.PauseWhen(func() bool { return pipelines.Paused(context.Background(), pause.Notifications) })
+	
+	// Initialize redaction of logs and notices, with the rules stored from the admin API
+	redactor, err := redact.New(ctx, &cfg.Scrub, repo)
+	if err != nil {
+		log.Fatalf("Failed to initialize redaction: %v", err)
+	}
+	
+	// Load the schemas log metadata must satisfy, registered from the admin API
+	schemas, err := logschema.New(ctx, repo)
+	if err != nil {
+		log.Fatalf("Failed to load log schemas: %v", err)
+	}
+	
+	// Initialize enrichment; its processors must be registered before the parsers naming them
+	enricher, err := enrich.New(&cfg.Enrich)
+	if err != nil {
+		log.Fatalf("Failed to initialize enrichment: %v", err)
+	}
+	enricher.RegisterProcessors()
+	
+	// Initialize log parsers
+	parsers, err := parser.NewRegistry(&cfg.Parser)
+	if err != nil {
+		log.Fatalf("Failed to initialize parsers: %v", err)
+	}
+	
+	// Apply grok patterns defined from the admin API before logs arrive, and follow changes
+	grokSync := parser.NewGrokSync(repo, parsers.Grok())
+	grokSync.Start()
+	defer grokSync.Shutdown()
+	
+	// Keep parser pipelines defined from the admin API in step across instances
+	pipelineSync := parser.NewPipelineSync(repo, parsers)
+	pipelineSync.Start()
+	defer pipelineSync.Shutdown()
+	
 	// Initialize handler
-	handler := api.NewHandler(batcher)
+	limits := validator.NewMetadataLimiter(&cfg.MetadataLimits)
+	logValidator := validator.NewValidator(redactor, &cfg.Levels, schemas, limits)
+	rejected := rejects.NewStore(&cfg.Rejects, redactor)
+	tracker := sources.NewTracker(rejected)
+	handler := api.NewHandler(batcher, parsers, keyManager, logValidator, flags, tracker)
+	handler.PauseWhen(func() bool { return pipelines.Paused(context.Background(), pause.Ingestion) })
 	
 	// Initialize admin handler
-	adminHandler := api.NewAdminHandler(repo, batcher, cfg)
+	guard := querycost.NewGuard(&cfg.QueryGuard, repo)
+	analytics := swr.New(&cfg.AnalyticsCache, cfg.Timeouts.Analytics)
+	noticeSampler := fault.NewNoticeSampler(&cfg.Faults.Sampling)
+	adminHandler := api.NewAdminHandler(repo, batcher, notifier, parsers, sessions, cfg, guard, analytics, noticeSampler)
+	
+	// Initialize background job runner; handlers register their job types before it starts
+	runner := jobs.NewRunner(repo, &cfg.Jobs)
 	
 	// Initialize fault handler
-	faultHandler := api.NewFaultHandler(repo)
+	faultHandler := api.NewFaultHandler(repo, notifier, noticeBatcher, flags, rejected, runner, &cfg.Faults, enricher, redactor, guard, analytics, limits, noticeSampler)
+	
+	// Initialize scheduled query subscriptions
+	subscriptions := subscription.NewScheduler(repo, runner, notify.NewMailer(&cfg.Notifications.SMTP), cfg.Notifications.Timeout, fault.NewSLAPolicy(&cfg.Faults.SLA))
+	
+	// Initialize severity escalation
+	escalator := fault.NewEscalator(repo, notifier)
+	
+	// Initialize SLA at-risk notifications
+	slaMonitor := fault.NewSLAMonitor(repo, notifier, fault.NewSLAPolicy(&cfg.Faults.SLA))
+	
+	// Initialize deploy error rate analysis
+	deployAnalyzer := fault.NewDeployAnalyzer(repo, &cfg.Faults.Deploys)
+	
+	// Initialize per-environment notice retention
+	environmentRetention := fault.NewEnvironmentRetention(repo)
+	
+	// Initialize collection of backtraces and payload sections no notice uses any more
+	backtraceCollector := fault.NewBacktraceCollector(repo)
+	
+	// Initialize avatar store
+	avatars, err := avatar.NewStore(&cfg.Avatars)
+	if err != nil {
+		log.Fatalf("Failed to initialize avatar store: %v", err)
+	}
+	
+	// Initialize profile handler
+	profileHandler := api.NewProfileHandler(repo, avatars, &cfg.Avatars)
+	
+	// Initialize passkey handler
+	passkeyHandler := api.NewPasskeyHandler(repo, sessions, &cfg.Auth)
 	
 	// Setup router
 	router := gin.Default()
@@ -68,7 +235,7 @@ func main() {
 		// Don't serve index.html for API routes
 		path := c.Request.URL.Path
 		if len(path) >= 4 && path[:4] == "/api" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+			problem.NotFound(c, "Not found", nil)
 			return
 		}
 		c.File("./web/dist/index.html")
@@ -78,30 +245,134 @@ func main() {
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 	
+	// Turn ingestion away while it is paused
+	router.Use(middleware.IngestionPause(pipelines))
+	
+	// Limit concurrent requests so analytics queries cannot starve ingestion
+	router.Use(middleware.Concurrency(&cfg.Concurrency))
+	
+	// Bound handler run time so slow queries return a timeout instead of hanging
+	router.Use(middleware.Timeout(&cfg.Timeouts))
+	
 	// Setup routes
 	api.SetupRoutes(router, handler, keyManager, cfg)
 	
 	// Setup fault routes
-	api.SetupFaultRoutes(router, faultHandler, keyManager, cfg)
+	api.SetupFaultRoutes(router, faultHandler, keyManager, sessions, cfg)
+	
+	// Setup regroup routes
+	api.SetupRegroupRoutes(router, faultHandler, sessions, cfg)
+	
+	// Setup Rollbar-compatible item routes
+	api.SetupRollbarRoutes(router, faultHandler, keyManager, cfg)
+	
+	// Setup Bugsnag-compatible notify routes
+	api.SetupBugsnagRoutes(router, faultHandler, keyManager, cfg)
+	
+	// Setup Airbrake-compatible notice routes
+	api.SetupAirbrakeRoutes(router, faultHandler, keyManager, cfg)
+	
+	// Setup background job routes
+	api.SetupJobRoutes(router, api.NewJobHandler(repo), keyManager, sessions, cfg)
+	
+	// Setup scheduled query subscription routes
+	api.SetupSubscriptionRoutes(router, api.NewSubscriptionHandler(repo, subscriptions), keyManager, sessions, cfg)
+	
+	// Setup profile routes
+	api.SetupProfileRoutes(router, profileHandler, keyManager, sessions, cfg)
+	
+	// Setup passkey routes
+	api.SetupPasskeyRoutes(router, passkeyHandler, keyManager, sessions, cfg)
+	
+	// Setup session management routes
+	api.SetupSessionRoutes(router, api.NewSessionHandler(repo), keyManager, sessions, cfg)
 	
 	// Setup admin routes
-	api.SetupAdminRoutes(router, adminHandler, cfg)
+	api.SetupAdminRoutes(router, adminHandler, sessions, cfg)
+	
+	// Setup feature flag routes
+	api.SetupFeatureRoutes(router, flags, sessions, cfg)
+	
+	// Setup pipeline pause routes
+	api.SetupPipelineRoutes(router, pipelines, sessions, cfg)
+	
+	// Setup effective configuration route
+	api.SetupConfigRoutes(router, flags, pipelines, sessions, cfg)
+	
+	// Setup ingestion source health routes
+	api.SetupSourceRoutes(router, tracker, repo, sessions, cfg)
+	
+	// Setup reject metrics and sample routes
+	api.SetupRejectRoutes(router, rejected, repo, sessions, cfg)
+	
+	// Setup public dashboard routes and public token management
+	api.SetupPublicRoutes(router, api.NewPublicHandler(repo), sessions, cfg)
+	
+	// Setup project badge routes
+	api.SetupBadgeRoutes(router, api.NewBadgeHandler(repo), cfg)
+	
+	// Setup grok pattern routes
+	api.SetupGrokRoutes(router, repo, parsers, grokSync, sessions, cfg)
+	
+	// Setup redaction rule routes
+	api.SetupRedactionRoutes(router, repo, redactor, sessions, cfg)
+	
+	// Setup sampling rule routes
+	api.SetupSamplingRoutes(router, repo, sampler, sessions, cfg)
+	
+	// Setup dead-letter queue routes
+	api.SetupDeadLetterRoutes(router, repo, deadLetters, sessions, cfg)
+	
+	// Setup log schema routes
+	api.SetupLogSchemaRoutes(router, repo, schemas, sessions, cfg)
+	
+	// Setup query audit log routes
+	api.SetupQueryAuditRoutes(router, repo, sessions, cfg)
+	
+	// Setup parser pipeline routes
+	api.SetupParserPipelineRoutes(router, repo, parsers, pipelineSync, sessions, cfg)
 	
-	// Create HTTP server
-	srv := &http.Server{
-		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
-		Handler:      router,
+	// Start the main API listener and any additional inputs
+	listeners := listener.NewManager()
+	api.SetupListenerRoutes(router, listeners, checks, sessions, cfg)
+	
+	if err := listeners.Add(listener.NewHTTP(config.MainListener, "http", fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port), router, listener.HTTPOptions{
 		ReadTimeout:  cfg.Server.ReadTimeout,
 		WriteTimeout: cfg.Server.WriteTimeout,
+		TLSCertFile:  cfg.Server.TLSCertFile,
+		TLSKeyFile:   cfg.Server.TLSKeyFile,
+	})); err != nil {
+		log.Fatalf("Failed to register listener: %v", err)
 	}
 	
-	// Start server in a goroutine
-	go func() {
-		log.Printf("Starting server on %s:%d", cfg.Server.Host, cfg.Server.Port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+	for i := range cfg.Listeners {
+		l, err := newListener(&cfg.Listeners[i], cfg, handler, keyManager, parsers, logValidator, batcher, pipelines, tracker, repo)
+		if err != nil {
+			log.Fatalf("Failed to configure listener %s: %v", cfg.Listeners[i].Name, err)
+		}
+		if err := listeners.Add(l); err != nil {
+			log.Fatalf("Failed to register listener: %v", err)
 		}
-	}()
+	}
+	
+	if err := listeners.StartAll(); err != nil {
+		log.Fatalf("Failed to start server: %v", err)
+	}
+	
+	runner.Start()
+	defer runner.Shutdown()
+	subscriptions.Start()
+	defer subscriptions.Shutdown()
+	escalator.Start()
+	defer escalator.Shutdown()
+	slaMonitor.Start()
+	defer slaMonitor.Shutdown()
+	deployAnalyzer.Start()
+	defer deployAnalyzer.Shutdown()
+	environmentRetention.Start()
+	defer environmentRetention.Shutdown()
+	backtraceCollector.Start()
+	defer backtraceCollector.Shutdown()
 	
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
@@ -114,10 +385,123 @@ func main() {
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 	
-	if err := srv.Shutdown(shutdownCtx); err != nil {
+	if err := listeners.StopAll(shutdownCtx); err != nil {
 		log.Fatalf("Server forced to shutdown: %v", err)
 	}
 	
 	log.Println("Server exited")
 }
 
+// syslogIdleTimeout closes syslog TCP connections that have gone quiet
+const syslogIdleTimeout = 10 * time.Minute
+
+// newListener builds an additional input from its configuration
+func newListener(lcfg *config.ListenerConfig, cfg *config.Config, handler *api.Handler, keyManager *auth.KeyManager, parsers *parser.Registry, logValidator *validator.Validator, batcher *batch.Batcher, pipelines *pause.Controller, tracker *sources.Tracker, repo *storage.Repository) (listener.Listener, error) {
+	if lcfg.Parser != "" && !parsers.Has(lcfg.Parser) {
+		return nil, fmt.Errorf("unknown parser %q", lcfg.Parser)
+	}
+	
+	addr := fmt.Sprintf("%s:%d", lcfg.Host, lcfg.Port)
+	switch lcfg.Type {
+	case config.ListenerIngest:
+		router := gin.Default()
+		router.Use(middleware.IngestionPause(pipelines), middleware.Concurrency(&cfg.Concurrency), middleware.Timeout(&cfg.Timeouts))
+		router.NoRoute(func(c *gin.Context) {
+			problem.NotFound(c, "Not found", nil)
+		})
+		api.SetupIngestRoutes(router, handler, keyManager, cfg, lcfg)
+		return listener.NewHTTP(lcfg.Name, lcfg.Type, addr, router, listener.HTTPOptions{
+			ReadTimeout:  cfg.Server.ReadTimeout,
+			WriteTimeout: cfg.Server.WriteTimeout,
+			TLSCertFile:  lcfg.TLSCertFile,
+			TLSKeyFile:   lcfg.TLSKeyFile,
+		}), nil
+	case config.ListenerOTLP:
+		router := gin.Default()
+		router.Use(middleware.IngestionPause(pipelines), middleware.Concurrency(&cfg.Concurrency), middleware.Timeout(&cfg.Timeouts))
+		router.NoRoute(func(c *gin.Context) {
+			problem.NotFound(c, "Not found", nil)
+		})
+		api.SetupOTLPRoutes(router, handler, keyManager, cfg, lcfg)
+		return listener.NewHTTP(lcfg.Name, lcfg.Type, addr, router, listener.HTTPOptions{
+			ReadTimeout:  cfg.Server.ReadTimeout,
+			WriteTimeout: cfg.Server.WriteTimeout,
+			TLSCertFile:  lcfg.TLSCertFile,
+			TLSKeyFile:   lcfg.TLSKeyFile,
+			HTTP2:        true,
+		}), nil
+	case config.ListenerSyslog:
+		timestamps, err := parser.NewTimestampParser(cfg.Parser.DefaultTimezone)
+		if err != nil {
+			return nil, err
+		}
+		return syslog.New(lcfg.Name, addr, syslog.Options{
+			Network:     lcfg.Network,
+			TLSCertFile: lcfg.TLSCertFile,
+			TLSKeyFile:  lcfg.TLSKeyFile,
+			IdleTimeout: syslogIdleTimeout,
+			Paused:      func() bool { return pipelines.Paused(context.Background(), pause.Ingestion) },
+			Sources:     tracker,
+		}, timestamps, logValidator, batcher), nil
+	case config.ListenerSQS, config.ListenerKinesis:
+		p, _ := parsers.Select(parser.Selection{Name: lcfg.Parser})
+		opts := aws.Options{
+			Region:   lcfg.Region,
+			Endpoint: lcfg.Endpoint,
+			DLQURL:   lcfg.DLQURL,
+			Paused:   func() bool { return pipelines.Paused(context.Background(), pause.Ingestion) },
+			Sources:  tracker,
+		}
+		if lcfg.Type == config.ListenerSQS {
+			return aws.NewSQS(lcfg.Name, lcfg.QueueURL, opts, p, logValidator, batcher), nil
+		}
+		return aws.NewKinesis(lcfg.Name, lcfg.Stream, lcfg.StartingPosition, opts, repo, p, logValidator, batcher), nil
+	}
+	return nil, fmt.Errorf("unsupported listener type %q", lcfg.Type)
+}
+
+// validateConfig reports every configuration problem, including those only found when building
+// the scrubber, enrichment and parsers, and returns the process exit code
+func validateConfig(cfg *config.Config) int {
+	var problems []string
+	if err := cfg.Validate(); err != nil {
+		var validationErrs config.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			validationErrs = config.ValidationErrors{err.Error()}
+		}
+		problems = append(problems, validationErrs...)
+	} else {
+		if _, err := validator.NewScrubber(&cfg.Scrub); err != nil {
+			problems = append(problems, err.Error())
+		}
+		if enricher, err := enrich.New(&cfg.Enrich); err != nil {
+			problems = append(problems, err.Error())
+		} else {
+			enricher.RegisterProcessors()
+		}
+		parsers, err := parser.NewRegistry(&cfg.Parser)
+		if err != nil {
+			problems = append(problems, err.Error())
+		} else {
+			for i, l := range cfg.Listeners {
+				if l.Parser != "" && !parsers.Has(l.Parser) {
+					problems = append(problems, fmt.Sprintf("listeners[%d].parser %q is not a registered parser", i, l.Parser))
+				}
+			}
+		}
+		if _, err := feature.NewFlags(nil, cfg.Features); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	
+	if len(problems) > 0 {
+		fmt.Fprintln(os.Stderr, "Configuration is invalid:")
+		for _, p := range problems {
+			fmt.Fprintf(os.Stderr, "  - %s\n", p)
+		}
+		return 1
+	}
+	
+	fmt.Println("Configuration is valid")
+	return 0
+}
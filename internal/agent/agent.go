@@ -0,0 +1,297 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/pkg/models"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Agent tails its sources, parses each line locally, buffers the logs on disk and forwards
+// them to the ingestion API. Delivery is at least once: source positions are saved after
+// logs are buffered, and logs leave the buffer after the API has taken them, so a crash can
+// send some logs twice but does not lose them.
+type Agent struct {
+	cfg         *Config
+	parsers     *parser.Registry
+	buffer      *Buffer
+	checkpoints *Checkpoints
+	forwarder   *Forwarder
+	sources     []Source
+	configs     map[string]SourceConfig
+	// multiline holds the joining applied to each source's lines, for sources whose lines are joined
+	multiline map[string]*parser.Multiline
+	hostname  string
+}
+
+// sourceLine is a line on its way from a source to the buffer
+type sourceLine struct {
+	Line
+	source string
+}
+
+// New creates an agent, opening its buffer and checkpoints in the state directory
+func New(cfg *Config) (*Agent, error) {
+	parsers, err := parser.NewRegistry(&cfg.Parser)
+	if err != nil {
+		return nil, fmt.Errorf("error creating parsers: %w", err)
+	}
+
+	if err := os.MkdirAll(cfg.StateDir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating state directory: %w", err)
+	}
+	checkpoints, err := LoadCheckpoints(cfg.StateDir)
+	if err != nil {
+		return nil, err
+	}
+	buffer, err := OpenBuffer(filepath.Join(cfg.StateDir, "buffer"), cfg.BufferMaxBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	hostname, _ := os.Hostname()
+	a := &Agent{
+		cfg:         cfg,
+		parsers:     parsers,
+		buffer:      buffer,
+		checkpoints: checkpoints,
+		forwarder:   NewForwarder(cfg, buffer),
+		configs:     make(map[string]SourceConfig, len(cfg.Sources)),
+		multiline:   make(map[string]*parser.Multiline),
+		hostname:    hostname,
+	}
+	for _, sc := range cfg.Sources {
+		src, err := NewSource(sc, cfg.PollInterval)
+		if err != nil {
+			buffer.Close()
+			return nil, err
+		}
+		if sc.Parser == "" && sc.Type == SourceJournald {
+			sc.Parser = parser.NameJournald
+		}
+		if sc.Parser != "" && !parsers.Has(sc.Parser) {
+			buffer.Close()
+			return nil, fmt.Errorf("source %s uses unknown parser %q", sc.Name, sc.Parser)
+		}
+		a.sources = append(a.sources, src)
+		a.configs[sc.Name] = sc
+
+		// Only lines parsed as text or logfmt are joined, like on the server
+		multiline := parsers.Multiline()
+		if sc.Multiline != nil {
+			if multiline, err = parser.NewMultiline(*sc.Multiline); err != nil {
+				buffer.Close()
+				return nil, fmt.Errorf("source %s: %w", sc.Name, err)
+			}
+		}
+		if _, name := parsers.Select(parser.Selection{Name: sc.Parser, Source: sc.Type}); multiline != nil && (name == parser.NameAuto || name == parser.NameText || name == parser.NameLogfmt) {
+			a.multiline[sc.Name] = multiline
+		}
+	}
+	return a, nil
+}
+
+// Run runs the sources and the forwarder until ctx is cancelled, then buffers the lines
+// already read and saves positions before returning
+func (a *Agent) Run(ctx context.Context) error {
+	defer a.buffer.Close()
+
+	lines := make(chan sourceLine, a.cfg.BatchSize)
+	var sources sync.WaitGroup
+	for _, src := range a.sources {
+		sources.Add(1)
+		go func(src Source) {
+			defer sources.Done()
+			a.runSource(ctx, src, lines)
+		}(src)
+	}
+	go func() {
+		sources.Wait()
+		close(lines)
+	}()
+
+	var forwarder sync.WaitGroup
+	forwarder.Add(1)
+	go func() {
+		defer forwarder.Done()
+		a.forwarder.Run(ctx)
+	}()
+
+	err := a.writeLines(lines)
+	forwarder.Wait()
+	return err
+}
+
+// runSource runs a source, restarting it with backoff when it fails
+func (a *Agent) runSource(ctx context.Context, src Source, lines chan<- sourceLine) {
+	emit := func(line Line) error {
+		select {
+		case lines <- sourceLine{Line: line, source: src.Name()}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	delay := minRetryDelay
+	for {
+		started := time.Now()
+		err := src.Run(ctx, a.checkpoints, emit)
+		if ctx.Err() != nil {
+			return
+		}
+		// A source that ran for a while before failing starts over with a short delay
+		if time.Since(started) > a.cfg.MaxRetryDelay {
+			delay = minRetryDelay
+		}
+		log.Printf("agent: source %s stopped, restarting in %s: %v", src.Name(), delay, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > a.cfg.MaxRetryDelay {
+			delay = a.cfg.MaxRetryDelay
+		}
+	}
+}
+
+// writeLines parses lines and appends them to the buffer in batches until lines is closed.
+// Lines of sources with multi-line joining are held until their log is complete. While the
+// buffer cannot be written, e.g. because the disk is full, lines are held and the sources
+// block until a later attempt succeeds.
+func (a *Agent) writeLines(lines <-chan sourceLine) error {
+	ticker := time.NewTicker(a.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	pending := make([]sourceLine, 0, a.cfg.BatchSize)
+	// Joiners are kept per checkpoint key, so lines of different files are never joined
+	joiners := make(map[string]*parser.LineJoiner[sourceLine])
+	add := func(line sourceLine) {
+		multiline := a.multiline[line.source]
+		if multiline == nil {
+			pending = append(pending, line)
+			return
+		}
+		joiner, ok := joiners[line.CheckpointKey]
+		if !ok {
+			joiner = parser.NewLineJoiner[sourceLine](multiline)
+			joiners[line.CheckpointKey] = joiner
+		}
+		for _, log := range joiner.Add(line.Data, line) {
+			pending = append(pending, joinedLine(log))
+		}
+	}
+
+	for {
+		in := lines
+		if len(pending) >= a.cfg.BatchSize {
+			in = nil
+		}
+		select {
+		case line, ok := <-in:
+			if !ok {
+				for _, joiner := range joiners {
+					if log, ok := joiner.Flush(); ok {
+						pending = append(pending, joinedLine(log))
+					}
+				}
+				return a.bufferLines(pending)
+			}
+			add(line)
+			if len(pending) < a.cfg.BatchSize {
+				continue
+			}
+		case now := <-ticker.C:
+			for _, joiner := range joiners {
+				if log, ok := joiner.Expired(now); ok {
+					pending = append(pending, joinedLine(log))
+				}
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+		if err := a.bufferLines(pending); err != nil {
+			log.Printf("agent: %v", err)
+			continue
+		}
+		pending = pending[:0]
+	}
+}
+
+// joinedLine is a joined log as a line: it resumes after its last line and takes the time of its first
+func joinedLine(log parser.JoinedLog[sourceLine]) sourceLine {
+	line := log.Last
+	line.Data = log.Data
+	line.Time = log.First.Time
+	return line
+}
+
+// bufferLines parses lines, appends them to the buffer and then saves the source positions
+func (a *Agent) bufferLines(pending []sourceLine) error {
+	if len(pending) == 0 {
+		return nil
+	}
+	encoded := make([][]byte, 0, len(pending))
+	for _, line := range pending {
+		entry := a.parse(line)
+		data, err := json.Marshal(entry)
+		if err != nil {
+			log.Printf("agent: source %s: dropping unencodable log: %v", line.source, err)
+			continue
+		}
+		encoded = append(encoded, data)
+	}
+	if err := a.buffer.Append(encoded); err != nil {
+		return err
+	}
+
+	for _, line := range pending {
+		a.checkpoints.Set(line.CheckpointKey, line.Checkpoint)
+	}
+	return a.checkpoints.Save()
+}
+
+// parse turns a line into a log with the source's parser. Lines the parser rejects are kept
+// as plain messages.
+func (a *Agent) parse(line sourceLine) *models.LogEntry {
+	sc := a.configs[line.source]
+	p, _ := a.parsers.Select(parser.Selection{Name: sc.Parser, Source: sc.Type})
+
+	parsedAt := time.Now()
+	entry, err := p.Parse(line.Data)
+	if err != nil {
+		entry = &models.LogEntry{
+			Timestamp: parsedAt,
+			Level:     "INFO",
+			Message:   string(line.Data),
+			Metadata:  map[string]interface{}{"parse_error": err.Error()},
+		}
+	}
+
+	// Parsers stamp logs without a timestamp with the current time; the source's time is closer
+	if !line.Time.IsZero() && !entry.Timestamp.Before(parsedAt) {
+		entry.Timestamp = line.Time
+	}
+	if entry.Service == "" || entry.Service == parser.UnknownService {
+		entry.Service = sc.Service
+	}
+	if entry.Metadata == nil {
+		entry.Metadata = make(map[string]interface{})
+	}
+	if _, ok := entry.Metadata["hostname"]; !ok && a.hostname != "" {
+		entry.Metadata["hostname"] = a.hostname
+	}
+	if _, ok := entry.Metadata["source"]; !ok {
+		entry.Metadata["source"] = line.source
+	}
+	return entry
+}
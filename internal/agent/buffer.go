@@ -0,0 +1,296 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxSegmentBytes is the size at which the buffer starts a new segment file
+	maxSegmentBytes = 8 << 20
+	// segmentExt names segment files, which hold one JSON log per line
+	segmentExt = ".ndjson"
+	// readPositionFile records the first log not yet forwarded
+	readPositionFile = "read.pos"
+)
+
+// Position is a place in the buffer: a segment and a byte offset within it
+type Position struct {
+	Segment uint64
+	Offset  int64
+}
+
+func (p Position) before(other Position) bool {
+	return p.Segment < other.Segment || (p.Segment == other.Segment && p.Offset < other.Offset)
+}
+
+// Buffer is an append-only queue of log lines on disk, split into numbered segment files.
+// Appends are synced before they return, and the read position only moves on Ack, so logs
+// survive a restart until the API has accepted them. When the buffer grows past its limit
+// the oldest segment is dropped.
+type Buffer struct {
+	dir          string
+	maxBytes     int64
+	segmentBytes int64
+	notify       chan struct{}
+
+	mu       sync.Mutex
+	segments []uint64
+	sizes    map[uint64]int64
+	writer   *os.File
+	writeID  uint64
+	read     Position
+}
+
+// OpenBuffer opens the buffer in dir, creating it if needed. Writes always go to a new
+// segment, so a line left incomplete by a crash is never appended to.
+func OpenBuffer(dir string, maxBytes int64) (*Buffer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating buffer directory: %w", err)
+	}
+
+	b := &Buffer{
+		dir:          dir,
+		maxBytes:     maxBytes,
+		segmentBytes: maxSegmentBytes,
+		notify:       make(chan struct{}, 1),
+		sizes:        make(map[uint64]int64),
+	}
+	if quarter := maxBytes / 4; quarter > 0 && quarter < b.segmentBytes {
+		b.segmentBytes = quarter
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error listing buffer directory: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("error reading buffer segment: %w", err)
+		}
+		b.segments = append(b.segments, id)
+		b.sizes[id] = info.Size()
+	}
+	sort.Slice(b.segments, func(i, j int) bool { return b.segments[i] < b.segments[j] })
+
+	if err := b.loadReadPosition(); err != nil {
+		return nil, err
+	}
+
+	next := uint64(1)
+	if n := len(b.segments); n > 0 {
+		next = b.segments[n-1] + 1
+	}
+	if err := b.openSegment(next); err != nil {
+		return nil, err
+	}
+	if b.read.Segment < b.segments[0] {
+		b.read = Position{Segment: b.segments[0]}
+	}
+	return b, nil
+}
+
+// loadReadPosition restores the saved read position, defaulting to the oldest segment
+func (b *Buffer) loadReadPosition() error {
+	data, err := os.ReadFile(filepath.Join(b.dir, readPositionFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading buffer position: %w", err)
+	}
+	if _, err := fmt.Sscanf(string(data), "%d %d", &b.read.Segment, &b.read.Offset); err != nil {
+		return fmt.Errorf("invalid buffer position %q: %w", data, err)
+	}
+	return nil
+}
+
+// openSegment starts a new segment for writes
+func (b *Buffer) openSegment(id uint64) error {
+	f, err := os.OpenFile(b.segmentPath(id), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("error creating buffer segment: %w", err)
+	}
+	if b.writer != nil {
+		b.writer.Close()
+	}
+	b.writer = f
+	b.writeID = id
+	b.segments = append(b.segments, id)
+	b.sizes[id] = 0
+	return nil
+}
+
+func (b *Buffer) segmentPath(id uint64) string {
+	return filepath.Join(b.dir, fmt.Sprintf("%020d%s", id, segmentExt))
+}
+
+// Append writes lines to the buffer and syncs them to disk. Lines must not contain newlines.
+func (b *Buffer) Append(lines [][]byte) error {
+	if len(lines) == 0 {
+		return nil
+	}
+
+	var data bytes.Buffer
+	for _, line := range lines {
+		data.Write(line)
+		data.WriteByte('\n')
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := b.writer.Write(data.Bytes()); err != nil {
+		return fmt.Errorf("error writing buffer segment: %w", err)
+	}
+	if err := b.writer.Sync(); err != nil {
+		return fmt.Errorf("error syncing buffer segment: %w", err)
+	}
+	b.sizes[b.writeID] += int64(data.Len())
+
+	if b.sizes[b.writeID] >= b.segmentBytes {
+		if err := b.openSegment(b.writeID + 1); err != nil {
+			return err
+		}
+	}
+	b.enforceLimit()
+
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// enforceLimit drops the oldest segments while the buffer is over its limit
+func (b *Buffer) enforceLimit() {
+	if b.maxBytes <= 0 {
+		return
+	}
+	var total int64
+	for _, id := range b.segments {
+		total += b.sizes[id]
+	}
+	for total > b.maxBytes && len(b.segments) > 1 {
+		oldest := b.segments[0]
+		log.Printf("agent: buffer over %d bytes, dropping %d bytes of unsent logs", b.maxBytes, b.sizes[oldest])
+		total -= b.sizes[oldest]
+		b.removeSegment(oldest)
+		if b.read.Segment <= oldest {
+			b.read = Position{Segment: b.segments[0]}
+		}
+	}
+}
+
+func (b *Buffer) removeSegment(id uint64) {
+	if err := os.Remove(b.segmentPath(id)); err != nil && !os.IsNotExist(err) {
+		log.Printf("agent: failed to remove buffer segment %d: %v", id, err)
+	}
+	delete(b.sizes, id)
+	b.segments = b.segments[1:]
+}
+
+// Notify receives a value after logs are appended
+func (b *Buffer) Notify() <-chan struct{} {
+	return b.notify
+}
+
+// Read returns up to max lines from the read position, without consuming them, and the
+// position after them to pass to Ack. It returns no lines when the buffer is drained.
+func (b *Buffer) Read(max int) ([][]byte, Position, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for {
+		lines, next, err := b.readSegment(b.read, max)
+		if err != nil || len(lines) > 0 || b.read.Segment == b.writeID {
+			return lines, next, err
+		}
+		// A finished segment is fully read; any incomplete line at its end was cut off by a crash
+		if err := b.advance(Position{Segment: b.read.Segment + 1}); err != nil {
+			return nil, b.read, err
+		}
+	}
+}
+
+// readSegment reads complete lines from one segment
+func (b *Buffer) readSegment(pos Position, max int) ([][]byte, Position, error) {
+	f, err := os.Open(b.segmentPath(pos.Segment))
+	if os.IsNotExist(err) {
+		return nil, pos, nil
+	}
+	if err != nil {
+		return nil, pos, fmt.Errorf("error opening buffer segment: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(pos.Offset, io.SeekStart); err != nil {
+		return nil, pos, fmt.Errorf("error seeking buffer segment: %w", err)
+	}
+
+	var lines [][]byte
+	reader := bufio.NewReader(f)
+	for len(lines) < max {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, pos, fmt.Errorf("error reading buffer segment: %w", err)
+		}
+		pos.Offset += int64(len(line))
+		if len(line) > 1 {
+			lines = append(lines, line[:len(line)-1])
+		}
+	}
+	return lines, pos, nil
+}
+
+// Ack consumes the logs before pos. Positions behind the current read position, e.g. in a
+// segment dropped while its logs were being sent, are ignored.
+func (b *Buffer) Ack(pos Position) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if pos.before(b.read) {
+		return nil
+	}
+	return b.advance(pos)
+}
+
+// advance moves the read position, saves it and removes segments before it
+func (b *Buffer) advance(pos Position) error {
+	b.read = pos
+	for len(b.segments) > 0 && b.segments[0] < pos.Segment {
+		b.removeSegment(b.segments[0])
+	}
+	data := []byte(fmt.Sprintf("%d %d", pos.Segment, pos.Offset))
+	if err := writeFileAtomic(filepath.Join(b.dir, readPositionFile), data); err != nil {
+		return fmt.Errorf("error saving buffer position: %w", err)
+	}
+	return nil
+}
+
+// Close closes the segment being written
+func (b *Buffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.writer.Close()
+}
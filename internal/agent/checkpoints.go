@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// checkpointFile holds source positions inside the state directory
+const checkpointFile = "checkpoints.json"
+
+// Checkpoints records how far each source has been read, keyed by source name and, for file
+// sources, path. A position is saved only after the logs before it are in the disk buffer, so
+// after a crash logs are read again rather than lost.
+type Checkpoints struct {
+	path string
+
+	mu        sync.Mutex
+	positions map[string]string
+	dirty     bool
+}
+
+// LoadCheckpoints reads the checkpoints saved in dir, if any
+func LoadCheckpoints(dir string) (*Checkpoints, error) {
+	c := &Checkpoints{
+		path:      filepath.Join(dir, checkpointFile),
+		positions: make(map[string]string),
+	}
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoints: %w", err)
+	}
+	if err := json.Unmarshal(data, &c.positions); err != nil {
+		return nil, fmt.Errorf("error decoding checkpoints %s: %w", c.path, err)
+	}
+	return c, nil
+}
+
+// Get returns the saved position for a key
+func (c *Checkpoints) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pos, ok := c.positions[key]
+	return pos, ok
+}
+
+// Set records a position in memory; Save persists it
+func (c *Checkpoints) Set(key, pos string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.positions[key] != pos {
+		c.positions[key] = pos
+		c.dirty = true
+	}
+}
+
+// Save writes the positions to disk when they changed, replacing the file atomically
+func (c *Checkpoints) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	data, err := json.Marshal(c.positions)
+	if err != nil {
+		return fmt.Errorf("error encoding checkpoints: %w", err)
+	}
+	if err := writeFileAtomic(c.path, data); err != nil {
+		return fmt.Errorf("error saving checkpoints: %w", err)
+	}
+	c.dirty = false
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
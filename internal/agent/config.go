@@ -0,0 +1,183 @@
+package agent
+
+import (
+	"fmt"
+	"log-ingestion-service/pkg/config"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Source types
+const (
+	SourceFile     = "file"
+	SourceJournald = "journald"
+	SourceDocker   = "docker"
+)
+
+// Config configures the agent
+type Config struct {
+	// Endpoint is the base URL of the ingestion API, e.g. https://logs.example.com
+	Endpoint string `mapstructure:"endpoint"`
+	APIKey   string `mapstructure:"api_key"`
+	// StateDir holds the disk buffer and source checkpoints
+	StateDir string `mapstructure:"state_dir"`
+	// BufferMaxBytes bounds the disk buffer; the oldest logs are dropped beyond it
+	BufferMaxBytes int64         `mapstructure:"buffer_max_bytes"`
+	BatchSize      int           `mapstructure:"batch_size"`
+	FlushInterval  time.Duration `mapstructure:"flush_interval"`
+	PollInterval   time.Duration `mapstructure:"poll_interval"`
+	RequestTimeout time.Duration `mapstructure:"request_timeout"`
+	MaxRetryDelay  time.Duration `mapstructure:"max_retry_delay"`
+	// Parser configures the local parsers, like the server's parser section
+	Parser  config.ParserConfig `mapstructure:"parser"`
+	Sources []SourceConfig      `mapstructure:"sources"`
+}
+
+// SourceConfig declares one thing to tail
+type SourceConfig struct {
+	// Name identifies the source in checkpoints and logs; it defaults to type and target
+	Name string `mapstructure:"name"`
+	Type string `mapstructure:"type"`
+	// Path is a file path or glob, for file sources
+	Path string `mapstructure:"path"`
+	// FromBeginning reads files found at the first start from their start instead of their end
+	FromBeginning bool `mapstructure:"from_beginning"`
+	// Units limits a journald source to these systemd units; empty follows the whole journal
+	Units []string `mapstructure:"units"`
+	// Container is a docker container name or ID, for docker sources
+	Container string `mapstructure:"container"`
+	// Parser names the local parser; empty auto-detects, except journald sources use the journald parser
+	Parser string `mapstructure:"parser"`
+	// Multiline overrides parser.multiline for this source
+	Multiline *config.MultilineConfig `mapstructure:"multiline"`
+	// Service is set on logs whose parser found no service; it defaults to the file name
+	// without extension, the container name, or "journald"
+	Service string `mapstructure:"service"`
+}
+
+// LoadConfig reads the agent configuration from a YAML file, with LOG_AGENT_* environment overrides
+func LoadConfig(path string) (*Config, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType("yaml")
+
+	v.SetDefault("state_dir", "/var/lib/log-agent")
+	v.SetDefault("buffer_max_bytes", 256<<20)
+	v.SetDefault("batch_size", 500)
+	v.SetDefault("flush_interval", "1s")
+	v.SetDefault("poll_interval", "250ms")
+	v.SetDefault("request_timeout", "30s")
+	v.SetDefault("max_retry_delay", "1m")
+	v.SetDefault("parser.multiline.timeout", "2s")
+	v.SetDefault("parser.multiline.max_lines", 500)
+
+	v.SetEnvPrefix("LOG_AGENT")
+	v.AutomaticEnv()
+	v.BindEnv("endpoint", "LOG_AGENT_ENDPOINT")
+	v.BindEnv("api_key", "LOG_AGENT_API_KEY")
+	v.BindEnv("state_dir", "LOG_AGENT_STATE_DIR")
+
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("error reading config file %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("error unmarshaling config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// Validate checks the configuration and fills in source names
+func (c *Config) Validate() error {
+	if c.Endpoint == "" {
+		return fmt.Errorf("endpoint is required")
+	}
+	if c.APIKey == "" {
+		return fmt.Errorf("api_key is required")
+	}
+	if c.StateDir == "" {
+		return fmt.Errorf("state_dir is required")
+	}
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("batch_size must be positive")
+	}
+	if c.FlushInterval <= 0 || c.PollInterval <= 0 || c.RequestTimeout <= 0 || c.MaxRetryDelay <= 0 {
+		return fmt.Errorf("flush_interval, poll_interval, request_timeout and max_retry_delay must be positive")
+	}
+	if len(c.Sources) == 0 {
+		return fmt.Errorf("at least one source is required")
+	}
+
+	names := make(map[string]bool, len(c.Sources))
+	for i := range c.Sources {
+		src := &c.Sources[i]
+		switch src.Type {
+		case SourceFile:
+			if src.Path == "" {
+				return fmt.Errorf("source %d: path is required for file sources", i)
+			}
+		case SourceJournald:
+		case SourceDocker:
+			if src.Container == "" {
+				return fmt.Errorf("source %d: container is required for docker sources", i)
+			}
+		default:
+			return fmt.Errorf("source %d: unknown type %q", i, src.Type)
+		}
+		if src.Name == "" {
+			src.Name = src.defaultName()
+		}
+		if src.Service == "" {
+			src.Service = src.defaultService()
+		}
+		if m := src.Multiline; m != nil {
+			if m.Timeout <= 0 {
+				m.Timeout = c.Parser.Multiline.Timeout
+			}
+			if m.MaxLines <= 0 {
+				m.MaxLines = c.Parser.Multiline.MaxLines
+			}
+		}
+		if names[src.Name] {
+			return fmt.Errorf("duplicate source name %q", src.Name)
+		}
+		names[src.Name] = true
+	}
+	return nil
+}
+
+func (s *SourceConfig) defaultName() string {
+	switch s.Type {
+	case SourceFile:
+		return SourceFile + ":" + s.Path
+	case SourceDocker:
+		return SourceDocker + ":" + s.Container
+	default:
+		if len(s.Units) == 0 {
+			return SourceJournald
+		}
+		return SourceJournald + ":" + strings.Join(s.Units, ",")
+	}
+}
+
+func (s *SourceConfig) defaultService() string {
+	switch s.Type {
+	case SourceFile:
+		base := filepath.Base(s.Path)
+		if name := strings.TrimSuffix(base, filepath.Ext(base)); name != "" && !strings.ContainsAny(name, "*?[") {
+			return name
+		}
+		return filepath.Base(filepath.Dir(s.Path))
+	case SourceDocker:
+		return s.Container
+	default:
+		return SourceJournald
+	}
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// DockerSource follows a container's output through `docker logs`, resuming from the
+// timestamp of the last line buffered. Lines written in the same instant as that line may be
+// read twice after a restart.
+type DockerSource struct {
+	cfg SourceConfig
+}
+
+// NewDockerSource creates a docker source
+func NewDockerSource(cfg SourceConfig) *DockerSource {
+	return &DockerSource{cfg: cfg}
+}
+
+// Name returns the source name
+func (s *DockerSource) Name() string { return s.cfg.Name }
+
+// Run runs docker logs until ctx is cancelled or it exits, e.g. because the container stopped
+func (s *DockerSource) Run(ctx context.Context, checkpoints *Checkpoints, emit func(Line) error) error {
+	args := []string{"logs", "--follow", "--timestamps"}
+	if since, ok := checkpoints.Get(s.cfg.Name); ok {
+		args = append(args, "--since="+since)
+	} else if !s.cfg.FromBeginning {
+		args = append(args, "--tail=0")
+	}
+	args = append(args, s.cfg.Container)
+
+	// stdout and stderr share one pipe, so at most one of them writes at a time
+	reader, writer := io.Pipe()
+	cmd := exec.CommandContext(ctx, "docker", args...)
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting docker logs: %w", err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		err := cmd.Wait()
+		writer.CloseWithError(io.EOF)
+		done <- err
+	}()
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64<<10), 4<<20)
+	for scanner.Scan() {
+		// Each line starts with an RFC 3339 timestamp and a space
+		stamp, data, found := bytes.Cut(scanner.Bytes(), []byte{' '})
+		if !found {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, string(stamp))
+		if err != nil {
+			continue
+		}
+		data = bytes.TrimSuffix(data, []byte{'\r'})
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+		if err := emit(Line{
+			Data:          truncateLine(append([]byte(nil), data...)),
+			Time:          t,
+			CheckpointKey: s.cfg.Name,
+			Checkpoint:    string(stamp),
+		}); err != nil {
+			cmd.Process.Kill()
+			reader.Close()
+			<-done
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		cmd.Process.Kill()
+		reader.Close()
+		<-done
+		return fmt.Errorf("error reading docker logs output: %w", err)
+	}
+
+	err := <-done
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("docker logs exited: %v", err)
+}
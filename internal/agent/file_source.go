@@ -0,0 +1,256 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fileHeadBytes is how much of a file's start identifies it across rotations and restarts
+const fileHeadBytes = 64
+
+// FileSource tails the files matching a path or glob by polling them. Files are followed
+// across rename-based rotation: a rotated file is read to its end before the new file at
+// the path is opened, and a truncated file is read again from its start.
+type FileSource struct {
+	cfg          SourceConfig
+	pollInterval time.Duration
+	files        map[string]*tailedFile
+}
+
+// tailedFile is an open file being followed
+type tailedFile struct {
+	path string
+	file *os.File
+	// offset is the end of the last complete line read
+	offset  int64
+	head    []byte
+	partial []byte
+}
+
+// NewFileSource creates a file source
+func NewFileSource(cfg SourceConfig, pollInterval time.Duration) *FileSource {
+	return &FileSource{cfg: cfg, pollInterval: pollInterval, files: make(map[string]*tailedFile)}
+}
+
+// Name returns the source name
+func (s *FileSource) Name() string { return s.cfg.Name }
+
+// Run polls the matching files until ctx is cancelled
+func (s *FileSource) Run(ctx context.Context, checkpoints *Checkpoints, emit func(Line) error) error {
+	defer func() {
+		for path, tf := range s.files {
+			tf.file.Close()
+			delete(s.files, path)
+		}
+	}()
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	// Files already there at the first start are read from their end unless configured otherwise;
+	// files that appear later, e.g. after rotation, are read from their start
+	initial := true
+	for {
+		if err := s.poll(checkpoints, emit, initial); err != nil {
+			return err
+		}
+		initial = false
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll opens new matches and reads what was appended to each followed file
+func (s *FileSource) poll(checkpoints *Checkpoints, emit func(Line) error, initial bool) error {
+	matches, err := filepath.Glob(s.cfg.Path)
+	if err != nil {
+		return fmt.Errorf("invalid path pattern %q: %w", s.cfg.Path, err)
+	}
+	for _, path := range matches {
+		if _, tracked := s.files[path]; tracked {
+			continue
+		}
+		tf, err := s.open(path, checkpoints, initial)
+		if err != nil {
+			log.Printf("agent: source %s: %v", s.cfg.Name, err)
+			continue
+		}
+		if tf != nil {
+			s.files[path] = tf
+		}
+	}
+
+	for path, tf := range s.files {
+		if err := s.read(tf, checkpoints, emit); err != nil {
+			return err
+		}
+		if !s.stillCurrent(tf) {
+			// Rotated or removed: everything written to the old file has been read
+			if len(tf.partial) > 0 {
+				if err := s.emit(tf, tf.partial, tf.offset+int64(len(tf.partial)), checkpoints, emit); err != nil {
+					return err
+				}
+			}
+			tf.file.Close()
+			delete(s.files, path)
+		}
+	}
+	return nil
+}
+
+// open starts following a file at its checkpoint, if the checkpoint is for the same file
+func (s *FileSource) open(path string, checkpoints *Checkpoints, initial bool) (*tailedFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		f.Close()
+		return nil, nil
+	}
+
+	tf := &tailedFile{path: path, file: f}
+	tf.head = readHead(f)
+
+	if pos, ok := checkpoints.Get(s.checkpointKey(path)); ok {
+		if offset, head, ok := parseFileCheckpoint(pos); ok && bytes.HasPrefix(tf.head, head) && offset <= info.Size() {
+			tf.offset = offset
+		}
+	} else if initial && !s.cfg.FromBeginning {
+		tf.offset = info.Size()
+	}
+
+	if _, err := f.Seek(tf.offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return tf, nil
+}
+
+// read emits the complete lines appended to a file since the last poll
+func (s *FileSource) read(tf *tailedFile, checkpoints *Checkpoints, emit func(Line) error) error {
+	info, err := tf.file.Stat()
+	if err != nil {
+		return nil
+	}
+	if info.Size() < tf.offset+int64(len(tf.partial)) {
+		// Truncated in place; start over
+		tf.offset = 0
+		tf.partial = nil
+		if _, err := tf.file.Seek(0, io.SeekStart); err != nil {
+			return nil
+		}
+	}
+	if len(tf.head) < fileHeadBytes {
+		tf.head = readHead(tf.file)
+	}
+
+	chunk := make([]byte, 64<<10)
+	for {
+		n, err := tf.file.Read(chunk)
+		if n > 0 {
+			data := append(tf.partial, chunk[:n]...)
+			end := tf.offset
+			for {
+				i := bytes.IndexByte(data, '\n')
+				if i < 0 {
+					break
+				}
+				end += int64(i) + 1
+				if err := s.emit(tf, data[:i], end, checkpoints, emit); err != nil {
+					return err
+				}
+				data = data[i+1:]
+			}
+			if len(data) > maxLineBytes {
+				end += int64(len(data))
+				if err := s.emit(tf, data, end, checkpoints, emit); err != nil {
+					return err
+				}
+				data = nil
+			}
+			tf.partial = append([]byte(nil), data...)
+		}
+		if err == io.EOF || n == 0 {
+			return nil
+		}
+		if err != nil {
+			log.Printf("agent: source %s: error reading %s: %v", s.cfg.Name, tf.path, err)
+			return nil
+		}
+	}
+}
+
+// emit passes one line on and records the offset after it
+func (s *FileSource) emit(tf *tailedFile, data []byte, end int64, checkpoints *Checkpoints, emit func(Line) error) error {
+	tf.offset = end
+	data = bytes.TrimSuffix(data, []byte{'\r'})
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+	return emit(Line{
+		Data:          truncateLine(append([]byte(nil), data...)),
+		CheckpointKey: s.checkpointKey(tf.path),
+		Checkpoint:    formatFileCheckpoint(end, tf.head),
+	})
+}
+
+// stillCurrent reports whether the path still names the open file
+func (s *FileSource) stillCurrent(tf *tailedFile) bool {
+	pathInfo, err := os.Stat(tf.path)
+	if err != nil {
+		return false
+	}
+	openInfo, err := tf.file.Stat()
+	if err != nil {
+		return false
+	}
+	return os.SameFile(pathInfo, openInfo)
+}
+
+func (s *FileSource) checkpointKey(path string) string {
+	return s.cfg.Name + "|" + path
+}
+
+// readHead returns the first bytes of a file, which identify it
+func readHead(f *os.File) []byte {
+	head := make([]byte, fileHeadBytes)
+	n, _ := f.ReadAt(head, 0)
+	return head[:n]
+}
+
+// formatFileCheckpoint encodes an offset and the file's head
+func formatFileCheckpoint(offset int64, head []byte) string {
+	return strconv.FormatInt(offset, 10) + " " + hex.EncodeToString(head)
+}
+
+func parseFileCheckpoint(pos string) (int64, []byte, bool) {
+	offsetText, headText, _ := strings.Cut(pos, " ")
+	offset, err := strconv.ParseInt(offsetText, 10, 64)
+	if err != nil {
+		return 0, nil, false
+	}
+	head, err := hex.DecodeString(headText)
+	if err != nil {
+		return 0, nil, false
+	}
+	return offset, head, true
+}
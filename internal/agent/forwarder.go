@@ -0,0 +1,173 @@
+package agent
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ingestPath is the NDJSON ingestion endpoint, relative to the configured endpoint
+	ingestPath = "/api/v1/logs/ndjson"
+	// minRetryDelay is the first wait after a failed send
+	minRetryDelay = time.Second
+)
+
+// Forwarder sends buffered logs to the ingestion API in batches. A batch leaves the buffer
+// only once the API has taken it: network errors, 5xx, 429 and authentication failures are
+// retried with exponential backoff, honouring Retry-After. Batches the API refuses as
+// malformed are dropped, since sending them again cannot succeed.
+type Forwarder struct {
+	client        *http.Client
+	url           string
+	apiKey        string
+	buffer        *Buffer
+	batchSize     int
+	flushInterval time.Duration
+	maxRetryDelay time.Duration
+}
+
+// NewForwarder creates a forwarder for the buffer
+func NewForwarder(cfg *Config, buffer *Buffer) *Forwarder {
+	return &Forwarder{
+		client:        &http.Client{Timeout: cfg.RequestTimeout},
+		url:           strings.TrimRight(cfg.Endpoint, "/") + ingestPath,
+		apiKey:        cfg.APIKey,
+		buffer:        buffer,
+		batchSize:     cfg.BatchSize,
+		flushInterval: cfg.FlushInterval,
+		maxRetryDelay: cfg.MaxRetryDelay,
+	}
+}
+
+// Run forwards batches until ctx is cancelled. Logs still buffered are sent on the next start.
+func (f *Forwarder) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		lines, next, err := f.buffer.Read(f.batchSize)
+		if err != nil {
+			log.Printf("agent: %v", err)
+		}
+		if len(lines) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-f.buffer.Notify():
+			case <-ticker.C:
+			}
+			continue
+		}
+
+		if !f.sendWithRetry(ctx, lines) {
+			return
+		}
+		if err := f.buffer.Ack(next); err != nil {
+			log.Printf("agent: %v", err)
+		}
+	}
+}
+
+// sendError is a failed send, and whether it is worth retrying
+type sendError struct {
+	err        error
+	retry      bool
+	retryAfter time.Duration
+}
+
+func (e *sendError) Error() string { return e.err.Error() }
+
+// sendWithRetry sends a batch until it is taken or dropped. It returns false if ctx was
+// cancelled first.
+func (f *Forwarder) sendWithRetry(ctx context.Context, lines [][]byte) bool {
+	delay := minRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := f.send(ctx, lines)
+		if err == nil {
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		if !err.retry {
+			log.Printf("agent: dropping %d logs: %v", len(lines), err)
+			return true
+		}
+
+		wait := delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+		if err.retryAfter > wait {
+			wait = err.retryAfter
+		}
+		log.Printf("agent: sending %d logs failed (attempt %d), retrying in %s: %v", len(lines), attempt, wait.Round(time.Millisecond), err)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(wait):
+		}
+		if delay *= 2; delay > f.maxRetryDelay {
+			delay = f.maxRetryDelay
+		}
+	}
+}
+
+// send posts a batch as gzip-compressed NDJSON
+func (f *Forwarder) send(ctx context.Context, lines [][]byte) *sendError {
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	for _, line := range lines {
+		zw.Write(line)
+		zw.Write([]byte{'\n'})
+	}
+	if err := zw.Close(); err != nil {
+		return &sendError{err: fmt.Errorf("error compressing batch: %w", err)}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.url, &body)
+	if err != nil {
+		return &sendError{err: err}
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("X-API-Key", f.apiKey)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return &sendError{err: err, retry: true}
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		var result struct {
+			Rejected int      `json:"rejected"`
+			Errors   []string `json:"errors"`
+		}
+		if json.Unmarshal(respBody, &result) == nil && result.Rejected > 0 {
+			log.Printf("agent: API rejected %d of %d logs: %s", result.Rejected, len(lines), strings.Join(result.Errors, "; "))
+		}
+		return nil
+	}
+
+	sendErr := &sendError{err: fmt.Errorf("API responded %s: %s", resp.Status, bytes.TrimSpace(respBody))}
+	switch {
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		// A bad or revoked key is fixed by an operator; keep the logs until then
+		sendErr.retry = true
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			sendErr.retryAfter = time.Duration(seconds) * time.Second
+		}
+	}
+	return sendErr
+}
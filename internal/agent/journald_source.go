@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log-ingestion-service/internal/parser"
+	"os/exec"
+)
+
+// JournaldSource follows the systemd journal through journalctl's JSON output, resuming
+// after the cursor of the last entry buffered
+type JournaldSource struct {
+	cfg SourceConfig
+}
+
+// NewJournaldSource creates a journald source. It uses the journald parser unless another is configured.
+func NewJournaldSource(cfg SourceConfig) *JournaldSource {
+	if cfg.Parser == "" {
+		cfg.Parser = parser.NameJournald
+	}
+	return &JournaldSource{cfg: cfg}
+}
+
+// Name returns the source name
+func (s *JournaldSource) Name() string { return s.cfg.Name }
+
+// Run runs journalctl until ctx is cancelled or it exits
+func (s *JournaldSource) Run(ctx context.Context, checkpoints *Checkpoints, emit func(Line) error) error {
+	args := []string{"--output=json", "--follow", "--no-pager"}
+	if cursor, ok := checkpoints.Get(s.cfg.Name); ok {
+		args = append(args, "--after-cursor="+cursor)
+	} else if !s.cfg.FromBeginning {
+		args = append(args, "--lines=0")
+	}
+	for _, unit := range s.cfg.Units {
+		args = append(args, "--unit="+unit)
+	}
+
+	cmd := exec.CommandContext(ctx, "journalctl", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("error starting journalctl: %w", err)
+	}
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 0, 64<<10), 4<<20)
+	for scanner.Scan() {
+		data := scanner.Bytes()
+		var entry struct {
+			Cursor string `json:"__CURSOR"`
+		}
+		if err := json.Unmarshal(data, &entry); err != nil || entry.Cursor == "" {
+			continue
+		}
+		if err := emit(Line{
+			Data:          append([]byte(nil), data...),
+			CheckpointKey: s.cfg.Name,
+			Checkpoint:    entry.Cursor,
+		}); err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("error reading journalctl output: %w", err)
+	}
+
+	err = cmd.Wait()
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("journalctl exited: %v", err)
+}
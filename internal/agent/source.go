@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// maxLineBytes caps a single log line; longer lines are truncated
+const maxLineBytes = 256 << 10
+
+// Line is a raw log line read from a source
+type Line struct {
+	Data []byte
+	// Time is when the source recorded the line, if it says; it is used when the parser finds no timestamp
+	Time time.Time
+	// CheckpointKey and Checkpoint are saved once the line is buffered, to resume after it
+	CheckpointKey string
+	Checkpoint    string
+}
+
+// Source reads log lines until its context is cancelled or it fails. Run picks up from the
+// positions in checkpoints and passes each line to emit, which blocks while the agent is busy.
+type Source interface {
+	Name() string
+	Run(ctx context.Context, checkpoints *Checkpoints, emit func(Line) error) error
+}
+
+// NewSource creates the source described by cfg
+func NewSource(cfg SourceConfig, pollInterval time.Duration) (Source, error) {
+	switch cfg.Type {
+	case SourceFile:
+		return NewFileSource(cfg, pollInterval), nil
+	case SourceJournald:
+		return NewJournaldSource(cfg), nil
+	case SourceDocker:
+		return NewDockerSource(cfg), nil
+	default:
+		return nil, fmt.Errorf("unknown source type %q", cfg.Type)
+	}
+}
+
+// truncateLine cuts a line down to maxLineBytes
+func truncateLine(line []byte) []byte {
+	if len(line) > maxLineBytes {
+		return line[:maxLineBytes]
+	}
+	return line
+}
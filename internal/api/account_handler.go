@@ -0,0 +1,84 @@
+package api
+
+import (
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetFaultAccounts handles GET /api/v1/faults/:id/accounts
+func (h *FaultHandler) GetFaultAccounts(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid fault ID", nil)
+		return
+	}
+	
+	limit, offset, err := parsePagination(c, h.searchParser)
+	if err != nil {
+		problem.BadRequest(c, "Invalid pagination parameters", err)
+		return
+	}
+	
+	if _, err := h.repo.GetFault(ctx, id); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to get fault", err)
+		return
+	}
+	
+	// Fetch one extra row to detect whether another page exists
+	accounts, err := h.repo.ListFaultAccounts(ctx, id, limit+1, offset)
+	if err != nil {
+		problem.Internal(c, "Failed to get affected accounts", err)
+		return
+	}
+	accounts, hasMore := trimLookahead(accounts, limit)
+	
+	respondPage(c, "accounts", accounts, newPagination(limit, offset, len(accounts), hasMore, nil), gin.H{
+		"limit": limit,
+		"offset": offset,
+	})
+}
+
+// GetAccountFaults handles GET /api/v1/accounts/:account_id/faults.
+// ?unresolved=true leaves out resolved and ignored faults.
+func (h *FaultHandler) GetAccountFaults(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	accountID := c.Param("account_id")
+	unresolved := false
+	if value := c.Query("unresolved"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			problem.BadRequest(c, "Invalid unresolved parameter", err)
+			return
+		}
+		unresolved = parsed
+	}
+	
+	limit, offset, err := parsePagination(c, h.searchParser)
+	if err != nil {
+		problem.BadRequest(c, "Invalid pagination parameters", err)
+		return
+	}
+	
+	faults, err := h.repo.ListAccountFaults(ctx, accountID, unresolved, limit+1, offset)
+	if err != nil {
+		problem.Internal(c, "Failed to get account faults", err)
+		return
+	}
+	faults, hasMore := trimLookahead(faults, limit)
+	
+	respondPage(c, "faults", faults, newPagination(limit, offset, len(faults), hasMore, nil), gin.H{
+		"account_id": accountID,
+		"limit": limit,
+		"offset": offset,
+	})
+}
@@ -8,7 +8,15 @@ import (
 	"log"
 	"log-ingestion-service/internal/auth"
 	"log-ingestion-service/internal/batch"
+	"log-ingestion-service/internal/fault"
+	"log-ingestion-service/internal/highlight"
+	"log-ingestion-service/internal/middleware"
+	"log-ingestion-service/internal/notify"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/querycost"
 	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/internal/swr"
 	"log-ingestion-service/pkg/config"
 	"log-ingestion-service/pkg/models"
 	"net/http"
@@ -19,27 +27,42 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// rateLimitStatsLimit caps the number of callers listed in the throttling metrics
+const rateLimitStatsLimit = 20
+
 // AdminHandler handles admin web interface requests
 type AdminHandler struct {
 	repository *storage.Repository
 	batcher    *batch.Batcher
+	notifier   *notify.Dispatcher
+	parsers    *parser.Registry
+	sessions   *auth.SessionStore
 	config     *config.Config
+	guard      *querycost.Guard
+	analytics  *swr.Cache
+	notices    *fault.NoticeSampler
 	startTime  time.Time
 }
 
 // NewAdminHandler creates a new admin handler
-func NewAdminHandler(repo *storage.Repository, batcher *batch.Batcher, cfg *config.Config) *AdminHandler {
+func NewAdminHandler(repo *storage.Repository, batcher *batch.Batcher, notifier *notify.Dispatcher, parsers *parser.Registry, sessions *auth.SessionStore, cfg *config.Config, guard *querycost.Guard, analytics *swr.Cache, notices *fault.NoticeSampler) *AdminHandler {
 	return &AdminHandler{
 		repository: repo,
 		batcher:    batcher,
+		notifier:   notifier,
+		parsers:    parsers,
+		sessions:   sessions,
 		config:     cfg,
+		guard:      guard,
+		analytics:  analytics,
+		notices:    notices,
 		startTime:  time.Now(),
 	}
 }
 
 // Health returns detailed health status
 func (h *AdminHandler) Health(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	// Check database health
 	dbHealthy := true
@@ -60,11 +83,14 @@ func (h *AdminHandler) Health(c *gin.Context) {
 			"error":   dbError,
 		},
 		"batcher": gin.H{
-			"healthy":        batcherMetrics.ErrorCount == 0,
+			"healthy":        !batcherMetrics.Failing,
 			"current_batch":  batcherMetrics.CurrentBatchSize,
 			"total_processed": batcherMetrics.TotalProcessed,
 			"flush_count":    batcherMetrics.FlushCount,
 			"error_count":    batcherMetrics.ErrorCount,
+			"failed_entries": batcherMetrics.FailedEntries,
+			"last_error":     batcherMetrics.LastError,
+			"last_error_at":  batcherMetrics.LastErrorAt,
 			"uptime":         batcherMetrics.Uptime.String(),
 		},
 		"config": gin.H{
@@ -77,6 +103,9 @@ func (h *AdminHandler) Health(c *gin.Context) {
 	
 	if !dbHealthy {
 		health["status"] = "unhealthy"
+	} else if batcherMetrics.Failing {
+		// Logs are still accepted, but recent batches could not be stored
+		health["status"] = "degraded"
 	}
 	
 	c.JSON(http.StatusOK, health)
@@ -84,7 +113,7 @@ func (h *AdminHandler) Health(c *gin.Context) {
 
 // Metrics returns service metrics
 func (h *AdminHandler) Metrics(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	// Get time range from query (default: 1 hour)
 	timeRangeStr := c.DefaultQuery("range", "1h")
@@ -96,10 +125,7 @@ func (h *AdminHandler) Metrics(c *gin.Context) {
 	// Get stats
 	stats, err := h.repository.GetLogStats(ctx, timeRange)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get stats",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to get stats", err)
 		return
 	}
 	
@@ -112,9 +138,29 @@ func (h *AdminHandler) Metrics(c *gin.Context) {
 		logsPerSecond = float64(stats.TotalLogs) / timeRange.Seconds()
 	}
 	
-	// Get time series data
+	// Get time series data, bucketed in the requested timezone
 	interval := c.DefaultQuery("interval", "5m")
-	timeSeries, err := h.repository.GetTimeSeriesData(ctx, timeRange, interval)
+	location, err := parseTimezone(c)
+	if err != nil {
+		problem.BadRequest(c, "Invalid timezone", err)
+		return
+	}
+	compareDays, err := parseCompare(c)
+	if err != nil {
+		problem.BadRequest(c, "Invalid compare parameter", err)
+		return
+	}
+	
+	// Widen the buckets so long ranges return at most max_points points
+	if s := c.Query("max_points"); s != "" {
+		maxPoints, err := parseInt(s)
+		if err != nil || maxPoints < 1 {
+			problem.BadRequest(c, "Invalid max_points", fmt.Errorf("max_points must be a positive integer"))
+			return
+		}
+		interval = storage.DownsampleInterval(interval, timeRange, maxPoints)
+	}
+	timeSeries, err := h.repository.GetTimeSeriesData(ctx, timeRange, interval, location)
 	if err != nil {
 		// Log error but don't fail the request
 		timeSeries = []storage.TimeSeriesPoint{}
@@ -122,6 +168,7 @@ func (h *AdminHandler) Metrics(c *gin.Context) {
 	
 	metrics := gin.H{
 		"time_range": timeRange.String(),
+		"interval": interval,
 		"logs": gin.H{
 			"total":         stats.TotalLogs,
 			"per_second":    logsPerSecond,
@@ -131,16 +178,39 @@ func (h *AdminHandler) Metrics(c *gin.Context) {
 			"recent_errors": stats.RecentErrors,
 		},
 		"batcher": batcherMetrics,
+		"rate_limit": gin.H{
+			"enabled":   h.config.RateLimit.Enabled,
+			"throttled": middleware.SharedRateLimiter(&h.config.RateLimit).Stats(rateLimitStatsLimit),
+		},
+		"concurrency": gin.H{
+			"enabled":       h.config.Concurrency.Enabled,
+			"max_in_flight": h.config.Concurrency.MaxInFlight,
+			"classes":       middleware.SharedConcurrencyLimiter(&h.config.Concurrency).Stats(),
+		},
+		"notice_sampling": h.notices.Stats(),
 		"time_series": timeSeries,
+		"timezone": location.String(),
 		"uptime": time.Since(h.startTime).String(),
 	}
 	
+	// The same window a day or week earlier, to overlay on the time series
+	if compareDays > 0 {
+		comparison, err := h.repository.GetComparisonTimeSeriesData(ctx, timeRange, interval, location, compareDays)
+		if err != nil {
+			comparison = []storage.TimeSeriesPoint{}
+		}
+		metrics["comparison"] = gin.H{
+			"compare":     c.Query("compare"),
+			"time_series": comparison,
+		}
+	}
+	
 	c.JSON(http.StatusOK, metrics)
 }
 
-// RecentLogs returns recent logs as JSON
+// RecentLogs returns recent logs as JSON, or with q the logs matching a search
 func (h *AdminHandler) RecentLogs(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	limit := 100
 	if limitStr := c.Query("limit"); limitStr != "" {
@@ -149,50 +219,114 @@ func (h *AdminHandler) RecentLogs(c *gin.Context) {
 		}
 	}
 	
-	logs, err := h.repository.GetRecentLogs(ctx, limit)
+	offset := 0
+	if cursor := c.Query("cursor"); cursor != "" {
+		parsedOffset, err := decodeCursor(cursor)
+		if err != nil {
+			problem.BadRequest(c, "Invalid pagination parameters", err)
+			return
+		}
+		offset = parsedOffset
+	} else if offsetStr := c.Query("offset"); offsetStr != "" {
+		if parsedOffset, err := parseInt(offsetStr); err == nil && parsedOffset >= 0 {
+			offset = parsedOffset
+		}
+	}
+	
+	if query := c.Query("q"); query != "" {
+		h.searchLogs(c, query, limit, offset)
+		return
+	}
+	
+	logs, err := h.repository.GetRecentLogs(ctx, limit+1, offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get recent logs",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to get recent logs", err)
 		return
 	}
+	logs, hasMore := trimLookahead(logs, limit)
 	
-	c.JSON(http.StatusOK, gin.H{
-		"logs": logs,
+	respondPage(c, "logs", logs, newPagination(limit, offset, len(logs), hasMore, nil), gin.H{
 		"count": len(logs),
 	})
 }
 
+// searchedLog is a log matched by a search, with a snippet of its message around the first
+// match and where the search text occurs in the message and the snippet
+type searchedLog struct {
+	models.LogEntry
+	Snippet    string             `json:"snippet"`
+	Highlights []models.Highlight `json:"highlights"`
+}
+
+// searchLogs responds to RecentLogs with the logs matching a query in the log query language
+// of subscriptions, newest first. Searches go through the query guard.
+func (h *AdminHandler) searchLogs(c *gin.Context, query string, limit, offset int) {
+	filters, err := parser.NewSearchParser(nil).ParseLogQuery(query)
+	if err != nil {
+		problem.BadRequest(c, "Invalid search query", err)
+		return
+	}
+	filters.Limit = limit + 1
+	filters.Offset = offset
+	
+	done, ok := admitQuery(c, h.guard, h.repository, func(ctx context.Context) (*storage.QueryEstimate, error) {
+		return h.repository.EstimateLogs(ctx, *filters)
+	})
+	if !ok {
+		return
+	}
+	defer done()
+	
+	logs, _, err := h.repository.SearchLogs(c.Request.Context(), *filters)
+	if err != nil {
+		problem.Internal(c, "Failed to search logs", err)
+		return
+	}
+	logs, hasMore := trimLookahead(logs, limit)
+	
+	results := make([]searchedLog, len(logs))
+	for i, entry := range logs {
+		results[i] = searchedLog{LogEntry: entry, Snippet: entry.Message, Highlights: []models.Highlight{}}
+		if filters.Search == "" {
+			continue
+		}
+		results[i].Highlights = appendHighlights(results[i].Highlights, "message", entry.Message, filters.Search)
+		var spans []highlight.Span
+		results[i].Snippet, spans = highlight.Snippet(entry.Message, filters.Search, searchSnippetWidth)
+		results[i].Highlights = appendSpans(results[i].Highlights, "snippet", spans)
+	}
+	
+	respondPage(c, "logs", results, newPagination(limit, offset, len(results), hasMore, nil), gin.H{
+		"count": len(results),
+	})
+}
+
 // GetLogByID returns a single log entry by ID
 func (h *AdminHandler) GetLogByID(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	idStr := c.Param("id")
 	var id int64
 	if _, err := fmt.Sscanf(idStr, "%d", &id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid log ID",
-		})
+		problem.BadRequest(c, "Invalid log ID", nil)
 		return
 	}
 	
 	log, err := h.repository.GetLogByID(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Log not found",
-			"details": err.Error(),
-		})
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Log not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to get log", err)
 		return
 	}
 	
 	c.JSON(http.StatusOK, log)
 }
 
-// Stats returns aggregated statistics
+// Stats returns aggregated statistics, served from the analytics cache
 func (h *AdminHandler) Stats(c *gin.Context) {
-	ctx := context.Background()
-	
 	// Get time range from query (default: 24 hours)
 	timeRangeStr := c.DefaultQuery("range", "24h")
 	timeRange, err := time.ParseDuration(timeRangeStr)
@@ -200,24 +334,29 @@ func (h *AdminHandler) Stats(c *gin.Context) {
 		timeRange = 24 * time.Hour
 	}
 	
+	response, err := cachedAnalytics(c, h.analytics, func(ctx context.Context) (interface{}, error) {
+		return h.stats(ctx, timeRange)
+	})
+	if err != nil {
+		problem.Internal(c, "Failed to get stats", err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, response)
+}
+
+// stats computes the statistics over a time range
+func (h *AdminHandler) stats(ctx context.Context, timeRange time.Duration) (gin.H, error) {
 	// Get total count
 	totalCount, err := h.repository.GetTotalLogCount(ctx)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get total count",
-			"details": err.Error(),
-		})
-		return
+		return nil, err
 	}
 	
 	// Get stats
 	stats, err := h.repository.GetLogStats(ctx, timeRange)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get stats",
-			"details": err.Error(),
-		})
-		return
+		return nil, err
 	}
 	
 	// Get error logs
@@ -226,14 +365,12 @@ func (h *AdminHandler) Stats(c *gin.Context) {
 		errorLogs = []models.LogEntry{}
 	}
 	
-	response := gin.H{
+	return gin.H{
 		"total_logs": totalCount,
 		"time_range": timeRange.String(),
 		"stats": stats,
 		"recent_errors": errorLogs,
-	}
-	
-	c.JSON(http.StatusOK, response)
+	}, nil
 }
 
 // RegisterRequest represents the request to register a new user
@@ -247,10 +384,7 @@ type RegisterRequest struct {
 func (h *AdminHandler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"details": err.Error(),
-		})
+		problem.BadRequest(c, "Invalid request", err)
 		return
 	}
 
@@ -258,12 +392,10 @@ func (h *AdminHandler) Register(c *gin.Context) {
 	req.Name = strings.TrimSpace(req.Name)
 
 	// Check if user already exists
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	existing, _ := h.repository.GetUserByEmail(ctx, req.Email)
 	if existing != nil {
-		c.JSON(http.StatusConflict, gin.H{
-			"error": "A user with this email already exists",
-		})
+		problem.Respond(c, http.StatusConflict, problem.CodeConflict, "A user with this email already exists", nil)
 		return
 	}
 
@@ -271,9 +403,7 @@ func (h *AdminHandler) Register(c *gin.Context) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Printf("ERROR: Failed to hash password: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create account",
-		})
+		problem.Internal(c, "Failed to create account", nil)
 		return
 	}
 
@@ -281,10 +411,7 @@ func (h *AdminHandler) Register(c *gin.Context) {
 	user, err := h.repository.CreateUserWithPassword(ctx, req.Email, req.Name, string(hashedPassword))
 	if err != nil {
 		log.Printf("ERROR: Failed to create user: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Failed to create account",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to create account", err)
 		return
 	}
 
@@ -312,75 +439,85 @@ type LoginRequest struct {
 func (h *AdminHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Invalid request",
-			"details": err.Error(),
-		})
+		problem.BadRequest(c, "Invalid request", err)
 		return
 	}
 
 	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
 
 	// Look up user by email
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	user, err := h.repository.GetUserByEmail(ctx, req.Email)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"error":   "Invalid email or password",
-		})
+		problem.Respond(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid email or password", nil)
 		return
 	}
 
 	// Verify password
 	if user.PasswordHash == nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"error":   "Invalid email or password",
-		})
+		problem.Respond(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid email or password", nil)
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(*user.PasswordHash), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"error":   "Invalid email or password",
-		})
+		problem.Respond(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid email or password", nil)
 		return
 	}
 
-	// Generate JWT
-	token, err := auth.GenerateJWT(h.config.Auth.JWTSecret, user.ID, user.Email, user.Name, user.IsAdmin)
+	log.Printf("INFO: User logged in: ID=%d, Email=%s", user.ID, user.Email)
+	startSession(c, h.repository, h.sessions, user)
+}
+
+// startSession starts a session for a user who has just authenticated, issues its token as an
+// HttpOnly session cookie and responds with the token and the user's details
+func startSession(c *gin.Context, repository *storage.Repository, sessions *auth.SessionStore, user *models.User) {
+	token, err := sessions.Start(c, user)
 	if err != nil {
-		log.Printf("ERROR: Failed to generate JWT: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to generate authentication token",
-		})
+		log.Printf("ERROR: Failed to start session: %v", err)
+		problem.Internal(c, "Failed to generate authentication token", nil)
 		return
 	}
 
-	// Set auth cookie (7 days, httpOnly=false so JS can read it)
-	c.SetCookie("auth_token", token, 86400*7, "/", "", false, false)
+	// Issue the signed token as an HttpOnly session cookie
+	auth.SetSessionCookie(c, token)
 
-	log.Printf("INFO: User logged in: ID=%d, Email=%s", user.ID, user.Email)
+	userInfo := gin.H{
+		"id":         user.ID,
+		"email":      user.Email,
+		"name":       user.Name,
+		"avatar_url": user.AvatarURL,
+		"is_admin":   user.IsAdmin,
+	}
+
+	// Include preferences so the SPA can apply theme and timezone immediately
+	if profile, err := repository.GetUserProfile(c.Request.Context(), user.ID); err == nil {
+		userInfo["preferences"] = profile.Preferences
+	} else {
+		log.Printf("WARN: Failed to load preferences for user %d: %v", user.ID, err)
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "Login successful",
 		"token":   token,
-		"user": gin.H{
-			"id":       user.ID,
-			"email":    user.Email,
-			"name":     user.Name,
-			"is_admin": user.IsAdmin,
-		},
+		"user":    userInfo,
+	})
+}
+
+// Logout ends the current session, revoking it and expiring the session cookie
+func (h *AdminHandler) Logout(c *gin.Context) {
+	h.sessions.End(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Logged out",
 	})
 }
 
 // ListAPIKeys returns API keys as JSON (keys are masked for security).
 // Admins see all keys; non-admins see only keys they created.
 func (h *AdminHandler) ListAPIKeys(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 
 	isAdmin, _ := c.Get("is_admin")
 	userID, _ := c.Get("user_id")
@@ -397,10 +534,7 @@ func (h *AdminHandler) ListAPIKeys(c *gin.Context) {
 
 	if err != nil {
 		log.Printf("ERROR: Failed to list API keys: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to list API keys",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to list API keys", err)
 		return
 	}
 	
@@ -414,6 +548,7 @@ func (h *AdminHandler) ListAPIKeys(c *gin.Context) {
 			"created_at":         key.CreatedAt,
 			"is_active":          key.IsActive,
 			"created_by_user_id": key.CreatedByUserID,
+			"parser":             key.Parser,
 		}
 	}
 	
@@ -433,10 +568,7 @@ func (h *AdminHandler) CreateAPIKey(c *gin.Context) {
 	var req CreateAPIKeyRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		log.Printf("ERROR: Invalid request to create API key: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request",
-			"details": err.Error(),
-		})
+		problem.BadRequest(c, "Invalid request", err)
 		return
 	}
 	
@@ -444,24 +576,18 @@ func (h *AdminHandler) CreateAPIKey(c *gin.Context) {
 	keyBytes := make([]byte, 32)
 	if _, err := rand.Read(keyBytes); err != nil {
 		log.Printf("ERROR: Failed to generate random bytes for API key: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to generate API key",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to generate API key", err)
 		return
 	}
 	apiKey := base64.URLEncoding.EncodeToString(keyBytes)
 
 	userID := c.GetInt64("user_id")
 	
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	createdKey, err := h.repository.CreateAPIKey(ctx, req.Name, req.Description, apiKey, userID)
 	if err != nil {
 		log.Printf("ERROR: Failed to create API key in database: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create API key",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to create API key", err)
 		return
 	}
 	
@@ -486,14 +612,12 @@ func (h *AdminHandler) DeleteAPIKey(c *gin.Context) {
 	idStr := c.Param("id")
 	var id int64
 	if _, err := fmt.Sscanf(idStr, "%d", &id); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid API key ID",
-		})
+		problem.BadRequest(c, "Invalid API key ID", nil)
 		return
 	}
 
 	isAdmin, _ := c.Get("is_admin")
-	ctx := context.Background()
+	ctx := c.Request.Context()
 
 	var deleteErr error
 	if isAdmin == true {
@@ -504,10 +628,11 @@ func (h *AdminHandler) DeleteAPIKey(c *gin.Context) {
 	}
 
 	if deleteErr != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to delete API key",
-			"details": deleteErr.Error(),
-		})
+		if storage.IsNotFound(deleteErr) {
+			problem.NotFound(c, "API key not found", deleteErr)
+			return
+		}
+		problem.Internal(c, "Failed to delete API key", deleteErr)
 		return
 	}
 	
@@ -516,6 +641,130 @@ func (h *AdminHandler) DeleteAPIKey(c *gin.Context) {
 	})
 }
 
+// UpdateAPIKeyRequest represents the request to change an API key's settings
+type UpdateAPIKeyRequest struct {
+	// Parser names a registered parser; an empty string restores automatic selection
+	Parser *string `json:"parser"`
+	// TimestampLayouts are Go time layouts tried first for JSON logs sent with the key, and
+	// Timezone the timezone of their zone-less timestamps; empty values restore the defaults.
+	// They are set together.
+	TimestampLayouts *[]string `json:"timestamp_layouts"`
+	Timezone         *string   `json:"timezone"`
+}
+
+// UpdateAPIKey changes the parser and timestamp formats used for logs sent with an API key.
+// Admins can update any key; non-admins can only update keys they created.
+func (h *AdminHandler) UpdateAPIKey(c *gin.Context) {
+	idStr := c.Param("id")
+	var id int64
+	if _, err := fmt.Sscanf(idStr, "%d", &id); err != nil {
+		problem.BadRequest(c, "Invalid API key ID", nil)
+		return
+	}
+	
+	var req UpdateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request", err)
+		return
+	}
+	if req.Parser == nil && req.TimestampLayouts == nil && req.Timezone == nil {
+		problem.BadRequest(c, "No changes requested", nil)
+		return
+	}
+	
+	parserName := req.Parser
+	if parserName != nil && *parserName == "" {
+		parserName = nil
+	} else if parserName != nil && !h.parsers.Has(*parserName) {
+		problem.BadRequest(c, "Unknown parser", fmt.Errorf("parser %q is not registered", *parserName))
+		return
+	}
+	
+	layouts := []string{}
+	var timezone *string
+	setTimestamps := req.TimestampLayouts != nil || req.Timezone != nil
+	if setTimestamps {
+		if req.TimestampLayouts != nil {
+			layouts = *req.TimestampLayouts
+		}
+		if req.Timezone != nil && *req.Timezone != "" {
+			timezone = req.Timezone
+		}
+		tz := ""
+		if timezone != nil {
+			tz = *timezone
+		}
+		if _, err := h.parsers.Timestamps(layouts, tz); err != nil {
+			problem.BadRequest(c, "Invalid timestamp formats", err)
+			return
+		}
+	}
+	
+	isAdmin, _ := c.Get("is_admin")
+	ctx := c.Request.Context()
+	var userID *int64
+	if isAdmin != true {
+		uid := c.GetInt64("user_id")
+		userID = &uid
+	}
+	
+	var updateErr error
+	if req.Parser != nil {
+		updateErr = h.repository.SetAPIKeyParser(ctx, id, parserName, userID)
+	}
+	if updateErr == nil && setTimestamps {
+		updateErr = h.repository.SetAPIKeyTimestamps(ctx, id, layouts, timezone, userID)
+	}
+	
+	if updateErr != nil {
+		if storage.IsNotFound(updateErr) {
+			problem.NotFound(c, "API key not found", updateErr)
+			return
+		}
+		problem.Internal(c, "Failed to update API key", updateErr)
+		return
+	}
+	
+	response := gin.H{"id": id}
+	if req.Parser != nil {
+		response["parser"] = parserName
+	}
+	if setTimestamps {
+		response["timestamp_layouts"] = layouts
+		response["timezone"] = timezone
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// parseTimezone reads the tz query parameter, an IANA timezone such as Europe/Berlin.
+// It defaults to UTC.
+func parseTimezone(c *gin.Context) (*time.Location, error) {
+	tz := c.Query("tz")
+	if tz == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(tz)
+	if err != nil || tz == "Local" {
+		return nil, fmt.Errorf("unknown timezone %q", tz)
+	}
+	return location, nil
+}
+
+// parseCompare reads the compare query parameter, 1d or 1w, as the number of days a comparison
+// series is shifted back; 0 when it is not set
+func parseCompare(c *gin.Context) (int, error) {
+	switch compare := c.Query("compare"); compare {
+	case "":
+		return 0, nil
+	case "1d":
+		return 1, nil
+	case "1w":
+		return 7, nil
+	default:
+		return 0, fmt.Errorf("compare must be 1d or 1w, not %q", compare)
+	}
+}
+
 // Helper function to parse integer
 func parseInt(s string) (int, error) {
 	var result int
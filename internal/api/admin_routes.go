@@ -2,27 +2,31 @@ package api
 
 import (
 	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/middleware"
 	"log-ingestion-service/pkg/config"
 
 	"github.com/gin-gonic/gin"
 )
 
 // SetupAdminRoutes configures all admin routes
-func SetupAdminRoutes(router *gin.Engine, adminHandler *AdminHandler, cfg *config.Config) {
+func SetupAdminRoutes(router *gin.Engine, adminHandler *AdminHandler, sessions *auth.SessionStore, cfg *config.Config) {
 	// Auth routes (no auth required)
 	authGroup := router.Group("/auth")
 	{
 		authGroup.POST("/register", adminHandler.Register)
 		authGroup.POST("/login", adminHandler.Login)
+		authGroup.POST("/logout", adminHandler.Logout)
 	}
 
-	// Keep legacy login route for backward compatibility
+	// Admin login/logout (session cookie lifecycle, no auth required)
 	router.POST("/admin/login", adminHandler.Login)
+	router.POST("/admin/logout", adminHandler.Logout)
 
 	// Admin routes group (JWT-protected)
 	admin := router.Group("/admin")
 	{
-		admin.Use(auth.JWTAuth(cfg.Auth.JWTSecret))
+		admin.Use(auth.JWTAuth(sessions))
+		admin.Use(middleware.Idempotency(&cfg.Idempotency))
 
 		// Health status (JSON endpoint)
 		admin.GET("/health", adminHandler.Health)
@@ -33,16 +37,32 @@ func SetupAdminRoutes(router *gin.Engine, adminHandler *AdminHandler, cfg *confi
 		// Recent logs JSON endpoint
 		admin.GET("/logs/recent", adminHandler.RecentLogs)
 
+		// Stream the logs matching a filter as NDJSON
+		admin.GET("/logs/export", adminHandler.ExportLogs)
+
 		// Get log by ID endpoint
 		admin.GET("/logs/:id", adminHandler.GetLogByID)
 
 		// Statistics endpoint
 		admin.GET("/stats", adminHandler.Stats)
 
+		// What retention policies would delete, without deleting it
+		admin.GET("/retention/preview", adminHandler.RetentionPreview)
+		// Disk space by table and project, weekly growth and when the disk fills
+		admin.GET("/storage", adminHandler.StorageUsage)
+
 		// API Keys JSON endpoints
 		admin.GET("/api/keys", adminHandler.ListAPIKeys)
 		admin.POST("/api/keys", adminHandler.CreateAPIKey)
+		admin.PATCH("/api/keys/:id", adminHandler.UpdateAPIKey)
 		admin.DELETE("/api/keys/:id", adminHandler.DeleteAPIKey)
+
+		// Send a synthetic event through every notifier
+		admin.POST("/test-notification", adminHandler.TestNotification)
+
+		// Sample data for demos and UI development
+		admin.POST("/sandbox/seed", adminHandler.SeedSandbox)
+		admin.DELETE("/sandbox", adminHandler.WipeSandbox)
 	}
 }
 
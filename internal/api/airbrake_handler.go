@@ -0,0 +1,91 @@
+package api
+
+import (
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/ingest/airbrake"
+	"log-ingestion-service/internal/middleware"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/rejects"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxAirbrakeNoticeBytes caps the size of an Airbrake notice, as Airbrake does
+const maxAirbrakeNoticeBytes = 64 << 10
+
+// SetupAirbrakeRoutes configures the Airbrake v3 compatible notice endpoint. Airbrake notifiers
+// send the project key as a bearer token or in the key query parameter, which is checked as an
+// API key. The project ID in the path is not used: the API key identifies the project.
+func SetupAirbrakeRoutes(router *gin.Engine, faultHandler *FaultHandler, keyManager *auth.KeyManager, cfg *config.Config) {
+	airbrakeAPI := router.Group("/api/v3/projects/:id")
+	{
+		airbrakeAPI.Use(airbrakeProjectKey)
+		airbrakeAPI.Use(auth.APIKeyAuth(keyManager))
+		airbrakeAPI.Use(middleware.RateLimit(&cfg.RateLimit))
+		
+		airbrakeAPI.POST("/notices", faultHandler.IngestAirbrakeNotice)
+	}
+}
+
+// airbrakeProjectKey passes a project key sent in the key query parameter on as the X-API-Key
+// header and limits the body to maxAirbrakeNoticeBytes
+func airbrakeProjectKey(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxAirbrakeNoticeBytes)
+	if key := c.Query("key"); key != "" && c.GetHeader("X-API-Key") == "" {
+		c.Request.Header.Set("X-API-Key", key)
+	}
+	c.Next()
+}
+
+// IngestAirbrakeNotice handles POST /api/v3/projects/:id/notices (Airbrake-compatible).
+// Notices are converted and grouped into faults like Honeybadger notices. The response is the
+// one Airbrake notifiers expect.
+func (h *FaultHandler) IngestAirbrakeNotice(c *gin.Context) {
+	var notice airbrake.Notice
+	
+	if c.Request.ContentLength > maxAirbrakeNoticeBytes {
+		problem.Respond(c, http.StatusRequestEntityTooLarge, problem.CodeInvalidRequest, "Request body too large", nil)
+		return
+	}
+	if err := c.ShouldBindBodyWith(&notice, binding.JSON); err != nil {
+		h.rejectAirbrakeNotice(c, err)
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	
+	req, err := airbrake.Convert(&notice)
+	if err != nil {
+		h.rejectAirbrakeNotice(c, err)
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid Airbrake notice", err)
+		return
+	}
+	
+	if isDryRun(c) {
+		h.respondNoticeDryRun(c, req)
+		return
+	}
+	
+	_, stored, err := h.grouper.ProcessNotice(c.Request.Context(), req)
+	if err != nil {
+		respondNoticeError(c, "Failed to process notice", err)
+		return
+	}
+	
+	c.JSON(http.StatusCreated, gin.H{
+		"id": stored.ID,
+	})
+}
+
+// rejectAirbrakeNotice records a notice that could not be converted, outside dry runs
+func (h *FaultHandler) rejectAirbrakeNotice(c *gin.Context, err error) {
+	if isDryRun(c) {
+		return
+	}
+	body, _ := c.Get(gin.BodyBytesKey)
+	payload, _ := body.([]byte)
+	source := rejects.Source{APIKey: c.GetString("api_key")}
+	h.rejects.Record(rejects.KindNotice, rejects.ReasonInvalidBody, source, payload, err)
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"log-ingestion-service/internal/swr"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cacheStatusHeader tells how an analytics result was served: HIT when fresh from the cache,
+// STALE when from the cache while it is recomputed, MISS when computed for the request, or
+// BYPASS when the client asked for a fresh result. The Age header gives the result's age in
+// seconds.
+const cacheStatusHeader = "X-Cache"
+
+// cachedAnalytics returns an analytics result from cache, computing it with load when missing
+// or expired, and reports how it was served in the response headers. Clients get a fresh
+// result by sending Cache-Control: no-cache.
+func cachedAnalytics(c *gin.Context, cache *swr.Cache, load swr.Loader) (interface{}, error) {
+	value, status, err := cache.Get(c.Request.Context(), analyticsCacheKey(c), cacheBypassed(c), load)
+	if err != nil {
+		return nil, err
+	}
+	if cache != nil {
+		c.Header(cacheStatusHeader, status.Status)
+		c.Header("Age", strconv.Itoa(int(status.Age.Seconds())))
+	}
+	return value, nil
+}
+
+// analyticsCacheKey identifies an analytics result by the request's path and parameters
+func analyticsCacheKey(c *gin.Context) string {
+	return c.Request.URL.Path + "?" + queryParams(c)
+}
+
+// cacheBypassed reports whether the client asked for a freshly computed result
+func cacheBypassed(c *gin.Context) bool {
+	return strings.Contains(strings.ToLower(c.GetHeader("Cache-Control")), "no-cache")
+}
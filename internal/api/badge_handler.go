@@ -0,0 +1,205 @@
+package api
+
+import (
+	"fmt"
+	"html"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/middleware"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// badgeCacheTTL bounds how stale a badge may get; badges are fetched on every page view of
+// the READMEs embedding them, so the aggregates behind them are cached
+const badgeCacheTTL = 5 * time.Minute
+
+// badgeUptimeWindow is the period uptime badges cover
+const badgeUptimeWindow = 30 * 24 * time.Hour
+
+// Badge colors, as used by shields.io
+const (
+	badgeGreen  = "#4c1"
+	badgeYellow = "#dfb317"
+	badgeRed    = "#e05d44"
+	badgeGrey   = "#555"
+)
+
+// BadgeHandler renders SVG badges of a project's open faults, error rate and uptime, for
+// embedding in READMEs and wikis. Badges are authenticated with public tokens, usually passed
+// in the token query parameter since images cannot send headers.
+type BadgeHandler struct {
+	repo *storage.Repository
+
+	mu    sync.Mutex
+	stats map[int64]cachedBadgeStats
+	// lastSweep is when expired stats were last removed
+	lastSweep time.Time
+}
+
+type cachedBadgeStats struct {
+	stats    *storage.ProjectBadgeStats
+	loadedAt time.Time
+}
+
+// NewBadgeHandler creates a badge handler
+func NewBadgeHandler(repo *storage.Repository) *BadgeHandler {
+	return &BadgeHandler{repo: repo, stats: make(map[int64]cachedBadgeStats), lastSweep: time.Now()}
+}
+
+// SetupBadgeRoutes configures the badge routes
+func SetupBadgeRoutes(router *gin.Engine, badgeHandler *BadgeHandler, cfg *config.Config) {
+	badges := router.Group("/api/v1/public/badges")
+	{
+		badges.Use(auth.PublicTokenAuth(badgeHandler.repo))
+		badges.Use(middleware.RateLimit(&cfg.RateLimit))
+
+		badges.GET("/:project/faults.svg", badgeHandler.OpenFaults)
+		badges.GET("/:project/error-rate.svg", badgeHandler.ErrorRate)
+		badges.GET("/:project/uptime.svg", badgeHandler.Uptime)
+	}
+}
+
+// OpenFaults handles GET /api/v1/public/badges/:project/faults.svg
+func (h *BadgeHandler) OpenFaults(c *gin.Context) {
+	stats, ok := h.projectStats(c)
+	if !ok {
+		return
+	}
+
+	color := badgeGreen
+	switch {
+	case stats.OpenFaults >= 10:
+		color = badgeRed
+	case stats.OpenFaults > 0:
+		color = badgeYellow
+	}
+	respondBadge(c, "open faults", strconv.FormatInt(stats.OpenFaults, 10), color)
+}
+
+// ErrorRate handles GET /api/v1/public/badges/:project/error-rate.svg, showing the notices
+// received per hour over the last 24 hours
+func (h *BadgeHandler) ErrorRate(c *gin.Context) {
+	stats, ok := h.projectStats(c)
+	if !ok {
+		return
+	}
+
+	rate := float64(stats.DayNotices) / 24
+	color := badgeGreen
+	switch {
+	case rate >= 10:
+		color = badgeRed
+	case rate > 0:
+		color = badgeYellow
+	}
+	respondBadge(c, "error rate", formatBadgeRate(rate), color)
+}
+
+// Uptime handles GET /api/v1/public/badges/:project/uptime.svg, showing the share of hours in
+// the last 30 days without a notice of a high or critical fault
+func (h *BadgeHandler) Uptime(c *gin.Context) {
+	stats, ok := h.projectStats(c)
+	if !ok {
+		return
+	}
+
+	uptime := 100 * float64(stats.UptimeHours-stats.OutageHours) / float64(stats.UptimeHours)
+	color := badgeGreen
+	switch {
+	case uptime < 99:
+		color = badgeRed
+	case uptime < 99.9:
+		color = badgeYellow
+	}
+	respondBadge(c, "uptime", strconv.FormatFloat(uptime, 'f', 2, 64)+"%", color)
+}
+
+// projectStats returns the badge aggregates of the project named in the path, from the cache
+// when fresh. It responds 404 for projects outside the token's project and for projects that do
+// not exist, which are not cached.
+func (h *BadgeHandler) projectStats(c *gin.Context) (*storage.ProjectBadgeStats, bool) {
+	projectID, err := strconv.ParseInt(c.Param("project"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid project ID", nil)
+		return nil, false
+	}
+	if scoped, ok := c.Get(auth.PublicTokenProjectIDKey); ok && scoped.(int64) != projectID {
+		problem.NotFound(c, "Project not found", fmt.Errorf("the public token does not cover project %d", projectID))
+		return nil, false
+	}
+
+	h.mu.Lock()
+	cached, ok := h.stats[projectID]
+	h.mu.Unlock()
+	if ok && time.Since(cached.loadedAt) < badgeCacheTTL {
+		return cached.stats, true
+	}
+
+	stats, err := h.repo.GetProjectBadgeStats(c.Request.Context(), projectID, badgeUptimeWindow)
+	if err != nil {
+		problem.Internal(c, "Failed to get project badge stats", err)
+		return nil, false
+	}
+	if stats == nil {
+		problem.NotFound(c, "Project not found", nil)
+		return nil, false
+	}
+
+	now := time.Now()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// Projects whose badges are no longer viewed are dropped once stale
+	if now.Sub(h.lastSweep) > badgeCacheTTL {
+		for id, cached := range h.stats {
+			if now.Sub(cached.loadedAt) >= badgeCacheTTL {
+				delete(h.stats, id)
+			}
+		}
+		h.lastSweep = now
+	}
+	h.stats[projectID] = cachedBadgeStats{stats: stats, loadedAt: now}
+	return stats, true
+}
+
+// formatBadgeRate formats notices per hour with one decimal below ten and none above
+func formatBadgeRate(rate float64) string {
+	if rate < 10 {
+		return strconv.FormatFloat(rate, 'f', 1, 64) + "/h"
+	}
+	return strconv.FormatFloat(rate, 'f', 0, 64) + "/h"
+}
+
+// respondBadge writes a flat badge in the style of shields.io. The label query parameter
+// replaces the default label.
+func respondBadge(c *gin.Context, label, value, color string) {
+	if custom := c.Query("label"); custom != "" {
+		label = custom
+	}
+
+	// Verdana at 11px averages about 7px per character
+	labelWidth := 7*len([]rune(label)) + 10
+	valueWidth := 7*len([]rune(value)) + 10
+	width := labelWidth + valueWidth
+	label, value = html.EscapeString(label), html.EscapeString(value)
+
+	svg := fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">`+
+		`<title>%[4]s: %[5]s</title>`+
+		`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+
+		`<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="%[7]s"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[4]s</text><text x="%[8]d" y="14">%[4]s</text>`+
+		`<text x="%[9]d" y="15" fill="#010101" fill-opacity=".3">%[5]s</text><text x="%[9]d" y="14">%[5]s</text>`+
+		`</g></svg>`,
+		width, labelWidth, valueWidth, label, value, color, badgeGrey, labelWidth/2, labelWidth+valueWidth/2)
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(badgeCacheTTL.Seconds())))
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", []byte(svg))
+}
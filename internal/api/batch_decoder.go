@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// batchCapacityHint pre-sizes the accepted entries of a streamed batch
+const batchCapacityHint = 256
+
+// maxPooledEntryBytes keeps unusually large entry buffers out of the pool
+const maxPooledEntryBytes = 64 << 10
+
+// rawEntryPool reuses the buffer each batch entry is decoded into
+var rawEntryPool = sync.Pool{
+	New: func() interface{} {
+		buf := make(json.RawMessage, 0, 1024)
+		return &buf
+	},
+}
+
+// decodeBatchStream reads a {"logs": [...]} body token by token and calls fn with each entry.
+// The entry buffer is reused, so fn must not retain raw. Other top-level fields are skipped.
+// It returns the number of entries seen.
+func decodeBatchStream(body io.Reader, fn func(i int, raw json.RawMessage)) (int, error) {
+	dec := json.NewDecoder(body)
+
+	if err := expectDelim(dec, '{'); err != nil {
+		return 0, err
+	}
+
+	buf := rawEntryPool.Get().(*json.RawMessage)
+	defer func() {
+		if cap(*buf) <= maxPooledEntryBytes {
+			rawEntryPool.Put(buf)
+		}
+	}()
+
+	count := 0
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return count, err
+		}
+		if key, _ := token.(string); key != "logs" {
+			// Skip the value of any other field
+			if err := dec.Decode(buf); err != nil {
+				return count, err
+			}
+			continue
+		}
+
+		if token, err = dec.Token(); err != nil {
+			return count, err
+		}
+		if token == nil {
+			continue
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			return count, fmt.Errorf("logs must be an array")
+		}
+		for dec.More() {
+			*buf = (*buf)[:0]
+			if err := dec.Decode(buf); err != nil {
+				return count, err
+			}
+			fn(count, *buf)
+			count++
+		}
+		if err := expectDelim(dec, ']'); err != nil {
+			return count, err
+		}
+	}
+
+	return count, expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, want json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("expected %q in request body, got %v", want, token)
+	}
+	return nil
+}
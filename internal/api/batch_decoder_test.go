@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// benchmarkBatch builds a {"logs": [...]} body of n standard-shape entries
+func benchmarkBatch(n int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"logs":[`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `{"timestamp":"2024-01-15T10:30:%02d.123Z","service":"checkout","level":"ERROR","message":"payment %d declined","metadata":{"order_id":%d,"amount":12.5,"currency":"EUR"}}`, i%60, i, i)
+	}
+	buf.WriteString(`]}`)
+	return buf.Bytes()
+}
+
+func BenchmarkDecodeBatchStream(b *testing.B) {
+	for _, n := range []int{10, 1000} {
+		body := benchmarkBatch(n)
+		b.Run(fmt.Sprintf("entries=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				count, err := decodeBatchStream(bytes.NewReader(body), func(int, json.RawMessage) {})
+				if err != nil || count != n {
+					b.Fatalf("decoded %d entries: %v", count, err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkDecodeBatchUnmarshal is the decoding the stream replaced, for comparison
+func BenchmarkDecodeBatchUnmarshal(b *testing.B) {
+	for _, n := range []int{10, 1000} {
+		body := benchmarkBatch(n)
+		b.Run(fmt.Sprintf("entries=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(body)))
+			for i := 0; i < b.N; i++ {
+				var req rawBatchLogRequest
+				if err := json.Unmarshal(body, &req); err != nil || len(req.Logs) != n {
+					b.Fatalf("decoded %d entries: %v", len(req.Logs), err)
+				}
+			}
+		})
+	}
+}
+
+// TestDecodeBatchStreamAllocations guards the streaming decoder: its allocations must not grow
+// with the number of entries, as the entry buffer is reused
+func TestDecodeBatchStreamAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector allocates per read")
+	}
+	allocs := func(n int) float64 {
+		body := benchmarkBatch(n)
+		return testing.AllocsPerRun(20, func() {
+			decodeBatchStream(bytes.NewReader(body), func(int, json.RawMessage) {})
+		})
+	}
+	small, large := allocs(10), allocs(1000)
+	if large > small+10 {
+		t.Errorf("decoding 1000 entries allocated %.0f times, 10 entries %.0f times", large, small)
+	}
+}
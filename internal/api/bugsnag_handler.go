@@ -0,0 +1,94 @@
+package api
+
+import (
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/ingest/bugsnag"
+	"log-ingestion-service/internal/middleware"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/rejects"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxBugsnagPayloadBytes caps the size of a Bugsnag notify payload, as Bugsnag does
+const maxBugsnagPayloadBytes = 1 << 20
+
+// SetupBugsnagRoutes configures the Bugsnag-compatible notify endpoint. Bugsnag SDKs post to
+// the root of their notify endpoint and send the project API key, which is checked as an API key.
+func SetupBugsnagRoutes(router *gin.Engine, faultHandler *FaultHandler, keyManager *auth.KeyManager, cfg *config.Config) {
+	router.POST("/",
+		sdkAPIKey(bugsnag.APIKeyHeader, "apiKey", maxBugsnagPayloadBytes),
+		auth.APIKeyAuth(keyManager),
+		middleware.RateLimit(&cfg.RateLimit),
+		faultHandler.IngestBugsnagPayload,
+	)
+}
+
+// IngestBugsnagPayload handles POST / (Bugsnag-compatible).
+// Each event of the payload is converted into a notice and grouped into a fault like a
+// Honeybadger notice. A payload is converted as a whole, so one invalid event rejects it.
+func (h *FaultHandler) IngestBugsnagPayload(c *gin.Context) {
+	var payload bugsnag.Payload
+	
+	if c.Request.ContentLength > maxBugsnagPayloadBytes {
+		problem.Respond(c, http.StatusRequestEntityTooLarge, problem.CodeInvalidRequest, "Request body too large", nil)
+		return
+	}
+	if err := c.ShouldBindBodyWith(&payload, binding.JSON); err != nil {
+		h.rejectBugsnagPayload(c, err)
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	
+	reqs, err := bugsnag.Convert(&payload)
+	if err != nil {
+		h.rejectBugsnagPayload(c, err)
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid Bugsnag payload", err)
+		return
+	}
+	
+	if isDryRun(c) {
+		previews := make([]interface{}, 0, len(reqs))
+		for _, req := range reqs {
+			preview, err := h.grouper.Preview(c.Request.Context(), req)
+			if err != nil {
+				problem.Internal(c, "Failed to process event", err)
+				return
+			}
+			previews = append(previews, preview)
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"dry_run":  true,
+			"previews": previews,
+		})
+		return
+	}
+	
+	noticeIDs := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		_, notice, err := h.grouper.ProcessNotice(c.Request.Context(), req)
+		if err != nil {
+			respondNoticeError(c, "Failed to process event", err)
+			return
+		}
+		noticeIDs = append(noticeIDs, notice.ID)
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"notice_ids": noticeIDs,
+	})
+}
+
+// rejectBugsnagPayload records a payload that could not be converted, outside dry runs
+func (h *FaultHandler) rejectBugsnagPayload(c *gin.Context, err error) {
+	if isDryRun(c) {
+		return
+	}
+	body, _ := c.Get(gin.BodyBytesKey)
+	payload, _ := body.([]byte)
+	source := rejects.Source{APIKey: c.GetString("api_key")}
+	h.rejects.Record(rejects.KindNotice, rejects.ReasonInvalidBody, source, payload, err)
+}
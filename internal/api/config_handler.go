@@ -0,0 +1,54 @@
+package api
+
+import (
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/feature"
+	"log-ingestion-service/internal/pause"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupConfigRoutes configures the route showing the configuration in effect
+func SetupConfigRoutes(router *gin.Engine, flags *feature.Flags, pipelines *pause.Controller, sessions *auth.SessionStore, cfg *config.Config) {
+	admin := router.Group("/admin/config")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("", GetConfig(cfg, flags, pipelines))
+	}
+}
+
+// GetConfig returns a handler for GET /admin/config. It shows the settings this instance
+// loaded, with secrets redacted, and the overrides made at runtime from the admin API, which
+// take precedence over them.
+func GetConfig(cfg *config.Config, flags *feature.Flags, pipelines *pause.Controller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+
+		ctx := c.Request.Context()
+		features, err := flags.List(ctx)
+		if err != nil {
+			problem.Internal(c, "Failed to list feature flags", err)
+			return
+		}
+		statuses, err := pipelines.Statuses(ctx)
+		if err != nil {
+			problem.Internal(c, "Failed to list pipelines", err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"source": cfg.Source,
+			"config": cfg.Effective(),
+			"runtime": gin.H{
+				"features":  features,
+				"pipelines": statuses,
+			},
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/deadletter"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// deadLetterDefaultEntries and deadLetterMaxEntries bound the entries shown of a batch
+	deadLetterDefaultEntries = 100
+	deadLetterMaxEntries     = 1000
+)
+
+// SetupDeadLetterRoutes configures routes to inspect, retry and discard the log batches this
+// instance failed to write
+func SetupDeadLetterRoutes(router *gin.Engine, repo *storage.Repository, store *deadletter.Store, sessions *auth.SessionStore, cfg *config.Config) {
+	admin := router.Group("/admin/dead-letter")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("", ListDeadLetters(store))
+		admin.POST("/retry", RetryDeadLetters(repo, store))
+		admin.GET("/:id", GetDeadLetter(store))
+		admin.POST("/:id/retry", RetryDeadLetter(repo, store))
+		admin.DELETE("/:id", DiscardDeadLetter(store))
+	}
+}
+
+// ListDeadLetters returns a handler for GET /admin/dead-letter, which lists the batches in the
+// queue, oldest first, with its totals
+func ListDeadLetters(store *deadletter.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		batches, err := store.List()
+		if err != nil {
+			problem.Internal(c, "Failed to list dead-letter batches", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"stats":   store.Stats(),
+			"batches": batches,
+		})
+	}
+}
+
+// GetDeadLetter returns a handler for GET /admin/dead-letter/:id, which returns a batch with
+// the first limit of its entries
+func GetDeadLetter(store *deadletter.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := deadLetterDefaultEntries
+		if limitStr := c.Query("limit"); limitStr != "" {
+			var err error
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit < 1 || limit > deadLetterMaxEntries {
+				problem.BadRequest(c, "Invalid limit parameter", fmt.Errorf("limit must be between 1 and %d", deadLetterMaxEntries))
+				return
+			}
+		}
+
+		batch, logEntries, err := store.Get(c.Param("id"), limit)
+		if err != nil {
+			respondDeadLetterError(c, "Failed to get dead-letter batch", err)
+			return
+		}
+		if logEntries == nil {
+			logEntries = []models.LogEntry{}
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"batch":   batch,
+			"entries": logEntries,
+		})
+	}
+}
+
+// RetryDeadLetter returns a handler for POST /admin/dead-letter/:id/retry, which writes a
+// batch to the database and removes it from the queue. A batch that fails again stays queued.
+func RetryDeadLetter(repo *storage.Repository, store *deadletter.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		id := c.Param("id")
+		written, err := store.Replay(id, func(logEntries []models.LogEntry) error {
+			return repo.InsertBatch(c.Request.Context(), logEntries)
+		})
+		if errors.Is(err, deadletter.ErrNotFound) {
+			problem.NotFound(c, "Dead-letter batch not found", nil)
+			return
+		}
+		if err != nil {
+			// Most likely the database has not recovered yet
+			problem.Respond(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "Failed to retry dead-letter batch", err)
+			return
+		}
+		log.Printf("INFO: Dead-letter batch %s retried (%d entries)", id, written)
+		c.JSON(http.StatusOK, gin.H{
+			"id":      id,
+			"written": written,
+		})
+	}
+}
+
+// RetryDeadLetters returns a handler for POST /admin/dead-letter/retry, which retries every
+// batch, oldest first, stopping at the first that fails again
+func RetryDeadLetters(repo *storage.Repository, store *deadletter.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		batches, err := store.List()
+		if err != nil {
+			problem.Internal(c, "Failed to list dead-letter batches", err)
+			return
+		}
+
+		retried := []string{}
+		written := 0
+		for _, batch := range batches {
+			n, err := store.Replay(batch.ID, func(logEntries []models.LogEntry) error {
+				return repo.InsertBatch(c.Request.Context(), logEntries)
+			})
+			if errors.Is(err, deadletter.ErrNotFound) {
+				// Retried or discarded by another request meanwhile
+				continue
+			}
+			if err != nil {
+				problem.Respond(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "Failed to retry dead-letter batch",
+					fmt.Errorf("batch %s failed after %d batches were retried: %w", batch.ID, len(retried), err))
+				return
+			}
+			retried = append(retried, batch.ID)
+			written += n
+		}
+		log.Printf("INFO: %d dead-letter batches retried (%d entries)", len(retried), written)
+		c.JSON(http.StatusOK, gin.H{
+			"retried": retried,
+			"written": written,
+		})
+	}
+}
+
+// DiscardDeadLetter returns a handler for DELETE /admin/dead-letter/:id, which removes a batch
+// without writing it
+func DiscardDeadLetter(store *deadletter.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		id := c.Param("id")
+		if err := store.Discard(id); err != nil {
+			respondDeadLetterError(c, "Failed to discard dead-letter batch", err)
+			return
+		}
+		log.Printf("WARN: Dead-letter batch %s discarded", id)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// respondDeadLetterError answers 404 for batches not in the queue
+func respondDeadLetterError(c *gin.Context, title string, err error) {
+	if errors.Is(err, deadletter.ErrNotFound) {
+		problem.NotFound(c, "Dead-letter batch not found", nil)
+		return
+	}
+	problem.Internal(c, title, err)
+}
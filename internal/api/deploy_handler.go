@@ -0,0 +1,54 @@
+package api
+
+import (
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListDeploys handles GET /api/v1/deploys.
+// Deploys are listed most recent first with the error rates before and after them.
+// ?project_id= and ?environment= narrow the list, and ?spikes=true keeps only deploys flagged
+// as followed by an error spike.
+func (h *FaultHandler) ListDeploys(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	filters := storage.DeployFilters{Environment: c.Query("environment")}
+	if value := c.Query("project_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			problem.BadRequest(c, "Invalid project ID", nil)
+			return
+		}
+		filters.ProjectID = &id
+	}
+	if value := c.Query("spikes"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			problem.BadRequest(c, "Invalid spikes parameter", err)
+			return
+		}
+		filters.SpikesOnly = parsed
+	}
+	
+	limit, offset, err := parsePagination(c, h.searchParser)
+	if err != nil {
+		problem.BadRequest(c, "Invalid pagination parameters", err)
+		return
+	}
+	
+	deploys, err := h.repo.ListDeploys(ctx, filters, limit+1, offset)
+	if err != nil {
+		problem.Internal(c, "Failed to list deploys", err)
+		return
+	}
+	deploys, hasMore := trimLookahead(deploys, limit)
+	if deploys == nil {
+		deploys = []models.Deploy{}
+	}
+	
+	respondPage(c, "deploys", deploys, newPagination(limit, offset, len(deploys), hasMore, nil), nil)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/validator"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LogResult is the outcome of running one log entry through the ingestion pipeline without storing it
+type LogResult struct {
+	Index    int                      `json:"index"`
+	Valid    bool                     `json:"valid"`
+	Error    string                   `json:"error,omitempty"`
+	Record   *models.LogEntry         `json:"record,omitempty"`
+	Modified *validator.Modifications `json:"modified,omitempty"`
+}
+
+// validateLogsRequest accepts either a single log or a batch
+type validateLogsRequest struct {
+	Log  json.RawMessage   `json:"log"`
+	Logs []json.RawMessage `json:"logs"`
+}
+
+// isDryRun reports whether the request asked for ?dry_run=1
+func isDryRun(c *gin.Context) bool {
+	switch c.Query("dry_run") {
+	case "1", "true":
+		return true
+	}
+	return false
+}
+
+// ValidateLogs handles POST /api/v1/logs/validate
+func (h *Handler) ValidateLogs(c *gin.Context) {
+	var req validateLogsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+
+	entries := req.Logs
+	if len(req.Log) > 0 {
+		entries = append([]json.RawMessage{req.Log}, entries...)
+	}
+	if len(entries) == 0 {
+		problem.BadRequest(c, "Request must contain log or logs", nil)
+		return
+	}
+
+	h.respondDryRun(c, entries)
+}
+
+// respondDryRun parses, validates and sanitizes entries exactly as ingestion would and returns the resulting records
+func (h *Handler) respondDryRun(c *gin.Context, entries []json.RawMessage) {
+	h.respondDecodedDryRun(c, len(entries), func(i int) (*models.LogEntry, error) {
+		return h.decodeLog(c, entries[i])
+	})
+}
+
+// respondDecodedDryRun validates and sanitizes count entries returned by decode, as ingestion would
+func (h *Handler) respondDecodedDryRun(c *gin.Context, count int, decode func(i int) (*models.LogEntry, error)) {
+	results := make([]LogResult, 0, count)
+	valid := 0
+
+	for i := 0; i < count; i++ {
+		result := LogResult{Index: i}
+
+		entry, err := decode(i)
+		if err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		if err := h.validator.ValidateFrom(entry, c.GetString("api_key")); err != nil {
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		if mods := h.validator.Sanitize(entry); mods.Modified() {
+			result.Modified = &mods
+		}
+
+		result.Valid = true
+		result.Record = entry
+		results = append(results, result)
+		valid++
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run":  true,
+		"accepted": valid,
+		"rejected": count - valid,
+		"results":  results,
+	})
+}
+
+// ValidateNotice handles POST /api/v1/notices/validate.
+// The notice is parsed and fingerprinted and the fault it would group into is reported, but nothing is stored.
+func (h *FaultHandler) ValidateNotice(c *gin.Context) {
+	var req models.NoticeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+
+	h.respondNoticeDryRun(c, &req)
+}
+
+// respondNoticeDryRun previews how a notice would be grouped
+func (h *FaultHandler) respondNoticeDryRun(c *gin.Context, req *models.NoticeRequest) {
+	preview, err := h.grouper.Preview(c.Request.Context(), req)
+	if err != nil {
+		problem.Internal(c, "Failed to process notice", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dry_run": true,
+		"preview": preview,
+	})
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EnvironmentRequest represents the request to create or update an environment. On update,
+// omitted fields are left unchanged and a retention_days of 0 removes the retention period.
+type EnvironmentRequest struct {
+	ProjectID       *int64  `json:"project_id"`
+	Name            *string `json:"name"`
+	Position        *int    `json:"position"`
+	AlertingEnabled *bool   `json:"alerting_enabled"`
+	RetentionDays   *int    `json:"retention_days"`
+}
+
+// apply copies the fields set in the request onto an environment
+func (req *EnvironmentRequest) apply(env *models.Environment) {
+	if req.Name != nil {
+		env.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Position != nil {
+		env.Position = *req.Position
+	}
+	if req.AlertingEnabled != nil {
+		env.AlertingEnabled = *req.AlertingEnabled
+	}
+	if req.RetentionDays != nil {
+		if *req.RetentionDays == 0 {
+			env.RetentionDays = nil
+		} else {
+			days := *req.RetentionDays
+			env.RetentionDays = &days
+		}
+	}
+}
+
+// validateEnvironment checks an environment's settings before it is saved
+func validateEnvironment(env *models.Environment) error {
+	if env.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if env.RetentionDays != nil && *env.RetentionDays < 1 {
+		return fmt.Errorf("retention_days must be at least 1, or 0 to keep notices as long as global retention does")
+	}
+	return nil
+}
+
+// ListEnvironments handles GET /api/v1/environments
+func (h *FaultHandler) ListEnvironments(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	var projectID *int64
+	if value := c.Query("project_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			problem.BadRequest(c, "Invalid project ID", nil)
+			return
+		}
+		projectID = &id
+	}
+	
+	envs, err := h.repo.ListEnvironments(ctx, projectID)
+	if err != nil {
+		problem.Internal(c, "Failed to list environments", err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"environments": envs,
+	})
+}
+
+// CreateEnvironment handles POST /api/v1/environments
+func (h *FaultHandler) CreateEnvironment(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	var req EnvironmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	
+	env := &models.Environment{
+		ProjectID:       req.ProjectID,
+		AlertingEnabled: true,
+	}
+	req.apply(env)
+	if err := validateEnvironment(env); err != nil {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid environment", err)
+		return
+	}
+	
+	if err := h.repo.CreateEnvironment(ctx, env); err != nil {
+		h.respondEnvironmentError(c, "Failed to create environment", err)
+		return
+	}
+	h.grouper.Environments().Invalidate()
+	
+	c.JSON(http.StatusCreated, env)
+}
+
+// UpdateEnvironment handles PATCH /api/v1/environments/:id. The project cannot be changed.
+func (h *FaultHandler) UpdateEnvironment(c *gin.Context) {
+	env, ok := h.loadEnvironment(c)
+	if !ok {
+		return
+	}
+	
+	var req EnvironmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	if req.ProjectID != nil {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid update",
+			fmt.Errorf("field %q cannot be updated", "project_id"))
+		return
+	}
+	
+	req.apply(env)
+	if err := validateEnvironment(env); err != nil {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid environment", err)
+		return
+	}
+	
+	if err := h.repo.UpdateEnvironment(c.Request.Context(), env); err != nil {
+		h.respondEnvironmentError(c, "Failed to update environment", err)
+		return
+	}
+	h.grouper.Environments().Invalidate()
+	
+	c.JSON(http.StatusOK, env)
+}
+
+// DeleteEnvironment handles DELETE /api/v1/environments/:id. The environment's faults and
+// notices are kept.
+func (h *FaultHandler) DeleteEnvironment(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid environment ID", nil)
+		return
+	}
+	
+	if err := h.repo.DeleteEnvironment(c.Request.Context(), id); err != nil {
+		h.respondEnvironmentError(c, "Failed to delete environment", err)
+		return
+	}
+	h.grouper.Environments().Invalidate()
+	
+	c.Status(http.StatusNoContent)
+}
+
+// GetUnpromotedFaults handles GET /api/v1/environments/:id/unpromoted.
+// It lists the environment's unresolved faults whose error class and location have not been
+// seen in the next environment of the promotion order, or in the one named by ?target=, so
+// regressions can be caught before release. The list is paginated.
+func (h *FaultHandler) GetUnpromotedFaults(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	env, ok := h.loadEnvironment(c)
+	if !ok {
+		return
+	}
+	
+	var target *models.Environment
+	if name := strings.TrimSpace(c.Query("target")); name != "" {
+		target = &models.Environment{ProjectID: env.ProjectID, Name: name}
+	} else {
+		next, err := h.repo.NextEnvironment(ctx, env)
+		if err != nil {
+			problem.Internal(c, "Failed to get next environment", err)
+			return
+		}
+		if next == nil {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "No environment to promote to",
+				fmt.Errorf("environment %q is the last in the promotion order; pass ?target=", env.Name))
+			return
+		}
+		target = next
+	}
+	
+	limit, offset, err := parsePagination(c, h.searchParser)
+	if err != nil {
+		problem.BadRequest(c, "Invalid pagination parameters", err)
+		return
+	}
+	
+	faults, err := h.repo.ListUnpromotedFaults(ctx, env, target, limit+1, offset)
+	if err != nil {
+		problem.Internal(c, "Failed to list unpromoted faults", err)
+		return
+	}
+	faults, hasMore := trimLookahead(faults, limit)
+	if faults == nil {
+		faults = []models.Fault{}
+	}
+	
+	respondPage(c, "faults", faults, newPagination(limit, offset, len(faults), hasMore, nil), gin.H{
+		"environment": env.Name,
+		"target":      target.Name,
+	})
+}
+
+// loadEnvironment loads the environment named by the :id parameter, responding with an error
+// when it cannot
+func (h *FaultHandler) loadEnvironment(c *gin.Context) (*models.Environment, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid environment ID", nil)
+		return nil, false
+	}
+	env, err := h.repo.GetEnvironment(c.Request.Context(), id)
+	if err != nil {
+		h.respondEnvironmentError(c, "Failed to get environment", err)
+		return nil, false
+	}
+	return env, true
+}
+
+// respondEnvironmentError responds to a failed environment operation
+func (h *FaultHandler) respondEnvironmentError(c *gin.Context, title string, err error) {
+	switch {
+	case storage.IsNotFound(err):
+		problem.NotFound(c, "Environment not found", err)
+	case errors.Is(err, storage.ErrEnvironmentExists):
+		problem.Respond(c, http.StatusConflict, problem.CodeConflict, "Environment already exists", err)
+	default:
+		problem.Internal(c, title, err)
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"log-ingestion-service/internal/fault"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateEscalationRuleRequest represents the request to create a severity escalation rule
+type CreateEscalationRuleRequest struct {
+	ProjectID        *int64 `json:"project_id"`
+	Name             string `json:"name" binding:"required"`
+	MinOccurrences   *int64 `json:"min_occurrences"`
+	MinAffectedUsers *int64 `json:"min_affected_users"`
+	WindowSeconds    *int   `json:"window_seconds"`
+	Severity         string `json:"severity" binding:"required"`
+	Enabled          *bool  `json:"enabled"`
+}
+
+// ListEscalationRules handles GET /api/v1/escalation-rules
+func (h *FaultHandler) ListEscalationRules(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	var projectID *int64
+	if value := c.Query("project_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			problem.BadRequest(c, "Invalid project ID", nil)
+			return
+		}
+		projectID = &id
+	}
+	
+	rules, err := h.repo.ListEscalationRules(ctx, projectID, false)
+	if err != nil {
+		problem.Internal(c, "Failed to list escalation rules", err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"escalation_rules": rules,
+	})
+}
+
+// CreateEscalationRule handles POST /api/v1/escalation-rules
+func (h *FaultHandler) CreateEscalationRule(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	var req CreateEscalationRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	
+	rule := &models.EscalationRule{
+		ProjectID:        req.ProjectID,
+		Name:             strings.TrimSpace(req.Name),
+		MinOccurrences:   req.MinOccurrences,
+		MinAffectedUsers: req.MinAffectedUsers,
+		WindowSeconds:    req.WindowSeconds,
+		Severity:         req.Severity,
+		Enabled:          req.Enabled == nil || *req.Enabled,
+		CreatedBy:        actorID(c),
+	}
+	if err := fault.ValidateRule(rule); err != nil {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid escalation rule", err)
+		return
+	}
+	
+	if err := h.repo.CreateEscalationRule(ctx, rule); err != nil {
+		problem.Internal(c, "Failed to create escalation rule", err)
+		return
+	}
+	
+	c.JSON(http.StatusCreated, rule)
+}
+
+// DeleteEscalationRule handles DELETE /api/v1/escalation-rules/:id
+func (h *FaultHandler) DeleteEscalationRule(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid escalation rule ID", nil)
+		return
+	}
+	
+	if err := h.repo.DeleteEscalationRule(ctx, id); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Escalation rule not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to delete escalation rule", err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Escalation rule deleted successfully",
+	})
+}
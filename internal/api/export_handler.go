@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Trailers sent after an export's last log: how many logs it returned, and whether they were
+// all the matching logs or the export stopped early, at its row cap or on an error
+const (
+	exportRowsTrailer     = "X-Export-Rows"
+	exportCompleteTrailer = "X-Export-Complete"
+)
+
+// ExportLogs handles GET /admin/logs/export, streaming the logs matching a filter as NDJSON,
+// oldest first. The q parameter takes the log query language of subscriptions (service:,
+// level: and message text); from and to bound the timestamps, to defaulting to the start of
+// the export so logs arriving meanwhile are left out. fields limits the fields returned, as
+// for faults, with id and timestamp always included. Logs are read and flushed in batches.
+// At most limit logs, and never more than export.max_rows, are returned; an export that was
+// cut short resumes by passing the timestamp and id of the last log received as after and
+// after_id. Exports are estimated and admitted by the query guard before the first batch.
+func (h *AdminHandler) ExportLogs(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	cfg := h.config.Export
+	filters, after, limit, err := parseExportParams(c, cfg.MaxRows)
+	if err != nil {
+		problem.BadRequest(c, "Invalid export parameters", err)
+		return
+	}
+
+	done, ok := admitQuery(c, h.guard, h.repository, func(ctx context.Context) (*storage.QueryEstimate, error) {
+		return h.repository.EstimateLogs(ctx, *filters)
+	})
+	if !ok {
+		return
+	}
+	defer done()
+
+	ctx := c.Request.Context()
+	logs, err := h.repository.ExportLogs(ctx, *filters, after, min(cfg.BatchSize, limit))
+	if err != nil {
+		problem.Internal(c, "Failed to export logs", err)
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Trailer", exportRowsTrailer+", "+exportCompleteTrailer)
+	c.Header("X-Export-Row-Cap", strconv.Itoa(limit))
+	c.Status(http.StatusOK)
+
+	rc := http.NewResponseController(c.Writer)
+	encoder := json.NewEncoder(c.Writer)
+	rows, complete := 0, false
+	for {
+		batchSize := min(cfg.BatchSize, limit-rows)
+		// Each batch gets its own write deadline, as server.write_timeout would end the export
+		rc.SetWriteDeadline(time.Now().Add(cfg.WriteTimeout))
+		for i := range logs {
+			var row interface{} = &logs[i]
+			if filters.Fields != nil {
+				row = logFieldValues(&logs[i], filters.Fields)
+			}
+			if err := encoder.Encode(row); err != nil {
+				// The client has gone
+				return
+			}
+		}
+		rows += len(logs)
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		if len(logs) < batchSize {
+			complete = true
+			break
+		}
+		if rows >= limit {
+			break
+		}
+		last := logs[len(logs)-1]
+		after = &storage.LogCursor{Timestamp: last.Timestamp, ID: last.ID}
+		if logs, err = h.repository.ExportLogs(ctx, *filters, after, min(cfg.BatchSize, limit-rows)); err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Printf("ERROR: Log export stopped after %d logs: %v", rows, err)
+			}
+			break
+		}
+	}
+
+	c.Writer.Header().Set(exportRowsTrailer, strconv.Itoa(rows))
+	c.Writer.Header().Set(exportCompleteTrailer, strconv.FormatBool(complete))
+}
+
+// parseExportParams reads the filter, resume position and row cap of an export
+func parseExportParams(c *gin.Context, maxRows int) (*storage.LogFilters, *storage.LogCursor, int, error) {
+	filters, err := parser.NewSearchParser(nil).ParseLogQuery(c.Query("q"))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if from := c.Query("from"); from != "" {
+		if filters.From, err = time.Parse(time.RFC3339Nano, from); err != nil {
+			return nil, nil, 0, fmt.Errorf("from must be an RFC 3339 timestamp")
+		}
+	}
+	filters.To = time.Now()
+	if to := c.Query("to"); to != "" {
+		if filters.To, err = time.Parse(time.RFC3339Nano, to); err != nil {
+			return nil, nil, 0, fmt.Errorf("to must be an RFC 3339 timestamp")
+		}
+	}
+
+	if filters.Fields, err = parseFields(c.Query("fields"), models.LogEntry{}); err != nil {
+		return nil, nil, 0, err
+	}
+	if filters.Fields != nil && !slices.Contains(filters.Fields, "timestamp") {
+		// The next batch starts after the timestamp and id of the last log
+		filters.Fields = append(filters.Fields, "timestamp")
+	}
+
+	var after *storage.LogCursor
+	if c.Query("after") != "" || c.Query("after_id") != "" {
+		after = &storage.LogCursor{}
+		if after.Timestamp, err = time.Parse(time.RFC3339Nano, c.Query("after")); err != nil {
+			return nil, nil, 0, fmt.Errorf("after must be the RFC 3339 timestamp of the last log received")
+		}
+		if after.ID, err = strconv.ParseInt(c.Query("after_id"), 10, 64); err != nil {
+			return nil, nil, 0, fmt.Errorf("after_id must be the id of the last log received")
+		}
+	}
+
+	limit := maxRows
+	if s := c.Query("limit"); s != "" {
+		if limit, err = parseInt(s); err != nil || limit <= 0 || limit > maxRows {
+			return nil, nil, 0, fmt.Errorf("limit must be between 1 and %d", maxRows)
+		}
+	}
+	return filters, after, limit, nil
+}
+
+// logFieldValues returns the selected fields of a log for encoding. Metadata is left out when
+// empty, as it is from a whole log.
+func logFieldValues(log *models.LogEntry, fields []string) map[string]interface{} {
+	values := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "id":
+			values[field] = log.ID
+		case "timestamp":
+			values[field] = log.Timestamp
+		case "service":
+			values[field] = log.Service
+		case "level":
+			values[field] = log.Level
+		case "message":
+			values[field] = log.Message
+		case "metadata":
+			if len(log.Metadata) > 0 {
+				values[field] = log.Metadata
+			}
+		}
+	}
+	return values
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FaultFieldRequest represents the request to create or update a custom field definition.
+// The key, type and project are fixed once the field is created.
+type FaultFieldRequest struct {
+	ProjectID *int64    `json:"project_id"`
+	Key       *string   `json:"key"`
+	Name      *string   `json:"name"`
+	Type      *string   `json:"type"`
+	Options   *[]string `json:"options"`
+}
+
+// ListFaultFields handles GET /api/v1/fault-fields
+func (h *FaultHandler) ListFaultFields(c *gin.Context) {
+	var projectID *int64
+	if value := c.Query("project_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			problem.BadRequest(c, "Invalid project ID", nil)
+			return
+		}
+		projectID = &id
+	}
+	
+	defs, err := h.repo.ListFaultFieldDefinitions(c.Request.Context(), projectID)
+	if err != nil {
+		problem.Internal(c, "Failed to list custom fields", err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"fields": defs,
+	})
+}
+
+// CreateFaultField handles POST /api/v1/fault-fields
+func (h *FaultHandler) CreateFaultField(c *gin.Context) {
+	var req FaultFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	
+	def := &models.FaultFieldDefinition{ProjectID: req.ProjectID}
+	if req.Key != nil {
+		def.Key = strings.TrimSpace(*req.Key)
+	}
+	if req.Type != nil {
+		def.Type = *req.Type
+	}
+	if req.Name != nil {
+		def.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Options != nil {
+		def.Options = *req.Options
+	}
+	if err := def.Validate(); err != nil {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid custom field", err)
+		return
+	}
+	
+	if err := h.repo.CreateFaultFieldDefinition(c.Request.Context(), def); err != nil {
+		h.respondFaultFieldError(c, "Failed to create custom field", err)
+		return
+	}
+	
+	c.JSON(http.StatusCreated, def)
+}
+
+// UpdateFaultField handles PATCH /api/v1/fault-fields/:id. Only the name and enum options can change.
+func (h *FaultHandler) UpdateFaultField(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid custom field ID", nil)
+		return
+	}
+	
+	var req FaultFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	for field, set := range map[string]bool{"project_id": req.ProjectID != nil, "key": req.Key != nil, "type": req.Type != nil} {
+		if set {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid update",
+				fmt.Errorf("field %q cannot be updated", field))
+			return
+		}
+	}
+	
+	def, err := h.repo.GetFaultFieldDefinition(ctx, id)
+	if err != nil {
+		h.respondFaultFieldError(c, "Failed to get custom field", err)
+		return
+	}
+	if req.Name != nil {
+		def.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Options != nil {
+		def.Options = *req.Options
+	}
+	if err := def.Validate(); err != nil {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid custom field", err)
+		return
+	}
+	
+	if err := h.repo.UpdateFaultFieldDefinition(ctx, def); err != nil {
+		h.respondFaultFieldError(c, "Failed to update custom field", err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, def)
+}
+
+// DeleteFaultField handles DELETE /api/v1/fault-fields/:id. The field's values are removed
+// from the faults it applied to.
+func (h *FaultHandler) DeleteFaultField(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid custom field ID", nil)
+		return
+	}
+	
+	if err := h.repo.DeleteFaultFieldDefinition(c.Request.Context(), id); err != nil {
+		h.respondFaultFieldError(c, "Failed to delete custom field", err)
+		return
+	}
+	
+	c.Status(http.StatusNoContent)
+}
+
+// customFieldUpdates checks custom field values from a fault update against the fields that
+// apply to the fault's project and returns them as stored, with nil removing a field. It
+// responds with an error when the values are invalid.
+func (h *FaultHandler) customFieldUpdates(c *gin.Context, fault *models.Fault, raw interface{}) (map[string]interface{}, bool) {
+	values, ok := raw.(map[string]interface{})
+	if !ok {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid update",
+			fmt.Errorf("custom_fields must be an object"))
+		return nil, false
+	}
+	
+	defs, err := h.repo.FaultFieldDefinitionsFor(c.Request.Context(), fault.ProjectID)
+	if err != nil {
+		problem.Internal(c, "Failed to get custom fields", err)
+		return nil, false
+	}
+	byKey := make(map[string]*models.FaultFieldDefinition, len(defs))
+	for i := range defs {
+		byKey[defs[i].Key] = &defs[i]
+	}
+	
+	normalized := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		def, exists := byKey[key]
+		if !exists {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid update",
+				fmt.Errorf("unknown custom field %q", key))
+			return nil, false
+		}
+		if value == nil {
+			normalized[key] = nil
+			continue
+		}
+		v, err := def.NormalizeValue(value)
+		if err != nil {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid update", err)
+			return nil, false
+		}
+		normalized[key] = v
+	}
+	return normalized, true
+}
+
+// respondFaultFieldError responds to a failed custom field operation
+func (h *FaultHandler) respondFaultFieldError(c *gin.Context, title string, err error) {
+	switch {
+	case storage.IsNotFound(err):
+		problem.NotFound(c, "Custom field not found", err)
+	case errors.Is(err, storage.ErrFaultFieldExists):
+		problem.Respond(c, http.StatusConflict, problem.CodeConflict, "Custom field already exists", err)
+	default:
+		problem.Internal(c, title, err)
+	}
+}
@@ -2,14 +2,31 @@ package api
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log-ingestion-service/internal/batch"
+	"log-ingestion-service/internal/enrich"
 	"log-ingestion-service/internal/fault"
+	"log-ingestion-service/internal/jobs"
+	"log-ingestion-service/internal/feature"
+	"log-ingestion-service/internal/notify"
 	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/querycost"
+	"log-ingestion-service/internal/redact"
+	"log-ingestion-service/internal/rejects"
 	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/internal/swr"
+	"log-ingestion-service/internal/validator"
+	"log-ingestion-service/pkg/config"
 	"log-ingestion-service/pkg/models"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // FaultHandler handles fault-related API requests
@@ -17,14 +34,32 @@ type FaultHandler struct {
 	repo         *storage.Repository
 	grouper      *fault.Grouper
 	searchParser *parser.SearchParser
+	notifier     *notify.Dispatcher
+	rejects      *rejects.Store
+	jobs         *jobs.Runner
+	workflow     *fault.Workflow
+	sla          *models.SLAPolicy
+	guard        *querycost.Guard
+	analytics    *swr.Cache
 }
 
-// NewFaultHandler creates a new fault handler
-func NewFaultHandler(repo *storage.Repository) *FaultHandler {
+// NewFaultHandler creates a new fault handler, registering the fault job types with runner
+func NewFaultHandler(repo *storage.Repository, notifier *notify.Dispatcher, notices *batch.NoticeBatcher, flags *feature.Flags, rejected *rejects.Store, runner *jobs.Runner, faultCfg *config.FaultConfig, enricher *enrich.Enricher, redactor *redact.Engine, guard *querycost.Guard, analytics *swr.Cache, limits *validator.MetadataLimiter, sampler *fault.NoticeSampler) *FaultHandler {
+	workflow := fault.NewWorkflow(repo, &faultCfg.Workflow)
+	sla := fault.NewSLAPolicy(&faultCfg.SLA)
+	grouper := fault.NewGrouper(repo, notifier, notices, flags, workflow, faultCfg.AccountFields, enricher, redactor, limits, sampler)
+	runner.Register(fault.RegroupJob, fault.NewRegrouper(grouper, repo).Run)
 	return &FaultHandler{
 		repo:         repo,
-		grouper:      fault.NewGrouper(repo),
-		searchParser: parser.NewSearchParser(),
+		grouper:      grouper,
+		searchParser: parser.NewSearchParser(sla),
+		notifier:     notifier,
+		rejects:      rejected,
+		jobs:         runner,
+		workflow:     workflow,
+		sla:          sla,
+		guard:        guard,
+		analytics:    analytics,
 	}
 }
 
@@ -32,23 +67,28 @@ func NewFaultHandler(repo *storage.Repository) *FaultHandler {
 func (h *FaultHandler) IngestNotice(c *gin.Context) {
 	var req models.NoticeRequest
 	
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
-			"details": err.Error(),
-		})
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		if !isDryRun(c) {
+			body, _ := c.Get(gin.BodyBytesKey)
+			payload, _ := body.([]byte)
+			source := rejects.Source{APIKey: c.GetString("api_key")}
+			h.rejects.Record(rejects.KindNotice, rejects.ReasonInvalidBody, source, payload, err)
+		}
+		problem.BadRequest(c, "Invalid request body", err)
 		return
 	}
 	
-	ctx := context.Background()
+	if isDryRun(c) {
+		h.respondNoticeDryRun(c, &req)
+		return
+	}
+	
+	ctx := c.Request.Context()
 	
 	// Process notice and create/update fault
 	fault, notice, err := h.grouper.ProcessNotice(ctx, &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process notice",
-			"details": err.Error(),
-		})
+		respondNoticeError(c, "Failed to process notice", err)
 		return
 	}
 	
@@ -58,235 +98,495 @@ func (h *FaultHandler) IngestNotice(c *gin.Context) {
 	})
 }
 
+// respondNoticeError answers a notice that could not be processed, with a 503 when the notice
+// batcher refused it, so the client retries later
+func respondNoticeError(c *gin.Context, title string, err error) {
+	if errors.Is(err, batch.ErrNoticeBufferFull) || errors.Is(err, batch.ErrClosed) {
+		problem.RespondRetry(c, http.StatusServiceUnavailable, problem.CodeUnavailable, title, err, overloadRetryAfter)
+		return
+	}
+	problem.Internal(c, title, err)
+}
+
 // ListFaults handles GET /api/v1/faults
 func (h *FaultHandler) ListFaults(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	// Parse search query
 	query := c.Query("q")
 	filters, err := h.searchParser.ParseQuery(query)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid search query",
-			"details": err.Error(),
-		})
+		problem.BadRequest(c, "Invalid search query", err)
 		return
 	}
 	
-	// Parse limit and offset
-	limit, offset, err := h.searchParser.ParseLimitOffset(
-		c.Query("limit"),
-		c.Query("offset"),
-	)
+	// Parse limit and offset (or cursor)
+	limit, offset, err := parsePagination(c, h.searchParser)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid pagination parameters",
-			"details": err.Error(),
-		})
+		problem.BadRequest(c, "Invalid pagination parameters", err)
 		return
 	}
 	
 	filters.Limit = limit
 	filters.Offset = offset
 	
+	if err := parseFaultSort(c, filters); err != nil {
+		problem.BadRequest(c, "Invalid sort parameter", err)
+		return
+	}
+	
+	// Parse sparse fieldsets and opt-in expansions
+	expand, err := parseExpand(c.Query("expand"), "assignee", "latest_notice")
+	if err != nil {
+		problem.BadRequest(c, "Invalid expand parameter", err)
+		return
+	}
+	fields, err := parseFields(c.Query("fields"), models.Fault{})
+	if err != nil {
+		problem.BadRequest(c, "Invalid fields parameter", err)
+		return
+	}
+	if fields != nil {
+		for name := range expand {
+			fields = append(fields, name)
+		}
+	}
+	filters.ExpandAssignee = expand["assignee"]
+	filters.ExpandLatestNotice = expand["latest_notice"]
+	
+	done, ok := admitQuery(c, h.guard, h.repo, func(ctx context.Context) (*storage.QueryEstimate, error) {
+		return h.repo.EstimateFaults(ctx, *filters)
+	})
+	if !ok {
+		return
+	}
+	defer done()
+	
 	// Get faults
 	faults, total, err := h.repo.ListFaults(ctx, *filters)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to list faults",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to list faults", err)
 		return
 	}
 	
-	c.JSON(http.StatusOK, gin.H{
-		"faults": faults,
+	now := time.Now()
+	for i := range faults {
+		faults[i].SLA = h.sla.Status(&faults[i], now)
+	}
+	if filters.Search != "" {
+		highlightFaults(faults, filters.Search)
+	}
+	
+	items, err := selectFields(faults, fields)
+	if err != nil {
+		problem.Internal(c, "Failed to select fields", err)
+		return
+	}
+	
+	hasMore := int64(offset+len(faults)) < total
+	respondPage(c, "faults", items, newPagination(limit, offset, len(faults), hasMore, &total), gin.H{
 		"total": total,
 		"limit": limit,
 		"offset": offset,
 	})
 }
 
+// respondFault writes a fault along with its SLA timers
+func (h *FaultHandler) respondFault(c *gin.Context, fault *models.Fault) {
+	fault.SLA = h.sla.Status(fault, time.Now())
+	c.JSON(http.StatusOK, fault)
+}
+
+// updatableFaultFields lists the columns PATCH /api/v1/faults/:id may change
+var updatableFaultFields = map[string]bool{
+	"message":     true,
+	"environment": true,
+	"resolved":    true,
+	"ignored":     true,
+	"state":       true,
+	"assignee_id": true,
+	"tags":        true,
+	"public":      true,
+	"severity":    true,
+	"custom_fields": true,
+}
+
+// duplicateCommentWindow is how long an identical comment from the same user is treated as a resubmission
+const duplicateCommentWindow = 10 * time.Second
+
+// facetTagLimit caps how many of the most common tags the facets endpoint returns
+const facetTagLimit = 20
+
+// GetFaultFacets handles GET /api/v1/faults/facets. Facets are served from the analytics
+// cache; only computing them goes through the query guard.
+func (h *FaultHandler) GetFaultFacets(c *gin.Context) {
+	filters, err := h.searchParser.ParseQuery(c.Query("q"))
+	if err != nil {
+		problem.BadRequest(c, "Invalid search query", err)
+		return
+	}
+	
+	if !h.analytics.Cached(analyticsCacheKey(c)) || cacheBypassed(c) {
+		done, ok := admitQuery(c, h.guard, h.repo, func(ctx context.Context) (*storage.QueryEstimate, error) {
+			return h.repo.EstimateFaults(ctx, *filters)
+		})
+		if !ok {
+			return
+		}
+		defer done()
+	}
+	
+	facets, err := cachedAnalytics(c, h.analytics, func(ctx context.Context) (interface{}, error) {
+		return h.repo.GetFaultFacets(ctx, *filters, facetTagLimit)
+	})
+	if err != nil {
+		problem.Internal(c, "Failed to get fault facets", err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"query":  c.Query("q"),
+		"facets": facets,
+	})
+}
+
+// GetFaultNeighbors handles GET /api/v1/faults/:id/neighbors.
+// It accepts the same q, sort and order parameters as the fault list.
+func (h *FaultHandler) GetFaultNeighbors(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid fault ID", nil)
+		return
+	}
+	
+	filters, err := h.searchParser.ParseQuery(c.Query("q"))
+	if err != nil {
+		problem.BadRequest(c, "Invalid search query", err)
+		return
+	}
+	
+	if err := parseFaultSort(c, filters); err != nil {
+		problem.BadRequest(c, "Invalid sort parameter", err)
+		return
+	}
+	
+	prevID, nextID, err := h.repo.GetFaultNeighbors(ctx, id, *filters)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to get neighboring faults", err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"fault_id":    id,
+		"previous_id": prevID,
+		"next_id":     nextID,
+	})
+}
+
+// parseFaultSort applies ?sort= and ?order= to fault filters
+func parseFaultSort(c *gin.Context, filters *storage.FaultFilters) error {
+	if sort := c.Query("sort"); sort != "" {
+		if _, ok := storage.FaultSortColumns[sort]; !ok {
+			return fmt.Errorf("unknown sort %q (allowed: last_seen, first_seen, occurrences, created)", sort)
+		}
+		filters.Sort = sort
+	}
+	
+	switch strings.ToLower(c.Query("order")) {
+	case "", "desc":
+		filters.SortAscending = false
+	case "asc":
+		filters.SortAscending = true
+	default:
+		return fmt.Errorf("order must be asc or desc")
+	}
+	
+	return nil
+}
+
 // GetFault handles GET /api/v1/faults/:id
 func (h *FaultHandler) GetFault(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
 	fault, err := h.repo.GetFault(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Fault not found",
-			"details": err.Error(),
-		})
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to get fault", err)
 		return
 	}
 	
-	c.JSON(http.StatusOK, fault)
+	merges, err := h.repo.GetFaultMerges(ctx, id)
+	if err != nil {
+		problem.Internal(c, "Failed to get fault merges", err)
+		return
+	}
+	fault.Merges = merges
+	
+	links, err := h.repo.GetFaultLinks(ctx, id)
+	if err != nil {
+		problem.Internal(c, "Failed to get fault links", err)
+		return
+	}
+	fault.Links = links
+	
+	h.respondFault(c, fault)
 }
 
 // UpdateFault handles PATCH /api/v1/faults/:id
 func (h *FaultHandler) UpdateFault(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
 	var updates map[string]interface{}
 	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
-			"details": err.Error(),
-		})
-		return
-	}
-	
-	if err := h.repo.UpdateFault(ctx, id, updates); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to update fault",
-			"details": err.Error(),
-		})
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	
+	for field := range updates {
+		if !updatableFaultFields[field] {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid update",
+				fmt.Errorf("field %q cannot be updated", field))
+			return
+		}
+	}
+	if severity, ok := updates["severity"]; ok {
+		if s, isString := severity.(string); !isString || models.SeverityRank(s) < 0 {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid update",
+				fmt.Errorf("severity must be one of %s", strings.Join(models.Severities, ", ")))
+			return
+		}
+	}
+	
+	// The state and the legacy flags move the fault through its workflow
+	var state string
+	if raw, ok := updates["state"]; ok {
+		delete(updates, "state")
+		s, isString := raw.(string)
+		if !isString || !h.workflow.Valid(s) {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid update",
+				fmt.Errorf("state must be one of %s", strings.Join(h.workflow.Info().States, ", ")))
+			return
+		}
+		state = s
+	}
+	flags := make(map[string]bool)
+	for _, flag := range []string{"resolved", "ignored"} {
+		raw, ok := updates[flag]
+		if !ok {
+			continue
+		}
+		delete(updates, flag)
+		value, isBool := raw.(bool)
+		if !isBool {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid update",
+				fmt.Errorf("%s must be a boolean", flag))
+			return
+		}
+		flags[flag] = value
+	}
+	if state != "" && len(flags) > 0 {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid update",
+			fmt.Errorf("state cannot be combined with resolved or ignored"))
+		return
+	}
+	
+	// Custom fields are checked against the fault's project and merged into its existing values
+	var customFields map[string]interface{}
+	if raw, ok := updates["custom_fields"]; ok {
+		delete(updates, "custom_fields")
+		fault, err := h.repo.GetFault(ctx, id)
+		if err != nil {
+			if storage.IsNotFound(err) {
+				problem.NotFound(c, "Fault not found", err)
+				return
+			}
+			problem.Internal(c, "Failed to get fault", err)
+			return
+		}
+		if customFields, ok = h.customFieldUpdates(c, fault, raw); !ok {
+			return
+		}
+	}
+	
+	if err := h.moveFault(ctx, id, state, flags, actorID(c)); err != nil {
+		if errors.Is(err, fault.ErrTransitionNotAllowed) {
+			problem.Respond(c, http.StatusConflict, problem.CodeConflict, "Invalid state transition", err)
+			return
+		}
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to update fault", err)
+		return
+	}
+	if err := h.repo.UpdateFaultTracked(ctx, id, updates, actorID(c)); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to update fault", err)
+		return
+	}
+	if err := h.repo.SetFaultCustomFields(ctx, id, customFields, actorID(c)); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to update fault", err)
 		return
 	}
 	
 	// Return updated fault
 	fault, err := h.repo.GetFault(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get updated fault",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to get updated fault", err)
 		return
 	}
 	
-	c.JSON(http.StatusOK, fault)
+	h.respondFault(c, fault)
+}
+
+// moveFault moves a fault to state, or when state is empty applies the legacy resolved and
+// ignored flags given in flags
+func (h *FaultHandler) moveFault(ctx context.Context, id int64, state string, flags map[string]bool, actorID *int64) error {
+	if state != "" {
+		return h.workflow.Transition(ctx, id, state, actorID)
+	}
+	if resolved, ok := flags["resolved"]; ok {
+		if err := h.workflow.SetResolved(ctx, id, resolved, actorID); err != nil {
+			return err
+		}
+	}
+	if ignored, ok := flags["ignored"]; ok {
+		return h.workflow.SetIgnored(ctx, id, ignored, actorID)
+	}
+	return nil
+}
+
+// GetFaultWorkflow handles GET /api/v1/faults/workflow
+func (h *FaultHandler) GetFaultWorkflow(c *gin.Context) {
+	c.JSON(http.StatusOK, h.workflow.Info())
 }
 
 // ResolveFault handles POST /api/v1/faults/:id/resolve
 func (h *FaultHandler) ResolveFault(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
-	var userID *int64
-	// TODO: Get user ID from auth context
-	// For now, nil
+	userID := actorID(c)
 	
-	if err := h.repo.ResolveFault(ctx, id, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to resolve fault",
-			"details": err.Error(),
-		})
+	if err := h.workflow.SetResolved(ctx, id, true, userID); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to resolve fault", err)
 		return
 	}
 	
 	fault, err := h.repo.GetFault(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get fault",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to get fault", err)
 		return
 	}
 	
-	c.JSON(http.StatusOK, fault)
+	h.respondFault(c, fault)
 }
 
 // UnresolveFault handles POST /api/v1/faults/:id/unresolve
 func (h *FaultHandler) UnresolveFault(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
-	var userID *int64
-	// TODO: Get user ID from auth context
+	userID := actorID(c)
 	
-	if err := h.repo.UnresolveFault(ctx, id, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to unresolve fault",
-			"details": err.Error(),
-		})
+	if err := h.workflow.SetResolved(ctx, id, false, userID); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to unresolve fault", err)
 		return
 	}
 	
 	fault, err := h.repo.GetFault(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get fault",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to get fault", err)
 		return
 	}
 	
-	c.JSON(http.StatusOK, fault)
+	h.respondFault(c, fault)
 }
 
 // IgnoreFault handles POST /api/v1/faults/:id/ignore
 func (h *FaultHandler) IgnoreFault(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
-	var userID *int64
+	userID := actorID(c)
 	
-	if err := h.repo.IgnoreFault(ctx, id, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to ignore fault",
-			"details": err.Error(),
-		})
+	if err := h.workflow.SetIgnored(ctx, id, true, userID); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to ignore fault", err)
 		return
 	}
 	
 	fault, err := h.repo.GetFault(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get fault",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to get fault", err)
 		return
 	}
 	
-	c.JSON(http.StatusOK, fault)
+	h.respondFault(c, fault)
 }
 
 // AssignFault handles POST /api/v1/faults/:id/assign
 func (h *FaultHandler) AssignFault(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
@@ -295,42 +595,35 @@ func (h *FaultHandler) AssignFault(c *gin.Context) {
 	}
 	
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
-			"details": err.Error(),
-		})
+		problem.BadRequest(c, "Invalid request body", err)
 		return
 	}
 	
-	if err := h.repo.AssignFault(ctx, id, req.UserID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to assign fault",
-			"details": err.Error(),
-		})
+	if err := h.repo.AssignFault(ctx, id, req.UserID, actorID(c)); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to assign fault", err)
 		return
 	}
 	
 	fault, err := h.repo.GetFault(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get fault",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to get fault", err)
 		return
 	}
 	
-	c.JSON(http.StatusOK, fault)
+	h.respondFault(c, fault)
 }
 
 // AddFaultTags handles POST /api/v1/faults/:id/tags
 func (h *FaultHandler) AddFaultTags(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
@@ -339,42 +632,35 @@ func (h *FaultHandler) AddFaultTags(c *gin.Context) {
 	}
 	
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
-			"details": err.Error(),
-		})
+		problem.BadRequest(c, "Invalid request body", err)
 		return
 	}
 	
-	if err := h.repo.AddFaultTags(ctx, id, req.Tags); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to add tags",
-			"details": err.Error(),
-		})
+	if err := h.repo.AddFaultTags(ctx, id, req.Tags, actorID(c)); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to add tags", err)
 		return
 	}
 	
 	fault, err := h.repo.GetFault(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get fault",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to get fault", err)
 		return
 	}
 	
-	c.JSON(http.StatusOK, fault)
+	h.respondFault(c, fault)
 }
 
 // ReplaceFaultTags handles PUT /api/v1/faults/:id/tags
 func (h *FaultHandler) ReplaceFaultTags(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
@@ -383,91 +669,71 @@ func (h *FaultHandler) ReplaceFaultTags(c *gin.Context) {
 	}
 	
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
-			"details": err.Error(),
-		})
+		problem.BadRequest(c, "Invalid request body", err)
 		return
 	}
 	
-	if err := h.repo.ReplaceFaultTags(ctx, id, req.Tags); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to replace tags",
-			"details": err.Error(),
-		})
+	if err := h.repo.ReplaceFaultTags(ctx, id, req.Tags, actorID(c)); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to replace tags", err)
 		return
 	}
 	
 	fault, err := h.repo.GetFault(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get fault",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to get fault", err)
 		return
 	}
 	
-	c.JSON(http.StatusOK, fault)
+	h.respondFault(c, fault)
 }
 
 // GetFaultNotices handles GET /api/v1/faults/:id/notices
 func (h *FaultHandler) GetFaultNotices(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
-	limit, offset, err := h.searchParser.ParseLimitOffset(
-		c.Query("limit"),
-		c.Query("offset"),
-	)
+	limit, offset, err := parsePagination(c, h.searchParser)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid pagination parameters",
-			"details": err.Error(),
-		})
+		problem.BadRequest(c, "Invalid pagination parameters", err)
 		return
 	}
 	
-	notices, err := h.repo.GetFaultOccurrences(ctx, id, limit, offset)
+	// Fetch one extra row to detect whether another page exists
+	notices, err := h.repo.GetFaultOccurrences(ctx, id, limit+1, offset)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get notices",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to get notices", err)
 		return
 	}
+	notices, hasMore := trimLookahead(notices, limit)
 	
-	c.JSON(http.StatusOK, gin.H{
-		"notices": notices,
+	respondPage(c, "notices", notices, newPagination(limit, offset, len(notices), hasMore, nil), gin.H{
 		"limit": limit,
 		"offset": offset,
 	})
 }
 
-// GetFaultStats handles GET /api/v1/faults/:id/stats
+// GetFaultStats handles GET /api/v1/faults/:id/stats, served from the analytics cache
 func (h *FaultHandler) GetFaultStats(c *gin.Context) {
-	ctx := context.Background()
-	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
-	stats, err := h.repo.GetFaultStats(ctx, id)
+	stats, err := cachedAnalytics(c, h.analytics, func(ctx context.Context) (interface{}, error) {
+		return h.repo.GetFaultStats(ctx, id)
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get stats",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to get stats", err)
 		return
 	}
 	
@@ -476,13 +742,11 @@ func (h *FaultHandler) GetFaultStats(c *gin.Context) {
 
 // CreateComment handles POST /api/v1/faults/:id/comments
 func (h *FaultHandler) CreateComment(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
@@ -492,10 +756,7 @@ func (h *FaultHandler) CreateComment(c *gin.Context) {
 	}
 	
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
-			"details": err.Error(),
-		})
+		problem.BadRequest(c, "Invalid request body", err)
 		return
 	}
 	
@@ -505,61 +766,74 @@ func (h *FaultHandler) CreateComment(c *gin.Context) {
 		Comment: req.Comment,
 	}
 	
+	// Treat an identical comment posted moments ago as a double submission
+	if existing, err := h.repo.FindRecentComment(ctx, comment, duplicateCommentWindow); err == nil {
+		c.Header("X-Duplicate-Of", strconv.FormatInt(existing.ID, 10))
+		c.JSON(http.StatusOK, existing)
+		return
+	} else if !storage.IsNotFound(err) {
+		problem.Internal(c, "Failed to create comment", err)
+		return
+	}
+	
 	if err := h.repo.CreateComment(ctx, comment); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create comment",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to create comment", err)
 		return
 	}
 	
 	c.JSON(http.StatusCreated, comment)
 }
 
-// GetFaultComments handles GET /api/v1/faults/:id/comments
+// GetFaultComments handles GET /api/v1/faults/:id/comments. Without limit, offset or cursor
+// every comment is returned, as the UI expects.
 func (h *FaultHandler) GetFaultComments(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
-	comments, err := h.repo.GetFaultComments(ctx, id)
+	if !paginationRequested(c) {
+		comments, err := h.repo.GetFaultComments(ctx, id, 0, 0)
+		if err != nil {
+			problem.Internal(c, "Failed to get comments", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"comments": comments})
+		return
+	}
+	
+	limit, offset, err := parsePagination(c, h.searchParser)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get comments",
-			"details": err.Error(),
-		})
+		problem.BadRequest(c, "Invalid pagination parameters", err)
 		return
 	}
 	
-	c.JSON(http.StatusOK, gin.H{
-		"comments": comments,
-	})
+	comments, err := h.repo.GetFaultComments(ctx, id, limit+1, offset)
+	if err != nil {
+		problem.Internal(c, "Failed to get comments", err)
+		return
+	}
+	comments, hasMore := trimLookahead(comments, limit)
+	
+	respondPage(c, "comments", comments, newPagination(limit, offset, len(comments), hasMore, nil), nil)
 }
 
 // GetFaultHistory handles GET /api/v1/faults/:id/history
 func (h *FaultHandler) GetFaultHistory(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
 	history, err := h.repo.GetFaultHistory(ctx, id)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get history",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to get history", err)
 		return
 	}
 	
@@ -570,13 +844,11 @@ func (h *FaultHandler) GetFaultHistory(c *gin.Context) {
 
 // MergeFaults handles POST /api/v1/faults/:id/merge
 func (h *FaultHandler) MergeFaults(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
@@ -585,18 +857,19 @@ func (h *FaultHandler) MergeFaults(c *gin.Context) {
 	}
 	
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
-			"details": err.Error(),
-		})
+		problem.BadRequest(c, "Invalid request body", err)
 		return
 	}
 	
-	if err := h.repo.MergeFaults(ctx, id, req.TargetFaultID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to merge faults",
-			"details": err.Error(),
-		})
+	if err := h.repo.MergeFaults(ctx, id, req.TargetFaultID, actorID(c)); err != nil {
+		switch {
+		case errors.Is(err, storage.ErrSelfMerge):
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid merge", err)
+		case storage.IsNotFound(err):
+			problem.NotFound(c, "Fault not found", err)
+		default:
+			problem.Internal(c, "Failed to merge faults", err)
+		}
 		return
 	}
 	
@@ -607,21 +880,16 @@ func (h *FaultHandler) MergeFaults(c *gin.Context) {
 
 // DeleteFault handles DELETE /api/v1/faults/:id
 func (h *FaultHandler) DeleteFault(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
 	
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid fault ID",
-		})
+		problem.BadRequest(c, "Invalid fault ID", nil)
 		return
 	}
 	
 	if err := h.repo.DeleteFault(ctx, id); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to delete fault",
-			"details": err.Error(),
-		})
+		problem.Internal(c, "Failed to delete fault", err)
 		return
 	}
 	
@@ -630,20 +898,82 @@ func (h *FaultHandler) DeleteFault(c *gin.Context) {
 	})
 }
 
-// GetUsers handles GET /api/v1/users
+// GetUsers handles GET /api/v1/users. Without limit, offset or cursor every user is returned,
+// as before the endpoint was paginated.
 func (h *FaultHandler) GetUsers(c *gin.Context) {
-	ctx := context.Background()
+	ctx := c.Request.Context()
+	
+	if !paginationRequested(c) {
+		users, err := h.repo.GetUsers(ctx, 0, 0)
+		if err != nil {
+			problem.Internal(c, "Failed to get users", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"users": users})
+		return
+	}
 	
-	users, err := h.repo.GetUsers(ctx)
+	limit, offset, err := parsePagination(c, h.searchParser)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to get users",
-			"details": err.Error(),
-		})
+		problem.BadRequest(c, "Invalid pagination parameters", err)
 		return
 	}
 	
-	c.JSON(http.StatusOK, gin.H{
-		"users": users,
-	})
+	users, err := h.repo.GetUsers(ctx, limit+1, offset)
+	if err != nil {
+		problem.Internal(c, "Failed to get users", err)
+		return
+	}
+	users, hasMore := trimLookahead(users, limit)
+	
+	respondPage(c, "users", users, newPagination(limit, offset, len(users), hasMore, nil), nil)
+}
+
+// GetProjectActivity handles GET /api/v1/projects/:id/activity
+func (h *FaultHandler) GetProjectActivity(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	projectID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid project ID", nil)
+		return
+	}
+	
+	limit, offset, err := parsePagination(c, h.searchParser)
+	if err != nil {
+		problem.BadRequest(c, "Invalid pagination parameters", err)
+		return
+	}
+	
+	types, err := parseExpand(c.Query("types"), models.ActivityTypes...)
+	if err != nil {
+		problem.BadRequest(c, "Invalid types parameter", err)
+		return
+	}
+	var typeFilter []string
+	for _, t := range models.ActivityTypes {
+		if types[t] {
+			typeFilter = append(typeFilter, t)
+		}
+	}
+	
+	events, err := h.repo.GetProjectActivity(ctx, projectID, typeFilter, limit+1, offset)
+	if err != nil {
+		problem.Internal(c, "Failed to get project activity", err)
+		return
+	}
+	events, hasMore := trimLookahead(events, limit)
+	if events == nil {
+		events = []models.ActivityEvent{}
+	}
+	
+	respondPage(c, "activity", events, newPagination(limit, offset, len(events), hasMore, nil), nil)
+}
+
+// actorID returns the signed-in user making a change, or nil for API key callers
+func actorID(c *gin.Context) *int64 {
+	if userID, ok := currentUserID(c); ok {
+		return &userID
+	}
+	return nil
 }
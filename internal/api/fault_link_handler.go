@@ -0,0 +1,122 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateFaultLinkRequest represents the request to link a fault to another
+type CreateFaultLinkRequest struct {
+	FaultID int64  `json:"fault_id" binding:"required"`
+	Kind    string `json:"kind"`
+}
+
+// GetFaultLinks handles GET /api/v1/faults/:id/links
+func (h *FaultHandler) GetFaultLinks(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid fault ID", nil)
+		return
+	}
+	
+	if _, err := h.repo.GetFault(ctx, id); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to get fault", err)
+		return
+	}
+	
+	links, err := h.repo.GetFaultLinks(ctx, id)
+	if err != nil {
+		problem.Internal(c, "Failed to get fault links", err)
+		return
+	}
+	if links == nil {
+		links = []models.FaultLink{}
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"links": links,
+	})
+}
+
+// CreateFaultLink handles POST /api/v1/faults/:id/links. The linked fault may be in any project.
+// kind defaults to relates_to; duplicate_of marks this fault as a duplicate of the other.
+func (h *FaultHandler) CreateFaultLink(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid fault ID", nil)
+		return
+	}
+	
+	var req CreateFaultLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	if req.Kind == "" {
+		req.Kind = models.LinkRelatesTo
+	}
+	if req.Kind != models.LinkRelatesTo && req.Kind != models.LinkDuplicateOf {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid link",
+			fmt.Errorf("kind must be %q or %q, got %q", models.LinkRelatesTo, models.LinkDuplicateOf, req.Kind))
+		return
+	}
+	
+	link, err := h.repo.LinkFaults(ctx, id, req.FaultID, req.Kind, actorID(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrSelfLink):
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid link", err)
+		case errors.Is(err, storage.ErrLinkExists):
+			problem.Respond(c, http.StatusConflict, problem.CodeConflict, "Faults already linked", err)
+		case storage.IsNotFound(err):
+			problem.NotFound(c, "Fault not found", err)
+		default:
+			problem.Internal(c, "Failed to link faults", err)
+		}
+		return
+	}
+	
+	c.JSON(http.StatusCreated, link)
+}
+
+// DeleteFaultLink handles DELETE /api/v1/faults/:id/links/:link_id
+func (h *FaultHandler) DeleteFaultLink(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid fault ID", nil)
+		return
+	}
+	linkID, err := strconv.ParseInt(c.Param("link_id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid link ID", nil)
+		return
+	}
+	
+	if err := h.repo.UnlinkFaults(ctx, id, linkID, actorID(c)); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault link not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to unlink faults", err)
+		return
+	}
+	
+	c.Status(http.StatusNoContent)
+}
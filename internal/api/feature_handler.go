@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/feature"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setFeatureRequest overrides a flag for one project, or globally when ProjectID is omitted
+type setFeatureRequest struct {
+	Enabled   *bool  `json:"enabled" binding:"required"`
+	ProjectID *int64 `json:"project_id"`
+}
+
+// SetupFeatureRoutes configures feature flag management routes
+func SetupFeatureRoutes(router *gin.Engine, flags *feature.Flags, sessions *auth.SessionStore, cfg *config.Config) {
+	admin := router.Group("/admin/features")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("", ListFeatures(flags))
+		admin.PUT("/:name", SetFeature(flags))
+		admin.DELETE("/:name", ClearFeature(flags))
+	}
+}
+
+// ListFeatures returns a handler for GET /admin/features
+func ListFeatures(flags *feature.Flags) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		statuses, err := flags.List(c.Request.Context())
+		if err != nil {
+			problem.Internal(c, "Failed to list feature flags", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"features": statuses})
+	}
+}
+
+// SetFeature returns a handler for PUT /admin/features/:name
+func SetFeature(flags *feature.Flags) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		var req setFeatureRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.BadRequest(c, "Invalid request body", err)
+			return
+		}
+
+		override, err := flags.Set(c.Request.Context(), c.Param("name"), req.ProjectID, *req.Enabled, actorID(c))
+		if err != nil {
+			respondFeatureError(c, "Failed to set feature flag", err)
+			return
+		}
+		c.JSON(http.StatusOK, override)
+	}
+}
+
+// ClearFeature returns a handler for DELETE /admin/features/:name.
+// The project_id query parameter selects a project override; without it the global override is removed.
+func ClearFeature(flags *feature.Flags) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		var projectID *int64
+		if raw := c.Query("project_id"); raw != "" {
+			id, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				problem.BadRequest(c, "Invalid project_id", fmt.Errorf("project_id must be an integer"))
+				return
+			}
+			projectID = &id
+		}
+
+		if err := flags.Clear(c.Request.Context(), c.Param("name"), projectID); err != nil {
+			respondFeatureError(c, "Failed to clear feature flag", err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func respondFeatureError(c *gin.Context, title string, err error) {
+	switch {
+	case errors.Is(err, feature.ErrUnknownFlag):
+		problem.NotFound(c, "Feature flag not found", err)
+	case storage.IsNotFound(err):
+		problem.NotFound(c, "Feature flag override not found", err)
+	default:
+		problem.Internal(c, title, err)
+	}
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// parseCSVParam splits a comma-separated query parameter into trimmed, non-empty values
+func parseCSVParam(value string) []string {
+	if value == "" {
+		return nil
+	}
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out
+}
+
+// parseExpand validates ?expand= values against the expansions an endpoint supports
+func parseExpand(value string, allowed ...string) (map[string]bool, error) {
+	expand := make(map[string]bool)
+	for _, name := range parseCSVParam(value) {
+		name = strings.ToLower(name)
+		ok := false
+		for _, a := range allowed {
+			if a == name {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown expansion %q (allowed: %s)", name, strings.Join(allowed, ", "))
+		}
+		expand[name] = true
+	}
+	return expand, nil
+}
+
+// parseFields validates ?fields= values against the JSON field names of model.
+// The id field is always included so clients can address returned items.
+func parseFields(value string, model interface{}) ([]string, error) {
+	fields := parseCSVParam(value)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	known := jsonFieldNames(reflect.TypeOf(model))
+	selected := []string{"id"}
+	for _, f := range fields {
+		if !known[f] {
+			return nil, fmt.Errorf("unknown field %q", f)
+		}
+		if f != "id" {
+			selected = append(selected, f)
+		}
+	}
+	return selected, nil
+}
+
+// selectFields projects each element of items onto the given JSON fields.
+// A nil fields slice returns items unchanged.
+func selectFields(items interface{}, fields []string) (interface{}, error) {
+	if fields == nil {
+		return items, nil
+	}
+
+	raw, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &rows); err != nil {
+		return nil, err
+	}
+
+	projected := make([]map[string]json.RawMessage, len(rows))
+	for i, row := range rows {
+		out := make(map[string]json.RawMessage, len(fields))
+		for _, f := range fields {
+			if v, ok := row[f]; ok {
+				out[f] = v
+			}
+		}
+		projected[i] = out
+	}
+	return projected, nil
+}
+
+// jsonFieldNames returns the set of JSON keys a struct type serializes to
+func jsonFieldNames(t reflect.Type) map[string]bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	names := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		names[name] = true
+	}
+	return names
+}
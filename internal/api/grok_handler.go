@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setGrokPatternRequest defines or replaces a runtime grok pattern
+type setGrokPatternRequest struct {
+	Pattern string `json:"pattern" binding:"required"`
+}
+
+// SetupGrokRoutes configures grok pattern management routes
+func SetupGrokRoutes(router *gin.Engine, repo *storage.Repository, parsers *parser.Registry, grokSync *parser.GrokSync, sessions *auth.SessionStore, cfg *config.Config) {
+	admin := router.Group("/admin/parsers/grok/patterns")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("", ListGrokPatterns(parsers.Grok()))
+		admin.PUT("/:name", SetGrokPattern(repo, parsers.Grok(), grokSync))
+		admin.DELETE("/:name", DeleteGrokPattern(repo, parsers.Grok(), grokSync))
+	}
+}
+
+// ListGrokPatterns returns a handler for GET /admin/parsers/grok/patterns
+func ListGrokPatterns(library *parser.GrokLibrary) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"patterns": library.Patterns()})
+	}
+}
+
+// SetGrokPattern returns a handler for PUT /admin/parsers/grok/patterns/:name.
+// The change is rejected unless the pattern and every grok parser compile with it.
+func SetGrokPattern(repo *storage.Repository, library *parser.GrokLibrary, grokSync *parser.GrokSync) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		var req setGrokPatternRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.BadRequest(c, "Invalid request body", err)
+			return
+		}
+
+		ctx := c.Request.Context()
+		name := strings.ToUpper(c.Param("name"))
+		patterns, err := storedGrokPatterns(ctx, repo)
+		if err != nil {
+			problem.Internal(c, "Failed to load grok patterns", err)
+			return
+		}
+		patterns[name] = req.Pattern
+		if err := library.Check(patterns); err != nil {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid grok pattern", err)
+			return
+		}
+
+		pattern := &models.GrokPattern{Name: name, Pattern: req.Pattern, UpdatedBy: actorID(c)}
+		if err := repo.SetGrokPattern(ctx, pattern); err != nil {
+			problem.Internal(c, "Failed to set grok pattern", err)
+			return
+		}
+		grokSync.Reload(ctx)
+		log.Printf("INFO: Grok pattern %s set", name)
+		c.JSON(http.StatusOK, pattern)
+	}
+}
+
+// DeleteGrokPattern returns a handler for DELETE /admin/parsers/grok/patterns/:name, which
+// restores the configured or built-in pattern of the same name, if any
+func DeleteGrokPattern(repo *storage.Repository, library *parser.GrokLibrary, grokSync *parser.GrokSync) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+
+		ctx := c.Request.Context()
+		name := strings.ToUpper(c.Param("name"))
+		patterns, err := storedGrokPatterns(ctx, repo)
+		if err != nil {
+			problem.Internal(c, "Failed to load grok patterns", err)
+			return
+		}
+		if _, exists := patterns[name]; !exists {
+			problem.NotFound(c, "Grok pattern not found", fmt.Errorf("no runtime grok pattern named %s", name))
+			return
+		}
+		delete(patterns, name)
+		if err := library.Check(patterns); err != nil {
+			problem.Respond(c, http.StatusConflict, problem.CodeConflict, "Grok pattern is in use", err)
+			return
+		}
+
+		if err := repo.DeleteGrokPattern(ctx, name); err != nil {
+			if storage.IsNotFound(err) {
+				problem.NotFound(c, "Grok pattern not found", err)
+				return
+			}
+			problem.Internal(c, "Failed to delete grok pattern", err)
+			return
+		}
+		grokSync.Reload(ctx)
+		log.Printf("INFO: Grok pattern %s deleted", name)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// storedGrokPatterns returns the runtime grok patterns by name
+func storedGrokPatterns(ctx context.Context, repo *storage.Repository) (map[string]string, error) {
+	stored, err := repo.ListGrokPatterns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	patterns := make(map[string]string, len(stored))
+	for _, p := range stored {
+		patterns[p.Name] = p.Pattern
+	}
+	return patterns, nil
+}
@@ -1,119 +1,290 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
+	"log-ingestion-service/internal/auth"
 	"log-ingestion-service/internal/batch"
+	"log-ingestion-service/internal/feature"
+	"log-ingestion-service/internal/ingest/logpb"
 	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/sources"
+	"log-ingestion-service/internal/storage"
 	"log-ingestion-service/internal/validator"
 	"log-ingestion-service/pkg/models"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
+// SourceHeader tags a request with the source of its logs, which may select a parser
+const SourceHeader = "X-Log-Source"
+
+// overloadRetryAfter is the wait suggested to clients when logs cannot be buffered for storage
+const overloadRetryAfter = 5 * time.Second
+
 // Handler handles HTTP requests
 type Handler struct {
 	parser    *parser.AutoParser
+	parsers   *parser.Registry
+	keys      *auth.KeyManager
 	validator *validator.Validator
 	batcher   *batch.Batcher
+	flags     *feature.Flags
+	sources   *sources.Tracker
+	paused    func() bool
+}
+
+// rawLogRequest defers decoding of a log entry so the parser's field mapping can be applied
+type rawLogRequest struct {
+	Log json.RawMessage `json:"log" binding:"required"`
+}
+
+// rawBatchLogRequest defers decoding of batch entries so the parser's field mapping can be applied
+type rawBatchLogRequest struct {
+	Logs []json.RawMessage `json:"logs"`
 }
 
 // NewHandler creates a new handler
-func NewHandler(batcher *batch.Batcher) *Handler {
+func NewHandler(batcher *batch.Batcher, parsers *parser.Registry, keys *auth.KeyManager, validator *validator.Validator, flags *feature.Flags, tracker *sources.Tracker) *Handler {
 	return &Handler{
-		parser:    parser.NewAutoParser(),
-		validator: validator.NewValidator(),
+		parser:    parsers.Auto(),
+		parsers:   parsers,
+		keys:      keys,
+		validator: validator,
 		batcher:   batcher,
+		flags:     flags,
+		sources:   tracker,
+	}
+}
+
+// apiKeyParsingKey caches how a request's API key is parsed, looked up once per request
+const apiKeyParsingKey = "api_key_parsing"
+
+// decodeLog decodes a log object with the configured field mapping, reading its timestamp with
+// the API key's formats when it has any. A log sent as a string is parsed with the parser
+// selected for the API key, the source header or auto-detection. When that is a pipeline, its
+// processors also run on log objects.
+func (h *Handler) decodeLog(c *gin.Context, raw json.RawMessage) (*models.LogEntry, error) {
+	p, _ := h.selectParser(c, "")
+	
+	var line string
+	if err := json.Unmarshal(raw, &line); err != nil {
+		logEntry, err := h.parser.DecodeJSONWith(raw, h.keyTimestamps(c))
+		if pipeline, ok := p.(*parser.Pipeline); ok && err == nil {
+			pipeline.Process(logEntry)
+		}
+		return logEntry, err
+	}
+	
+	return p.Parse([]byte(line))
+}
+
+// selectParser chooses the parser for a request from its API key, the listener it arrived on,
+// the source header and the content type
+func (h *Handler) selectParser(c *gin.Context, contentType string) (parser.Parser, string) {
+	sel := parser.Selection{
+		Source:      c.GetHeader(SourceHeader),
+		ContentType: contentType,
+	}
+	sel.Name = h.keyParsing(c).Parser
+	if sel.Name == "" {
+		sel.Name = c.GetString(listenerParserKey)
 	}
+	return h.parsers.Select(sel)
+}
+
+// keyParsing returns how logs sent with the request's API key are parsed
+func (h *Handler) keyParsing(c *gin.Context) storage.APIKeyParsing {
+	apiKey := c.GetString("api_key")
+	if apiKey == "" {
+		return storage.APIKeyParsing{}
+	}
+	if parsing, cached := c.Get(apiKeyParsingKey); cached {
+		return parsing.(storage.APIKeyParsing)
+	}
+	parsing := h.keys.KeyParsing(c.Request.Context(), apiKey)
+	c.Set(apiKeyParsingKey, parsing)
+	return parsing
+}
+
+// keyTimestamps returns the timestamp parser of the request's API key, or nil when the key has
+// no formats of its own. Formats that no longer compile are ignored.
+func (h *Handler) keyTimestamps(c *gin.Context) *parser.TimestampParser {
+	parsing := h.keyParsing(c)
+	if len(parsing.TimestampLayouts) == 0 && parsing.Timezone == "" {
+		return nil
+	}
+	timestamps, err := h.parsers.Timestamps(parsing.TimestampLayouts, parsing.Timezone)
+	if err != nil {
+		return nil
+	}
+	return timestamps
+}
+
+// recordSources starts counting a request's logs per source for GET /admin/sources
+func (h *Handler) recordSources(c *gin.Context) *sources.Recorder {
+	return h.sources.Begin(c.GetString("api_key"), "")
+}
+
+// rejectBody counts a request body that could not be decoded
+func (h *Handler) rejectBody(c *gin.Context, body []byte, err error) {
+	rec := h.recordSources(c)
+	rec.RejectBody(body, err)
+	rec.Flush()
+}
+
+// ListParsers returns the registered parsers and the content types and sources that select them,
+// and the compiled-in processors
+func (h *Handler) ListParsers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"parsers":    h.parsers.List(),
+		"processors": parser.ProcessorNames(),
+		"default":    parser.NameAuto,
+	})
 }
 
 // IngestLog handles single log ingestion
 func (h *Handler) IngestLog(c *gin.Context) {
-	var req models.LogRequest
+	var req rawLogRequest
 	
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
-			"details": err.Error(),
-		})
+	if err := c.ShouldBindBodyWith(&req, binding.JSON); err != nil {
+		if !isDryRun(c) {
+			body, _ := c.Get(gin.BodyBytesKey)
+			payload, _ := body.([]byte)
+			h.rejectBody(c, payload, err)
+		}
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	
+	if isDryRun(c) {
+		h.respondDryRun(c, []json.RawMessage{req.Log})
+		return
+	}
+	
+	rec := h.recordSources(c)
+	defer rec.Flush()
+	
+	logEntry, err := h.decodeLog(c, req.Log)
+	if err != nil {
+		rec.Reject("", req.Log, err)
+		problem.BadRequest(c, "Invalid log entry", err)
 		return
 	}
 	
 	// Validate
-	if err := h.validator.Validate(&req.Log); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Validation failed",
-			"details": err.Error(),
-		})
+	if err := h.validator.ValidateFrom(logEntry, c.GetString("api_key")); err != nil {
+		rec.Reject(logEntry.Service, req.Log, err)
+		problem.Respond(c, http.StatusBadRequest, problem.CodeValidationFailed, "Validation failed", err)
 		return
 	}
 	
 	// Sanitize
-	h.validator.Sanitize(&req.Log)
+	mods := h.validator.Sanitize(logEntry)
 	
 	// Add to batch
-	if err := h.batcher.Add(req.Log); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process log",
-			"details": err.Error(),
-		})
+	if err := h.batcher.Add(*logEntry); err != nil {
+		problem.RespondRetry(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "Failed to process log", err, overloadRetryAfter)
 		return
 	}
+	rec.Accept(logEntry.Service)
 	
-	c.JSON(http.StatusAccepted, gin.H{
+	response := gin.H{
 		"message": "Log accepted",
-	})
+	}
+	if mods.Modified() {
+		response["modified"] = mods
+	}
+	
+	c.JSON(http.StatusAccepted, response)
 }
 
-// IngestBatch handles batch log ingestion
+// IngestBatch handles batch log ingestion.
+// The body is decoded as a stream so large batches are never held as a slice of raw entries.
+// Bodies sent as application/x-protobuf are decoded as a protobuf LogBatch instead.
 func (h *Handler) IngestBatch(c *gin.Context) {
-	var req models.BatchLogRequest
-	
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid request body",
-			"details": err.Error(),
-		})
+	if c.ContentType() == logpb.ContentType {
+		h.ingestProtoBatch(c)
 		return
 	}
 	
-	if len(req.Logs) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Empty batch",
-		})
+	if isDryRun(c) {
+		var req rawBatchLogRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.BadRequest(c, "Invalid request body", err)
+			return
+		}
+		if len(req.Logs) == 0 {
+			problem.BadRequest(c, "Empty batch", nil)
+			return
+		}
+		h.respondDryRun(c, req.Logs)
 		return
 	}
 	
 	// Validate and sanitize all logs
-	validLogs := make([]models.LogEntry, 0, len(req.Logs))
+	validLogs := make([]models.LogEntry, 0, batchCapacityHint)
 	var validationErrors []string
+	modified := 0
+	rec := h.recordSources(c)
+	defer rec.Flush()
 	
-	for i, logEntry := range req.Logs {
-		if err := h.validator.Validate(&logEntry); err != nil {
+	total, err := decodeBatchStream(c.Request.Body, func(i int, raw json.RawMessage) {
+		logEntry, err := h.decodeLog(c, raw)
+		if err != nil {
+			rec.Reject("", raw, err)
+			validationErrors = append(validationErrors,
+				fmt.Sprintf("Log entry %d could not be parsed: %s", i, err.Error()))
+			return
+		}
+		
+		if err := h.validator.ValidateFrom(logEntry, c.GetString("api_key")); err != nil {
+			rec.Reject(logEntry.Service, raw, err)
 			validationErrors = append(validationErrors, 
 				fmt.Sprintf("Log entry %d validation failed: %s", i, err.Error()))
-			continue
+			return
 		}
 		
-		h.validator.Sanitize(&logEntry)
-		validLogs = append(validLogs, logEntry)
+		if h.validator.Sanitize(logEntry).Modified() {
+			modified++
+		}
+		validLogs = append(validLogs, *logEntry)
+	})
+	if err != nil {
+		// The body was read as a stream, so there is no payload to sample
+		rec.RejectBody(nil, err)
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	
+	if total == 0 {
+		problem.BadRequest(c, "Empty batch", nil)
+		return
 	}
 	
 	// Add valid logs to batch
 	if len(validLogs) > 0 {
 		if err := h.batcher.AddBatch(validLogs); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to process logs",
-				"details": err.Error(),
-			})
+			problem.RespondRetry(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "Failed to process logs", err, overloadRetryAfter)
 			return
 		}
+		for i := range validLogs {
+			rec.Accept(validLogs[i].Service)
+		}
 	}
 	
 	response := gin.H{
 		"message": "Batch processed",
 		"accepted": len(validLogs),
-		"total": len(req.Logs),
+		"total": total,
+	}
+	
+	if modified > 0 {
+		response["modified"] = modified
 	}
 	
 	if len(validationErrors) > 0 {
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"fmt"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/middleware"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobHandler serves the status and cancellation of background jobs. Jobs are visible to the
+// user who started them and to admins.
+type JobHandler struct {
+	repo         *storage.Repository
+	searchParser *parser.SearchParser
+}
+
+// NewJobHandler creates a job handler
+func NewJobHandler(repo *storage.Repository) *JobHandler {
+	return &JobHandler{repo: repo, searchParser: parser.NewSearchParser(nil)}
+}
+
+// SetupJobRoutes configures the background job status routes
+func SetupJobRoutes(router *gin.Engine, jobHandler *JobHandler, keyManager *auth.KeyManager, sessions *auth.SessionStore, cfg *config.Config) {
+	v1 := router.Group("/api/v1")
+	{
+		v1.Use(auth.CombinedAuth(keyManager, sessions))
+		v1.Use(middleware.RateLimit(&cfg.RateLimit))
+
+		v1.GET("/jobs", jobHandler.ListJobs)
+		v1.GET("/jobs/:id", jobHandler.GetJob)
+		v1.POST("/jobs/:id/cancel", jobHandler.CancelJob)
+	}
+}
+
+// ListJobs handles GET /api/v1/jobs, newest first. ?type= and ?status= filter; admins see every
+// user's jobs.
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	limit, offset, err := parsePagination(c, h.searchParser)
+	if err != nil {
+		problem.BadRequest(c, "Invalid pagination parameters", err)
+		return
+	}
+	filters := storage.JobFilters{Type: c.Query("type"), Status: c.Query("status"), Limit: limit, Offset: offset}
+	switch filters.Status {
+	case "", models.JobQueued, models.JobRunning, models.JobSucceeded, models.JobFailed, models.JobCancelled:
+	default:
+		problem.BadRequest(c, "Invalid status", fmt.Errorf("unknown job status %q", filters.Status))
+		return
+	}
+	if isAdmin, _ := c.Get("is_admin"); isAdmin != true {
+		userID, ok := currentUserID(c)
+		if !ok {
+			respondPage(c, "jobs", []models.Job{}, newPagination(limit, offset, 0, false, new(int64)), nil)
+			return
+		}
+		filters.CreatedBy = &userID
+	}
+
+	jobs, total, err := h.repo.ListJobs(c.Request.Context(), filters)
+	if err != nil {
+		problem.Internal(c, "Failed to list jobs", err)
+		return
+	}
+	hasMore := int64(offset+len(jobs)) < total
+	respondPage(c, "jobs", jobs, newPagination(limit, offset, len(jobs), hasMore, &total), nil)
+}
+
+// GetJob handles GET /api/v1/jobs/:id, a job's status, progress and, once finished, its result
+// or error
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelJob handles POST /api/v1/jobs/:id/cancel. A queued job is cancelled at once; a running
+// job is stopped by its worker shortly after, so 202 is returned with cancel_requested set.
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	job, ok := h.loadJob(c)
+	if !ok {
+		return
+	}
+	if job.Finished() {
+		problem.Respond(c, http.StatusConflict, problem.CodeConflict, "Job already finished",
+			fmt.Errorf("job is %s", job.Status))
+		return
+	}
+
+	job, err := h.repo.CancelJob(c.Request.Context(), job.ID)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Job not found", nil)
+			return
+		}
+		problem.Internal(c, "Failed to cancel job", err)
+		return
+	}
+	status := http.StatusAccepted
+	if job.Finished() {
+		status = http.StatusOK
+	}
+	c.JSON(status, job)
+}
+
+// loadJob reads the job named in the path, responding 404 when it does not exist or belongs to
+// another user
+func (h *JobHandler) loadJob(c *gin.Context) (*models.Job, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid job ID", nil)
+		return nil, false
+	}
+	job, err := h.repo.GetJob(c.Request.Context(), id)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Job not found", nil)
+			return nil, false
+		}
+		problem.Internal(c, "Failed to get job", err)
+		return nil, false
+	}
+	if isAdmin, _ := c.Get("is_admin"); isAdmin != true {
+		userID, ok := currentUserID(c)
+		if !ok || job.CreatedBy == nil || *job.CreatedBy != userID {
+			problem.NotFound(c, "Job not found", nil)
+			return nil, false
+		}
+	}
+	return job, true
+}
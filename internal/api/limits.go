@@ -0,0 +1,36 @@
+package api
+
+import (
+	"log-ingestion-service/internal/middleware"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits returns a handler for GET /api/v1/limits, which reports the caller's
+// rate limit and remaining requests so clients can pace themselves instead of hitting 429s
+func Limits(cfg *config.RateLimitConfig) gin.HandlerFunc {
+	limiter := middleware.SharedRateLimiter(cfg)
+
+	return func(c *gin.Context) {
+		status := limiter.Status(middleware.LimiterKey(c))
+
+		resetIn := time.Until(status.ResetAt).Seconds()
+		if resetIn < 0 {
+			resetIn = 0
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"rate_limit": gin.H{
+				"enabled":             status.Enabled,
+				"requests_per_second": status.RequestsPerSecond,
+				"burst":               status.Burst,
+				"remaining":           status.Remaining,
+				"reset_at":            status.ResetAt.UTC(),
+				"reset_in_seconds":    resetIn,
+			},
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/listener"
+	"log-ingestion-service/internal/preflight"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// listenerParserKey holds the default parser of the listener a request arrived on
+const listenerParserKey = "listener_parser"
+
+// listenerStopTimeout bounds how long stopping a listener waits for in-flight requests
+const listenerStopTimeout = 30 * time.Second
+
+// withListenerParser selects a parser for every request on a listener whose API key does not choose one
+func withListenerParser(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(listenerParserKey, name)
+		c.Next()
+	}
+}
+
+// SetupListenerRoutes configures readiness and listener management routes
+func SetupListenerRoutes(router *gin.Engine, listeners *listener.Manager, checks *preflight.Runner, sessions *auth.SessionStore, cfg *config.Config) {
+	router.GET("/readyz", Readyz(listeners, checks))
+
+	admin := router.Group("/admin/listeners")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("", ListListeners(listeners))
+		admin.POST("/:name/start", StartListener(listeners))
+		admin.POST("/:name/stop", StopListener(listeners))
+	}
+}
+
+// Readyz returns a handler for GET /readyz, which is 200 only once the preflight checks have
+// passed and every listener has started, and while none has failed. Listeners stopped from the
+// admin API do not count. Failed checks are listed with their reasons.
+func Readyz(listeners *listener.Manager, checks *preflight.Runner) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := http.StatusOK
+		preflightStatus := checks.Status()
+		ready := listeners.Ready() && preflightStatus.Passed
+		if !ready {
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, gin.H{
+			"ready":     ready,
+			"listeners": listeners.Statuses(),
+			"preflight": preflightStatus,
+		})
+	}
+}
+
+// ListListeners returns a handler for GET /admin/listeners
+func ListListeners(listeners *listener.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"listeners": listeners.Statuses()})
+	}
+}
+
+// StartListener returns a handler for POST /admin/listeners/:name/start
+func StartListener(listeners *listener.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		if err := listeners.Start(c.Param("name")); err != nil {
+			respondListenerError(c, "Failed to start listener", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"listeners": listeners.Statuses()})
+	}
+}
+
+// StopListener returns a handler for POST /admin/listeners/:name/stop.
+// The main API listener cannot be stopped this way since it serves this endpoint.
+func StopListener(listeners *listener.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		name := c.Param("name")
+		if name == config.MainListener {
+			problem.RespondDetail(c, http.StatusConflict, problem.CodeConflict, "Cannot stop the main listener",
+				"the main API listener serves the admin API; stop the process instead")
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), listenerStopTimeout)
+		defer cancel()
+		if err := listeners.Stop(ctx, name); err != nil {
+			respondListenerError(c, "Failed to stop listener", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"listeners": listeners.Statuses()})
+	}
+}
+
+func respondListenerError(c *gin.Context, title string, err error) {
+	if errors.Is(err, listener.ErrUnknownListener) {
+		problem.NotFound(c, "Listener not found", err)
+		return
+	}
+	problem.Internal(c, title, err)
+}
+
+// requireAdmin responds with 403 unless the request carries an admin session
+func requireAdmin(c *gin.Context) bool {
+	if isAdmin, _ := c.Get("is_admin"); isAdmin != true {
+		problem.Respond(c, http.StatusForbidden, problem.CodeForbidden, "Admin access required", nil)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/logschema"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createLogSchemaRequest registers a schema for a service or an API key
+type createLogSchemaRequest struct {
+	Service     *string         `json:"service"`
+	APIKeyID    *int64          `json:"api_key_id"`
+	Schema      json.RawMessage `json:"schema" binding:"required"`
+	Mode        string          `json:"mode"`
+	Description string          `json:"description"`
+}
+
+// updateLogSchemaRequest changes a schema; omitted fields are kept
+type updateLogSchemaRequest struct {
+	Schema      json.RawMessage `json:"schema"`
+	Mode        *string         `json:"mode"`
+	Description *string         `json:"description"`
+}
+
+// SetupLogSchemaRoutes configures log schema management routes
+func SetupLogSchemaRoutes(router *gin.Engine, repo *storage.Repository, registry *logschema.Registry, sessions *auth.SessionStore, cfg *config.Config) {
+	admin := router.Group("/admin/log-schemas")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("", ListLogSchemas(repo))
+		admin.POST("", CreateLogSchema(repo, registry))
+		admin.PUT("/:id", UpdateLogSchema(repo, registry))
+		admin.DELETE("/:id", DeleteLogSchema(repo, registry))
+	}
+}
+
+// ListLogSchemas returns a handler for GET /admin/log-schemas
+func ListLogSchemas(repo *storage.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		schemas, err := repo.ListLogSchemas(c.Request.Context())
+		if err != nil {
+			problem.Internal(c, "Failed to list log schemas", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"log_schemas": schemas})
+	}
+}
+
+// CreateLogSchema returns a handler for POST /admin/log-schemas. Schemas that do not compile
+// are rejected.
+func CreateLogSchema(repo *storage.Repository, registry *logschema.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		var req createLogSchemaRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.BadRequest(c, "Invalid request body", err)
+			return
+		}
+
+		schema := &models.LogSchema{
+			Service:     req.Service,
+			APIKeyID:    req.APIKeyID,
+			Schema:      req.Schema,
+			Mode:        strings.ToLower(req.Mode),
+			Description: strings.TrimSpace(req.Description),
+			CreatedBy:   actorID(c),
+		}
+		if schema.Mode == "" {
+			schema.Mode = models.SchemaReject
+		}
+		if err := logschema.Check(schema); err != nil {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid log schema", err)
+			return
+		}
+
+		ctx := c.Request.Context()
+		if err := repo.CreateLogSchema(ctx, schema); err != nil {
+			switch {
+			case errors.Is(err, storage.ErrLogSchemaExists):
+				problem.Respond(c, http.StatusConflict, problem.CodeConflict, "Log schema already exists", err)
+			case storage.IsNotFound(err):
+				problem.NotFound(c, "API key not found", err)
+			default:
+				problem.Internal(c, "Failed to create log schema", err)
+			}
+			return
+		}
+		if err := registry.Reload(ctx); err != nil {
+			log.Printf("WARN: Failed to reload log schemas: %v", err)
+		}
+		log.Printf("INFO: Log schema %d registered (%s mode)", schema.ID, schema.Mode)
+		c.JSON(http.StatusCreated, schema)
+	}
+}
+
+// UpdateLogSchema returns a handler for PUT /admin/log-schemas/:id
+func UpdateLogSchema(repo *storage.Repository, registry *logschema.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			problem.BadRequest(c, "Invalid log schema ID", nil)
+			return
+		}
+		var req updateLogSchemaRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.BadRequest(c, "Invalid request body", err)
+			return
+		}
+
+		ctx := c.Request.Context()
+		schema, err := repo.GetLogSchema(ctx, id)
+		if err != nil {
+			if storage.IsNotFound(err) {
+				problem.NotFound(c, "Log schema not found", err)
+				return
+			}
+			problem.Internal(c, "Failed to get log schema", err)
+			return
+		}
+		if req.Schema != nil {
+			schema.Schema = req.Schema
+		}
+		if req.Mode != nil {
+			schema.Mode = strings.ToLower(*req.Mode)
+		}
+		if req.Description != nil {
+			schema.Description = strings.TrimSpace(*req.Description)
+		}
+		if err := logschema.Check(schema); err != nil {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid log schema", err)
+			return
+		}
+
+		if err := repo.UpdateLogSchema(ctx, schema); err != nil {
+			if storage.IsNotFound(err) {
+				problem.NotFound(c, "Log schema not found", err)
+				return
+			}
+			problem.Internal(c, "Failed to update log schema", err)
+			return
+		}
+		if err := registry.Reload(ctx); err != nil {
+			log.Printf("WARN: Failed to reload log schemas: %v", err)
+		}
+		log.Printf("INFO: Log schema %d updated (%s mode)", schema.ID, schema.Mode)
+		c.JSON(http.StatusOK, schema)
+	}
+}
+
+// DeleteLogSchema returns a handler for DELETE /admin/log-schemas/:id
+func DeleteLogSchema(repo *storage.Repository, registry *logschema.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			problem.BadRequest(c, "Invalid log schema ID", nil)
+			return
+		}
+
+		ctx := c.Request.Context()
+		if err := repo.DeleteLogSchema(ctx, id); err != nil {
+			if storage.IsNotFound(err) {
+				problem.NotFound(c, "Log schema not found", err)
+				return
+			}
+			problem.Internal(c, "Failed to delete log schema", err)
+			return
+		}
+		if err := registry.Reload(ctx); err != nil {
+			log.Printf("WARN: Failed to reload log schemas: %v", err)
+		}
+		log.Printf("INFO: Log schema %d deleted", id)
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"fmt"
+	"log-ingestion-service/internal/fault"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateMergeRuleRequest represents the request to create an automatic merge rule
+type CreateMergeRuleRequest struct {
+	ProjectID      *int64  `json:"project_id"`
+	Name           string  `json:"name" binding:"required"`
+	ErrorClass     *string `json:"error_class"`
+	MessagePattern *string `json:"message_pattern"`
+	Environment    *string `json:"environment"`
+	TargetFaultID  int64   `json:"target_fault_id" binding:"required"`
+	Enabled        *bool   `json:"enabled"`
+}
+
+// ListMergeRules handles GET /api/v1/merge-rules
+func (h *FaultHandler) ListMergeRules(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	var projectID *int64
+	if value := c.Query("project_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			problem.BadRequest(c, "Invalid project ID", nil)
+			return
+		}
+		projectID = &id
+	}
+	
+	rules, err := h.repo.ListMergeRules(ctx, projectID, false)
+	if err != nil {
+		problem.Internal(c, "Failed to list merge rules", err)
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"merge_rules": rules,
+	})
+}
+
+// CreateMergeRule handles POST /api/v1/merge-rules
+func (h *FaultHandler) CreateMergeRule(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	var req CreateMergeRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	
+	req.ErrorClass = trimOptional(req.ErrorClass)
+	req.MessagePattern = trimOptional(req.MessagePattern)
+	req.Environment = trimOptional(req.Environment)
+	
+	if req.ErrorClass == nil && req.MessagePattern == nil {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid merge rule",
+			fmt.Errorf("at least one of error_class or message_pattern is required"))
+		return
+	}
+	if req.MessagePattern != nil {
+		if _, err := fault.CompileMergePattern(*req.MessagePattern); err != nil {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid merge rule", err)
+			return
+		}
+	}
+	
+	if _, err := h.repo.GetFault(ctx, req.TargetFaultID); err != nil {
+		if storage.IsNotFound(err) {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid merge rule",
+				fmt.Errorf("target fault %d does not exist", req.TargetFaultID))
+			return
+		}
+		problem.Internal(c, "Failed to get target fault", err)
+		return
+	}
+	
+	rule := &models.MergeRule{
+		ProjectID:      req.ProjectID,
+		Name:           strings.TrimSpace(req.Name),
+		ErrorClass:     req.ErrorClass,
+		MessagePattern: req.MessagePattern,
+		Environment:    req.Environment,
+		TargetFaultID:  req.TargetFaultID,
+		Enabled:        req.Enabled == nil || *req.Enabled,
+		CreatedBy:      actorID(c),
+	}
+	
+	if err := h.repo.CreateMergeRule(ctx, rule); err != nil {
+		problem.Internal(c, "Failed to create merge rule", err)
+		return
+	}
+	h.grouper.MergeRules().Invalidate()
+	
+	c.JSON(http.StatusCreated, rule)
+}
+
+// DeleteMergeRule handles DELETE /api/v1/merge-rules/:id
+func (h *FaultHandler) DeleteMergeRule(c *gin.Context) {
+	ctx := c.Request.Context()
+	
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid merge rule ID", nil)
+		return
+	}
+	
+	if err := h.repo.DeleteMergeRule(ctx, id); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Merge rule not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to delete merge rule", err)
+		return
+	}
+	h.grouper.MergeRules().Invalidate()
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Merge rule deleted successfully",
+	})
+}
+
+// trimOptional trims an optional string, treating blank values as unset
+func trimOptional(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*s)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
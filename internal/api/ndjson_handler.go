@@ -0,0 +1,149 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxNDJSONBodyBytes caps the size of an NDJSON request as sent. Decompressed bodies are not
+// capped since they are never held in memory.
+const maxNDJSONBodyBytes = 512 << 20
+
+// IngestNDJSON handles POST /api/v1/logs/ndjson.
+// The body holds one JSON log per line and is read as a stream: each line is decoded, validated
+// and sanitized as it arrives, and valid logs are handed to the batcher in chunks, so only a
+// chunk is held in memory however large the upload. A line holding a JSON string is parsed like
+// a string log in a batch. Bodies may be gzip-compressed.
+// If logs cannot be buffered partway through, the response says how many were accepted.
+func (h *Handler) IngestNDJSON(c *gin.Context) {
+	var body io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, maxNDJSONBodyBytes)
+	switch encoding := c.GetHeader("Content-Encoding"); encoding {
+	case "", "identity":
+	case "gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			problem.BadRequest(c, "Invalid gzip body", err)
+			return
+		}
+		defer zr.Close()
+		body = zr
+	default:
+		problem.Respond(c, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "Unsupported content encoding",
+			fmt.Errorf("unsupported content encoding %q", encoding))
+		return
+	}
+
+	rec := h.recordSources(c)
+	defer rec.Flush()
+
+	chunk := make([]models.LogEntry, 0, batchCapacityHint)
+	var lineErrors []string
+	accepted, rejected, modified, total := 0, 0, 0, 0
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		if err := h.batcher.AddBatch(chunk); err != nil {
+			return err
+		}
+		for i := range chunk {
+			rec.Accept(chunk[i].Service)
+		}
+		accepted += len(chunk)
+		chunk = chunk[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRawLineBytes)
+	lineNumber, chunkStart := 0, 0
+	for scanner.Scan() {
+		lineNumber++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		total++
+
+		entry, err := h.decodeLog(c, line)
+		service := ""
+		if err == nil {
+			service = entry.Service
+			err = h.validator.ValidateFrom(entry, c.GetString("api_key"))
+		}
+		if err != nil {
+			rec.Reject(service, line, err)
+			rejected++
+			if len(lineErrors) < rawErrorLimit {
+				lineErrors = append(lineErrors, fmt.Sprintf("Line %d: %s", lineNumber, err.Error()))
+			}
+			continue
+		}
+
+		if h.validator.Sanitize(entry).Modified() {
+			modified++
+		}
+		if len(chunk) == 0 {
+			chunkStart = lineNumber
+		}
+		chunk = append(chunk, *entry)
+		if len(chunk) == cap(chunk) {
+			if err := flush(); err != nil {
+				h.respondNDJSONOverload(c, err, accepted, chunkStart)
+				return
+			}
+		}
+	}
+	// Logs read before a failure are kept, so the client can resume after them
+	if err := flush(); err != nil {
+		h.respondNDJSONOverload(c, err, accepted, chunkStart)
+		return
+	}
+	if err := scanner.Err(); err != nil {
+		err = fmt.Errorf("%w; %d logs were accepted, resume from line %d", err, accepted, lineNumber+1)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			problem.Respond(c, http.StatusRequestEntityTooLarge, problem.CodeInvalidRequest, "Request body too large", err)
+			return
+		}
+		rec.RejectBody(nil, err)
+		problem.BadRequest(c, "Failed to read log lines", err)
+		return
+	}
+
+	if total == 0 {
+		problem.BadRequest(c, "Empty request body", nil)
+		return
+	}
+
+	response := gin.H{
+		"message":  "NDJSON logs processed",
+		"accepted": accepted,
+		"total":    total,
+	}
+	if modified > 0 {
+		response["modified"] = modified
+	}
+	if rejected > 0 {
+		response["errors"] = lineErrors
+		response["rejected"] = rejected
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+// respondNDJSONOverload answers 503 when a chunk could not be buffered, noting how many logs
+// were already accepted so a client can resume rather than resend them
+func (h *Handler) respondNDJSONOverload(c *gin.Context, err error, accepted, resumeLine int) {
+	problem.RespondRetry(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "Failed to process logs",
+		fmt.Errorf("%w; %d logs were accepted, resume from line %d", err, accepted, resumeLine), overloadRetryAfter)
+}
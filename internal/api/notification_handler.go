@@ -0,0 +1,91 @@
+package api
+
+import (
+	"fmt"
+	"log-ingestion-service/internal/notify"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// testNotificationRequest optionally overrides the message of a synthetic event
+type testNotificationRequest struct {
+	Message string `json:"message"`
+}
+
+// TestNotification handles POST /admin/test-notification.
+// A synthetic event is sent through every configured notifier and the delivery results are returned.
+func (h *AdminHandler) TestNotification(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var req testNotificationRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.BadRequest(c, "Invalid request body", err)
+			return
+		}
+	}
+	if req.Message == "" {
+		req.Message = "Test notification"
+	}
+
+	results := h.notifier.Dispatch(c.Request.Context(), notify.Event{
+		Type:    notify.EventTest,
+		Test:    true,
+		Message: req.Message,
+	})
+
+	respondNotificationResults(c, notify.EventTest, results)
+}
+
+// TestAlert handles POST /api/v1/faults/:id/test-alert.
+// The fault is sent as a new-fault alert, marked as a test, so integrations see a real payload.
+func (h *FaultHandler) TestAlert(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid fault ID", nil)
+		return
+	}
+
+	fault, err := h.repo.GetFault(ctx, id)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Fault not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to get fault", err)
+		return
+	}
+
+	results := h.notifier.Dispatch(ctx, notify.Event{
+		Type:    notify.EventFaultCreated,
+		Test:    true,
+		Message: fmt.Sprintf("Test alert: %s: %s", fault.ErrorClass, fault.Message),
+		Fault:   fault,
+	})
+
+	respondNotificationResults(c, notify.EventFaultCreated, results)
+}
+
+// respondNotificationResults reports per-notifier delivery outcomes
+func respondNotificationResults(c *gin.Context, eventType string, results []notify.Result) {
+	delivered := 0
+	for _, r := range results {
+		if r.Delivered {
+			delivered++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"event":     eventType,
+		"delivered": delivered,
+		"failed":    len(results) - delivered,
+		"results":   results,
+	})
+}
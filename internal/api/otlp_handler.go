@@ -0,0 +1,199 @@
+package api
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log-ingestion-service/internal/ingest/otlp"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/sources"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxOTLPBodyBytes caps an OTLP export request after decompression
+const maxOTLPBodyBytes = maxRawBodyBytes
+
+// IngestOTLP handles OTLP/HTTP log exports (POST /v1/logs on an otlp listener, or
+// POST /api/v1/otlp/v1/logs). Bodies are protobuf or JSON, optionally gzip compressed, and the
+// response is an ExportLogsServiceResponse in the same encoding.
+func (h *Handler) IngestOTLP(c *gin.Context) {
+	var decode func([]byte) (*otlp.ExportLogsServiceRequest, error)
+	switch c.ContentType() {
+	case "application/x-protobuf":
+		decode = otlp.DecodeProto
+	case "application/json":
+		decode = otlp.DecodeJSON
+	default:
+		problem.Respond(c, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "Unsupported content type",
+			fmt.Errorf("content type %q is not application/x-protobuf or application/json", c.ContentType()))
+		return
+	}
+
+	body, err := readEncodedBody(c)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || errors.Is(err, errBodyTooLarge) {
+			problem.Respond(c, http.StatusRequestEntityTooLarge, problem.CodeInvalidRequest, "Request body too large", err)
+			return
+		}
+		problem.BadRequest(c, "Failed to read request body", err)
+		return
+	}
+
+	req, err := decode(body)
+	if err != nil {
+		h.rejectBody(c, body, err)
+		problem.BadRequest(c, "Invalid OTLP payload", err)
+		return
+	}
+
+	rec := h.recordSources(c)
+	defer rec.Flush()
+	rejected, message, err := h.acceptOTLP(rec, req, c.GetString("api_key"))
+	if err != nil {
+		problem.RespondRetry(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "Failed to process logs", err, overloadRetryAfter)
+		return
+	}
+
+	if c.ContentType() == "application/x-protobuf" {
+		c.Data(http.StatusOK, "application/x-protobuf", otlp.EncodeResponse(rejected, message))
+		return
+	}
+	response := gin.H{}
+	if rejected > 0 {
+		response["partialSuccess"] = gin.H{
+			"rejectedLogRecords": strconv.FormatInt(rejected, 10),
+			"errorMessage":       message,
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportOTLPGRPC handles the unary gRPC LogsService/Export method of OTLP/gRPC. Errors are
+// reported as gRPC statuses in a trailers-only response.
+func (h *Handler) ExportOTLPGRPC(c *gin.Context) {
+	if !strings.HasPrefix(c.ContentType(), "application/grpc") {
+		problem.Respond(c, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "Unsupported content type",
+			fmt.Errorf("content type %q is not application/grpc", c.ContentType()))
+		return
+	}
+
+	msg, err := otlp.ReadGRPCMessage(c.Request.Body, c.GetHeader("Grpc-Encoding"), maxOTLPBodyBytes)
+	if err != nil {
+		grpcError(c, otlp.GRPCInvalidArgument, err)
+		return
+	}
+	req, err := otlp.DecodeProto(msg)
+	if err != nil {
+		h.rejectBody(c, msg, err)
+		grpcError(c, otlp.GRPCInvalidArgument, err)
+		return
+	}
+
+	rec := h.recordSources(c)
+	defer rec.Flush()
+	rejected, message, err := h.acceptOTLP(rec, req, c.GetString("api_key"))
+	if err != nil {
+		grpcError(c, otlp.GRPCUnavailable, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/grpc")
+	c.Status(http.StatusOK)
+	c.Writer.Write(otlp.FrameGRPCMessage(otlp.EncodeResponse(rejected, message)))
+	c.Writer.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(otlp.GRPCOK))
+}
+
+// acceptOTLP validates and enqueues the records of an export request sent with apiKey. It
+// returns the number of rejected records with a message describing the first rejection; err is
+// set when the valid records could not be buffered, in which case the client should retry the
+// whole request.
+func (h *Handler) acceptOTLP(rec *sources.Recorder, req *otlp.ExportLogsServiceRequest, apiKey string) (int64, string, error) {
+	entries := otlp.Convert(req, time.Now().UTC())
+
+	validLogs := make([]models.LogEntry, 0, len(entries))
+	var rejected int64
+	var firstError string
+	for i := range entries {
+		entry := &entries[i]
+		if err := h.validator.ValidateFrom(entry, apiKey); err != nil {
+			rec.Reject(entry.Service, protoRecordPayload(entry), err)
+			if rejected == 0 {
+				firstError = fmt.Sprintf("log record %d: %s", i, err.Error())
+			}
+			rejected++
+			continue
+		}
+		h.validator.Sanitize(entry)
+		validLogs = append(validLogs, *entry)
+	}
+
+	if len(validLogs) > 0 {
+		if err := h.batcher.AddBatch(validLogs); err != nil {
+			return 0, "", err
+		}
+		for i := range validLogs {
+			rec.Accept(validLogs[i].Service)
+		}
+	}
+	if rejected == 0 {
+		return 0, "", nil
+	}
+	return rejected, fmt.Sprintf("%d of %d log records rejected (%s)", rejected, len(entries), firstError), nil
+}
+
+// protoRecordPayload stands in for a rejected record with its converted JSON form, since records
+// are not delimited in a protobuf body
+func protoRecordPayload(entry *models.LogEntry) []byte {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return []byte(entry.Message)
+	}
+	return data
+}
+
+// errBodyTooLarge is returned when a compressed body expands beyond maxOTLPBodyBytes
+var errBodyTooLarge = fmt.Errorf("request body exceeds %d bytes after decompression", maxOTLPBodyBytes)
+
+// readEncodedBody reads a binary request body, decompressing it when Content-Encoding is gzip
+func readEncodedBody(c *gin.Context) ([]byte, error) {
+	var body io.Reader = http.MaxBytesReader(c.Writer, c.Request.Body, maxOTLPBodyBytes)
+	switch encoding := c.GetHeader("Content-Encoding"); encoding {
+	case "", "identity":
+	case "gzip":
+		zr, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		body = io.LimitReader(zr, maxOTLPBodyBytes+1)
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxOTLPBodyBytes {
+		return nil, errBodyTooLarge
+	}
+	return data, nil
+}
+
+// grpcError ends a gRPC call with a non-OK status
+func grpcError(c *gin.Context, code int, err error) {
+	c.Header("Content-Type", "application/grpc")
+	c.Header("Grpc-Status", strconv.Itoa(code))
+	c.Header("Grpc-Message", url.PathEscape(err.Error()))
+	c.AbortWithStatus(http.StatusOK)
+}
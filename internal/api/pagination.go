@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"log-ingestion-service/internal/parser"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Pagination describes the position of a list response within the full result set
+type Pagination struct {
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	Total      *int64 `json:"total,omitempty"`
+	HasMore    bool   `json:"has_more"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// paginationRequested reports whether the request asks for a page. Endpoints that returned
+// everything before they were paginated still do when it does not.
+func paginationRequested(c *gin.Context) bool {
+	return c.Query("limit") != "" || c.Query("offset") != "" || c.Query("cursor") != ""
+}
+
+// parsePagination reads limit and offset from the query string.
+// An opaque ?cursor= (as returned in next_cursor) takes precedence over ?offset=.
+func parsePagination(c *gin.Context, searchParser *parser.SearchParser) (int, int, error) {
+	limit, offset, err := searchParser.ParseLimitOffset(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		offset, err = decodeCursor(cursor)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return limit, offset, nil
+}
+
+// newPagination builds pagination metadata. total may be nil when counting is expensive.
+func newPagination(limit, offset, returned int, hasMore bool, total *int64) Pagination {
+	p := Pagination{
+		Limit:   limit,
+		Offset:  offset,
+		Total:   total,
+		HasMore: hasMore,
+	}
+	if hasMore {
+		p.NextCursor = encodeCursor(offset + returned)
+	}
+	return p
+}
+
+// trimLookahead drops the extra row fetched to detect whether another page exists
+func trimLookahead[T any](items []T, limit int) ([]T, bool) {
+	if len(items) > limit {
+		return items[:limit], true
+	}
+	return items, false
+}
+
+// setLinkHeader writes RFC 5988 Link relations for the current page
+func setLinkHeader(c *gin.Context, p Pagination) {
+	var links []string
+
+	links = append(links, formatLink(c, p.Limit, 0, "first"))
+	if p.Offset > 0 {
+		prev := p.Offset - p.Limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, formatLink(c, p.Limit, prev, "prev"))
+	}
+	if p.HasMore {
+		links = append(links, formatLink(c, p.Limit, p.Offset+p.Limit, "next"))
+	}
+	if p.Total != nil && *p.Total > 0 {
+		last := int((*p.Total - 1) / int64(p.Limit) * int64(p.Limit))
+		links = append(links, formatLink(c, p.Limit, last, "last"))
+	}
+
+	c.Header("Link", strings.Join(links, ", "))
+}
+
+// respondPage writes a list response with pagination metadata and Link headers.
+// extra holds endpoint-specific top-level fields kept for backward compatibility.
+func respondPage(c *gin.Context, key string, items interface{}, p Pagination, extra gin.H) {
+	setLinkHeader(c, p)
+
+	body := gin.H{
+		key:          items,
+		"pagination": p,
+	}
+	for k, v := range extra {
+		body[k] = v
+	}
+
+	c.JSON(http.StatusOK, body)
+}
+
+func formatLink(c *gin.Context, limit, offset int, rel string) string {
+	u := *c.Request.URL
+	q := u.Query()
+	q.Del("offset")
+	q.Set("limit", strconv.Itoa(limit))
+	if offset > 0 {
+		q.Set("cursor", encodeCursor(offset))
+	} else {
+		q.Del("cursor")
+	}
+	u.RawQuery = q.Encode()
+	return fmt.Sprintf("<%s>; rel=\"%s\"", u.RequestURI(), rel)
+}
+
+func encodeCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("o:" + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(raw), "o:") {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(raw), "o:"))
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return offset, nil
+}
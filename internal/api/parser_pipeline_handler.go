@@ -0,0 +1,131 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setParserPipelineRequest defines or replaces a parser pipeline
+type setParserPipelineRequest struct {
+	Sources []string              `json:"sources"`
+	Steps   []models.PipelineStep `json:"steps" binding:"required"`
+}
+
+// SetupParserPipelineRoutes configures parser pipeline management routes
+func SetupParserPipelineRoutes(router *gin.Engine, repo *storage.Repository, parsers *parser.Registry, pipelineSync *parser.PipelineSync, sessions *auth.SessionStore, cfg *config.Config) {
+	admin := router.Group("/admin/parsers/pipelines")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("", ListParserPipelines(repo))
+		admin.PUT("/:name", SetParserPipeline(repo, parsers, pipelineSync))
+		admin.DELETE("/:name", DeleteParserPipeline(repo, pipelineSync))
+	}
+}
+
+// ListParserPipelines returns a handler for GET /admin/parsers/pipelines
+func ListParserPipelines(repo *storage.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		pipelines, err := repo.ListParserPipelines(c.Request.Context())
+		if err != nil {
+			problem.Internal(c, "Failed to list parser pipelines", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"pipelines": pipelines})
+	}
+}
+
+// SetParserPipeline returns a handler for PUT /admin/parsers/pipelines/:name.
+// The change is rejected unless every pipeline compiles with it.
+func SetParserPipeline(repo *storage.Repository, parsers *parser.Registry, pipelineSync *parser.PipelineSync) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		var req setParserPipelineRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.BadRequest(c, "Invalid request body", err)
+			return
+		}
+
+		ctx := c.Request.Context()
+		pipeline := models.ParserPipeline{Name: c.Param("name"), Sources: req.Sources, Steps: req.Steps, UpdatedBy: actorID(c)}
+		if pipeline.Sources == nil {
+			pipeline.Sources = []string{}
+		}
+		pipelines, err := repo.ListParserPipelines(ctx)
+		if err != nil {
+			problem.Internal(c, "Failed to load parser pipelines", err)
+			return
+		}
+		pipelines = replacePipeline(pipelines, pipeline)
+		if err := parsers.CheckPipelines(pipelines); err != nil {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid parser pipeline", err)
+			return
+		}
+
+		if err := repo.SetParserPipeline(ctx, &pipeline); err != nil {
+			problem.Internal(c, "Failed to set parser pipeline", err)
+			return
+		}
+		pipelineSync.Reload(ctx)
+		log.Printf("INFO: Parser pipeline %s set", pipeline.Name)
+		c.JSON(http.StatusOK, pipeline)
+	}
+}
+
+// DeleteParserPipeline returns a handler for DELETE /admin/parsers/pipelines/:name.
+// Pipelines that API keys are set to use cannot be deleted.
+func DeleteParserPipeline(repo *storage.Repository, pipelineSync *parser.PipelineSync) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+
+		ctx := c.Request.Context()
+		name := c.Param("name")
+		keys, err := repo.CountAPIKeysWithParser(ctx, name)
+		if err != nil {
+			problem.Internal(c, "Failed to check API keys", err)
+			return
+		}
+		if keys > 0 {
+			problem.Respond(c, http.StatusConflict, problem.CodeConflict, "Parser pipeline is in use",
+				fmt.Errorf("%d API keys use pipeline %s; set their parser to another one first", keys, name))
+			return
+		}
+
+		if err := repo.DeleteParserPipeline(ctx, name); err != nil {
+			if storage.IsNotFound(err) {
+				problem.NotFound(c, "Parser pipeline not found", err)
+				return
+			}
+			problem.Internal(c, "Failed to delete parser pipeline", err)
+			return
+		}
+		pipelineSync.Reload(ctx)
+		log.Printf("INFO: Parser pipeline %s deleted", name)
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// replacePipeline returns pipelines with the one of the same name replaced by pipeline, or
+// pipeline added when there is none
+func replacePipeline(pipelines []models.ParserPipeline, pipeline models.ParserPipeline) []models.ParserPipeline {
+	replaced := make([]models.ParserPipeline, 0, len(pipelines)+1)
+	for _, p := range pipelines {
+		if p.Name != pipeline.Name {
+			replaced = append(replaced, p)
+		}
+	}
+	return append(replaced, pipeline)
+}
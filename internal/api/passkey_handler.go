@@ -0,0 +1,330 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/internal/webauthn"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxPasskeyNameLength bounds passkey names
+const maxPasskeyNameLength = 100
+
+// defaultPasskeyName names passkeys registered without a name
+const defaultPasskeyName = "Passkey"
+
+// PasskeyHandler handles passkey (WebAuthn) registration, login and credential management
+type PasskeyHandler struct {
+	repository *storage.Repository
+	rp         *webauthn.RelyingParty
+	sessions   *auth.SessionStore
+}
+
+// NewPasskeyHandler creates a new passkey handler. Passkeys are unavailable unless a relying
+// party ID is configured.
+func NewPasskeyHandler(repository *storage.Repository, sessions *auth.SessionStore, cfg *config.AuthConfig) *PasskeyHandler {
+	return &PasskeyHandler{
+		repository: repository,
+		rp:         webauthn.New(&cfg.WebAuthn, repository),
+		sessions:   sessions,
+	}
+}
+
+// FinishPasskeyRegistrationRequest completes a passkey registration
+type FinishPasskeyRegistrationRequest struct {
+	Session    string                         `json:"session" binding:"required"`
+	Name       string                         `json:"name"`
+	Credential *webauthn.RegistrationResponse `json:"credential" binding:"required"`
+}
+
+// BeginPasskeyLoginRequest optionally names the account logging in
+type BeginPasskeyLoginRequest struct {
+	Email string `json:"email"`
+}
+
+// FinishPasskeyLoginRequest completes a passkey login
+type FinishPasskeyLoginRequest struct {
+	Session    string                      `json:"session" binding:"required"`
+	Credential *webauthn.AssertionResponse `json:"credential" binding:"required"`
+}
+
+// RenamePasskeyRequest renames a passkey
+type RenamePasskeyRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// ListPasskeys handles GET /api/v1/me/passkeys
+func (h *PasskeyHandler) ListPasskeys(c *gin.Context) {
+	userID, ok := h.requireUser(c)
+	if !ok {
+		return
+	}
+
+	creds, err := h.repository.ListWebAuthnCredentials(c.Request.Context(), userID)
+	if err != nil {
+		problem.Internal(c, "Failed to list passkeys", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"passkeys": creds,
+	})
+}
+
+// BeginPasskeyRegistration handles POST /api/v1/me/passkeys/register/begin
+func (h *PasskeyHandler) BeginPasskeyRegistration(c *gin.Context) {
+	userID, ok := h.requireUser(c)
+	if !ok || !h.requireEnabled(c) {
+		return
+	}
+	ctx := c.Request.Context()
+
+	profile, err := h.repository.GetUserProfile(ctx, userID)
+	if err != nil {
+		problem.Internal(c, "Failed to get profile", err)
+		return
+	}
+	existing, err := h.repository.ListWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		problem.Internal(c, "Failed to list passkeys", err)
+		return
+	}
+
+	user := webauthn.User{ID: userID, Name: profile.Email, DisplayName: profile.Name}
+	options, session, err := h.rp.BeginRegistration(ctx, user, existing)
+	if err != nil {
+		problem.Internal(c, "Failed to start passkey registration", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session": session,
+		"options": options,
+	})
+}
+
+// FinishPasskeyRegistration handles POST /api/v1/me/passkeys/register/finish
+func (h *PasskeyHandler) FinishPasskeyRegistration(c *gin.Context) {
+	userID, ok := h.requireUser(c)
+	if !ok || !h.requireEnabled(c) {
+		return
+	}
+
+	var req FinishPasskeyRegistrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	name, ok := passkeyName(c, req.Name)
+	if !ok {
+		return
+	}
+
+	cred, err := h.rp.FinishRegistration(c.Request.Context(), req.Session, userID, req.Credential)
+	if err != nil {
+		if errors.Is(err, webauthn.ErrVerification) {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Passkey registration failed", err)
+			return
+		}
+		problem.Internal(c, "Failed to register passkey", err)
+		return
+	}
+	cred.Name = name
+
+	if err := h.repository.CreateWebAuthnCredential(c.Request.Context(), cred); err != nil {
+		if errors.Is(err, storage.ErrCredentialExists) {
+			problem.Respond(c, http.StatusConflict, problem.CodeConflict, "Passkey already registered", err)
+			return
+		}
+		problem.Internal(c, "Failed to save passkey", err)
+		return
+	}
+
+	log.Printf("INFO: User %d registered passkey %d", userID, cred.ID)
+	c.JSON(http.StatusCreated, cred)
+}
+
+// RenamePasskey handles PATCH /api/v1/me/passkeys/:id
+func (h *PasskeyHandler) RenamePasskey(c *gin.Context) {
+	userID, ok := h.requireUser(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid passkey ID", nil)
+		return
+	}
+
+	var req RenamePasskeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	name, ok := passkeyName(c, req.Name)
+	if !ok {
+		return
+	}
+
+	cred, err := h.repository.RenameWebAuthnCredential(c.Request.Context(), userID, id, name)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Passkey not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to rename passkey", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cred)
+}
+
+// DeletePasskey handles DELETE /api/v1/me/passkeys/:id
+func (h *PasskeyHandler) DeletePasskey(c *gin.Context) {
+	userID, ok := h.requireUser(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid passkey ID", nil)
+		return
+	}
+
+	if err := h.repository.DeleteWebAuthnCredential(c.Request.Context(), userID, id); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Passkey not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to delete passkey", err)
+		return
+	}
+
+	log.Printf("INFO: User %d deleted passkey %d", userID, id)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Passkey deleted successfully",
+	})
+}
+
+// BeginPasskeyLogin handles POST /auth/passkeys/login/begin. With an email, the options are
+// limited to that account's passkeys; an unknown email gets the same options as none, so
+// accounts cannot be discovered this way.
+func (h *PasskeyHandler) BeginPasskeyLogin(c *gin.Context) {
+	if !h.requireEnabled(c) {
+		return
+	}
+
+	var req BeginPasskeyLoginRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.BadRequest(c, "Invalid request body", err)
+			return
+		}
+	}
+
+	var allowed []models.WebAuthnCredential
+	if email := strings.ToLower(strings.TrimSpace(req.Email)); email != "" {
+		ctx := c.Request.Context()
+		if user, err := h.repository.GetUserByEmail(ctx, email); err == nil {
+			creds, err := h.repository.ListWebAuthnCredentials(ctx, user.ID)
+			if err != nil {
+				problem.Internal(c, "Failed to list passkeys", err)
+				return
+			}
+			allowed = creds
+		}
+	}
+
+	options, session, err := h.rp.BeginLogin(c.Request.Context(), allowed)
+	if err != nil {
+		problem.Internal(c, "Failed to start passkey login", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"session": session,
+		"options": options,
+	})
+}
+
+// FinishPasskeyLogin handles POST /auth/passkeys/login/finish, starting a session like a
+// password login
+func (h *PasskeyHandler) FinishPasskeyLogin(c *gin.Context) {
+	if !h.requireEnabled(c) {
+		return
+	}
+
+	var req FinishPasskeyLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	cred, err := h.rp.FinishLogin(ctx, req.Session, req.Credential, func(credentialID []byte) (*models.WebAuthnCredential, error) {
+		return h.repository.GetWebAuthnCredentialByCredentialID(ctx, credentialID)
+	})
+	if err != nil {
+		if storage.IsNotFound(err) || errors.Is(err, webauthn.ErrVerification) {
+			log.Printf("WARN: Passkey login failed: %v", err)
+			problem.Respond(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Passkey login failed", nil)
+			return
+		}
+		problem.Internal(c, "Failed to verify passkey", err)
+		return
+	}
+
+	if err := h.repository.RecordWebAuthnLogin(ctx, cred.ID, cred.SignCount); err != nil {
+		problem.Internal(c, "Failed to record passkey login", err)
+		return
+	}
+	profile, err := h.repository.GetUserProfile(ctx, cred.UserID)
+	if err != nil {
+		problem.Internal(c, "Failed to get user", err)
+		return
+	}
+
+	log.Printf("INFO: User logged in with passkey %d: ID=%d, Email=%s", cred.ID, profile.ID, profile.Email)
+	startSession(c, h.repository, h.sessions, &profile.User)
+}
+
+// requireUser returns the signed-in user, answering 403 for API key requests
+func (h *PasskeyHandler) requireUser(c *gin.Context) (int64, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		problem.Respond(c, http.StatusForbidden, problem.CodeForbidden, "Passkeys require a user session", nil)
+	}
+	return userID, ok
+}
+
+// requireEnabled answers 404 when no relying party ID is configured
+func (h *PasskeyHandler) requireEnabled(c *gin.Context) bool {
+	if !h.rp.Enabled() {
+		problem.NotFound(c, "Passkeys are not configured on this server", nil)
+		return false
+	}
+	return true
+}
+
+// passkeyName trims a passkey name, defaulting blank names, and answers 422 for long names
+func passkeyName(c *gin.Context, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return defaultPasskeyName, true
+	}
+	if len(name) > maxPasskeyNameLength {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid passkey name",
+			errors.New("name must be at most 100 characters"))
+		return "", false
+	}
+	return name, true
+}
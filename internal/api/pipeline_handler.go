@@ -0,0 +1,97 @@
+package api
+
+import (
+	"errors"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/pause"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pausePipelineRequest explains a pause to other operators
+type pausePipelineRequest struct {
+	Reason string `json:"reason"`
+}
+
+// SetupPipelineRoutes configures routes for pausing and resuming pipelines
+func SetupPipelineRoutes(router *gin.Engine, controller *pause.Controller, sessions *auth.SessionStore, cfg *config.Config) {
+	admin := router.Group("/admin/pipelines")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("", ListPipelines(controller))
+		admin.POST("/:name/pause", PausePipeline(controller))
+		admin.POST("/:name/resume", ResumePipeline(controller))
+	}
+}
+
+// ListPipelines returns a handler for GET /admin/pipelines
+func ListPipelines(controller *pause.Controller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		statuses, err := controller.Statuses(c.Request.Context())
+		if err != nil {
+			problem.Internal(c, "Failed to list pipelines", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"pipelines": statuses})
+	}
+}
+
+// PausePipeline returns a handler for POST /admin/pipelines/:name/pause.
+// Pausing a paused pipeline updates its reason.
+func PausePipeline(controller *pause.Controller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		var req pausePipelineRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				problem.BadRequest(c, "Invalid request body", err)
+				return
+			}
+		}
+
+		ctx := c.Request.Context()
+		if err := controller.Pause(ctx, c.Param("name"), req.Reason, actorID(c)); err != nil {
+			respondPipelineError(c, "Failed to pause pipeline", err)
+			return
+		}
+		respondPipelines(c, controller)
+	}
+}
+
+// ResumePipeline returns a handler for POST /admin/pipelines/:name/resume.
+// Resuming a running pipeline succeeds without changes.
+func ResumePipeline(controller *pause.Controller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		if err := controller.Resume(c.Request.Context(), c.Param("name")); err != nil {
+			respondPipelineError(c, "Failed to resume pipeline", err)
+			return
+		}
+		respondPipelines(c, controller)
+	}
+}
+
+func respondPipelines(c *gin.Context, controller *pause.Controller) {
+	statuses, err := controller.Statuses(c.Request.Context())
+	if err != nil {
+		problem.Internal(c, "Failed to list pipelines", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"pipelines": statuses})
+}
+
+func respondPipelineError(c *gin.Context, title string, err error) {
+	if errors.Is(err, pause.ErrUnknownPipeline) {
+		problem.NotFound(c, "Pipeline not found", err)
+		return
+	}
+	problem.Internal(c, title, err)
+}
@@ -0,0 +1,283 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/avatar"
+	"log-ingestion-service/internal/i18n"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validThemes lists the UI themes a user may choose
+var validThemes = map[string]bool{
+	"light":  true,
+	"dark":   true,
+	"system": true,
+}
+
+// ProfileHandler handles self-service profile requests for the authenticated user
+type ProfileHandler struct {
+	repository *storage.Repository
+	avatars    *avatar.Store
+	config     *config.AvatarConfig
+}
+
+// NewProfileHandler creates a new profile handler
+func NewProfileHandler(repository *storage.Repository, avatars *avatar.Store, cfg *config.AvatarConfig) *ProfileHandler {
+	return &ProfileHandler{
+		repository: repository,
+		avatars:    avatars,
+		config:     cfg,
+	}
+}
+
+// UpdateProfileRequest represents a partial update to the current user's profile.
+// Omitted fields are left unchanged; default_project_id may be set to null to clear it.
+type UpdateProfileRequest struct {
+	Name             *string                         `json:"name"`
+	AvatarURL        *string                         `json:"avatar_url"`
+	Timezone         *string                         `json:"timezone"`
+	DefaultProjectID json.RawMessage                 `json:"default_project_id"`
+	Theme            *string                         `json:"theme"`
+	Language         *string                         `json:"language"`
+	Notifications    *models.NotificationPreferences `json:"notifications"`
+}
+
+// GetMe returns the authenticated user's profile and preferences
+func (h *ProfileHandler) GetMe(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		problem.Respond(c, http.StatusForbidden, problem.CodeForbidden, "Profile requires a user session", nil)
+		return
+	}
+
+	ctx := c.Request.Context()
+	profile, err := h.repository.GetUserProfile(ctx, userID)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "User not found", nil)
+			return
+		}
+		log.Printf("ERROR: Failed to get profile for user %d: %v", userID, err)
+		problem.Internal(c, "Failed to get profile", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// UpdateMe applies changes to the authenticated user's profile and preferences
+func (h *ProfileHandler) UpdateMe(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		problem.Respond(c, http.StatusForbidden, problem.CodeForbidden, "Profile requires a user session", nil)
+		return
+	}
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request", err)
+		return
+	}
+
+	update, err := req.toUpdate()
+	if err != nil {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid profile", err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.repository.UpdateUserProfile(ctx, userID, update); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "User not found", nil)
+			return
+		}
+		log.Printf("ERROR: Failed to update profile for user %d: %v", userID, err)
+		problem.Internal(c, "Failed to update profile", err)
+		return
+	}
+
+	profile, err := h.repository.GetUserProfile(ctx, userID)
+	if err != nil {
+		problem.Internal(c, "Failed to get profile", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// UploadAvatar stores a multipart "avatar" image for the authenticated user.
+// The image is cropped to a square and resized before being saved.
+func (h *ProfileHandler) UploadAvatar(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		problem.Respond(c, http.StatusForbidden, problem.CodeForbidden, "Profile requires a user session", nil)
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.config.MaxUploadBytes)
+
+	file, _, err := c.Request.FormFile("avatar")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			problem.Respond(c, http.StatusRequestEntityTooLarge, problem.CodeInvalidRequest, "Avatar too large",
+				fmt.Errorf("avatar must be at most %d bytes", h.config.MaxUploadBytes))
+			return
+		}
+		problem.BadRequest(c, "Missing avatar file", err)
+		return
+	}
+	defer file.Close()
+
+	name, err := h.avatars.Save(userID, file)
+	if err != nil {
+		if errors.Is(err, avatar.ErrInvalidImage) {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid avatar", err)
+			return
+		}
+		log.Printf("ERROR: Failed to save avatar for user %d: %v", userID, err)
+		problem.Internal(c, "Failed to save avatar", err)
+		return
+	}
+
+	h.setAvatar(c, userID, avatar.URL(name))
+}
+
+// DeleteAvatar removes the authenticated user's avatar
+func (h *ProfileHandler) DeleteAvatar(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		problem.Respond(c, http.StatusForbidden, problem.CodeForbidden, "Profile requires a user session", nil)
+		return
+	}
+
+	h.setAvatar(c, userID, "")
+}
+
+// ServeAvatar serves a stored avatar image with long-lived cache headers
+func (h *ProfileHandler) ServeAvatar(c *gin.Context) {
+	path, ok := h.avatars.Path(c.Param("file"))
+	if !ok {
+		problem.NotFound(c, "Avatar not found", nil)
+		return
+	}
+
+	// File names are content hashes, so a given URL never changes
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.File(path)
+}
+
+// setAvatar points the user's avatar_url at avatarURL, removes the previous stored image and returns the profile
+func (h *ProfileHandler) setAvatar(c *gin.Context, userID int64, avatarURL string) {
+	ctx := c.Request.Context()
+	previous, err := h.repository.GetUserProfile(ctx, userID)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "User not found", nil)
+			return
+		}
+		problem.Internal(c, "Failed to get profile", err)
+		return
+	}
+
+	if err := h.repository.UpdateUserProfile(ctx, userID, storage.ProfileUpdate{AvatarURL: &avatarURL}); err != nil {
+		log.Printf("ERROR: Failed to update avatar for user %d: %v", userID, err)
+		problem.Internal(c, "Failed to update avatar", err)
+		return
+	}
+
+	if previous.AvatarURL != nil && *previous.AvatarURL != avatarURL {
+		h.avatars.Remove(*previous.AvatarURL)
+	}
+
+	profile, err := h.repository.GetUserProfile(ctx, userID)
+	if err != nil {
+		problem.Internal(c, "Failed to get profile", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// toUpdate validates the request and converts it into a storage update
+func (req *UpdateProfileRequest) toUpdate() (storage.ProfileUpdate, error) {
+	update := storage.ProfileUpdate{
+		Notifications: req.Notifications,
+	}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return update, fmt.Errorf("name must not be empty")
+		}
+		update.Name = &name
+	}
+
+	if req.AvatarURL != nil {
+		u, err := url.Parse(*req.AvatarURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https" && *req.AvatarURL != "") {
+			return update, fmt.Errorf("avatar_url must be an http(s) URL")
+		}
+		update.AvatarURL = req.AvatarURL
+	}
+
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" {
+			return update, fmt.Errorf("unknown timezone %q", *req.Timezone)
+		}
+		update.Timezone = req.Timezone
+	}
+
+	if len(req.DefaultProjectID) > 0 {
+		if string(req.DefaultProjectID) == "null" {
+			update.ClearProject = true
+		} else {
+			var projectID int64
+			if err := json.Unmarshal(req.DefaultProjectID, &projectID); err != nil || projectID <= 0 {
+				return update, fmt.Errorf("default_project_id must be a positive integer or null")
+			}
+			update.DefaultProjectID = &projectID
+		}
+	}
+
+	if req.Theme != nil {
+		theme := strings.ToLower(*req.Theme)
+		if !validThemes[theme] {
+			return update, fmt.Errorf("theme must be one of light, dark, system")
+		}
+		update.Theme = &theme
+	}
+
+	if req.Language != nil {
+		language := strings.ToLower(*req.Language)
+		if !i18n.Supported(language) {
+			return update, fmt.Errorf("language must be one of %s", strings.Join(i18n.Languages, ", "))
+		}
+		update.Language = &language
+	}
+
+	return update, nil
+}
+
+// currentUserID returns the ID of the user behind a JWT session, if any
+func currentUserID(c *gin.Context) (int64, bool) {
+	value, exists := c.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+	userID, ok := value.(int64)
+	return userID, ok
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log-ingestion-service/internal/ingest/logpb"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ingestProtoBatch handles POST /api/v1/logs/batch with a protobuf LogBatch body (see
+// internal/ingest/logpb/log_batch.proto), optionally gzip compressed. Entries carry the log
+// fields directly, so neither JSON decoding nor field mapping is involved; validation,
+// sanitizing and the response are the same as for JSON batches.
+func (h *Handler) ingestProtoBatch(c *gin.Context) {
+	body, err := readEncodedBody(c)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) || errors.Is(err, errBodyTooLarge) {
+			problem.Respond(c, http.StatusRequestEntityTooLarge, problem.CodeInvalidRequest, "Request body too large", err)
+			return
+		}
+		problem.BadRequest(c, "Failed to read request body", err)
+		return
+	}
+
+	if isDryRun(c) {
+		var entries []*models.LogEntry
+		if _, err := logpb.DecodeBatch(body, func(_ int, entry *models.LogEntry) {
+			entries = append(entries, entry)
+		}); err != nil {
+			problem.BadRequest(c, "Invalid request body", err)
+			return
+		}
+		if len(entries) == 0 {
+			problem.BadRequest(c, "Empty batch", nil)
+			return
+		}
+		h.respondDecodedDryRun(c, len(entries), func(i int) (*models.LogEntry, error) {
+			return entries[i], nil
+		})
+		return
+	}
+
+	validLogs := make([]models.LogEntry, 0, batchCapacityHint)
+	var validationErrors []string
+	modified := 0
+	rec := h.recordSources(c)
+	defer rec.Flush()
+
+	total, err := logpb.DecodeBatch(body, func(i int, entry *models.LogEntry) {
+		if err := h.validator.ValidateFrom(entry, c.GetString("api_key")); err != nil {
+			rec.Reject(entry.Service, protoRecordPayload(entry), err)
+			validationErrors = append(validationErrors,
+				fmt.Sprintf("Log entry %d validation failed: %s", i, err.Error()))
+			return
+		}
+		if h.validator.Sanitize(entry).Modified() {
+			modified++
+		}
+		validLogs = append(validLogs, *entry)
+	})
+	if err != nil {
+		rec.RejectBody(body, err)
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+
+	if total == 0 {
+		problem.BadRequest(c, "Empty batch", nil)
+		return
+	}
+
+	if len(validLogs) > 0 {
+		if err := h.batcher.AddBatch(validLogs); err != nil {
+			problem.RespondRetry(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "Failed to process logs", err, overloadRetryAfter)
+			return
+		}
+		for i := range validLogs {
+			rec.Accept(validLogs[i].Service)
+		}
+	}
+
+	response := gin.H{
+		"message":  "Batch processed",
+		"accepted": len(validLogs),
+		"total":    total,
+	}
+	if modified > 0 {
+		response["modified"] = modified
+	}
+	if len(validationErrors) > 0 {
+		response["errors"] = validationErrors
+		response["rejected"] = len(validationErrors)
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
@@ -0,0 +1,280 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/middleware"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublicHandler serves wallboard dashboards authenticated with public tokens, and the
+// management of those tokens. Dashboards see aggregated counts and faults marked public, in a
+// reduced form without notices, assignees, custom fields or history.
+type PublicHandler struct {
+	repo         *storage.Repository
+	searchParser *parser.SearchParser
+}
+
+// NewPublicHandler creates a public handler
+func NewPublicHandler(repo *storage.Repository) *PublicHandler {
+	return &PublicHandler{repo: repo, searchParser: parser.NewSearchParser(nil)}
+}
+
+// SetupPublicRoutes configures the read-only dashboard routes and public token management
+func SetupPublicRoutes(router *gin.Engine, publicHandler *PublicHandler, sessions *auth.SessionStore, cfg *config.Config) {
+	public := router.Group("/api/v1/public")
+	{
+		public.Use(auth.PublicTokenAuth(publicHandler.repo))
+		public.Use(middleware.RateLimit(&cfg.RateLimit))
+
+		public.GET("/faults", publicHandler.ListFaults)
+		public.GET("/faults/facets", publicHandler.GetFaultFacets)
+		public.GET("/faults/:id", publicHandler.GetFault)
+		public.GET("/faults/:id/stats", publicHandler.GetFaultStats)
+	}
+
+	admin := router.Group("/admin/public-tokens")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("", publicHandler.ListTokens)
+		admin.POST("", publicHandler.CreateToken)
+		admin.DELETE("/:id", publicHandler.RevokeToken)
+	}
+}
+
+// publicFault is the view of a fault shown to dashboards
+type publicFault struct {
+	ID              int64     `json:"id"`
+	ErrorClass      string    `json:"error_class"`
+	Message         string    `json:"message"`
+	Environment     string    `json:"environment"`
+	State           string    `json:"state"`
+	Resolved        bool      `json:"resolved"`
+	Severity        string    `json:"severity"`
+	OccurrenceCount int64     `json:"occurrence_count"`
+	FirstSeenAt     time.Time `json:"first_seen_at"`
+	LastSeenAt      time.Time `json:"last_seen_at"`
+}
+
+func newPublicFault(f *models.Fault) publicFault {
+	return publicFault{
+		ID:              f.ID,
+		ErrorClass:      f.ErrorClass,
+		Message:         f.Message,
+		Environment:     f.Environment,
+		State:           f.State,
+		Resolved:        f.Resolved,
+		Severity:        f.Severity,
+		OccurrenceCount: f.OccurrenceCount,
+		FirstSeenAt:     f.FirstSeenAt,
+		LastSeenAt:      f.LastSeenAt,
+	}
+}
+
+// publicFilters limits fault queries to public faults of the token's project, narrowed by the
+// environment and state query parameters. Search queries are not accepted, since matching on
+// hidden fields would reveal them.
+func publicFilters(c *gin.Context) *storage.FaultFilters {
+	public := true
+	filters := &storage.FaultFilters{Public: &public}
+	if projectID, ok := c.Get(auth.PublicTokenProjectIDKey); ok {
+		id := projectID.(int64)
+		filters.ProjectID = &id
+	}
+	if env := c.Query("environment"); env != "" {
+		filters.Environment = &env
+	}
+	if states := c.Query("state"); states != "" {
+		filters.States = strings.Split(states, ",")
+	}
+	return filters
+}
+
+// publicFaultVisible reports whether a public token may see a fault
+func publicFaultVisible(c *gin.Context, f *models.Fault) bool {
+	if !f.Public {
+		return false
+	}
+	if projectID, ok := c.Get(auth.PublicTokenProjectIDKey); ok {
+		return f.ProjectID != nil && *f.ProjectID == projectID.(int64)
+	}
+	return true
+}
+
+// ListFaults handles GET /api/v1/public/faults
+func (h *PublicHandler) ListFaults(c *gin.Context) {
+	filters := publicFilters(c)
+	limit, offset, err := parsePagination(c, h.searchParser)
+	if err != nil {
+		problem.BadRequest(c, "Invalid pagination parameters", err)
+		return
+	}
+	filters.Limit = limit
+	filters.Offset = offset
+	if err := parseFaultSort(c, filters); err != nil {
+		problem.BadRequest(c, "Invalid sort parameter", err)
+		return
+	}
+
+	faults, total, err := h.repo.ListFaults(c.Request.Context(), *filters)
+	if err != nil {
+		problem.Internal(c, "Failed to list faults", err)
+		return
+	}
+
+	items := make([]publicFault, len(faults))
+	for i := range faults {
+		items[i] = newPublicFault(&faults[i])
+	}
+	hasMore := int64(offset+len(faults)) < total
+	respondPage(c, "faults", items, newPagination(limit, offset, len(faults), hasMore, &total), nil)
+}
+
+// GetFaultFacets handles GET /api/v1/public/faults/facets, counting public faults by
+// environment, status and state
+func (h *PublicHandler) GetFaultFacets(c *gin.Context) {
+	facets, err := h.repo.GetFaultFacets(c.Request.Context(), *publicFilters(c), 0)
+	if err != nil {
+		problem.Internal(c, "Failed to get fault facets", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"facets": gin.H{
+			"environment": facets.Environment,
+			"status":      facets.Status,
+			"state":       facets.State,
+		},
+	})
+}
+
+// GetFault handles GET /api/v1/public/faults/:id
+func (h *PublicHandler) GetFault(c *gin.Context) {
+	fault, ok := h.visibleFault(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, newPublicFault(fault))
+}
+
+// GetFaultStats handles GET /api/v1/public/faults/:id/stats
+func (h *PublicHandler) GetFaultStats(c *gin.Context) {
+	fault, ok := h.visibleFault(c)
+	if !ok {
+		return
+	}
+
+	stats, err := h.repo.GetFaultStats(c.Request.Context(), fault.ID)
+	if err != nil {
+		problem.Internal(c, "Failed to get stats", err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// visibleFault loads the fault named in the path, responding 404 when the token may not see it
+func (h *PublicHandler) visibleFault(c *gin.Context) (*models.Fault, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid fault ID", nil)
+		return nil, false
+	}
+
+	fault, err := h.repo.GetFault(c.Request.Context(), id)
+	if err != nil && !storage.IsNotFound(err) {
+		problem.Internal(c, "Failed to get fault", err)
+		return nil, false
+	}
+	if err != nil || !publicFaultVisible(c, fault) {
+		problem.NotFound(c, "Fault not found", nil)
+		return nil, false
+	}
+	return fault, true
+}
+
+// createPublicTokenRequest creates a public token, limited to one project when ProjectID is set
+type createPublicTokenRequest struct {
+	Name      string `json:"name" binding:"required"`
+	ProjectID *int64 `json:"project_id"`
+}
+
+// ListTokens handles GET /admin/public-tokens.
+// Admins see every token; other users see the tokens they created.
+func (h *PublicHandler) ListTokens(c *gin.Context) {
+	tokens, err := h.repo.ListPublicTokens(c.Request.Context(), tokenOwnerFilter(c))
+	if err != nil {
+		problem.Internal(c, "Failed to list public tokens", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tokens": tokens})
+}
+
+// CreateToken handles POST /admin/public-tokens. The token is returned only in this response.
+func (h *PublicHandler) CreateToken(c *gin.Context) {
+	var req createPublicTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+
+	value, hash, err := auth.GeneratePublicToken()
+	if err != nil {
+		problem.Internal(c, "Failed to generate public token", err)
+		return
+	}
+	token := &models.PublicToken{Name: req.Name, ProjectID: req.ProjectID, CreatedBy: actorID(c)}
+	if err := h.repo.CreatePublicToken(c.Request.Context(), token, hash); err != nil {
+		problem.Internal(c, "Failed to create public token", err)
+		return
+	}
+
+	log.Printf("INFO: Public token created: ID=%d, Name=%s", token.ID, token.Name)
+	c.JSON(http.StatusCreated, gin.H{
+		"token":   token,
+		"value":   value,
+		"message": "Public token created. Please copy it now as it won't be shown again.",
+	})
+}
+
+// RevokeToken handles DELETE /admin/public-tokens/:id.
+// Admins can revoke any token; other users only the tokens they created.
+func (h *PublicHandler) RevokeToken(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid public token ID", nil)
+		return
+	}
+
+	if err := h.repo.RevokePublicToken(c.Request.Context(), id, tokenOwnerFilter(c)); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Public token not found", fmt.Errorf("no active public token %d of yours", id))
+			return
+		}
+		problem.Internal(c, "Failed to revoke public token", err)
+		return
+	}
+
+	log.Printf("INFO: Public token revoked: ID=%d", id)
+	c.Status(http.StatusNoContent)
+}
+
+// tokenOwnerFilter returns nil for admins, who manage every token, and the signed-in user otherwise
+func tokenOwnerFilter(c *gin.Context) *int64 {
+	if isAdmin, _ := c.Get("is_admin"); isAdmin == true {
+		return nil
+	}
+	userID, _ := currentUserID(c)
+	return &userID
+}
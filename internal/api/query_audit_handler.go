@@ -0,0 +1,110 @@
+package api
+
+import (
+	"errors"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/querycost"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetupQueryAuditRoutes configures the route reading the query audit log
+func SetupQueryAuditRoutes(router *gin.Engine, repo *storage.Repository, sessions *auth.SessionStore, cfg *config.Config) {
+	admin := router.Group("/admin/query-audit")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("", ListQueryAudit(repo))
+	}
+}
+
+// ListQueryAudit returns a handler for GET /admin/query-audit, the guarded queries run, newest
+// first, optionally for one user_id or outcome
+func ListQueryAudit(repo *storage.Repository) gin.HandlerFunc {
+	searchParser := parser.NewSearchParser(nil)
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		filters := storage.QueryAuditFilters{Outcome: c.Query("outcome")}
+		if value := c.Query("user_id"); value != "" {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				problem.BadRequest(c, "Invalid user ID", nil)
+				return
+			}
+			filters.UserID = &id
+		}
+		switch filters.Outcome {
+		case "", models.QueryAllowed, models.QueryQueued, models.QueryRejected:
+		default:
+			problem.BadRequest(c, "Invalid outcome", nil)
+			return
+		}
+
+		limit, offset, err := parsePagination(c, searchParser)
+		if err != nil {
+			problem.BadRequest(c, "Invalid pagination parameters", err)
+			return
+		}
+		entries, err := repo.ListQueryAudit(c.Request.Context(), filters, limit+1, offset)
+		if err != nil {
+			problem.Internal(c, "Failed to list query audit log", err)
+			return
+		}
+		entries, hasMore := trimLookahead(entries, limit)
+		if entries == nil {
+			entries = []models.QueryAuditEntry{}
+		}
+		respondPage(c, "entries", entries, newPagination(limit, offset, len(entries), hasMore, nil), nil)
+	}
+}
+
+// admitQuery runs a search or analytics query past the query guard, recording the user or API
+// key behind the request. When the query may not run it writes the error response and returns
+// false; otherwise the returned function must be called once the query is done.
+func admitQuery(c *gin.Context, guard *querycost.Guard, repo *storage.Repository, estimate querycost.Estimator) (func(), bool) {
+	ctx := c.Request.Context()
+	q := querycost.Query{
+		Route:  c.Request.Method + " " + c.FullPath(),
+		Query:  queryParams(c),
+		UserID: actorID(c),
+	}
+	if key := c.GetString("api_key"); key != "" {
+		if keys, err := repo.GetAPIKeysByValue(ctx, []string{key}); err == nil {
+			if apiKey, ok := keys[key]; ok {
+				q.APIKeyID = &apiKey.ID
+			}
+		}
+	}
+
+	done, err := guard.Admit(ctx, q, estimate)
+	if err != nil {
+		var tooExpensive *querycost.TooExpensiveError
+		switch {
+		case errors.As(err, &tooExpensive):
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeQueryTooExpensive, "Query too expensive", err)
+		case errors.Is(err, querycost.ErrQueueTimeout):
+			problem.RespondRetry(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "Too many expensive queries", err, time.Second)
+		default:
+			problem.Internal(c, "Failed to admit query", err)
+		}
+		return nil, false
+	}
+	return done, true
+}
+
+// queryParams returns a request's query parameters, sorted, without an API key passed as one
+func queryParams(c *gin.Context) string {
+	params := c.Request.URL.Query()
+	params.Del("api_key")
+	return params.Encode()
+}
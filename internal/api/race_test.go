@@ -0,0 +1,6 @@
+//go:build race
+
+package api
+
+// raceEnabled is set when tests run with the race detector, which adds allocations of its own
+const raceEnabled = true
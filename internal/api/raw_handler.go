@@ -0,0 +1,190 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log-ingestion-service/internal/feature"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/sources"
+	"log-ingestion-service/pkg/models"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRawBodyBytes caps the size of a raw ingestion request
+const maxRawBodyBytes = 10 << 20
+
+// maxRawLineBytes caps the size of a single line in a raw ingestion request
+const maxRawLineBytes = 1 << 20
+
+// rawErrorLimit caps the number of per-line errors returned for a raw request
+const rawErrorLimit = 100
+
+// IngestRaw handles POST /api/v1/logs/raw.
+// The body holds one log per line and is parsed with the parser selected for the API key,
+// the X-Log-Source header or the content type. text/plain and application/octet-stream bodies
+// are auto-detected per line; other content types must be registered with a parser. With
+// parser.multiline configured, continuation lines of auto-detected and text logs are joined
+// onto the line that starts them.
+// It responds 404 while the raw_ingest feature flag is off.
+func (h *Handler) IngestRaw(c *gin.Context) {
+	if h.flags != nil && !h.flags.Enabled(c.Request.Context(), feature.RawIngest, nil) {
+		problem.RespondDetail(c, http.StatusNotFound, problem.CodeNotFound, "Raw ingestion is disabled",
+			"the raw_ingest feature flag is off")
+		return
+	}
+
+	contentType := c.ContentType()
+	p, parserName := h.selectParser(c, contentType)
+	if parserName == parser.NameAuto && !acceptsRawContentType(contentType) {
+		problem.Respond(c, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "Unsupported content type",
+			fmt.Errorf("content type %q has no registered parser; send text/plain or application/octet-stream", contentType))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxRawBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			problem.Respond(c, http.StatusRequestEntityTooLarge, problem.CodeInvalidRequest, "Request body too large", err)
+			return
+		}
+		problem.BadRequest(c, "Failed to read request body", err)
+		return
+	}
+
+	source := c.GetHeader(SourceHeader)
+	validLogs := make([]models.LogEntry, 0)
+	var lineErrors []string
+	rejected, modified, total := 0, 0, 0
+	var rec *sources.Recorder
+	if !isDryRun(c) {
+		rec = h.recordSources(c)
+		defer rec.Flush()
+	}
+
+	ingest := func(line []byte, lineNumber int) {
+		total++
+
+		entry, err := p.Parse(line)
+		service := ""
+		if err == nil {
+			tagSource(entry, source)
+			service = entry.Service
+			err = h.validator.ValidateFrom(entry, c.GetString("api_key"))
+		}
+		if err != nil {
+			rec.Reject(service, line, err)
+			rejected++
+			if len(lineErrors) < rawErrorLimit {
+				lineErrors = append(lineErrors, fmt.Sprintf("Line %d: %s", lineNumber, err.Error()))
+			}
+			return
+		}
+
+		if h.validator.Sanitize(entry).Modified() {
+			modified++
+		}
+		validLogs = append(validLogs, *entry)
+	}
+
+	// Continuation lines, such as stack trace frames, are joined onto the line starting their log
+	var joiner *parser.LineJoiner[int]
+	if h.parsers.JoinsLines(parserName) {
+		joiner = parser.NewLineJoiner[int](h.parsers.Multiline())
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRawLineBytes)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if joiner == nil {
+			if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+				ingest(line, lineNumber)
+			}
+			continue
+		}
+		line := bytes.TrimRight(scanner.Bytes(), " \t\r")
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		for _, log := range joiner.Add(line, lineNumber) {
+			ingest(log.Data, log.First)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		problem.BadRequest(c, "Failed to read log lines", err)
+		return
+	}
+	if joiner != nil {
+		if log, ok := joiner.Flush(); ok {
+			ingest(log.Data, log.First)
+		}
+	}
+
+	if total == 0 {
+		problem.BadRequest(c, "Empty request body", nil)
+		return
+	}
+
+	if len(validLogs) > 0 && !isDryRun(c) {
+		if err := h.batcher.AddBatch(validLogs); err != nil {
+			problem.RespondRetry(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "Failed to process logs", err, overloadRetryAfter)
+			return
+		}
+		for i := range validLogs {
+			rec.Accept(validLogs[i].Service)
+		}
+	}
+
+	response := gin.H{
+		"message":  "Raw logs processed",
+		"parser":   parserName,
+		"accepted": len(validLogs),
+		"total":    total,
+	}
+	if isDryRun(c) {
+		response["dry_run"] = true
+		response["records"] = validLogs
+	}
+	if modified > 0 {
+		response["modified"] = modified
+	}
+	if rejected > 0 {
+		response["errors"] = lineErrors
+		response["rejected"] = rejected
+	}
+
+	c.JSON(http.StatusAccepted, response)
+}
+
+// acceptsRawContentType reports whether a body of this type can be auto-detected line by line
+func acceptsRawContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "text/plain" || mediaType == "application/octet-stream"
+}
+
+// tagSource records the request's source on an entry and uses it as the service when none was parsed
+func tagSource(entry *models.LogEntry, source string) {
+	if source == "" {
+		return
+	}
+	if entry.Metadata == nil {
+		entry.Metadata = make(map[string]interface{})
+	}
+	entry.Metadata["source"] = source
+	if entry.Service == "" || entry.Service == parser.UnknownService {
+		entry.Service = source
+	}
+}
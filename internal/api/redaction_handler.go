@@ -0,0 +1,126 @@
+package api
+
+import (
+	"log"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/redact"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createRedactionRuleRequest defines a redaction rule
+type createRedactionRuleRequest struct {
+	ProjectID   *int64 `json:"project_id"`
+	Kind        string `json:"kind" binding:"required"`
+	Pattern     string `json:"pattern" binding:"required"`
+	Description string `json:"description"`
+}
+
+// SetupRedactionRoutes configures redaction rule management routes
+func SetupRedactionRoutes(router *gin.Engine, repo *storage.Repository, engine *redact.Engine, sessions *auth.SessionStore, cfg *config.Config) {
+	admin := router.Group("/admin/redaction-rules")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("", ListRedactionRules(repo))
+		admin.POST("", CreateRedactionRule(repo, engine))
+		admin.DELETE("/:id", DeleteRedactionRule(repo, engine))
+	}
+}
+
+// ListRedactionRules returns a handler for GET /admin/redaction-rules. With ?project_id= it
+// lists the rules applying to that project's notices: its own and the global ones.
+func ListRedactionRules(repo *storage.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var projectID *int64
+		if value := c.Query("project_id"); value != "" {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				problem.BadRequest(c, "Invalid project ID", nil)
+				return
+			}
+			projectID = &id
+		}
+
+		rules, err := repo.ListRedactionRules(c.Request.Context(), projectID)
+		if err != nil {
+			problem.Internal(c, "Failed to list redaction rules", err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"redaction_rules": rules})
+	}
+}
+
+// CreateRedactionRule returns a handler for POST /admin/redaction-rules. Rules that do not
+// compile are rejected.
+func CreateRedactionRule(repo *storage.Repository, engine *redact.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		var req createRedactionRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.BadRequest(c, "Invalid request body", err)
+			return
+		}
+
+		rule := &models.RedactionRule{
+			ProjectID:   req.ProjectID,
+			Kind:        strings.ToLower(req.Kind),
+			Pattern:     req.Pattern,
+			Description: strings.TrimSpace(req.Description),
+			CreatedBy:   actorID(c),
+		}
+		if err := redact.Check(rule); err != nil {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid redaction rule", err)
+			return
+		}
+
+		ctx := c.Request.Context()
+		if err := repo.CreateRedactionRule(ctx, rule); err != nil {
+			problem.Internal(c, "Failed to create redaction rule", err)
+			return
+		}
+		if err := engine.Reload(ctx); err != nil {
+			log.Printf("WARN: Failed to reload redaction rules: %v", err)
+		}
+		log.Printf("INFO: Redaction rule %d added (%s %q)", rule.ID, rule.Kind, rule.Pattern)
+		c.JSON(http.StatusCreated, rule)
+	}
+}
+
+// DeleteRedactionRule returns a handler for DELETE /admin/redaction-rules/:id
+func DeleteRedactionRule(repo *storage.Repository, engine *redact.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			problem.BadRequest(c, "Invalid redaction rule ID", nil)
+			return
+		}
+
+		ctx := c.Request.Context()
+		if err := repo.DeleteRedactionRule(ctx, id); err != nil {
+			if storage.IsNotFound(err) {
+				problem.NotFound(c, "Redaction rule not found", err)
+				return
+			}
+			problem.Internal(c, "Failed to delete redaction rule", err)
+			return
+		}
+		if err := engine.Reload(ctx); err != nil {
+			log.Printf("WARN: Failed to reload redaction rules: %v", err)
+		}
+		log.Printf("INFO: Redaction rule %d deleted", id)
+		c.Status(http.StatusNoContent)
+	}
+}
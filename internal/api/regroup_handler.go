@@ -0,0 +1,102 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/fault"
+	"log-ingestion-service/internal/jobs"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// regroupRequest selects the notices to regroup; exactly one of notice_id, fault_id or project_id is set
+type regroupRequest struct {
+	NoticeID  string `json:"notice_id"`
+	FaultID   *int64 `json:"fault_id"`
+	ProjectID *int64 `json:"project_id"`
+	DryRun    bool   `json:"dry_run"`
+}
+
+// SetupRegroupRoutes configures routes for regrouping stored notices under the current grouping rules
+func SetupRegroupRoutes(router *gin.Engine, faultHandler *FaultHandler, sessions *auth.SessionStore, cfg *config.Config) {
+	admin := router.Group("/admin/regroup")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.POST("", faultHandler.StartRegroup)
+	}
+}
+
+// StartRegroup handles POST /admin/regroup. The notices of a fault, a project, or a single notice
+// are replayed through the current fingerprinting and merge rules by a background job, whose
+// progress and report are read from GET /api/v1/jobs/:id. With dry_run, nothing is changed.
+func (h *FaultHandler) StartRegroup(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	var req regroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+
+	scope := storage.RegroupScope{NoticeID: strings.TrimSpace(req.NoticeID), FaultID: req.FaultID, ProjectID: req.ProjectID}
+	set := 0
+	if scope.NoticeID != "" {
+		set++
+	}
+	if scope.FaultID != nil {
+		set++
+	}
+	if scope.ProjectID != nil {
+		set++
+	}
+	if set != 1 {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid regroup",
+			fmt.Errorf("exactly one of notice_id, fault_id or project_id is required"))
+		return
+	}
+
+	ctx := c.Request.Context()
+	if scope.FaultID != nil {
+		if _, err := h.repo.GetFault(ctx, *scope.FaultID); err != nil {
+			if storage.IsNotFound(err) {
+				problem.NotFound(c, "Fault not found", nil)
+				return
+			}
+			problem.Internal(c, "Failed to start regroup", err)
+			return
+		}
+	}
+	if scope.NoticeID != "" {
+		if _, err := h.repo.GetNotice(ctx, scope.NoticeID); err != nil {
+			if storage.IsNotFound(err) {
+				problem.NotFound(c, "Notice not found", nil)
+				return
+			}
+			problem.Internal(c, "Failed to start regroup", err)
+			return
+		}
+	}
+
+	opts := jobs.EnqueueOptions{CreatedBy: actorID(c)}
+	if !req.DryRun {
+		opts.UniqueKey = fault.RegroupUniqueKey
+	}
+	job, err := h.jobs.Enqueue(ctx, fault.RegroupJob, fault.RegroupPayload{RegroupScope: scope, DryRun: req.DryRun}, opts)
+	if err != nil {
+		if errors.Is(err, storage.ErrJobExists) {
+			problem.Respond(c, http.StatusConflict, problem.CodeConflict, "Regroup already running", err)
+			return
+		}
+		problem.Internal(c, "Failed to start regroup", err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"fmt"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/rejects"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rejectSampleResponse is one sample in GET /admin/rejects/samples
+type rejectSampleResponse struct {
+	// APIKeyID is nil for syslog listeners and for keys deleted since the payload arrived
+	APIKeyID   *int64 `json:"api_key_id"`
+	APIKeyName string `json:"api_key_name,omitempty"`
+	Listener   string `json:"listener,omitempty"`
+	Service    string `json:"service,omitempty"`
+	rejects.Sample
+}
+
+// SetupRejectRoutes configures routes for reject counts and sampled rejected payloads
+func SetupRejectRoutes(router *gin.Engine, store *rejects.Store, repo *storage.Repository, sessions *auth.SessionStore, cfg *config.Config) {
+	admin := router.Group("/admin/rejects")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("", ListRejects(store))
+		admin.GET("/samples", ListRejectSamples(store, repo))
+		admin.DELETE("/samples", ClearRejectSamples(store))
+	}
+}
+
+// ListRejects returns a handler for GET /admin/rejects, the number of rejected logs and notices by reason
+func ListRejects(store *rejects.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		counts := store.Counts()
+		totals := make(map[string]int64, len(counts))
+		for kind, byReason := range counts {
+			for _, n := range byReason {
+				totals[kind] += n
+			}
+		}
+		cfg := store.Config()
+		c.JSON(http.StatusOK, gin.H{
+			"counts": counts,
+			"totals": totals,
+			"sampling": gin.H{
+				"enabled":          cfg.SampleRate > 0,
+				"sample_rate":      cfg.SampleRate,
+				"max_samples":      cfg.MaxSamples,
+				"max_sample_bytes": cfg.MaxSampleBytes,
+			},
+			"since":        store.Started().UTC(),
+			"generated_at": time.Now().UTC(),
+		})
+	}
+}
+
+// ListRejectSamples returns a handler for GET /admin/rejects/samples, the kept rejected payloads
+// newest first. ?kind= and ?reason= filter and ?limit= caps the result. Samples may hold data
+// the scrubber does not recognize, so only admins can read them.
+func ListRejectSamples(store *rejects.Store, repo *storage.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		kind := c.Query("kind")
+		if kind != "" && kind != rejects.KindLog && kind != rejects.KindNotice {
+			problem.BadRequest(c, "Invalid kind", fmt.Errorf("kind must be %s or %s", rejects.KindLog, rejects.KindNotice))
+			return
+		}
+		limit := 0
+		if s := c.Query("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				problem.BadRequest(c, "Invalid limit", fmt.Errorf("limit must be a positive number"))
+				return
+			}
+			limit = n
+		}
+
+		samples := store.Samples(kind, c.Query("reason"))
+		if limit > 0 && len(samples) > limit {
+			samples = samples[:limit]
+		}
+
+		values := make([]string, 0)
+		seen := make(map[string]bool)
+		for _, s := range samples {
+			if s.Source.APIKey != "" && !seen[s.Source.APIKey] {
+				seen[s.Source.APIKey] = true
+				values = append(values, s.Source.APIKey)
+			}
+		}
+		keys, err := repo.GetAPIKeysByValue(c.Request.Context(), values)
+		if err != nil {
+			problem.Internal(c, "Failed to list reject samples", err)
+			return
+		}
+
+		list := make([]rejectSampleResponse, 0, len(samples))
+		for _, s := range samples {
+			r := rejectSampleResponse{Listener: s.Source.Listener, Service: s.Source.Service, Sample: s}
+			if key, ok := keys[s.Source.APIKey]; ok {
+				id := key.ID
+				r.APIKeyID = &id
+				r.APIKeyName = key.Name
+			}
+			list = append(list, r)
+		}
+		c.JSON(http.StatusOK, gin.H{"samples": list})
+	}
+}
+
+// ClearRejectSamples returns a handler for DELETE /admin/rejects/samples
+func ClearRejectSamples(store *rejects.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"deleted": store.ClearSamples()})
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"fmt"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RetentionPreview handles GET /admin/retention/preview. It reports, without deleting anything,
+// what each TimescaleDB retention policy would remove if it ran now. With ?table=&drop_after= it
+// previews a policy that does not exist yet instead, so settings can be checked before adding it.
+func (h *AdminHandler) RetentionPreview(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	ctx := c.Request.Context()
+
+	timescale, err := h.repository.HasTimescaleDB(ctx)
+	if err != nil {
+		problem.Internal(c, "Failed to preview retention", err)
+		return
+	}
+
+	var previews []storage.RetentionPreview
+	table, dropAfter := c.Query("table"), c.Query("drop_after")
+	if table != "" || dropAfter != "" {
+		if _, ok := storage.RetentionTables[table]; !ok {
+			problem.BadRequest(c, "Invalid table", fmt.Errorf("table must be one of %s", strings.Join(retentionTableNames(), ", ")))
+			return
+		}
+		age, err := parseRetentionAge(dropAfter)
+		if err != nil {
+			problem.BadRequest(c, "Invalid drop_after", err)
+			return
+		}
+		previews = []storage.RetentionPreview{{
+			Table:     table,
+			Source:    storage.RetentionSourceRequest,
+			DropAfter: dropAfter,
+			Cutoff:    time.Now().Add(-age),
+		}}
+	} else if timescale {
+		previews, err = h.repository.ListRetentionPolicies(ctx)
+		if err != nil {
+			problem.Internal(c, "Failed to list retention policies", err)
+			return
+		}
+	}
+
+	covered := make(map[string]bool)
+	for i := range previews {
+		if err := h.repository.PreviewRetention(ctx, &previews[i], timescale); err != nil {
+			problem.Internal(c, "Failed to preview retention", err)
+			return
+		}
+		covered[previews[i].Table] = true
+	}
+	if previews == nil {
+		previews = []storage.RetentionPreview{}
+	}
+
+	// Tables no policy applies to keep their data forever
+	unmanaged := []string{}
+	for _, name := range retentionTableNames() {
+		if !covered[name] {
+			unmanaged = append(unmanaged, name)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"generated_at":          time.Now().UTC(),
+		"timescaledb":           timescale,
+		"policies":              previews,
+		"tables_without_policy": unmanaged,
+	})
+}
+
+// parseRetentionAge reads a retention age such as "90d" or "720h"
+func parseRetentionAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("drop_after is required with table")
+	}
+	var age time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("drop_after %q is not a number of days", s)
+		}
+		age = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("drop_after %q is not a duration such as 90d or 720h", s)
+		}
+		age = d
+	}
+	if age <= 0 {
+		return 0, fmt.Errorf("drop_after must be positive, got %s", s)
+	}
+	return age, nil
+}
+
+func retentionTableNames() []string {
+	names := make([]string, 0, len(storage.RetentionTables))
+	for name := range storage.RetentionTables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
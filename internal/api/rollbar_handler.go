@@ -0,0 +1,89 @@
+package api
+
+import (
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/ingest/rollbar"
+	"log-ingestion-service/internal/middleware"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/rejects"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// maxRollbarItemBytes caps the size of a Rollbar item, as Rollbar does
+const maxRollbarItemBytes = 1 << 20
+
+// SetupRollbarRoutes configures the Rollbar-compatible item endpoint. Rollbar SDKs send the
+// project access token, which is checked as an API key.
+func SetupRollbarRoutes(router *gin.Engine, faultHandler *FaultHandler, keyManager *auth.KeyManager, cfg *config.Config) {
+	rollbarAPI := router.Group("/api/1")
+	{
+		rollbarAPI.Use(sdkAPIKey(rollbar.AccessTokenHeader, "access_token", maxRollbarItemBytes))
+		rollbarAPI.Use(auth.APIKeyAuth(keyManager))
+		rollbarAPI.Use(middleware.RateLimit(&cfg.RateLimit))
+		
+		rollbarAPI.POST("/item/", faultHandler.IngestRollbarItem)
+		rollbarAPI.POST("/item", faultHandler.IngestRollbarItem)
+	}
+}
+
+// IngestRollbarItem handles POST /api/1/item/ (Rollbar-compatible).
+// Trace, trace chain and message items are converted into notices and grouped into faults like
+// Honeybadger notices. The response is the one Rollbar SDKs expect.
+func (h *FaultHandler) IngestRollbarItem(c *gin.Context) {
+	var item rollbar.Item
+	
+	if c.Request.ContentLength > maxRollbarItemBytes {
+		problem.Respond(c, http.StatusRequestEntityTooLarge, problem.CodeInvalidRequest, "Request body too large", nil)
+		return
+	}
+	if err := c.ShouldBindBodyWith(&item, binding.JSON); err != nil {
+		h.rejectRollbarItem(c, err)
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+	
+	req, err := rollbar.Convert(&item)
+	if err != nil {
+		h.rejectRollbarItem(c, err)
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid Rollbar item", err)
+		return
+	}
+	
+	if isDryRun(c) {
+		h.respondNoticeDryRun(c, req)
+		return
+	}
+	
+	_, notice, err := h.grouper.ProcessNotice(c.Request.Context(), req)
+	if err != nil {
+		respondNoticeError(c, "Failed to process item", err)
+		return
+	}
+	
+	uuid := item.Data.UUID
+	if uuid == "" {
+		uuid = notice.ID
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"err": 0,
+		"result": gin.H{
+			"id":   nil,
+			"uuid": uuid,
+		},
+	})
+}
+
+// rejectRollbarItem records an item that could not be converted, outside dry runs
+func (h *FaultHandler) rejectRollbarItem(c *gin.Context, err error) {
+	if isDryRun(c) {
+		return
+	}
+	body, _ := c.Get(gin.BodyBytesKey)
+	payload, _ := body.([]byte)
+	source := rejects.Source{APIKey: c.GetString("api_key")}
+	h.rejects.Record(rejects.KindNotice, rejects.ReasonInvalidBody, source, payload, err)
+}
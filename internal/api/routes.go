@@ -2,6 +2,7 @@ package api
 
 import (
 	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/ingest/otlp"
 	"log-ingestion-service/internal/middleware"
 	"log-ingestion-service/pkg/config"
 
@@ -13,6 +14,15 @@ func SetupRoutes(router *gin.Engine, handler *Handler, keyManager *auth.KeyManag
 	// Health check (no auth required)
 	router.GET("/health", handler.Health)
 	
+	// WebSocket log stream; browsers cannot set headers on the upgrade, so the key may be in the URL
+	stream := router.Group(StreamPath)
+	{
+		stream.Use(streamKeyFromQuery())
+		stream.Use(auth.APIKeyAuth(keyManager))
+		stream.Use(middleware.RateLimit(&cfg.RateLimit))
+		stream.GET("", handler.StreamLogs)
+	}
+	
 	// API v1 routes
 	v1 := router.Group("/api/v1")
 	{
@@ -22,29 +32,104 @@ func SetupRoutes(router *gin.Engine, handler *Handler, keyManager *auth.KeyManag
 		// Apply rate limiting middleware
 		v1.Use(middleware.RateLimit(&cfg.RateLimit))
 		
+		// Rate limit self-inspection
+		v1.GET("/limits", Limits(&cfg.RateLimit))
+		
 		// Log ingestion endpoints
 		v1.POST("/logs", handler.IngestLog)
 		v1.POST("/logs/batch", handler.IngestBatch)
+		v1.POST("/logs/raw", handler.IngestRaw)
+		v1.POST("/logs/ndjson", handler.IngestNDJSON)
+		
+		// OpenTelemetry logs over OTLP/HTTP; the collector appends /v1/logs to its endpoint
+		v1.POST("/otlp"+otlp.HTTPPath, handler.IngestOTLP)
+		
+		// Dry-run ingestion for debugging payloads
+		v1.POST("/logs/validate", handler.ValidateLogs)
+		
+		// Registered log parsers
+		v1.GET("/parsers", handler.ListParsers)
+	}
+}
+
+// SetupIngestRoutes configures an ingestion-only listener with its own auth mode and default parser
+func SetupIngestRoutes(router *gin.Engine, handler *Handler, keyManager *auth.KeyManager, cfg *config.Config, listenerCfg *config.ListenerConfig) {
+	router.GET("/health", handler.Health)
+	
+	stream := router.Group(StreamPath)
+	{
+		if listenerCfg.Auth != config.ListenerAuthNone {
+			stream.Use(streamKeyFromQuery())
+			stream.Use(auth.APIKeyAuth(keyManager))
+		}
+		stream.Use(middleware.RateLimit(&cfg.RateLimit))
+		if listenerCfg.Parser != "" {
+			stream.Use(withListenerParser(listenerCfg.Parser))
+		}
+		stream.GET("", handler.StreamLogs)
+	}
+	
+	v1 := router.Group("/api/v1")
+	{
+		if listenerCfg.Auth != config.ListenerAuthNone {
+			v1.Use(auth.APIKeyAuth(keyManager))
+		}
+		v1.Use(middleware.RateLimit(&cfg.RateLimit))
+		if listenerCfg.Parser != "" {
+			v1.Use(withListenerParser(listenerCfg.Parser))
+		}
+		
+		v1.POST("/logs", handler.IngestLog)
+		v1.POST("/logs/batch", handler.IngestBatch)
+		v1.POST("/logs/raw", handler.IngestRaw)
+		v1.POST("/logs/ndjson", handler.IngestNDJSON)
+		v1.POST("/otlp"+otlp.HTTPPath, handler.IngestOTLP)
+		v1.POST("/logs/validate", handler.ValidateLogs)
+		v1.GET("/parsers", handler.ListParsers)
+	}
+}
+
+// SetupOTLPRoutes configures an OpenTelemetry receiver serving OTLP/HTTP and OTLP/gRPC log
+// exports on their standard paths, with the listener's auth mode
+func SetupOTLPRoutes(router *gin.Engine, handler *Handler, keyManager *auth.KeyManager, cfg *config.Config, listenerCfg *config.ListenerConfig) {
+	router.GET("/health", handler.Health)
+	
+	receiver := router.Group("")
+	{
+		if listenerCfg.Auth != config.ListenerAuthNone {
+			receiver.Use(auth.APIKeyAuth(keyManager))
+		}
+		receiver.Use(middleware.RateLimit(&cfg.RateLimit))
+		
+		receiver.POST(otlp.HTTPPath, handler.IngestOTLP)
+		receiver.POST(otlp.GRPCPath, handler.ExportOTLPGRPC)
 	}
 }
 
 // SetupFaultRoutes configures fault-related API routes
-func SetupFaultRoutes(router *gin.Engine, faultHandler *FaultHandler, keyManager *auth.KeyManager, cfg *config.Config) {
+func SetupFaultRoutes(router *gin.Engine, faultHandler *FaultHandler, keyManager *auth.KeyManager, sessions *auth.SessionStore, cfg *config.Config) {
 	// API v1 routes
 	v1 := router.Group("/api/v1")
 	{
 		// Apply combined auth middleware (accepts API key OR JWT token)
-		v1.Use(auth.CombinedAuth(keyManager, cfg.Auth.JWTSecret))
+		v1.Use(auth.CombinedAuth(keyManager, sessions))
 		
 		// Apply rate limiting middleware
 		v1.Use(middleware.RateLimit(&cfg.RateLimit))
 		
+		// Replay retried POSTs that carry an Idempotency-Key
+		v1.Use(middleware.Idempotency(&cfg.Idempotency))
+		
 		// Notice ingestion (Honeybadger-compatible)
 		v1.POST("/notices", faultHandler.IngestNotice)
+		v1.POST("/notices/validate", faultHandler.ValidateNotice)
 		
 		// Fault endpoints
 		v1.GET("/faults", faultHandler.ListFaults)
+		v1.GET("/faults/facets", faultHandler.GetFaultFacets)
+		v1.GET("/faults/workflow", faultHandler.GetFaultWorkflow)
 		v1.GET("/faults/:id", faultHandler.GetFault)
+		v1.GET("/faults/:id/neighbors", faultHandler.GetFaultNeighbors)
 		v1.PATCH("/faults/:id", faultHandler.UpdateFault)
 		v1.DELETE("/faults/:id", faultHandler.DeleteFault)
 		
@@ -56,6 +141,9 @@ func SetupFaultRoutes(router *gin.Engine, faultHandler *FaultHandler, keyManager
 		v1.POST("/faults/:id/tags", faultHandler.AddFaultTags)
 		v1.PUT("/faults/:id/tags", faultHandler.ReplaceFaultTags)
 		v1.POST("/faults/:id/merge", faultHandler.MergeFaults)
+		v1.POST("/faults/:id/links", faultHandler.CreateFaultLink)
+		v1.DELETE("/faults/:id/links/:link_id", faultHandler.DeleteFaultLink)
+		v1.POST("/faults/:id/test-alert", faultHandler.TestAlert)
 		
 		// Fault sub-resources
 		v1.GET("/faults/:id/notices", faultHandler.GetFaultNotices)
@@ -63,8 +151,85 @@ func SetupFaultRoutes(router *gin.Engine, faultHandler *FaultHandler, keyManager
 		v1.GET("/faults/:id/comments", faultHandler.GetFaultComments)
 		v1.POST("/faults/:id/comments", faultHandler.CreateComment)
 		v1.GET("/faults/:id/history", faultHandler.GetFaultHistory)
+		v1.GET("/faults/:id/links", faultHandler.GetFaultLinks)
+		v1.GET("/faults/:id/accounts", faultHandler.GetFaultAccounts)
+		
+		// Search across faults, notices, comments and logs
+		v1.GET("/search", faultHandler.Search)
+		
+		// Customer accounts affected by faults
+		v1.GET("/accounts/:account_id/faults", faultHandler.GetAccountFaults)
+		
+		// Automatic merge rules
+		v1.GET("/merge-rules", faultHandler.ListMergeRules)
+		v1.POST("/merge-rules", faultHandler.CreateMergeRule)
+		v1.DELETE("/merge-rules/:id", faultHandler.DeleteMergeRule)
+		
+		// Severity escalation rules
+		v1.GET("/escalation-rules", faultHandler.ListEscalationRules)
+		v1.POST("/escalation-rules", faultHandler.CreateEscalationRule)
+		v1.DELETE("/escalation-rules/:id", faultHandler.DeleteEscalationRule)
+		
+		// Projects
+		v1.GET("/projects/:id/activity", faultHandler.GetProjectActivity)
+		
+		// Error rates around deploys
+		v1.GET("/deploys", faultHandler.ListDeploys)
+		
+		// Environments and their promotion order
+		v1.GET("/environments", faultHandler.ListEnvironments)
+		v1.POST("/environments", faultHandler.CreateEnvironment)
+		v1.PATCH("/environments/:id", faultHandler.UpdateEnvironment)
+		v1.DELETE("/environments/:id", faultHandler.DeleteEnvironment)
+		v1.GET("/environments/:id/unpromoted", faultHandler.GetUnpromotedFaults)
+		
+		// Custom fields on faults
+		v1.GET("/fault-fields", faultHandler.ListFaultFields)
+		v1.POST("/fault-fields", faultHandler.CreateFaultField)
+		v1.PATCH("/fault-fields/:id", faultHandler.UpdateFaultField)
+		v1.DELETE("/fault-fields/:id", faultHandler.DeleteFaultField)
 		
 		// Users
 		v1.GET("/users", faultHandler.GetUsers)
 	}
-}
\ No newline at end of file
+}
+// SetupPasskeyRoutes configures passkey login and the signed-in user's passkey management
+func SetupPasskeyRoutes(router *gin.Engine, passkeyHandler *PasskeyHandler, keyManager *auth.KeyManager, sessions *auth.SessionStore, cfg *config.Config) {
+	// Login ceremonies (no auth required)
+	passkeys := router.Group("/auth/passkeys")
+	{
+		passkeys.POST("/login/begin", passkeyHandler.BeginPasskeyLogin)
+		passkeys.POST("/login/finish", passkeyHandler.FinishPasskeyLogin)
+	}
+	
+	v1 := router.Group("/api/v1")
+	{
+		v1.Use(auth.CombinedAuth(keyManager, sessions))
+		v1.Use(middleware.RateLimit(&cfg.RateLimit))
+		v1.Use(middleware.Idempotency(&cfg.Idempotency))
+		
+		v1.GET("/me/passkeys", passkeyHandler.ListPasskeys)
+		v1.POST("/me/passkeys/register/begin", passkeyHandler.BeginPasskeyRegistration)
+		v1.POST("/me/passkeys/register/finish", passkeyHandler.FinishPasskeyRegistration)
+		v1.PATCH("/me/passkeys/:id", passkeyHandler.RenamePasskey)
+		v1.DELETE("/me/passkeys/:id", passkeyHandler.DeletePasskey)
+	}
+}
+
+// SetupProfileRoutes configures self-service profile routes for the signed-in user
+func SetupProfileRoutes(router *gin.Engine, profileHandler *ProfileHandler, keyManager *auth.KeyManager, sessions *auth.SessionStore, cfg *config.Config) {
+	// Avatar images are public so they can be used directly in <img> tags
+	router.GET("/avatars/:file", profileHandler.ServeAvatar)
+	
+	v1 := router.Group("/api/v1")
+	{
+		v1.Use(auth.CombinedAuth(keyManager, sessions))
+		v1.Use(middleware.RateLimit(&cfg.RateLimit))
+		v1.Use(middleware.Idempotency(&cfg.Idempotency))
+		
+		v1.GET("/me", profileHandler.GetMe)
+		v1.PATCH("/me", profileHandler.UpdateMe)
+		v1.POST("/me/avatar", profileHandler.UploadAvatar)
+		v1.DELETE("/me/avatar", profileHandler.DeleteAvatar)
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"log"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/sampling"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// createSamplingRuleRequest defines a sampling rule. SampleRate is required, as leaving it out
+// would drop every matching log.
+type createSamplingRuleRequest struct {
+	Service        string   `json:"service"`
+	Level          string   `json:"level"`
+	MessagePattern string   `json:"message_pattern"`
+	SampleRate     *float64 `json:"sample_rate" binding:"required"`
+	Description    string   `json:"description"`
+}
+
+// SetupSamplingRoutes configures sampling rule management routes
+func SetupSamplingRoutes(router *gin.Engine, repo *storage.Repository, engine *sampling.Engine, sessions *auth.SessionStore, cfg *config.Config) {
+	admin := router.Group("/admin/sampling-rules")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("", ListSamplingRules(engine))
+		admin.POST("", CreateSamplingRule(repo, engine))
+		admin.DELETE("/:id", DeleteSamplingRule(repo, engine))
+	}
+}
+
+// ListSamplingRules returns a handler for GET /admin/sampling-rules, which lists the rules in
+// the order they are checked, from configuration and the admin API, with how many logs each
+// matched and dropped on this instance
+func ListSamplingRules(engine *sampling.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, engine.Stats())
+	}
+}
+
+// CreateSamplingRule returns a handler for POST /admin/sampling-rules. Rules that do not
+// compile are rejected.
+func CreateSamplingRule(repo *storage.Repository, engine *sampling.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		var req createSamplingRuleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			problem.BadRequest(c, "Invalid request body", err)
+			return
+		}
+
+		rule := &models.SamplingRule{
+			Service:        strings.TrimSpace(req.Service),
+			Level:          strings.ToUpper(strings.TrimSpace(req.Level)),
+			MessagePattern: req.MessagePattern,
+			SampleRate:     *req.SampleRate,
+			Description:    strings.TrimSpace(req.Description),
+			CreatedBy:      actorID(c),
+		}
+		if err := sampling.Check(rule); err != nil {
+			problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid sampling rule", err)
+			return
+		}
+
+		ctx := c.Request.Context()
+		if err := repo.CreateSamplingRule(ctx, rule); err != nil {
+			problem.Internal(c, "Failed to create sampling rule", err)
+			return
+		}
+		if err := engine.Reload(ctx); err != nil {
+			log.Printf("WARN: Failed to reload sampling rules: %v", err)
+		}
+		log.Printf("INFO: Sampling rule %d added (service %q, level %q, message %q, rate %g)",
+			rule.ID, rule.Service, rule.Level, rule.MessagePattern, rule.SampleRate)
+		c.JSON(http.StatusCreated, rule)
+	}
+}
+
+// DeleteSamplingRule returns a handler for DELETE /admin/sampling-rules/:id
+func DeleteSamplingRule(repo *storage.Repository, engine *sampling.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !requireAdmin(c) {
+			return
+		}
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			problem.BadRequest(c, "Invalid sampling rule ID", nil)
+			return
+		}
+
+		ctx := c.Request.Context()
+		if err := repo.DeleteSamplingRule(ctx, id); err != nil {
+			if storage.IsNotFound(err) {
+				problem.NotFound(c, "Sampling rule not found", err)
+				return
+			}
+			problem.Internal(c, "Failed to delete sampling rule", err)
+			return
+		}
+		if err := engine.Reload(ctx); err != nil {
+			log.Printf("WARN: Failed to reload sampling rules: %v", err)
+		}
+		log.Printf("INFO: Sampling rule %d deleted", id)
+		c.Status(http.StatusNoContent)
+	}
+}
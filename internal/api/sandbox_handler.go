@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/seed"
+	"log-ingestion-service/internal/storage"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SeedSandbox handles POST /admin/sandbox/seed.
+// Omitted options fall back to the defaults of the seed command.
+func (h *AdminHandler) SeedSandbox(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	opts := seed.DefaultOptions()
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&opts); err != nil {
+			problem.BadRequest(c, "Invalid request body", err)
+			return
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid seed options", err)
+		return
+	}
+
+	summary, err := seed.NewSeeder(h.repository).Run(c.Request.Context(), opts)
+	if errors.Is(err, storage.ErrNotSandbox) {
+		problem.Respond(c, http.StatusConflict, problem.CodeConflict, "Project is not a sandbox",
+			fmt.Errorf("project %d already has faults and is not a sandbox", opts.ProjectID))
+		return
+	}
+	if err != nil {
+		log.Printf("ERROR: Failed to seed sandbox project %d: %v", opts.ProjectID, err)
+		problem.Internal(c, "Failed to seed sandbox", err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, summary)
+}
+
+// WipeSandbox handles DELETE /admin/sandbox?project_id=
+func (h *AdminHandler) WipeSandbox(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	projectID := seed.DefaultProjectID
+	if s := c.Query("project_id"); s != "" {
+		parsed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || parsed <= 0 {
+			problem.BadRequest(c, "Invalid project_id", nil)
+			return
+		}
+		projectID = parsed
+	}
+
+	wipe, err := seed.NewSeeder(h.repository).Wipe(c.Request.Context(), projectID)
+	if errors.Is(err, storage.ErrNotSandbox) {
+		problem.Respond(c, http.StatusConflict, problem.CodeConflict, "Project is not a sandbox",
+			fmt.Errorf("project %d was not seeded as a sandbox", projectID))
+		return
+	}
+	if err != nil {
+		problem.Internal(c, "Failed to wipe sandbox", err)
+		return
+	}
+	log.Printf("INFO: Wiped sandbox project %d", projectID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"project_id": projectID,
+		"wiped":      wipe,
+	})
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log-ingestion-service/internal/problem"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sdkAPIKey passes the API key an error-reporting SDK sends, in its own header or in a top-level
+// body field, on as the X-API-Key header checked by auth.APIKeyAuth. Bodies are limited to
+// maxBytes; a body read for its key is kept for the handler to bind with ShouldBindBodyWith.
+func sdkAPIKey(header, field string, maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes)
+		if c.GetHeader("X-API-Key") != "" {
+			c.Next()
+			return
+		}
+		if key := c.GetHeader(header); key != "" {
+			c.Request.Header.Set("X-API-Key", key)
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				problem.Respond(c, http.StatusRequestEntityTooLarge, problem.CodeInvalidRequest, "Request body too large", err)
+				return
+			}
+			problem.BadRequest(c, "Failed to read request body", err)
+			return
+		}
+		c.Set(gin.BodyBytesKey, body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		var fields map[string]json.RawMessage
+		var key string
+		if json.Unmarshal(body, &fields) == nil && json.Unmarshal(fields[field], &key) == nil && key != "" {
+			c.Request.Header.Set("X-API-Key", key)
+		}
+		c.Next()
+	}
+}
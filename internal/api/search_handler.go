@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/internal/highlight"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// searchMinLength is the shortest query searched; shorter ones match nearly everything
+	searchMinLength = 2
+	searchMaxLength = 200
+	// searchDefaultLimit and searchMaxLimit bound the hits returned of each kind
+	searchDefaultLimit = 5
+	searchMaxLimit     = 50
+	// searchDefaultRange is how far back notices and logs are searched by default
+	searchDefaultRange = 7 * 24 * time.Hour
+	// searchSnippetWidth is the length, in characters, that long text is cut to around a match
+	searchSnippetWidth = 160
+)
+
+// Search handles GET /api/v1/search, which searches faults, notices, comments and log
+// messages at once for a search box. Results are grouped by kind, newest first, each with a
+// snippet and the offsets of the matches in it and in its title. q is required; types limits
+// the kinds searched, limit the hits of each kind, and range, a duration, how far back notices
+// and logs are searched. Searching logs goes through the query guard.
+func (h *FaultHandler) Search(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	query := strings.TrimSpace(c.Query("q"))
+	if n := utf8.RuneCountInString(query); n < searchMinLength || n > searchMaxLength {
+		problem.BadRequest(c, "Invalid search query", fmt.Errorf("q must be %d to %d characters", searchMinLength, searchMaxLength))
+		return
+	}
+
+	kinds, err := parseSearchKinds(c.Query("types"))
+	if err != nil {
+		problem.BadRequest(c, "Invalid types parameter", err)
+		return
+	}
+
+	limit := searchDefaultLimit
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit < 1 || limit > searchMaxLimit {
+			problem.BadRequest(c, "Invalid limit parameter", fmt.Errorf("limit must be between 1 and %d", searchMaxLimit))
+			return
+		}
+	}
+
+	searchRange := searchDefaultRange
+	if rangeStr := c.Query("range"); rangeStr != "" {
+		searchRange, err = time.ParseDuration(rangeStr)
+		if err != nil || searchRange <= 0 {
+			problem.BadRequest(c, "Invalid range parameter", fmt.Errorf("range must be a positive duration, such as 24h"))
+			return
+		}
+	}
+
+	filters := storage.SearchFilters{Query: query, Since: time.Now().Add(-searchRange), Limit: limit + 1}
+	// Logs are the only kind large enough to need the guard; they are always searched last
+	if kinds[len(kinds)-1] == models.SearchLog {
+		done, ok := admitQuery(c, h.guard, h.repo, func(ctx context.Context) (*storage.QueryEstimate, error) {
+			return h.repo.EstimateLogs(ctx, storage.LogFilters{Search: query, From: filters.Since})
+		})
+		if !ok {
+			return
+		}
+		defer done()
+	}
+
+	groups := make([]models.SearchGroup, 0, len(kinds))
+	for _, kind := range kinds {
+		hits, err := h.repo.SearchRecords(ctx, kind, filters)
+		if err != nil {
+			problem.Internal(c, "Failed to search", err)
+			return
+		}
+		hits, hasMore := trimLookahead(hits, limit)
+		for i := range hits {
+			highlightHit(&hits[i], query)
+		}
+		groups = append(groups, models.SearchGroup{Type: kind, Hits: hits, HasMore: hasMore})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"query":  query,
+		"groups": groups,
+	})
+}
+
+// parseSearchKinds parses a comma-separated list of kinds to search, in any order, returning
+// them in grouping order. An empty list searches every kind.
+func parseSearchKinds(value string) ([]string, error) {
+	if value == "" {
+		return models.SearchKinds, nil
+	}
+	requested := make(map[string]bool)
+	for _, kind := range strings.Split(value, ",") {
+		kind = strings.TrimSpace(kind)
+		known := false
+		for _, k := range models.SearchKinds {
+			if kind == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown type %q, expected one of %s", kind, strings.Join(models.SearchKinds, ", "))
+		}
+		requested[kind] = true
+	}
+	var kinds []string
+	for _, k := range models.SearchKinds {
+		if requested[k] {
+			kinds = append(kinds, k)
+		}
+	}
+	return kinds, nil
+}
+
+// highlightHit cuts a hit's text to a snippet and records where the query matches it and its
+// title
+func highlightHit(hit *models.SearchHit, query string) {
+	hit.Highlights = appendHighlights([]models.Highlight{}, "title", hit.Title, query)
+	var spans []highlight.Span
+	hit.Snippet, spans = highlight.Snippet(hit.Snippet, query, searchSnippetWidth)
+	hit.Highlights = appendSpans(hit.Highlights, "snippet", spans)
+}
+
+// highlightFaults records where the text of a fault search matches each fault
+func highlightFaults(faults []models.Fault, text string) {
+	for i := range faults {
+		f := &faults[i]
+		f.Highlights = appendHighlights(nil, "error_class", f.ErrorClass, text)
+		f.Highlights = appendHighlights(f.Highlights, "message", f.Message, text)
+		if f.Location != nil {
+			f.Highlights = appendHighlights(f.Highlights, "location", *f.Location, text)
+		}
+	}
+}
+
+// appendHighlights appends where text occurs in a field's value
+func appendHighlights(highlights []models.Highlight, field, value, text string) []models.Highlight {
+	return appendSpans(highlights, field, highlight.Find(value, text))
+}
+
+func appendSpans(highlights []models.Highlight, field string, spans []highlight.Span) []models.Highlight {
+	for _, span := range spans {
+		highlights = append(highlights, models.Highlight{Field: field, Start: span.Start, End: span.End})
+	}
+	return highlights
+}
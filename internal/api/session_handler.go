@@ -0,0 +1,197 @@
+package api
+
+import (
+	"log"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/middleware"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionHandler lists and revokes login sessions. Users manage their own sessions; admins can
+// sign any user out.
+type SessionHandler struct {
+	repository *storage.Repository
+}
+
+// NewSessionHandler creates a session handler
+func NewSessionHandler(repository *storage.Repository) *SessionHandler {
+	return &SessionHandler{repository: repository}
+}
+
+// SetupSessionRoutes configures the session management routes
+func SetupSessionRoutes(router *gin.Engine, sessionHandler *SessionHandler, keyManager *auth.KeyManager, sessions *auth.SessionStore, cfg *config.Config) {
+	v1 := router.Group("/api/v1")
+	{
+		v1.Use(auth.CombinedAuth(keyManager, sessions))
+		v1.Use(middleware.RateLimit(&cfg.RateLimit))
+
+		v1.GET("/me/sessions", sessionHandler.ListMySessions)
+		v1.DELETE("/me/sessions", sessionHandler.RevokeMyOtherSessions)
+		v1.DELETE("/me/sessions/:id", sessionHandler.RevokeMySession)
+	}
+
+	admin := router.Group("/admin")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("/users/:id/sessions", sessionHandler.ListUserSessions)
+		admin.DELETE("/users/:id/sessions", sessionHandler.RevokeUserSessions)
+		admin.DELETE("/users/:id/sessions/:session_id", sessionHandler.RevokeUserSession)
+	}
+}
+
+// ListMySessions handles GET /api/v1/me/sessions. The session making the request is marked
+// current.
+func (h *SessionHandler) ListMySessions(c *gin.Context) {
+	userID, ok := h.requireUser(c)
+	if !ok {
+		return
+	}
+	h.respondSessions(c, userID)
+}
+
+// RevokeMyOtherSessions handles DELETE /api/v1/me/sessions, signing out every session but the
+// current one
+func (h *SessionHandler) RevokeMyOtherSessions(c *gin.Context) {
+	userID, ok := h.requireUser(c)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.repository.RevokeSessions(c.Request.Context(), userID, c.GetString("session_id"))
+	if err != nil {
+		problem.Internal(c, "Failed to revoke sessions", err)
+		return
+	}
+
+	log.Printf("INFO: User %d revoked %d other sessions", userID, revoked)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Other sessions signed out",
+		"revoked": revoked,
+	})
+}
+
+// RevokeMySession handles DELETE /api/v1/me/sessions/:id. Revoking the current session also
+// clears its cookie.
+func (h *SessionHandler) RevokeMySession(c *gin.Context) {
+	userID, ok := h.requireUser(c)
+	if !ok {
+		return
+	}
+
+	id := c.Param("id")
+	if err := h.repository.RevokeSession(c.Request.Context(), userID, id); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Session not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to revoke session", err)
+		return
+	}
+	if id == c.GetString("session_id") {
+		auth.ClearSessionCookie(c)
+	}
+
+	log.Printf("INFO: User %d revoked session %s", userID, id)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session signed out",
+	})
+}
+
+// ListUserSessions handles GET /admin/users/:id/sessions
+func (h *SessionHandler) ListUserSessions(c *gin.Context) {
+	userID, ok := h.adminTarget(c)
+	if !ok {
+		return
+	}
+	h.respondSessions(c, userID)
+}
+
+// RevokeUserSessions handles DELETE /admin/users/:id/sessions, signing the user out everywhere
+func (h *SessionHandler) RevokeUserSessions(c *gin.Context) {
+	userID, ok := h.adminTarget(c)
+	if !ok {
+		return
+	}
+
+	revoked, err := h.repository.RevokeSessions(c.Request.Context(), userID, "")
+	if err != nil {
+		problem.Internal(c, "Failed to revoke sessions", err)
+		return
+	}
+
+	log.Printf("INFO: Admin %d revoked %d sessions of user %d", c.GetInt64("user_id"), revoked, userID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User signed out",
+		"revoked": revoked,
+	})
+}
+
+// RevokeUserSession handles DELETE /admin/users/:id/sessions/:session_id
+func (h *SessionHandler) RevokeUserSession(c *gin.Context) {
+	userID, ok := h.adminTarget(c)
+	if !ok {
+		return
+	}
+
+	id := c.Param("session_id")
+	if err := h.repository.RevokeSession(c.Request.Context(), userID, id); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Session not found", err)
+			return
+		}
+		problem.Internal(c, "Failed to revoke session", err)
+		return
+	}
+
+	log.Printf("INFO: Admin %d revoked session %s of user %d", c.GetInt64("user_id"), id, userID)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session signed out",
+	})
+}
+
+// respondSessions responds with a user's active sessions
+func (h *SessionHandler) respondSessions(c *gin.Context, userID int64) {
+	sessions, err := h.repository.ListSessions(c.Request.Context(), userID)
+	if err != nil {
+		problem.Internal(c, "Failed to list sessions", err)
+		return
+	}
+
+	current := c.GetString("session_id")
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+	})
+}
+
+// requireUser returns the signed-in user, answering 403 for API key requests
+func (h *SessionHandler) requireUser(c *gin.Context) (int64, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		problem.Respond(c, http.StatusForbidden, problem.CodeForbidden, "Sessions require a user session", nil)
+	}
+	return userID, ok
+}
+
+// adminTarget checks the caller is an admin and returns the user named by the :id parameter
+func (h *SessionHandler) adminTarget(c *gin.Context) (int64, bool) {
+	if !requireAdmin(c) {
+		return 0, false
+	}
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid user ID", nil)
+		return 0, false
+	}
+	return userID, true
+}
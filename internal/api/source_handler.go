@@ -0,0 +1,130 @@
+package api
+
+import (
+	"fmt"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/sources"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Sources returned by GET /admin/sources
+const (
+	defaultSourceLimit = 100
+	maxSourceLimit     = 1000
+)
+
+// sourceResponse is one source in GET /admin/sources
+type sourceResponse struct {
+	// APIKeyID is nil for syslog listeners and for keys deleted since the logs arrived
+	APIKeyID   *int64 `json:"api_key_id"`
+	APIKeyName string `json:"api_key_name,omitempty"`
+	Listener   string `json:"listener,omitempty"`
+	Service    string `json:"service"`
+	sources.Stats
+}
+
+// SetupSourceRoutes configures the ingestion source health route
+func SetupSourceRoutes(router *gin.Engine, tracker *sources.Tracker, repo *storage.Repository, sessions *auth.SessionStore, cfg *config.Config) {
+	admin := router.Group("/admin/sources")
+	{
+		admin.Use(auth.JWTAuth(sessions))
+
+		admin.GET("", ListSources(tracker, repo))
+	}
+}
+
+// ListSources returns a handler for GET /admin/sources. It summarizes ingestion per API key or
+// syslog listener and service, those rejecting the most logs per minute first. Non-admins see only
+// sources using keys they created. ?api_key_id= and ?service= filter, ?limit= caps the result.
+func ListSources(tracker *sources.Tracker, repo *storage.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := defaultSourceLimit
+		if s := c.Query("limit"); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 || n > maxSourceLimit {
+				problem.BadRequest(c, "Invalid limit", fmt.Errorf("limit must be between 1 and %d", maxSourceLimit))
+				return
+			}
+			limit = n
+		}
+		var keyID *int64
+		if s := c.Query("api_key_id"); s != "" {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				problem.BadRequest(c, "Invalid api_key_id", err)
+				return
+			}
+			keyID = &id
+		}
+		service, filterService := c.GetQuery("service")
+
+		var userID *int64
+		if isAdmin, _ := c.Get("is_admin"); isAdmin != true {
+			uid, _ := c.Get("user_id")
+			id, _ := uid.(int64)
+			userID = &id
+		}
+
+		stats := tracker.Snapshot()
+		values := make([]string, 0)
+		seen := make(map[string]bool)
+		for _, s := range stats {
+			if s.APIKey != "" && !seen[s.APIKey] {
+				seen[s.APIKey] = true
+				values = append(values, s.APIKey)
+			}
+		}
+		keys, err := repo.GetAPIKeysByValue(c.Request.Context(), values)
+		if err != nil {
+			problem.Internal(c, "Failed to list sources", err)
+			return
+		}
+
+		list := make([]sourceResponse, 0)
+		var accepted, rejected, rejectedBytes int64
+		for _, s := range stats {
+			r := sourceResponse{Listener: s.Listener, Service: s.Service, Stats: s}
+			if key, ok := keys[s.APIKey]; ok {
+				id := key.ID
+				r.APIKeyID = &id
+				r.APIKeyName = key.Name
+				if userID != nil && (key.CreatedByUserID == nil || *key.CreatedByUserID != *userID) {
+					continue
+				}
+			} else if userID != nil {
+				continue
+			}
+			if keyID != nil && (r.APIKeyID == nil || *r.APIKeyID != *keyID) {
+				continue
+			}
+			if filterService && s.Service != service {
+				continue
+			}
+
+			accepted += s.Accepted
+			rejected += s.Rejected
+			rejectedBytes += s.RejectedBytes
+			if len(list) < limit {
+				list = append(list, r)
+			}
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"sources": list,
+			"totals": gin.H{
+				"accepted":       accepted,
+				"rejected":       rejected,
+				"rejected_bytes": rejectedBytes,
+			},
+			"since":        tracker.Started().UTC(),
+			"generated_at": time.Now().UTC(),
+		})
+	}
+}
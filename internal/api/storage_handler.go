@@ -0,0 +1,128 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/internal/problem"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Weeks of growth reported by GET /admin/storage
+const (
+	defaultStorageWeeks = 8
+	maxStorageWeeks     = 52
+)
+
+// StorageUsage handles GET /admin/storage. It reports the size of each table, an estimate per
+// project, and how much logs and notices grew in each recent week. When the database capacity is
+// known (database.capacity_bytes or ?capacity_bytes=) it projects when the disk fills at the
+// average weekly growth. Reports are served from the analytics cache.
+func (h *AdminHandler) StorageUsage(c *gin.Context) {
+	if !requireAdmin(c) {
+		return
+	}
+	weeks := defaultStorageWeeks
+	if s := c.Query("weeks"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxStorageWeeks {
+			problem.BadRequest(c, "Invalid weeks", fmt.Errorf("weeks must be between 1 and %d", maxStorageWeeks))
+			return
+		}
+		weeks = n
+	}
+	capacity := h.config.Database.CapacityBytes
+	if s := c.Query("capacity_bytes"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			problem.BadRequest(c, "Invalid capacity_bytes", fmt.Errorf("capacity_bytes must be a non-negative number of bytes"))
+			return
+		}
+		capacity = n
+	}
+
+	usage, err := cachedAnalytics(c, h.analytics, func(ctx context.Context) (interface{}, error) {
+		return h.storageUsage(ctx, weeks, capacity)
+	})
+	if err != nil {
+		problem.Internal(c, "Failed to get storage usage", err)
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+// storageUsage computes the storage usage report over the last weeks
+func (h *AdminHandler) storageUsage(ctx context.Context, weeks int, capacity int64) (gin.H, error) {
+	timescale, err := h.repository.HasTimescaleDB(ctx)
+	if err != nil {
+		return nil, err
+	}
+	databaseBytes, err := h.repository.GetDatabaseSize(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := h.repository.GetTableUsage(ctx, timescale)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := h.repository.GetProjectUsage(ctx, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	// The current week is partial, so the rate uses the complete weeks before it
+	since := now.AddDate(0, 0, -7*weeks)
+	growth, err := h.repository.GetWeeklyGrowth(ctx, since, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	var perWeek *int64
+	if complete := len(growth) - 1; complete > 0 {
+		var total int64
+		for _, w := range growth[:complete] {
+			total += w.Bytes
+		}
+		avg := total / int64(complete)
+		perWeek = &avg
+	}
+
+	response := gin.H{
+		"generated_at":   now,
+		"timescaledb":    timescale,
+		"database_bytes": databaseBytes,
+		"tables":         tables,
+		"projects":       projects,
+		"growth": gin.H{
+			"weeks":          growth,
+			"bytes_per_week": perWeek,
+		},
+	}
+	if capacity > 0 {
+		response["capacity_bytes"] = capacity
+		response["projected_full_at"] = projectStorageFull(now, capacity, databaseBytes, perWeek)
+	}
+	return response, nil
+}
+
+// projectStorageFull returns when used bytes reach capacity at perWeek bytes a week, now if they
+// already have, or nil if usage is not growing
+func projectStorageFull(now time.Time, capacity, used int64, perWeek *int64) *time.Time {
+	if used >= capacity {
+		return &now
+	}
+	if perWeek == nil || *perWeek <= 0 {
+		return nil
+	}
+	weeks := float64(capacity-used) / float64(*perWeek)
+	// Beyond roughly a century the projection is meaningless and would overflow a Duration
+	if weeks > 5000 {
+		return nil
+	}
+	full := now.Add(time.Duration(weeks * float64(7*24*time.Hour))).Truncate(time.Hour)
+	return &full
+}
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/sources"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/websocket"
+)
+
+// StreamPath is the route of the WebSocket log stream
+const StreamPath = "/api/v1/logs/stream"
+
+// Stream tuning: acks are sent at most once per streamAckInterval, connections without a frame
+// for streamIdleTimeout are closed, and frames are limited like raw ingestion bodies
+const (
+	streamAckInterval  = time.Second
+	streamIdleTimeout  = 5 * time.Minute
+	streamWriteTimeout = 10 * time.Second
+	maxStreamFrameSize = maxRawBodyBytes
+)
+
+// streamPausedRetryAfter is the wait suggested to stream clients while ingestion is paused
+const streamPausedRetryAfter = 30 * time.Second
+
+// errStreamPaused is sent to stream clients while the ingestion pipeline is paused
+var errStreamPaused = errors.New("ingestion is paused by an administrator")
+
+// Types of the messages the server sends on a log stream
+const (
+	streamAck   = "ack"
+	streamError = "error"
+)
+
+// streamMessage is a message sent by the server on a log stream. An ack reports the offset of
+// the last entry handled, and the entries accepted and rejected so far. An error reports a
+// rejected entry, or with retry_after set, why the server is closing the stream.
+type streamMessage struct {
+	Type       string `json:"type"`
+	Offset     int64  `json:"offset"`
+	Accepted   int64  `json:"accepted,omitempty"`
+	Rejected   int64  `json:"rejected,omitempty"`
+	Error      string `json:"error,omitempty"`
+	RetryAfter int    `json:"retry_after,omitempty"`
+}
+
+// logStream is the state of one stream connection. Offsets number the entries received on the
+// connection from 1.
+type logStream struct {
+	h   *Handler
+	c   *gin.Context
+	ws  *websocket.Conn
+	rec *sources.Recorder
+	mu  sync.Mutex
+	wmu sync.Mutex
+
+	offset   int64
+	acked    int64
+	accepted int64
+	rejected int64
+}
+
+// StreamLogs handles GET /api/v1/logs/stream. After the WebSocket upgrade the client sends text
+// frames, each a log object, a log line as a string, or an array of either. Entries go through
+// the same decoding, validation and sanitizing as POST /api/v1/logs/batch. Once a second, when
+// entries were handled, the server acks the offset of the last one. If logs cannot be buffered
+// or ingestion is paused, the server sends an error with retry_after and closes the stream;
+// entries after the last ack should be sent again on a new connection.
+func (h *Handler) StreamLogs(c *gin.Context) {
+	if !c.IsWebsocket() {
+		problem.Respond(c, http.StatusUpgradeRequired, problem.CodeInvalidRequest, "WebSocket upgrade required", nil)
+		return
+	}
+	if h.isPaused() {
+		problem.RespondRetry(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "Ingestion paused", errStreamPaused, streamPausedRetryAfter)
+		return
+	}
+
+	server := websocket.Server{
+		// Streams are authenticated by API key rather than cookies, so cross-origin browser
+		// clients are allowed
+		Handshake: func(*websocket.Config, *http.Request) error { return nil },
+		Handler: func(ws *websocket.Conn) {
+			ws.MaxPayloadBytes = maxStreamFrameSize
+			s := &logStream{h: h, c: c, ws: ws, rec: h.recordSources(c)}
+			s.serve()
+		},
+	}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// streamKeyFromQuery lets browser clients, which cannot set headers on a WebSocket upgrade,
+// pass their API key as ?api_key=. A key in the headers takes precedence.
+func streamKeyFromQuery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key := c.Query("api_key"); key != "" && c.GetHeader("X-API-Key") == "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("X-API-Key", key)
+		}
+		c.Next()
+	}
+}
+
+// PauseWhen makes log streams turn entries away while paused reports true. HTTP ingestion is
+// paused by middleware instead, since a stream outlives the request that opened it.
+func (h *Handler) PauseWhen(paused func() bool) {
+	h.paused = paused
+}
+
+func (h *Handler) isPaused() bool {
+	return h.paused != nil && h.paused()
+}
+
+// serve reads frames until the client closes the stream or the stream fails, acking in the
+// background
+func (s *logStream) serve() {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(streamAckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := s.ack(); err != nil {
+					s.ws.Close()
+					return
+				}
+			}
+		}
+	}()
+	defer func() {
+		close(done)
+		wg.Wait()
+		s.ack()
+	}()
+
+	for {
+		s.ws.SetReadDeadline(time.Now().Add(streamIdleTimeout))
+		var frame []byte
+		if err := websocket.Message.Receive(s.ws, &frame); err != nil {
+			if errors.Is(err, websocket.ErrFrameTooLarge) {
+				s.rejectFrame(nil, fmt.Errorf("frame is larger than %d bytes", maxStreamFrameSize))
+				continue
+			}
+			return
+		}
+		if err := s.handle(frame); err != nil {
+			return
+		}
+	}
+}
+
+// handle ingests the entries of one frame. It returns an error when the stream should close.
+func (s *logStream) handle(frame []byte) error {
+	if s.h.isPaused() {
+		return s.fail(errStreamPaused, streamPausedRetryAfter)
+	}
+	entries, err := decodeStreamFrame(frame)
+	if err != nil {
+		s.rejectFrame(frame, err)
+		return nil
+	}
+
+	// Only this goroutine moves the offset; it is published once the frame is handled
+	first := s.offset + 1
+	var rejections []streamMessage
+	valid := make([]models.LogEntry, 0, len(entries))
+	for i, raw := range entries {
+		entry, err := s.h.decodeLog(s.c, raw)
+		if err == nil {
+			err = s.h.validator.ValidateFrom(entry, s.c.GetString("api_key"))
+		}
+		if err != nil {
+			service := ""
+			if entry != nil {
+				service = entry.Service
+			}
+			s.mu.Lock()
+			s.rec.Reject(service, raw, err)
+			s.mu.Unlock()
+			rejections = append(rejections, streamMessage{Type: streamError, Offset: first + int64(i), Error: err.Error()})
+			continue
+		}
+		s.h.validator.Sanitize(entry)
+		valid = append(valid, *entry)
+	}
+
+	if len(valid) > 0 {
+		if err := s.h.batcher.AddBatch(valid); err != nil {
+			// Nothing from this frame is acked, so the client sends it again
+			return s.fail(err, overloadRetryAfter)
+		}
+	}
+
+	s.mu.Lock()
+	s.offset += int64(len(entries))
+	for i := range valid {
+		s.rec.Accept(valid[i].Service)
+	}
+	s.accepted += int64(len(valid))
+	s.rejected += int64(len(rejections))
+	s.mu.Unlock()
+
+	for _, msg := range rejections {
+		if err := s.send(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rejectFrame rejects a frame that could not be decoded. It takes one offset.
+func (s *logStream) rejectFrame(frame []byte, err error) {
+	s.mu.Lock()
+	s.offset++
+	s.rejected++
+	offset := s.offset
+	s.rec.RejectBody(frame, err)
+	s.mu.Unlock()
+	s.send(streamMessage{Type: streamError, Offset: offset, Error: err.Error()})
+}
+
+// fail acks what was handled, then tells the client why the stream is closing and when to retry
+func (s *logStream) fail(err error, retryAfter time.Duration) error {
+	if ackErr := s.ack(); ackErr != nil {
+		return ackErr
+	}
+	s.mu.Lock()
+	offset := s.acked
+	s.mu.Unlock()
+	s.send(streamMessage{Type: streamError, Offset: offset, Error: err.Error(), RetryAfter: int(retryAfter / time.Second)})
+	return err
+}
+
+// ack sends the offset of the last entry handled, if it moved since the last ack, and adds
+// the stream's counts to the source tracker
+func (s *logStream) ack() error {
+	s.mu.Lock()
+	s.rec.Flush()
+	if s.offset == s.acked {
+		s.mu.Unlock()
+		return nil
+	}
+	msg := streamMessage{Type: streamAck, Offset: s.offset, Accepted: s.accepted, Rejected: s.rejected}
+	s.acked = s.offset
+	s.mu.Unlock()
+	return s.send(msg)
+}
+
+// send writes a message to the client. Acks and errors are written from different goroutines.
+func (s *logStream) send(msg streamMessage) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	s.ws.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+	if err := websocket.JSON.Send(s.ws, msg); err != nil {
+		log.Printf("WARN: Closing log stream from %s: %v", s.c.ClientIP(), err)
+		return err
+	}
+	return nil
+}
+
+// decodeStreamFrame splits a frame into its entries: a JSON array, or a single log object or
+// string
+func decodeStreamFrame(frame []byte) ([]json.RawMessage, error) {
+	trimmed := strings.TrimSpace(string(frame))
+	if trimmed == "" {
+		return nil, fmt.Errorf("empty frame")
+	}
+	if strings.HasPrefix(trimmed, "[") {
+		var entries []json.RawMessage
+		if err := json.Unmarshal([]byte(trimmed), &entries); err != nil {
+			return nil, fmt.Errorf("invalid JSON array: %w", err)
+		}
+		return entries, nil
+	}
+	if !json.Valid([]byte(trimmed)) {
+		return nil, fmt.Errorf("frame is not valid JSON")
+	}
+	return []json.RawMessage{json.RawMessage(trimmed)}, nil
+}
@@ -0,0 +1,273 @@
+package api
+
+import (
+	"errors"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/middleware"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/internal/subscription"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultSubscriptionResults is the number of results delivered when max_results is not set
+const defaultSubscriptionResults = 100
+
+// SubscriptionHandler manages scheduled query subscriptions. Subscriptions belong to the user
+// who created them; admins can see and change everyone's.
+type SubscriptionHandler struct {
+	repo      *storage.Repository
+	scheduler *subscription.Scheduler
+}
+
+// NewSubscriptionHandler creates a subscription handler
+func NewSubscriptionHandler(repo *storage.Repository, scheduler *subscription.Scheduler) *SubscriptionHandler {
+	return &SubscriptionHandler{repo: repo, scheduler: scheduler}
+}
+
+// subscriptionRequest creates a subscription, or partially updates one. Omitted fields keep
+// their current value, or their default on create.
+type subscriptionRequest struct {
+	Name       *string          `json:"name"`
+	Kind       *string          `json:"kind"`
+	Query      *string          `json:"query"`
+	Schedule   *models.Schedule `json:"schedule"`
+	Channel    *string          `json:"channel"`
+	Target     *string          `json:"target"`
+	MaxResults *int             `json:"max_results"`
+	Enabled    *bool            `json:"enabled"`
+}
+
+// SetupSubscriptionRoutes configures the scheduled query subscription routes
+func SetupSubscriptionRoutes(router *gin.Engine, subscriptionHandler *SubscriptionHandler, keyManager *auth.KeyManager, sessions *auth.SessionStore, cfg *config.Config) {
+	v1 := router.Group("/api/v1")
+	{
+		v1.Use(auth.CombinedAuth(keyManager, sessions))
+		v1.Use(middleware.RateLimit(&cfg.RateLimit))
+		v1.Use(middleware.Idempotency(&cfg.Idempotency))
+
+		v1.GET("/subscriptions", subscriptionHandler.ListSubscriptions)
+		v1.POST("/subscriptions", subscriptionHandler.CreateSubscription)
+		v1.GET("/subscriptions/:id", subscriptionHandler.GetSubscription)
+		v1.PATCH("/subscriptions/:id", subscriptionHandler.UpdateSubscription)
+		v1.DELETE("/subscriptions/:id", subscriptionHandler.DeleteSubscription)
+		v1.POST("/subscriptions/:id/run", subscriptionHandler.RunSubscription)
+	}
+}
+
+// ListSubscriptions handles GET /api/v1/subscriptions
+func (h *SubscriptionHandler) ListSubscriptions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		problem.Respond(c, http.StatusForbidden, problem.CodeForbidden, "Subscriptions require a user session", nil)
+		return
+	}
+	var owner *int64
+	if isAdmin, _ := c.Get("is_admin"); isAdmin != true {
+		owner = &userID
+	}
+	subscriptions, err := h.repo.ListSubscriptions(c.Request.Context(), owner)
+	if err != nil {
+		problem.Internal(c, "Failed to list subscriptions", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subscriptions})
+}
+
+// CreateSubscription handles POST /api/v1/subscriptions. The schedule's timezone defaults to
+// the user's preferred timezone, and an email subscription's target to the user's address.
+func (h *SubscriptionHandler) CreateSubscription(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		problem.Respond(c, http.StatusForbidden, problem.CodeForbidden, "Subscriptions require a user session", nil)
+		return
+	}
+	var req subscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+
+	ctx := c.Request.Context()
+	profile, err := h.repo.GetUserProfile(ctx, userID)
+	if err != nil {
+		problem.Internal(c, "Failed to create subscription", err)
+		return
+	}
+
+	sub := &models.Subscription{
+		UserID:     userID,
+		Schedule:   models.Schedule{Timezone: profile.Preferences.Timezone},
+		MaxResults: defaultSubscriptionResults,
+		Enabled:    true,
+	}
+	if sub.Schedule.Timezone == "" {
+		sub.Schedule.Timezone = "UTC"
+	}
+	req.apply(sub)
+	if sub.Channel == models.ChannelEmail && sub.Target == "" {
+		sub.Target = profile.Email
+	}
+	if err := h.scheduler.Validate(ctx, sub); err != nil {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid subscription", err)
+		return
+	}
+	sub.NextRunAt = sub.Schedule.Next(time.Now())
+
+	if err := h.repo.CreateSubscription(ctx, sub); err != nil {
+		problem.Internal(c, "Failed to create subscription", err)
+		return
+	}
+	c.JSON(http.StatusCreated, sub)
+}
+
+// GetSubscription handles GET /api/v1/subscriptions/:id
+func (h *SubscriptionHandler) GetSubscription(c *gin.Context) {
+	sub, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// UpdateSubscription handles PATCH /api/v1/subscriptions/:id. A changed schedule takes effect
+// from its next run after now.
+func (h *SubscriptionHandler) UpdateSubscription(c *gin.Context) {
+	sub, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+	var req subscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.BadRequest(c, "Invalid request body", err)
+		return
+	}
+
+	enabled := sub.Enabled
+	req.apply(sub)
+	if err := h.scheduler.Validate(c.Request.Context(), sub); err != nil {
+		problem.Respond(c, http.StatusUnprocessableEntity, problem.CodeValidationFailed, "Invalid subscription", err)
+		return
+	}
+	if req.Schedule != nil || (sub.Enabled && !enabled) {
+		sub.NextRunAt = sub.Schedule.Next(time.Now())
+	}
+
+	if err := h.repo.UpdateSubscription(c.Request.Context(), sub); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Subscription not found", nil)
+			return
+		}
+		problem.Internal(c, "Failed to update subscription", err)
+		return
+	}
+	c.JSON(http.StatusOK, sub)
+}
+
+// DeleteSubscription handles DELETE /api/v1/subscriptions/:id
+func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
+	sub, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+	if err := h.repo.DeleteSubscription(c.Request.Context(), sub.ID); err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Subscription not found", nil)
+			return
+		}
+		problem.Internal(c, "Failed to delete subscription", err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RunSubscription handles POST /api/v1/subscriptions/:id/run. The query runs over the last
+// period of the schedule, up to now, and is delivered as usual; the schedule is not changed.
+// It returns 202 with the job.
+func (h *SubscriptionHandler) RunSubscription(c *gin.Context) {
+	sub, ok := h.loadSubscription(c)
+	if !ok {
+		return
+	}
+	job, err := h.scheduler.RunNow(c.Request.Context(), sub, actorID(c))
+	if err != nil {
+		if errors.Is(err, storage.ErrJobExists) {
+			problem.Respond(c, http.StatusConflict, problem.CodeConflict, "Subscription run already in progress", err)
+			return
+		}
+		problem.Internal(c, "Failed to run subscription", err)
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// loadSubscription reads the subscription named in the path, responding 404 when it does not
+// exist or belongs to another user
+func (h *SubscriptionHandler) loadSubscription(c *gin.Context) (*models.Subscription, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		problem.Respond(c, http.StatusForbidden, problem.CodeForbidden, "Subscriptions require a user session", nil)
+		return nil, false
+	}
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.BadRequest(c, "Invalid subscription ID", nil)
+		return nil, false
+	}
+	sub, err := h.repo.GetSubscription(c.Request.Context(), id)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			problem.NotFound(c, "Subscription not found", nil)
+			return nil, false
+		}
+		problem.Internal(c, "Failed to get subscription", err)
+		return nil, false
+	}
+	if isAdmin, _ := c.Get("is_admin"); isAdmin != true && sub.UserID != userID {
+		problem.NotFound(c, "Subscription not found", nil)
+		return nil, false
+	}
+	return sub, true
+}
+
+// apply copies the request's fields onto a subscription
+func (req *subscriptionRequest) apply(sub *models.Subscription) {
+	if req.Name != nil {
+		sub.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Kind != nil {
+		sub.Kind = *req.Kind
+	}
+	if req.Query != nil {
+		sub.Query = strings.TrimSpace(*req.Query)
+	}
+	if req.Schedule != nil {
+		timezone := sub.Schedule.Timezone
+		sub.Schedule = *req.Schedule
+		if sub.Schedule.Timezone == "" {
+			sub.Schedule.Timezone = timezone
+		}
+		if sub.Schedule.At == "" {
+			sub.Schedule.At = "00:00"
+		}
+	}
+	if req.Channel != nil {
+		sub.Channel = *req.Channel
+	}
+	if req.Target != nil {
+		sub.Target = strings.TrimSpace(*req.Target)
+	}
+	if req.MaxResults != nil {
+		sub.MaxResults = *req.MaxResults
+	}
+	if req.Enabled != nil {
+		sub.Enabled = *req.Enabled
+	}
+}
@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/problem"
 	"log-ingestion-service/pkg/config"
 	"net/http"
 	"strings"
@@ -38,16 +41,20 @@ func AdminAuth(cfg *config.AuthConfig) gin.HandlerFunc {
 			}
 		}
 		
-		// Also try query parameter for web interface convenience
+		// Also try query parameter for web interface convenience.
+		// Deprecated: credentials in URLs leak into logs; use /admin/login sessions instead.
 		if apiKey == "" {
-			apiKey = c.Query("api_key")
+			if apiKey = c.Query("api_key"); apiKey != "" {
+				markDeprecatedCredential(c, "?api_key= query parameter")
+			}
 		}
 		
-		// Check cookie for API key
+		// Check cookie for API key (deprecated in favour of session cookies)
 		if apiKey == "" {
 			cookieKey, err := c.Cookie("admin_api_key")
 			if err == nil && cookieKey != "" {
 				apiKey = cookieKey
+				markDeprecatedCredential(c, "admin_api_key cookie")
 			}
 		}
 		
@@ -61,12 +68,10 @@ func AdminAuth(cfg *config.AuthConfig) gin.HandlerFunc {
 			if wantsHTML {
 				c.HTML(http.StatusUnauthorized, "error.html", gin.H{
 					"error": "Admin API key is required",
-					"message": "Please provide an admin API key via X-API-Key header, Authorization header, ?api_key= query parameter, or login at /admin/login",
+					"message": "Please provide an admin API key via X-API-Key header or Authorization header, or login at /admin/login",
 				})
 			} else {
-				c.JSON(http.StatusUnauthorized, gin.H{
-					"error": "Admin API key is required",
-				})
+				problem.Respond(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Admin API key is required", nil)
 			}
 			c.Abort()
 			return
@@ -88,9 +93,7 @@ func AdminAuth(cfg *config.AuthConfig) gin.HandlerFunc {
 					"message": "The provided admin API key is not valid",
 				})
 			} else {
-				c.JSON(http.StatusForbidden, gin.H{
-					"error": "Invalid admin API key",
-				})
+				problem.Respond(c, http.StatusForbidden, problem.CodeForbidden, "Invalid admin API key", nil)
 			}
 			c.Abort()
 			return
@@ -102,3 +105,10 @@ func AdminAuth(cfg *config.AuthConfig) gin.HandlerFunc {
 	}
 }
 
+
+// markDeprecatedCredential flags a response that authenticated via a deprecated mechanism
+func markDeprecatedCredential(c *gin.Context, mechanism string) {
+	log.Printf("WARN: admin request to %s authenticated via deprecated %s", c.Request.URL.Path, mechanism)
+	c.Header("Deprecation", "true")
+	c.Header("Warning", fmt.Sprintf(`299 - "Authentication via %s is deprecated; use /admin/login"`, mechanism))
+}
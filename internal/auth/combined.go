@@ -1,17 +1,17 @@
 package auth
 
 import (
-	"fmt"
+	"log-ingestion-service/internal/problem"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 )
 
 // CombinedAuth middleware accepts either a valid API key OR a valid JWT token.
 // This allows the frontend (JWT) and external services (API key) to both access /api/v1/* routes.
-func CombinedAuth(keyManager *KeyManager, jwtSecret string) gin.HandlerFunc {
+// JWTs are accepted only while their session is active.
+func CombinedAuth(keyManager *KeyManager, sessions *SessionStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Extract token/key from headers
 		apiKey := c.GetHeader("X-API-Key")
@@ -27,11 +27,8 @@ func CombinedAuth(keyManager *KeyManager, jwtSecret string) gin.HandlerFunc {
 			}
 		}
 
-		// Also try auth_token cookie (for frontend)
-		cookieToken := ""
-		if cookie, err := c.Cookie("auth_token"); err == nil && cookie != "" {
-			cookieToken = cookie
-		}
+		// Also try session cookie (for frontend)
+		cookieToken := sessionTokenFromCookie(c)
 
 		// Strategy 1: Try API key auth first (X-API-Key header)
 		if apiKey != "" {
@@ -50,28 +47,14 @@ func CombinedAuth(keyManager *KeyManager, jwtSecret string) gin.HandlerFunc {
 		}
 
 		if tokenString != "" {
-			claims := &JWTClaims{}
-			token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-				}
-				return []byte(jwtSecret), nil
-			})
-
-			if err == nil && token.Valid {
-				c.Set("user_id", claims.UserID)
-				c.Set("user_email", claims.UserEmail)
-				c.Set("user_name", claims.UserName)
-				c.Set("is_admin", claims.IsAdmin)
+			if claims, err := sessions.Authenticate(c, tokenString); err == nil {
+				setClaims(c, claims)
 				c.Next()
 				return
 			}
 		}
 
 		// Neither auth method succeeded
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error": "Valid API key or authentication token required",
-		})
-		c.Abort()
+		problem.Respond(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Valid API key or authentication token required", nil)
 	}
 }
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"fmt"
+	"log-ingestion-service/internal/problem"
 	"net/http"
 	"strings"
 	"time"
@@ -10,6 +11,9 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// SessionDuration is how long an issued token and its session cookie remain valid
+const SessionDuration = 24 * time.Hour
+
 // JWTClaims represents the claims stored in a JWT token
 type JWTClaims struct {
 	UserID    int64  `json:"user_id"`
@@ -19,17 +23,18 @@ type JWTClaims struct {
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT creates a new JWT token for a user
-func GenerateJWT(secret string, userID int64, email, name string, isAdmin bool) (string, error) {
+// GenerateJWT creates a new JWT token for a user's session. The session ID is the token ID.
+func GenerateJWT(secret, sessionID string, userID int64, email, name string, isAdmin bool) (string, error) {
 	claims := JWTClaims{
 		UserID:    userID,
 		UserEmail: email,
 		UserName:  name,
 		IsAdmin:   isAdmin,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(SessionDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "cmd-log",
+			ID:        sessionID,
 		},
 	}
 
@@ -42,8 +47,9 @@ func GenerateJWT(secret string, userID int64, email, name string, isAdmin bool)
 	return tokenString, nil
 }
 
-// JWTAuth middleware validates JWT tokens from Authorization header or auth_token cookie
-func JWTAuth(secret string) gin.HandlerFunc {
+// JWTAuth middleware validates JWT tokens from Authorization header or session cookie,
+// accepting them only while their session is active
+func JWTAuth(sessions *SessionStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := ""
 
@@ -61,43 +67,24 @@ func JWTAuth(secret string) gin.HandlerFunc {
 			tokenString = c.GetHeader("X-API-Key")
 		}
 
-		// Try auth_token cookie
+		// Try session cookie
 		if tokenString == "" {
-			if cookie, err := c.Cookie("auth_token"); err == nil && cookie != "" {
-				tokenString = cookie
-			}
+			tokenString = sessionTokenFromCookie(c)
 		}
 
 		if tokenString == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Authentication required",
-			})
-			c.Abort()
+			problem.Respond(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Authentication required", nil)
 			return
 		}
 
-		// Parse and validate the token
-		claims := &JWTClaims{}
-		token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(secret), nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid or expired token",
-			})
-			c.Abort()
+		claims, err := sessions.Authenticate(c, tokenString)
+		if err != nil {
+			problem.Respond(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid or expired token", nil)
 			return
 		}
 
 		// Set user info in context
-		c.Set("user_id", claims.UserID)
-		c.Set("user_email", claims.UserEmail)
-		c.Set("user_name", claims.UserName)
-		c.Set("is_admin", claims.IsAdmin)
+		setClaims(c, claims)
 		c.Next()
 	}
 }
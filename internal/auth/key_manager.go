@@ -30,6 +30,16 @@ func (km *KeyManager) ValidateKey(ctx context.Context, apiKey string) bool {
 	return exists
 }
 
+// KeyParsing returns the parser and timestamp formats configured for an API key; an empty
+// parser selects one automatically
+func (km *KeyManager) KeyParsing(ctx context.Context, apiKey string) storage.APIKeyParsing {
+	parsing, err := km.repository.GetAPIKeyParsing(ctx, apiKey)
+	if err != nil {
+		return storage.APIKeyParsing{}
+	}
+	return parsing
+}
+
 // GetKeys returns all valid API keys (for admin purposes)
 func (km *KeyManager) GetKeys(ctx context.Context) ([]string, error) {
 	return km.repository.GetAllActiveAPIKeys(ctx)
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"log-ingestion-service/internal/problem"
 	"net/http"
 	"strings"
 
@@ -26,10 +27,7 @@ func APIKeyAuth(keyManager *KeyManager) gin.HandlerFunc {
 		}
 		
 		if apiKey == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "API key is required",
-			})
-			c.Abort()
+			problem.Respond(c, http.StatusUnauthorized, problem.CodeUnauthorized, "API key is required", nil)
 			return
 		}
 		
@@ -38,10 +36,7 @@ func APIKeyAuth(keyManager *KeyManager) gin.HandlerFunc {
 		valid := keyManager.ValidateKey(ctx, apiKey)
 		
 		if !valid {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid API key",
-			})
-			c.Abort()
+			problem.Respond(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid API key", nil)
 			return
 		}
 		
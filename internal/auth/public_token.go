@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/internal/storage"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublicTokenPrefix starts every public token, so a leaked one is easy to recognize
+const PublicTokenPrefix = "pub_"
+
+// Context keys set for requests authenticated with a public token
+const (
+	PublicTokenIDKey        = "public_token_id"
+	PublicTokenProjectIDKey = "public_token_project_id"
+)
+
+// GeneratePublicToken returns a new random public token and the hash to store for it
+func GeneratePublicToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("error generating public token: %w", err)
+	}
+	token := PublicTokenPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return token, HashPublicToken(token), nil
+}
+
+// HashPublicToken returns the stored form of a public token
+func HashPublicToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// PublicTokenAuth middleware accepts only public tokens, from a Bearer Authorization header or,
+// for dashboards embedded where headers cannot be set, the token query parameter. API keys and
+// session tokens are not accepted, so the routes behind it never see more than a public token may.
+func PublicTokenAuth(repo *storage.Repository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := c.Query("token")
+		if parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2); len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+			token = parts[1]
+		}
+		if !strings.HasPrefix(token, PublicTokenPrefix) {
+			problem.Respond(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Public token required", nil)
+			return
+		}
+
+		publicToken, err := repo.GetPublicTokenByHash(c.Request.Context(), HashPublicToken(token))
+		if err != nil {
+			if storage.IsNotFound(err) {
+				problem.Respond(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid public token", nil)
+				return
+			}
+			problem.Internal(c, "Failed to check public token", err)
+			return
+		}
+
+		c.Set(PublicTokenIDKey, publicToken.ID)
+		if publicToken.ProjectID != nil {
+			c.Set(PublicTokenProjectIDKey, *publicToken.ProjectID)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SessionCookieName is the HttpOnly cookie carrying the signed session token
+const SessionCookieName = "cmdlog_session"
+
+// legacyTokenCookieName is the script-readable cookie set by older releases
+const legacyTokenCookieName = "auth_token"
+
+// SetSessionCookie issues the signed session token as an HttpOnly cookie
+func SetSessionCookie(c *gin.Context, token string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(SessionCookieName, token, int(SessionDuration.Seconds()), "/", "", isSecureRequest(c), true)
+}
+
+// ClearSessionCookie expires the session cookie and the legacy token cookie
+func ClearSessionCookie(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(SessionCookieName, "", -1, "/", "", isSecureRequest(c), true)
+	c.SetCookie(legacyTokenCookieName, "", -1, "/", "", false, false)
+}
+
+// sessionTokenFromCookie returns the session token from the HttpOnly cookie,
+// falling back to the legacy auth_token cookie
+func sessionTokenFromCookie(c *gin.Context) string {
+	if cookie, err := c.Cookie(SessionCookieName); err == nil && cookie != "" {
+		return cookie
+	}
+	if cookie, err := c.Cookie(legacyTokenCookieName); err == nil && cookie != "" {
+		return cookie
+	}
+	return ""
+}
+
+// isSecureRequest reports whether the request arrived over TLS, directly or via a proxy
+func isSecureRequest(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
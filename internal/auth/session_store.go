@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/internal/useragent"
+	"log-ingestion-service/pkg/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrSessionEnded is returned for a token whose session was revoked or has expired
+var ErrSessionEnded = errors.New("the session has ended")
+
+// maxUserAgentLength bounds the user agent stored with a session
+const maxUserAgentLength = 512
+
+// SessionStore issues session tokens backed by server-side sessions. A token is only accepted
+// while its session is active, so revoking a session signs it out immediately.
+type SessionStore struct {
+	repository *storage.Repository
+	secret     string
+}
+
+// NewSessionStore creates a session store signing tokens with secret
+func NewSessionStore(repo *storage.Repository, secret string) *SessionStore {
+	return &SessionStore{repository: repo, secret: secret}
+}
+
+// Start creates a session for a user who has just authenticated, recording the device and IP
+// address of the request, and returns its signed token
+func (s *SessionStore) Start(c *gin.Context, user *models.User) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("error generating session ID: %w", err)
+	}
+
+	userAgent := c.GetHeader("User-Agent")
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	session := &models.UserSession{
+		ID:        hex.EncodeToString(id),
+		UserID:    user.ID,
+		Device:    DescribeDevice(userAgent),
+		UserAgent: userAgent,
+		IPAddress: c.ClientIP(),
+		ExpiresAt: time.Now().Add(SessionDuration),
+	}
+	if err := s.repository.CreateSession(c.Request.Context(), session); err != nil {
+		return "", err
+	}
+
+	return GenerateJWT(s.secret, session.ID, user.ID, user.Email, user.Name, user.IsAdmin)
+}
+
+// Authenticate verifies a session token and checks its session is still active
+func (s *SessionStore) Authenticate(c *gin.Context, tokenString string) (*JWTClaims, error) {
+	claims := &JWTClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.secret), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	// Tokens issued before sessions were tracked carry no session and cannot be revoked
+	if claims.ID == "" {
+		return nil, ErrSessionEnded
+	}
+	active, err := s.repository.TouchSession(c.Request.Context(), claims.ID, claims.UserID, c.ClientIP())
+	if err != nil {
+		// Fail closed, like API keys
+		log.Printf("ERROR: Failed to check session: %v", err)
+		return nil, err
+	}
+	if !active {
+		return nil, ErrSessionEnded
+	}
+	return claims, nil
+}
+
+// End revokes the session of the request's session cookie or bearer token, if any, and
+// clears the session cookie
+func (s *SessionStore) End(c *gin.Context) {
+	defer ClearSessionCookie(c)
+
+	tokenString := bearerTokenFromHeader(c)
+	if tokenString == "" {
+		tokenString = sessionTokenFromCookie(c)
+	}
+	if tokenString == "" {
+		return
+	}
+	claims, err := s.Authenticate(c, tokenString)
+	if err != nil {
+		return
+	}
+	if err := s.repository.RevokeSession(c.Request.Context(), claims.UserID, claims.ID); err != nil && !storage.IsNotFound(err) {
+		log.Printf("ERROR: Failed to revoke session %s: %v", claims.ID, err)
+	}
+}
+
+// setClaims stores the user and session of a verified token in the request context
+func setClaims(c *gin.Context, claims *JWTClaims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("user_email", claims.UserEmail)
+	c.Set("user_name", claims.UserName)
+	c.Set("is_admin", claims.IsAdmin)
+	c.Set("session_id", claims.ID)
+}
+
+// bearerTokenFromHeader returns the token of an Authorization: Bearer header
+func bearerTokenFromHeader(c *gin.Context) string {
+	parts := strings.SplitN(c.GetHeader("Authorization"), " ", 2)
+	if len(parts) == 2 && strings.ToLower(parts[0]) == "bearer" {
+		return parts[1]
+	}
+	return ""
+}
+
+// DescribeDevice summarizes a user agent as browser and platform, e.g. "Firefox on Linux"
+func DescribeDevice(userAgent string) string {
+	agent := useragent.Parse(userAgent)
+	browser, platform := agent.Browser, agent.OS
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	case userAgent == "":
+		return "Unknown device"
+	default:
+		return "Other client"
+	}
+}
@@ -0,0 +1,182 @@
+package avatar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"log-ingestion-service/pkg/config"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// URLPrefix is the route prefix under which stored avatars are served
+const URLPrefix = "/avatars/"
+
+// ErrInvalidImage is returned when an upload cannot be decoded as an image
+var ErrInvalidImage = errors.New("unsupported or corrupt image")
+
+// maxSourceDimension bounds the width and height of uploaded images to limit decode memory
+const maxSourceDimension = 4096
+
+var fileNamePattern = regexp.MustCompile(`^[0-9]+-[0-9a-f]{16}\.png$`)
+
+// Store persists resized avatar images on local disk
+type Store struct {
+	dir  string
+	size int
+}
+
+// NewStore creates an avatar store, creating the storage directory if needed
+func NewStore(cfg *config.AvatarConfig) (*Store, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating avatar directory: %w", err)
+	}
+	return &Store{
+		dir:  cfg.Dir,
+		size: cfg.Size,
+	}, nil
+}
+
+// Save decodes an uploaded image, crops it to a square, resizes it and writes it as PNG.
+// The returned file name is content-addressed so it can be cached indefinitely.
+func (s *Store) Save(userID int64, r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("error reading avatar: %w", err)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrInvalidImage
+	}
+	if cfg.Width > maxSourceDimension || cfg.Height > maxSourceDimension {
+		return "", fmt.Errorf("%w: image exceeds %dx%d pixels", ErrInvalidImage, maxSourceDimension, maxSourceDimension)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", ErrInvalidImage
+	}
+
+	dst := resize(cropSquare(src), s.size)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return "", fmt.Errorf("error encoding avatar: %w", err)
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	name := fmt.Sprintf("%d-%s.png", userID, hex.EncodeToString(sum[:8]))
+
+	if err := os.WriteFile(filepath.Join(s.dir, name), buf.Bytes(), 0o644); err != nil {
+		return "", fmt.Errorf("error writing avatar: %w", err)
+	}
+
+	return name, nil
+}
+
+// Path returns the on-disk path of a stored avatar, or false if the name is not valid
+func (s *Store) Path(name string) (string, bool) {
+	if !fileNamePattern.MatchString(name) {
+		return "", false
+	}
+	return filepath.Join(s.dir, name), true
+}
+
+// Remove deletes a stored avatar referenced by its public URL.
+// URLs that do not point at this store are ignored.
+func (s *Store) Remove(url string) {
+	name := strings.TrimPrefix(url, URLPrefix)
+	if name == url {
+		return
+	}
+	if path, ok := s.Path(name); ok {
+		os.Remove(path)
+	}
+}
+
+// URL returns the public URL for a stored avatar
+func URL(name string) string {
+	return URLPrefix + name
+}
+
+// cropSquare returns the centered square region of an image
+func cropSquare(src image.Image) image.Image {
+	b := src.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	return subImage(src, image.Rect(x0, y0, x0+side, y0+side))
+}
+
+func subImage(src image.Image, r image.Rectangle) image.Image {
+	if s, ok := src.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(r)
+	}
+	return src
+}
+
+// resize scales a square image to size x size using box averaging.
+// Images smaller than size are left at their original dimensions.
+func resize(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	if b.Dx() <= size {
+		size = b.Dx()
+	}
+	if size <= 0 {
+		size = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, size, size))
+	scale := float64(b.Dx()) / float64(size)
+
+	for y := 0; y < size; y++ {
+		sy0 := b.Min.Y + int(float64(y)*scale)
+		sy1 := b.Min.Y + int(float64(y+1)*scale)
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for x := 0; x < size; x++ {
+			sx0 := b.Min.X + int(float64(x)*scale)
+			sx1 := b.Min.X + int(float64(x+1)*scale)
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+
+			var r, g, bl, a, n uint64
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					c := color.NRGBAModel.Convert(src.At(sx, sy)).(color.NRGBA)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					bl += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(r / n),
+				G: uint8(g / n),
+				B: uint8(bl / n),
+				A: uint8(a / n),
+			})
+		}
+	}
+
+	return dst
+}
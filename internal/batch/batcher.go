@@ -2,43 +2,136 @@ package batch
 
 import (
 	"context"
+	"errors"
+	"log"
 	"log-ingestion-service/internal/storage"
 	"log-ingestion-service/pkg/config"
 	"log-ingestion-service/pkg/models"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Batcher collects log entries and flushes them in batches
+// Errors returned when entries cannot be enqueued. They only concern the caller's own entries;
+// failures writing earlier batches are reported through GetMetrics instead.
+var (
+	ErrClosed     = errors.New("batcher is shut down")
+	ErrBufferFull = errors.New("log buffer is full")
+)
+
+// Sampler decides which log entries are stored; the others are dropped before being buffered
+type Sampler interface {
+	Keep(logEntry *models.LogEntry) bool
+}
+
+// DeadLetters keeps batches that could not be written, so they can be retried later
+type DeadLetters interface {
+	Put(logEntries []models.LogEntry, cause error) (string, error)
+}
+
+// maxBufferedBatches bounds how many full batches a shard holds while flushes are backed up
+const maxBufferedBatches = 4
+
+// Batcher collects log entries and flushes them in batches. Entries are spread over
+// several shards, each with its own lock and buffer, so concurrent producers do not
+// serialize on a single mutex. Full batches are written by background flush workers,
+// so enqueueing never waits on the database. With a write-ahead log, entries are also
+// appended to disk before they are accepted, so a crash does not lose the buffers.
 type Batcher struct {
 	repository    *storage.Repository
 	config        *config.BatchConfig
-	batch         []models.LogEntry
-	mu            sync.Mutex
+	sampler       Sampler
+	deadLetters   DeadLetters
+	wal           *WAL
+	shards        []*shard
+	shardSize     int
+	next          uint32
+	flushes       chan pendingBatch
+	closed        int32
 	flushTicker   *time.Ticker
 	ctx           context.Context
 	cancel        context.CancelFunc
 	wg            sync.WaitGroup
+	workers       sync.WaitGroup
 	// Metrics
 	totalProcessed int64
 	flushCount     int64
 	errorCount     int64
+	failedEntries  int64
+	deadLettered   int64
 	startTime      time.Time
+	errMu          sync.Mutex
+	lastError      error
+	lastErrorAt    time.Time
+	lastSuccessAt  time.Time
+}
+
+// shard is one independently locked buffer of a Batcher
+type shard struct {
+	mu    sync.Mutex
+	batch []models.LogEntry
+	// segments counts the batch's entries per write-ahead log segment
+	segments map[uint64]int
+}
+
+// pendingBatch is a batch to flush, with the write-ahead log segments holding its entries
+type pendingBatch struct {
+	entries  []models.LogEntry
+	segments map[uint64]int
+	// replayed holds the entries' positions in their segment when ReplayWAL flushes them
+	replayed []int
+}
+
+// take removes the shard's batch; the caller holds the shard lock
+func (s *shard) take(size int) pendingBatch {
+	pending := pendingBatch{entries: s.batch, segments: s.segments}
+	s.batch = make([]models.LogEntry, 0, size)
+	s.segments = nil
+	return pending
 }
 
-// NewBatcher creates a new batcher
-func NewBatcher(repo *storage.Repository, cfg *config.BatchConfig) *Batcher {
+// NewBatcher creates a new batcher. Entries sampler does not keep are dropped as they are
+// added; a nil sampler keeps them all. Buffered batches that fail to be written are handed to
+// deadLetters, unless it is nil. Entries are appended to wal before they are accepted, unless
+// it is nil.
+func NewBatcher(repo *storage.Repository, cfg *config.BatchConfig, sampler Sampler, deadLetters DeadLetters, wal *WAL) *Batcher {
 	ctx, cancel := context.WithCancel(context.Background())
 	
+	shardCount := cfg.Shards
+	if shardCount <= 0 {
+		shardCount = runtime.GOMAXPROCS(0)
+	}
+	// Each shard flushes at its share of the configured size, so about as many entries are
+	// buffered in total as with a single batch
+	shardSize := cfg.Size / shardCount
+	if shardSize < 1 {
+		shardSize = 1
+	}
+	
 	b := &Batcher{
 		repository:  repo,
 		config:      cfg,
-		batch:       make([]models.LogEntry, 0, cfg.Size),
+		sampler:     sampler,
+		deadLetters: deadLetters,
+		wal:         wal,
+		shards:      make([]*shard, shardCount),
+		shardSize:   shardSize,
+		flushes:     make(chan pendingBatch, shardCount),
 		flushTicker: time.NewTicker(cfg.FlushInterval),
 		ctx:         ctx,
 		cancel:      cancel,
 		startTime:   time.Now(),
 	}
+	for i := range b.shards {
+		b.shards[i] = &shard{batch: make([]models.LogEntry, 0, shardSize)}
+	}
+	
+	// Start flush workers, one per shard
+	for i := 0; i < shardCount; i++ {
+		b.workers.Add(1)
+		go b.flushWorker()
+	}
 	
 	// Start background flush routine
 	b.wg.Add(1)
@@ -47,74 +140,266 @@ func NewBatcher(repo *storage.Repository, cfg *config.BatchConfig) *Batcher {
 	return b
 }
 
-// Add adds a log entry to the batch
+// Add enqueues a log entry. An error means the entry was not accepted; an entry dropped by
+// the sampler is accepted.
 func (b *Batcher) Add(logEntry models.LogEntry) error {
-	b.mu.Lock()
-	defer b.mu.Unlock()
+	if b.sampler != nil && !b.sampler.Keep(&logEntry) {
+		return nil
+	}
+	return b.enqueue([]models.LogEntry{logEntry})
+}
+
+// AddBatch enqueues multiple log entries, which stay together in one shard.
+// An error means none of the entries were accepted.
+func (b *Batcher) AddBatch(logEntries []models.LogEntry) error {
+	logEntries = b.sample(logEntries)
+	if len(logEntries) == 0 {
+		return nil
+	}
+	return b.enqueue(logEntries)
+}
+
+// WriteBatch writes entries to storage immediately, bypassing the buffers, and returns once they
+// are stored. It is for callers that acknowledge their source only after logs are durable.
+func (b *Batcher) WriteBatch(logEntries []models.LogEntry) error {
+	if atomic.LoadInt32(&b.closed) == 1 {
+		return ErrClosed
+	}
+	logEntries = b.sample(logEntries)
+	atomic.AddInt64(&b.totalProcessed, int64(len(logEntries)))
+	return b.write(logEntries)
+}
+
+// sample returns the entries the sampler keeps, in a new slice when it drops any, so the
+// caller's slice is left alone
+func (b *Batcher) sample(logEntries []models.LogEntry) []models.LogEntry {
+	if b.sampler == nil {
+		return logEntries
+	}
+	for i := range logEntries {
+		if b.sampler.Keep(&logEntries[i]) {
+			continue
+		}
+		kept := append(make([]models.LogEntry, 0, len(logEntries)-1), logEntries[:i]...)
+		for j := i + 1; j < len(logEntries); j++ {
+			if b.sampler.Keep(&logEntries[j]) {
+				kept = append(kept, logEntries[j])
+			}
+		}
+		return kept
+	}
+	return logEntries
+}
+
+// enqueue appends entries to a shard, after the write-ahead log, and hands the shard's batch to
+// the flush workers once full
+func (b *Batcher) enqueue(logEntries []models.LogEntry) error {
+	n := len(logEntries)
+	s := b.pickShard()
+	if err := b.admit(s, n); err != nil {
+		return err
+	}
+	
+	// Appended outside the shard lock, so a slow disk does not hold up the shard's other
+	// producers. The segment is counted in with the entries below, so a flush never releases
+	// entries before they are logged.
+	var segment uint64
+	if b.wal != nil {
+		var err error
+		if segment, err = b.wal.Append(logEntries); err != nil {
+			if errors.Is(err, errWALClosed) {
+				return ErrClosed
+			}
+			return err
+		}
+	}
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	
-	b.batch = append(b.batch, logEntry)
-	b.totalProcessed++
+	// Checked again, as Shutdown may have taken the shard's last batch during the append
+	if atomic.LoadInt32(&b.closed) == 1 {
+		if b.wal != nil {
+			// Refused, so released rather than written on the next start
+			b.wal.Release(map[uint64]int{segment: n})
+		}
+		return ErrClosed
+	}
 	
-	// Flush if batch is full
-	if len(b.batch) >= b.config.Size {
-		return b.flushLocked()
+	if b.wal != nil {
+		if s.segments == nil {
+			s.segments = make(map[uint64]int)
+		}
+		s.segments[segment] += n
+	}
+	
+	s.batch = append(s.batch, logEntries...)
+	atomic.AddInt64(&b.totalProcessed, int64(n))
+	
+	if len(s.batch) >= b.shardSize {
+		select {
+		case b.flushes <- pendingBatch{entries: s.batch, segments: s.segments}:
+			s.batch = make([]models.LogEntry, 0, b.shardSize)
+			s.segments = nil
+		default:
+			// Every worker is busy; the entries stay buffered until the next attempt
+		}
 	}
 	
 	return nil
 }
 
-// AddBatch adds multiple log entries to the batch
-func (b *Batcher) AddBatch(logEntries []models.LogEntry) error {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	
-	b.batch = append(b.batch, logEntries...)
-	b.totalProcessed += int64(len(logEntries))
+// admit checks that a shard takes n more entries. Producers admitted together may overshoot the
+// buffer limit by the entries they append at once, which is bounded by their number.
+func (b *Batcher) admit(s *shard, n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	
-	// Flush if batch is full
-	if len(b.batch) >= b.config.Size {
-		return b.flushLocked()
+	// Checked under the shard lock, so Shutdown can wait out callers that got in first
+	if atomic.LoadInt32(&b.closed) == 1 {
+		return ErrClosed
 	}
 	
+	// Flushes are backed up; refuse new entries rather than buffer without bound
+	if len(s.batch) > 0 && len(s.batch)+n > b.shardSize*maxBufferedBatches {
+		return ErrBufferFull
+	}
 	return nil
 }
 
-// Flush flushes the current batch
+// pickShard spreads producers over the shards round-robin
+func (b *Batcher) pickShard() *shard {
+	return b.shards[atomic.AddUint32(&b.next, 1)%uint32(len(b.shards))]
+}
+
+// Flush writes every shard's buffered entries and waits for the writes, returning the first error
 func (b *Batcher) Flush() error {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	return b.flushLocked()
+	var firstErr error
+	for _, s := range b.shards {
+		s.mu.Lock()
+		pending := s.take(b.shardSize)
+		s.mu.Unlock()
+		
+		if err := b.flush(pending); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
 
-// flushLocked flushes the batch (must be called with lock held)
-func (b *Batcher) flushLocked() error {
-	if len(b.batch) == 0 {
+// flush writes a buffered batch, handing it to the dead-letter queue when that fails. Batches
+// from WriteBatch are not, as their callers report the failure to the sender, who retries.
+// Once the entries are written or dead-lettered they are released from the write-ahead log;
+// otherwise the log keeps them for the next start.
+func (b *Batcher) flush(pending pendingBatch) error {
+	batch := pending.entries
+	err := b.write(batch)
+	if err == nil {
+		b.release(pending)
+		return nil
+	}
+	if b.deadLetters == nil {
+		b.logLost(len(batch), err)
+		return err
+	}
+	id, dlErr := b.deadLetters.Put(batch, err)
+	if dlErr != nil {
+		b.logLost(len(batch), dlErr)
+		return err
+	}
+	b.release(pending)
+	atomic.AddInt64(&b.deadLettered, int64(len(batch)))
+	log.Printf("WARN: Kept %d log entries that failed to flush as dead-letter batch %s", len(batch), id)
+	return err
+}
+
+// release frees a flushed batch's entries in the write-ahead log. Replayed entries are
+// checkpointed first, so their segment is not written again whole should the replay be cut short.
+func (b *Batcher) release(pending pendingBatch) {
+	if len(pending.replayed) > 0 {
+		for id := range pending.segments {
+			if err := b.wal.checkpoint(id, pending.replayed); err != nil {
+				log.Printf("WARN: Failed to checkpoint the replay of write-ahead log segment %d: %v", id, err)
+			}
+		}
+	}
+	b.wal.Release(pending.segments)
+}
+
+// logLost reports entries that failed to flush and could not be dead-lettered
+func (b *Batcher) logLost(n int, err error) {
+	if b.wal != nil {
+		log.Printf("ERROR: Kept %d log entries that failed to flush in the write-ahead log until the next start: %v", n, err)
+		return
+	}
+	if b.deadLetters != nil {
+		log.Printf("ERROR: Lost %d log entries that failed to flush: %v", n, err)
+	}
+}
+
+// ReplayWAL writes the entries the write-ahead log kept from a previous run, in batches of the
+// configured size, and returns how many were written. Batches that fail are dead-lettered like
+// any other; segments whose entries can be neither written nor dead-lettered are kept for the
+// next start, which skips the batches checkpointed as written or dead-lettered.
+func (b *Batcher) ReplayWAL() (int, error) {
+	var replayed int
+	var firstErr error
+	for _, id := range b.wal.replayable() {
+		logEntries, positions, err := b.wal.load(id)
+		if err != nil {
+			log.Printf("ERROR: Failed to replay write-ahead log segment %d: %v", id, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for start := 0; start < len(logEntries); start += b.config.Size {
+			end := min(start+b.config.Size, len(logEntries))
+			chunk := logEntries[start:end]
+			pending := pendingBatch{entries: chunk, segments: map[uint64]int{id: len(chunk)}, replayed: positions[start:end]}
+			if err := b.flush(pending); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			replayed += len(chunk)
+		}
+	}
+	return replayed, firstErr
+}
+
+// write inserts a batch and records the outcome
+func (b *Batcher) write(batch []models.LogEntry) error {
+	if len(batch) == 0 {
 		return nil
 	}
 	
-	// Create a copy of the batch
-	batchCopy := make([]models.LogEntry, len(b.batch))
-	copy(batchCopy, b.batch)
-	
-	// Clear the batch
-	b.batch = b.batch[:0]
-	
-	// Release lock before database operation
-	b.mu.Unlock()
-	
-	// Insert batch into database
-	err := b.repository.InsertBatch(b.ctx, batchCopy)
-	
-	// Re-acquire lock
-	b.mu.Lock()
+	// Writes are not tied to b.ctx, so the final flush on shutdown still succeeds
+	err := b.repository.InsertBatch(context.Background(), batch)
 	
-	// Update metrics
-	b.flushCount++
+	atomic.AddInt64(&b.flushCount, 1)
+	b.errMu.Lock()
+	defer b.errMu.Unlock()
 	if err != nil {
-		b.errorCount++
+		atomic.AddInt64(&b.errorCount, 1)
+		atomic.AddInt64(&b.failedEntries, int64(len(batch)))
+		b.lastError = err
+		b.lastErrorAt = time.Now()
+		log.Printf("ERROR: Failed to flush %d log entries: %v", len(batch), err)
+		return err
 	}
+	b.lastSuccessAt = time.Now()
+	return nil
+}
+
+// flushWorker writes batches handed off by full shards
+func (b *Batcher) flushWorker() {
+	defer b.workers.Done()
 	
-	return err
+	for pending := range b.flushes {
+		b.flush(pending)
+	}
 }
 
 // flushRoutine periodically flushes the batch
@@ -124,8 +409,6 @@ func (b *Batcher) flushRoutine() {
 	for {
 		select {
 		case <-b.ctx.Done():
-			// Final flush on shutdown
-			b.Flush()
 			return
 		case <-b.flushTicker.C:
 			b.Flush()
@@ -133,27 +416,62 @@ func (b *Batcher) flushRoutine() {
 	}
 }
 
-// Shutdown gracefully shuts down the batcher
+// Shutdown stops accepting entries, waits for pending writes and flushes what is left
 func (b *Batcher) Shutdown() error {
+	if !atomic.CompareAndSwapInt32(&b.closed, 0, 1) {
+		return nil
+	}
 	b.cancel()
 	b.flushTicker.Stop()
 	b.wg.Wait()
-	return b.Flush()
+	
+	// Callers that took a shard lock before closed was set may still hand off a batch;
+	// taking each lock once waits them out before the channel is closed
+	for _, s := range b.shards {
+		s.mu.Lock()
+		s.mu.Unlock()
+	}
+	close(b.flushes)
+	b.workers.Wait()
+	err := b.Flush()
+	if walErr := b.wal.Close(); walErr != nil && err == nil {
+		err = walErr
+	}
+	return err
 }
 
 // GetMetrics returns current batcher metrics
 func (b *Batcher) GetMetrics() BatcherMetrics {
-	b.mu.Lock()
-	defer b.mu.Unlock()
-	
-	return BatcherMetrics{
-		CurrentBatchSize: len(b.batch),
-		TotalProcessed:   b.totalProcessed,
-		FlushCount:       b.flushCount,
-		ErrorCount:       b.errorCount,
+	current := 0
+	for _, s := range b.shards {
+		s.mu.Lock()
+		current += len(s.batch)
+		s.mu.Unlock()
+	}
+	
+	metrics := BatcherMetrics{
+		CurrentBatchSize: current,
+		TotalProcessed:   atomic.LoadInt64(&b.totalProcessed),
+		FlushCount:       atomic.LoadInt64(&b.flushCount),
+		ErrorCount:       atomic.LoadInt64(&b.errorCount),
+		FailedEntries:    atomic.LoadInt64(&b.failedEntries),
+		DeadLettered:     atomic.LoadInt64(&b.deadLettered),
+		WAL:              b.wal.Stats(),
+		Shards:           len(b.shards),
 		Uptime:           time.Since(b.startTime),
 		Config:           *b.config,
 	}
+	
+	b.errMu.Lock()
+	defer b.errMu.Unlock()
+	if b.lastError != nil {
+		lastErrorAt := b.lastErrorAt
+		metrics.LastError = b.lastError.Error()
+		metrics.LastErrorAt = &lastErrorAt
+		// Healthy again once a later flush has succeeded
+		metrics.Failing = !b.lastSuccessAt.After(b.lastErrorAt)
+	}
+	return metrics
 }
 
 // BatcherMetrics holds batcher performance metrics
@@ -162,6 +480,16 @@ type BatcherMetrics struct {
 	TotalProcessed   int64         `json:"total_processed"`
 	FlushCount       int64         `json:"flush_count"`
 	ErrorCount       int64         `json:"error_count"`
+	FailedEntries    int64         `json:"failed_entries"`
+	// DeadLettered counts the failed entries kept in the dead-letter queue
+	DeadLettered     int64         `json:"dead_lettered"`
+	// WAL describes the write-ahead log, when it is enabled
+	WAL              *WALStats     `json:"wal,omitempty"`
+	// LastError is the most recent flush failure; Failing is set until a later flush succeeds
+	LastError        string        `json:"last_error,omitempty"`
+	LastErrorAt      *time.Time    `json:"last_error_at,omitempty"`
+	Failing          bool          `json:"failing"`
+	Shards           int           `json:"shards,omitempty"`
 	Uptime           time.Duration `json:"uptime"`
 	Config           config.BatchConfig `json:"config"`
 }
\ No newline at end of file
@@ -0,0 +1,138 @@
+package batch
+
+import (
+	"errors"
+	"fmt"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// benchmarkShards are the shard counts compared; run with -cpu to vary the producers
+var benchmarkShards = []int{1, 4, 16}
+
+// newBenchmarkBatcher returns a batcher with the given number of shards whose full batches are
+// discarded instead of written, so benchmarks measure enqueueing alone. The returned function
+// stops the discarding.
+func newBenchmarkBatcher(shards int, wal *WAL) (*Batcher, func()) {
+	cfg := &config.BatchConfig{Size: 1000, FlushInterval: time.Hour, Shards: shards}
+	shardSize := cfg.Size / shards
+	b := &Batcher{
+		config:    cfg,
+		wal:       wal,
+		shards:    make([]*shard, shards),
+		shardSize: shardSize,
+		flushes:   make(chan pendingBatch, 64*shards),
+		startTime: time.Now(),
+	}
+	for i := range b.shards {
+		b.shards[i] = &shard{batch: make([]models.LogEntry, 0, shardSize)}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for pending := range b.flushes {
+			b.wal.Release(pending.segments)
+		}
+	}()
+	return b, func() {
+		close(b.flushes)
+		<-done
+	}
+}
+
+func benchmarkEntry() models.LogEntry {
+	return models.LogEntry{
+		Timestamp: time.Unix(1700000000, 0).UTC(),
+		Service:   "checkout",
+		Level:     "info",
+		Message:   "order placed",
+		Metadata:  map[string]interface{}{"order_id": 42},
+	}
+}
+
+// discardBuffered empties every shard as the periodic flush would, without writing the entries
+func discardBuffered(batcher *Batcher) {
+	for _, s := range batcher.shards {
+		s.mu.Lock()
+		pending := s.take(batcher.shardSize)
+		s.mu.Unlock()
+		batcher.wal.Release(pending.segments)
+	}
+}
+
+// runParallel calls add from GOMAXPROCS producers. A call refused because the discarding has
+// fallen behind empties the shards, as the periodic flush would, and is retried; the retries
+// are reported as retries/op.
+func runParallel(b *testing.B, batcher *Batcher, add func() error) {
+	var retries int64
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			err := add()
+			for errors.Is(err, ErrBufferFull) {
+				atomic.AddInt64(&retries, 1)
+				discardBuffered(batcher)
+				err = add()
+			}
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+	b.ReportMetric(float64(retries)/float64(b.N), "retries/op")
+}
+
+// BenchmarkBatcherAddParallel measures Add from concurrent producers, with a single shard, which
+// behaves like one mutex, against several
+func BenchmarkBatcherAddParallel(b *testing.B) {
+	for _, shards := range benchmarkShards {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			batcher, stop := newBenchmarkBatcher(shards, nil)
+			defer stop()
+			entry := benchmarkEntry()
+
+			runParallel(b, batcher, func() error { return batcher.Add(entry) })
+		})
+	}
+}
+
+// BenchmarkBatcherAddBatchParallel measures AddBatch of 50 entries from concurrent producers
+func BenchmarkBatcherAddBatchParallel(b *testing.B) {
+	logEntries := make([]models.LogEntry, 50)
+	for i := range logEntries {
+		logEntries[i] = benchmarkEntry()
+	}
+	for _, shards := range benchmarkShards {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			batcher, stop := newBenchmarkBatcher(shards, nil)
+			defer stop()
+
+			runParallel(b, batcher, func() error { return batcher.AddBatch(logEntries) })
+		})
+	}
+}
+
+// BenchmarkBatcherAddParallelWAL measures Add from concurrent producers with the write-ahead
+// log enabled, without syncing
+func BenchmarkBatcherAddParallelWAL(b *testing.B) {
+	for _, shards := range benchmarkShards {
+		b.Run(fmt.Sprintf("shards=%d", shards), func(b *testing.B) {
+			wal, err := OpenWAL(&config.WALConfig{Enabled: true, Dir: b.TempDir(), SegmentBytes: 64 << 20})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer wal.Close()
+			batcher, stop := newBenchmarkBatcher(shards, wal)
+			defer stop()
+			entry := benchmarkEntry()
+
+			runParallel(b, batcher, func() error { return batcher.Add(entry) })
+		})
+	}
+}
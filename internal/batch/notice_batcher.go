@@ -0,0 +1,257 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"log"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"sync"
+	"time"
+)
+
+// ErrNoticeBufferFull is returned when notices cannot be queued because earlier batches are
+// still waiting to be written
+var ErrNoticeBufferFull = errors.New("notice buffer is full")
+
+// maxNoticeFlushAttempts is how many times a notice batch is written before it is given up on
+const maxNoticeFlushAttempts = 5
+
+// NoticeBatcher collects notices and writes them in bulk, so an error storm costs one
+// COPY per flush instead of an insert and a fault update per notice. Flushes run in the
+// background, and a batch that fails to be written is kept and retried with the next ones.
+type NoticeBatcher struct {
+	repository *storage.Repository
+	config     *config.BatchConfig
+	batch      []*models.Notice
+	counted    map[int64]int32
+	// failed holds batches that could not be written yet, oldest first
+	failed      []noticeBatch
+	retained    int
+	closed      bool
+	mu          sync.Mutex
+	flushMu     sync.Mutex
+	full        chan struct{}
+	flushTicker *time.Ticker
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	// Metrics
+	totalProcessed int64
+	flushCount     int64
+	errorCount     int64
+	failedEntries  int64
+	lastError      error
+	lastErrorAt    time.Time
+	lastSuccessAt  time.Time
+	startTime      time.Time
+}
+
+// noticeBatch is a batch to write, with the number of failed attempts at writing it
+type noticeBatch struct {
+	notices  []*models.Notice
+	counted  map[int64]int32
+	attempts int
+}
+
+// NewNoticeBatcher creates a new notice batcher
+func NewNoticeBatcher(repo *storage.Repository, cfg *config.BatchConfig) *NoticeBatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	b := &NoticeBatcher{
+		repository:  repo,
+		config:      cfg,
+		batch:       make([]*models.Notice, 0, cfg.Size),
+		counted:     make(map[int64]int32),
+		full:        make(chan struct{}, 1),
+		flushTicker: time.NewTicker(cfg.FlushInterval),
+		ctx:         ctx,
+		cancel:      cancel,
+		startTime:   time.Now(),
+	}
+
+	// Start background flush routine
+	b.wg.Add(1)
+	go b.flushRoutine()
+
+	return b
+}
+
+// Add queues a notice whose fault has already been resolved. An error means the notice was not
+// accepted; failures writing it later are reported through GetMetrics instead.
+func (b *NoticeBatcher) Add(notice *models.Notice) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return ErrClosed
+	}
+	// Writes are failing; refuse new notices rather than keep them without bound
+	if b.retained+len(b.batch) >= b.config.Size*maxBufferedBatches {
+		return ErrNoticeBufferFull
+	}
+
+	b.batch = append(b.batch, notice)
+	b.totalProcessed++
+
+	// Wake the flush routine once the batch is full
+	if len(b.batch) >= b.config.Size {
+		select {
+		case b.full <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Count adds an occurrence of a fault whose notice is not stored, written with the next flush
+func (b *NoticeBatcher) Count(faultID int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.counted[faultID]++
+}
+
+// Flush writes the batches kept from failed flushes and the current batch, returning the
+// first error. Batches that fail again are kept until they have been tried
+// maxNoticeFlushAttempts times.
+func (b *NoticeBatcher) Flush() error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+
+	b.mu.Lock()
+	pending := b.failed
+	if len(b.batch) > 0 || len(b.counted) > 0 {
+		pending = append(pending, noticeBatch{notices: b.batch, counted: b.counted})
+		b.batch = make([]*models.Notice, 0, b.config.Size)
+		b.counted = make(map[int64]int32)
+	}
+	b.failed = nil
+	b.mu.Unlock()
+
+	// Producers are not blocked while the batches are written
+	var firstErr error
+	var failed []noticeBatch
+	for _, nb := range pending {
+		err := b.write(nb)
+		if err == nil {
+			continue
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		nb.attempts++
+		if nb.attempts >= maxNoticeFlushAttempts {
+			b.lose(nb, err)
+			continue
+		}
+		failed = append(failed, nb)
+	}
+
+	b.mu.Lock()
+	b.failed = failed
+	b.retained = 0
+	for _, nb := range failed {
+		b.retained += len(nb.notices)
+	}
+	b.mu.Unlock()
+
+	return firstErr
+}
+
+// write inserts a batch and records the outcome. Writes are not tied to b.ctx, so the final
+// flush on shutdown still succeeds.
+func (b *NoticeBatcher) write(nb noticeBatch) error {
+	dropped, err := b.repository.InsertNoticeBatch(context.Background(), nb.notices, nb.counted)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.flushCount++
+	if err != nil {
+		b.errorCount++
+		b.lastError = err
+		b.lastErrorAt = time.Now()
+		log.Printf("ERROR: Failed to flush %d notices (attempt %d of %d): %v", len(nb.notices), nb.attempts+1, maxNoticeFlushAttempts, err)
+		return err
+	}
+	b.lastSuccessAt = time.Now()
+	if dropped > 0 {
+		log.Printf("WARN: Dropped %d notices of faults deleted before they were written", dropped)
+	}
+	return nil
+}
+
+// lose reports a batch given up on
+func (b *NoticeBatcher) lose(nb noticeBatch, err error) {
+	b.mu.Lock()
+	b.failedEntries += int64(len(nb.notices))
+	b.mu.Unlock()
+	log.Printf("ERROR: Lost %d notices that failed to flush %d times: %v", len(nb.notices), nb.attempts, err)
+}
+
+// flushRoutine flushes periodically and whenever the batch fills up
+func (b *NoticeBatcher) flushRoutine() {
+	defer b.wg.Done()
+
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-b.flushTicker.C:
+			b.Flush()
+		case <-b.full:
+			b.Flush()
+		}
+	}
+}
+
+// Shutdown stops accepting notices, stops the flush routine and writes any queued notices,
+// giving each batch kept from failed flushes one last attempt
+func (b *NoticeBatcher) Shutdown() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	b.mu.Unlock()
+
+	b.flushTicker.Stop()
+	b.cancel()
+	b.wg.Wait()
+
+	err := b.Flush()
+	b.mu.Lock()
+	failed := b.failed
+	b.failed, b.retained = nil, 0
+	b.mu.Unlock()
+	for _, nb := range failed {
+		b.lose(nb, err)
+	}
+	return err
+}
+
+// GetMetrics returns current notice batcher metrics
+func (b *NoticeBatcher) GetMetrics() BatcherMetrics {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	metrics := BatcherMetrics{
+		CurrentBatchSize: len(b.batch) + b.retained,
+		TotalProcessed:   b.totalProcessed,
+		FlushCount:       b.flushCount,
+		ErrorCount:       b.errorCount,
+		FailedEntries:    b.failedEntries,
+		Uptime:           time.Since(b.startTime),
+		Config:           *b.config,
+	}
+	if b.lastError != nil {
+		lastErrorAt := b.lastErrorAt
+		metrics.LastError = b.lastError.Error()
+		metrics.LastErrorAt = &lastErrorAt
+		// Healthy again once a later flush has succeeded
+		metrics.Failing = !b.lastSuccessAt.After(b.lastErrorAt)
+	}
+	return metrics
+}
@@ -0,0 +1,416 @@
+package batch
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// walExt is the extension of write-ahead log segment files
+const walExt = ".wal"
+
+// checkpointExt is the extension of the files recording which entries of a segment left by a
+// previous run have been replayed
+const checkpointExt = ".checkpoint"
+
+var errWALClosed = errors.New("write-ahead log is closed")
+
+// WAL is a write-ahead log of the entries a Batcher buffers. Entries are appended to the active
+// segment file before they are accepted, one record per append, and a segment is removed once
+// every entry in it has been written or dead-lettered. Segments found when the log is opened
+// were left by a run that did not flush them all; ReplayWAL writes them, checkpointing each
+// chunk it writes so a replay cut short does not write it again.
+type WAL struct {
+	dir          string
+	segmentBytes int64
+	sync         bool
+
+	mu       sync.Mutex
+	active   uint64
+	file     *os.File
+	segments map[uint64]*walSegment
+	replay   []uint64
+	closed   bool
+}
+
+// walSegment counts a segment's entries not yet flushed
+type walSegment struct {
+	pending int
+	size    int64
+	// unread is set on segments of a previous run until they are replayed
+	unread bool
+	// replayed holds the positions, within a segment of a previous run, of the entries written
+	// by this and earlier replays
+	replayed map[int]bool
+}
+
+// walCheckpoint is the content of a checkpoint file
+type walCheckpoint struct {
+	// Replayed are ranges of entry positions, each from the first to the last
+	Replayed [][2]int `json:"replayed"`
+}
+
+// WALStats describes the segments of the write-ahead log
+type WALStats struct {
+	Segments       int   `json:"segments"`
+	PendingEntries int   `json:"pending_entries"`
+	Bytes          int64 `json:"bytes"`
+	Sync           bool  `json:"sync"`
+}
+
+// OpenWAL opens the write-ahead log, creating its directory if needed, and starts a new
+// segment after those left by a previous run. It returns nil when the log is disabled.
+func OpenWAL(cfg *config.WALConfig) (*WAL, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating write-ahead log directory: %w", err)
+	}
+	files, err := os.ReadDir(cfg.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("error listing write-ahead log segments: %w", err)
+	}
+
+	w := &WAL{dir: cfg.Dir, segmentBytes: cfg.SegmentBytes, sync: cfg.Sync, segments: make(map[uint64]*walSegment)}
+	var checkpoints []uint64
+	for _, file := range files {
+		if id, err := strconv.ParseUint(strings.TrimSuffix(file.Name(), checkpointExt), 10, 64); err == nil && strings.HasSuffix(file.Name(), checkpointExt) {
+			checkpoints = append(checkpoints, id)
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(file.Name(), walExt), 10, 64)
+		if file.IsDir() || !strings.HasSuffix(file.Name(), walExt) || err != nil {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			return nil, fmt.Errorf("error reading write-ahead log segment: %w", err)
+		}
+		w.segments[id] = &walSegment{size: info.Size(), unread: true}
+		w.replay = append(w.replay, id)
+	}
+	sort.Slice(w.replay, func(i, j int) bool { return w.replay[i] < w.replay[j] })
+	// A checkpoint outlives its segment only when a crash came between their removals
+	for _, id := range checkpoints {
+		if _, ok := w.segments[id]; !ok {
+			w.removeCheckpoint(id)
+		}
+	}
+
+	next := uint64(1)
+	if len(w.replay) > 0 {
+		next = w.replay[len(w.replay)-1] + 1
+	}
+	if err := w.openSegmentLocked(next); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Append writes entries as one record and returns the segment holding them. Once it returns,
+// the entries survive a crash of the process, and with sync a crash of the machine.
+func (w *WAL) Append(logEntries []models.LogEntry) (uint64, error) {
+	record, err := json.Marshal(logEntries)
+	if err != nil {
+		return 0, fmt.Errorf("error encoding write-ahead log record: %w", err)
+	}
+	record = append(record, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return 0, errWALClosed
+	}
+	if err := w.writeLocked(record); err != nil {
+		// A partial record would corrupt the next one, so appends go on in a new segment
+		if rotateErr := w.rotateLocked(); rotateErr != nil {
+			log.Printf("ERROR: Failed to start a new write-ahead log segment: %v", rotateErr)
+		}
+		return 0, err
+	}
+
+	id := w.active
+	segment := w.segments[id]
+	segment.pending += len(logEntries)
+	segment.size += int64(len(record))
+	if segment.size >= w.segmentBytes {
+		if err := w.rotateLocked(); err != nil {
+			log.Printf("WARN: Failed to start a new write-ahead log segment: %v", err)
+		}
+	}
+	return id, nil
+}
+
+func (w *WAL) writeLocked(record []byte) error {
+	if _, err := w.file.Write(record); err != nil {
+		return fmt.Errorf("error writing write-ahead log: %w", err)
+	}
+	if w.sync {
+		if err := w.file.Sync(); err != nil {
+			return fmt.Errorf("error syncing write-ahead log: %w", err)
+		}
+	}
+	return nil
+}
+
+// Release records that entries have been flushed, given as counts per segment, and removes
+// segments with none left
+func (w *WAL) Release(segments map[uint64]int) {
+	if w == nil || len(segments) == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for id, n := range segments {
+		if segment, ok := w.segments[id]; ok {
+			segment.pending -= n
+			w.removeIfDoneLocked(id)
+		}
+	}
+}
+
+// Close closes the active segment, removing it if every entry in it was flushed
+func (w *WAL) Close() error {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	err := w.file.Close()
+	w.removeIfDoneLocked(w.active)
+	return err
+}
+
+// Stats returns the totals of the segments, or nil when the log is disabled
+func (w *WAL) Stats() *WALStats {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stats := &WALStats{Segments: len(w.segments), Sync: w.sync}
+	for _, segment := range w.segments {
+		stats.PendingEntries += segment.pending
+		stats.Bytes += segment.size
+	}
+	return stats
+}
+
+// replayable returns the segments left by a previous run, oldest first
+func (w *WAL) replayable() []uint64 {
+	if w == nil {
+		return nil
+	}
+	return w.replay
+}
+
+// load reads the entries of a segment left by a previous run that an earlier replay did not
+// write, with their positions in the segment, and counts them as pending. A record cut short by
+// a crash ends the segment and is skipped, as its entries were never accepted.
+func (w *WAL) load(id uint64) ([]models.LogEntry, []int, error) {
+	replayed, err := w.readCheckpoint(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(w.path(id))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading write-ahead log segment %d: %w", id, err)
+	}
+	defer f.Close()
+
+	var logEntries []models.LogEntry
+	var positions []int
+	position := 0
+	r := bufio.NewReader(f)
+	for {
+		// Records are read whole, as they may be longer than a scanner's buffer
+		record, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			if len(record) > 0 {
+				log.Printf("WARN: Skipping a partial record at the end of write-ahead log segment %d", id)
+			}
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading write-ahead log segment %d: %w", id, err)
+		}
+		var batch []models.LogEntry
+		if err := json.Unmarshal(record, &batch); err != nil {
+			log.Printf("WARN: Skipping a corrupt record in write-ahead log segment %d: %v", id, err)
+			continue
+		}
+		for _, logEntry := range batch {
+			if !replayed[position] {
+				logEntries = append(logEntries, logEntry)
+				positions = append(positions, position)
+			}
+			position++
+		}
+	}
+	if len(replayed) > 0 {
+		log.Printf("INFO: Skipping %d entries of write-ahead log segment %d written by an earlier replay", len(replayed), id)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	segment := w.segments[id]
+	segment.unread = false
+	segment.pending = len(logEntries)
+	segment.replayed = replayed
+	w.removeIfDoneLocked(id)
+	return logEntries, positions, nil
+}
+
+// checkpoint records that the entries at positions of a segment of a previous run have been
+// written, so the next start does not write them again should this replay be cut short. The
+// checkpoint file is replaced whole, so a crash leaves either the old or the new one.
+func (w *WAL) checkpoint(id uint64, positions []int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	segment, ok := w.segments[id]
+	if !ok {
+		return nil
+	}
+	if segment.replayed == nil {
+		segment.replayed = make(map[int]bool)
+	}
+	for _, position := range positions {
+		segment.replayed[position] = true
+	}
+
+	data, err := json.Marshal(walCheckpoint{Replayed: positionRanges(segment.replayed)})
+	if err != nil {
+		return fmt.Errorf("error encoding write-ahead log checkpoint: %w", err)
+	}
+	tmp := w.checkpointPath(id) + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("error writing write-ahead log checkpoint: %w", err)
+	}
+	_, err = f.Write(data)
+	if err == nil && w.sync {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, w.checkpointPath(id))
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing write-ahead log checkpoint: %w", err)
+	}
+	return nil
+}
+
+// readCheckpoint returns the positions of a segment's entries written by earlier replays
+func (w *WAL) readCheckpoint(id uint64) (map[int]bool, error) {
+	data, err := os.ReadFile(w.checkpointPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading write-ahead log checkpoint %d: %w", id, err)
+	}
+	var checkpoint walCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, fmt.Errorf("error decoding write-ahead log checkpoint %d: %w", id, err)
+	}
+	replayed := make(map[int]bool)
+	for _, r := range checkpoint.Replayed {
+		for position := r[0]; position <= r[1]; position++ {
+			replayed[position] = true
+		}
+	}
+	return replayed, nil
+}
+
+// positionRanges compacts positions into ranges, in order
+func positionRanges(positions map[int]bool) [][2]int {
+	sorted := make([]int, 0, len(positions))
+	for position := range positions {
+		sorted = append(sorted, position)
+	}
+	sort.Ints(sorted)
+	var ranges [][2]int
+	for _, position := range sorted {
+		if n := len(ranges); n > 0 && ranges[n-1][1] == position-1 {
+			ranges[n-1][1] = position
+			continue
+		}
+		ranges = append(ranges, [2]int{position, position})
+	}
+	return ranges
+}
+
+// rotateLocked starts a new segment, keeping the current one if that fails
+func (w *WAL) rotateLocked() error {
+	previous, file := w.active, w.file
+	if err := w.openSegmentLocked(previous + 1); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		log.Printf("WARN: Failed to close write-ahead log segment %d: %v", previous, err)
+	}
+	w.removeIfDoneLocked(previous)
+	return nil
+}
+
+func (w *WAL) openSegmentLocked(id uint64) error {
+	file, err := os.OpenFile(w.path(id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("error creating write-ahead log segment: %w", err)
+	}
+	w.active = id
+	w.file = file
+	w.segments[id] = &walSegment{}
+	return nil
+}
+
+// removeIfDoneLocked removes a segment once none of its entries are pending, unless it is
+// still being appended to or has yet to be replayed
+func (w *WAL) removeIfDoneLocked(id uint64) {
+	segment, ok := w.segments[id]
+	if !ok || segment.pending > 0 || segment.unread || (id == w.active && !w.closed) {
+		return
+	}
+	if err := os.Remove(w.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("WARN: Failed to remove write-ahead log segment %d: %v", id, err)
+		return
+	}
+	if segment.replayed != nil {
+		w.removeCheckpoint(id)
+	}
+	delete(w.segments, id)
+}
+
+func (w *WAL) removeCheckpoint(id uint64) {
+	if err := os.Remove(w.checkpointPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("WARN: Failed to remove write-ahead log checkpoint %d: %v", id, err)
+	}
+}
+
+func (w *WAL) path(id uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%020d%s", id, walExt))
+}
+
+func (w *WAL) checkpointPath(id uint64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%020d%s", id, checkpointExt))
+}
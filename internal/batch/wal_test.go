@@ -0,0 +1,92 @@
+package batch
+
+import (
+	"fmt"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestWALReplaySkipsCheckpointedEntries cuts a replay short after its first chunk and checks
+// that the next start loads only the entries the replay did not get to
+func TestWALReplaySkipsCheckpointedEntries(t *testing.T) {
+	cfg := &config.WALConfig{Enabled: true, Dir: t.TempDir(), SegmentBytes: 1 << 20}
+	w, err := OpenWAL(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two records, so positions run across them
+	for _, messages := range [][]string{{"0", "1", "2"}, {"3", "4"}} {
+		logEntries := make([]models.LogEntry, len(messages))
+		for i, message := range messages {
+			logEntries[i] = models.LogEntry{Service: "checkout", Level: "info", Message: message}
+		}
+		if _, err := w.Append(logEntries); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// First start: the chunk of entries 0 to 2 is written, then the replay is cut short
+	w, err = OpenWAL(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := w.replayable()[0]
+	logEntries, positions, err := w.load(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logEntries) != 5 {
+		t.Fatalf("loaded %d entries, want 5", len(logEntries))
+	}
+	if err := w.checkpoint(id, positions[:3]); err != nil {
+		t.Fatal(err)
+	}
+	w.Release(map[uint64]int{id: 3})
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Second start: only entries 3 and 4 are left
+	w, err = OpenWAL(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logEntries, positions, err = w.load(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var messages []string
+	for _, logEntry := range logEntries {
+		messages = append(messages, logEntry.Message)
+	}
+	if fmt.Sprint(messages) != "[3 4]" || fmt.Sprint(positions) != "[3 4]" {
+		t.Fatalf("loaded messages %v at positions %v, want [3 4] at [3 4]", messages, positions)
+	}
+
+	// Once the rest is written the segment and its checkpoint are removed
+	if err := w.checkpoint(id, positions); err != nil {
+		t.Fatal(err)
+	}
+	w.Release(map[uint64]int{id: len(logEntries)})
+	for _, path := range []string{w.path(id), w.checkpointPath(id)} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was kept: %v", filepath.Base(path), err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPositionRanges(t *testing.T) {
+	positions := map[int]bool{0: true, 1: true, 2: true, 5: true, 7: true, 8: true}
+	if got := fmt.Sprint(positionRanges(positions)); got != "[[0 2] [5 5] [7 8]]" {
+		t.Fatalf("positionRanges = %s", got)
+	}
+}
@@ -0,0 +1,275 @@
+// Package deadletter keeps log batches that could not be written to the database on local
+// disk, so they can be inspected and retried, or discarded, instead of being lost. Each batch is
+// a file holding a header line describing it, then one JSON log entry per line. The queue
+// belongs to the instance whose writes failed.
+package deadletter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Errors returned by the store
+var (
+	ErrDisabled = errors.New("dead-letter queue is disabled")
+	ErrFull     = errors.New("dead-letter queue is full")
+	ErrNotFound = errors.New("dead-letter batch not found")
+)
+
+// fileExt is the extension of batch files; files being written have a temporary one
+const fileExt = ".jsonl"
+
+var idPattern = regexp.MustCompile(`^[0-9]+-[0-9]+$`)
+
+// Batch describes a batch of log entries that could not be written
+type Batch struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Entries   int       `json:"entries"`
+	// Error is why the batch could not be written
+	Error string `json:"error"`
+	// Size is the size of the batch file in bytes
+	Size int64 `json:"size"`
+}
+
+// Stats describes the batches in the queue
+type Stats struct {
+	Enabled  bool  `json:"enabled"`
+	Batches  int   `json:"batches"`
+	Entries  int   `json:"entries"`
+	Bytes    int64 `json:"bytes"`
+	MaxBytes int64 `json:"max_bytes"`
+}
+
+// Store keeps dead-letter batches in a directory
+type Store struct {
+	enabled  bool
+	dir      string
+	maxBytes int64
+	seq      uint32
+
+	// mu guards the totals, and serializes replays so a batch is not written twice
+	mu      sync.Mutex
+	batches int
+	entries int
+	bytes   int64
+}
+
+// NewStore creates a store, creating its directory if needed and counting the batches already
+// in it. A disabled store keeps nothing.
+func NewStore(cfg *config.DeadLetterConfig) (*Store, error) {
+	s := &Store{enabled: cfg.Enabled, dir: cfg.Dir, maxBytes: cfg.MaxBytes}
+	if !s.enabled {
+		return s, nil
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating dead-letter directory: %w", err)
+	}
+	batches, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range batches {
+		s.batches++
+		s.entries += b.Entries
+		s.bytes += b.Size
+	}
+	return s, nil
+}
+
+// Put keeps a batch that could not be written because of cause and returns its ID. Batches
+// that would take the queue past its size limit are refused with ErrFull.
+func (s *Store) Put(logEntries []models.LogEntry, cause error) (string, error) {
+	if !s.enabled {
+		return "", ErrDisabled
+	}
+
+	now := time.Now()
+	id := fmt.Sprintf("%d-%d", now.UnixNano(), atomic.AddUint32(&s.seq, 1))
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	if err := enc.Encode(Batch{ID: id, CreatedAt: now.UTC(), Entries: len(logEntries), Error: cause.Error()}); err != nil {
+		return "", fmt.Errorf("error encoding dead-letter batch: %w", err)
+	}
+	for i := range logEntries {
+		if err := enc.Encode(&logEntries[i]); err != nil {
+			return "", fmt.Errorf("error encoding dead-letter batch: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bytes+int64(buf.Len()) > s.maxBytes {
+		return "", ErrFull
+	}
+	// Written under a temporary name and renamed, so a crash never leaves half a batch
+	tmp := filepath.Join(s.dir, "."+id+".tmp")
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("error writing dead-letter batch: %w", err)
+	}
+	if err := os.Rename(tmp, s.path(id)); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("error writing dead-letter batch: %w", err)
+	}
+	s.batches++
+	s.entries += len(logEntries)
+	s.bytes += int64(buf.Len())
+	return id, nil
+}
+
+// List returns the batches in the queue, oldest first
+func (s *Store) List() ([]Batch, error) {
+	batches := []Batch{}
+	if !s.enabled {
+		return batches, nil
+	}
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("error listing dead-letter batches: %w", err)
+	}
+	for _, file := range files {
+		id := strings.TrimSuffix(file.Name(), fileExt)
+		if file.IsDir() || !idPattern.MatchString(id) || id+fileExt != file.Name() {
+			continue
+		}
+		batch, _, err := s.read(id, 0)
+		if errors.Is(err, ErrNotFound) {
+			// Replayed or discarded since the directory was read
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, *batch)
+	}
+	sort.Slice(batches, func(i, j int) bool { return batches[i].CreatedAt.Before(batches[j].CreatedAt) })
+	return batches, nil
+}
+
+// Get returns a batch with up to limit of its entries
+func (s *Store) Get(id string, limit int) (*Batch, []models.LogEntry, error) {
+	if !s.enabled || !idPattern.MatchString(id) {
+		return nil, nil, ErrNotFound
+	}
+	return s.read(id, limit)
+}
+
+// Replay hands a batch's entries to write and removes the batch once they are written,
+// returning how many there were. Replays run one at a time, so a batch is not written twice.
+func (s *Store) Replay(id string, write func([]models.LogEntry) error) (int, error) {
+	if !s.enabled || !idPattern.MatchString(id) {
+		return 0, ErrNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch, logEntries, err := s.read(id, -1)
+	if err != nil {
+		return 0, err
+	}
+	if err := write(logEntries); err != nil {
+		return 0, err
+	}
+	return len(logEntries), s.removeLocked(batch)
+}
+
+// Discard removes a batch without writing it
+func (s *Store) Discard(id string) error {
+	if !s.enabled || !idPattern.MatchString(id) {
+		return ErrNotFound
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	batch, _, err := s.read(id, 0)
+	if err != nil {
+		return err
+	}
+	return s.removeLocked(batch)
+}
+
+// Stats returns the totals of the queue
+func (s *Store) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Stats{Enabled: s.enabled, Batches: s.batches, Entries: s.entries, Bytes: s.bytes, MaxBytes: s.maxBytes}
+}
+
+func (s *Store) removeLocked(batch *Batch) error {
+	if err := os.Remove(s.path(batch.ID)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("error removing dead-letter batch: %w", err)
+	}
+	s.batches--
+	s.entries -= batch.Entries
+	s.bytes -= batch.Size
+	return nil
+}
+
+// read reads a batch's header and up to limit of its entries; a negative limit reads them all
+func (s *Store) read(id string, limit int) (*Batch, []models.LogEntry, error) {
+	f, err := os.Open(s.path(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("error reading dead-letter batch: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading dead-letter batch: %w", err)
+	}
+
+	// Lines are read whole, as log entries may be longer than a scanner's buffer
+	r := bufio.NewReader(f)
+	var batch Batch
+	if err := readLine(r, &batch); err != nil {
+		return nil, nil, fmt.Errorf("error reading dead-letter batch %s: %w", id, err)
+	}
+	batch.Size = info.Size()
+
+	var logEntries []models.LogEntry
+	for limit < 0 || len(logEntries) < limit {
+		var entry models.LogEntry
+		err := readLine(r, &entry)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("error reading dead-letter batch %s: %w", id, err)
+		}
+		logEntries = append(logEntries, entry)
+	}
+	return &batch, logEntries, nil
+}
+
+func readLine(r *bufio.Reader, v interface{}) error {
+	line, err := r.ReadBytes('\n')
+	if len(line) == 0 {
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return json.Unmarshal(line, v)
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+fileExt)
+}
@@ -0,0 +1,163 @@
+// Package enrich adds derived fields to logs and notices: the location of the client IP, from
+// a MaxMind database, and the browser, operating system and device of the user agent.
+package enrich
+
+import (
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/useragent"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"strings"
+)
+
+// Names of the processors registered by RegisterProcessors
+const (
+	ProcessorGeoIP     = "geoip"
+	ProcessorUserAgent = "user_agent"
+)
+
+// Fields set on log metadata and notice context
+const (
+	GeoField       = "geo"
+	UserAgentField = "user_agent_info"
+)
+
+// Enricher looks up client IPs and parses user agents found at the configured fields
+type Enricher struct {
+	geoip           *GeoIP
+	ipFields        []string
+	userAgentFields []string
+	notices         bool
+}
+
+// New creates an enricher, opening the GeoIP database when one is configured
+func New(cfg *config.EnrichConfig) (*Enricher, error) {
+	e := &Enricher{ipFields: cfg.IPFields, userAgentFields: cfg.UserAgentFields, notices: cfg.Notices}
+	if cfg.GeoIPDatabase != "" {
+		geoip, err := OpenGeoIP(cfg.GeoIPDatabase)
+		if err != nil {
+			return nil, err
+		}
+		e.geoip = geoip
+	}
+	return e, nil
+}
+
+// RegisterProcessors registers the user_agent processor, and the geoip processor when a
+// database is configured, for use in parser.processors and pipeline process steps. It must be
+// called once, before the parser registry is created.
+func (e *Enricher) RegisterProcessors() {
+	if e.geoip != nil {
+		parser.RegisterProcessor(ProcessorGeoIP, parser.ProcessorFunc(e.enrichGeo))
+	}
+	parser.RegisterProcessor(ProcessorUserAgent, parser.ProcessorFunc(e.enrichUserAgent))
+}
+
+// enrichGeo sets the geo field of a log from its client IP. Logs that already have one, such
+// as logs enriched by the agent, are left alone.
+func (e *Enricher) enrichGeo(logEntry *models.LogEntry) {
+	if _, done := logEntry.Metadata[GeoField]; done {
+		return
+	}
+	if geo := e.geo(logEntry.Metadata); geo != nil {
+		logEntry.Metadata[GeoField] = geo
+	}
+}
+
+// enrichUserAgent sets the user_agent_info field of a log from its user agent
+func (e *Enricher) enrichUserAgent(logEntry *models.LogEntry) {
+	if _, done := logEntry.Metadata[UserAgentField]; done {
+		return
+	}
+	if agent := e.userAgent(logEntry.Metadata); agent != nil {
+		logEntry.Metadata[UserAgentField] = agent
+	}
+}
+
+// EnrichNotice sets the geo and user_agent_info fields of a notice's context from the client
+// IP and user agent in its context or, failing that, the CGI data of its request. It does
+// nothing unless enrich.notices is on.
+func (e *Enricher) EnrichNotice(notice *models.Notice, cgiData map[string]interface{}) {
+	if e == nil || !e.notices {
+		return
+	}
+	var geo, agent map[string]interface{}
+	for _, fields := range []map[string]interface{}{notice.Context, cgiData} {
+		if geo == nil && e.geoip != nil {
+			geo = e.geo(fields)
+		}
+		if agent == nil {
+			agent = e.userAgent(fields)
+		}
+	}
+	if geo == nil && agent == nil {
+		return
+	}
+
+	if notice.Context == nil {
+		notice.Context = make(map[string]interface{})
+	}
+	if geo != nil {
+		notice.Context[GeoField] = geo
+	}
+	if agent != nil {
+		notice.Context[UserAgentField] = agent
+	}
+}
+
+// geo looks up the first client IP found at the IP fields
+func (e *Enricher) geo(fields map[string]interface{}) map[string]interface{} {
+	for _, field := range e.ipFields {
+		if ip := lookupString(fields, field); ip != "" {
+			if geo := e.geoip.Lookup(ip); geo != nil {
+				return geo
+			}
+		}
+	}
+	return nil
+}
+
+// userAgent parses the first user agent found at the user agent fields
+func (e *Enricher) userAgent(fields map[string]interface{}) map[string]interface{} {
+	for _, field := range e.userAgentFields {
+		userAgent := lookupString(fields, field)
+		if userAgent == "" {
+			continue
+		}
+		agent := useragent.Parse(userAgent)
+		info := map[string]interface{}{"device": agent.Device, "bot": agent.Bot()}
+		for key, value := range map[string]string{
+			"browser":         agent.Browser,
+			"browser_version": agent.BrowserVersion,
+			"os":              agent.OS,
+			"os_version":      agent.OSVersion,
+		} {
+			if value != "" {
+				info[key] = value
+			}
+		}
+		return info
+	}
+	return nil
+}
+
+// lookupString returns the string at a field, which is tried as a key first and then, when it
+// is dotted, as a path through nested objects
+func lookupString(fields map[string]interface{}, field string) string {
+	value, ok := fields[field]
+	if !ok && strings.Contains(field, ".") {
+		var current interface{} = fields
+		for _, key := range strings.Split(field, ".") {
+			nested, isObject := current.(map[string]interface{})
+			if !isObject {
+				return ""
+			}
+			if current, ok = nested[key]; !ok {
+				return ""
+			}
+		}
+		value = current
+	}
+	s, _ := value.(string)
+	return s
+}
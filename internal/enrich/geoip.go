@@ -0,0 +1,102 @@
+package enrich
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// geoLanguage is the language of the place names taken from the database
+const geoLanguage = "en"
+
+// GeoIP resolves IP addresses to the country, region and city they are located in, using a
+// MaxMind City or Country database
+type GeoIP struct {
+	reader *mmdbReader
+}
+
+// OpenGeoIP opens a MaxMind GeoIP2 or GeoLite2 City or Country database
+func OpenGeoIP(path string) (*GeoIP, error) {
+	reader, err := openMMDB(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(reader.DatabaseType, "City") && !strings.Contains(reader.DatabaseType, "Country") {
+		return nil, fmt.Errorf("%s is a %s database, not a City or Country one", path, reader.DatabaseType)
+	}
+	return &GeoIP{reader: reader}, nil
+}
+
+// Lookup returns the geo fields of an IP address: country_code, country, region, city,
+// latitude, longitude and time_zone, as far as the database knows them. It returns nil for
+// addresses that cannot be parsed or are not in the database, such as private ones. Addresses
+// may carry a port, and the first of a comma-separated X-Forwarded-For list is used.
+func (g *GeoIP) Lookup(ip string) map[string]interface{} {
+	addr, ok := parseClientIP(ip)
+	if !ok || addr.IsPrivate() || addr.IsLoopback() {
+		return nil
+	}
+	value, err := g.reader.lookup(addr)
+	if err != nil {
+		return nil
+	}
+	record, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	geo := make(map[string]interface{})
+	country := lookupMap(record, "country")
+	if code, ok := country["iso_code"].(string); ok {
+		geo["country_code"] = code
+	}
+	if name := placeName(country); name != "" {
+		geo["country"] = name
+	}
+	if subdivisions, ok := record["subdivisions"].([]interface{}); ok && len(subdivisions) > 0 {
+		if region, ok := subdivisions[0].(map[string]interface{}); ok {
+			if name := placeName(region); name != "" {
+				geo["region"] = name
+			}
+		}
+	}
+	if name := placeName(lookupMap(record, "city")); name != "" {
+		geo["city"] = name
+	}
+	location := lookupMap(record, "location")
+	if lat, ok := location["latitude"].(float64); ok {
+		if lon, ok := location["longitude"].(float64); ok {
+			geo["latitude"], geo["longitude"] = lat, lon
+		}
+	}
+	if tz, ok := location["time_zone"].(string); ok {
+		geo["time_zone"] = tz
+	}
+	if len(geo) == 0 {
+		return nil
+	}
+	return geo
+}
+
+// parseClientIP parses an address as found in logs and request data
+func parseClientIP(ip string) (netip.Addr, bool) {
+	ip, _, _ = strings.Cut(ip, ",")
+	ip = strings.TrimSpace(ip)
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	addr, err := netip.ParseAddr(strings.Trim(ip, "[]"))
+	return addr, err == nil
+}
+
+func lookupMap(record map[string]interface{}, key string) map[string]interface{} {
+	nested, _ := record[key].(map[string]interface{})
+	return nested
+}
+
+// placeName returns the English name of a country, subdivision or city record
+func placeName(place map[string]interface{}) string {
+	name, _ := lookupMap(place, "names")[geoLanguage].(string)
+	return name
+}
@@ -0,0 +1,353 @@
+package enrich
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"os"
+)
+
+// metadataMarker precedes the metadata map at the end of a MaxMind DB file
+var metadataMarker = []byte("\xab\xcd\xefMaxMind.com")
+
+// maxMetadataSize bounds how far from the end of the file the metadata marker is looked for
+const maxMetadataSize = 128 * 1024
+
+// maxDecodeDepth bounds recursion into nested maps and arrays of a corrupt database
+const maxDecodeDepth = 32
+
+// errCorrupt is returned for data that does not follow the MaxMind DB format
+var errCorrupt = errors.New("corrupt MaxMind database")
+
+// Data section field types
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBool
+	typeFloat
+)
+
+// mmdbReader looks addresses up in a MaxMind DB file, the format of the GeoIP2 and GeoLite2
+// databases. The file is read into memory; records are decoded into maps, slices, strings,
+// numbers and booleans.
+type mmdbReader struct {
+	buf        []byte
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	// ipv4Start is the node IPv4 lookups start from in an IPv6 tree, reached through the
+	// 96 zero bits of an IPv4-mapped address
+	ipv4Start uint
+	// DatabaseType is the kind of database, such as "GeoLite2-City"
+	DatabaseType string
+}
+
+// openMMDB reads and checks a MaxMind DB file
+func openMMDB(path string) (*mmdbReader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	searchFrom := 0
+	if len(buf) > maxMetadataSize {
+		searchFrom = len(buf) - maxMetadataSize
+	}
+	i := bytes.LastIndex(buf[searchFrom:], metadataMarker)
+	if i < 0 {
+		return nil, fmt.Errorf("%s is not a MaxMind database: no metadata found", path)
+	}
+	metaStart := searchFrom + i + len(metadataMarker)
+	metaValue, _, err := (&decoder{data: buf[metaStart:]}).decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("reading metadata of %s: %w", path, err)
+	}
+	meta, ok := metaValue.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("reading metadata of %s: %w", path, errCorrupt)
+	}
+
+	r := &mmdbReader{buf: buf}
+	r.nodeCount = metadataUint(meta, "node_count")
+	r.recordSize = metadataUint(meta, "record_size")
+	r.ipVersion = metadataUint(meta, "ip_version")
+	r.DatabaseType, _ = meta["database_type"].(string)
+	if major := metadataUint(meta, "binary_format_major_version"); major != 2 {
+		return nil, fmt.Errorf("%s has unsupported MaxMind format version %d", path, major)
+	}
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("%s has unsupported record size %d", path, r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("%s has unsupported IP version %d", path, r.ipVersion)
+	}
+
+	// The search tree is followed by 16 zero bytes, then the data section
+	treeSize := r.nodeCount * r.recordSize / 4
+	dataStart := treeSize + 16
+	if dataStart > uint(searchFrom+i) {
+		return nil, fmt.Errorf("reading %s: %w", path, errCorrupt)
+	}
+	r.data = buf[dataStart : searchFrom+i]
+
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for bit := 0; bit < 96 && node < r.nodeCount; bit++ {
+			if node, err = r.record(node, 0); err != nil {
+				return nil, fmt.Errorf("reading %s: %w", path, err)
+			}
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// lookup returns the record of the network containing addr, or nil when there is none
+func (r *mmdbReader) lookup(addr netip.Addr) (interface{}, error) {
+	addr = addr.Unmap()
+	node, bits := uint(0), 128
+	if addr.Is4() {
+		bits = 32
+		if r.ipVersion == 6 {
+			node = r.ipv4Start
+		}
+	} else if r.ipVersion == 4 {
+		return nil, nil
+	}
+
+	ip := addr.AsSlice()
+	for i := 0; i < bits && node < r.nodeCount; i++ {
+		bit := uint(ip[i/8]>>(7-uint(i%8))) & 1
+		var err error
+		if node, err = r.record(node, bit); err != nil {
+			return nil, err
+		}
+	}
+	if node <= r.nodeCount {
+		// Past the last bit or at the empty record: the address is not in the database
+		return nil, nil
+	}
+
+	offset := node - r.nodeCount - 16
+	value, _, err := (&decoder{data: r.data}).decode(offset, 0)
+	return value, err
+}
+
+// record reads the left (bit 0) or right (bit 1) record of a search tree node
+func (r *mmdbReader) record(node, bit uint) (uint, error) {
+	nodeSize := r.recordSize / 4
+	start := node * nodeSize
+	if start+nodeSize > uint(len(r.buf)) {
+		return 0, errCorrupt
+	}
+	b := r.buf[start : start+nodeSize]
+
+	switch r.recordSize {
+	case 24:
+		b = b[bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+	case 28:
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6]), nil
+	default:
+		return uint(binary.BigEndian.Uint32(b[bit*4:])), nil
+	}
+}
+
+// metadataUint reads an unsigned integer field of the metadata map
+func metadataUint(meta map[string]interface{}, key string) uint {
+	switch v := meta[key].(type) {
+	case uint64:
+		return uint(v)
+	case uint32:
+		return uint(v)
+	case uint16:
+		return uint(v)
+	}
+	return 0
+}
+
+// decoder decodes values of a MaxMind DB data section, in which pointers are offsets from the
+// start of the section
+type decoder struct {
+	data []byte
+}
+
+// decode decodes the value at offset, returning it and the offset after it
+func (d *decoder) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > maxDecodeDepth {
+		return nil, 0, errCorrupt
+	}
+	if offset >= uint(len(d.data)) {
+		return nil, 0, errCorrupt
+	}
+	ctrl := d.data[offset]
+	offset++
+	kind := uint(ctrl >> 5)
+
+	if kind == typePointer {
+		pointer, next, err := d.pointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(pointer, depth+1)
+		return value, next, err
+	}
+
+	if kind == typeExtended {
+		if offset >= uint(len(d.data)) {
+			return nil, 0, errCorrupt
+		}
+		kind = 7 + uint(d.data[offset])
+		offset++
+	}
+	size, offset, err := d.size(ctrl, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch kind {
+	case typeMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			var key, value interface{}
+			if key, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			name, ok := key.(string)
+			if !ok {
+				return nil, 0, errCorrupt
+			}
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			m[name] = value
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			var value interface{}
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+		}
+		return a, offset, nil
+	case typeBool:
+		return size != 0, offset, nil
+	}
+
+	end := offset + size
+	if end > uint(len(d.data)) || end < offset {
+		return nil, 0, errCorrupt
+	}
+	b := d.data[offset:end]
+	switch kind {
+	case typeString:
+		return string(b), end, nil
+	case typeBytes:
+		return append([]byte(nil), b...), end, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errCorrupt
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), end, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errCorrupt
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), end, nil
+	case typeUint16, typeUint32, typeUint64:
+		if size > 8 {
+			return nil, 0, errCorrupt
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n, end, nil
+	case typeInt32:
+		if size > 4 {
+			return nil, 0, errCorrupt
+		}
+		var n uint32
+		for _, c := range b {
+			n = n<<8 | uint32(c)
+		}
+		return int64(int32(n)), end, nil
+	case typeUint128:
+		if size > 16 {
+			return nil, 0, errCorrupt
+		}
+		return new(big.Int).SetBytes(b), end, nil
+	}
+	return nil, 0, errCorrupt
+}
+
+// size reads the payload size of a field, which may continue into the bytes after the
+// control byte
+func (d *decoder) size(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint(ctrl & 0x1f)
+	if size < 29 {
+		return size, offset, nil
+	}
+	extra := size - 28
+	if offset+extra > uint(len(d.data)) {
+		return 0, 0, errCorrupt
+	}
+	var n uint
+	for _, c := range d.data[offset : offset+extra] {
+		n = n<<8 | uint(c)
+	}
+	switch size {
+	case 29:
+		size = 29 + n
+	case 30:
+		size = 285 + n
+	default:
+		size = 65821 + n
+	}
+	return size, offset + extra, nil
+}
+
+// pointer reads a pointer field, returning the offset it points to and the offset after it
+func (d *decoder) pointer(ctrl byte, offset uint) (uint, uint, error) {
+	extra := uint((ctrl>>3)&0x3) + 1
+	if offset+extra > uint(len(d.data)) {
+		return 0, 0, errCorrupt
+	}
+	var n uint
+	if extra < 4 {
+		n = uint(ctrl & 0x7)
+	}
+	for _, c := range d.data[offset : offset+extra] {
+		n = n<<8 | uint(c)
+	}
+	switch extra {
+	case 2:
+		n += 2048
+	case 3:
+		n += 526336
+	}
+	return n, offset + extra, nil
+}
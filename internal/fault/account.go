@@ -0,0 +1,62 @@
+package fault
+
+import (
+	"strconv"
+	"strings"
+)
+
+// maxAccountIDLength bounds extracted account identifiers; longer values are not treated as one
+const maxAccountIDLength = 255
+
+// ExtractAccount returns the account or tenant a notice was reported for: the first string or
+// number found in its context at any of fields. A field may be a top-level key, including one
+// containing dots, or a dotted path into nested objects. It returns "" when none is found.
+func ExtractAccount(context map[string]interface{}, fields []string) string {
+	if len(context) == 0 {
+		return ""
+	}
+	for _, field := range fields {
+		value, ok := context[field]
+		if !ok && strings.Contains(field, ".") {
+			value, ok = lookupPath(context, strings.Split(field, "."))
+		}
+		if !ok {
+			continue
+		}
+		if account := accountString(value); account != "" {
+			return account
+		}
+	}
+	return ""
+}
+
+// lookupPath follows a path of keys through nested objects
+func lookupPath(object map[string]interface{}, path []string) (interface{}, bool) {
+	var current interface{} = object
+	for _, key := range path {
+		nested, isObject := current.(map[string]interface{})
+		if !isObject {
+			return nil, false
+		}
+		var found bool
+		if current, found = nested[key]; !found {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// accountString formats an account identifier, or returns "" for values that cannot be one
+func accountString(value interface{}) string {
+	var account string
+	switch v := value.(type) {
+	case string:
+		account = strings.TrimSpace(v)
+	case float64:
+		account = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if len(account) > maxAccountIDLength {
+		return ""
+	}
+	return account
+}
@@ -0,0 +1,90 @@
+package fault
+
+import (
+	"context"
+	"log"
+	"log-ingestion-service/internal/storage"
+	"sync"
+	"time"
+)
+
+// backtraceCollectInterval is how often unused backtraces and payload sections are deleted
+const backtraceCollectInterval = time.Hour
+
+// backtraceGrace keeps recently used backtraces and payload sections, which notices being stored
+// may still reference
+const backtraceGrace = 24 * time.Hour
+
+// backtraceCollectBatch bounds the rows deleted by one statement
+const backtraceCollectBatch = 10000
+
+// BacktraceCollector periodically deletes stored backtraces and shared payload sections that no
+// notice references any more, once retention or fault deletion removed their notices. Several
+// instances may run it at once.
+type BacktraceCollector struct {
+	repo   *storage.Repository
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewBacktraceCollector creates a backtrace collector
+func NewBacktraceCollector(repo *storage.Repository) *BacktraceCollector {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &BacktraceCollector{
+		repo:   repo,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start begins deleting unused backtraces and payload sections every hour
+func (b *BacktraceCollector) Start() {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		ticker := time.NewTicker(backtraceCollectInterval)
+		defer ticker.Stop()
+		for {
+			b.collect(b.ctx)
+			select {
+			case <-b.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Shutdown stops the collector, waiting for a pass in progress
+func (b *BacktraceCollector) Shutdown() {
+	b.cancel()
+	b.wg.Wait()
+}
+
+// collect deletes unused backtraces, then unused payload sections
+func (b *BacktraceCollector) collect(ctx context.Context) {
+	b.collectUnused(ctx, "backtraces", b.repo.DeleteUnusedBacktraces)
+	b.collectUnused(ctx, "payload sections", b.repo.DeleteUnusedPayloadSections)
+}
+
+// collectUnused runs deleteUnused in batches until nothing unused is left
+func (b *BacktraceCollector) collectUnused(ctx context.Context, what string, deleteUnused func(context.Context, time.Duration, int) (int64, error)) {
+	var total int64
+	for ctx.Err() == nil {
+		deleted, err := deleteUnused(ctx, backtraceGrace, backtraceCollectBatch)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("ERROR: Failed to delete unused %s: %v", what, err)
+			}
+			break
+		}
+		total += deleted
+		if deleted < backtraceCollectBatch {
+			break
+		}
+	}
+	if total > 0 {
+		log.Printf("INFO: Deleted %d unused %s", total, what)
+	}
+}
@@ -0,0 +1,116 @@
+package fault
+
+import (
+	"context"
+	"log"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"sync"
+	"time"
+)
+
+// deployAnalysisInterval is how often new deploys are recorded and recent ones analyzed
+const deployAnalysisInterval = 5 * time.Minute
+
+// deployScanOverlap is how far each pass looks back before the previous one, for notices that
+// were queued for a batched insert when it ran
+const deployScanOverlap = 10 * time.Minute
+
+// deploySettle is how long after a deploy's window ends its counts keep being refreshed, so
+// notices still queued at the end of the window are counted
+const deploySettle = 5 * time.Minute
+
+// deployAnalysisBatch bounds the deploys analyzed by one statement
+const deployAnalysisBatch = 100
+
+// DeployAnalyzer periodically records deploys, inferred from the first notice reported with
+// each revision in an environment, and compares the notices in equal windows before and after
+// each deploy to flag deploys followed by an error spike. Counts are refreshed until the window
+// after a deploy has ended and are then final. Several instances may run it at once.
+type DeployAnalyzer struct {
+	repo    *storage.Repository
+	cfg     *config.DeployConfig
+	lastRun time.Time
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// NewDeployAnalyzer creates a deploy analyzer
+func NewDeployAnalyzer(repo *storage.Repository, cfg *config.DeployConfig) *DeployAnalyzer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &DeployAnalyzer{
+		repo:   repo,
+		cfg:    cfg,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start begins analyzing deploys every five minutes
+func (a *DeployAnalyzer) Start() {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+		ticker := time.NewTicker(deployAnalysisInterval)
+		defer ticker.Stop()
+		for {
+			a.analyze(a.ctx, time.Now())
+			select {
+			case <-a.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Shutdown stops the analyzer, waiting for a pass in progress
+func (a *DeployAnalyzer) Shutdown() {
+	a.cancel()
+	a.wg.Wait()
+}
+
+// analyze records the deploys seen since the previous pass, then refreshes the counts of every
+// deploy that is not final. The first pass after startup looks back from the latest recorded
+// deploy, or over every stored notice when none is recorded yet.
+func (a *DeployAnalyzer) analyze(ctx context.Context, now time.Time) {
+	since := a.lastRun
+	if since.IsZero() {
+		latest, err := a.repo.LatestDeployAt(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("ERROR: Failed to get latest deploy: %v", err)
+			}
+			return
+		}
+		if latest != nil {
+			since = *latest
+		}
+	}
+
+	if _, err := a.repo.RecordDeploys(ctx, since.Add(-deployScanOverlap)); err != nil {
+		if ctx.Err() == nil {
+			log.Printf("ERROR: Failed to record deploys: %v", err)
+		}
+		return
+	}
+	a.lastRun = now
+
+	completeBefore := now.Add(-deploySettle)
+	var afterID int64
+	for ctx.Err() == nil {
+		analyzed, lastID, err := a.repo.AnalyzeDeploys(ctx, a.cfg.Window, a.cfg.SpikeFactor, a.cfg.MinNotices,
+			completeBefore, afterID, deployAnalysisBatch)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("ERROR: Failed to analyze deploys: %v", err)
+			}
+			return
+		}
+		if analyzed < deployAnalysisBatch {
+			return
+		}
+		afterID = lastID
+	}
+}
@@ -0,0 +1,162 @@
+package fault
+
+import (
+	"context"
+	"log"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"sync"
+	"time"
+)
+
+// environmentRefreshInterval bounds how stale the cached environments may get
+const environmentRefreshInterval = 30 * time.Second
+
+// environmentRetentionInterval is how often notices past their environment's retention are deleted
+const environmentRetentionInterval = time.Hour
+
+// environmentRetentionBatch bounds the notices deleted by one statement
+const environmentRetentionBatch = 10000
+
+// EnvironmentSet caches environments and answers per-environment settings for faults
+type EnvironmentSet struct {
+	repo     *storage.Repository
+	mu       sync.Mutex
+	envs     []models.Environment
+	loadedAt time.Time
+}
+
+// NewEnvironmentSet creates a new environment set
+func NewEnvironmentSet(repo *storage.Repository) *EnvironmentSet {
+	return &EnvironmentSet{repo: repo}
+}
+
+// Invalidate forces the next lookup to reload environments from the database
+func (s *EnvironmentSet) Invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// Lookup returns the environment a fault belongs to: its project's environment of that name, or
+// else the global one. It returns nil for environments that are not defined.
+func (s *EnvironmentSet) Lookup(ctx context.Context, fault *models.Fault) *models.Environment {
+	var global *models.Environment
+	envs := s.load(ctx)
+	for i := range envs {
+		env := &envs[i]
+		if env.Name != fault.Environment {
+			continue
+		}
+		if env.ProjectID == nil {
+			global = env
+		} else if fault.ProjectID != nil && *env.ProjectID == *fault.ProjectID {
+			return env
+		}
+	}
+	return global
+}
+
+// AlertingEnabled reports whether notifications are sent for a fault's environment. Faults in
+// undefined environments alert.
+func (s *EnvironmentSet) AlertingEnabled(ctx context.Context, fault *models.Fault) bool {
+	env := s.Lookup(ctx, fault)
+	return env == nil || env.AlertingEnabled
+}
+
+// load returns the cached environments, refreshing them when stale. If they cannot be loaded
+// the last known environments are used.
+func (s *EnvironmentSet) load(ctx context.Context) []models.Environment {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if time.Since(s.loadedAt) < environmentRefreshInterval {
+		return s.envs
+	}
+
+	envs, err := s.repo.ListEnvironments(ctx, nil)
+	if err != nil {
+		log.Printf("WARN: Failed to load environments: %v", err)
+		return s.envs
+	}
+	s.envs = envs
+	s.loadedAt = time.Now()
+	return s.envs
+}
+
+// EnvironmentRetention periodically deletes the notices of environments with a retention
+// period once they are older than it. Several instances may run it at once.
+type EnvironmentRetention struct {
+	repo   *storage.Repository
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewEnvironmentRetention creates an environment retention enforcer
+func NewEnvironmentRetention(repo *storage.Repository) *EnvironmentRetention {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EnvironmentRetention{
+		repo:   repo,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start begins deleting expired notices every hour
+func (e *EnvironmentRetention) Start() {
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(environmentRetentionInterval)
+		defer ticker.Stop()
+		for {
+			e.enforce(e.ctx, time.Now())
+			select {
+			case <-e.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Shutdown stops the enforcer, waiting for a pass in progress
+func (e *EnvironmentRetention) Shutdown() {
+	e.cancel()
+	e.wg.Wait()
+}
+
+// enforce deletes the expired notices of every environment with a retention period
+func (e *EnvironmentRetention) enforce(ctx context.Context, now time.Time) {
+	envs, err := e.repo.ListEnvironments(ctx, nil)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("ERROR: Failed to list environments: %v", err)
+		}
+		return
+	}
+	for _, env := range envs {
+		if env.RetentionDays == nil {
+			continue
+		}
+		cutoff := now.AddDate(0, 0, -*env.RetentionDays)
+		var total int64
+		for ctx.Err() == nil {
+			deleted, err := e.repo.DeleteExpiredEnvironmentNotices(ctx, env.ID, cutoff, environmentRetentionBatch)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.Printf("ERROR: Failed to delete expired notices of environment %q: %v", env.Name, err)
+				}
+				break
+			}
+			total += deleted
+			if deleted < environmentRetentionBatch {
+				break
+			}
+		}
+		if total > 0 {
+			log.Printf("INFO: Deleted %d notices past the %d-day retention of environment %q", total, *env.RetentionDays, env.Name)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package fault
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/notify"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"sync"
+	"time"
+)
+
+// escalationInterval is how often the escalator evaluates its rules
+const escalationInterval = time.Minute
+
+// escalationLookback bounds the faults checked on the first pass; later passes check faults
+// seen since the previous one
+const escalationLookback = 24 * time.Hour
+
+// escalationBatch bounds the faults one rule escalates per pass
+const escalationBatch = 100
+
+// Escalator periodically raises the severity of faults that cross the thresholds of an
+// escalation rule, recording each escalation in fault history and notifying about it. Rules
+// only ever raise severity, so several instances may run it at once.
+type Escalator struct {
+	repo         *storage.Repository
+	notifier     *notify.Dispatcher
+	environments *EnvironmentSet
+	lastRun      time.Time
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// NewEscalator creates an escalator
+func NewEscalator(repo *storage.Repository, notifier *notify.Dispatcher) *Escalator {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Escalator{
+		repo:         repo,
+		notifier:     notifier,
+		environments: NewEnvironmentSet(repo),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Start begins evaluating escalation rules every minute
+func (e *Escalator) Start() {
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(escalationInterval)
+		defer ticker.Stop()
+		for {
+			e.evaluate(e.ctx, time.Now())
+			select {
+			case <-e.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Shutdown stops the escalator, waiting for a pass in progress
+func (e *Escalator) Shutdown() {
+	e.cancel()
+	e.wg.Wait()
+}
+
+// ValidateRule checks an escalation rule's settings before it is saved
+func ValidateRule(rule *models.EscalationRule) error {
+	if rule.MinOccurrences == nil && rule.MinAffectedUsers == nil {
+		return fmt.Errorf("at least one of min_occurrences or min_affected_users is required")
+	}
+	if rule.MinOccurrences != nil && *rule.MinOccurrences < 1 {
+		return fmt.Errorf("min_occurrences must be at least 1")
+	}
+	if rule.MinAffectedUsers != nil && *rule.MinAffectedUsers < 1 {
+		return fmt.Errorf("min_affected_users must be at least 1")
+	}
+	if rule.WindowSeconds != nil && *rule.WindowSeconds < 1 {
+		return fmt.Errorf("window_seconds must be at least 1")
+	}
+	if models.SeverityRank(rule.Severity) <= 0 {
+		return fmt.Errorf("severity must be one of %q, %q or %q, got %q",
+			models.SeverityMedium, models.SeverityHigh, models.SeverityCritical, rule.Severity)
+	}
+	return nil
+}
+
+// evaluate runs one pass of every enabled rule over the faults seen since the previous pass.
+// Faults that stop occurring are not escalated later, even if a windowed threshold would
+// still hold.
+func (e *Escalator) evaluate(ctx context.Context, now time.Time) {
+	since := e.lastRun
+	if since.IsZero() {
+		since = now.Add(-escalationLookback)
+	}
+
+	rules, err := e.repo.ListEscalationRules(ctx, nil, true)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("ERROR: Failed to list escalation rules: %v", err)
+		}
+		return
+	}
+
+	failed := false
+	for i := range rules {
+		if err := e.apply(ctx, &rules[i], since); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("ERROR: Failed to apply escalation rule %d: %v", rules[i].ID, err)
+			failed = true
+		}
+	}
+	// Retry the same period next time if a rule could not be applied
+	if !failed {
+		e.lastRun = now
+	}
+}
+
+// apply escalates the faults crossing one rule's thresholds
+func (e *Escalator) apply(ctx context.Context, rule *models.EscalationRule, since time.Time) error {
+	ids, err := e.repo.FindEscalationCandidates(ctx, rule, since, escalationBatch)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		previous, err := e.repo.EscalateFault(ctx, id, rule)
+		if err != nil {
+			return err
+		}
+		if previous == "" {
+			// Already raised by another rule or instance
+			continue
+		}
+		e.notifyEscalated(ctx, id, previous, rule)
+	}
+	return nil
+}
+
+// notifyEscalated announces a fault whose severity a rule raised, unless alerting is off for its
+// environment
+func (e *Escalator) notifyEscalated(ctx context.Context, faultID int64, previous string, rule *models.EscalationRule) {
+	if e.notifier == nil {
+		return
+	}
+	fault, err := e.repo.GetFault(ctx, faultID)
+	if err != nil {
+		log.Printf("WARN: Failed to load escalated fault %d: %v", faultID, err)
+		return
+	}
+	if !e.environments.AlertingEnabled(ctx, fault) {
+		return
+	}
+	e.notifier.DispatchAsync(notify.Event{
+		Type: notify.EventFaultEscalated,
+		Message: fmt.Sprintf("Fault escalated from %s to %s by rule %q: %s: %s",
+			previous, rule.Severity, rule.Name, fault.ErrorClass, fault.Message),
+		Fault: fault,
+	})
+}
@@ -3,29 +3,189 @@ package fault
 import (
 	"context"
 	"fmt"
+	"log-ingestion-service/internal/batch"
+	"log-ingestion-service/internal/enrich"
+	"log-ingestion-service/internal/feature"
+	"log-ingestion-service/internal/notify"
+	"log-ingestion-service/internal/redact"
 	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/internal/validator"
 	"log-ingestion-service/pkg/models"
 	"time"
 )
 
 // Grouper handles fault grouping logic
 type Grouper struct {
-	repo *storage.Repository
+	repo         *storage.Repository
+	mergeRules   *MergeRuleSet
+	environments *EnvironmentSet
+	notifier     *notify.Dispatcher
+	notices      *batch.NoticeBatcher
+	flags        *feature.Flags
+	workflow     *Workflow
+	// accountFields are the notice context paths holding the affected account
+	accountFields []string
+	enricher      *enrich.Enricher
+	redactor      *redact.Engine
+	limits        *validator.MetadataLimiter
+	sampler       *NoticeSampler
 }
 
-// NewGrouper creates a new grouper
-func NewGrouper(repo *storage.Repository) *Grouper {
-	return &Grouper{repo: repo}
+// NewGrouper creates a new grouper. When notices is nil, or the notice_batching flag is off for
+// the fault's project, each notice is written as it is processed. Notices are tagged with the
+// account found in their context at accountFields, enriched by enricher and then have sensitive
+// data masked by redactor, each when it is not nil. Their context, params, session, cookies and
+// environment are then truncated to limits. Notices left out by sampler only count towards their
+// fault's occurrences. New faults start in the workflow's initial state.
+func NewGrouper(repo *storage.Repository, notifier *notify.Dispatcher, notices *batch.NoticeBatcher, flags *feature.Flags, workflow *Workflow, accountFields []string, enricher *enrich.Enricher, redactor *redact.Engine, limits *validator.MetadataLimiter, sampler *NoticeSampler) *Grouper {
+	return &Grouper{
+		repo:          repo,
+		mergeRules:    NewMergeRuleSet(repo),
+		environments:  NewEnvironmentSet(repo),
+		notifier:      notifier,
+		notices:       notices,
+		flags:         flags,
+		workflow:      workflow,
+		accountFields: accountFields,
+		enricher:      enricher,
+		redactor:      redactor,
+		limits:        limits,
+		sampler:       sampler,
+	}
+}
+
+// MergeRules returns the grouper's merge rule set
+func (g *Grouper) MergeRules() *MergeRuleSet {
+	return g.mergeRules
+}
+
+// Environments returns the grouper's environment set
+func (g *Grouper) Environments() *EnvironmentSet {
+	return g.environments
 }
 
 // ProcessNotice processes a notice and creates or updates the corresponding fault
 func (g *Grouper) ProcessNotice(ctx context.Context, noticeReq *models.NoticeRequest) (*models.Fault, *models.Notice, error) {
-	// Extract error information
-	errorClass := noticeReq.Error.Class
-	if errorClass == "" {
-		errorClass = "UnknownError"
+	fault := g.buildFault(noticeReq)
+	
+	// Find or create fault
+	created := false
+	existingFault, err := g.repo.FindFaultByFingerprint(ctx, fault)
+	if err != nil {
+		// Fault doesn't exist; a merge rule may route it to a canonical fault
+		canonical, err := g.applyMergeRules(ctx, fault)
+		if err != nil {
+			return nil, nil, err
+		}
+		if canonical != nil {
+			fault = canonical
+		} else {
+			createdFault, err := g.repo.CreateFault(ctx, fault)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error creating fault: %w", err)
+			}
+			fault = createdFault
+			created = true
+		}
+	} else {
+		fault = existingFault
+		// Update last_seen_at
+		fault.LastSeenAt = time.Now()
+	}
+	
+	// During an error storm most notices of a fault only count towards its occurrences
+	if !created && !g.sampler.Store(fault.ID) {
+		return g.countNotice(ctx, fault)
 	}
 	
+	// With a notice batcher, the notice and its occurrence count are written in the next flush
+	if g.batchNotices(ctx, fault) {
+		notice := g.buildNotice(ctx, noticeReq, fault.ID)
+		notice.SharePayload = g.sharePayload(ctx, fault)
+		if err := g.notices.Add(notice); err != nil {
+			return nil, nil, fmt.Errorf("error queueing notice: %w", err)
+		}
+		fault.OccurrenceCount++
+		if created {
+			g.notifyCreated(ctx, fault)
+		}
+		return fault, notice, nil
+	}
+	
+	// Increment occurrence count
+	if err := g.repo.IncrementFaultOccurrence(ctx, fault.ID); err != nil {
+		return nil, nil, fmt.Errorf("error incrementing occurrence: %w", err)
+	}
+	
+	// Create notice
+	notice := g.buildNotice(ctx, noticeReq, fault.ID)
+	notice.SharePayload = g.sharePayload(ctx, fault)
+	
+	// Save notice
+	if err := g.repo.CreateNotice(ctx, notice); err != nil {
+		return nil, nil, fmt.Errorf("error creating notice: %w", err)
+	}
+	
+	// Update fault occurrence count from database
+	updatedFault, err := g.repo.GetFault(ctx, fault.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error getting updated fault: %w", err)
+	}
+	
+	if created {
+		g.notifyCreated(ctx, updatedFault)
+	}
+	
+	return updatedFault, notice, nil
+}
+
+// countNotice adds an occurrence to a fault without storing its notice. The notice returned
+// has an ID for the response but is not stored.
+func (g *Grouper) countNotice(ctx context.Context, fault *models.Fault) (*models.Fault, *models.Notice, error) {
+	if g.batchNotices(ctx, fault) {
+		g.notices.Count(fault.ID)
+	} else if err := g.repo.IncrementFaultOccurrence(ctx, fault.ID); err != nil {
+		return nil, nil, fmt.Errorf("error incrementing occurrence: %w", err)
+	}
+	fault.OccurrenceCount++
+	notice := &models.Notice{
+		ID:        generateULID(),
+		FaultID:   fault.ID,
+		CreatedAt: time.Now(),
+	}
+	return fault, notice, nil
+}
+
+// batchNotices reports whether notices of a fault go through the notice batcher
+func (g *Grouper) batchNotices(ctx context.Context, fault *models.Fault) bool {
+	if g.notices == nil {
+		return false
+	}
+	return g.flags == nil || g.flags.Enabled(ctx, feature.NoticeBatching, fault.ProjectID)
+}
+
+// sharePayload reports whether notices of a fault share their environment data with other notices
+func (g *Grouper) sharePayload(ctx context.Context, fault *models.Fault) bool {
+	return g.flags != nil && g.flags.Enabled(ctx, feature.NoticePayloadDedup, fault.ProjectID)
+}
+
+// notifyCreated announces a fault seen for the first time, unless alerting is off for its environment
+func (g *Grouper) notifyCreated(ctx context.Context, fault *models.Fault) {
+	if g.notifier == nil || !g.environments.AlertingEnabled(ctx, fault) {
+		return
+	}
+	g.notifier.DispatchAsync(notify.Event{
+		Type:    notify.EventFaultCreated,
+		Message: fmt.Sprintf("New fault: %s: %s", fault.ErrorClass, fault.Message),
+		Fault:   fault,
+	})
+}
+
+// buildFault extracts the fault a notice belongs to, before it is matched against stored faults
+func (g *Grouper) buildFault(noticeReq *models.NoticeRequest) *models.Fault {
+	// Extract error information
+	errorClass := noticeErrorClass(noticeReq)
+	
 	message := noticeReq.Error.Message
 	if message == "" {
 		message = "No error message"
@@ -41,7 +201,7 @@ func (g *Grouper) ProcessNotice(ctx context.Context, noticeReq *models.NoticeReq
 	}
 	
 	// Create fault fingerprint
-	fault := &models.Fault{
+	return &models.Fault{
 		ProjectID:   nil, // Single project for now
 		ErrorClass:  errorClass,
 		Message:     message,
@@ -49,47 +209,84 @@ func (g *Grouper) ProcessNotice(ctx context.Context, noticeReq *models.NoticeReq
 		Environment:  environment,
 		Resolved:    false,
 		Ignored:     false,
+		State:       g.workflow.Initial(),
 		Tags:        []string{},
 		Public:      false,
 		FirstSeenAt: time.Now(),
 		LastSeenAt:  time.Now(),
 	}
+}
+
+// noticeErrorClass returns the error class a notice is grouped by
+func noticeErrorClass(req *models.NoticeRequest) string {
+	if req.Error.Class == "" {
+		return "UnknownError"
+	}
+	return req.Error.Class
+}
+
+// NoticePreview describes how a notice would be grouped, without storing anything
+type NoticePreview struct {
+	Fault           *models.Fault  `json:"fault"`
+	Notice          *models.Notice `json:"notice"`
+	Fingerprint     string         `json:"fingerprint"`
+	ExistingFaultID *int64         `json:"existing_fault_id,omitempty"`
+	MergeRuleID     *int64         `json:"merge_rule_id,omitempty"`
+}
+
+// Preview runs a notice through extraction and fingerprinting and reports which fault it
+// would be grouped into. Nothing is written, so merge rule match counts are not updated.
+func (g *Grouper) Preview(ctx context.Context, noticeReq *models.NoticeRequest) (*NoticePreview, error) {
+	fault := g.buildFault(noticeReq)
+	preview := &NoticePreview{
+		Fault:       fault,
+		Notice:      g.buildNotice(ctx, noticeReq, 0),
+		Fingerprint: Fingerprint(fault),
+	}
 	
-	// Find or create fault
 	existingFault, err := g.repo.FindFaultByFingerprint(ctx, fault)
-	if err != nil {
-		// Fault doesn't exist, create it
-		createdFault, err := g.repo.CreateFault(ctx, fault)
-		if err != nil {
-			return nil, nil, fmt.Errorf("error creating fault: %w", err)
-		}
-		fault = createdFault
-	} else {
-		fault = existingFault
-		// Update last_seen_at
-		fault.LastSeenAt = time.Now()
+	if err == nil {
+		preview.ExistingFaultID = &existingFault.ID
+		return preview, nil
 	}
 	
-	// Increment occurrence count
-	if err := g.repo.IncrementFaultOccurrence(ctx, fault.ID); err != nil {
-		return nil, nil, fmt.Errorf("error incrementing occurrence: %w", err)
+	rule, err := g.mergeRules.Match(ctx, fault)
+	if err != nil {
+		return nil, fmt.Errorf("error matching merge rules: %w", err)
+	}
+	if rule != nil {
+		preview.ExistingFaultID = &rule.TargetFaultID
+		preview.MergeRuleID = &rule.ID
 	}
 	
-	// Create notice
-	notice := g.buildNotice(noticeReq, fault.ID)
-	
-	// Save notice
-	if err := g.repo.CreateNotice(ctx, notice); err != nil {
-		return nil, nil, fmt.Errorf("error creating notice: %w", err)
+	return preview, nil
+}
+
+// applyMergeRules returns the canonical fault a new fault should be grouped into, or nil
+func (g *Grouper) applyMergeRules(ctx context.Context, fault *models.Fault) (*models.Fault, error) {
+	rule, err := g.mergeRules.Match(ctx, fault)
+	if err != nil {
+		return nil, fmt.Errorf("error matching merge rules: %w", err)
+	}
+	if rule == nil {
+		return nil, nil
 	}
 	
-	// Update fault occurrence count from database
-	updatedFault, err := g.repo.GetFault(ctx, fault.ID)
+	target, err := g.repo.GetFault(ctx, rule.TargetFaultID)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error getting updated fault: %w", err)
+		if storage.IsNotFound(err) {
+			// The target was deleted after the cache was loaded
+			g.mergeRules.Invalidate()
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error getting merge rule target: %w", err)
 	}
 	
-	return updatedFault, notice, nil
+	if err := g.repo.RecordMergeRuleMatch(ctx, rule.ID); err != nil {
+		return nil, fmt.Errorf("error recording merge rule match: %w", err)
+	}
+	
+	return target, nil
 }
 
 // extractLocation extracts the location from a notice request
@@ -115,7 +312,7 @@ func (g *Grouper) extractLocation(req *models.NoticeRequest) string {
 }
 
 // buildNotice builds a Notice from a NoticeRequest
-func (g *Grouper) buildNotice(req *models.NoticeRequest, faultID int64) *models.Notice {
+func (g *Grouper) buildNotice(ctx context.Context, req *models.NoticeRequest, faultID int64) *models.Notice {
 	// Generate ULID for notice ID
 	noticeID := generateULID()
 	
@@ -132,6 +329,10 @@ func (g *Grouper) buildNotice(req *models.NoticeRequest, faultID int64) *models.
 		Environment: req.Server.Data,
 		Breadcrumbs: req.Breadcrumbs.Trail,
 		CreatedAt:   time.Now(),
+		ErrorClass:  noticeErrorClass(req),
+		Component:   req.Request.Component,
+		Action:      req.Request.Action,
+		AccountID:   ExtractAccount(req.Request.Context, g.accountFields),
 	}
 	
 	// Add environment name to environment data
@@ -147,6 +348,11 @@ func (g *Grouper) buildNotice(req *models.NoticeRequest, faultID int64) *models.
 	if req.Server.Revision != "" {
 		notice.Revision = &req.Server.Revision
 	}
+	g.enricher.EnrichNotice(notice, req.Request.CGIData)
+	g.redactor.RedactNotice(ctx, notice)
+	for _, data := range []map[string]interface{}{notice.Context, notice.Params, notice.Session, notice.Cookies, notice.Environment} {
+		g.limits.Limit(data)
+	}
 	
 	return notice
 }
@@ -172,6 +378,6 @@ func Fingerprint(fault *models.Fault) string {
 }
 
 // MergeFaults merges two faults (for manual merging)
-func (g *Grouper) MergeFaults(ctx context.Context, sourceFaultID, targetFaultID int64) error {
-	return g.repo.MergeFaults(ctx, sourceFaultID, targetFaultID)
+func (g *Grouper) MergeFaults(ctx context.Context, sourceFaultID, targetFaultID int64, actorID *int64) error {
+	return g.repo.MergeFaults(ctx, sourceFaultID, targetFaultID, actorID)
 }
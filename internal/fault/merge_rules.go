@@ -0,0 +1,139 @@
+package fault
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mergeRuleRefreshInterval bounds how stale the cached rule set may get
+const mergeRuleRefreshInterval = 30 * time.Second
+
+var (
+	uuidPattern   = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	hexPattern    = regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b|\b[0-9a-f]{12,}\b`)
+	numberPattern = regexp.MustCompile(`\d+(\.\d+)?`)
+	quotedPattern = regexp.MustCompile(`"[^"]*"|'[^']*'`)
+	spacePattern  = regexp.MustCompile(`\s+`)
+)
+
+// NormalizeMessage replaces volatile parts of an error message (quoted values, UUIDs,
+// hex IDs and numbers) with placeholders so equivalent messages compare equal
+func NormalizeMessage(message string) string {
+	m := quotedPattern.ReplaceAllString(message, "<str>")
+	m = uuidPattern.ReplaceAllString(m, "<uuid>")
+	m = hexPattern.ReplaceAllString(m, "<hex>")
+	m = numberPattern.ReplaceAllString(m, "<n>")
+	m = spacePattern.ReplaceAllString(m, " ")
+	return strings.TrimSpace(m)
+}
+
+// compiledMergeRule is a merge rule with its message pattern compiled
+type compiledMergeRule struct {
+	rule    models.MergeRule
+	pattern *regexp.Regexp
+}
+
+// matches reports whether a new fault satisfies every criterion of the rule
+func (r *compiledMergeRule) matches(fault *models.Fault, normalized string) bool {
+	if r.rule.ProjectID != nil && (fault.ProjectID == nil || *fault.ProjectID != *r.rule.ProjectID) {
+		return false
+	}
+	if r.rule.ErrorClass != nil && *r.rule.ErrorClass != fault.ErrorClass {
+		return false
+	}
+	if r.rule.Environment != nil && *r.rule.Environment != fault.Environment {
+		return false
+	}
+	if r.pattern != nil && !r.pattern.MatchString(normalized) {
+		return false
+	}
+	return true
+}
+
+// MergeRuleSet caches enabled merge rules and matches new faults against them
+type MergeRuleSet struct {
+	repo     *storage.Repository
+	mu       sync.Mutex
+	rules    []compiledMergeRule
+	loadedAt time.Time
+}
+
+// NewMergeRuleSet creates a new merge rule set
+func NewMergeRuleSet(repo *storage.Repository) *MergeRuleSet {
+	return &MergeRuleSet{repo: repo}
+}
+
+// CompileMergePattern validates a rule's message pattern
+func CompileMergePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message_pattern: %w", err)
+	}
+	return re, nil
+}
+
+// Invalidate forces the next match to reload rules from the database
+func (s *MergeRuleSet) Invalidate() {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// Match returns the first enabled rule that applies to a new fault, or nil
+func (s *MergeRuleSet) Match(ctx context.Context, fault *models.Fault) (*models.MergeRule, error) {
+	rules, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	
+	normalized := NormalizeMessage(fault.Message)
+	for i := range rules {
+		if rules[i].matches(fault, normalized) {
+			rule := rules[i].rule
+			return &rule, nil
+		}
+	}
+	return nil, nil
+}
+
+// load returns the cached rules, refreshing them when stale
+func (s *MergeRuleSet) load(ctx context.Context) ([]compiledMergeRule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if time.Since(s.loadedAt) < mergeRuleRefreshInterval {
+		return s.rules, nil
+	}
+	
+	rules, err := s.repo.ListMergeRules(ctx, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	
+	compiled := make([]compiledMergeRule, 0, len(rules))
+	for _, rule := range rules {
+		c := compiledMergeRule{rule: rule}
+		if rule.MessagePattern != nil {
+			re, err := CompileMergePattern(*rule.MessagePattern)
+			if err != nil {
+				// Rules are validated on creation; skip any that no longer compile
+				continue
+			}
+			c.pattern = re
+		}
+		compiled = append(compiled, c)
+	}
+	
+	s.rules = compiled
+	s.loadedAt = time.Now()
+	return s.rules, nil
+}
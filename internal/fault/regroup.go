@@ -0,0 +1,397 @@
+package fault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log-ingestion-service/internal/jobs"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"sort"
+	"strings"
+)
+
+// RegroupJob is the job type that regroups notices
+const RegroupJob = "regroup"
+
+// RegroupUniqueKey keeps a single regroup that changes faults queued or running at a time
+const RegroupUniqueKey = "regroup"
+
+// regroupPageSize is the number of notices read and moved at a time
+const regroupPageSize = 500
+
+// RegroupPayload is the payload of a regroup job: the notices to regroup, and whether to only
+// report the moves
+type RegroupPayload struct {
+	storage.RegroupScope
+	DryRun bool `json:"dry_run"`
+}
+
+// RegroupMove is a set of notices of one fault that now group elsewhere
+type RegroupMove struct {
+	// TargetFaultID is nil in a dry run when the notices would create a new fault
+	TargetFaultID *int64 `json:"target_fault_id"`
+	Fingerprint   string `json:"fingerprint"`
+	NewFault      bool   `json:"new_fault"`
+	MergeRuleID   *int64 `json:"merge_rule_id,omitempty"`
+	Notices       int64  `json:"notices"`
+}
+
+// RegroupFaultReport describes how the notices of one fault were regrouped
+type RegroupFaultReport struct {
+	FaultID        int64         `json:"fault_id"`
+	Fingerprint    string        `json:"fingerprint"`
+	NoticesScanned int64         `json:"notices_scanned"`
+	NoticesMoved   int64         `json:"notices_moved"`
+	Moves          []RegroupMove `json:"moves"`
+	// Merged is set when every notice left the fault, which is then merged into the fault that
+	// received the most of them
+	Merged     bool   `json:"merged"`
+	MergedInto *int64 `json:"merged_into,omitempty"`
+}
+
+// RegroupReport is the result of a regroup job
+type RegroupReport struct {
+	DryRun         bool                 `json:"dry_run"`
+	NoticesScanned int64                `json:"notices_scanned"`
+	NoticesMoved   int64                `json:"notices_moved"`
+	FaultsCreated  int                  `json:"faults_created"`
+	FaultsMerged   int                  `json:"faults_merged"`
+	Faults         []RegroupFaultReport `json:"faults"`
+}
+
+// regroupRun accumulates the report of a regroup job while it runs
+type regroupRun struct {
+	job       *jobs.Job
+	scope     storage.RegroupScope
+	dryRun    bool
+	total     int64
+	report    RegroupReport
+	reports   map[int64]*faultRegroup
+	newFaults map[string]bool
+}
+
+// faultRegroup accumulates a fault's report while a run is in progress
+type faultRegroup struct {
+	report RegroupFaultReport
+	moves  map[string]*RegroupMove
+}
+
+// Regrouper replays stored notices through the grouper's current fingerprinting and merge rules,
+// moving notices whose fault changed. Notices that now fingerprint alike are gathered into one
+// fault, splitting faults whose notices diverge, and faults left without notices are merged into
+// the fault that received most of them. Regroups run as background jobs.
+type Regrouper struct {
+	grouper *Grouper
+	repo    *storage.Repository
+}
+
+// NewRegrouper creates a regrouper using grouper's rules
+func NewRegrouper(grouper *Grouper, repo *storage.Repository) *Regrouper {
+	return &Regrouper{grouper: grouper, repo: repo}
+}
+
+// Run is the handler of regroup jobs. Its progress counts the notices scanned, and its result
+// is a RegroupReport. Moves made before a failure or cancellation are kept.
+func (r *Regrouper) Run(ctx context.Context, job *jobs.Job) (interface{}, error) {
+	var payload RegroupPayload
+	if err := job.Decode(&payload); err != nil {
+		return nil, err
+	}
+	total, err := r.repo.CountRegroupNotices(ctx, payload.RegroupScope)
+	if err != nil {
+		return nil, err
+	}
+
+	run := &regroupRun{
+		job:       job,
+		scope:     payload.RegroupScope,
+		dryRun:    payload.DryRun,
+		total:     total,
+		report:    RegroupReport{DryRun: payload.DryRun},
+		reports:   make(map[int64]*faultRegroup),
+		newFaults: make(map[string]bool),
+	}
+	job.Progress(0, total, "")
+	if err := r.regroup(ctx, run); err != nil {
+		return nil, err
+	}
+	return run.result(), nil
+}
+
+// result returns the run's report with its fault reports ordered by fault ID
+func (run *regroupRun) result() *RegroupReport {
+	out := run.report
+	out.Faults = make([]RegroupFaultReport, 0, len(run.reports))
+	for _, fr := range run.reports {
+		report := fr.report
+		report.Moves = make([]RegroupMove, 0, len(fr.moves))
+		for _, m := range fr.moves {
+			report.Moves = append(report.Moves, *m)
+		}
+		sort.Slice(report.Moves, func(i, j int) bool { return report.Moves[i].Notices > report.Moves[j].Notices })
+		if report.NoticesMoved > 0 {
+			out.Faults = append(out.Faults, report)
+		}
+	}
+	sort.Slice(out.Faults, func(i, j int) bool { return out.Faults[i].FaultID < out.Faults[j].FaultID })
+	return &out
+}
+
+// pendingMove collects notices of one fault that group under one fingerprint
+type pendingMove struct {
+	source      *models.Fault
+	fault       *models.Fault
+	targetID    *int64
+	mergeRuleID *int64
+	noticeIDs   []string
+}
+
+// regroup reads the notices in scope a page at a time, moving each page before reading the next
+func (r *Regrouper) regroup(ctx context.Context, run *regroupRun) error {
+	faults := make(map[int64]*models.Fault)
+	var cursor *storage.RegroupCursor
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		notices, err := r.repo.ListRegroupNotices(ctx, run.scope, cursor, regroupPageSize)
+		if err != nil {
+			return err
+		}
+		if len(notices) == 0 {
+			break
+		}
+		last := notices[len(notices)-1]
+		cursor = &storage.RegroupCursor{CreatedAt: last.CreatedAt, ID: last.ID}
+
+		var order []string
+		pending := make(map[string]*pendingMove)
+		for i := range notices {
+			n := &notices[i]
+			current, ok := faults[n.FaultID]
+			if !ok {
+				current, err = r.repo.GetFault(ctx, n.FaultID)
+				if err != nil {
+					if storage.IsNotFound(err) {
+						// Deleted since the page was read
+						continue
+					}
+					return fmt.Errorf("error getting fault %d: %w", n.FaultID, err)
+				}
+				faults[n.FaultID] = current
+			}
+			r.record(run, current)
+
+			fault := r.grouper.regroupFault(n, current)
+			targetID, mergeRuleID, err := r.resolve(ctx, fault, current)
+			if err != nil {
+				return err
+			}
+			if targetID != nil && *targetID == current.ID {
+				continue
+			}
+
+			key := fmt.Sprintf("%d|%s", current.ID, Fingerprint(fault))
+			move, ok := pending[key]
+			if !ok {
+				move = &pendingMove{source: current, fault: fault, targetID: targetID, mergeRuleID: mergeRuleID}
+				pending[key] = move
+				order = append(order, key)
+			}
+			move.noticeIDs = append(move.noticeIDs, n.ID)
+		}
+
+		for _, key := range order {
+			if err := r.apply(ctx, run, pending[key]); err != nil {
+				return err
+			}
+		}
+		run.job.Progress(run.report.NoticesScanned, run.total, fmt.Sprintf("%d notices moved", run.report.NoticesMoved))
+		if len(notices) < regroupPageSize {
+			break
+		}
+	}
+
+	if run.dryRun || run.scope.NoticeID != "" {
+		r.predictMerges(run)
+		return nil
+	}
+	return r.mergeEmptied(ctx, run)
+}
+
+// resolve finds the fault a rebuilt fault groups into: a fault with its fingerprint (or one it
+// was merged into), else a merge rule's target. Nil means a new fault.
+func (r *Regrouper) resolve(ctx context.Context, fault, current *models.Fault) (*int64, *int64, error) {
+	if Fingerprint(fault) == Fingerprint(current) {
+		return &current.ID, nil, nil
+	}
+	existing, err := r.repo.FindFaultByFingerprint(ctx, fault)
+	if err == nil {
+		return &existing.ID, nil, nil
+	}
+	rule, err := r.grouper.mergeRules.Match(ctx, fault)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error matching merge rules: %w", err)
+	}
+	if rule != nil {
+		return &rule.TargetFaultID, &rule.ID, nil
+	}
+	return nil, nil, nil
+}
+
+// apply moves a page's notices to their new fault, creating it when needed. A dry run only
+// counts them.
+func (r *Regrouper) apply(ctx context.Context, run *regroupRun, move *pendingMove) error {
+	count := int64(len(move.noticeIDs))
+	created := false
+	if !run.dryRun {
+		if move.targetID == nil {
+			move.fault.ProjectID = move.source.ProjectID
+			target, err := r.repo.CreateFault(ctx, move.fault)
+			if err != nil {
+				return fmt.Errorf("error creating fault: %w", err)
+			}
+			move.targetID = &target.ID
+			created = true
+		}
+		moved, err := r.repo.MoveNotices(ctx, move.noticeIDs, move.source.ID, *move.targetID, run.job.CreatedBy)
+		if err != nil {
+			return fmt.Errorf("error moving notices of fault %d: %w", move.source.ID, err)
+		}
+		count = moved
+	}
+
+	fr := run.reports[move.source.ID]
+	fingerprint := Fingerprint(move.fault)
+	m, ok := fr.moves[fingerprint]
+	if !ok {
+		m = &RegroupMove{Fingerprint: fingerprint, MergeRuleID: move.mergeRuleID}
+		fr.moves[fingerprint] = m
+	}
+	if move.targetID != nil {
+		m.TargetFaultID = move.targetID
+	}
+	if move.targetID == nil || created {
+		m.NewFault = true
+		if !run.newFaults[fingerprint] {
+			run.newFaults[fingerprint] = true
+			run.report.FaultsCreated++
+		}
+	}
+	m.Notices += count
+	fr.report.NoticesMoved += count
+	run.report.NoticesMoved += count
+	return nil
+}
+
+// record counts a scanned notice of a fault, adding the fault to the report on first sight
+func (r *Regrouper) record(run *regroupRun, fault *models.Fault) {
+	fr, ok := run.reports[fault.ID]
+	if !ok {
+		fr = &faultRegroup{
+			report: RegroupFaultReport{FaultID: fault.ID, Fingerprint: Fingerprint(fault)},
+			moves:  make(map[string]*RegroupMove),
+		}
+		run.reports[fault.ID] = fr
+	}
+	fr.report.NoticesScanned++
+	run.report.NoticesScanned++
+}
+
+// largestMove returns the move that took the most notices
+func largestMove(fr *faultRegroup) *RegroupMove {
+	var best *RegroupMove
+	for _, m := range fr.moves {
+		if best == nil || m.Notices > best.Notices {
+			best = m
+		}
+	}
+	return best
+}
+
+// predictMerges marks the faults a dry run would leave without notices
+func (r *Regrouper) predictMerges(run *regroupRun) {
+	if run.scope.NoticeID != "" {
+		return
+	}
+	for _, fr := range run.reports {
+		if fr.report.NoticesMoved == 0 || fr.report.NoticesMoved < fr.report.NoticesScanned {
+			continue
+		}
+		fr.report.Merged = true
+		if best := largestMove(fr); best != nil {
+			fr.report.MergedInto = best.TargetFaultID
+		}
+		run.report.FaultsMerged++
+	}
+}
+
+// mergeEmptied merges each fault left without notices into the fault that received most of them,
+// so its history, comments and fingerprint follow its notices
+func (r *Regrouper) mergeEmptied(ctx context.Context, run *regroupRun) error {
+	type candidate struct {
+		faultID  int64
+		targetID int64
+	}
+	var candidates []candidate
+	for id, fr := range run.reports {
+		if best := largestMove(fr); best != nil && best.TargetFaultID != nil {
+			candidates = append(candidates, candidate{faultID: id, targetID: *best.TargetFaultID})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].faultID < candidates[j].faultID })
+
+	for _, c := range candidates {
+		remaining, err := r.repo.CountFaultNotices(ctx, c.faultID)
+		if err != nil {
+			return err
+		}
+		if remaining > 0 {
+			continue
+		}
+		if err := r.repo.MergeFaults(ctx, c.faultID, c.targetID, run.job.CreatedBy); err != nil {
+			if storage.IsNotFound(err) || errors.Is(err, storage.ErrSelfMerge) {
+				continue
+			}
+			return fmt.Errorf("error merging emptied fault %d: %w", c.faultID, err)
+		}
+		fr := run.reports[c.faultID]
+		fr.report.Merged = true
+		target := c.targetID
+		fr.report.MergedInto = &target
+		run.report.FaultsMerged++
+	}
+	return nil
+}
+
+// regroupFault rebuilds the fault a stored notice belongs to under the current grouping rules.
+// Notices stored before their grouping inputs were recorded use their fault's error class, and
+// keep its component#action location when it has one.
+func (g *Grouper) regroupFault(n *models.Notice, current *models.Fault) *models.Fault {
+	var req models.NoticeRequest
+	req.Error.Class = n.ErrorClass
+	req.Error.Message = n.Message
+	req.Error.Backtrace = n.Backtrace
+	req.Request.Component = n.Component
+	req.Request.Action = n.Action
+	if env, ok := n.Environment["environment_name"].(string); ok {
+		req.Server.EnvironmentName = env
+	}
+
+	if n.ErrorClass == "" {
+		req.Error.Class = current.ErrorClass
+		if current.Location != nil {
+			if component, action, ok := strings.Cut(*current.Location, "#"); ok && component != "" && action != "" {
+				req.Request.Component = component
+				req.Request.Action = action
+			}
+		}
+	}
+
+	fault := g.buildFault(&req)
+	fault.ProjectID = current.ProjectID
+	fault.FirstSeenAt = n.CreatedAt
+	fault.LastSeenAt = n.CreatedAt
+	return fault
+}
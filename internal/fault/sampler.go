@@ -0,0 +1,110 @@
+package fault
+
+import (
+	"log-ingestion-service/pkg/config"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// NoticeSampler decides which notices of a fault are stored during an error storm. Each
+// fault's notices are counted per minute on this instance; past the threshold only a sample of
+// them is stored, and the rest only count towards the fault's occurrences.
+type NoticeSampler struct {
+	threshold int
+	rate      float64
+	started   time.Time
+
+	mu         sync.Mutex
+	minute     int64
+	counts     map[int64]*noticeCount
+	sampledOut int64
+}
+
+// noticeCount counts a fault's notices in the current minute
+type noticeCount struct {
+	notices    int
+	sampledOut int
+}
+
+// NoticeSamplingStats describes the faults being sampled in the current minute and how many
+// notices were not stored since the instance started
+type NoticeSamplingStats struct {
+	Enabled    bool                 `json:"enabled"`
+	Threshold  int                  `json:"threshold"`
+	SampleRate float64              `json:"sample_rate"`
+	Since      time.Time            `json:"since"`
+	SampledOut int64                `json:"sampled_out"`
+	Faults     []FaultSamplingStats `json:"faults"`
+}
+
+// FaultSamplingStats counts a fault's notices in the current minute
+type FaultSamplingStats struct {
+	FaultID    int64 `json:"fault_id"`
+	Notices    int   `json:"notices"`
+	SampledOut int   `json:"sampled_out"`
+}
+
+// NewNoticeSampler creates a notice sampler; with a threshold of 0 every notice is stored
+func NewNoticeSampler(cfg *config.NoticeSamplingConfig) *NoticeSampler {
+	return &NoticeSampler{
+		threshold: cfg.Threshold,
+		rate:      cfg.SampleRate,
+		started:   time.Now(),
+		counts:    make(map[int64]*noticeCount),
+	}
+}
+
+// Store counts a notice of a fault and reports whether it is stored in full
+func (s *NoticeSampler) Store(faultID int64) bool {
+	if s == nil || s.threshold <= 0 {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Counts start over each minute, which also forgets faults no longer seen
+	if minute := time.Now().Unix() / 60; minute != s.minute {
+		s.minute = minute
+		s.counts = make(map[int64]*noticeCount)
+	}
+	count, ok := s.counts[faultID]
+	if !ok {
+		count = &noticeCount{}
+		s.counts[faultID] = count
+	}
+	count.notices++
+	if count.notices <= s.threshold || (s.rate > 0 && rand.Float64() < s.rate) {
+		return true
+	}
+	count.sampledOut++
+	s.sampledOut++
+	return false
+}
+
+// Stats returns the faults over the threshold in the current minute, most notices first
+func (s *NoticeSampler) Stats() NoticeSamplingStats {
+	stats := NoticeSamplingStats{Faults: []FaultSamplingStats{}}
+	if s == nil {
+		return stats
+	}
+	stats.Enabled = s.threshold > 0
+	stats.Threshold = s.threshold
+	stats.SampleRate = s.rate
+	stats.Since = s.started
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats.SampledOut = s.sampledOut
+	if s.minute != time.Now().Unix()/60 {
+		return stats
+	}
+	for id, count := range s.counts {
+		if count.notices > s.threshold {
+			stats.Faults = append(stats.Faults, FaultSamplingStats{FaultID: id, Notices: count.notices, SampledOut: count.sampledOut})
+		}
+	}
+	sort.Slice(stats.Faults, func(i, j int) bool { return stats.Faults[i].Notices > stats.Faults[j].Notices })
+	return stats
+}
@@ -0,0 +1,144 @@
+package fault
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/notify"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"sync"
+	"time"
+)
+
+// slaCheckInterval is how often the SLA monitor looks for timers at risk
+const slaCheckInterval = time.Minute
+
+// slaWarningBatch bounds the notifications of each timer sent by one pass
+const slaWarningBatch = 100
+
+// NewSLAPolicy creates the SLA policy of validated configuration
+func NewSLAPolicy(cfg *config.SLAConfig) *models.SLAPolicy {
+	policy := &models.SLAPolicy{
+		Targets: make(map[string]models.SLATarget, len(cfg.Targets)),
+		WarnAt:  cfg.WarnAt,
+	}
+	for severity, target := range cfg.Targets {
+		policy.Targets[severity] = models.SLATarget{Acknowledge: target.Acknowledge, Resolve: target.Resolve}
+	}
+	return policy
+}
+
+// SLAMonitor periodically notifies about fault SLA timers that are about to breach. Each timer
+// is notified about once per opening of its fault, when it passes the policy's warning point,
+// or when it is found already breached. Claims are atomic, so several instances may run it at
+// once.
+type SLAMonitor struct {
+	repo         *storage.Repository
+	notifier     *notify.Dispatcher
+	policy       *models.SLAPolicy
+	environments *EnvironmentSet
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// NewSLAMonitor creates an SLA monitor
+func NewSLAMonitor(repo *storage.Repository, notifier *notify.Dispatcher, policy *models.SLAPolicy) *SLAMonitor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &SLAMonitor{
+		repo:         repo,
+		notifier:     notifier,
+		policy:       policy,
+		environments: NewEnvironmentSet(repo),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Start begins checking SLA timers every minute
+func (m *SLAMonitor) Start() {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		ticker := time.NewTicker(slaCheckInterval)
+		defer ticker.Stop()
+		for {
+			m.check(m.ctx)
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Shutdown stops the monitor, waiting for a pass in progress
+func (m *SLAMonitor) Shutdown() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// check notifies about the timers that reached their warning point since the previous pass
+func (m *SLAMonitor) check(ctx context.Context) {
+	warnings, err := m.repo.FindSLAWarnings(ctx, m.policy, slaWarningBatch)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("ERROR: Failed to find faults at SLA risk: %v", err)
+		}
+		return
+	}
+
+	for _, warning := range warnings {
+		claimed, err := m.repo.MarkSLAWarned(ctx, warning)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("ERROR: Failed to record SLA warning for fault %d: %v", warning.FaultID, err)
+			}
+			return
+		}
+		if claimed {
+			m.notify(ctx, warning)
+		}
+	}
+}
+
+// notify announces a timer at risk or breached, unless alerting is off for its fault's environment
+func (m *SLAMonitor) notify(ctx context.Context, warning storage.SLAWarning) {
+	if m.notifier == nil {
+		return
+	}
+	fault, err := m.repo.GetFault(ctx, warning.FaultID)
+	if err != nil {
+		log.Printf("WARN: Failed to load fault %d at SLA risk: %v", warning.FaultID, err)
+		return
+	}
+	if !m.environments.AlertingEnabled(ctx, fault) {
+		return
+	}
+
+	fault.SLA = m.policy.Status(fault, time.Now())
+	if fault.SLA == nil {
+		return
+	}
+	timer := fault.SLA.Acknowledge
+	verb := "acknowledged"
+	if warning.Timer == models.SLAResolve {
+		timer, verb = fault.SLA.Resolve, "resolved"
+	}
+	if timer == nil {
+		return
+	}
+
+	due := fmt.Sprintf("is due to be %s by %s", verb, timer.DueAt.UTC().Format(time.RFC3339))
+	if timer.Status == models.SLABreached {
+		due = fmt.Sprintf("was due to be %s by %s", verb, timer.DueAt.UTC().Format(time.RFC3339))
+	}
+	m.notifier.DispatchAsync(notify.Event{
+		Type:    notify.EventFaultSLAAtRisk,
+		Message: fmt.Sprintf("%s fault %s: %s: %s", fault.Severity, due, fault.ErrorClass, fault.Message),
+		Fault:   fault,
+	})
+}
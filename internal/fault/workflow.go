@@ -0,0 +1,162 @@
+package fault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+)
+
+// ErrTransitionNotAllowed is returned when the workflow does not allow a fault's move
+var ErrTransitionNotAllowed = errors.New("transition not allowed")
+
+// Workflow moves faults between the configured workflow states. The legacy resolved and ignored
+// flags are kept in step: they are set while a fault is in the resolved or ignored state.
+type Workflow struct {
+	repo        *storage.Repository
+	states      []string
+	transitions map[string][]string
+	resolved    string
+	ignored     string
+}
+
+// WorkflowInfo describes the workflow for API clients
+type WorkflowInfo struct {
+	States        []string            `json:"states"`
+	InitialState  string              `json:"initial_state"`
+	ResolvedState string              `json:"resolved_state"`
+	IgnoredState  string              `json:"ignored_state"`
+	Transitions   map[string][]string `json:"transitions"`
+}
+
+// NewWorkflow creates a workflow from validated configuration
+func NewWorkflow(repo *storage.Repository, cfg *config.WorkflowConfig) *Workflow {
+	return &Workflow{
+		repo:        repo,
+		states:      cfg.States,
+		transitions: cfg.Transitions,
+		resolved:    cfg.ResolvedState,
+		ignored:     cfg.IgnoredState,
+	}
+}
+
+// Initial is the state of new and reopened faults
+func (w *Workflow) Initial() string {
+	return w.states[0]
+}
+
+// Valid reports whether state is a workflow state
+func (w *Workflow) Valid(state string) bool {
+	for _, s := range w.states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether a fault may move from one state to another. Staying in a state is
+// always allowed.
+func (w *Workflow) Allows(from, to string) bool {
+	if from == to {
+		return true
+	}
+	if len(w.transitions) == 0 {
+		return w.Valid(to)
+	}
+	for _, next := range w.transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Info describes the workflow. Without configured transitions every state lists every other.
+func (w *Workflow) Info() WorkflowInfo {
+	transitions := make(map[string][]string, len(w.states))
+	for _, from := range w.states {
+		next := []string{}
+		for _, to := range w.states {
+			if to != from && w.Allows(from, to) {
+				next = append(next, to)
+			}
+		}
+		transitions[from] = next
+	}
+	return WorkflowInfo{
+		States:        w.states,
+		InitialState:  w.Initial(),
+		ResolvedState: w.resolved,
+		IgnoredState:  w.ignored,
+		Transitions:   transitions,
+	}
+}
+
+// Transition moves a fault to state, failing with ErrTransitionNotAllowed when the workflow
+// does not allow the move from its current state
+func (w *Workflow) Transition(ctx context.Context, id int64, state string, actorID *int64) error {
+	return w.move(ctx, id, "state_changed", actorID, func(from string) (string, error) {
+		if !w.Valid(from) {
+			// Faults left in a state removed from the configuration may move anywhere
+			return state, nil
+		}
+		if !w.Allows(from, state) {
+			return "", fmt.Errorf("%w: %s to %s", ErrTransitionNotAllowed, from, state)
+		}
+		return state, nil
+	})
+}
+
+// SetResolved maps the legacy resolved flag onto the workflow: resolving moves a fault to the
+// resolved state and unresolving a resolved fault reopens it. Transitions are not checked, so
+// clients using the flags keep working under any workflow.
+func (w *Workflow) SetResolved(ctx context.Context, id int64, resolved bool, actorID *int64) error {
+	action := "resolved"
+	if !resolved {
+		action = "unresolved"
+	}
+	return w.move(ctx, id, action, actorID, func(from string) (string, error) {
+		return w.legacyState(from, w.resolved, resolved), nil
+	})
+}
+
+// SetIgnored maps the legacy ignored flag onto the workflow like SetResolved does the resolved flag
+func (w *Workflow) SetIgnored(ctx context.Context, id int64, ignored bool, actorID *int64) error {
+	action := "ignored"
+	if !ignored {
+		action = "unignored"
+	}
+	return w.move(ctx, id, action, actorID, func(from string) (string, error) {
+		return w.legacyState(from, w.ignored, ignored), nil
+	})
+}
+
+// legacyState is the state a legacy flag update leads to: the flag's state when it is set, or
+// the initial state when it is cleared on a fault in that state
+func (w *Workflow) legacyState(from, flagState string, set bool) string {
+	switch {
+	case set:
+		return flagState
+	case from == flagState:
+		return w.Initial()
+	default:
+		return from
+	}
+}
+
+// move moves a fault to the state next picks, keeping the legacy flags and SLA clock in step
+func (w *Workflow) move(ctx context.Context, id int64, action string, actorID *int64, next func(from string) (string, error)) error {
+	err := w.repo.MoveFault(ctx, id, action, func(from string) (string, bool, bool, error) {
+		to, err := next(from)
+		if err != nil {
+			return "", false, false, err
+		}
+		return to, to == w.resolved, to == w.ignored, nil
+	}, actorID)
+	if err != nil {
+		return err
+	}
+	return w.repo.UpdateFaultSLAClock(ctx, id, w.Initial(), []string{w.resolved, w.ignored})
+}
@@ -0,0 +1,215 @@
+// Package feature gates risky subsystems behind flags that can be set in configuration and
+// overridden at runtime, globally or per project, so they can be rolled out gradually.
+package feature
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Known flags
+const (
+	// NoticeBatching queues notices for bulk insert instead of writing each one as it arrives
+	NoticeBatching = "notice_batching"
+	// RawIngest accepts raw log bodies on POST /api/v1/logs/raw
+	RawIngest = "raw_ingest"
+	// NoticePayloadDedup stores a notice's environment data once, shared by notices sending the same data
+	NoticePayloadDedup = "notice_payload_dedup"
+)
+
+// Definition describes a known flag
+type Definition struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+// definitions lists every flag with its built-in default
+var definitions = []Definition{
+	{NoticeBatching, "Queue notices and write them in bulk", true},
+	{RawIngest, "Accept raw log bodies on POST /api/v1/logs/raw", true},
+	{NoticePayloadDedup, "Store repeated notice environment data once and share it between notices", false},
+}
+
+// ErrUnknownFlag is returned for a flag name that is not defined
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+// refreshInterval bounds how stale the cached overrides may get, so changes made on
+// another instance are picked up
+const refreshInterval = 30 * time.Second
+
+// Status reports a flag's effective global value and its overrides
+type Status struct {
+	Definition
+	// Configured is the value from configuration, before runtime overrides
+	Configured bool                         `json:"configured"`
+	Enabled    bool                         `json:"enabled"`
+	Overrides  []models.FeatureFlagOverride `json:"overrides"`
+}
+
+// Flags answers whether a feature is enabled. A project override takes precedence over a
+// global override, which takes precedence over configuration and then the built-in default.
+type Flags struct {
+	repo       *storage.Repository
+	configured map[string]bool
+
+	mu        sync.Mutex
+	overrides []models.FeatureFlagOverride
+	loadedAt  time.Time
+}
+
+// NewFlags creates the flag set. configured holds values from configuration; names must be known.
+func NewFlags(repo *storage.Repository, configured map[string]bool) (*Flags, error) {
+	values := make(map[string]bool, len(definitions))
+	for _, d := range definitions {
+		values[d.Name] = d.Default
+	}
+	var unknown []string
+	for name, enabled := range configured {
+		if !Known(name) {
+			unknown = append(unknown, name)
+		}
+		values[name] = enabled
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("features: %w: %s", ErrUnknownFlag, strings.Join(unknown, ", "))
+	}
+	return &Flags{repo: repo, configured: values}, nil
+}
+
+// Known reports whether name is a defined flag
+func Known(name string) bool {
+	for _, d := range definitions {
+		if d.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Enabled reports whether a feature is on for a project, or globally when projectID is nil.
+// If overrides cannot be loaded the last known values are used.
+func (f *Flags) Enabled(ctx context.Context, name string, projectID *int64) bool {
+	enabled := f.configured[name]
+	for _, o := range f.load(ctx) {
+		if o.Name != name {
+			continue
+		}
+		if o.ProjectID == nil {
+			enabled = o.Enabled
+		} else if projectID != nil && *o.ProjectID == *projectID {
+			return o.Enabled
+		}
+	}
+	return enabled
+}
+
+// List reports every flag, with overrides read fresh from the database
+func (f *Flags) List(ctx context.Context) ([]Status, error) {
+	overrides, err := f.repo.ListFeatureFlagOverrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	f.overrides = overrides
+	f.loadedAt = time.Now()
+	f.mu.Unlock()
+
+	statuses := make([]Status, 0, len(definitions))
+	for _, d := range definitions {
+		status := Status{
+			Definition: d,
+			Configured: f.configured[d.Name],
+			Enabled:    f.configured[d.Name],
+			Overrides:  []models.FeatureFlagOverride{},
+		}
+		for _, o := range overrides {
+			if o.Name != d.Name {
+				continue
+			}
+			if o.ProjectID == nil {
+				status.Enabled = o.Enabled
+			}
+			status.Overrides = append(status.Overrides, o)
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+// Set overrides a flag for a project, or globally when projectID is nil
+func (f *Flags) Set(ctx context.Context, name string, projectID *int64, enabled bool, actorID *int64) (*models.FeatureFlagOverride, error) {
+	if !Known(name) {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+	}
+	override := &models.FeatureFlagOverride{
+		Name:      name,
+		ProjectID: projectID,
+		Enabled:   enabled,
+		UpdatedBy: actorID,
+	}
+	if err := f.repo.SetFeatureFlagOverride(ctx, override); err != nil {
+		return nil, err
+	}
+	f.Invalidate()
+	log.Printf("INFO: Feature flag %s set to %t (project %s)", name, enabled, scope(projectID))
+	return override, nil
+}
+
+// Clear removes an override, so the flag falls back to the next level
+func (f *Flags) Clear(ctx context.Context, name string, projectID *int64) error {
+	if !Known(name) {
+		return fmt.Errorf("%w: %s", ErrUnknownFlag, name)
+	}
+	if err := f.repo.DeleteFeatureFlagOverride(ctx, name, projectID); err != nil {
+		return err
+	}
+	f.Invalidate()
+	log.Printf("INFO: Feature flag %s override cleared (project %s)", name, scope(projectID))
+	return nil
+}
+
+// Invalidate forces the next check to reload overrides from the database
+func (f *Flags) Invalidate() {
+	f.mu.Lock()
+	f.loadedAt = time.Time{}
+	f.mu.Unlock()
+}
+
+// load returns the cached overrides, refreshing them when stale
+func (f *Flags) load(ctx context.Context) []models.FeatureFlagOverride {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if time.Since(f.loadedAt) < refreshInterval {
+		return f.overrides
+	}
+
+	overrides, err := f.repo.ListFeatureFlagOverrides(ctx)
+	if err != nil {
+		// Keep serving the last known values; retry on a later check rather than every request
+		log.Printf("WARN: Failed to load feature flags: %v", err)
+		f.loadedAt = time.Now().Add(-refreshInterval + 5*time.Second)
+		return f.overrides
+	}
+	f.overrides = overrides
+	f.loadedAt = time.Now()
+	return f.overrides
+}
+
+func scope(projectID *int64) string {
+	if projectID == nil {
+		return "all"
+	}
+	return fmt.Sprintf("%d", *projectID)
+}
@@ -0,0 +1,83 @@
+// Package highlight finds where a search term occurs in text and cuts long text down to a
+// snippet around it, so clients can highlight matches without re-implementing matching.
+// Offsets count characters (runes), not bytes.
+package highlight
+
+import "unicode"
+
+// ellipsis marks text cut from a snippet
+const ellipsis = '…'
+
+// Span is a match, from Start up to but excluding End
+type Span struct {
+	Start int
+	End   int
+}
+
+// Find returns the non-overlapping occurrences of term in text, case-insensitively
+func Find(text, term string) []Span {
+	return find([]rune(text), []rune(term))
+}
+
+func find(text, term []rune) []Span {
+	if len(term) == 0 {
+		return nil
+	}
+	var spans []Span
+	for i := 0; i+len(term) <= len(text); i++ {
+		if matchAt(text, term, i) {
+			spans = append(spans, Span{Start: i, End: i + len(term)})
+			i += len(term) - 1
+		}
+	}
+	return spans
+}
+
+func matchAt(text, term []rune, at int) bool {
+	for j, r := range term {
+		if unicode.ToLower(text[at+j]) != unicode.ToLower(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Snippet returns text, cut to about width characters around the first match of term when it
+// is longer, with an ellipsis where it was cut, and the matches in what is returned
+func Snippet(text, term string, width int) (string, []Span) {
+	runes := []rune(text)
+	spans := find(runes, []rune(term))
+	if len(runes) <= width {
+		return text, spans
+	}
+
+	// Start a little before the first match, so it is read in context
+	start := 0
+	if len(spans) > 0 && spans[0].Start > width/4 {
+		start = spans[0].Start - width/4
+	}
+	end := start + width
+	if end > len(runes) {
+		end = len(runes)
+		start = end - width
+	}
+
+	snippet := make([]rune, 0, width+2)
+	shift := -start
+	if start > 0 {
+		snippet = append(snippet, ellipsis)
+		shift++
+	}
+	snippet = append(snippet, runes[start:end]...)
+	if end < len(runes) {
+		snippet = append(snippet, ellipsis)
+	}
+
+	var kept []Span
+	for _, s := range spans {
+		if s.Start >= start && s.End <= end {
+			kept = append(kept, Span{Start: s.Start + shift, End: s.End + shift})
+		}
+	}
+	return string(snippet), kept
+}
@@ -0,0 +1,97 @@
+// Package i18n translates user-facing messages: the titles of error responses and report
+// emails. Messages have stable IDs, so API clients can match on them whatever the language,
+// and fall back to English when a translation is missing.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Default is the language used when none is asked for or none of those asked for is supported
+const Default = "en"
+
+// Languages are the supported languages
+var Languages = []string{"en", "de", "ja"}
+
+// byEnglish indexes the message IDs by their English text
+var byEnglish = func() map[string]string {
+	index := make(map[string]string, len(catalog))
+	for id, texts := range catalog {
+		index[texts["en"]] = id
+	}
+	return index
+}()
+
+// Supported reports whether lang is a supported language
+func Supported(lang string) bool {
+	for _, l := range Languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Text returns a message in a language, formatted with args. Missing translations fall back
+// to English, and unknown IDs to the ID itself.
+func Text(lang, id string, args ...interface{}) string {
+	texts, ok := catalog[id]
+	if !ok {
+		return id
+	}
+	text, ok := texts[lang]
+	if !ok {
+		text = texts[Default]
+	}
+	if len(args) == 0 {
+		return text
+	}
+	return fmt.Sprintf(text, args...)
+}
+
+// MessageID returns the ID of the message whose English text is english
+func MessageID(english string) (string, bool) {
+	id, ok := byEnglish[english]
+	return id, ok
+}
+
+// Negotiate picks the supported language a client prefers most from an Accept-Language
+// header, such as "de-CH, de;q=0.9, en;q=0.5". Regional variants match their language, and
+// languages of equal weight are taken in the order listed.
+func Negotiate(header string) string {
+	type preference struct {
+		lang   string
+		weight float64
+	}
+	var prefs []preference
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		weight := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		if tag == "" || weight <= 0 {
+			continue
+		}
+		lang, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		prefs = append(prefs, preference{lang: lang, weight: weight})
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].weight > prefs[j].weight })
+
+	for _, p := range prefs {
+		if p.lang == "*" {
+			return Default
+		}
+		if Supported(p.lang) {
+			return p.lang
+		}
+	}
+	return Default
+}
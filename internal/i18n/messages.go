@@ -0,0 +1,261 @@
+package i18n
+
+// catalog holds each message by ID and language. IDs are part of the API and must not change;
+// a message whose wording changes keeps its ID. Placeholders use explicit argument indexes
+// where translations reorder them.
+var catalog = map[string]map[string]string{
+	// Authentication and access
+	"authentication_required": {
+		"en": "Authentication required",
+		"de": "Authentifizierung erforderlich",
+		"ja": "認証が必要です",
+	},
+	"credentials_required": {
+		"en": "Valid API key or authentication token required",
+		"de": "Gültiger API-Schlüssel oder Authentifizierungstoken erforderlich",
+		"ja": "有効な API キーまたは認証トークンが必要です",
+	},
+	"api_key_required": {
+		"en": "API key is required",
+		"de": "API-Schlüssel ist erforderlich",
+		"ja": "API キーが必要です",
+	},
+	"api_key_invalid": {
+		"en": "Invalid API key",
+		"de": "Ungültiger API-Schlüssel",
+		"ja": "API キーが無効です",
+	},
+	"admin_api_key_required": {
+		"en": "Admin API key is required",
+		"de": "Admin-API-Schlüssel ist erforderlich",
+		"ja": "管理者 API キーが必要です",
+	},
+	"admin_api_key_invalid": {
+		"en": "Invalid admin API key",
+		"de": "Ungültiger Admin-API-Schlüssel",
+		"ja": "管理者 API キーが無効です",
+	},
+	"token_invalid": {
+		"en": "Invalid or expired token",
+		"de": "Ungültiges oder abgelaufenes Token",
+		"ja": "トークンが無効か期限切れです",
+	},
+	"public_token_required": {
+		"en": "Public token required",
+		"de": "Öffentliches Token erforderlich",
+		"ja": "公開トークンが必要です",
+	},
+	"public_token_invalid": {
+		"en": "Invalid public token",
+		"de": "Ungültiges öffentliches Token",
+		"ja": "公開トークンが無効です",
+	},
+	"admin_required": {
+		"en": "Admin access required",
+		"de": "Administratorzugriff erforderlich",
+		"ja": "管理者権限が必要です",
+	},
+	"login_invalid": {
+		"en": "Invalid email or password",
+		"de": "Ungültige E-Mail-Adresse oder ungültiges Passwort",
+		"ja": "メールアドレスまたはパスワードが正しくありません",
+	},
+
+	// Requests
+	"request_body_invalid": {
+		"en": "Invalid request body",
+		"de": "Ungültiger Anfragetext",
+		"ja": "リクエスト本文が無効です",
+	},
+	"request_invalid": {
+		"en": "Invalid request",
+		"de": "Ungültige Anfrage",
+		"ja": "リクエストが無効です",
+	},
+	"request_body_too_large": {
+		"en": "Request body too large",
+		"de": "Anfragetext zu groß",
+		"ja": "リクエスト本文が大きすぎます",
+	},
+	"request_body_unreadable": {
+		"en": "Failed to read request body",
+		"de": "Anfragetext konnte nicht gelesen werden",
+		"ja": "リクエスト本文を読み取れませんでした",
+	},
+	"content_type_unsupported": {
+		"en": "Unsupported content type",
+		"de": "Nicht unterstützter Inhaltstyp",
+		"ja": "サポートされていないコンテンツタイプです",
+	},
+	"pagination_invalid": {
+		"en": "Invalid pagination parameters",
+		"de": "Ungültige Paginierungsparameter",
+		"ja": "ページ指定のパラメーターが無効です",
+	},
+	"search_query_invalid": {
+		"en": "Invalid search query",
+		"de": "Ungültige Suchanfrage",
+		"ja": "検索クエリが無効です",
+	},
+	"sort_invalid": {
+		"en": "Invalid sort parameter",
+		"de": "Ungültiger Sortierparameter",
+		"ja": "並べ替えのパラメーターが無効です",
+	},
+	"update_invalid": {
+		"en": "Invalid update",
+		"de": "Ungültige Änderung",
+		"ja": "更新内容が無効です",
+	},
+	"batch_empty": {
+		"en": "Empty batch",
+		"de": "Leerer Stapel",
+		"ja": "バッチが空です",
+	},
+	"idempotency_key_invalid": {
+		"en": "Invalid Idempotency-Key",
+		"de": "Ungültiger Idempotency-Key",
+		"ja": "Idempotency-Key が無効です",
+	},
+	"idempotency_key_reused": {
+		"en": "Idempotency-Key reused",
+		"de": "Idempotency-Key wurde wiederverwendet",
+		"ja": "Idempotency-Key が再利用されました",
+	},
+	"request_in_progress": {
+		"en": "Request in progress",
+		"de": "Anfrage wird bereits bearbeitet",
+		"ja": "リクエストを処理中です",
+	},
+
+	// Resources
+	"fault_id_invalid": {
+		"en": "Invalid fault ID",
+		"de": "Ungültige Fehler-ID",
+		"ja": "エラー ID が無効です",
+	},
+	"fault_not_found": {
+		"en": "Fault not found",
+		"de": "Fehler nicht gefunden",
+		"ja": "エラーが見つかりません",
+	},
+	"project_id_invalid": {
+		"en": "Invalid project ID",
+		"de": "Ungültige Projekt-ID",
+		"ja": "プロジェクト ID が無効です",
+	},
+	"user_not_found": {
+		"en": "User not found",
+		"de": "Benutzer nicht gefunden",
+		"ja": "ユーザーが見つかりません",
+	},
+	"api_key_not_found": {
+		"en": "API key not found",
+		"de": "API-Schlüssel nicht gefunden",
+		"ja": "API キーが見つかりません",
+	},
+	"subscription_not_found": {
+		"en": "Subscription not found",
+		"de": "Abonnement nicht gefunden",
+		"ja": "購読が見つかりません",
+	},
+	"job_not_found": {
+		"en": "Job not found",
+		"de": "Auftrag nicht gefunden",
+		"ja": "ジョブが見つかりません",
+	},
+	"session_not_found": {
+		"en": "Session not found",
+		"de": "Sitzung nicht gefunden",
+		"ja": "セッションが見つかりません",
+	},
+	"profile_session_required": {
+		"en": "Profile requires a user session",
+		"de": "Das Profil erfordert eine Benutzersitzung",
+		"ja": "プロフィールにはユーザーセッションが必要です",
+	},
+
+	// Availability
+	"rate_limited": {
+		"en": "Rate limit exceeded",
+		"de": "Anfragelimit überschritten",
+		"ja": "リクエスト数の上限を超えました",
+	},
+	"server_busy": {
+		"en": "Server busy",
+		"de": "Server ausgelastet",
+		"ja": "サーバーが混み合っています",
+	},
+	"ingestion_paused": {
+		"en": "Ingestion paused",
+		"de": "Annahme pausiert",
+		"ja": "取り込みは一時停止中です",
+	},
+	"request_timed_out": {
+		"en": "Request timed out",
+		"de": "Zeitüberschreitung der Anfrage",
+		"ja": "リクエストがタイムアウトしました",
+	},
+	"query_too_expensive": {
+		"en": "Query too expensive",
+		"de": "Abfrage zu aufwendig",
+		"ja": "クエリの負荷が高すぎます",
+	},
+	"query_queue_full": {
+		"en": "Too many expensive queries",
+		"de": "Zu viele aufwendige Abfragen",
+		"ja": "負荷の高いクエリが多すぎます",
+	},
+
+	// Report emails
+	"report_subject": {
+		"en": "%[1]s: %[2]d %[3]s",
+		"de": "%[1]s: %[2]d %[3]s",
+		"ja": "%[1]s: %[3]s %[2]d 件",
+	},
+	"report_query": {
+		"en": "Query: %s",
+		"de": "Abfrage: %s",
+		"ja": "クエリ: %s",
+	},
+	"report_period": {
+		"en": "Period: %[1]s to %[2]s",
+		"de": "Zeitraum: %[1]s bis %[2]s",
+		"ja": "期間: %[1]s 〜 %[2]s",
+	},
+	"report_none": {
+		"en": "No %s matched.",
+		"de": "Keine %s gefunden.",
+		"ja": "該当する%sはありません。",
+	},
+	"report_partial": {
+		"en": "%[1]d %[2]s matched; the first %[3]d are shown.",
+		"de": "%[1]d %[2]s gefunden; die ersten %[3]d werden angezeigt.",
+		"ja": "%[2]sが %[1]d 件見つかりました。最初の %[3]d 件を表示します。",
+	},
+	"report_all": {
+		"en": "%[1]d %[2]s matched.",
+		"de": "%[1]d %[2]s gefunden.",
+		"ja": "%[2]sが %[1]d 件見つかりました。",
+	},
+	"report_kind_logs": {
+		"en": "logs",
+		"de": "Logs",
+		"ja": "ログ",
+	},
+	"report_kind_faults": {
+		"en": "faults",
+		"de": "Fehler",
+		"ja": "エラー",
+	},
+	"report_fault_location": {
+		"en": " at %s",
+		"de": " in %s",
+		"ja": "（%s）",
+	},
+	"report_fault_summary": {
+		"en": "%[1]s, %[2]d occurrences, last seen %[3]s",
+		"de": "%[1]s, %[2]d Vorkommen, zuletzt gesehen %[3]s",
+		"ja": "%[1]s、発生 %[2]d 回、最終発生 %[3]s",
+	},
+}
@@ -0,0 +1,199 @@
+// Package airbrake converts Airbrake v3 notices into notices, so Airbrake notifiers can report
+// errors to the service unchanged.
+package airbrake
+
+import (
+	"encoding/json"
+	"errors"
+	"log-ingestion-service/pkg/models"
+	"strconv"
+	"strings"
+)
+
+// ProjectRoot is the placeholder Airbrake notifiers put in place of the project root in
+// backtrace file paths
+const ProjectRoot = "/PROJECT_ROOT"
+
+// Notice is the body of a v3 notice request
+type Notice struct {
+	Errors      []Error                `json:"errors"`
+	Context     Context                `json:"context"`
+	Environment map[string]interface{} `json:"environment"`
+	Session     map[string]interface{} `json:"session"`
+	Params      map[string]interface{} `json:"params"`
+}
+
+// Error is an error of a notice; the first is the one raised and the others its causes
+type Error struct {
+	Type      string  `json:"type"`
+	Message   string  `json:"message"`
+	Backtrace []Frame `json:"backtrace"`
+}
+
+// Frame is one backtrace frame, most recent first
+type Frame struct {
+	File     string            `json:"file"`
+	Line     json.Number       `json:"line"`
+	Column   json.Number       `json:"column"`
+	Function string            `json:"function"`
+	Code     map[string]string `json:"code"`
+}
+
+// Context describes where a notice was reported from
+type Context struct {
+	Notifier      Notifier `json:"notifier"`
+	Environment   string   `json:"environment"`
+	Severity      string   `json:"severity"`
+	Component     string   `json:"component"`
+	Action        string   `json:"action"`
+	OS            string   `json:"os"`
+	Hostname      string   `json:"hostname"`
+	Language      string   `json:"language"`
+	Version       string   `json:"version"`
+	Revision      string   `json:"revision"`
+	RootDirectory string   `json:"rootDirectory"`
+	URL           string   `json:"url"`
+	Route         string   `json:"route"`
+	HTTPMethod    string   `json:"httpMethod"`
+	UserAgent     string   `json:"userAgent"`
+	UserAddr      string   `json:"userAddr"`
+	RemoteAddr    string   `json:"remoteAddr"`
+	User          *User    `json:"user"`
+}
+
+// Notifier identifies the notifier that sent a notice
+type Notifier struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+}
+
+// User identifies the affected user
+type User struct {
+	ID       json.RawMessage `json:"id"`
+	Name     string          `json:"name"`
+	Username string          `json:"username"`
+	Email    string          `json:"email"`
+}
+
+// Convert turns a notice into a notice request for the fault grouper. The first error is the
+// one raised, with the type and message of the others kept in the notice context as causes.
+// The notice environment, together with the context fields that describe the host and client,
+// becomes the server data.
+func Convert(notice *Notice) (*models.NoticeRequest, error) {
+	if len(notice.Errors) == 0 {
+		return nil, errors.New("errors must contain at least one error")
+	}
+
+	req := &models.NoticeRequest{}
+	ctx := &notice.Context
+	req.Notifier.Name = ctx.Notifier.Name
+	req.Notifier.Version = ctx.Notifier.Version
+	req.Notifier.URL = ctx.Notifier.URL
+
+	raised := &notice.Errors[0]
+	req.Error.Class = raised.Type
+	req.Error.Message = raised.Message
+	req.Error.Backtrace = make([]models.BacktraceFrame, 0, len(raised.Backtrace))
+	for i := range raised.Backtrace {
+		req.Error.Backtrace = append(req.Error.Backtrace, convertFrame(&raised.Backtrace[i]))
+	}
+
+	req.Request.URL = ctx.URL
+	req.Request.Component = ctx.Component
+	req.Request.Action = ctx.Action
+	req.Request.Params = notice.Params
+	req.Request.Session = notice.Session
+
+	context := map[string]interface{}{}
+	if len(notice.Errors) > 1 {
+		var causes []map[string]interface{}
+		for _, cause := range notice.Errors[1:] {
+			causes = append(causes, map[string]interface{}{
+				"class":   cause.Type,
+				"message": cause.Message,
+			})
+		}
+		context["causes"] = causes
+	}
+	if ctx.Severity != "" {
+		context["severity"] = ctx.Severity
+	}
+	if u := ctx.User; u != nil {
+		for key, value := range map[string]string{
+			"user_id":    userID(u.ID),
+			"user_email": u.Email,
+			"user_name":  u.Name,
+			"username":   u.Username,
+		} {
+			if value != "" {
+				context[key] = value
+			}
+		}
+	}
+	if len(context) > 0 {
+		req.Request.Context = context
+	}
+
+	req.Server.EnvironmentName = ctx.Environment
+	req.Server.Hostname = ctx.Hostname
+	req.Server.ProjectRoot = ctx.RootDirectory
+	req.Server.Revision = ctx.Revision
+	if req.Server.Revision == "" {
+		req.Server.Revision = ctx.Version
+	}
+
+	serverData := make(map[string]interface{}, len(notice.Environment))
+	for key, value := range notice.Environment {
+		serverData[key] = value
+	}
+	for key, value := range map[string]string{
+		"os":             ctx.OS,
+		"language":       ctx.Language,
+		"version":        ctx.Version,
+		"route":          ctx.Route,
+		"request_method": ctx.HTTPMethod,
+		"user_agent":     ctx.UserAgent,
+		"user_addr":      ctx.UserAddr,
+		"remote_addr":    ctx.RemoteAddr,
+	} {
+		if value != "" {
+			serverData[key] = value
+		}
+	}
+	if len(serverData) > 0 {
+		req.Server.Data = serverData
+	}
+
+	return req, nil
+}
+
+// convertFrame converts a backtrace frame. Frames under the project root placeholder are
+// application frames; the frame's own line of code, when sent, becomes the frame code.
+func convertFrame(frame *Frame) models.BacktraceFrame {
+	converted := models.BacktraceFrame{
+		File:     frame.File,
+		Function: frame.Function,
+		Context:  "all",
+	}
+	if line, err := strconv.Atoi(frame.Line.String()); err == nil {
+		converted.Line = &line
+		converted.Code = frame.Code[frame.Line.String()]
+	}
+	if strings.HasPrefix(frame.File, ProjectRoot+"/") {
+		converted.Context = "app"
+	}
+	return converted
+}
+
+// userID returns a user ID sent as a string or a number
+func userID(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return string(raw)
+}
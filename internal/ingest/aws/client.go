@@ -0,0 +1,145 @@
+// Package aws reads logs from Amazon SQS queues and Kinesis Data Streams, such as CloudWatch Logs
+// subscriptions and logs shipped from Lambda. It speaks the services' JSON protocols directly,
+// signing requests with the AWS SDK's Signature Version 4 signer and default credential chain.
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	sdkaws "github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// maxResponseBytes bounds an AWS API response; GetRecords returns at most 10 MB
+const maxResponseBytes = 16 << 20
+
+// APIError is an error returned by an AWS API
+type APIError struct {
+	Status  int
+	Type    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d): %s", e.Type, e.Status, e.Message)
+}
+
+// client calls one AWS service's JSON protocol
+type client struct {
+	service      string
+	region       string
+	endpoint     string
+	contentType  string
+	targetPrefix string
+	httpClient   *http.Client
+	signer       *v4.Signer
+
+	mu sync.Mutex
+	// credentials is loaded from the default chain on first use
+	credentials sdkaws.CredentialsProvider
+}
+
+// newClient creates a client for service in region. Endpoint overrides the regional endpoint,
+// for example to use LocalStack.
+func newClient(service, region, endpoint, contentType, targetPrefix string) *client {
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com", service, region)
+	}
+	httpClient := &http.Client{Timeout: 60 * time.Second}
+	return &client{
+		service:      service,
+		region:       region,
+		endpoint:     strings.TrimSuffix(endpoint, "/"),
+		contentType:  contentType,
+		targetPrefix: targetPrefix,
+		httpClient:   httpClient,
+		signer:       v4.NewSigner(),
+	}
+}
+
+// call invokes action with input and decodes the response into output
+func (c *client) call(ctx context.Context, action string, input, output interface{}) error {
+	body, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	provider, err := c.credentialsProvider(ctx)
+	if err != nil {
+		return err
+	}
+	creds, err := provider.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("error retrieving AWS credentials: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", c.contentType)
+	req.Header.Set("X-Amz-Target", c.targetPrefix+"."+action)
+	payloadHash := sha256.Sum256(body)
+	if err := c.signer.SignHTTP(ctx, creds, req, hex.EncodeToString(payloadHash[:]), c.service, c.region, time.Now()); err != nil {
+		return fmt.Errorf("error signing request: %w", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := &APIError{Status: resp.StatusCode, Message: strings.TrimSpace(string(data))}
+		var failure struct {
+			Type         string `json:"__type"`
+			Message      string `json:"message"`
+			MessageUpper string `json:"Message"`
+		}
+		if json.Unmarshal(data, &failure) == nil && failure.Type != "" {
+			// Types may be namespaced, as in com.amazonaws.sqs#QueueDoesNotExist
+			apiErr.Type = failure.Type[strings.LastIndex(failure.Type, "#")+1:]
+			apiErr.Message = failure.Message + failure.MessageUpper
+		}
+		return apiErr
+	}
+	if output == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, output)
+}
+
+// credentialsProvider loads the default credential chain: environment variables, shared
+// config and credentials files, web identity, and the ECS and EC2 instance roles. Credentials
+// are cached and refreshed before they expire; a failed load is retried on the next call.
+func (c *client) credentialsProvider(ctx context.Context) (sdkaws.CredentialsProvider, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.credentials != nil {
+		return c.credentials, nil
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(c.region))
+	if err != nil {
+		return nil, fmt.Errorf("error loading AWS configuration: %w", err)
+	}
+	if cfg.Credentials == nil {
+		return nil, fmt.Errorf("no AWS credentials found in the environment, shared configuration or instance role")
+	}
+	c.credentials = cfg.Credentials
+	return c.credentials, nil
+}
@@ -0,0 +1,175 @@
+package aws
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/pkg/models"
+	"strings"
+	"time"
+)
+
+// maxPayloadBytes caps a message or record after decompression
+const maxPayloadBytes = 10 << 20
+
+// item is one log decoded from a payload, or the reason it could not be
+type item struct {
+	entry *models.LogEntry
+	raw   []byte
+	err   error
+}
+
+// envelope holds the fields that identify the wrappers a payload may arrive in
+type envelope struct {
+	// SNS notification delivered to SQS
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+	// CloudWatch Logs subscription
+	MessageType string            `json:"messageType"`
+	LogGroup    string            `json:"logGroup"`
+	LogStream   string            `json:"logStream"`
+	Owner       string            `json:"owner"`
+	LogEvents   []cloudWatchEvent `json:"logEvents"`
+	// Batch in the shape of POST /api/v1/logs/batch
+	Logs []json.RawMessage `json:"logs"`
+}
+
+type cloudWatchEvent struct {
+	ID        string `json:"id"`
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// decodePayload reads the logs in a message body or stream record. Payloads may be gzip
+// compressed and may be wrapped in an SNS notification. They hold a CloudWatch Logs subscription
+// message, a {"logs": [...]} batch, a JSON array or object, or newline-delimited lines, each
+// parsed with p. An error means the payload as a whole could not be read.
+func decodePayload(p parser.Parser, data []byte) ([]item, error) {
+	return decodeWrapped(p, data, 0)
+}
+
+func decodeWrapped(p parser.Parser, data []byte, depth int) ([]item, error) {
+	if len(data) >= 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing payload: %w", err)
+		}
+		defer zr.Close()
+		data, err = io.ReadAll(io.LimitReader(zr, maxPayloadBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing payload: %w", err)
+		}
+		if len(data) > maxPayloadBytes {
+			return nil, fmt.Errorf("payload exceeds %d bytes after decompression", maxPayloadBytes)
+		}
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("payload is empty")
+	}
+
+	switch trimmed[0] {
+	case '{':
+		var env envelope
+		if err := json.Unmarshal(trimmed, &env); err != nil {
+			return nil, fmt.Errorf("payload is not valid JSON: %w", err)
+		}
+		switch {
+		case env.Type == "Notification" && env.Message != "" && depth == 0:
+			return decodeWrapped(p, []byte(env.Message), depth+1)
+		case env.MessageType == "CONTROL_MESSAGE":
+			// Sent by CloudWatch Logs to check the destination is reachable
+			return nil, nil
+		case env.MessageType == "DATA_MESSAGE":
+			return decodeCloudWatch(p, &env), nil
+		case env.Logs != nil:
+			items := make([]item, 0, len(env.Logs))
+			for _, raw := range env.Logs {
+				items = append(items, parseItem(p, raw))
+			}
+			return items, nil
+		}
+		return []item{parseItem(p, trimmed)}, nil
+	case '[':
+		var raws []json.RawMessage
+		if err := json.Unmarshal(trimmed, &raws); err != nil {
+			return nil, fmt.Errorf("payload is not valid JSON: %w", err)
+		}
+		items := make([]item, 0, len(raws))
+		for _, raw := range raws {
+			items = append(items, parseItem(p, raw))
+		}
+		return items, nil
+	}
+
+	var items []item
+	scanner := bufio.NewScanner(bytes.NewReader(trimmed))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxPayloadBytes)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		items = append(items, parseItem(p, append([]byte(nil), line...)))
+	}
+	return items, scanner.Err()
+}
+
+// parseItem parses one log. A JSON string is parsed as a log line.
+func parseItem(p parser.Parser, raw []byte) item {
+	data := raw
+	var line string
+	if json.Unmarshal(raw, &line) == nil {
+		data = []byte(line)
+	}
+	entry, err := p.Parse(data)
+	return item{entry: entry, raw: raw, err: err}
+}
+
+// decodeCloudWatch parses the events of a CloudWatch Logs subscription message. Events keep their
+// CloudWatch timestamp, and are attributed to the log group when the parser finds no service.
+func decodeCloudWatch(p parser.Parser, env *envelope) []item {
+	service := logGroupService(env.LogGroup)
+	items := make([]item, 0, len(env.LogEvents))
+	for _, event := range env.LogEvents {
+		it := parseItem(p, []byte(event.Message))
+		it.raw = []byte(event.Message)
+		if it.entry != nil {
+			if it.entry.Service == "" || it.entry.Service == parser.UnknownService {
+				it.entry.Service = service
+			}
+			if event.Timestamp > 0 {
+				it.entry.Timestamp = time.UnixMilli(event.Timestamp).UTC()
+			}
+			if it.entry.Metadata == nil {
+				it.entry.Metadata = make(map[string]interface{})
+			}
+			it.entry.Metadata["log_group"] = env.LogGroup
+			it.entry.Metadata["log_stream"] = env.LogStream
+			it.entry.Metadata["cloudwatch_event_id"] = event.ID
+			if env.Owner != "" {
+				it.entry.Metadata["aws_account_id"] = env.Owner
+			}
+		}
+		items = append(items, it)
+	}
+	return items
+}
+
+// logGroupService names a service after the last part of a log group, so /aws/lambda/checkout
+// becomes checkout
+func logGroupService(group string) string {
+	group = strings.TrimRight(group, "/")
+	if i := strings.LastIndex(group, "/"); i >= 0 {
+		group = group[i+1:]
+	}
+	if group == "" {
+		return parser.UnknownService
+	}
+	return group
+}
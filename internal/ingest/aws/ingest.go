@@ -0,0 +1,198 @@
+package aws
+
+import (
+	"context"
+	"log"
+	"log-ingestion-service/internal/batch"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/sources"
+	"log-ingestion-service/internal/validator"
+	"log-ingestion-service/pkg/models"
+	"sync"
+	"time"
+)
+
+// Listener kinds
+const (
+	KindSQS     = "sqs"
+	KindKinesis = "kinesis"
+)
+
+// pausedWait is how long a paused poller waits before checking again
+const pausedWait = 5 * time.Second
+
+// ackTimeout bounds deleting messages and saving checkpoints, which continue after Stop so
+// stored logs are not read again
+const ackTimeout = 30 * time.Second
+
+// Options configures an SQS or Kinesis listener
+type Options struct {
+	// Region is the AWS region of the queue or stream
+	Region string
+	// Endpoint overrides the regional endpoint, for example for LocalStack
+	Endpoint string
+	// DLQURL is an SQS queue that receives payloads holding no valid logs; without it they are dropped
+	DLQURL string
+	// Paused stops polling while it reports true; data waits in the queue or stream
+	Paused func() bool
+	// Sources counts accepted and rejected logs under the listener's name
+	Sources *sources.Tracker
+}
+
+// ingester validates and stores the logs in payloads read by a poller
+type ingester struct {
+	name      string
+	parser    parser.Parser
+	validator *validator.Validator
+	batcher   *batch.Batcher
+	opts      Options
+	dlq       *client
+
+	// stop ends polling; wg tracks the pollers
+	stop    context.CancelFunc
+	wg      sync.WaitGroup
+	lastLog time.Time
+	logMu   sync.Mutex
+}
+
+func newIngester(name string, opts Options, p parser.Parser, v *validator.Validator, b *batch.Batcher) *ingester {
+	in := &ingester{name: name, parser: p, validator: v, batcher: b, opts: opts}
+	if opts.DLQURL != "" {
+		in.dlq = newSQSClient(opts.Region, opts.Endpoint)
+	}
+	return in
+}
+
+// payload is one SQS message or Kinesis record
+type payload struct {
+	data     []byte
+	metadata map[string]interface{}
+}
+
+// result is the outcome of decoding a payload
+type result struct {
+	entries []models.LogEntry
+	// deadLetter is set when the payload holds no valid logs
+	deadLetter bool
+	reason     string
+}
+
+// decode parses and validates a payload's logs. Invalid logs are counted by rec and dropped.
+func (in *ingester) decode(rec *sources.Recorder, p payload) result {
+	items, err := decodePayload(in.parser, p.data)
+	if err != nil {
+		rec.RejectBody(p.data, err)
+		return result{deadLetter: true, reason: err.Error()}
+	}
+
+	var res result
+	for _, it := range items {
+		if it.err != nil {
+			rec.Reject("", it.raw, it.err)
+			res.reason = it.err.Error()
+			continue
+		}
+		entry := it.entry
+		if entry.Metadata == nil {
+			entry.Metadata = make(map[string]interface{})
+		}
+		for k, v := range p.metadata {
+			entry.Metadata[k] = v
+		}
+		entry.Metadata["listener"] = in.name
+		if err := in.validator.Validate(entry); err != nil {
+			rec.Reject(entry.Service, it.raw, err)
+			res.reason = err.Error()
+			continue
+		}
+		in.validator.Sanitize(entry)
+		res.entries = append(res.entries, *entry)
+	}
+	res.deadLetter = len(items) > 0 && len(res.entries) == 0
+	return res
+}
+
+// store writes entries, returning once they are durable
+func (in *ingester) store(rec *sources.Recorder, entries []models.LogEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := in.batcher.WriteBatch(entries); err != nil {
+		return err
+	}
+	for i := range entries {
+		rec.Accept(entries[i].Service)
+	}
+	return nil
+}
+
+// deadLetter sends a payload to the DLQ, if one is configured
+func (in *ingester) deadLetter(ctx context.Context, data []byte, source, reason string) error {
+	if in.dlq == nil {
+		return nil
+	}
+	return sendMessage(ctx, in.dlq, in.opts.DLQURL, data, map[string]string{
+		"listener": in.name,
+		"source":   source,
+		"reason":   reason,
+	})
+}
+
+// paused reports whether ingestion is paused, waiting before returning true so callers can loop
+func (in *ingester) paused(ctx context.Context) bool {
+	if in.opts.Paused == nil || !in.opts.Paused() {
+		return false
+	}
+	sleep(ctx, pausedWait)
+	return true
+}
+
+// warn logs a polling failure at most once a minute
+func (in *ingester) warn(format string, args ...interface{}) {
+	in.logMu.Lock()
+	defer in.logMu.Unlock()
+	if time.Since(in.lastLog) < time.Minute {
+		return
+	}
+	in.lastLog = time.Now()
+	log.Printf("WARN: Listener %s: "+format, append([]interface{}{in.name}, args...)...)
+}
+
+// wait stops the pollers and waits for them until ctx is done
+func (in *ingester) wait(ctx context.Context) error {
+	if in.stop != nil {
+		in.stop()
+	}
+	done := make(chan struct{})
+	go func() {
+		in.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// backoff doubles a retry delay up to a minute
+func backoff(d time.Duration) time.Duration {
+	if d <= 0 {
+		return time.Second
+	}
+	if d *= 2; d > time.Minute {
+		return time.Minute
+	}
+	return d
+}
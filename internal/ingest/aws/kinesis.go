@@ -0,0 +1,314 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/batch"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/validator"
+	"log-ingestion-service/pkg/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Kinesis read pacing; a shard allows five GetRecords calls a second
+const (
+	kinesisRecordLimit = 1000
+	kinesisIdleWait    = time.Second
+	kinesisBusyWait    = 200 * time.Millisecond
+	kinesisShardRescan = time.Minute
+)
+
+// Checkpoints stores the last sequence number stored from each shard
+type Checkpoints interface {
+	GetCheckpoints(ctx context.Context, listener, stream string) (map[string]string, error)
+	SaveCheckpoint(ctx context.Context, listener, stream, shard, sequence string) error
+}
+
+type kinesisRecord struct {
+	Data           []byte `json:"Data"`
+	SequenceNumber string `json:"SequenceNumber"`
+	PartitionKey   string `json:"PartitionKey"`
+}
+
+// KinesisListener reads every shard of a Kinesis data stream. Each shard's position is
+// checkpointed once its logs are stored, so a restart resumes after the last stored record;
+// records are retried until stored or sent to the DLQ. Shards are read independently, so a stream
+// should be read by one instance only.
+type KinesisListener struct {
+	*ingester
+	stream      string
+	position    string
+	client      *client
+	checkpoints Checkpoints
+
+	mu sync.Mutex
+	// shards holds the shards being read, and those read to their end
+	shards map[string]bool
+}
+
+// NewKinesis creates a listener reading stream. Shards without a checkpoint start at position,
+// LATEST or TRIM_HORIZON.
+func NewKinesis(name, stream, position string, opts Options, checkpoints Checkpoints, p parser.Parser, v *validator.Validator, b *batch.Batcher) *KinesisListener {
+	if position == "" {
+		position = "LATEST"
+	}
+	return &KinesisListener{
+		ingester:    newIngester(name, opts, p, v, b),
+		stream:      stream,
+		position:    position,
+		client:      newClient("kinesis", opts.Region, opts.Endpoint, "application/x-amz-json-1.1", "Kinesis_20131202"),
+		checkpoints: checkpoints,
+		shards:      make(map[string]bool),
+	}
+}
+
+// Name returns the listener name
+func (l *KinesisListener) Name() string { return l.name }
+
+// Kind returns the listener kind
+func (l *KinesisListener) Kind() string { return KindKinesis }
+
+// Addr returns the stream name
+func (l *KinesisListener) Addr() string { return l.stream }
+
+// TLS reports whether the stream is reached over HTTPS
+func (l *KinesisListener) TLS() bool { return strings.HasPrefix(l.client.endpoint, "https://") }
+
+// Start checks the stream can be read and reads its shards in the background
+func (l *KinesisListener) Start(fail func(error)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	shards, err := l.listShards(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing shards of stream %s: %w", l.stream, err)
+	}
+	checkpoints, err := l.checkpoints.GetCheckpoints(ctx, l.name, l.stream)
+	if err != nil {
+		return err
+	}
+
+	readCtx, stop := context.WithCancel(context.Background())
+	l.stop = stop
+	// A restarted listener reads every shard again from its checkpoint
+	l.mu.Lock()
+	l.shards = make(map[string]bool)
+	l.mu.Unlock()
+	l.startShards(readCtx, shards, checkpoints)
+	l.wg.Add(1)
+	go l.watch(readCtx)
+	return nil
+}
+
+// Stop ends reading and waits for records being stored until ctx is done
+func (l *KinesisListener) Stop(ctx context.Context) error {
+	return l.wait(ctx)
+}
+
+// listShards returns the IDs of the stream's shards, including closed ones still holding records
+func (l *KinesisListener) listShards(ctx context.Context) ([]string, error) {
+	var ids []string
+	input := map[string]interface{}{"StreamName": l.stream}
+	for {
+		var out struct {
+			Shards []struct {
+				ShardID string `json:"ShardId"`
+			} `json:"Shards"`
+			NextToken string `json:"NextToken"`
+		}
+		if err := l.client.call(ctx, "ListShards", input, &out); err != nil {
+			return nil, err
+		}
+		for _, s := range out.Shards {
+			ids = append(ids, s.ShardID)
+		}
+		if out.NextToken == "" {
+			return ids, nil
+		}
+		// Continuation calls name only the token
+		input = map[string]interface{}{"NextToken": out.NextToken}
+	}
+}
+
+// watch starts readers for shards created by resharding
+func (l *KinesisListener) watch(ctx context.Context) {
+	defer l.wg.Done()
+	ticker := time.NewTicker(kinesisShardRescan)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		shards, err := l.listShards(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				l.warn("listing shards of %s failed: %v", l.stream, err)
+			}
+			continue
+		}
+		checkpoints, err := l.checkpoints.GetCheckpoints(ctx, l.name, l.stream)
+		if err != nil {
+			if ctx.Err() == nil {
+				l.warn("loading checkpoints failed: %v", err)
+			}
+			continue
+		}
+		l.startShards(ctx, shards, checkpoints)
+	}
+}
+
+// startShards starts a reader for each shard not already read
+func (l *KinesisListener) startShards(ctx context.Context, shards []string, checkpoints map[string]string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, shard := range shards {
+		if l.shards[shard] {
+			continue
+		}
+		l.shards[shard] = true
+		l.wg.Add(1)
+		go l.read(ctx, shard, checkpoints[shard])
+	}
+}
+
+// read stores the records of one shard, resuming after sequence when it is set
+func (l *KinesisListener) read(ctx context.Context, shard, sequence string) {
+	defer l.wg.Done()
+
+	var iterator string
+	var delay time.Duration
+	retry := func(format string, args ...interface{}) {
+		delay = backoff(delay)
+		l.warn(format, args...)
+		sleep(ctx, delay)
+	}
+
+	for ctx.Err() == nil {
+		if l.paused(ctx) {
+			// Iterators expire after five minutes, so take a fresh one once resumed
+			iterator = ""
+			continue
+		}
+
+		if iterator == "" {
+			var err error
+			iterator, err = l.shardIterator(ctx, shard, sequence)
+			if err != nil {
+				if ctx.Err() == nil {
+					retry("getting an iterator for shard %s failed: %v", shard, err)
+				}
+				continue
+			}
+		}
+
+		var out struct {
+			Records           []kinesisRecord `json:"Records"`
+			NextShardIterator *string         `json:"NextShardIterator"`
+		}
+		err := l.client.call(ctx, "GetRecords", map[string]interface{}{
+			"ShardIterator": iterator,
+			"Limit":         kinesisRecordLimit,
+		}, &out)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.Type == "ExpiredIteratorException" {
+				iterator = ""
+				continue
+			}
+			retry("reading shard %s failed: %v", shard, err)
+			continue
+		}
+
+		if len(out.Records) > 0 {
+			last, err := l.process(shard, out.Records)
+			if err != nil {
+				// Read the same records again from the last checkpoint
+				iterator = ""
+				retry("storing records from shard %s failed; they will be retried: %v", shard, err)
+				continue
+			}
+			sequence = last
+		}
+		delay = 0
+
+		if out.NextShardIterator == nil {
+			log.Printf("Listener %s: shard %s of %s is closed and fully read", l.name, shard, l.stream)
+			return
+		}
+		iterator = *out.NextShardIterator
+		if len(out.Records) == 0 {
+			sleep(ctx, kinesisIdleWait)
+		} else {
+			sleep(ctx, kinesisBusyWait)
+		}
+	}
+}
+
+// shardIterator starts reading a shard after sequence, or at the configured position
+func (l *KinesisListener) shardIterator(ctx context.Context, shard, sequence string) (string, error) {
+	input := map[string]interface{}{
+		"StreamName":        l.stream,
+		"ShardId":           shard,
+		"ShardIteratorType": l.position,
+	}
+	if sequence != "" {
+		input["ShardIteratorType"] = "AFTER_SEQUENCE_NUMBER"
+		input["StartingSequenceNumber"] = sequence
+	}
+	var out struct {
+		ShardIterator string `json:"ShardIterator"`
+	}
+	if err := l.client.call(ctx, "GetShardIterator", input, &out); err != nil {
+		return "", err
+	}
+	return out.ShardIterator, nil
+}
+
+// process stores the logs of a batch of records and checkpoints the last one, returning its
+// sequence number. It is not tied to the read context, so a batch being stored during Stop is
+// still checkpointed.
+func (l *KinesisListener) process(shard string, records []kinesisRecord) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+	defer cancel()
+
+	rec := l.opts.Sources.Begin("", l.name)
+	defer rec.Flush()
+
+	var entries []models.LogEntry
+	for _, r := range records {
+		res := l.decode(rec, payload{
+			data: r.Data,
+			metadata: map[string]interface{}{
+				"kinesis_stream":  l.stream,
+				"shard_id":        shard,
+				"sequence_number": r.SequenceNumber,
+				"partition_key":   r.PartitionKey,
+			},
+		})
+		if res.deadLetter {
+			if err := l.deadLetter(ctx, r.Data, shard+"/"+r.SequenceNumber, res.reason); err != nil {
+				return "", fmt.Errorf("error sending record %s to the DLQ: %w", r.SequenceNumber, err)
+			}
+			continue
+		}
+		entries = append(entries, res.entries...)
+	}
+
+	if err := l.store(rec, entries); err != nil {
+		return "", err
+	}
+	last := records[len(records)-1].SequenceNumber
+	if err := l.checkpoints.SaveCheckpoint(ctx, l.name, l.stream, shard, last); err != nil {
+		// The logs are stored; a restart before the next checkpoint reads them again
+		l.warn("checkpointing shard %s failed: %v", shard, err)
+	}
+	return last, nil
+}
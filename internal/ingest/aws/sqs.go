@@ -0,0 +1,216 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/internal/batch"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/validator"
+	"log-ingestion-service/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SQS long polling limits
+const (
+	sqsMaxMessages = 10
+	sqsWaitSeconds = 20
+)
+
+func newSQSClient(region, endpoint string) *client {
+	return newClient("sqs", region, endpoint, "application/x-amz-json-1.0", "AmazonSQS")
+}
+
+type sqsMessage struct {
+	MessageID     string `json:"MessageId"`
+	ReceiptHandle string `json:"ReceiptHandle"`
+	Body          string `json:"Body"`
+}
+
+type sqsAttribute struct {
+	DataType    string `json:"DataType"`
+	StringValue string `json:"StringValue"`
+}
+
+// sendMessage sends body to a queue with string message attributes
+func sendMessage(ctx context.Context, c *client, queueURL string, body []byte, attributes map[string]string) error {
+	attrs := make(map[string]sqsAttribute, len(attributes))
+	for name, value := range attributes {
+		if value != "" {
+			attrs[name] = sqsAttribute{DataType: "String", StringValue: truncate(value, 256)}
+		}
+	}
+	return c.call(ctx, "SendMessage", map[string]interface{}{
+		"QueueUrl":          queueURL,
+		"MessageBody":       string(body),
+		"MessageAttributes": attrs,
+	}, nil)
+}
+
+// SQSListener drains log messages from an SQS queue. A message is deleted once its logs are
+// stored, or sent to the DLQ when it holds none; otherwise it becomes visible again and is
+// retried after the queue's visibility timeout.
+type SQSListener struct {
+	*ingester
+	queueURL string
+	client   *client
+}
+
+// NewSQS creates a listener reading queueURL
+func NewSQS(name, queueURL string, opts Options, p parser.Parser, v *validator.Validator, b *batch.Batcher) *SQSListener {
+	return &SQSListener{
+		ingester: newIngester(name, opts, p, v, b),
+		queueURL: queueURL,
+		client:   newSQSClient(opts.Region, opts.Endpoint),
+	}
+}
+
+// Name returns the listener name
+func (l *SQSListener) Name() string { return l.name }
+
+// Kind returns the listener kind
+func (l *SQSListener) Kind() string { return KindSQS }
+
+// Addr returns the queue URL
+func (l *SQSListener) Addr() string { return l.queueURL }
+
+// TLS reports whether the queue is reached over HTTPS
+func (l *SQSListener) TLS() bool { return strings.HasPrefix(l.client.endpoint, "https://") }
+
+// Start checks the queue can be read and polls it in the background
+func (l *SQSListener) Start(fail func(error)) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	err := l.client.call(ctx, "GetQueueAttributes", map[string]interface{}{
+		"QueueUrl":       l.queueURL,
+		"AttributeNames": []string{"QueueArn"},
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("error reaching queue %s: %w", l.queueURL, err)
+	}
+
+	pollCtx, stop := context.WithCancel(context.Background())
+	l.stop = stop
+	l.wg.Add(1)
+	go l.poll(pollCtx)
+	return nil
+}
+
+// Stop ends polling and waits for messages being processed until ctx is done
+func (l *SQSListener) Stop(ctx context.Context) error {
+	return l.wait(ctx)
+}
+
+func (l *SQSListener) poll(ctx context.Context) {
+	defer l.wg.Done()
+
+	var delay time.Duration
+	for ctx.Err() == nil {
+		if l.paused(ctx) {
+			continue
+		}
+
+		var out struct {
+			Messages []sqsMessage `json:"Messages"`
+		}
+		err := l.client.call(ctx, "ReceiveMessage", map[string]interface{}{
+			"QueueUrl":            l.queueURL,
+			"MaxNumberOfMessages": sqsMaxMessages,
+			"WaitTimeSeconds":     sqsWaitSeconds,
+		}, &out)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			delay = backoff(delay)
+			l.warn("receiving from %s failed, retrying in %s: %v", l.queueURL, delay, err)
+			sleep(ctx, delay)
+			continue
+		}
+		delay = 0
+
+		if len(out.Messages) > 0 {
+			l.process(out.Messages)
+		}
+	}
+}
+
+// process stores the logs of a batch of messages and deletes the messages that are done with.
+// It is not tied to the poll context, so messages whose logs were stored are still deleted
+// during Stop.
+func (l *SQSListener) process(messages []sqsMessage) {
+	ctx, cancel := context.WithTimeout(context.Background(), ackTimeout)
+	defer cancel()
+
+	rec := l.opts.Sources.Begin("", l.name)
+	defer rec.Flush()
+
+	var done, pending []sqsMessage
+	var entries []models.LogEntry
+	for _, m := range messages {
+		res := l.decode(rec, payload{
+			data:     []byte(m.Body),
+			metadata: map[string]interface{}{"sqs_message_id": m.MessageID},
+		})
+		if res.deadLetter {
+			if err := l.deadLetter(ctx, []byte(m.Body), m.MessageID, res.reason); err != nil {
+				l.warn("sending message %s to the DLQ failed: %v", m.MessageID, err)
+				continue
+			}
+			done = append(done, m)
+			continue
+		}
+		entries = append(entries, res.entries...)
+		pending = append(pending, m)
+	}
+
+	// One write for the whole receive; if it fails every message is retried
+	if err := l.store(rec, entries); err != nil {
+		l.warn("storing logs from %d message(s) failed; they will be retried: %v", len(pending), err)
+	} else {
+		done = append(done, pending...)
+	}
+	l.deleteMessages(ctx, done)
+}
+
+// deleteMessages removes processed messages from the queue. Messages that cannot be deleted are
+// received again, so their logs may be stored twice.
+func (l *SQSListener) deleteMessages(ctx context.Context, messages []sqsMessage) {
+	if len(messages) == 0 {
+		return
+	}
+	entries := make([]map[string]string, len(messages))
+	for i, m := range messages {
+		entries[i] = map[string]string{"Id": strconv.Itoa(i), "ReceiptHandle": m.ReceiptHandle}
+	}
+	var out struct {
+		Failed []struct {
+			ID      string `json:"Id"`
+			Message string `json:"Message"`
+		} `json:"Failed"`
+	}
+	err := l.client.call(ctx, "DeleteMessageBatch", map[string]interface{}{
+		"QueueUrl": l.queueURL,
+		"Entries":  entries,
+	}, &out)
+	if err != nil {
+		l.warn("deleting %d message(s) failed; they will be received again: %v", len(messages), err)
+		return
+	}
+	if len(out.Failed) > 0 {
+		l.warn("deleting %d message(s) failed; they will be received again: %s", len(out.Failed), out.Failed[0].Message)
+	}
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
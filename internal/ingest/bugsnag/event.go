@@ -0,0 +1,267 @@
+// Package bugsnag converts Bugsnag error reporting payloads into notices, so Bugsnag SDKs can
+// report errors to the service unchanged.
+package bugsnag
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIKeyHeader carries the project API key sent by Bugsnag SDKs
+const APIKeyHeader = "Bugsnag-Api-Key"
+
+// Payload is the body of a notify request
+type Payload struct {
+	APIKey         string   `json:"apiKey"`
+	PayloadVersion string   `json:"payloadVersion"`
+	Notifier       Notifier `json:"notifier"`
+	Events         []Event  `json:"events"`
+}
+
+// Notifier identifies the SDK that sent a payload
+type Notifier struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	URL     string `json:"url"`
+}
+
+// Event is one error occurrence
+type Event struct {
+	Exceptions   []Exception                       `json:"exceptions"`
+	Breadcrumbs  []Breadcrumb                      `json:"breadcrumbs"`
+	Request      *Request                          `json:"request"`
+	Context      string                            `json:"context"`
+	GroupingHash string                            `json:"groupingHash"`
+	Unhandled    *bool                             `json:"unhandled"`
+	Severity     string                            `json:"severity"`
+	User         *User                             `json:"user"`
+	App          *App                              `json:"app"`
+	Device       map[string]interface{}            `json:"device"`
+	MetaData     map[string]map[string]interface{} `json:"metaData"`
+}
+
+// Exception is an error of an event; the first is the one thrown and the others its causes
+type Exception struct {
+	ErrorClass string       `json:"errorClass"`
+	Message    string       `json:"message"`
+	Type       string       `json:"type"`
+	Stacktrace []StackFrame `json:"stacktrace"`
+}
+
+// StackFrame is one frame of a stacktrace, most recent first
+type StackFrame struct {
+	File         string            `json:"file"`
+	LineNumber   json.Number       `json:"lineNumber"`
+	ColumnNumber json.Number       `json:"columnNumber"`
+	Method       string            `json:"method"`
+	InProject    bool              `json:"inProject"`
+	Code         map[string]string `json:"code"`
+}
+
+// Breadcrumb is an event leading up to an error
+type Breadcrumb struct {
+	Timestamp string                 `json:"timestamp"`
+	Name      string                 `json:"name"`
+	Type      string                 `json:"type"`
+	MetaData  map[string]interface{} `json:"metaData"`
+}
+
+// Request describes the HTTP request being served
+type Request struct {
+	ClientIP   string                 `json:"clientIp"`
+	Headers    map[string]interface{} `json:"headers"`
+	HTTPMethod string                 `json:"httpMethod"`
+	URL        string                 `json:"url"`
+	Referer    string                 `json:"referer"`
+}
+
+// User identifies the affected user
+type User struct {
+	ID    json.RawMessage `json:"id"`
+	Name  string          `json:"name"`
+	Email string          `json:"email"`
+}
+
+// App describes the application that reported an event
+type App struct {
+	ID           string `json:"id"`
+	Version      string `json:"version"`
+	ReleaseStage string `json:"releaseStage"`
+	Type         string `json:"type"`
+}
+
+// Convert turns every event of a payload into a notice request for the fault grouper. The
+// first exception is the error, with the class and message of the others kept in the notice
+// context as causes. Each metaData tab becomes a notice context key, so account fields such as
+// "account.id" can name a tab's values. A context of the form "controller#action" sets the
+// request component and action.
+func Convert(payload *Payload) ([]*models.NoticeRequest, error) {
+	if len(payload.Events) == 0 {
+		return nil, errors.New("events must contain at least one event")
+	}
+
+	reqs := make([]*models.NoticeRequest, 0, len(payload.Events))
+	for i := range payload.Events {
+		req, err := convertEvent(&payload.Events[i], &payload.Notifier)
+		if err != nil {
+			return nil, fmt.Errorf("events[%d]: %w", i, err)
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+// convertEvent converts one event
+func convertEvent(event *Event, notifier *Notifier) (*models.NoticeRequest, error) {
+	if len(event.Exceptions) == 0 {
+		return nil, errors.New("exceptions must contain at least one exception")
+	}
+
+	req := &models.NoticeRequest{}
+	req.Notifier.Name = notifier.Name
+	req.Notifier.Version = notifier.Version
+	req.Notifier.URL = notifier.URL
+
+	thrown := &event.Exceptions[0]
+	req.Error.Class = thrown.ErrorClass
+	req.Error.Message = thrown.Message
+	req.Error.Backtrace = make([]models.BacktraceFrame, 0, len(thrown.Stacktrace))
+	for i := range thrown.Stacktrace {
+		req.Error.Backtrace = append(req.Error.Backtrace, convertFrame(&thrown.Stacktrace[i]))
+	}
+
+	context := map[string]interface{}{}
+	for tab, values := range event.MetaData {
+		context[tab] = values
+	}
+	if len(event.Exceptions) > 1 {
+		var causes []map[string]interface{}
+		for _, cause := range event.Exceptions[1:] {
+			causes = append(causes, map[string]interface{}{
+				"class":   cause.ErrorClass,
+				"message": cause.Message,
+			})
+		}
+		context["causes"] = causes
+	}
+	if component, action, ok := strings.Cut(event.Context, "#"); ok && component != "" && action != "" {
+		req.Request.Component = component
+		req.Request.Action = action
+	} else if event.Context != "" {
+		context["context"] = event.Context
+	}
+	if event.Severity != "" {
+		context["severity"] = event.Severity
+	}
+	if event.Unhandled != nil {
+		context["unhandled"] = *event.Unhandled
+	}
+	if event.GroupingHash != "" {
+		context["grouping_hash"] = event.GroupingHash
+	}
+	if u := event.User; u != nil {
+		if id := userID(u.ID); id != "" {
+			context["user_id"] = id
+		}
+		if u.Email != "" {
+			context["user_email"] = u.Email
+		}
+		if u.Name != "" {
+			context["user_name"] = u.Name
+		}
+	}
+	if len(context) > 0 {
+		req.Request.Context = context
+	}
+
+	serverData := map[string]interface{}{}
+	if event.Device != nil {
+		serverData["device"] = event.Device
+		req.Server.Hostname, _ = event.Device["hostname"].(string)
+	}
+	if app := event.App; app != nil {
+		req.Server.EnvironmentName = app.ReleaseStage
+		req.Server.Revision = app.Version
+		if app.ID != "" {
+			serverData["app_id"] = app.ID
+		}
+		if app.Type != "" {
+			serverData["app_type"] = app.Type
+		}
+	}
+	if r := event.Request; r != nil {
+		req.Request.URL = r.URL
+		for key, value := range map[string]string{
+			"request_method": r.HTTPMethod,
+			"client_ip":      r.ClientIP,
+			"referer":        r.Referer,
+		} {
+			if value != "" {
+				serverData[key] = value
+			}
+		}
+		if r.Headers != nil {
+			serverData["headers"] = r.Headers
+		}
+	}
+	if len(serverData) > 0 {
+		req.Server.Data = serverData
+	}
+
+	for i := range event.Breadcrumbs {
+		req.Breadcrumbs.Trail = append(req.Breadcrumbs.Trail, convertBreadcrumb(&event.Breadcrumbs[i]))
+	}
+	req.Breadcrumbs.Enabled = len(req.Breadcrumbs.Trail) > 0
+
+	return req, nil
+}
+
+// convertFrame converts a stack frame. The frame's own line of code, when sent, becomes the
+// frame code.
+func convertFrame(frame *StackFrame) models.BacktraceFrame {
+	converted := models.BacktraceFrame{
+		File:     frame.File,
+		Function: frame.Method,
+	}
+	if line, err := strconv.Atoi(frame.LineNumber.String()); err == nil {
+		converted.Line = &line
+		converted.Code = frame.Code[frame.LineNumber.String()]
+	}
+	if frame.InProject {
+		converted.Context = "app"
+	} else {
+		converted.Context = "all"
+	}
+	return converted
+}
+
+// convertBreadcrumb converts a breadcrumb. The category is the breadcrumb type and the message
+// its name; timestamps that do not parse are left zero.
+func convertBreadcrumb(crumb *Breadcrumb) models.Breadcrumb {
+	converted := models.Breadcrumb{
+		Category: crumb.Type,
+		Message:  crumb.Name,
+		Metadata: crumb.MetaData,
+	}
+	if t, err := time.Parse(time.RFC3339Nano, crumb.Timestamp); err == nil {
+		converted.Time = t
+	}
+	return converted
+}
+
+// userID returns a user ID sent as a string or a number
+func userID(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return string(raw)
+}
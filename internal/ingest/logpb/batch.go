@@ -0,0 +1,300 @@
+// Package logpb encodes and decodes the protobuf log batch format described in log_batch.proto
+package logpb
+
+import (
+	"errors"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ContentType is the media type of protobuf log batches
+const ContentType = "application/x-protobuf"
+
+// errTruncated is returned for protobuf input that ends inside a field
+var errTruncated = errors.New("truncated protobuf message")
+
+// maxNesting bounds nested list and map values so hostile payloads cannot exhaust the stack
+const maxNesting = 32
+
+// DecodeBatch decodes a LogBatch, calling fn with each entry in order. The entry is not reused,
+// so fn may retain it. Fields this service does not know are skipped. It returns the number of
+// entries decoded.
+func DecodeBatch(data []byte, fn func(i int, entry *models.LogEntry)) (int, error) {
+	count := 0
+	err := walk(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if num != 1 || typ != protowire.BytesType {
+			return nil
+		}
+		entry, err := decodeEntry(value)
+		if err != nil {
+			return fmt.Errorf("log entry %d: %w", count, err)
+		}
+		fn(count, entry)
+		count++
+		return nil
+	})
+	if err != nil {
+		return count, fmt.Errorf("invalid protobuf log batch: %w", err)
+	}
+	return count, nil
+}
+
+// EncodeBatch encodes entries as a LogBatch. Metadata values must be of the types produced by
+// decoding JSON: nil, string, bool, numbers, []interface{} and map[string]interface{}.
+func EncodeBatch(entries []models.LogEntry) ([]byte, error) {
+	var out []byte
+	for i := range entries {
+		entry, err := appendEntry(nil, &entries[i])
+		if err != nil {
+			return nil, fmt.Errorf("log entry %d: %w", i, err)
+		}
+		out = protowire.AppendTag(out, 1, protowire.BytesType)
+		out = protowire.AppendBytes(out, entry)
+	}
+	return out, nil
+}
+
+func decodeEntry(data []byte) (*models.LogEntry, error) {
+	entry := &models.LogEntry{}
+	err := walk(data, func(num protowire.Number, typ protowire.Type, value []byte, n uint64) error {
+		switch {
+		case num == 1 && typ == protowire.Fixed64Type:
+			if n != 0 {
+				entry.Timestamp = time.Unix(0, int64(n)).UTC()
+			}
+		case num == 2 && typ == protowire.BytesType:
+			entry.Service = string(value)
+		case num == 3 && typ == protowire.BytesType:
+			entry.Level = string(value)
+		case num == 4 && typ == protowire.BytesType:
+			entry.Message = string(value)
+		case num == 5 && typ == protowire.BytesType:
+			if entry.Metadata == nil {
+				entry.Metadata = make(map[string]interface{})
+			}
+			return decodeMapEntry(value, entry.Metadata, 0)
+		}
+		return nil
+	})
+	return entry, err
+}
+
+// decodeMapEntry decodes one entry of a map<string, Value> into fields
+func decodeMapEntry(data []byte, fields map[string]interface{}, depth int) error {
+	var key string
+	var value interface{}
+	err := walk(data, func(num protowire.Number, typ protowire.Type, raw []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			key = string(raw)
+		case 2:
+			v, err := decodeValue(raw, depth)
+			if err != nil {
+				return err
+			}
+			value = v
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fields[key] = value
+	return nil
+}
+
+func decodeValue(data []byte, depth int) (interface{}, error) {
+	if depth > maxNesting {
+		return nil, fmt.Errorf("values nested deeper than %d levels", maxNesting)
+	}
+
+	var v interface{}
+	err := walk(data, func(num protowire.Number, typ protowire.Type, value []byte, n uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v = string(value)
+		case num == 2 && typ == protowire.VarintType:
+			v = n != 0
+		case num == 3 && typ == protowire.VarintType:
+			v = int64(n)
+		case num == 4 && typ == protowire.Fixed64Type:
+			f := math.Float64frombits(n)
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				// JSON has no representation for these, and metadata is stored as JSON
+				v = strconv.FormatFloat(f, 'g', -1, 64)
+			} else {
+				v = f
+			}
+		case num == 5 && typ == protowire.BytesType:
+			values := []interface{}{}
+			err := walk(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					item, err := decodeValue(value, depth+1)
+					if err != nil {
+						return err
+					}
+					values = append(values, item)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			v = values
+		case num == 6 && typ == protowire.BytesType:
+			fields := map[string]interface{}{}
+			err := walk(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					return decodeMapEntry(value, fields, depth+1)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			v = fields
+		}
+		return nil
+	})
+	return v, err
+}
+
+// walk calls fn for each field of a message. value holds the payload of length-delimited
+// fields and n the value of varint and fixed-width fields.
+func walk(data []byte, fn func(num protowire.Number, typ protowire.Type, value []byte, n uint64) error) error {
+	for len(data) > 0 {
+		num, typ, tagLen := protowire.ConsumeTag(data)
+		if tagLen < 0 {
+			return errTruncated
+		}
+		data = data[tagLen:]
+
+		var value []byte
+		var n uint64
+		var fieldLen int
+		switch typ {
+		case protowire.VarintType:
+			n, fieldLen = protowire.ConsumeVarint(data)
+		case protowire.Fixed64Type:
+			n, fieldLen = protowire.ConsumeFixed64(data)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, fieldLen = protowire.ConsumeFixed32(data)
+			n = uint64(v)
+		case protowire.BytesType:
+			value, fieldLen = protowire.ConsumeBytes(data)
+		default:
+			fieldLen = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if fieldLen < 0 {
+			return errTruncated
+		}
+		data = data[fieldLen:]
+
+		if err := fn(num, typ, value, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func appendEntry(out []byte, entry *models.LogEntry) ([]byte, error) {
+	if !entry.Timestamp.IsZero() {
+		out = protowire.AppendTag(out, 1, protowire.Fixed64Type)
+		out = protowire.AppendFixed64(out, uint64(entry.Timestamp.UnixNano()))
+	}
+	out = appendString(out, 2, entry.Service)
+	out = appendString(out, 3, entry.Level)
+	out = appendString(out, 4, entry.Message)
+	return appendMap(out, 5, entry.Metadata, 0)
+}
+
+func appendString(out []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return out
+	}
+	out = protowire.AppendTag(out, num, protowire.BytesType)
+	return protowire.AppendString(out, s)
+}
+
+// appendMap appends fields as map<string, Value> field num, in key order so output is stable
+func appendMap(out []byte, num protowire.Number, fields map[string]interface{}, depth int) ([]byte, error) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value, err := appendValue(nil, fields[key], depth)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		var entry []byte
+		entry = appendString(entry, 1, key)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendBytes(entry, value)
+
+		out = protowire.AppendTag(out, num, protowire.BytesType)
+		out = protowire.AppendBytes(out, entry)
+	}
+	return out, nil
+}
+
+func appendValue(out []byte, value interface{}, depth int) ([]byte, error) {
+	if depth > maxNesting {
+		return nil, fmt.Errorf("values nested deeper than %d levels", maxNesting)
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return out, nil
+	case string:
+		out = protowire.AppendTag(out, 1, protowire.BytesType)
+		return protowire.AppendString(out, v), nil
+	case bool:
+		out = protowire.AppendTag(out, 2, protowire.VarintType)
+		return protowire.AppendVarint(out, protowire.EncodeBool(v)), nil
+	case int:
+		return appendInt(out, int64(v)), nil
+	case int64:
+		return appendInt(out, v), nil
+	case float64:
+		out = protowire.AppendTag(out, 4, protowire.Fixed64Type)
+		return protowire.AppendFixed64(out, math.Float64bits(v)), nil
+	case []interface{}:
+		var list []byte
+		for _, item := range v {
+			encoded, err := appendValue(nil, item, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			list = protowire.AppendTag(list, 1, protowire.BytesType)
+			list = protowire.AppendBytes(list, encoded)
+		}
+		out = protowire.AppendTag(out, 5, protowire.BytesType)
+		return protowire.AppendBytes(out, list), nil
+	case map[string]interface{}:
+		fields, err := appendMap(nil, 1, v, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		out = protowire.AppendTag(out, 6, protowire.BytesType)
+		return protowire.AppendBytes(out, fields), nil
+	}
+	return nil, fmt.Errorf("unsupported metadata value type %T", value)
+}
+
+func appendInt(out []byte, v int64) []byte {
+	out = protowire.AppendTag(out, 3, protowire.VarintType)
+	return protowire.AppendVarint(out, uint64(v))
+}
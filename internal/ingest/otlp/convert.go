@@ -0,0 +1,143 @@
+package otlp
+
+import (
+	"encoding/json"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/pkg/models"
+	"strings"
+	"time"
+)
+
+// serviceNameAttribute is the resource attribute naming the service (semantic conventions)
+const serviceNameAttribute = "service.name"
+
+// severityLevels maps OTLP severity number ranges (TRACE, DEBUG, INFO, WARN, ERROR, FATAL,
+// four numbers each) to levels
+var severityLevels = [6]string{"DEBUG", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"}
+
+// severityTexts maps common severity texts to levels, for records without a severity number
+var severityTexts = map[string]string{
+	"TRACE":       "DEBUG",
+	"DEBUG":       "DEBUG",
+	"INFO":        "INFO",
+	"INFORMATION": "INFO",
+	"NOTICE":      "INFO",
+	"WARN":        "WARN",
+	"WARNING":     "WARN",
+	"ERROR":       "ERROR",
+	"ERR":         "ERROR",
+	"CRITICAL":    "CRITICAL",
+	"FATAL":       "FATAL",
+	"EMERGENCY":   "FATAL",
+}
+
+// Convert turns every record of a request into a log entry. The service comes from the
+// service.name resource attribute; record attributes become metadata, with the remaining
+// resource attributes under "resource" and the instrumentation scope under "scope".
+// Records without a timestamp get received.
+func Convert(req *ExportLogsServiceRequest, received time.Time) []models.LogEntry {
+	var entries []models.LogEntry
+	for _, rl := range req.ResourceLogs {
+		resource := attributeMap(rl.Resource.Attributes)
+		service, _ := resource[serviceNameAttribute].(string)
+		if service == "" {
+			service = parser.UnknownService
+		}
+
+		for _, sl := range rl.ScopeLogs {
+			for _, record := range sl.LogRecords {
+				entries = append(entries, convertRecord(record, service, resource, sl.Scope, received))
+			}
+		}
+	}
+	return entries
+}
+
+// Count returns the number of records in a request
+func Count(req *ExportLogsServiceRequest) int {
+	n := 0
+	for _, rl := range req.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			n += len(sl.LogRecords)
+		}
+	}
+	return n
+}
+
+func convertRecord(record LogRecord, service string, resource map[string]interface{}, scope Scope, received time.Time) models.LogEntry {
+	metadata := attributeMap(record.Attributes)
+	if len(resource) > 0 {
+		metadata["resource"] = resource
+	}
+	if scope.Name != "" {
+		scopeMeta := map[string]interface{}{"name": scope.Name}
+		if scope.Version != "" {
+			scopeMeta["version"] = scope.Version
+		}
+		if len(scope.Attributes) > 0 {
+			scopeMeta["attributes"] = attributeMap(scope.Attributes)
+		}
+		metadata["scope"] = scopeMeta
+	}
+	if record.TraceID != "" {
+		metadata["trace_id"] = record.TraceID
+	}
+	if record.SpanID != "" {
+		metadata["span_id"] = record.SpanID
+	}
+	if record.SeverityText != "" {
+		metadata["severity_text"] = record.SeverityText
+	}
+	if record.SeverityNumber != 0 {
+		metadata["severity_number"] = record.SeverityNumber
+	}
+	if record.EventName != "" {
+		metadata["event_name"] = record.EventName
+	}
+
+	timestamp := received
+	if record.TimeUnixNano != 0 {
+		timestamp = time.Unix(0, int64(record.TimeUnixNano)).UTC()
+	} else if record.ObservedTimeUnixNano != 0 {
+		timestamp = time.Unix(0, int64(record.ObservedTimeUnixNano)).UTC()
+	}
+
+	return models.LogEntry{
+		Timestamp: timestamp,
+		Level:     level(record.SeverityNumber, record.SeverityText),
+		Message:   message(record, metadata),
+		Service:   service,
+		Metadata:  metadata,
+	}
+}
+
+// level picks the level from the severity number, falling back to the severity text and then INFO
+func level(number int32, text string) string {
+	if number >= 1 && number <= 24 {
+		return severityLevels[(number-1)/4]
+	}
+	if level, ok := severityTexts[strings.ToUpper(strings.TrimSpace(text))]; ok {
+		return level
+	}
+	return "INFO"
+}
+
+// message renders the record body. Structured bodies are kept in metadata as "body" and stored as
+// JSON text; records without a body use their event name.
+func message(record LogRecord, metadata map[string]interface{}) string {
+	switch body := record.Body.Value.(type) {
+	case nil:
+		return record.EventName
+	case string:
+		return body
+	case []byte:
+		return string(body)
+	case map[string]interface{}, []interface{}:
+		metadata["body"] = body
+	}
+	data, err := json.Marshal(record.Body.Value)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -0,0 +1,68 @@
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Paths of the OTLP logs export on the standard OTLP/HTTP and OTLP/gRPC receivers
+const (
+	HTTPPath = "/v1/logs"
+	GRPCPath = "/opentelemetry.proto.collector.logs.v1.LogsService/Export"
+)
+
+// gRPC status codes used by the export service
+const (
+	GRPCOK              = 0
+	GRPCInvalidArgument = 3
+	GRPCUnavailable     = 14
+)
+
+// ReadGRPCMessage reads the single length-prefixed message of a unary gRPC request. Compressed
+// messages are accepted when encoding is gzip. Messages over max bytes are rejected, before and
+// after decompression.
+func ReadGRPCMessage(r io.Reader, encoding string, max int) ([]byte, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		return nil, fmt.Errorf("error reading gRPC message prefix: %w", err)
+	}
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if int64(length) > int64(max) {
+		return nil, fmt.Errorf("gRPC message of %d bytes exceeds %d", length, max)
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, fmt.Errorf("error reading gRPC message: %w", err)
+	}
+
+	if prefix[0] == 0 {
+		return msg, nil
+	}
+	if encoding != "gzip" {
+		return nil, fmt.Errorf("unsupported gRPC message encoding %q", encoding)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(msg))
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing gRPC message: %w", err)
+	}
+	defer zr.Close()
+	msg, err = io.ReadAll(io.LimitReader(zr, int64(max)+1))
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing gRPC message: %w", err)
+	}
+	if len(msg) > max {
+		return nil, fmt.Errorf("decompressed gRPC message exceeds %d bytes", max)
+	}
+	return msg, nil
+}
+
+// FrameGRPCMessage prefixes an uncompressed message for a gRPC response body
+func FrameGRPCMessage(msg []byte) []byte {
+	framed := make([]byte, 5+len(msg))
+	binary.BigEndian.PutUint32(framed[1:5], uint32(len(msg)))
+	copy(framed[5:], msg)
+	return framed
+}
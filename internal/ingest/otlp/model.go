@@ -0,0 +1,159 @@
+// Package otlp decodes OpenTelemetry logs export requests, in the protobuf and JSON encodings of
+// OTLP, and converts their records into log entries so an OpenTelemetry Collector can export to
+// the service directly.
+package otlp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// ExportLogsServiceRequest is the payload of OTLP logs exports
+type ExportLogsServiceRequest struct {
+	ResourceLogs []ResourceLogs `json:"resourceLogs"`
+}
+
+// ResourceLogs holds the records of one resource, such as a service instance
+type ResourceLogs struct {
+	Resource  Resource    `json:"resource"`
+	ScopeLogs []ScopeLogs `json:"scopeLogs"`
+}
+
+// Resource describes the entity producing logs
+type Resource struct {
+	Attributes []KeyValue `json:"attributes"`
+}
+
+// ScopeLogs holds the records emitted by one instrumentation scope
+type ScopeLogs struct {
+	Scope      Scope       `json:"scope"`
+	LogRecords []LogRecord `json:"logRecords"`
+}
+
+// Scope identifies the instrumentation library that emitted records
+type Scope struct {
+	Name       string     `json:"name"`
+	Version    string     `json:"version"`
+	Attributes []KeyValue `json:"attributes"`
+}
+
+// LogRecord is a single log record
+type LogRecord struct {
+	TimeUnixNano         Uint64     `json:"timeUnixNano"`
+	ObservedTimeUnixNano Uint64     `json:"observedTimeUnixNano"`
+	SeverityNumber       int32      `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 AnyValue   `json:"body"`
+	Attributes           []KeyValue `json:"attributes"`
+	Flags                uint32     `json:"flags"`
+	// TraceID and SpanID are hex encoded, as in OTLP/JSON
+	TraceID   string `json:"traceId"`
+	SpanID    string `json:"spanId"`
+	EventName string `json:"eventName"`
+}
+
+// KeyValue is an attribute
+type KeyValue struct {
+	Key   string   `json:"key"`
+	Value AnyValue `json:"value"`
+}
+
+// AnyValue holds an attribute or body value as a string, bool, int64, float64, []byte,
+// []interface{} or map[string]interface{}, or nil when unset
+type AnyValue struct {
+	Value interface{}
+}
+
+// Uint64 is a 64-bit integer, which OTLP/JSON encodes as a decimal string or a number
+type Uint64 uint64
+
+// UnmarshalJSON accepts a string or a number
+func (u *Uint64) UnmarshalJSON(data []byte) error {
+	s := string(bytes.Trim(data, `"`))
+	if s == "" || s == "null" {
+		*u = 0
+		return nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid 64-bit integer %s", data)
+	}
+	*u = Uint64(v)
+	return nil
+}
+
+// anyValueJSON has one field set, per the AnyValue oneof
+type anyValueJSON struct {
+	StringValue *string         `json:"stringValue"`
+	BoolValue   *bool           `json:"boolValue"`
+	IntValue    json.RawMessage `json:"intValue"`
+	DoubleValue *float64        `json:"doubleValue"`
+	ArrayValue  *struct {
+		Values []AnyValue `json:"values"`
+	} `json:"arrayValue"`
+	KvlistValue *struct {
+		Values []KeyValue `json:"values"`
+	} `json:"kvlistValue"`
+	BytesValue *string `json:"bytesValue"`
+}
+
+// UnmarshalJSON decodes the OTLP/JSON form of AnyValue
+func (v *AnyValue) UnmarshalJSON(data []byte) error {
+	var raw anyValueJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	switch {
+	case raw.StringValue != nil:
+		v.Value = *raw.StringValue
+	case raw.BoolValue != nil:
+		v.Value = *raw.BoolValue
+	case raw.IntValue != nil:
+		n, err := strconv.ParseInt(string(bytes.Trim(raw.IntValue, `"`)), 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid intValue %s", raw.IntValue)
+		}
+		v.Value = n
+	case raw.DoubleValue != nil:
+		v.Value = *raw.DoubleValue
+	case raw.ArrayValue != nil:
+		values := make([]interface{}, len(raw.ArrayValue.Values))
+		for i, item := range raw.ArrayValue.Values {
+			values[i] = item.Value
+		}
+		v.Value = values
+	case raw.KvlistValue != nil:
+		v.Value = attributeMap(raw.KvlistValue.Values)
+	case raw.BytesValue != nil:
+		b, err := base64.StdEncoding.DecodeString(*raw.BytesValue)
+		if err != nil {
+			return fmt.Errorf("invalid bytesValue: %w", err)
+		}
+		v.Value = b
+	default:
+		v.Value = nil
+	}
+	return nil
+}
+
+// DecodeJSON decodes an OTLP/JSON export request
+func DecodeJSON(data []byte) (*ExportLogsServiceRequest, error) {
+	var req ExportLogsServiceRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, fmt.Errorf("invalid OTLP JSON: %w", err)
+	}
+	return &req, nil
+}
+
+// attributeMap converts attributes to a map, later keys replacing earlier duplicates
+func attributeMap(attrs []KeyValue) map[string]interface{} {
+	m := make(map[string]interface{}, len(attrs))
+	for _, kv := range attrs {
+		m[kv.Key] = kv.Value.Value
+	}
+	return m
+}
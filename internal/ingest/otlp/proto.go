@@ -0,0 +1,283 @@
+package otlp
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// errTruncated is returned for protobuf input that ends inside a field
+var errTruncated = errors.New("truncated protobuf message")
+
+// maxNesting bounds nested array and kvlist values so hostile payloads cannot exhaust the stack
+const maxNesting = 32
+
+// DecodeProto decodes an OTLP/protobuf export request. Fields this service does not use are skipped.
+func DecodeProto(data []byte) (*ExportLogsServiceRequest, error) {
+	var req ExportLogsServiceRequest
+	err := walk(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if num == 1 && typ == protowire.BytesType {
+			rl, err := decodeResourceLogs(value)
+			if err != nil {
+				return err
+			}
+			req.ResourceLogs = append(req.ResourceLogs, rl)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP protobuf: %w", err)
+	}
+	return &req, nil
+}
+
+// EncodeResponse encodes an ExportLogsServiceResponse. A partial success is only included when
+// records were rejected or there is a message, as the specification requires.
+func EncodeResponse(rejected int64, message string) []byte {
+	if rejected == 0 && message == "" {
+		return []byte{}
+	}
+	var partial []byte
+	if rejected != 0 {
+		partial = protowire.AppendTag(partial, 1, protowire.VarintType)
+		partial = protowire.AppendVarint(partial, uint64(rejected))
+	}
+	if message != "" {
+		partial = protowire.AppendTag(partial, 2, protowire.BytesType)
+		partial = protowire.AppendString(partial, message)
+	}
+	out := protowire.AppendTag(nil, 1, protowire.BytesType)
+	return protowire.AppendBytes(out, partial)
+}
+
+// walk calls fn for each field of a message. value holds the payload of length-delimited
+// fields and n the value of varint and fixed-width fields.
+func walk(data []byte, fn func(num protowire.Number, typ protowire.Type, value []byte, n uint64) error) error {
+	for len(data) > 0 {
+		num, typ, tagLen := protowire.ConsumeTag(data)
+		if tagLen < 0 {
+			return errTruncated
+		}
+		data = data[tagLen:]
+
+		var value []byte
+		var n uint64
+		var fieldLen int
+		switch typ {
+		case protowire.VarintType:
+			n, fieldLen = protowire.ConsumeVarint(data)
+		case protowire.Fixed64Type:
+			n, fieldLen = protowire.ConsumeFixed64(data)
+		case protowire.Fixed32Type:
+			var v uint32
+			v, fieldLen = protowire.ConsumeFixed32(data)
+			n = uint64(v)
+		case protowire.BytesType:
+			value, fieldLen = protowire.ConsumeBytes(data)
+		default:
+			fieldLen = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if fieldLen < 0 {
+			return errTruncated
+		}
+		data = data[fieldLen:]
+
+		if err := fn(num, typ, value, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeResourceLogs(data []byte) (ResourceLogs, error) {
+	var rl ResourceLogs
+	err := walk(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			return walk(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					kv, err := decodeKeyValue(value, 0)
+					if err != nil {
+						return err
+					}
+					rl.Resource.Attributes = append(rl.Resource.Attributes, kv)
+				}
+				return nil
+			})
+		case 2:
+			sl, err := decodeScopeLogs(value)
+			if err != nil {
+				return err
+			}
+			rl.ScopeLogs = append(rl.ScopeLogs, sl)
+		}
+		return nil
+	})
+	return rl, err
+}
+
+func decodeScopeLogs(data []byte) (ScopeLogs, error) {
+	var sl ScopeLogs
+	err := walk(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			return walk(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				if typ != protowire.BytesType {
+					return nil
+				}
+				switch num {
+				case 1:
+					sl.Scope.Name = string(value)
+				case 2:
+					sl.Scope.Version = string(value)
+				case 3:
+					kv, err := decodeKeyValue(value, 0)
+					if err != nil {
+						return err
+					}
+					sl.Scope.Attributes = append(sl.Scope.Attributes, kv)
+				}
+				return nil
+			})
+		case 2:
+			record, err := decodeLogRecord(value)
+			if err != nil {
+				return err
+			}
+			sl.LogRecords = append(sl.LogRecords, record)
+		}
+		return nil
+	})
+	return sl, err
+}
+
+func decodeLogRecord(data []byte) (LogRecord, error) {
+	var r LogRecord
+	err := walk(data, func(num protowire.Number, typ protowire.Type, value []byte, n uint64) error {
+		switch {
+		case num == 1 && typ == protowire.Fixed64Type:
+			r.TimeUnixNano = Uint64(n)
+		case num == 11 && typ == protowire.Fixed64Type:
+			r.ObservedTimeUnixNano = Uint64(n)
+		case num == 2 && typ == protowire.VarintType:
+			r.SeverityNumber = int32(n)
+		case num == 3 && typ == protowire.BytesType:
+			r.SeverityText = string(value)
+		case num == 5 && typ == protowire.BytesType:
+			body, err := decodeAnyValue(value, 0)
+			if err != nil {
+				return err
+			}
+			r.Body = body
+		case num == 6 && typ == protowire.BytesType:
+			kv, err := decodeKeyValue(value, 0)
+			if err != nil {
+				return err
+			}
+			r.Attributes = append(r.Attributes, kv)
+		case num == 8 && typ == protowire.Fixed32Type:
+			r.Flags = uint32(n)
+		case num == 9 && typ == protowire.BytesType:
+			r.TraceID = hex.EncodeToString(value)
+		case num == 10 && typ == protowire.BytesType:
+			r.SpanID = hex.EncodeToString(value)
+		case num == 12 && typ == protowire.BytesType:
+			r.EventName = string(value)
+		}
+		return nil
+	})
+	return r, err
+}
+
+func decodeKeyValue(data []byte, depth int) (KeyValue, error) {
+	var kv KeyValue
+	err := walk(data, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1:
+			kv.Key = string(value)
+		case 2:
+			v, err := decodeAnyValue(value, depth)
+			if err != nil {
+				return err
+			}
+			kv.Value = v
+		}
+		return nil
+	})
+	return kv, err
+}
+
+func decodeAnyValue(data []byte, depth int) (AnyValue, error) {
+	if depth > maxNesting {
+		return AnyValue{}, fmt.Errorf("values nested deeper than %d levels", maxNesting)
+	}
+
+	var v AnyValue
+	err := walk(data, func(num protowire.Number, typ protowire.Type, value []byte, n uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v.Value = string(value)
+		case num == 2 && typ == protowire.VarintType:
+			v.Value = n != 0
+		case num == 3 && typ == protowire.VarintType:
+			v.Value = int64(n)
+		case num == 4 && typ == protowire.Fixed64Type:
+			f := math.Float64frombits(n)
+			if math.IsNaN(f) || math.IsInf(f, 0) {
+				// JSON has no representation for these, and metadata is stored as JSON
+				v.Value = strconv.FormatFloat(f, 'g', -1, 64)
+			} else {
+				v.Value = f
+			}
+		case num == 5 && typ == protowire.BytesType:
+			values := []interface{}{}
+			err := walk(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					item, err := decodeAnyValue(value, depth+1)
+					if err != nil {
+						return err
+					}
+					values = append(values, item.Value)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			v.Value = values
+		case num == 6 && typ == protowire.BytesType:
+			var attrs []KeyValue
+			err := walk(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					kv, err := decodeKeyValue(value, depth+1)
+					if err != nil {
+						return err
+					}
+					attrs = append(attrs, kv)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			v.Value = attributeMap(attrs)
+		case num == 7 && typ == protowire.BytesType:
+			v.Value = append([]byte(nil), value...)
+		}
+		return nil
+	})
+	return v, err
+}
@@ -0,0 +1,357 @@
+// Package rollbar converts Rollbar item payloads into notices, so Rollbar SDKs can report
+// errors and messages to the service unchanged.
+package rollbar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"strings"
+	"time"
+)
+
+// AccessTokenHeader carries the project access token sent by Rollbar SDKs
+const AccessTokenHeader = "X-Rollbar-Access-Token"
+
+// MessageClass is the error class of message items, which have no exception
+const MessageClass = "Message"
+
+// maxMessageKeyLength bounds the message text used to group message items
+const maxMessageKeyLength = 100
+
+// Item is the payload of POST /api/1/item/
+type Item struct {
+	AccessToken string `json:"access_token"`
+	Data        Data   `json:"data"`
+}
+
+// Data is the occurrence reported by an item
+type Data struct {
+	Environment string                 `json:"environment"`
+	Body        Body                   `json:"body"`
+	Level       string                 `json:"level"`
+	Timestamp   int64                  `json:"timestamp"`
+	CodeVersion string                 `json:"code_version"`
+	Platform    string                 `json:"platform"`
+	Language    string                 `json:"language"`
+	Framework   string                 `json:"framework"`
+	Context     string                 `json:"context"`
+	Request     *Request               `json:"request"`
+	Person      *Person                `json:"person"`
+	Server      *Server                `json:"server"`
+	Client      map[string]interface{} `json:"client"`
+	Custom      map[string]interface{} `json:"custom"`
+	Fingerprint string                 `json:"fingerprint"`
+	Title       string                 `json:"title"`
+	UUID        string                 `json:"uuid"`
+	Notifier    struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	} `json:"notifier"`
+}
+
+// Body holds exactly one of a trace, a trace chain or a message, with optional telemetry
+type Body struct {
+	Trace      *Trace      `json:"trace"`
+	TraceChain []Trace     `json:"trace_chain"`
+	Message    *Message    `json:"message"`
+	Telemetry  []Telemetry `json:"telemetry"`
+}
+
+// Trace is an exception with its stack, oldest frame first
+type Trace struct {
+	Frames    []Frame   `json:"frames"`
+	Exception Exception `json:"exception"`
+}
+
+// Frame is one stack frame of a trace
+type Frame struct {
+	Filename string                 `json:"filename"`
+	Lineno   *int                   `json:"lineno"`
+	Colno    *int                   `json:"colno"`
+	Method   string                 `json:"method"`
+	Code     string                 `json:"code"`
+	Locals   map[string]interface{} `json:"locals"`
+}
+
+// Exception describes the error of a trace
+type Exception struct {
+	Class       string `json:"class"`
+	Message     string `json:"message"`
+	Description string `json:"description"`
+}
+
+// Message is a message item's text; any other keys are extra data
+type Message struct {
+	Body  string
+	Extra map[string]interface{}
+}
+
+// UnmarshalJSON splits a message's body from its extra data
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	m.Body, _ = fields["body"].(string)
+	delete(fields, "body")
+	if len(fields) > 0 {
+		m.Extra = fields
+	}
+	return nil
+}
+
+// Telemetry is an event leading up to an item, such as a log line or network request
+type Telemetry struct {
+	Level       string                 `json:"level"`
+	Type        string                 `json:"type"`
+	Source      string                 `json:"source"`
+	TimestampMs int64                  `json:"timestamp_ms"`
+	Body        map[string]interface{} `json:"body"`
+}
+
+// Request describes the HTTP request being served
+type Request struct {
+	URL         string                 `json:"url"`
+	Method      string                 `json:"method"`
+	Headers     map[string]interface{} `json:"headers"`
+	Params      map[string]interface{} `json:"params"`
+	GET         map[string]interface{} `json:"GET"`
+	POST        map[string]interface{} `json:"POST"`
+	QueryString string                 `json:"query_string"`
+	UserIP      string                 `json:"user_ip"`
+}
+
+// Person identifies the affected user
+type Person struct {
+	ID       json.RawMessage `json:"id"`
+	Username string          `json:"username"`
+	Email    string          `json:"email"`
+}
+
+// Server describes the host that reported the item
+type Server struct {
+	Host        string `json:"host"`
+	Root        string `json:"root"`
+	Branch      string `json:"branch"`
+	CodeVersion string `json:"code_version"`
+}
+
+// Convert turns an item into a notice request for the fault grouper. Traces become the error
+// and backtrace, most recent frame first; in a trace chain the first trace is the error and the
+// others are recorded as its causes. Message items have the error class "Message" and are
+// grouped by their context or, without one, by their text. A context of the form
+// "controller#action" sets the request component and action.
+func Convert(item *Item) (*models.NoticeRequest, error) {
+	data := &item.Data
+	req := &models.NoticeRequest{}
+	req.Notifier.Name = data.Notifier.Name
+	req.Notifier.Version = data.Notifier.Version
+
+	context := map[string]interface{}{}
+	switch {
+	case data.Body.Trace != nil:
+		setTrace(req, data.Body.Trace)
+	case len(data.Body.TraceChain) > 0:
+		setTrace(req, &data.Body.TraceChain[0])
+		var causes []map[string]interface{}
+		for _, cause := range data.Body.TraceChain[1:] {
+			causes = append(causes, map[string]interface{}{
+				"class":   cause.Exception.Class,
+				"message": exceptionMessage(&cause.Exception),
+			})
+		}
+		if len(causes) > 0 {
+			context["causes"] = causes
+		}
+	case data.Body.Message != nil:
+		req.Error.Class = MessageClass
+		req.Error.Message = data.Body.Message.Body
+		if data.Body.Message.Extra != nil {
+			context["message_data"] = data.Body.Message.Extra
+		}
+	default:
+		return nil, errors.New("data.body must contain a trace, trace_chain or message")
+	}
+	if data.Title != "" && req.Error.Message == "" {
+		req.Error.Message = data.Title
+	}
+
+	if component, action, ok := strings.Cut(data.Context, "#"); ok && component != "" && action != "" {
+		req.Request.Component = component
+		req.Request.Action = action
+	} else if data.Context != "" {
+		context["context"] = data.Context
+	}
+	if req.Error.Class == MessageClass && req.Request.Component == "" && data.Context == "" {
+		req.Request.Component = "message"
+		req.Request.Action = messageKey(req.Error.Message)
+	}
+
+	if data.Level != "" {
+		context["level"] = data.Level
+	}
+	if data.Fingerprint != "" {
+		context["fingerprint"] = data.Fingerprint
+	}
+	if data.UUID != "" {
+		context["uuid"] = data.UUID
+	}
+	if data.Custom != nil {
+		context["custom"] = data.Custom
+	}
+	if p := data.Person; p != nil {
+		if id := personID(p.ID); id != "" {
+			context["user_id"] = id
+		}
+		if p.Email != "" {
+			context["user_email"] = p.Email
+		}
+		if p.Username != "" {
+			context["username"] = p.Username
+		}
+	}
+	if len(context) > 0 {
+		req.Request.Context = context
+	}
+
+	serverData := map[string]interface{}{}
+	for key, value := range map[string]string{
+		"platform":  data.Platform,
+		"language":  data.Language,
+		"framework": data.Framework,
+	} {
+		if value != "" {
+			serverData[key] = value
+		}
+	}
+	if data.Client != nil {
+		serverData["client"] = data.Client
+	}
+	if r := data.Request; r != nil {
+		req.Request.URL = r.URL
+		req.Request.Params = mergeParams(r.GET, r.POST, r.Params)
+		if r.Method != "" {
+			serverData["request_method"] = r.Method
+		}
+		if r.Headers != nil {
+			serverData["headers"] = r.Headers
+		}
+		if r.QueryString != "" {
+			serverData["query_string"] = r.QueryString
+		}
+		if r.UserIP != "" {
+			serverData["user_ip"] = r.UserIP
+		}
+	}
+	if len(serverData) > 0 {
+		req.Server.Data = serverData
+	}
+
+	req.Server.EnvironmentName = data.Environment
+	req.Server.Revision = data.CodeVersion
+	if s := data.Server; s != nil {
+		req.Server.Hostname = s.Host
+		req.Server.ProjectRoot = s.Root
+		if req.Server.Revision == "" {
+			req.Server.Revision = s.CodeVersion
+		}
+	}
+
+	for _, event := range data.Body.Telemetry {
+		req.Breadcrumbs.Trail = append(req.Breadcrumbs.Trail, breadcrumb(&event))
+	}
+	req.Breadcrumbs.Enabled = len(req.Breadcrumbs.Trail) > 0
+
+	return req, nil
+}
+
+// setTrace sets a notice's error from a trace. Rollbar lists frames oldest first; notices list
+// the most recent frame first.
+func setTrace(req *models.NoticeRequest, trace *Trace) {
+	req.Error.Class = trace.Exception.Class
+	req.Error.Message = exceptionMessage(&trace.Exception)
+	req.Error.Backtrace = make([]models.BacktraceFrame, 0, len(trace.Frames))
+	for i := len(trace.Frames) - 1; i >= 0; i-- {
+		frame := &trace.Frames[i]
+		req.Error.Backtrace = append(req.Error.Backtrace, models.BacktraceFrame{
+			File:     frame.Filename,
+			Line:     frame.Lineno,
+			Function: frame.Method,
+			Code:     frame.Code,
+			Vars:     frame.Locals,
+		})
+	}
+}
+
+// exceptionMessage returns an exception's message, falling back to its description
+func exceptionMessage(e *Exception) string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return e.Description
+}
+
+// messageKey returns the first line of a message, truncated, to group message items by
+func messageKey(message string) string {
+	key, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	if len(key) > maxMessageKeyLength {
+		key = strings.ToValidUTF8(key[:maxMessageKeyLength], "")
+	}
+	if key == "" {
+		return "(empty)"
+	}
+	return key
+}
+
+// personID returns a person ID sent as a string or a number
+func personID(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// mergeParams merges query, form and route parameters, later ones winning
+func mergeParams(sets ...map[string]interface{}) map[string]interface{} {
+	var params map[string]interface{}
+	for _, set := range sets {
+		for key, value := range set {
+			if params == nil {
+				params = make(map[string]interface{})
+			}
+			params[key] = value
+		}
+	}
+	return params
+}
+
+// breadcrumb converts a telemetry event. The category is the event type, and the message is
+// the body's message or, for network events, its method and URL.
+func breadcrumb(event *Telemetry) models.Breadcrumb {
+	crumb := models.Breadcrumb{
+		Category: event.Type,
+		Metadata: event.Body,
+		Time:     time.UnixMilli(event.TimestampMs).UTC(),
+	}
+	if message, ok := event.Body["message"].(string); ok {
+		crumb.Message = message
+	} else if url, ok := event.Body["url"].(string); ok {
+		method, _ := event.Body["method"].(string)
+		crumb.Message = strings.TrimSpace(fmt.Sprintf("%s %s", method, url))
+	} else if subtype, ok := event.Body["subtype"].(string); ok {
+		crumb.Message = subtype
+	}
+	if event.Level != "" {
+		if crumb.Metadata == nil {
+			crumb.Metadata = make(map[string]interface{})
+		}
+		crumb.Metadata["level"] = event.Level
+	}
+	return crumb
+}
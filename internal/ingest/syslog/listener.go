@@ -0,0 +1,374 @@
+// Package syslog receives RFC 5424 and RFC 3164 syslog messages over UDP and TCP and feeds them
+// through the validator into the batcher, so hosts that can only emit syslog need no relay.
+package syslog
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log-ingestion-service/internal/batch"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/sources"
+	"log-ingestion-service/internal/validator"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Kind is the listener kind reported by the listener manager
+const Kind = "syslog"
+
+// Networks a listener can receive on
+const (
+	NetworkUDP  = "udp"
+	NetworkTCP  = "tcp"
+	NetworkBoth = ""
+)
+
+// maxMessageBytes bounds a single message; longer UDP datagrams are truncated by the read and
+// longer TCP frames are discarded
+const maxMessageBytes = 64 * 1024
+
+// maxFrameLengthDigits bounds the octet count that prefixes a TCP frame, which is read before
+// the frame's length is known to fit maxMessageBytes
+const maxFrameLengthDigits = 6
+
+// errPaused is reported for messages received while ingestion is paused
+var errPaused = errors.New("ingestion is paused")
+
+// rejectLogInterval limits how often rejected messages are logged
+const rejectLogInterval = time.Minute
+
+// Options configures a syslog listener
+type Options struct {
+	// Network is udp, tcp, or empty for both on the same port
+	Network string
+	// TLSCertFile and TLSKeyFile enable TLS on TCP (RFC 5425) when both are set
+	TLSCertFile string
+	TLSKeyFile  string
+	// IdleTimeout closes TCP connections that send nothing for this long; 0 disables it
+	IdleTimeout time.Duration
+	// Paused rejects messages while it reports true; syslog senders cannot be asked to retry
+	Paused func() bool
+	// Sources counts accepted and rejected messages per service under the listener's name
+	Sources *sources.Tracker
+}
+
+// Listener receives syslog messages on UDP and/or TCP
+type Listener struct {
+	name       string
+	addr       string
+	opts       Options
+	timestamps *parser.TimestampParser
+	validator  *validator.Validator
+	batcher    *batch.Batcher
+
+	mu       sync.Mutex
+	packet   net.PacketConn
+	stream   net.Listener
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+	rejected int64
+	lastLog  time.Time
+}
+
+// New creates a syslog listener. Timestamps without a zone are read by timestamps.
+func New(name, addr string, opts Options, timestamps *parser.TimestampParser, v *validator.Validator, b *batch.Batcher) *Listener {
+	return &Listener{
+		name:       name,
+		addr:       addr,
+		opts:       opts,
+		timestamps: timestamps,
+		validator:  v,
+		batcher:    b,
+	}
+}
+
+// Name returns the listener name
+func (l *Listener) Name() string { return l.name }
+
+// Kind returns the listener kind
+func (l *Listener) Kind() string { return Kind }
+
+// Addr returns the address the listener binds to, with its networks
+func (l *Listener) Addr() string {
+	if l.opts.Network == NetworkBoth {
+		return "udp+tcp://" + l.addr
+	}
+	return l.opts.Network + "://" + l.addr
+}
+
+// TLS reports whether TCP connections use TLS
+func (l *Listener) TLS() bool { return l.opts.TLSCertFile != "" && l.opts.TLSKeyFile != "" }
+
+// Start binds the configured networks and receives in the background
+func (l *Listener) Start(fail func(error)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var tlsConfig *tls.Config
+	if l.TLS() {
+		cert, err := tls.LoadX509KeyPair(l.opts.TLSCertFile, l.opts.TLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("error loading TLS certificate: %w", err)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	}
+
+	var packet net.PacketConn
+	if l.opts.Network == NetworkUDP || l.opts.Network == NetworkBoth {
+		pc, err := net.ListenPacket("udp", l.addr)
+		if err != nil {
+			return err
+		}
+		packet = pc
+	}
+
+	var stream net.Listener
+	if l.opts.Network == NetworkTCP || l.opts.Network == NetworkBoth {
+		ln, err := net.Listen("tcp", l.addr)
+		if err != nil {
+			if packet != nil {
+				packet.Close()
+			}
+			return err
+		}
+		if tlsConfig != nil {
+			ln = tls.NewListener(ln, tlsConfig)
+		}
+		stream = ln
+	}
+
+	l.packet = packet
+	l.stream = stream
+	l.conns = make(map[net.Conn]struct{})
+
+	if packet != nil {
+		l.wg.Add(1)
+		go l.servePackets(packet, fail)
+	}
+	if stream != nil {
+		l.wg.Add(1)
+		go l.acceptStreams(stream, fail)
+	}
+	return nil
+}
+
+// Stop closes the sockets and open connections and waits for receivers to finish until ctx is done
+func (l *Listener) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	if l.packet != nil {
+		l.packet.Close()
+		l.packet = nil
+	}
+	if l.stream != nil {
+		l.stream.Close()
+		l.stream = nil
+	}
+	for conn := range l.conns {
+		conn.Close()
+	}
+	l.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		l.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// servePackets receives one message per UDP datagram
+func (l *Listener) servePackets(pc net.PacketConn, fail func(error)) {
+	defer l.wg.Done()
+
+	buf := make([]byte, maxMessageBytes)
+	for {
+		n, remote, err := pc.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				fail(err)
+			}
+			return
+		}
+		l.handle(buf[:n], remote)
+	}
+}
+
+// acceptStreams accepts TCP connections until the listener is closed
+func (l *Listener) acceptStreams(ln net.Listener, fail func(error)) {
+	defer l.wg.Done()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			if !errors.Is(err, net.ErrClosed) {
+				fail(err)
+			}
+			return
+		}
+
+		l.mu.Lock()
+		if l.conns == nil || l.stream == nil {
+			// Stopped while accepting
+			l.mu.Unlock()
+			conn.Close()
+			return
+		}
+		l.conns[conn] = struct{}{}
+		l.wg.Add(1)
+		l.mu.Unlock()
+
+		go l.serveStream(conn)
+	}
+}
+
+// serveStream reads messages framed by octet counting ("LEN MSG", RFC 6587) or by newlines
+func (l *Listener) serveStream(conn net.Conn) {
+	defer l.wg.Done()
+	defer func() {
+		l.mu.Lock()
+		delete(l.conns, conn)
+		l.mu.Unlock()
+		conn.Close()
+	}()
+
+	reader := bufio.NewReaderSize(conn, 16*1024)
+	for {
+		if l.opts.IdleTimeout > 0 {
+			conn.SetReadDeadline(time.Now().Add(l.opts.IdleTimeout))
+		}
+		msg, err := readFrame(reader)
+		if len(msg) > 0 {
+			l.handle(msg, conn.RemoteAddr())
+		}
+		if err != nil {
+			if err != io.EOF && !errors.Is(err, net.ErrClosed) {
+				l.reject(conn.RemoteAddr(), err)
+			}
+			return
+		}
+	}
+}
+
+// readFrame reads the next message from a TCP stream
+func readFrame(r *bufio.Reader) ([]byte, error) {
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if first[0] >= '1' && first[0] <= '9' {
+		length, err := readFrameLength(r)
+		if err != nil {
+			return nil, err
+		}
+		msg := make([]byte, length)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	}
+
+	var msg []byte
+	for {
+		line, err := r.ReadSlice('\n')
+		msg = append(msg, line...)
+		if len(msg) > maxMessageBytes {
+			return nil, fmt.Errorf("syslog message exceeds %d bytes", maxMessageBytes)
+		}
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		return msg, err
+	}
+}
+
+// readFrameLength reads the octet count of a frame and the space that ends it. The digits are
+// read one at a time, up to maxFrameLengthDigits, so a client that never sends the space cannot
+// grow the buffer.
+func readFrameLength(r *bufio.Reader) (int, error) {
+	var prefix []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if c == ' ' {
+			break
+		}
+		prefix = append(prefix, c)
+		if c < '0' || c > '9' || len(prefix) > maxFrameLengthDigits {
+			return 0, fmt.Errorf("invalid syslog frame length %q", prefix)
+		}
+	}
+	length, err := strconv.Atoi(string(prefix))
+	if err != nil || length > maxMessageBytes {
+		return 0, fmt.Errorf("invalid syslog frame length %q", prefix)
+	}
+	return length, nil
+}
+
+// handle parses, validates and enqueues one message
+func (l *Listener) handle(data []byte, remote net.Addr) {
+	if l.opts.Paused != nil && l.opts.Paused() {
+		l.reject(remote, errPaused)
+		return
+	}
+	logEntry, err := Parse(data, l.timestamps, time.Now().UTC())
+	if err == errEmptyMessage {
+		return
+	}
+	rec := l.opts.Sources.Begin("", l.name)
+	defer rec.Flush()
+	if err != nil {
+		rec.Reject("", data, err)
+		l.reject(remote, err)
+		return
+	}
+	if remote != nil {
+		logEntry.Metadata["remote_addr"] = remote.String()
+	}
+	logEntry.Metadata["listener"] = l.name
+
+	if err := l.validator.Validate(logEntry); err != nil {
+		rec.Reject(logEntry.Service, data, err)
+		l.reject(remote, err)
+		return
+	}
+	l.validator.Sanitize(logEntry)
+
+	if err := l.batcher.Add(*logEntry); err != nil {
+		l.reject(remote, err)
+		return
+	}
+	rec.Accept(logEntry.Service)
+}
+
+// reject counts a dropped message, logging at most once per rejectLogInterval
+func (l *Listener) reject(remote net.Addr, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rejected++
+	if time.Since(l.lastLog) < rejectLogInterval {
+		return
+	}
+	log.Printf("WARN: Syslog listener %s rejected %d message(s), latest from %v: %v", l.name, l.rejected, remote, err)
+	l.rejected = 0
+	l.lastLog = time.Now()
+}
@@ -0,0 +1,71 @@
+package syslog
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"testing"
+)
+
+// digits is an endless stream of digits, as sent by a client that never ends a frame's length
+type digits struct {
+	read int
+}
+
+func (d *digits) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = '1'
+	}
+	d.read += len(p)
+	return len(p), nil
+}
+
+func TestReadFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "octet counted", input: "5 hello3 abc", want: []string{"hello", "abc"}},
+		{name: "newline delimited", input: "<13>hello\n<13>world\n", want: []string{"<13>hello\n", "<13>world\n"}},
+		{name: "length over the maximum", input: "65537 x", wantErr: true},
+		{name: "length too long", input: "1234567 x", wantErr: true},
+		{name: "length not numeric", input: "12a4 x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			var got []string
+			for {
+				msg, err := readFrame(r)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					if !tt.wantErr {
+						t.Fatalf("readFrame() error = %v", err)
+					}
+					return
+				}
+				got = append(got, string(msg))
+			}
+			if tt.wantErr {
+				t.Fatalf("readFrame() read %q, want an error", got)
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("readFrame() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadFrameRejectsEndlessLength(t *testing.T) {
+	stream := &digits{}
+	if _, err := readFrame(bufio.NewReaderSize(stream, 16)); err == nil {
+		t.Fatal("readFrame() accepted an endless length")
+	}
+	if stream.read > 16 {
+		t.Fatalf("readFrame() read %d bytes of the length", stream.read)
+	}
+}
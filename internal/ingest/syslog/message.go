@@ -0,0 +1,251 @@
+package syslog
+
+import (
+	"errors"
+	"fmt"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nilValue marks an absent RFC 5424 header field
+const nilValue = "-"
+
+// utf8BOM may prefix an RFC 5424 message
+const utf8BOM = "\xef\xbb\xbf"
+
+// defaultPriority is assumed for messages without a PRI part (user.notice, RFC 3164 section 4.3.3)
+const defaultPriority = 13
+
+// severityNames and facilityNames are stored in metadata
+var severityNames = [8]string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+var facilityNames = [24]string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+var errEmptyMessage = errors.New("empty syslog message")
+
+// Parse parses an RFC 5424 or RFC 3164 message. Timestamps without a zone are read by ts;
+// messages without a usable timestamp get received.
+func Parse(data []byte, ts *parser.TimestampParser, received time.Time) (*models.LogEntry, error) {
+	msg := strings.TrimRight(string(data), "\r\n\x00")
+	if strings.TrimSpace(msg) == "" {
+		return nil, errEmptyMessage
+	}
+
+	priority, rest, err := parsePriority(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	logEntry := &models.LogEntry{
+		Timestamp: received,
+		// The validator maps the numeric severity to a level, per levels.syslog_severities
+		Level:     strconv.Itoa(priority % 8),
+		Metadata: map[string]interface{}{
+			"facility": facilityNames[priority/8],
+			"severity": severityNames[priority%8],
+		},
+	}
+
+	if strings.HasPrefix(rest, "1 ") {
+		err = parseRFC5424(rest[2:], logEntry)
+	} else {
+		parseRFC3164(rest, ts, logEntry)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if logEntry.Service == "" {
+		if host, ok := logEntry.Metadata["hostname"].(string); ok {
+			logEntry.Service = host
+		} else {
+			logEntry.Service = parser.UnknownService
+		}
+	}
+	return logEntry, nil
+}
+
+// parsePriority reads the <PRI> prefix, returning the priority and the remainder
+func parsePriority(msg string) (int, string, error) {
+	if msg[0] != '<' {
+		return defaultPriority, msg, nil
+	}
+	end := strings.IndexByte(msg, '>')
+	if end < 2 || end > 4 {
+		return 0, "", fmt.Errorf("invalid syslog priority in %q", truncate(msg))
+	}
+	priority, err := strconv.Atoi(msg[1:end])
+	if err != nil || priority < 0 || priority > 191 {
+		return 0, "", fmt.Errorf("invalid syslog priority %q", msg[1:end])
+	}
+	return priority, msg[end+1:], nil
+}
+
+// parseRFC5424 reads TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA [MSG]
+func parseRFC5424(rest string, logEntry *models.LogEntry) error {
+	fields := make([]string, 5)
+	for i := range fields {
+		var ok bool
+		fields[i], rest, ok = strings.Cut(rest, " ")
+		if !ok && i < len(fields)-1 {
+			return fmt.Errorf("truncated RFC 5424 header")
+		}
+	}
+
+	if fields[0] != nilValue {
+		t, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid RFC 5424 timestamp %q", fields[0])
+		}
+		logEntry.Timestamp = t
+	}
+	setField(logEntry.Metadata, "hostname", fields[1])
+	if fields[2] != nilValue {
+		logEntry.Service = fields[2]
+		logEntry.Metadata["app_name"] = fields[2]
+	}
+	setField(logEntry.Metadata, "procid", fields[3])
+	setField(logEntry.Metadata, "msgid", fields[4])
+
+	if strings.HasPrefix(rest, nilValue) {
+		rest = strings.TrimPrefix(rest[1:], " ")
+	} else if strings.HasPrefix(rest, "[") {
+		sd, remaining, err := parseStructuredData(rest)
+		if err != nil {
+			return err
+		}
+		logEntry.Metadata["structured_data"] = sd
+		if remaining == "" {
+			// Messages may carry only structured data
+			remaining = rest
+		}
+		rest = strings.TrimPrefix(remaining, " ")
+	}
+
+	logEntry.Message = strings.TrimPrefix(rest, utf8BOM)
+	return nil
+}
+
+// parseStructuredData reads one or more [SD-ID PARAM="VALUE" ...] elements
+func parseStructuredData(s string) (map[string]interface{}, string, error) {
+	elements := make(map[string]interface{})
+	for strings.HasPrefix(s, "[") {
+		s = s[1:]
+		idEnd := strings.IndexAny(s, " ]")
+		if idEnd <= 0 {
+			return nil, "", fmt.Errorf("invalid structured data element")
+		}
+		id := s[:idEnd]
+		s = s[idEnd:]
+
+		params := make(map[string]interface{})
+		for strings.HasPrefix(s, " ") {
+			s = s[1:]
+			eq := strings.Index(s, "=\"")
+			if eq <= 0 {
+				return nil, "", fmt.Errorf("invalid structured data parameter in %q", id)
+			}
+			name := s[:eq]
+			s = s[eq+2:]
+
+			var value strings.Builder
+			closed := false
+			for i := 0; i < len(s); i++ {
+				c := s[i]
+				if c == '\\' && i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\' || s[i+1] == ']') {
+					value.WriteByte(s[i+1])
+					i++
+					continue
+				}
+				if c == '"' {
+					s = s[i+1:]
+					closed = true
+					break
+				}
+				value.WriteByte(c)
+			}
+			if !closed {
+				return nil, "", fmt.Errorf("unterminated structured data value in %q", id)
+			}
+			params[name] = value.String()
+		}
+
+		if !strings.HasPrefix(s, "]") {
+			return nil, "", fmt.Errorf("unterminated structured data element %q", id)
+		}
+		s = s[1:]
+		elements[id] = params
+	}
+	return elements, s, nil
+}
+
+// parseRFC3164 reads the loosely specified BSD format: TIMESTAMP [HOSTNAME] TAG[PID]: MSG.
+// Anything that does not fit is kept as the message.
+func parseRFC3164(rest string, ts *parser.TimestampParser, logEntry *models.LogEntry) {
+	// "Mmm dd hh:mm:ss", the day padded with a space
+	found := false
+	if len(rest) >= len(time.Stamp) {
+		if t, err := ts.ParseLayout(time.Stamp, rest[:len(time.Stamp)]); err == nil {
+			logEntry.Timestamp = t
+			rest = strings.TrimPrefix(rest[len(time.Stamp):], " ")
+			found = true
+		} else if token, remaining, ok := strings.Cut(rest, " "); ok {
+			// Some senders use an RFC 3339 timestamp instead
+			if t, err := ts.ParseString(token); err == nil {
+				logEntry.Timestamp = t
+				rest = remaining
+				found = true
+			}
+		}
+	}
+	// Without a header the whole text is the message
+	if !found {
+		logEntry.Message = rest
+		return
+	}
+
+	// The hostname is omitted by local senders, in which case the first word is the tag
+	if word, remaining, ok := strings.Cut(rest, " "); ok && !isTag(word) {
+		logEntry.Metadata["hostname"] = word
+		rest = remaining
+	}
+
+	if word, remaining, ok := strings.Cut(rest, " "); ok && isTag(word) {
+		tag := strings.TrimSuffix(word, ":")
+		if open := strings.IndexByte(tag, '['); open > 0 && strings.HasSuffix(tag, "]") {
+			logEntry.Metadata["procid"] = tag[open+1 : len(tag)-1]
+			tag = tag[:open]
+		}
+		logEntry.Service = tag
+		logEntry.Metadata["app_name"] = tag
+		rest = remaining
+	}
+
+	logEntry.Message = rest
+}
+
+// isTag reports whether word looks like an RFC 3164 tag ("app:" or "app[123]:")
+func isTag(word string) bool {
+	return len(word) > 1 && strings.HasSuffix(word, ":")
+}
+
+func setField(metadata map[string]interface{}, key, value string) {
+	if value != "" && value != nilValue {
+		metadata[key] = value
+	}
+}
+
+// truncate shortens a message for use in an error
+func truncate(s string) string {
+	if len(s) > 64 {
+		return s[:64] + "…"
+	}
+	return s
+}
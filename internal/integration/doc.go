@@ -0,0 +1,5 @@
+// Package integration holds end-to-end tests that run the service's router against a throwaway
+// TimescaleDB container. They are built with the integration tag and need Docker:
+//
+//	go test -tags integration ./internal/integration/
+package integration
@@ -0,0 +1,183 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestLogIngestion sends logs through each ingestion endpoint and checks they are stored and
+// returned by the admin query
+func TestLogIngestion(t *testing.T) {
+	runID := fmt.Sprintf("logs-%d", time.Now().UnixNano())
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	status, body := srv.ingest(http.MethodPost, "/api/v1/logs", map[string]interface{}{
+		"timestamp": now, "service": "integration", "level": "INFO", "message": "single " + runID,
+	})
+	expectStatus(t, "single log", status, http.StatusAccepted, body)
+
+	status, body = srv.ingest(http.MethodPost, "/api/v1/logs/batch", map[string]interface{}{
+		"logs": []map[string]interface{}{
+			{"timestamp": now, "service": "integration", "level": "WARN", "message": "batch-1 " + runID},
+			{"timestamp": now, "service": "integration", "level": "ERROR", "message": "batch-2 " + runID},
+			{"service": "integration", "level": "NOPE", "message": "invalid " + runID},
+		},
+	})
+	var batch struct {
+		Accepted int `json:"accepted"`
+	}
+	decode(t, body, &batch)
+	if batch.Accepted != 2 {
+		t.Fatalf("batch accepted %d entries, want the 2 valid ones (status %d): %s", batch.Accepted, status, body)
+	}
+
+	raw := fmt.Sprintf(`{"timestamp":%q,"service":"integration","level":"DEBUG","message":"raw %s"}`, now, runID)
+	status, body = srv.send(http.MethodPost, "/api/v1/logs/raw", raw,
+		http.Header{"X-Api-Key": {apiKey}, "Content-Type": {"text/plain"}})
+	expectStatus(t, "raw log", status, http.StatusAccepted, body)
+
+	eventually(t, "the 4 valid logs are stored", func() bool {
+		return srv.count(t, `SELECT COUNT(*) FROM logs WHERE message LIKE '%' || $1`, runID) == 4
+	})
+
+	status, body = srv.admin(http.MethodGet, "/admin/logs/recent?limit=100", nil)
+	expectStatus(t, "recent logs", status, http.StatusOK, body)
+	if n := bytes.Count(body, []byte(runID)); n != 4 {
+		t.Errorf("recent logs show %d of the run's logs, want 4", n)
+	}
+}
+
+// TestNoticeGrouping sends the same notice twice and checks both group into one fault whose
+// occurrences, notices and search results reflect them
+func TestNoticeGrouping(t *testing.T) {
+	runID := fmt.Sprintf("notices-%d", time.Now().UnixNano())
+	notice := map[string]interface{}{
+		"error": map[string]interface{}{
+			"class":     "IntegrationError",
+			"message":   "boom " + runID,
+			"backtrace": []map[string]interface{}{{"file": "app/integration.rb", "line": 42, "function": "run"}},
+		},
+		"server": map[string]interface{}{"environment_name": "integration"},
+	}
+
+	first := postNotice(t, notice)
+	second := postNotice(t, notice)
+	if first != second {
+		t.Fatalf("repeated notice grouped into fault %d, want %d", second, first)
+	}
+
+	eventually(t, "both notices are stored", func() bool {
+		return srv.count(t, `SELECT COUNT(*) FROM notices WHERE fault_id = $1 AND message = $2`, first, "boom "+runID) == 2
+	})
+	if n := srv.count(t, `SELECT occurrence_count FROM faults WHERE id = $1`, first); n != 2 {
+		t.Errorf("fault occurrence count is %d, want 2", n)
+	}
+
+	status, body := srv.ingest(http.MethodGet, fmt.Sprintf("/api/v1/faults/%d/notices", first), nil)
+	expectStatus(t, "fault notices", status, http.StatusOK, body)
+	if n := bytes.Count(body, []byte("boom "+runID)); n < 2 {
+		t.Errorf("fault notices show %d of the run's notices, want 2", n)
+	}
+
+	status, body = srv.ingest(http.MethodGet, "/api/v1/faults?q=IntegrationError", nil)
+	expectStatus(t, "fault search", status, http.StatusOK, body)
+	if !bytes.Contains(body, []byte(fmt.Sprintf(`"id":%d`, first))) {
+		t.Errorf("fault search does not find fault %d: %s", first, body)
+	}
+}
+
+// TestRetention checks that the retention preview reports expired logs, that dropping them as
+// a retention policy would removes only those, and that an environment's retention period
+// deletes its notices
+func TestRetention(t *testing.T) {
+	ctx := context.Background()
+	runID := fmt.Sprintf("retention-%d", time.Now().UnixNano())
+
+	// Logs this old are refused by validation, so the expired one is written directly
+	if _, err := srv.pool.Exec(ctx, `
+		INSERT INTO logs (timestamp, service, level, message)
+		VALUES (NOW() - INTERVAL '90 days', 'integration', 'INFO', $1)
+	`, "expired "+runID); err != nil {
+		t.Fatalf("inserting an expired log: %v", err)
+	}
+	status, body := srv.ingest(http.MethodPost, "/api/v1/logs", map[string]interface{}{
+		"timestamp": time.Now().UTC().Format(time.RFC3339), "service": "integration", "level": "INFO", "message": "kept " + runID,
+	})
+	expectStatus(t, "current log", status, http.StatusAccepted, body)
+	eventually(t, "the current log is stored", func() bool {
+		return srv.count(t, `SELECT COUNT(*) FROM logs WHERE message = $1`, "kept "+runID) == 1
+	})
+
+	status, body = srv.admin(http.MethodGet, "/admin/retention/preview?table=logs&drop_after=30d", nil)
+	expectStatus(t, "retention preview", status, http.StatusOK, body)
+	var preview struct {
+		Policies []struct {
+			Table        string `json:"table"`
+			RowsToDelete int64  `json:"rows_to_delete"`
+		} `json:"policies"`
+	}
+	decode(t, body, &preview)
+	if len(preview.Policies) != 1 || preview.Policies[0].Table != "logs" || preview.Policies[0].RowsToDelete < 1 {
+		t.Fatalf("retention preview does not report the expired log: %s", body)
+	}
+
+	// What a TimescaleDB retention policy of 30 days runs
+	if _, err := srv.pool.Exec(ctx, `SELECT drop_chunks('logs', older_than => INTERVAL '30 days')`); err != nil {
+		t.Fatalf("dropping expired chunks: %v", err)
+	}
+	if n := srv.count(t, `SELECT COUNT(*) FROM logs WHERE message = $1`, "expired "+runID); n != 0 {
+		t.Errorf("expired log is still stored after retention")
+	}
+	if n := srv.count(t, `SELECT COUNT(*) FROM logs WHERE message = $1`, "kept "+runID); n != 1 {
+		t.Errorf("current log was removed by retention")
+	}
+
+	// Notices of an environment with a retention period are deleted once older than it
+	environment := "retention-" + runID
+	var envID int64
+	if err := srv.pool.QueryRow(ctx, `
+		INSERT INTO environments (name, retention_days) VALUES ($1, 1) RETURNING id
+	`, environment).Scan(&envID); err != nil {
+		t.Fatalf("creating an environment: %v", err)
+	}
+	faultID := postNotice(t, map[string]interface{}{
+		"error":  map[string]interface{}{"class": "RetentionError", "message": "expiring " + runID},
+		"server": map[string]interface{}{"environment_name": environment},
+	})
+	eventually(t, "the notice is stored", func() bool {
+		return srv.count(t, `SELECT COUNT(*) FROM notices WHERE fault_id = $1`, faultID) == 1
+	})
+
+	// As the enforcer would delete it two days from now
+	deleted, err := srv.repo.DeleteExpiredEnvironmentNotices(ctx, envID, time.Now().Add(24*time.Hour), 100)
+	if err != nil {
+		t.Fatalf("deleting expired notices: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("retention deleted %d notices, want 1", deleted)
+	}
+	if n := srv.count(t, `SELECT occurrence_count FROM faults WHERE id = $1`, faultID); n != 1 {
+		t.Errorf("fault occurrence count is %d after retention, want it kept at 1", n)
+	}
+}
+
+// postNotice sends a Honeybadger notice and returns the fault it was grouped into
+func postNotice(t *testing.T, notice interface{}) int64 {
+	t.Helper()
+	status, body := srv.ingest(http.MethodPost, "/api/v1/notices", notice)
+	expectStatus(t, "notice", status, http.StatusCreated, body)
+	var response struct {
+		FaultID int64 `json:"fault_id"`
+	}
+	decode(t, body, &response)
+	if response.FaultID == 0 {
+		t.Fatalf("notice response has no fault ID: %s", body)
+	}
+	return response.FaultID
+}
@@ -0,0 +1,378 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"log-ingestion-service/internal/api"
+	"log-ingestion-service/internal/auth"
+	"log-ingestion-service/internal/batch"
+	"log-ingestion-service/internal/enrich"
+	"log-ingestion-service/internal/fault"
+	"log-ingestion-service/internal/feature"
+	"log-ingestion-service/internal/jobs"
+	"log-ingestion-service/internal/logschema"
+	"log-ingestion-service/internal/middleware"
+	"log-ingestion-service/internal/notify"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/pause"
+	"log-ingestion-service/internal/querycost"
+	"log-ingestion-service/internal/redact"
+	"log-ingestion-service/internal/rejects"
+	"log-ingestion-service/internal/sampling"
+	"log-ingestion-service/internal/sources"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/internal/swr"
+	"log-ingestion-service/internal/validator"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// defaultImage is the database image the tests run against, overridden by INTEGRATION_IMAGE
+const defaultImage = "timescale/timescaledb:latest-pg16"
+
+// Credentials of the API key and admin user the harness creates
+const (
+	apiKey        = "integration-key"
+	adminEmail    = "integration@example.com"
+	adminPassword = "integration-password"
+)
+
+// server is the service under test: the database, the router with every route these tests
+// use, and an admin session token
+type server struct {
+	cfg        *config.Config
+	pool       *pgxpool.Pool
+	repo       *storage.Repository
+	router     *gin.Engine
+	adminToken string
+	shutdown   []func()
+}
+
+var srv *server
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+// run starts the database container and the server, runs the tests and removes them again,
+// unless INTEGRATION_KEEP=1 is set
+func run(m *testing.M) int {
+	ctx := context.Background()
+	image := os.Getenv("INTEGRATION_IMAGE")
+	if image == "" {
+		image = defaultImage
+	}
+
+	container, err := postgres.RunContainer(ctx,
+		testcontainers.WithImage(image),
+		postgres.WithDatabase("logs"),
+		postgres.WithUsername("postgres"),
+		postgres.WithPassword("postgres"),
+		// The server restarts once after the init scripts, so it is ready on the second message
+		testcontainers.WithWaitStrategy(wait.ForLog("database system is ready to accept connections").
+			WithOccurrence(2).WithStartupTimeout(2*time.Minute)),
+	)
+	if err != nil {
+		log.Printf("ERROR: Failed to start %s: %v", image, err)
+		return 1
+	}
+	defer func() {
+		if os.Getenv("INTEGRATION_KEEP") == "1" {
+			log.Printf("WARN: Keeping database container %s", container.GetContainerID())
+			return
+		}
+		if err := container.Terminate(ctx); err != nil {
+			log.Printf("WARN: Failed to remove database container: %v", err)
+		}
+	}()
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		log.Printf("ERROR: Failed to get database host: %v", err)
+		return 1
+	}
+	port, err := container.MappedPort(ctx, "5432/tcp")
+	if err != nil {
+		log.Printf("ERROR: Failed to get database port: %v", err)
+		return 1
+	}
+
+	srv, err = startServer(ctx, host, port.Int())
+	if err != nil {
+		log.Printf("ERROR: Failed to start the server: %v", err)
+		return 1
+	}
+	defer srv.close()
+
+	return m.Run()
+}
+
+// startServer loads the configuration the way the server does, with the database and short
+// flush intervals set through the environment, applies the migrations and builds the router
+func startServer(ctx context.Context, host string, port int) (*server, error) {
+	for key, value := range map[string]string{
+		"LOG_INGESTION_DB_HOST":                     host,
+		"LOG_INGESTION_DB_PORT":                     strconv.Itoa(port),
+		"LOG_INGESTION_DB_PASSWORD":                 "postgres",
+		"LOG_INGESTION_JWT_SECRET":                  "integration-secret",
+		"LOG_INGESTION_RATELIMIT_ENABLED":           "false",
+		"LOG_INGESTION_BATCH_FLUSH_INTERVAL":        "100ms",
+		"LOG_INGESTION_NOTICE_BATCH_FLUSH_INTERVAL": "100ms",
+	} {
+		os.Setenv(key, value)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	pool, err := storage.NewConnection(ctx, &cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	s := &server{cfg: cfg, pool: pool, repo: storage.NewRepository(pool)}
+	s.shutdown = append(s.shutdown, pool.Close)
+
+	if err := applyMigrations(ctx, pool); err != nil {
+		s.close()
+		return nil, err
+	}
+	if _, err := pool.Exec(ctx, `INSERT INTO api_keys (key, name) VALUES ($1, 'integration')`, apiKey); err != nil {
+		s.close()
+		return nil, fmt.Errorf("error creating API key: %w", err)
+	}
+	if err := s.buildRouter(ctx); err != nil {
+		s.close()
+		return nil, err
+	}
+	if err := s.login(ctx); err != nil {
+		s.close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// applyMigrations runs every migration in order, as make migrate does
+func applyMigrations(ctx context.Context, pool *pgxpool.Pool) error {
+	files, err := filepath.Glob(filepath.Join("..", "..", "migrations", "*.sql"))
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no migrations found")
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		// Without arguments the file runs over the simple protocol, which allows several statements
+		if _, err := pool.Exec(ctx, string(migration)); err != nil {
+			return fmt.Errorf("error applying %s: %w", filepath.Base(file), err)
+		}
+	}
+	return nil
+}
+
+// buildRouter wires the handlers as cmd/server does and registers the routes the tests use,
+// behind the same middleware
+func (s *server) buildRouter(ctx context.Context) error {
+	cfg, repo := s.cfg, s.repo
+
+	keyManager := auth.NewKeyManager(repo)
+	sessions := auth.NewSessionStore(repo, cfg.Auth.JWTSecret)
+	flags, err := feature.NewFlags(repo, cfg.Features)
+	if err != nil {
+		return err
+	}
+	pipelines, err := pause.NewController(ctx, repo)
+	if err != nil {
+		return err
+	}
+	sampler, err := sampling.New(ctx, &cfg.Sampling, repo)
+	if err != nil {
+		return err
+	}
+	batcher := batch.NewBatcher(repo, &cfg.Batch, sampler, nil, nil)
+	noticeBatcher := batch.NewNoticeBatcher(repo, &cfg.NoticeBatch)
+	s.shutdown = append(s.shutdown, func() { batcher.Shutdown() }, func() { noticeBatcher.Shutdown() })
+
+	notifier := notify.NewDispatcher(&cfg.Notifications)
+	redactor, err := redact.New(ctx, &cfg.Scrub, repo)
+	if err != nil {
+		return err
+	}
+	schemas, err := logschema.New(ctx, repo)
+	if err != nil {
+		return err
+	}
+	enricher, err := enrich.New(&cfg.Enrich)
+	if err != nil {
+		return err
+	}
+	enricher.RegisterProcessors()
+	parsers, err := parser.NewRegistry(&cfg.Parser)
+	if err != nil {
+		return err
+	}
+
+	limits := validator.NewMetadataLimiter(&cfg.MetadataLimits)
+	logValidator := validator.NewValidator(redactor, &cfg.Levels, schemas, limits)
+	rejected := rejects.NewStore(&cfg.Rejects, redactor)
+	tracker := sources.NewTracker(rejected)
+	handler := api.NewHandler(batcher, parsers, keyManager, logValidator, flags, tracker)
+	guard := querycost.NewGuard(&cfg.QueryGuard, repo)
+	analytics := swr.New(&cfg.AnalyticsCache, cfg.Timeouts.Analytics)
+	noticeSampler := fault.NewNoticeSampler(&cfg.Faults.Sampling)
+	adminHandler := api.NewAdminHandler(repo, batcher, notifier, parsers, sessions, cfg, guard, analytics, noticeSampler)
+	runner := jobs.NewRunner(repo, &cfg.Jobs)
+	faultHandler := api.NewFaultHandler(repo, notifier, noticeBatcher, flags, rejected, runner, &cfg.Faults, enricher, redactor, guard, analytics, limits, noticeSampler)
+
+	gin.SetMode(gin.ReleaseMode)
+	router := gin.New()
+	router.Use(gin.Recovery())
+	router.Use(middleware.IngestionPause(pipelines))
+	router.Use(middleware.Concurrency(&cfg.Concurrency))
+	router.Use(middleware.Timeout(&cfg.Timeouts))
+	api.SetupRoutes(router, handler, keyManager, cfg)
+	api.SetupFaultRoutes(router, faultHandler, keyManager, sessions, cfg)
+	api.SetupRollbarRoutes(router, faultHandler, keyManager, cfg)
+	api.SetupBugsnagRoutes(router, faultHandler, keyManager, cfg)
+	api.SetupAirbrakeRoutes(router, faultHandler, keyManager, cfg)
+	api.SetupAdminRoutes(router, adminHandler, sessions, cfg)
+	s.router = router
+	return nil
+}
+
+// login registers the admin user, grants it admin rights and keeps its session token
+func (s *server) login(ctx context.Context) error {
+	register := map[string]string{"email": adminEmail, "name": "Integration", "password": adminPassword}
+	if status, body := s.send(http.MethodPost, "/auth/register", register, nil); status != http.StatusCreated {
+		return fmt.Errorf("registering the admin user returned %d: %s", status, body)
+	}
+	if _, err := s.pool.Exec(ctx, `UPDATE users SET is_admin = TRUE WHERE email = $1`, adminEmail); err != nil {
+		return fmt.Errorf("error granting admin rights: %w", err)
+	}
+
+	credentials := map[string]string{"email": adminEmail, "password": adminPassword}
+	status, body := s.send(http.MethodPost, "/auth/login", credentials, nil)
+	if status != http.StatusOK {
+		return fmt.Errorf("logging in returned %d: %s", status, body)
+	}
+	var response struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || response.Token == "" {
+		return fmt.Errorf("logging in returned no token: %s", body)
+	}
+	s.adminToken = response.Token
+	return nil
+}
+
+// close stops the batchers, writing what they hold, and closes the database pool
+func (s *server) close() {
+	for i := len(s.shutdown) - 1; i >= 0; i-- {
+		s.shutdown[i]()
+	}
+}
+
+// send serves a request through the router. A body that is not a string or []byte is sent as
+// JSON.
+func (s *server) send(method, path string, body interface{}, header http.Header) (int, []byte) {
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = bytes.NewBufferString(b)
+	case []byte:
+		reader = bytes.NewReader(b)
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			panic(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	rec := httptest.NewRecorder()
+	s.router.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.Bytes()
+}
+
+// ingest sends a request authenticated with the API key
+func (s *server) ingest(method, path string, body interface{}) (int, []byte) {
+	return s.send(method, path, body, http.Header{"X-Api-Key": {apiKey}})
+}
+
+// admin sends a request authenticated as the admin user
+func (s *server) admin(method, path string, body interface{}) (int, []byte) {
+	return s.send(method, path, body, http.Header{"Authorization": {"Bearer " + s.adminToken}})
+}
+
+// count returns the single integer a query selects
+func (s *server) count(t *testing.T, query string, args ...interface{}) int64 {
+	t.Helper()
+	var n int64
+	if err := s.pool.QueryRow(context.Background(), query, args...).Scan(&n); err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	return n
+}
+
+// eventually retries check for up to 10 seconds, for rows written by the batchers
+func eventually(t *testing.T, what string, check func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !check() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting until %s", what)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// decode unmarshals a response body, failing the test if it is not JSON
+func decode(t *testing.T, body []byte, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(body, v); err != nil {
+		t.Fatalf("response is not JSON: %v: %s", err, body)
+	}
+}
+
+// expectStatus fails the test unless a response has the given status
+func expectStatus(t *testing.T, what string, status, want int, body []byte) {
+	t.Helper()
+	if status != want {
+		t.Fatalf("%s returned %d, want %d: %s", what, status, want, body)
+	}
+}
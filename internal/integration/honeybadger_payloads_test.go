@@ -0,0 +1,74 @@
+//go:build integration
+
+package integration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestHoneybadgerPayloads sends payloads captured from the official Honeybadger SDKs, in
+// integrations/honeybadger-payloads/, twice each. Both must be accepted and group into one
+// fault with the expected class and location, and the stored notices must keep the SDK's
+// backtrace line numbers.
+func TestHoneybadgerPayloads(t *testing.T) {
+	cases := []struct {
+		sdk      string
+		class    string
+		location string
+		line     int
+	}{
+		{sdk: "ruby", class: "ActiveRecord::RecordNotFound", location: "users#show", line: 17},
+		{sdk: "javascript", class: "TypeError", location: "webpack-internal:///./src/components/OrderList.jsx:23", line: 23},
+		{sdk: "php", class: `Illuminate\Database\QueryException`, location: `App\Http\Controllers\InvoiceController#index`, line: 58},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.sdk, func(t *testing.T) {
+			payload, err := os.ReadFile(filepath.Join("..", "..", "integrations", "honeybadger-payloads", tc.sdk+".json"))
+			if err != nil {
+				t.Fatalf("reading payload: %v", err)
+			}
+
+			var since time.Time
+			if err := srv.pool.QueryRow(context.Background(), `SELECT NOW()`).Scan(&since); err != nil {
+				t.Fatalf("reading the database clock: %v", err)
+			}
+
+			first := postNotice(t, payload)
+			second := postNotice(t, payload)
+			if first != second {
+				t.Fatalf("repeated payload grouped into fault %d, want %d", second, first)
+			}
+
+			var class, location string
+			if err := srv.pool.QueryRow(context.Background(), `
+				SELECT error_class, COALESCE(location, '') FROM faults WHERE id = $1
+			`, first).Scan(&class, &location); err != nil {
+				t.Fatalf("reading fault %d: %v", first, err)
+			}
+			if class != tc.class {
+				t.Errorf("fault class is %q, want %q", class, tc.class)
+			}
+			if location != tc.location {
+				t.Errorf("fault location is %q, want %q", location, tc.location)
+			}
+
+			eventually(t, "both notices are stored", func() bool {
+				return srv.count(t, `SELECT COUNT(*) FROM notices WHERE fault_id = $1 AND created_at >= $2`, first, since) == 2
+			})
+
+			status, body := srv.ingest(http.MethodGet, fmt.Sprintf("/api/v1/faults/%d/notices", first), nil)
+			expectStatus(t, "fault notices", status, http.StatusOK, body)
+			if !bytes.Contains(body, []byte(fmt.Sprintf(`"line":%d`, tc.line))) {
+				t.Errorf("stored notices lost backtrace line %d: %s", tc.line, body)
+			}
+		})
+	}
+}
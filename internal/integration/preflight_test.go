@@ -0,0 +1,50 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"log-ingestion-service/internal/preflight"
+	"strings"
+	"testing"
+)
+
+// TestPreflightEncryption runs the preflight checks against the container, whose connections
+// do not use SSL and whose relations are all in pg_default
+func TestPreflightEncryption(t *testing.T) {
+	cases := []struct {
+		name        string
+		requireSSL  bool
+		tablespaces []string
+		ok          bool
+		detail      string
+	}{
+		{name: "not asserted", ok: true, detail: "encryption at rest not asserted"},
+		{name: "default tablespace encrypted", tablespaces: []string{"pg_default"}, ok: true, detail: "in encrypted tablespaces pg_default"},
+		{name: "other tablespace encrypted", tablespaces: []string{"encrypted"}, detail: "relations outside the encrypted tablespaces"},
+		{name: "SSL required", requireSSL: true, detail: "connection does not use SSL"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := srv.cfg.Preflight
+			cfg.Enabled = true
+			cfg.RequireSSL = tc.requireSSL
+			cfg.EncryptedTablespaces = tc.tablespaces
+
+			status := preflight.New(&cfg, preflight.DatabaseChecks(&cfg, srv.repo)...).Run(context.Background())
+			for _, check := range status.Checks {
+				if check.Name != "encryption" {
+					if !check.OK {
+						t.Errorf("check %s failed: %s", check.Name, check.Reason)
+					}
+					continue
+				}
+				if check.OK != tc.ok || !strings.Contains(check.Detail, tc.detail) {
+					t.Errorf("encryption check is ok=%v with detail %q (reason %q), want ok=%v with %q", check.OK, check.Detail, check.Reason, tc.ok, tc.detail)
+				}
+				return
+			}
+			t.Fatal("no encryption check was run")
+		})
+	}
+}
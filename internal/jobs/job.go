@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"sync"
+)
+
+// Job is a claimed job as seen by its handler
+type Job struct {
+	*models.Job
+
+	mu      sync.Mutex
+	done    int64
+	total   *int64
+	message string
+}
+
+// Decode unmarshals the job's payload into v
+func (j *Job) Decode(v interface{}) error {
+	if err := json.Unmarshal(j.Payload, v); err != nil {
+		return Permanent(fmt.Errorf("invalid %s job payload: %w", j.Type, err))
+	}
+	return nil
+}
+
+// Progress records how much of the job is done. A total of zero or less means the total is
+// unknown. Progress is saved with the next heartbeat.
+func (j *Job) Progress(done, total int64, message string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.done = done
+	j.total = nil
+	if total > 0 {
+		j.total = &total
+	}
+	j.message = message
+}
+
+// progress returns the last recorded progress
+func (j *Job) progress() (int64, *int64, string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.done, j.total, j.message
+}
@@ -0,0 +1,320 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"os"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+// cleanupInterval is how often finished jobs past the retention period are deleted
+const cleanupInterval = time.Hour
+
+// maxRetryDelay caps the wait before a failed job is retried
+const maxRetryDelay = 30 * time.Minute
+
+// Handler runs one job. The returned value is stored as the job's JSON result. ctx is cancelled
+// when cancellation of the job is requested or the runner shuts down.
+type Handler func(ctx context.Context, job *Job) (interface{}, error)
+
+// EnqueueOptions controls how a job is queued
+type EnqueueOptions struct {
+	// UniqueKey, when set, refuses the job with storage.ErrJobExists while another job with the
+	// same key is queued or running
+	UniqueKey string
+	// MaxAttempts overrides the configured number of attempts
+	MaxAttempts int
+	CreatedBy   *int64
+}
+
+// permanentError marks a handler error that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so the job fails at once instead of being retried
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Runner queues jobs in the database and runs them on a pool of workers. Any number of
+// instances may share the queue: a job is claimed by one worker, which holds a lease on it
+// while it runs and extends the lease with each progress heartbeat. A job whose worker stops
+// heartbeating is claimed again once its lease expires. Failed jobs are retried with backoff
+// up to their maximum attempts.
+type Runner struct {
+	repo     *storage.Repository
+	config   *config.JobsConfig
+	handlers map[string]Handler
+	id       string
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	started  bool
+}
+
+// NewRunner creates a runner. Handlers are registered before Start.
+func NewRunner(repo *storage.Repository, cfg *config.JobsConfig) *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	hostname, _ := os.Hostname()
+	return &Runner{
+		repo:     repo,
+		config:   cfg,
+		handlers: make(map[string]Handler),
+		id:       fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Register sets the handler for a job type
+func (r *Runner) Register(jobType string, handler Handler) {
+	if r.started {
+		panic("jobs: Register called after Start")
+	}
+	r.handlers[jobType] = handler
+}
+
+// Enqueue queues a job of a registered type with payload encoded as JSON
+func (r *Runner) Enqueue(ctx context.Context, jobType string, payload interface{}, opts EnqueueOptions) (*models.Job, error) {
+	if _, ok := r.handlers[jobType]; !ok {
+		return nil, fmt.Errorf("unknown job type %q", jobType)
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding job payload: %w", err)
+	}
+	job := &models.Job{
+		Type:        jobType,
+		Payload:     data,
+		MaxAttempts: r.config.MaxAttempts,
+		CreatedBy:   opts.CreatedBy,
+	}
+	if opts.MaxAttempts > 0 {
+		job.MaxAttempts = opts.MaxAttempts
+	}
+	if opts.UniqueKey != "" {
+		job.UniqueKey = &opts.UniqueKey
+	}
+	return r.repo.CreateJob(ctx, job)
+}
+
+// Start launches the workers and the cleanup of finished jobs. With no workers configured,
+// jobs are only queued, for other instances to run.
+func (r *Runner) Start() {
+	r.started = true
+	if r.config.Workers <= 0 || len(r.handlers) == 0 {
+		return
+	}
+	types := make([]string, 0, len(r.handlers))
+	for t := range r.handlers {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	for i := 0; i < r.config.Workers; i++ {
+		r.wg.Add(1)
+		go r.work(fmt.Sprintf("%s-%d", r.id, i), types)
+	}
+	r.wg.Add(1)
+	go r.cleanup()
+}
+
+// Shutdown stops the workers, waiting for running jobs to return. Interrupted jobs are queued
+// again.
+func (r *Runner) Shutdown() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// work claims and runs jobs until shutdown
+func (r *Runner) work(worker string, types []string) {
+	defer r.wg.Done()
+	for {
+		job, err := r.repo.ClaimJob(r.ctx, types, worker, r.config.Lease)
+		if err != nil && r.ctx.Err() == nil {
+			log.Printf("ERROR: Failed to claim job: %v", err)
+		}
+		if job != nil {
+			r.run(worker, job)
+			continue
+		}
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-time.After(r.config.PollInterval):
+		}
+	}
+}
+
+// run executes a claimed job while heartbeating its progress, then records the outcome
+func (r *Runner) run(worker string, claimed *models.Job) {
+	// A job claimed again after its worker was lost may have been cancelled while nobody was
+	// running it
+	if claimed.CancelRequested {
+		r.finish(worker, claimed, models.JobCancelled, nil, context.Canceled, nil)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.ctx)
+	defer cancel()
+	job := &Job{Job: claimed, done: claimed.ProgressDone, total: claimed.ProgressTotal, message: claimed.ProgressMessage}
+
+	var cancelled bool
+	heartbeats := make(chan struct{})
+	go func() {
+		defer close(heartbeats)
+		cancelled = r.heartbeat(ctx, worker, job, cancel)
+	}()
+
+	result, err := r.call(ctx, job)
+	cancel()
+	<-heartbeats
+	r.saveProgress(worker, job)
+
+	switch {
+	case cancelled:
+		r.finish(worker, claimed, models.JobCancelled, nil, context.Canceled, nil)
+	case r.ctx.Err() != nil:
+		// Shutting down; another worker picks the job up
+		now := time.Now()
+		r.finish(worker, claimed, models.JobQueued, nil, nil, &now)
+	case err == nil:
+		data, encErr := json.Marshal(result)
+		if encErr != nil {
+			r.finish(worker, claimed, models.JobFailed, nil, fmt.Errorf("error encoding job result: %w", encErr), nil)
+			return
+		}
+		r.finish(worker, claimed, models.JobSucceeded, data, nil, nil)
+	default:
+		var permanent *permanentError
+		if errors.As(err, &permanent) || claimed.Attempts >= claimed.MaxAttempts {
+			log.Printf("ERROR: Job %d (%s) failed after %d attempts: %v", claimed.ID, claimed.Type, claimed.Attempts, err)
+			r.finish(worker, claimed, models.JobFailed, nil, err, nil)
+			return
+		}
+		retryAt := time.Now().Add(retryDelay(claimed.Attempts))
+		log.Printf("WARN: Job %d (%s) attempt %d failed, retrying at %s: %v", claimed.ID, claimed.Type, claimed.Attempts, retryAt.Format(time.RFC3339), err)
+		r.finish(worker, claimed, models.JobQueued, nil, err, &retryAt)
+	}
+}
+
+// call runs the job's handler, turning a panic into an error
+func (r *Runner) call(ctx context.Context, job *Job) (result interface{}, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			log.Printf("ERROR: Job %d (%s) panicked: %v\n%s", job.ID, job.Type, p, debug.Stack())
+			err = Permanent(fmt.Errorf("job panicked: %v", p))
+		}
+	}()
+	return r.handlers[job.Type](ctx, job)
+}
+
+// heartbeat saves the job's progress and extends its lease until ctx is done. When
+// cancellation is requested, or the lease was lost, it cancels the job and reports whether
+// the job was cancelled.
+func (r *Runner) heartbeat(ctx context.Context, worker string, job *Job, cancel context.CancelFunc) bool {
+	ticker := time.NewTicker(r.config.Lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+		done, total, message := job.progress()
+		cancelRequested, err := r.repo.HeartbeatJob(ctx, job.ID, worker, r.config.Lease, done, total, message)
+		if err != nil {
+			if storage.IsNotFound(err) {
+				log.Printf("WARN: Job %d lost its lease, stopping", job.ID)
+				cancel()
+				return false
+			}
+			if ctx.Err() == nil {
+				log.Printf("ERROR: Failed to save progress of job %d: %v", job.ID, err)
+			}
+			continue
+		}
+		if cancelRequested {
+			cancel()
+			return true
+		}
+	}
+}
+
+// saveProgress stores a job's final progress, which the last heartbeat may have missed
+func (r *Runner) saveProgress(worker string, job *Job) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	done, total, message := job.progress()
+	if _, err := r.repo.HeartbeatJob(ctx, job.ID, worker, r.config.Lease, done, total, message); err != nil && !storage.IsNotFound(err) {
+		log.Printf("ERROR: Failed to save progress of job %d: %v", job.ID, err)
+	}
+}
+
+// finish records a job's outcome
+func (r *Runner) finish(worker string, job *models.Job, status string, result []byte, jobErr error, retryAt *time.Time) {
+	// The runner's context may already be cancelled at shutdown
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var errMsg *string
+	if jobErr != nil {
+		msg := jobErr.Error()
+		if errors.Is(jobErr, context.Canceled) && status == models.JobCancelled {
+			msg = "cancelled"
+		}
+		errMsg = &msg
+	}
+	if err := r.repo.FinishJob(ctx, job.ID, worker, status, result, errMsg, retryAt); err != nil {
+		if storage.IsNotFound(err) {
+			log.Printf("WARN: Job %d was taken over by another worker before it finished", job.ID)
+			return
+		}
+		log.Printf("ERROR: Failed to record outcome of job %d: %v", job.ID, err)
+	}
+}
+
+// cleanup deletes finished jobs older than the retention period
+func (r *Runner) cleanup() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		deleted, err := r.repo.DeleteFinishedJobs(r.ctx, time.Now().Add(-r.config.Retention))
+		if err != nil && r.ctx.Err() == nil {
+			log.Printf("ERROR: Failed to delete finished jobs: %v", err)
+		} else if deleted > 0 {
+			log.Printf("Deleted %d finished jobs", deleted)
+		}
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// retryDelay is the wait before retrying a job that failed its nth attempt
+func retryDelay(attempt int) time.Duration {
+	delay := time.Duration(attempt*attempt) * 30 * time.Second
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
@@ -0,0 +1,395 @@
+// Package jsonschema validates decoded JSON against a JSON schema. It implements the keywords
+// used to describe log fields: type, enum, const, properties, required, additionalProperties,
+// items, minItems, maxItems, minLength, maxLength, pattern, minimum, maximum,
+// exclusiveMinimum and exclusiveMaximum. Annotations such as title, description and format
+// are accepted and ignored; any other keyword is an error, so a schema never appears to
+// enforce something it does not.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxErrors caps the violations reported for one value
+const maxErrors = 10
+
+// annotations are keywords that describe a schema without constraining values
+var annotations = map[string]bool{
+	"$schema":     true,
+	"$id":         true,
+	"$comment":    true,
+	"title":       true,
+	"description": true,
+	"default":     true,
+	"examples":    true,
+	"format":      true,
+	"deprecated":  true,
+	"readOnly":    true,
+	"writeOnly":   true,
+}
+
+// types are the values of the type keyword
+var types = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true, "number": true, "integer": true, "string": true,
+}
+
+// Schema is a compiled JSON schema
+type Schema struct {
+	// never is set for the false schema, which no value satisfies
+	never bool
+
+	types    []string
+	enum     []json.RawMessage
+	constant json.RawMessage
+
+	properties   map[string]*Schema
+	required     []string
+	additional   *Schema
+	noAdditional bool
+
+	items              *Schema
+	minItems, maxItems *int
+
+	minLength, maxLength *int
+	pattern              *regexp.Regexp
+
+	minimum, maximum                   *float64
+	exclusiveMinimum, exclusiveMaximum *float64
+}
+
+// Compile parses and compiles a JSON schema
+func Compile(raw []byte) (*Schema, error) {
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("schema is not valid JSON: %w", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("schema must be a single JSON value")
+	}
+	return compile(doc, "#")
+}
+
+// compile compiles the schema node found at the JSON pointer at
+func compile(node interface{}, at string) (*Schema, error) {
+	switch node := node.(type) {
+	case bool:
+		return &Schema{never: !node}, nil
+	case map[string]interface{}:
+		s := &Schema{}
+		for _, key := range sortedKeys(node) {
+			if err := s.set(key, node[key], at+"/"+key); err != nil {
+				return nil, err
+			}
+		}
+		return s, nil
+	default:
+		return nil, fmt.Errorf("%s: a schema must be an object or a boolean", at)
+	}
+}
+
+// set compiles one keyword of a schema
+func (s *Schema) set(key string, value interface{}, at string) error {
+	var err error
+	switch key {
+	case "type":
+		switch value := value.(type) {
+		case string:
+			s.types = []string{value}
+		case []interface{}:
+			for _, t := range value {
+				name, ok := t.(string)
+				if !ok {
+					return fmt.Errorf("%s: types must be strings", at)
+				}
+				s.types = append(s.types, name)
+			}
+		default:
+			return fmt.Errorf("%s: must be a type name or a list of them", at)
+		}
+		for _, t := range s.types {
+			if !types[t] {
+				return fmt.Errorf("%s: unknown type %q", at, t)
+			}
+		}
+	case "enum":
+		values, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be a list", at)
+		}
+		for _, v := range values {
+			encoded, _ := json.Marshal(v)
+			s.enum = append(s.enum, encoded)
+		}
+	case "const":
+		s.constant, _ = json.Marshal(value)
+	case "properties":
+		props, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be an object", at)
+		}
+		s.properties = make(map[string]*Schema, len(props))
+		for _, name := range sortedKeys(props) {
+			if s.properties[name], err = compile(props[name], at+"/"+name); err != nil {
+				return err
+			}
+		}
+	case "required":
+		names, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be a list of property names", at)
+		}
+		for _, name := range names {
+			n, ok := name.(string)
+			if !ok {
+				return fmt.Errorf("%s: must be a list of property names", at)
+			}
+			s.required = append(s.required, n)
+		}
+	case "additionalProperties":
+		if allowed, ok := value.(bool); ok {
+			s.noAdditional = !allowed
+			return nil
+		}
+		s.additional, err = compile(value, at)
+	case "items":
+		s.items, err = compile(value, at)
+	case "minItems":
+		s.minItems, err = count(value, at)
+	case "maxItems":
+		s.maxItems, err = count(value, at)
+	case "minLength":
+		s.minLength, err = count(value, at)
+	case "maxLength":
+		s.maxLength, err = count(value, at)
+	case "pattern":
+		pattern, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: must be a regular expression", at)
+		}
+		if s.pattern, err = regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%s: %w", at, err)
+		}
+	case "minimum":
+		s.minimum, err = number(value, at)
+	case "maximum":
+		s.maximum, err = number(value, at)
+	case "exclusiveMinimum":
+		s.exclusiveMinimum, err = number(value, at)
+	case "exclusiveMaximum":
+		s.exclusiveMaximum, err = number(value, at)
+	default:
+		if !annotations[key] {
+			return fmt.Errorf("%s: unsupported keyword %q", at, key)
+		}
+	}
+	return err
+}
+
+// Validate checks a decoded JSON value against the schema, returning a description of each
+// violation, prefixed with the dotted path of the offending value under root. It returns nil
+// when the value is valid.
+func (s *Schema) Validate(value interface{}, root string) []string {
+	var errs []string
+	s.validate(normalize(value), root, &errs)
+	return errs
+}
+
+func (s *Schema) validate(value interface{}, path string, errs *[]string) {
+	fail := func(format string, args ...interface{}) {
+		if len(*errs) < maxErrors {
+			*errs = append(*errs, path+": "+fmt.Sprintf(format, args...))
+		}
+	}
+	if s.never {
+		fail("not allowed")
+		return
+	}
+
+	if len(s.types) > 0 && !s.hasType(value) {
+		fail("must be %s, got %s", strings.Join(s.types, " or "), typeOf(value))
+		return
+	}
+	if s.constant != nil && !equal(value, s.constant) {
+		fail("must be %s", s.constant)
+	}
+	if s.enum != nil {
+		found := false
+		for _, allowed := range s.enum {
+			if equal(value, allowed) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			fail("must be one of %s", joinRaw(s.enum))
+		}
+	}
+
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.required {
+			if _, ok := value[name]; !ok {
+				fail("%s is required", name)
+			}
+		}
+		for _, name := range sortedKeys(value) {
+			child := path + "." + name
+			if prop, ok := s.properties[name]; ok {
+				prop.validate(value[name], child, errs)
+			} else if s.noAdditional {
+				fail("%s is not allowed", name)
+			} else if s.additional != nil {
+				s.additional.validate(value[name], child, errs)
+			}
+		}
+	case []interface{}:
+		if s.minItems != nil && len(value) < *s.minItems {
+			fail("must have at least %d items, got %d", *s.minItems, len(value))
+		}
+		if s.maxItems != nil && len(value) > *s.maxItems {
+			fail("must have at most %d items, got %d", *s.maxItems, len(value))
+		}
+		if s.items != nil {
+			for i, item := range value {
+				s.items.validate(item, fmt.Sprintf("%s[%d]", path, i), errs)
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(value)
+		if s.minLength != nil && length < *s.minLength {
+			fail("must be at least %d characters, got %d", *s.minLength, length)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			fail("must be at most %d characters, got %d", *s.maxLength, length)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(value) {
+			fail("must match %s", s.pattern)
+		}
+	case float64:
+		if s.minimum != nil && value < *s.minimum {
+			fail("must be at least %g, got %g", *s.minimum, value)
+		}
+		if s.maximum != nil && value > *s.maximum {
+			fail("must be at most %g, got %g", *s.maximum, value)
+		}
+		if s.exclusiveMinimum != nil && value <= *s.exclusiveMinimum {
+			fail("must be greater than %g, got %g", *s.exclusiveMinimum, value)
+		}
+		if s.exclusiveMaximum != nil && value >= *s.exclusiveMaximum {
+			fail("must be less than %g, got %g", *s.exclusiveMaximum, value)
+		}
+	}
+}
+
+// hasType reports whether a value is of one of the schema's types
+func (s *Schema) hasType(value interface{}) bool {
+	actual := typeOf(value)
+	for _, t := range s.types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+// typeOf returns the JSON type of a normalized value, integer for whole numbers
+func typeOf(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if value == math.Trunc(value) && !math.IsInf(value, 0) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// normalize turns values set by parsers and processors, such as ints and string maps, into the
+// types encoding/json decodes to
+func normalize(value interface{}) interface{} {
+	switch value := value.(type) {
+	case nil, bool, string, float64:
+		return value
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			normalized[k] = normalize(v)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(value))
+		for i, v := range value {
+			normalized[i] = normalize(v)
+		}
+		return normalized
+	default:
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return value
+		}
+		var decoded interface{}
+		if json.Unmarshal(encoded, &decoded) != nil {
+			return value
+		}
+		return decoded
+	}
+}
+
+// equal compares a value with an encoded one, as JSON
+func equal(value interface{}, encoded json.RawMessage) bool {
+	actual, err := json.Marshal(value)
+	return err == nil && bytes.Equal(actual, encoded)
+}
+
+func joinRaw(values []json.RawMessage) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = string(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func count(value interface{}, at string) (*int, error) {
+	n, ok := value.(float64)
+	if !ok || n < 0 || n != math.Trunc(n) {
+		return nil, fmt.Errorf("%s: must be a non-negative integer", at)
+	}
+	i := int(n)
+	return &i, nil
+}
+
+func number(value interface{}, at string) (*float64, error) {
+	n, ok := value.(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be a number", at)
+	}
+	return &n, nil
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
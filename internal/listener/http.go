@@ -0,0 +1,119 @@
+package listener
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// HTTPOptions configures an HTTP listener
+type HTTPOptions struct {
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// TLSCertFile and TLSKeyFile enable HTTPS when both are set
+	TLSCertFile string
+	TLSKeyFile  string
+	// HTTP2 serves HTTP/2 alongside HTTP/1.1, negotiated over TLS or as cleartext h2c without it
+	HTTP2 bool
+}
+
+// HTTPListener serves an http.Handler. A new http.Server is created on every start,
+// so the listener can be stopped and started again.
+type HTTPListener struct {
+	name    string
+	kind    string
+	addr    string
+	handler http.Handler
+	opts    HTTPOptions
+
+	mu     sync.Mutex
+	server *http.Server
+}
+
+// NewHTTP creates an HTTP listener of the given kind
+func NewHTTP(name, kind, addr string, handler http.Handler, opts HTTPOptions) *HTTPListener {
+	return &HTTPListener{
+		name:    name,
+		kind:    kind,
+		addr:    addr,
+		handler: handler,
+		opts:    opts,
+	}
+}
+
+// Name returns the listener name
+func (l *HTTPListener) Name() string { return l.name }
+
+// Kind returns the listener kind
+func (l *HTTPListener) Kind() string { return l.kind }
+
+// Addr returns the address the listener binds to
+func (l *HTTPListener) Addr() string { return l.addr }
+
+// TLS reports whether the listener serves HTTPS
+func (l *HTTPListener) TLS() bool { return l.opts.TLSCertFile != "" && l.opts.TLSKeyFile != "" }
+
+// Start binds the address and serves in the background
+func (l *HTTPListener) Start(fail func(error)) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	handler := l.handler
+	if l.opts.HTTP2 && !l.TLS() {
+		handler = h2c.NewHandler(handler, &http2.Server{})
+	}
+	server := &http.Server{
+		Addr:         l.addr,
+		Handler:      handler,
+		ReadTimeout:  l.opts.ReadTimeout,
+		WriteTimeout: l.opts.WriteTimeout,
+	}
+
+	// Load certificates up front so a bad path fails the start instead of the background goroutine
+	if l.TLS() {
+		cert, err := tls.LoadX509KeyPair(l.opts.TLSCertFile, l.opts.TLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("error loading TLS certificate: %w", err)
+		}
+		server.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+		if l.opts.HTTP2 {
+			server.TLSConfig.NextProtos = []string{"h2", "http/1.1"}
+		}
+	}
+
+	ln, err := net.Listen("tcp", l.addr)
+	if err != nil {
+		return err
+	}
+	if server.TLSConfig != nil {
+		ln = tls.NewListener(ln, server.TLSConfig)
+	}
+
+	l.server = server
+	go func() {
+		if err := server.Serve(ln); err != nil && err != http.ErrServerClosed {
+			fail(err)
+		}
+	}()
+	return nil
+}
+
+// Stop gracefully shuts the server down
+func (l *HTTPListener) Stop(ctx context.Context) error {
+	l.mu.Lock()
+	server := l.server
+	l.server = nil
+	l.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+	return server.Shutdown(ctx)
+}
@@ -0,0 +1,210 @@
+package listener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Listener states reported by the manager
+const (
+	StateStopped = "stopped"
+	StateRunning = "running"
+	StateFailed  = "failed"
+)
+
+// ErrUnknownListener is returned for operations on a listener name that was never added
+var ErrUnknownListener = errors.New("unknown listener")
+
+// Listener is an input the service accepts data on, such as an HTTP server or a syslog socket
+type Listener interface {
+	Name() string
+	Kind() string
+	Addr() string
+	TLS() bool
+	// Start binds the listener and serves in the background. Errors after binding are reported through fail.
+	Start(fail func(error)) error
+	// Stop stops accepting connections and waits for in-flight work until ctx is done
+	Stop(ctx context.Context) error
+}
+
+// Status describes a listener's configuration and current state
+type Status struct {
+	Name      string     `json:"name"`
+	Kind      string     `json:"kind"`
+	Addr      string     `json:"addr"`
+	TLS       bool       `json:"tls"`
+	State     string     `json:"state"`
+	Error     string     `json:"error,omitempty"`
+	StartedAt *time.Time `json:"started_at,omitempty"`
+}
+
+type entry struct {
+	listener  Listener
+	state     string
+	err       error
+	startedAt time.Time
+	// stopped is set when the listener was stopped on purpose, rather than not started yet
+	stopped bool
+}
+
+// Manager starts, stops and reports on a set of listeners
+type Manager struct {
+	mu      sync.Mutex
+	entries []*entry
+}
+
+// NewManager creates an empty listener manager
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// Add registers a listener without starting it
+func (m *Manager) Add(l Listener) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.entries {
+		if e.listener.Name() == l.Name() {
+			return fmt.Errorf("listener %q is already registered", l.Name())
+		}
+	}
+	m.entries = append(m.entries, &entry{listener: l, state: StateStopped})
+	return nil
+}
+
+// StartAll starts every stopped listener in the order they were added, stopping at the first failure
+func (m *Manager) StartAll() error {
+	for _, status := range m.Statuses() {
+		if status.State == StateRunning {
+			continue
+		}
+		if err := m.Start(status.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Start starts a single listener
+func (m *Manager) Start(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.find(name)
+	if e == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownListener, name)
+	}
+	if e.state == StateRunning {
+		return nil
+	}
+
+	if err := e.listener.Start(func(err error) { m.fail(e, err) }); err != nil {
+		e.state = StateFailed
+		e.err = err
+		return fmt.Errorf("error starting listener %s on %s: %w", name, e.listener.Addr(), err)
+	}
+	e.state = StateRunning
+	e.err = nil
+	e.startedAt = time.Now()
+	log.Printf("Listener %s (%s) started on %s", name, e.listener.Kind(), e.listener.Addr())
+	return nil
+}
+
+// Stop stops a single listener
+func (m *Manager) Stop(ctx context.Context, name string) error {
+	m.mu.Lock()
+	e := m.find(name)
+	m.mu.Unlock()
+	if e == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownListener, name)
+	}
+
+	err := e.listener.Stop(ctx)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.state = StateStopped
+	e.stopped = true
+	if err != nil {
+		return fmt.Errorf("error stopping listener %s: %w", name, err)
+	}
+	log.Printf("Listener %s stopped", name)
+	return nil
+}
+
+// StopAll stops every listener in reverse order, returning the first error
+func (m *Manager) StopAll(ctx context.Context) error {
+	statuses := m.Statuses()
+	var firstErr error
+	for i := len(statuses) - 1; i >= 0; i-- {
+		if statuses[i].State != StateRunning {
+			continue
+		}
+		if err := m.Stop(ctx, statuses[i].Name); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Statuses reports every listener in the order they were added
+func (m *Manager) Statuses() []Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	statuses := make([]Status, 0, len(m.entries))
+	for _, e := range m.entries {
+		status := Status{
+			Name:  e.listener.Name(),
+			Kind:  e.listener.Kind(),
+			Addr:  e.listener.Addr(),
+			TLS:   e.listener.TLS(),
+			State: e.state,
+		}
+		if e.err != nil {
+			status.Error = e.err.Error()
+		}
+		if e.state == StateRunning {
+			startedAt := e.startedAt
+			status.StartedAt = &startedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Ready reports whether every listener has been started and none has failed. Listeners
+// stopped on purpose, such as from the admin API, do not make the service unready.
+func (m *Manager) Ready() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.entries {
+		if e.state == StateFailed || (e.state == StateStopped && !e.stopped) {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *Manager) fail(e *entry, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	log.Printf("ERROR: Listener %s failed: %v", e.listener.Name(), err)
+	e.state = StateFailed
+	e.err = err
+}
+
+func (m *Manager) find(name string) *entry {
+	for _, e := range m.entries {
+		if e.listener.Name() == name {
+			return e
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+// Package logschema holds the JSON schemas registered for services and API keys, which the
+// metadata of incoming logs must satisfy, so producers cannot silently break the fields
+// dashboards and alerts rely on.
+package logschema
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/jsonschema"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/internal/validator"
+	"log-ingestion-service/pkg/models"
+	"sync"
+	"time"
+)
+
+// refreshInterval bounds how long a schema changed on another instance takes to apply here
+const refreshInterval = 30 * time.Second
+
+// loadTimeout bounds reloading schemas, which are looked up without a request context
+const loadTimeout = 5 * time.Second
+
+// Registry keeps the compiled schemas by service and by API key
+type Registry struct {
+	repo *storage.Repository
+
+	mu        sync.Mutex
+	byService map[string]*validator.MetadataSchema
+	byKey     map[string]*validator.MetadataSchema
+	loadedAt  time.Time
+}
+
+// New creates a registry and loads the stored schemas, so they apply from the first log
+func New(ctx context.Context, repo *storage.Repository) (*Registry, error) {
+	r := &Registry{repo: repo}
+	if err := r.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Check reports whether a schema is complete and compiles
+func Check(schema *models.LogSchema) error {
+	if (schema.Service == nil) == (schema.APIKeyID == nil) {
+		return fmt.Errorf("exactly one of service and api_key_id is required")
+	}
+	if schema.Service != nil && *schema.Service == "" {
+		return fmt.Errorf("service must not be empty")
+	}
+	if schema.Mode != models.SchemaReject && schema.Mode != models.SchemaFlag {
+		return fmt.Errorf("mode must be %s or %s", models.SchemaReject, models.SchemaFlag)
+	}
+	_, err := jsonschema.Compile(schema.Schema)
+	return err
+}
+
+// MetadataSchemas returns the schemas for the logs of a service sent with an API key, the
+// service's first. If schemas cannot be reloaded the last known ones are used.
+func (r *Registry) MetadataSchemas(service, apiKey string) []*validator.MetadataSchema {
+	r.mu.Lock()
+	stale := time.Since(r.loadedAt) >= refreshInterval
+	if stale {
+		// One caller reloads; the others carry on with the current schemas
+		r.loadedAt = time.Now()
+	}
+	r.mu.Unlock()
+	if stale {
+		ctx, cancel := context.WithTimeout(context.Background(), loadTimeout)
+		defer cancel()
+		if err := r.Reload(ctx); err != nil {
+			log.Printf("WARN: Failed to load log schemas: %v", err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var schemas []*validator.MetadataSchema
+	if schema, ok := r.byService[service]; ok {
+		schemas = append(schemas, schema)
+	}
+	if apiKey != "" {
+		if schema, ok := r.byKey[apiKey]; ok {
+			schemas = append(schemas, schema)
+		}
+	}
+	return schemas
+}
+
+// Reload reads the stored schemas and compiles them. Schemas that no longer compile are
+// skipped and logged.
+func (r *Registry) Reload(ctx context.Context) error {
+	stored, err := r.repo.ListLogSchemas(ctx)
+	if err != nil {
+		return err
+	}
+
+	byService := make(map[string]*validator.MetadataSchema)
+	byKey := make(map[string]*validator.MetadataSchema)
+	for _, s := range stored {
+		compiled, err := jsonschema.Compile(s.Schema)
+		if err != nil {
+			log.Printf("WARN: Skipping log schema %d: %v", s.ID, err)
+			continue
+		}
+		schema := &validator.MetadataSchema{Schema: compiled, Flag: s.Mode == models.SchemaFlag}
+		switch {
+		case s.Service != nil:
+			byService[*s.Service] = schema
+		case s.APIKey != "":
+			byKey[s.APIKey] = schema
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.byService = byService
+	r.byKey = byKey
+	r.loadedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,299 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log-ingestion-service/internal/ingest/otlp"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PriorityClass groups routes that share a concurrency limit. Lower values have higher priority.
+type PriorityClass int
+
+// Priority classes, highest priority first
+const (
+	ClassIngest PriorityClass = iota
+	ClassQuery
+	ClassAnalytics
+	numClasses
+
+	// ClassUnlimited marks routes that bypass concurrency limits (health checks, static files)
+	ClassUnlimited PriorityClass = -1
+)
+
+// classNames are used in metrics
+var classNames = [numClasses]string{"ingest", "query", "analytics"}
+
+func (pc PriorityClass) String() string {
+	if pc >= 0 && pc < numClasses {
+		return classNames[pc]
+	}
+	return "unlimited"
+}
+
+// ingestRoutes are the SDK-compatible endpoints outside /api/v1 that accept notices with POST
+var ingestRoutes = map[string]bool{
+	// Bugsnag notify
+	"/": true,
+	// Rollbar items
+	"/api/1/item":  true,
+	"/api/1/item/": true,
+	// Airbrake v3 notices
+	"/api/v3/projects/:id/notices": true,
+}
+
+// analyticsRoutes are aggregate queries that scan many rows
+var analyticsRoutes = map[string]bool{
+	"/admin/logs/export":            true,
+	"/admin/metrics":                true,
+	"/admin/retention/preview":      true,
+	"/admin/storage":                true,
+	"/admin/stats":                  true,
+	"/api/v1/faults/facets":         true,
+	"/api/v1/faults/:id/stats":      true,
+	"/api/v1/projects/:id/activity": true,
+}
+
+// shedRetryAfter is the wait suggested to clients whose request was shed
+const shedRetryAfter = time.Second
+
+// errShed is returned when a request cannot be admitted in time
+var errShed = errors.New("server is at its concurrency limit")
+
+// ClassStats reports admission counts for one priority class
+type ClassStats struct {
+	Class    string `json:"class"`
+	Limit    int    `json:"limit"`
+	InFlight int    `json:"in_flight"`
+	Waiting  int    `json:"waiting"`
+	Admitted int64  `json:"admitted"`
+	Delayed  int64  `json:"delayed"`
+	Shed     int64  `json:"shed"`
+}
+
+type waiter struct {
+	ready    chan struct{}
+	admitted bool
+}
+
+// ConcurrencyLimiter bounds the number of requests handled at once. Each class may use up to its
+// own limit of the shared capacity; when capacity frees up, queued requests are admitted in
+// priority order so ingestion is not starved by dashboard and analytics queries.
+type ConcurrencyLimiter struct {
+	mu       sync.Mutex
+	config   *config.ConcurrencyConfig
+	limits   [numClasses]int
+	inFlight int
+	classes  [numClasses]struct {
+		inFlight int
+		queue    []*waiter
+		admitted int64
+		delayed  int64
+		shed     int64
+	}
+}
+
+var (
+	sharedConcurrency   = make(map[*config.ConcurrencyConfig]*ConcurrencyLimiter)
+	sharedConcurrencyMu sync.Mutex
+)
+
+// NewConcurrencyLimiter creates a concurrency limiter
+func NewConcurrencyLimiter(cfg *config.ConcurrencyConfig) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		config: cfg,
+		limits: [numClasses]int{cfg.IngestLimit, cfg.QueryLimit, cfg.AnalyticsLimit},
+	}
+}
+
+// SharedConcurrencyLimiter returns the process-wide limiter for cfg, so every listener draws
+// from the same capacity
+func SharedConcurrencyLimiter(cfg *config.ConcurrencyConfig) *ConcurrencyLimiter {
+	sharedConcurrencyMu.Lock()
+	defer sharedConcurrencyMu.Unlock()
+
+	cl, exists := sharedConcurrency[cfg]
+	if !exists {
+		cl = NewConcurrencyLimiter(cfg)
+		sharedConcurrency[cfg] = cl
+	}
+	return cl
+}
+
+// Acquire admits a request of the given class, waiting in its queue if necessary.
+// The returned function must be called when the request completes.
+func (cl *ConcurrencyLimiter) Acquire(ctx context.Context, class PriorityClass) (func(), error) {
+	cl.mu.Lock()
+	if cl.canAdmit(class) && !cl.queuedAtOrAbove(class) {
+		cl.admit(class)
+		cl.mu.Unlock()
+		return cl.releaser(class), nil
+	}
+
+	state := &cl.classes[class]
+	if len(state.queue) >= cl.config.QueueSize {
+		state.shed++
+		cl.mu.Unlock()
+		return nil, errShed
+	}
+	w := &waiter{ready: make(chan struct{})}
+	state.queue = append(state.queue, w)
+	state.delayed++
+	cl.mu.Unlock()
+
+	timer := time.NewTimer(cl.config.QueueTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-w.ready:
+		return cl.releaser(class), nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	// The slot may have been granted while we were timing out
+	if w.admitted {
+		return cl.releaser(class), nil
+	}
+	cl.removeWaiter(class, w)
+	state.shed++
+	return nil, errShed
+}
+
+// Stats reports per-class admission counts
+func (cl *ConcurrencyLimiter) Stats() []ClassStats {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+
+	stats := make([]ClassStats, 0, numClasses)
+	for class := PriorityClass(0); class < numClasses; class++ {
+		state := cl.classes[class]
+		stats = append(stats, ClassStats{
+			Class:    class.String(),
+			Limit:    cl.limits[class],
+			InFlight: state.inFlight,
+			Waiting:  len(state.queue),
+			Admitted: state.admitted,
+			Delayed:  state.delayed,
+			Shed:     state.shed,
+		})
+	}
+	return stats
+}
+
+func (cl *ConcurrencyLimiter) canAdmit(class PriorityClass) bool {
+	return cl.inFlight < cl.config.MaxInFlight && cl.classes[class].inFlight < cl.limits[class]
+}
+
+// queuedAtOrAbove reports whether requests of this or a higher priority are already waiting
+func (cl *ConcurrencyLimiter) queuedAtOrAbove(class PriorityClass) bool {
+	for c := PriorityClass(0); c <= class; c++ {
+		if len(cl.classes[c].queue) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (cl *ConcurrencyLimiter) admit(class PriorityClass) {
+	cl.inFlight++
+	cl.classes[class].inFlight++
+	cl.classes[class].admitted++
+}
+
+func (cl *ConcurrencyLimiter) releaser(class PriorityClass) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			cl.mu.Lock()
+			defer cl.mu.Unlock()
+
+			cl.inFlight--
+			cl.classes[class].inFlight--
+			cl.dispatch()
+		})
+	}
+}
+
+// dispatch admits queued requests in priority order while capacity allows
+func (cl *ConcurrencyLimiter) dispatch() {
+	for class := PriorityClass(0); class < numClasses; class++ {
+		state := &cl.classes[class]
+		for len(state.queue) > 0 && cl.canAdmit(class) {
+			w := state.queue[0]
+			state.queue = state.queue[1:]
+			w.admitted = true
+			cl.admit(class)
+			close(w.ready)
+		}
+	}
+}
+
+func (cl *ConcurrencyLimiter) removeWaiter(class PriorityClass, w *waiter) {
+	queue := cl.classes[class].queue
+	for i, queued := range queue {
+		if queued == w {
+			cl.classes[class].queue = append(queue[:i], queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// ClassifyRoute assigns a route to a priority class from its method and route pattern
+func ClassifyRoute(method, route string) PriorityClass {
+	switch {
+	case route == "" || route == "/health" || route == "/readyz":
+		return ClassUnlimited
+	case route == "/api/v1/logs/stream":
+		// A log stream is held open indefinitely, so it takes no slot and has no deadline
+		return ClassUnlimited
+	case method == http.MethodPost && (strings.HasPrefix(route, "/api/v1/logs") || strings.HasPrefix(route, "/api/v1/notices")):
+		return ClassIngest
+	case method == http.MethodPost && (strings.HasPrefix(route, "/api/v1/otlp/") || route == otlp.HTTPPath || route == otlp.GRPCPath):
+		return ClassIngest
+	case method == http.MethodPost && ingestRoutes[route]:
+		return ClassIngest
+	case analyticsRoutes[route]:
+		return ClassAnalytics
+	case strings.HasPrefix(route, "/api/") || strings.HasPrefix(route, "/admin") || strings.HasPrefix(route, "/auth"):
+		return ClassQuery
+	}
+	return ClassUnlimited
+}
+
+// Concurrency middleware limits concurrent requests per priority class, queueing briefly and
+// shedding with 503 when a class stays at its limit
+func Concurrency(cfg *config.ConcurrencyConfig) gin.HandlerFunc {
+	limiter := SharedConcurrencyLimiter(cfg)
+
+	return func(c *gin.Context) {
+		if !cfg.Enabled {
+			c.Next()
+			return
+		}
+
+		class := ClassifyRoute(c.Request.Method, c.FullPath())
+		if class == ClassUnlimited {
+			c.Next()
+			return
+		}
+
+		release, err := limiter.Acquire(c.Request.Context(), class)
+		if err != nil {
+			problem.RespondRetry(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "Server busy", err, shedRetryAfter)
+			return
+		}
+		defer release()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestClassifyRoute(t *testing.T) {
+	cases := []struct {
+		method string
+		route  string
+		want   PriorityClass
+	}{
+		{http.MethodGet, "/health", ClassUnlimited},
+		{http.MethodGet, "/api/v1/logs/stream", ClassUnlimited},
+		{http.MethodPost, "/api/v1/logs/batch", ClassIngest},
+		{http.MethodPost, "/api/v1/notices", ClassIngest},
+		{http.MethodPost, "/", ClassIngest},
+		{http.MethodGet, "/", ClassUnlimited},
+		{http.MethodPost, "/api/1/item", ClassIngest},
+		{http.MethodPost, "/api/1/item/", ClassIngest},
+		{http.MethodPost, "/api/v3/projects/:id/notices", ClassIngest},
+		{http.MethodGet, "/admin/stats", ClassAnalytics},
+		{http.MethodGet, "/api/v1/faults", ClassQuery},
+	}
+	for _, tc := range cases {
+		if got := ClassifyRoute(tc.method, tc.route); got != tc.want {
+			t.Errorf("ClassifyRoute(%s, %q) = %d, want %d", tc.method, tc.route, got, tc.want)
+		}
+	}
+}
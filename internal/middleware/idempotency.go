@@ -0,0 +1,293 @@
+package middleware
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader is the request header clients use to make a POST safely retryable
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayHeader marks responses replayed from the idempotency store
+const IdempotentReplayHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLength bounds the size of client-supplied keys
+const maxIdempotencyKeyLength = 255
+
+// idempotencyEntry records the outcome of the first request made with a key
+type idempotencyEntry struct {
+	key         string
+	fingerprint string
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+	expiresAt   time.Time
+	// element is the entry's place in the store's order
+	element *list.Element
+}
+
+// IdempotencyStore keeps recent idempotency keys and their responses in memory, bounded in
+// keys and response bytes
+type IdempotencyStore struct {
+	entries map[string]*idempotencyEntry
+	// order holds the entries oldest first
+	order     *list.List
+	bytes     int64
+	mu        sync.Mutex
+	config    *config.IdempotencyConfig
+	lastSweep time.Time
+}
+
+var (
+	sharedIdempotencyStores   = make(map[*config.IdempotencyConfig]*IdempotencyStore)
+	sharedIdempotencyStoresMu sync.Mutex
+)
+
+// NewIdempotencyStore creates a new idempotency store
+func NewIdempotencyStore(cfg *config.IdempotencyConfig) *IdempotencyStore {
+	return &IdempotencyStore{
+		entries:   make(map[string]*idempotencyEntry),
+		order:     list.New(),
+		config:    cfg,
+		lastSweep: time.Now(),
+	}
+}
+
+// SharedIdempotencyStore returns the process-wide store for cfg, so every route group keeps its
+// keys within the same bounds
+func SharedIdempotencyStore(cfg *config.IdempotencyConfig) *IdempotencyStore {
+	sharedIdempotencyStoresMu.Lock()
+	defer sharedIdempotencyStoresMu.Unlock()
+
+	s, exists := sharedIdempotencyStores[cfg]
+	if !exists {
+		s = NewIdempotencyStore(cfg)
+		sharedIdempotencyStores[cfg] = s
+	}
+	return s
+}
+
+// begin reserves key for a new request. If the key is already known the existing
+// entry is returned and the caller must not run the handler.
+func (s *IdempotencyStore) begin(key, fingerprint string) (*idempotencyEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > s.config.TTL {
+		for _, e := range s.entries {
+			if e.done && now.After(e.expiresAt) {
+				s.removeLocked(e)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if e, exists := s.entries[key]; exists {
+		if !e.done || now.Before(e.expiresAt) {
+			copied := *e
+			return &copied, false
+		}
+		s.removeLocked(e)
+	}
+
+	e := &idempotencyEntry{key: key, fingerprint: fingerprint}
+	e.element = s.order.PushBack(e)
+	s.entries[key] = e
+	s.evictLocked()
+	return nil, true
+}
+
+// finish stores the response for key, or forgets the key if the response should not be replayed
+func (s *IdempotencyStore) finish(key string, status int, contentType string, body []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, exists := s.entries[key]
+	if !exists {
+		return
+	}
+
+	// Server errors are not cached so the client can retry with the same key, and responses
+	// that would not fit are not either
+	if status >= http.StatusInternalServerError || int64(len(body)) > s.config.MaxStoredBytes {
+		s.removeLocked(e)
+		return
+	}
+
+	e.done = true
+	e.status = status
+	e.contentType = contentType
+	e.body = body
+	e.expiresAt = time.Now().Add(s.config.TTL)
+	s.bytes += int64(len(body))
+	s.evictLocked()
+}
+
+// evictLocked forgets the oldest finished entries until the store is within its bounds. Entries
+// still in progress are kept, as forgetting them would let a retry run alongside; there are at
+// most as many as requests being served.
+func (s *IdempotencyStore) evictLocked() {
+	element := s.order.Front()
+	for element != nil && (len(s.entries) > s.config.MaxEntries || s.bytes > s.config.MaxStoredBytes) {
+		e := element.Value.(*idempotencyEntry)
+		element = element.Next()
+		if e.done {
+			s.removeLocked(e)
+		}
+	}
+}
+
+// forget removes key, so a retry runs the request again
+func (s *IdempotencyStore) forget(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, exists := s.entries[key]; exists {
+		s.removeLocked(e)
+	}
+}
+
+func (s *IdempotencyStore) removeLocked(e *idempotencyEntry) {
+	delete(s.entries, e.key)
+	s.order.Remove(e.element)
+	s.bytes -= int64(len(e.body))
+}
+
+// capturingWriter copies the response body so it can be stored for replay. It stops copying
+// once the body outgrows limit, as the store would not keep it.
+type capturingWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	limit    int64
+	tooLarge bool
+}
+
+func (w *capturingWriter) Write(b []byte) (int, error) {
+	w.capture(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *capturingWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *capturingWriter) capture(b []byte) {
+	if w.tooLarge {
+		return
+	}
+	if int64(w.body.Len()+len(b)) > w.limit {
+		w.tooLarge = true
+		w.body = bytes.Buffer{}
+		return
+	}
+	w.body.Write(b)
+}
+
+// Idempotency middleware makes POST requests carrying an Idempotency-Key header safe to retry.
+// The first response for a key is stored and replayed for repeats from the same caller;
+// reusing a key with a different request body is rejected.
+func Idempotency(cfg *config.IdempotencyConfig) gin.HandlerFunc {
+	if !cfg.Enabled {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	store := SharedIdempotencyStore(cfg)
+
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" || c.Request.Method != http.MethodPost {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			problem.RespondDetail(c, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid Idempotency-Key",
+				fmt.Sprintf("key must be at most %d characters", maxIdempotencyKeyLength))
+			return
+		}
+
+		// The body is buffered to fingerprint it, so it is bounded here; handlers with lower
+		// limits, such as avatar uploads, still apply them to the buffered copy
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, cfg.MaxBodyBytes))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				problem.Respond(c, http.StatusRequestEntityTooLarge, problem.CodeInvalidRequest, "Request body too large",
+					fmt.Errorf("requests with an %s must be at most %d bytes", IdempotencyKeyHeader, cfg.MaxBodyBytes))
+				return
+			}
+			problem.BadRequest(c, "Failed to read request body", err)
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scopedKey := idempotencyScope(c) + "|" + key
+		sum := sha256.Sum256(append([]byte(c.Request.Method+" "+c.Request.URL.Path+"\n"), body...))
+		fingerprint := hex.EncodeToString(sum[:])
+
+		existing, reserved := store.begin(scopedKey, fingerprint)
+		if !reserved {
+			switch {
+			case existing.fingerprint != fingerprint:
+				problem.RespondDetail(c, http.StatusUnprocessableEntity, problem.CodeInvalidRequest, "Idempotency-Key reused",
+					"the key was already used for a different request")
+			case !existing.done:
+				problem.RespondDetail(c, http.StatusConflict, problem.CodeConflict, "Request in progress",
+					"a request with this Idempotency-Key is still being processed")
+			default:
+				c.Header(IdempotentReplayHeader, "true")
+				c.Data(existing.status, existing.contentType, existing.body)
+				c.Abort()
+			}
+			return
+		}
+
+		writer := &capturingWriter{ResponseWriter: c.Writer, limit: cfg.MaxStoredBytes}
+		c.Writer = writer
+
+		// Release the key if the handler panics so the client can retry
+		defer func() {
+			if r := recover(); r != nil {
+				store.forget(scopedKey)
+				panic(r)
+			}
+		}()
+
+		c.Next()
+
+		if writer.tooLarge {
+			store.forget(scopedKey)
+			return
+		}
+		store.finish(scopedKey, writer.Status(), writer.Header().Get("Content-Type"), writer.body.Bytes())
+	}
+}
+
+// idempotencyScope identifies the caller so keys from different clients never collide
+func idempotencyScope(c *gin.Context) string {
+	if userID, exists := c.Get("user_id"); exists {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	if apiKey, exists := c.Get("api_key"); exists {
+		sum := sha256.Sum256([]byte(fmt.Sprint(apiKey)))
+		return "key:" + hex.EncodeToString(sum[:8])
+	}
+	return "anonymous:" + c.ClientIP()
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"fmt"
+	"log-ingestion-service/pkg/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newTestIdempotencyStore(maxEntries int, maxStoredBytes int64) *IdempotencyStore {
+	return NewIdempotencyStore(&config.IdempotencyConfig{Enabled: true, TTL: time.Minute, MaxEntries: maxEntries, MaxStoredBytes: maxStoredBytes})
+}
+
+// known reports whether the store still replays or holds key
+func known(s *IdempotencyStore, key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, exists := s.entries[key]
+	return exists
+}
+
+func TestIdempotencyStoreEvictsOldestEntries(t *testing.T) {
+	s := newTestIdempotencyStore(3, 1<<20)
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprint(i)
+		s.begin(key, "fp")
+		s.finish(key, http.StatusCreated, "application/json", []byte("{}"))
+	}
+	for i, want := range []bool{false, false, true, true, true} {
+		if got := known(s, fmt.Sprint(i)); got != want {
+			t.Errorf("key %d kept = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestIdempotencyStoreBoundsResponseBytes(t *testing.T) {
+	s := newTestIdempotencyStore(100, 10)
+	for _, key := range []string{"a", "b", "c"} {
+		s.begin(key, "fp")
+		s.finish(key, http.StatusOK, "text/plain", []byte("1234"))
+	}
+	if known(s, "a") || !known(s, "b") || !known(s, "c") {
+		t.Errorf("kept a=%v b=%v c=%v, want only b and c", known(s, "a"), known(s, "b"), known(s, "c"))
+	}
+	if s.bytes != 8 {
+		t.Errorf("stored %d bytes, want 8", s.bytes)
+	}
+
+	// A response that could never fit is not stored, leaving the others
+	s.begin("large", "fp")
+	s.finish("large", http.StatusOK, "text/plain", []byte("12345678901"))
+	if known(s, "large") || !known(s, "b") {
+		t.Errorf("kept large=%v b=%v, want only b", known(s, "large"), known(s, "b"))
+	}
+}
+
+func TestIdempotencyStoreKeepsEntriesInProgress(t *testing.T) {
+	s := newTestIdempotencyStore(1, 1<<20)
+	s.begin("running", "fp")
+	s.begin("next", "fp")
+	if !known(s, "running") {
+		t.Fatal("an entry in progress was evicted")
+	}
+	if existing, reserved := s.begin("running", "fp"); reserved || existing.done {
+		t.Fatalf("begin() on a running key = %+v, %v, want the running entry", existing, reserved)
+	}
+}
+
+// TestIdempotencySharedAcrossGroups checks that route groups set up with the same configuration
+// keep their keys in one store
+func TestIdempotencySharedAcrossGroups(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &config.IdempotencyConfig{Enabled: true, TTL: time.Minute, MaxBodyBytes: 1 << 10, MaxEntries: 10, MaxStoredBytes: 1 << 10}
+	router := gin.New()
+	calls := 0
+	handler := func(c *gin.Context) {
+		calls++
+		c.String(http.StatusCreated, "created %d", calls)
+	}
+	router.Group("/a", Idempotency(cfg)).POST("", handler)
+	router.Group("/b", Idempotency(cfg)).POST("", handler)
+
+	if SharedIdempotencyStore(cfg) != SharedIdempotencyStore(cfg) {
+		t.Fatal("SharedIdempotencyStore() returned different stores for one configuration")
+	}
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader("{}"))
+		req.Header.Set(IdempotencyKeyHeader, "k")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	if w := post("/a"); w.Code != http.StatusCreated {
+		t.Fatalf("POST /a = %d, want 201", w.Code)
+	}
+	if w := post("/a"); w.Body.String() != "created 1" || w.Header().Get(IdempotentReplayHeader) != "true" {
+		t.Fatalf("retried POST /a = %q, want the first response replayed", w.Body.String())
+	}
+	// The key belongs to the request to /a, in whichever group it is reused
+	if w := post("/b"); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("POST /b with the key of /a = %d, want 422", w.Code)
+	}
+	if got := len(SharedIdempotencyStore(cfg).entries); got != 1 {
+		t.Fatalf("store holds %d keys, want 1", got)
+	}
+}
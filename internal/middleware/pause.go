@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"errors"
+	"log-ingestion-service/internal/pause"
+	"log-ingestion-service/internal/problem"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pausedRetryAfter is the wait suggested to clients while ingestion is paused
+const pausedRetryAfter = 30 * time.Second
+
+// errIngestionPaused is returned for ingestion requests while the ingestion pipeline is paused
+var errIngestionPaused = errors.New("ingestion is paused by an administrator")
+
+// IngestionPause middleware answers ingestion requests with 503 and Retry-After while the
+// ingestion pipeline is paused, so clients buffer and retry. Validation-only routes stay available.
+func IngestionPause(controller *pause.Controller) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if ClassifyRoute(c.Request.Method, route) != ClassIngest || strings.HasSuffix(route, "/validate") {
+			c.Next()
+			return
+		}
+		if controller.Paused(c.Request.Context(), pause.Ingestion) {
+			problem.RespondRetry(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "Ingestion paused", errIngestionPaused, pausedRetryAfter)
+			return
+		}
+		c.Next()
+	}
+}
@@ -1,9 +1,16 @@
 package middleware
 
 import (
+	"fmt"
+	"log-ingestion-service/internal/problem"
 	"log-ingestion-service/pkg/config"
+	"math"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
@@ -12,18 +19,57 @@ import (
 // RateLimiter manages rate limiting per API key
 type RateLimiter struct {
 	limiters map[string]*rate.Limiter
+	stats    map[string]*KeyStats
 	mu       sync.RWMutex
+	statsMu  sync.Mutex
 	config   *config.RateLimitConfig
 }
 
+// KeyStats counts allowed and throttled requests for one caller
+type KeyStats struct {
+	Key             string     `json:"key"`
+	Allowed         int64      `json:"allowed"`
+	Throttled       int64      `json:"throttled"`
+	LastThrottledAt *time.Time `json:"last_throttled_at,omitempty"`
+}
+
+// LimitStatus describes a caller's current position in their rate limit bucket
+type LimitStatus struct {
+	Enabled           bool      `json:"enabled"`
+	RequestsPerSecond float64   `json:"requests_per_second"`
+	Burst             int       `json:"burst"`
+	Remaining         int       `json:"remaining"`
+	ResetAt           time.Time `json:"reset_at"`
+}
+
+var (
+	sharedLimiters   = make(map[*config.RateLimitConfig]*RateLimiter)
+	sharedLimitersMu sync.Mutex
+)
+
 // NewRateLimiter creates a new rate limiter
 func NewRateLimiter(cfg *config.RateLimitConfig) *RateLimiter {
 	return &RateLimiter{
 		limiters: make(map[string]*rate.Limiter),
+		stats:    make(map[string]*KeyStats),
 		config:   cfg,
 	}
 }
 
+// SharedRateLimiter returns the process-wide limiter for cfg, so every route group
+// draws from the same bucket for a given caller
+func SharedRateLimiter(cfg *config.RateLimitConfig) *RateLimiter {
+	sharedLimitersMu.Lock()
+	defer sharedLimitersMu.Unlock()
+	
+	rl, exists := sharedLimiters[cfg]
+	if !exists {
+		rl = NewRateLimiter(cfg)
+		sharedLimiters[cfg] = rl
+	}
+	return rl
+}
+
 // getLimiter returns or creates a limiter for the given API key
 func (rl *RateLimiter) getLimiter(apiKey string) *rate.Limiter {
 	rl.mu.RLock()
@@ -51,6 +97,118 @@ func (rl *RateLimiter) getLimiter(apiKey string) *rate.Limiter {
 	return limiter
 }
 
+// Status reports the remaining requests for a key and when its bucket will be full again
+func (rl *RateLimiter) Status(key string) LimitStatus {
+	now := time.Now()
+	status := LimitStatus{
+		Enabled:           rl.config.Enabled,
+		RequestsPerSecond: float64(rl.config.DefaultRPS),
+		Burst:             rl.config.Burst,
+		Remaining:         rl.config.Burst,
+		ResetAt:           now,
+	}
+	if !rl.config.Enabled {
+		return status
+	}
+	
+	tokens := rl.getLimiter(key).TokensAt(now)
+	if tokens < 0 {
+		tokens = 0
+	}
+	status.Remaining = int(math.Floor(tokens))
+	
+	if missing := float64(rl.config.Burst) - tokens; missing > 0 && rl.config.DefaultRPS > 0 {
+		status.ResetAt = now.Add(time.Duration(missing / float64(rl.config.DefaultRPS) * float64(time.Second)))
+	}
+	return status
+}
+
+// RetryAfter returns how long a key must wait before its next request is allowed
+func (rl *RateLimiter) RetryAfter(key string) time.Duration {
+	if rl.config.DefaultRPS <= 0 {
+		return time.Second
+	}
+	tokens := rl.getLimiter(key).Tokens()
+	if tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - tokens) / float64(rl.config.DefaultRPS) * float64(time.Second))
+}
+
+// record updates the throttling statistics for a key
+func (rl *RateLimiter) record(key string, allowed bool) {
+	rl.statsMu.Lock()
+	defer rl.statsMu.Unlock()
+	
+	s, exists := rl.stats[key]
+	if !exists {
+		s = &KeyStats{Key: maskLimiterKey(key)}
+		rl.stats[key] = s
+	}
+	if allowed {
+		s.Allowed++
+		return
+	}
+	now := time.Now()
+	s.Throttled++
+	s.LastThrottledAt = &now
+}
+
+// Stats returns per-key throttling statistics, most throttled first, capped at limit entries
+func (rl *RateLimiter) Stats(limit int) []KeyStats {
+	rl.statsMu.Lock()
+	out := make([]KeyStats, 0, len(rl.stats))
+	for _, s := range rl.stats {
+		out = append(out, *s)
+	}
+	rl.statsMu.Unlock()
+	
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Throttled != out[j].Throttled {
+			return out[i].Throttled > out[j].Throttled
+		}
+		return out[i].Allowed > out[j].Allowed
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out
+}
+
+// maskLimiterKey hides all but a short prefix of API keys in statistics
+func maskLimiterKey(key string) string {
+	if strings.HasPrefix(key, "user:") || strings.HasPrefix(key, "public_token:") || key == "anonymous" {
+		return key
+	}
+	if len(key) <= 8 {
+		return "****"
+	}
+	return key[:8] + "****"
+}
+
+// LimiterKey identifies the caller whose bucket a request draws from
+func LimiterKey(c *gin.Context) string {
+	if apiKey, exists := c.Get("api_key"); exists {
+		if s, ok := apiKey.(string); ok && s != "" {
+			return s
+		}
+	}
+	if userID, exists := c.Get("user_id"); exists {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	if tokenID, exists := c.Get("public_token_id"); exists {
+		return fmt.Sprintf("public_token:%v", tokenID)
+	}
+	return "anonymous"
+}
+
+// setRateLimitHeaders advertises the caller's limit so clients can pace themselves
+func setRateLimitHeaders(c *gin.Context, status LimitStatus) {
+	c.Header("X-RateLimit-Limit", strconv.Itoa(status.Burst))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(status.Remaining))
+	c.Header("X-RateLimit-Reset", strconv.FormatInt(status.ResetAt.Unix(), 10))
+}
+
 // RateLimit middleware enforces rate limiting
 func RateLimit(cfg *config.RateLimitConfig) gin.HandlerFunc {
 	if !cfg.Enabled {
@@ -59,30 +217,22 @@ func RateLimit(cfg *config.RateLimitConfig) gin.HandlerFunc {
 		}
 	}
 	
-	limiter := NewRateLimiter(cfg)
+	limiter := SharedRateLimiter(cfg)
 	
 	return func(c *gin.Context) {
-		apiKey, exists := c.Get("api_key")
-		if !exists {
-			apiKey = "anonymous"
-		}
+		key := LimiterKey(c)
 		
-		apiKeyStr, ok := apiKey.(string)
-		if !ok {
-			apiKeyStr = "anonymous"
-		}
+		l := limiter.getLimiter(key)
+		allowed := l.Allow()
+		limiter.record(key, allowed)
 		
-		l := limiter.getLimiter(apiKeyStr)
+		setRateLimitHeaders(c, limiter.Status(key))
 		
-		if !l.Allow() {
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "Rate limit exceeded",
-			})
-			c.Abort()
+		if !allowed {
+			problem.RespondRetry(c, http.StatusTooManyRequests, problem.CodeRateLimited, "Rate limit exceeded", nil, limiter.RetryAfter(key))
 			return
 		}
 		
 		c.Next()
 	}
 }
-
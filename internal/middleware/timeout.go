@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"log-ingestion-service/internal/problem"
+	"log-ingestion-service/pkg/config"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamingRoutes write their response as they go for as long as it takes, bounding each
+// write instead, so they have no deadline unless one is configured for the route
+var streamingRoutes = map[string]bool{
+	"/admin/logs/export": true,
+}
+
+// RouteTimeout returns the handler deadline for a route: an explicit "METHOD /route" entry,
+// otherwise the default for the route's priority class. Zero means no deadline.
+// Route keys are matched case-insensitively since configuration keys are lowercased when loaded.
+func RouteTimeout(cfg *config.TimeoutConfig, method, route string) time.Duration {
+	if d, ok := cfg.Routes[strings.ToLower(method+" "+route)]; ok {
+		return d
+	}
+	if streamingRoutes[route] {
+		return 0
+	}
+	switch ClassifyRoute(method, route) {
+	case ClassIngest:
+		return cfg.Ingest
+	case ClassQuery:
+		return cfg.Query
+	case ClassAnalytics:
+		return cfg.Analytics
+	}
+	return 0
+}
+
+// Timeout middleware bounds each request with a context deadline. Handlers pass the request
+// context to the database, so slow queries are cancelled and the client gets a 503 timeout
+// problem instead of waiting indefinitely.
+func Timeout(cfg *config.TimeoutConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		d := RouteTimeout(cfg, c.Request.Method, c.FullPath())
+		if d <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), d)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			problem.Timeout(c, ctx.Err())
+		}
+	}
+}
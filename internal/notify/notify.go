@@ -0,0 +1,202 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Event types delivered to notifiers
+const (
+	EventFaultCreated   = "fault.created"
+	EventFaultEscalated = "fault.escalated"
+	EventFaultSLAAtRisk = "fault.sla_at_risk"
+	EventTest           = "test"
+)
+
+// Event is a single notification delivered to every configured notifier
+type Event struct {
+	Type       string        `json:"type"`
+	Test       bool          `json:"test"`
+	Message    string        `json:"message"`
+	Fault      *models.Fault `json:"fault,omitempty"`
+	OccurredAt time.Time     `json:"occurred_at"`
+}
+
+// Notifier delivers events to one integration
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, event Event) error
+}
+
+// Result is the outcome of delivering an event to one notifier
+type Result struct {
+	Notifier   string `json:"notifier"`
+	Delivered  bool   `json:"delivered"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Dispatcher fans events out to all configured notifiers
+type Dispatcher struct {
+	notifiers []Notifier
+	timeout   time.Duration
+	paused    func() bool
+}
+
+// NewDispatcher creates a dispatcher with the log notifier plus one webhook notifier per configured URL
+func NewDispatcher(cfg *config.NotificationConfig) *Dispatcher {
+	client := &http.Client{Timeout: cfg.Timeout}
+
+	notifiers := []Notifier{LogNotifier{}}
+	hosts := make(map[string]int)
+	for _, webhookURL := range cfg.WebhookURLs {
+		notifiers = append(notifiers, &WebhookNotifier{URL: webhookURL, name: webhookName(webhookURL, hosts), client: client})
+	}
+
+	return &Dispatcher{
+		notifiers: notifiers,
+		timeout:   cfg.Timeout,
+	}
+}
+
+// PauseWhen makes DispatchAsync drop events while paused reports true. Dispatch, used for
+// explicit sends such as test alerts, is not affected.
+func (d *Dispatcher) PauseWhen(paused func() bool) {
+	d.paused = paused
+}
+
+// Dispatch delivers an event to every notifier concurrently and waits for the results
+func (d *Dispatcher) Dispatch(ctx context.Context, event Event) []Result {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	results := make([]Result, len(d.notifiers))
+	var wg sync.WaitGroup
+	for i, n := range d.notifiers {
+		wg.Add(1)
+		go func(i int, n Notifier) {
+			defer wg.Done()
+			start := time.Now()
+			err := n.Notify(ctx, event)
+			results[i] = Result{
+				Notifier:   n.Name(),
+				Delivered:  err == nil,
+				DurationMS: time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}(i, n)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// DispatchAsync delivers an event in the background, logging delivery failures
+func (d *Dispatcher) DispatchAsync(event Event) {
+	if d.paused != nil && d.paused() {
+		log.Printf("INFO: Notifications paused, dropped %s: %s", event.Type, event.Message)
+		return
+	}
+	go func() {
+		for _, r := range d.Dispatch(context.Background(), event) {
+			if !r.Delivered {
+				log.Printf("WARN: Notifier %s failed to deliver %s: %s", r.Notifier, event.Type, r.Error)
+			}
+		}
+	}()
+}
+
+// LogNotifier writes events to the server log
+type LogNotifier struct{}
+
+// Name returns the notifier name
+func (LogNotifier) Name() string {
+	return "log"
+}
+
+// Notify logs the event
+func (LogNotifier) Notify(ctx context.Context, event Event) error {
+	prefix := ""
+	if event.Test {
+		prefix = "[test] "
+	}
+	log.Printf("NOTIFY: %s%s: %s", prefix, event.Type, event.Message)
+	return nil
+}
+
+// WebhookNotifier POSTs events as JSON to a URL
+type WebhookNotifier struct {
+	URL    string
+	name   string
+	client *http.Client
+}
+
+// Name returns the notifier name. It shows only the URL's host, as webhook URLs often embed a
+// token, and names end up in logs and test results.
+func (w *WebhookNotifier) Name() string {
+	return w.name
+}
+
+// webhookName names a webhook after its host, numbering webhooks that share one
+func webhookName(webhookURL string, hosts map[string]int) string {
+	host := "invalid-url"
+	if u, err := url.Parse(webhookURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	hosts[host]++
+	if n := hosts[host]; n > 1 {
+		return fmt.Sprintf("webhook:%s#%d", host, n)
+	}
+	return "webhook:" + host
+}
+
+// Notify posts the event and treats any non-2xx response as a failure
+func (w *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error encoding event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating webhook request: %w", urlFree(err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Type", event.Type)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending webhook: %w", urlFree(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// urlFree drops the URL that net/http errors quote, as it may hold the webhook's token
+func urlFree(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s: %w", urlErr.Op, urlErr.Err)
+	}
+	return err
+}
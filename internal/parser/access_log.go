@@ -0,0 +1,179 @@
+package parser
+
+import (
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// NameAccessLog is the built-in parser for Apache and Nginx access logs in the common or
+// combined format
+const NameAccessLog = "access_log"
+
+// accessLogLayout is the time layout of access log timestamps, e.g. 10/Oct/2000:13:55:36 -0700
+const accessLogLayout = "02/Jan/2006:15:04:05 -0700"
+
+// accessLogPattern matches the common log format, optionally followed by the referer and user
+// agent of the combined format and by any further fields
+var accessLogPattern = regexp.MustCompile(`^(\S+) (\S+) (\S+) \[([^\]]+)\] "((?:[^"\\]|\\.)*)" (\d{3}) (\d+|-)(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?(.*)$`)
+
+// accessLogLatencyKeys name trailing fields holding the request time in seconds, as logged by
+// nginx's $request_time
+var accessLogLatencyKeys = map[string]bool{"rt": true, "request_time": true}
+
+// accessLogUpstreamKeys name trailing fields holding nginx's $upstream_response_time in seconds
+var accessLogUpstreamKeys = map[string]bool{"urt": true, "upstream_response_time": true}
+
+// AccessLogParser parses Apache and Nginx access log lines. The client IP, method, path, query,
+// protocol, status, response size, referer, user agent and latency go to metadata, and the
+// level follows from the status: ERROR for 5xx, WARN for 4xx and INFO otherwise.
+//
+// Latency is read from fields after the combined format: a bare decimal number is the request
+// time in seconds (nginx's $request_time), a bare integer is microseconds (Apache's %D), and
+// rt= or request_time= hold seconds. Other key=value fields are kept in metadata.
+type AccessLogParser struct {
+	timestamps *TimestampParser
+}
+
+// NewAccessLogParser creates a new access log parser
+func NewAccessLogParser(timestamps *TimestampParser) *AccessLogParser {
+	return &AccessLogParser{timestamps: timestamps}
+}
+
+// Parse parses a single access log line
+func (p *AccessLogParser) Parse(data []byte) (*models.LogEntry, error) {
+	line := strings.TrimSpace(string(data))
+	match := accessLogPattern.FindStringSubmatch(line)
+	if match == nil {
+		return nil, fmt.Errorf("line is not in the common or combined access log format")
+	}
+	client, user, stamp, request, statusText, size := match[1], match[3], match[4], match[5], match[6], match[7]
+
+	timestamp, err := p.timestamps.ParseLayout(accessLogLayout, stamp)
+	if err != nil {
+		return nil, err
+	}
+	status, _ := strconv.Atoi(statusText)
+
+	logEntry := models.LogEntry{
+		Timestamp: timestamp,
+		Service:   UnknownService,
+		Level:     accessLogLevel(status),
+		Metadata: map[string]interface{}{
+			"client_ip": client,
+			"status":    status,
+		},
+	}
+	if user != "-" {
+		logEntry.Metadata["user"] = user
+	}
+	if size != "-" {
+		if n, err := strconv.ParseInt(size, 10, 64); err == nil {
+			logEntry.Metadata["bytes"] = n
+		}
+	}
+	if referer := match[8]; referer != "" && referer != "-" {
+		logEntry.Metadata["referer"] = referer
+	}
+	if agent := match[9]; agent != "" && agent != "-" {
+		logEntry.Metadata["user_agent"] = agent
+	}
+
+	method, target, protocol := splitRequestLine(request)
+	if method == "" {
+		logEntry.Metadata["request"] = request
+		logEntry.Message = fmt.Sprintf("\"%s\" %d", request, status)
+	} else {
+		path, query, _ := strings.Cut(target, "?")
+		logEntry.Metadata["method"] = method
+		logEntry.Metadata["path"] = path
+		if query != "" {
+			logEntry.Metadata["query"] = query
+		}
+		if protocol != "" {
+			logEntry.Metadata["protocol"] = protocol
+		}
+		logEntry.Message = fmt.Sprintf("%s %s %d", method, path, status)
+	}
+
+	readAccessLogExtras(strings.TrimSpace(match[10]), logEntry.Metadata)
+	return &logEntry, nil
+}
+
+// accessLogLevel derives a level from an HTTP status
+func accessLogLevel(status int) string {
+	switch {
+	case status >= 500:
+		return "ERROR"
+	case status >= 400:
+		return "WARN"
+	default:
+		return "INFO"
+	}
+}
+
+// splitRequestLine splits "GET /path HTTP/1.1" into its parts. It returns an empty method for
+// malformed request lines, such as "-" or binary probes.
+func splitRequestLine(request string) (method, target, protocol string) {
+	parts := strings.Fields(request)
+	if len(parts) < 2 || len(parts) > 3 || strings.ToUpper(parts[0]) != parts[0] {
+		return "", "", ""
+	}
+	if len(parts) == 3 {
+		protocol = parts[2]
+	}
+	return parts[0], parts[1], protocol
+}
+
+// readAccessLogExtras reads the latency and key=value fields following the combined format.
+// Fields that cannot be read are kept as they are under "extra".
+func readAccessLogExtras(extras string, metadata map[string]interface{}) {
+	if extras == "" {
+		return
+	}
+	pairs, err := decodeLogfmt(extras)
+	if err != nil {
+		metadata["extra"] = extras
+		return
+	}
+
+	var unread []string
+	for _, pair := range pairs {
+		switch {
+		case pair.bare:
+			if strings.Contains(pair.key, ".") {
+				if seconds, err := strconv.ParseFloat(pair.key, 64); err == nil {
+					metadata["latency_ms"] = seconds * 1000
+					continue
+				}
+			} else if usec, err := strconv.ParseInt(pair.key, 10, 64); err == nil {
+				metadata["latency_ms"] = float64(usec) / 1000
+				continue
+			}
+			unread = append(unread, pair.key)
+		case accessLogLatencyKeys[pair.key]:
+			if seconds, ok := parseSeconds(pair.value); ok {
+				metadata["latency_ms"] = seconds * 1000
+			}
+		case accessLogUpstreamKeys[pair.key]:
+			if seconds, ok := parseSeconds(pair.value); ok {
+				metadata["upstream_latency_ms"] = seconds * 1000
+			}
+		default:
+			metadata[pair.key] = pair.value
+		}
+	}
+	if len(unread) > 0 {
+		metadata["extra"] = strings.Join(unread, " ")
+	}
+}
+
+// parseSeconds reads a time in seconds; nginx lists one per upstream tried ("0.100, 0.050"),
+// and the first is used
+func parseSeconds(value string) (float64, bool) {
+	first, _, _ := strings.Cut(value, ",")
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(first), 64)
+	return seconds, err == nil
+}
@@ -0,0 +1,291 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"strings"
+	"sync"
+)
+
+// numericLevels maps pino/bunyan numeric levels to names
+var numericLevels = map[int]string{
+	10: "DEBUG",
+	20: "DEBUG",
+	30: "INFO",
+	40: "WARN",
+	50: "ERROR",
+	60: "FATAL",
+}
+
+// FieldMapping lists alternative fields a JSON log may carry its core values in.
+// Paths are dotted; "log.level" matches a literal "log.level" key or a nested {"log": {"level": ...}}.
+type FieldMapping struct {
+	Level     []string
+	Service   []string
+	Timestamp []string
+	Message   []string
+
+	timestamps *TimestampParser
+	// serviceTimestamps holds the timestamp parsers of services with their own formats, by
+	// lowercase service name
+	serviceTimestamps map[string]*TimestampParser
+}
+
+// NewFieldMapping builds a field mapping from configuration
+func NewFieldMapping(cfg *config.ParserConfig) (FieldMapping, error) {
+	timestamps, err := NewTimestampParser(cfg.DefaultTimezone)
+	if err != nil {
+		return FieldMapping{}, err
+	}
+	serviceTimestamps := make(map[string]*TimestampParser, len(cfg.ServiceTimestamps))
+	for service, formats := range cfg.ServiceTimestamps {
+		p, err := timestamps.WithFormats(formats.Layouts, formats.Timezone)
+		if err != nil {
+			return FieldMapping{}, fmt.Errorf("parser.service_timestamps.%s: %w", service, err)
+		}
+		serviceTimestamps[strings.ToLower(service)] = p
+	}
+	return FieldMapping{
+		Level:             cfg.LevelFields,
+		Service:           cfg.ServiceFields,
+		Timestamp:         cfg.TimestampFields,
+		Message:           cfg.MessageFields,
+		timestamps:        timestamps,
+		serviceTimestamps: serviceTimestamps,
+	}, nil
+}
+
+// timestampsFor returns the timestamp parser for a log of service: timestamps when it is not
+// nil, else the service's own, else the default one
+func (m FieldMapping) timestampsFor(service string, timestamps *TimestampParser) *TimestampParser {
+	if timestamps != nil {
+		return timestamps
+	}
+	if p, ok := m.serviceTimestamps[strings.ToLower(service)]; ok {
+		return p
+	}
+	return m.timestamps
+}
+
+// standardLog is the shape of a log that uses the standard field names throughout
+type standardLog struct {
+	Timestamp json.RawMessage        `json:"timestamp"`
+	Service   string                 `json:"service"`
+	Level     string                 `json:"level"`
+	Message   string                 `json:"message"`
+	Metadata  map[string]interface{} `json:"metadata"`
+}
+
+// standardKeys are the JSON keys of standardLog
+var standardKeys = [][]byte{[]byte("timestamp"), []byte("service"), []byte("level"), []byte("message"), []byte("metadata")}
+
+// standardLogPool reuses the struct standard logs are decoded into
+var standardLogPool = sync.Pool{
+	New: func() interface{} { return new(standardLog) },
+}
+
+// decode unmarshals a JSON log, reading Level, Service, Timestamp and Message from the
+// standard fields or, when those are absent or not scalars, from the mapped fields.
+// Timestamps are read with timestamps when it is not nil, as for an API key with its own
+// formats. It does not apply defaults or require fields.
+func (m FieldMapping) decode(data []byte, timestamps *TimestampParser) (*models.LogEntry, error) {
+	if logEntry, ok := m.decodeStandard(data, timestamps); ok {
+		return logEntry, nil
+	}
+	return m.decodeFields(data, timestamps)
+}
+
+// decodeFields decodes a JSON log into a map and looks its core values up by exact key, first
+// under the standard names and then the mapped ones
+func (m FieldMapping) decodeFields(data []byte, timestamps *TimestampParser) (*models.LogEntry, error) {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON log: %w", err)
+	}
+
+	var logEntry models.LogEntry
+	if metadata, ok := fields["metadata"].(map[string]interface{}); ok {
+		logEntry.Metadata = metadata
+	}
+
+	if value, ok := lookupField(fields, append([]string{"level"}, m.Level...)); ok {
+		logEntry.Level = normalizeLevel(value)
+	}
+	if value, ok := lookupField(fields, append([]string{"service"}, m.Service...)); ok {
+		logEntry.Service = fmt.Sprint(value)
+	}
+	if value, ok := lookupString(fields, append([]string{"message"}, m.Message...)); ok {
+		logEntry.Message = value
+	}
+	if value, ok := lookupField(fields, append([]string{"timestamp"}, m.Timestamp...)); ok {
+		t, err := m.timestampsFor(logEntry.Service, timestamps).Parse(value)
+		if err != nil {
+			return nil, err
+		}
+		logEntry.Timestamp = t
+	}
+
+	return &logEntry, nil
+}
+
+// decodeStandard is the fast path for logs carrying all four core values as strings under the
+// standard names, which avoids decoding the whole object into a map. It reports false when the
+// log needs the field mapping, in which case the slow path decides the outcome.
+func (m FieldMapping) decodeStandard(data []byte, timestamps *TimestampParser) (*models.LogEntry, bool) {
+	std := standardLogPool.Get().(*standardLog)
+	defer standardLogPool.Put(std)
+	*std = standardLog{Timestamp: std.Timestamp[:0]}
+
+	if err := json.Unmarshal(data, std); err != nil {
+		return nil, false
+	}
+	// Unmarshal also fills the struct from keys such as "Level", which the slow path ignores
+	if hasFoldedStandardKey(data) {
+		return nil, false
+	}
+	if std.Service == "" || std.Level == "" || std.Message == "" || len(std.Timestamp) == 0 {
+		return nil, false
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(std.Timestamp, &value); err != nil || !isScalar(value) {
+		return nil, false
+	}
+	t, err := m.timestampsFor(std.Service, timestamps).Parse(value)
+	if err != nil {
+		return nil, false
+	}
+
+	return &models.LogEntry{
+		Timestamp: t,
+		Service:   std.Service,
+		Level:     normalizeLevel(std.Level),
+		Message:   std.Message,
+		Metadata:  std.Metadata,
+	}, true
+}
+
+// hasFoldedStandardKey reports whether a valid JSON object has a top-level key that differs from
+// a standard key but matches it ignoring case, as encoding/json matches struct fields
+func hasFoldedStandardKey(data []byte) bool {
+	depth := 0
+	key := false
+	for i := 0; i < len(data); i++ {
+		switch data[i] {
+		case '{':
+			depth++
+			key = depth == 1
+		case '[':
+			depth++
+		case '}', ']':
+			depth--
+		case ',':
+			key = depth == 1
+		case '"':
+			end := i + 1
+			for ; data[end] != '"'; end++ {
+				if data[end] == '\\' {
+					end++
+				}
+			}
+			if key && isFoldedStandardKey(data[i:end+1]) {
+				return true
+			}
+			key = false
+			i = end
+		}
+	}
+	return false
+}
+
+// isFoldedStandardKey reports whether a quoted JSON key matches a standard key only ignoring case
+func isFoldedStandardKey(quoted []byte) bool {
+	raw := quoted[1 : len(quoted)-1]
+	if bytes.IndexByte(raw, '\\') >= 0 || !isASCII(raw) {
+		// Escaped and non-ASCII keys are rare; compare them decoded, with Unicode folding
+		var key string
+		if err := json.Unmarshal(quoted, &key); err != nil {
+			return true
+		}
+		raw = []byte(key)
+	}
+	for _, standard := range standardKeys {
+		if bytes.EqualFold(raw, standard) && !bytes.Equal(raw, standard) {
+			return true
+		}
+	}
+	return false
+}
+
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupString returns the first non-empty string value found at any of the paths
+func lookupString(fields map[string]interface{}, paths []string) (string, bool) {
+	for _, path := range paths {
+		if value, ok := lookupField(fields, []string{path}); ok {
+			if s, isString := value.(string); isString {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
+
+// lookupField returns the first non-empty value found at any of the paths
+func lookupField(fields map[string]interface{}, paths []string) (interface{}, bool) {
+	for _, path := range paths {
+		if value, ok := fields[path]; ok && isScalar(value) {
+			return value, true
+		}
+
+		var current interface{} = fields
+		found := true
+		for _, part := range strings.Split(path, ".") {
+			object, isObject := current.(map[string]interface{})
+			if !isObject {
+				found = false
+				break
+			}
+			if current, found = object[part]; !found {
+				break
+			}
+		}
+		if found && isScalar(current) {
+			return current, true
+		}
+	}
+	return nil, false
+}
+
+// isScalar reports whether a decoded JSON value is a non-empty string, number or bool
+func isScalar(value interface{}) bool {
+	switch v := value.(type) {
+	case string:
+		return v != ""
+	case float64, bool:
+		return true
+	}
+	return false
+}
+
+// normalizeLevel converts a mapped severity, which may be a name or a number, to a level name.
+// Other numbers, such as syslog severities, and level aliases are mapped by the validator.
+func normalizeLevel(value interface{}) string {
+	if n, ok := value.(float64); ok {
+		if level, known := numericLevels[int(n)]; known {
+			return level
+		}
+		return fmt.Sprint(n)
+	}
+	return strings.ToUpper(strings.TrimSpace(fmt.Sprint(value)))
+}
@@ -0,0 +1,85 @@
+package parser
+
+import (
+	"log-ingestion-service/pkg/config"
+	"reflect"
+	"testing"
+)
+
+func benchmarkFieldMapping(b testing.TB) FieldMapping {
+	m, err := NewFieldMapping(&config.ParserConfig{
+		LevelFields:     []string{"severity", "lvl"},
+		ServiceFields:   []string{"app"},
+		TimestampFields: []string{"ts", "@timestamp"},
+		MessageFields:   []string{"msg"},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return m
+}
+
+func BenchmarkFieldMappingDecode(b *testing.B) {
+	m := benchmarkFieldMapping(b)
+	for _, tc := range []struct {
+		name string
+		data []byte
+	}{
+		// Standard field names take the fast path
+		{"standard", []byte(`{"timestamp":"2024-01-15T10:30:00.123Z","service":"checkout","level":"ERROR","message":"payment declined","metadata":{"order_id":42,"amount":12.5}}`)},
+		// Mapped field names need the map-based path
+		{"mapped", []byte(`{"ts":"2024-01-15T10:30:00.123Z","app":"checkout","severity":"ERROR","msg":"payment declined","order_id":42,"amount":12.5}`)},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(tc.data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := m.decode(tc.data, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestFieldMappingFastPath checks that logs the fast path decodes come out as the map-based
+// path decodes them, including logs with keys that differ from the standard ones only in case
+func TestFieldMappingFastPath(t *testing.T) {
+	m := benchmarkFieldMapping(t)
+	tests := []struct {
+		name string
+		data string
+		// fast is whether the fast path takes the log
+		fast bool
+	}{
+		{"standard", `{"timestamp":"2024-01-15T10:30:00Z","service":"checkout","level":"error","message":"declined","metadata":{"order_id":42}}`, true},
+		{"numeric timestamp", `{"timestamp":1705314600,"service":"checkout","level":"info","message":"placed"}`, true},
+		{"escaped standard key", `{"timestamp":"2024-01-15T10:30:00Z","service":"checkout","\u006cevel":"warn","message":"slow"}`, true},
+		{"folded key in metadata", `{"timestamp":"2024-01-15T10:30:00Z","service":"checkout","level":"info","message":"placed","metadata":{"Level":"debug","list":[{"MESSAGE":"x"}]}}`, true},
+		{"folded key in a value", `{"timestamp":"2024-01-15T10:30:00Z","service":"checkout","level":"info","message":"Level"}`, true},
+		{"capitalized keys with a mapped level", `{"timestamp":"2024-01-15T10:30:00Z","service":"checkout","Level":"debug","MESSAGE":"placed","severity":"error","msg":"declined"}`, false},
+		{"capitalized duplicate", `{"timestamp":"2024-01-15T10:30:00Z","service":"checkout","level":"info","message":"placed","Level":"error"}`, false},
+		{"capitalized metadata", `{"timestamp":"2024-01-15T10:30:00Z","service":"checkout","level":"info","message":"placed","Metadata":{"a":1}}`, false},
+		{"unicode folded key", `{"timestamp":"2024-01-15T10:30:00Z","ſervice":"billing","service":"checkout","level":"info","message":"placed"}`, false},
+		{"escaped folded key", `{"timestamp":"2024-01-15T10:30:00Z","service":"checkout","\u004cevel":"debug","level":"info","message":"placed"}`, false},
+		{"mapped fields", `{"ts":"2024-01-15T10:30:00Z","app":"checkout","severity":"error","msg":"declined"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := m.decodeFields([]byte(tt.data), nil)
+			if err != nil {
+				t.Fatalf("decodeFields() error = %v", err)
+			}
+			got, ok := m.decodeStandard([]byte(tt.data), nil)
+			if ok != tt.fast {
+				t.Fatalf("decodeStandard() took the log = %v, want %v", ok, tt.fast)
+			}
+			if ok && !reflect.DeepEqual(got, want) {
+				t.Fatalf("decodeStandard() = %+v, decodeFields() = %+v", got, want)
+			}
+			if decoded, err := m.decode([]byte(tt.data), nil); err != nil || !reflect.DeepEqual(decoded, want) {
+				t.Fatalf("decode() = %+v, %v, want %+v", decoded, err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,409 @@
+package parser
+
+import (
+	"fmt"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Grok pattern sources, from lowest to highest precedence
+const (
+	GrokBuiltin = "builtin"
+	GrokConfig  = "config"
+	GrokRuntime = "runtime"
+)
+
+// grokBuiltins is the common pattern library, adapted from Logstash's to RE2, which has no
+// lookaround or atomic groups
+var grokBuiltins = map[string]string{
+	"USERNAME":          `[a-zA-Z0-9._-]+`,
+	"USER":              `%{USERNAME}`,
+	"EMAILLOCALPART":    `[a-zA-Z0-9!#$%&'*+/=?^_{|}~-]+(?:\.[a-zA-Z0-9!#$%&'*+/=?^_{|}~-]+)*`,
+	"EMAILADDRESS":      `%{EMAILLOCALPART}@%{HOSTNAME}`,
+	"INT":               `[+-]?\d+`,
+	"BASE10NUM":         `[+-]?(?:\d+(?:\.\d*)?|\.\d+)`,
+	"NUMBER":            `%{BASE10NUM}`,
+	"BASE16NUM":         `[+-]?(?:0x)?[0-9A-Fa-f]+`,
+	"POSINT":            `\b[1-9]\d*\b`,
+	"NONNEGINT":         `\b\d+\b`,
+	"WORD":              `\b\w+\b`,
+	"NOTSPACE":          `\S+`,
+	"SPACE":             `\s*`,
+	"DATA":              `.*?`,
+	"GREEDYDATA":        `.*`,
+	"QUOTEDSTRING":      `(?:"(?:[^"\\]|\\.)*"|'(?:[^'\\]|\\.)*')`,
+	"QS":                `%{QUOTEDSTRING}`,
+	"UUID":              `[A-Fa-f0-9]{8}-(?:[A-Fa-f0-9]{4}-){3}[A-Fa-f0-9]{12}`,
+	"MAC":               `(?:[A-Fa-f0-9]{2}[:-]){5}[A-Fa-f0-9]{2}`,
+	"IPV4":              `(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)`,
+	"IPV6":              `(?:[0-9A-Fa-f]{0,4}:){2,7}(?:[0-9A-Fa-f]{1,4}|%{IPV4})?`,
+	"IP":                `(?:%{IPV4}|%{IPV6})`,
+	"HOSTNAME":          `\b[0-9A-Za-z][0-9A-Za-z-]{0,62}(?:\.[0-9A-Za-z][0-9A-Za-z-]{0,62})*\.?\b`,
+	"IPORHOST":          `(?:%{IP}|%{HOSTNAME})`,
+	"HOSTPORT":          `%{IPORHOST}:%{POSINT}`,
+	"UNIXPATH":          `(?:/[\w%!$@:.,+~-]*)+`,
+	"WINPATH":           `(?:[A-Za-z]+:|\\)(?:\\[^\\?*]*)+`,
+	"PATH":              `(?:%{UNIXPATH}|%{WINPATH})`,
+	"URIPROTO":          `[A-Za-z][A-Za-z0-9+.-]*`,
+	"URIHOST":           `%{IPORHOST}(?::%{POSINT})?`,
+	"URIPATH":           `(?:/[A-Za-z0-9$.+!*'(){},~:;=@#%&_-]*)+`,
+	"URIPARAM":          `\?[A-Za-z0-9$.+!*'|(){},~@#%&/=:;_?\[\]<>-]*`,
+	"URIPATHPARAM":      `%{URIPATH}(?:%{URIPARAM})?`,
+	"URI":               `%{URIPROTO}://(?:%{USER}(?::[^@]*)?@)?(?:%{URIHOST})?(?:%{URIPATHPARAM})?`,
+	"MONTH":             `\b(?:[Jj]an(?:uary)?|[Ff]eb(?:ruary)?|[Mm]ar(?:ch)?|[Aa]pr(?:il)?|[Mm]ay|[Jj]une?|[Jj]uly?|[Aa]ug(?:ust)?|[Ss]ep(?:tember)?|[Oo]ct(?:ober)?|[Nn]ov(?:ember)?|[Dd]ec(?:ember)?)\b`,
+	"MONTHNUM":          `(?:0?[1-9]|1[0-2])`,
+	"MONTHDAY":          `(?:0[1-9]|[12]\d|3[01]|[1-9])`,
+	"DAY":               `(?:Mon(?:day)?|Tue(?:sday)?|Wed(?:nesday)?|Thu(?:rsday)?|Fri(?:day)?|Sat(?:urday)?|Sun(?:day)?)`,
+	"YEAR":              `\d\d(?:\d\d)?`,
+	"HOUR":              `(?:2[0-3]|[01]?\d)`,
+	"MINUTE":            `[0-5]\d`,
+	"SECOND":            `(?:[0-5]?\d|60)(?:[:.,]\d+)?`,
+	"TIME":              `%{HOUR}:%{MINUTE}(?::%{SECOND})?`,
+	"ISO8601_TIMEZONE":  `(?:Z|[+-]%{HOUR}(?::?%{MINUTE})?)`,
+	"TIMESTAMP_ISO8601": `%{YEAR}-%{MONTHNUM}-%{MONTHDAY}[T ]%{HOUR}:?%{MINUTE}(?::?%{SECOND})?%{ISO8601_TIMEZONE}?`,
+	"DATE_US":           `%{MONTHNUM}[/-]%{MONTHDAY}[/-]%{YEAR}`,
+	"DATE_EU":           `%{MONTHDAY}[./-]%{MONTHNUM}[./-]%{YEAR}`,
+	"HTTPDATE":          `%{MONTHDAY}/%{MONTH}/%{YEAR}:%{TIME} %{INT}`,
+	"SYSLOGTIMESTAMP":   `%{MONTH} +%{MONTHDAY} %{TIME}`,
+	"PROG":              `[\x21-\x5a\x5c\x5e-\x7e]+`,
+	"SYSLOGPROG":        `%{PROG:program}(?:\[%{POSINT:pid}\])?`,
+	"LOGLEVEL":          `(?i:alert|trace|debug|notice|info(?:rmation)?|warn(?:ing)?|err(?:or)?|crit(?:ical)?|fatal|severe|emerg(?:ency)?)`,
+	"HTTPDUSER":         `(?:%{EMAILADDRESS}|%{USER})`,
+	"COMMONAPACHELOG":   `%{IPORHOST:clientip} %{HTTPDUSER:ident} %{USER:auth} \[%{HTTPDATE:timestamp}\] "(?:%{WORD:verb} %{NOTSPACE:request}(?: HTTP/%{NUMBER:httpversion})?|%{DATA:rawrequest})" %{NUMBER:response} (?:%{NUMBER:bytes}|-)`,
+	"COMBINEDAPACHELOG": `%{COMMONAPACHELOG} %{QS:referrer} %{QS:agent}`,
+}
+
+// grokReference matches %{NAME}, %{NAME:field} and %{NAME:field:type}, with type int or float
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::([^:}]+))?(?::(int|float))?\}`)
+
+// grokPatternName is the form of pattern names
+var grokPatternName = regexp.MustCompile(`^[A-Z0-9_]+$`)
+
+// grokMaxDepth bounds how deeply pattern references nest, which also catches reference cycles
+const grokMaxDepth = 32
+
+// GrokPatternInfo describes a pattern of the library
+type GrokPatternInfo struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	Source  string `json:"source"`
+}
+
+// GrokLibrary holds the grok pattern library and the grok parsers compiled from it. Runtime
+// patterns can be replaced while parsers are in use: every parser is recompiled once, and
+// the change is rejected unless all of them still compile.
+type GrokLibrary struct {
+	mu         sync.Mutex
+	configured map[string]string
+	runtime    map[string]string
+	parsers    []*GrokParser
+}
+
+// NewGrokLibrary creates the library from the built-in patterns and configured ones
+func NewGrokLibrary(patterns map[string]string) (*GrokLibrary, error) {
+	configured := make(map[string]string, len(patterns))
+	for name, pattern := range patterns {
+		configured[strings.ToUpper(name)] = pattern
+	}
+	l := &GrokLibrary{configured: configured, runtime: map[string]string{}}
+	if err := l.checkPatterns(l.merged(l.runtime), configured); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// NewParser compiles a declarative grok parser and keeps it up to date with the library
+func (l *GrokLibrary) NewParser(cfg config.CustomParserConfig, timestamps *TimestampParser) (*GrokParser, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expr, err := compileGrok(cfg.Grok, l.merged(l.runtime))
+	if err != nil {
+		return nil, fmt.Errorf("invalid grok expression for parser %q: %w", cfg.Name, err)
+	}
+
+	level := cfg.Level
+	if level == "" {
+		level = "INFO"
+	}
+	p := &GrokParser{
+		name:            cfg.Name,
+		grok:            cfg.Grok,
+		timestampFormat: cfg.TimestampFormat,
+		service:         cfg.Service,
+		level:           level,
+		timestamps:      timestamps,
+	}
+	p.expr.Store(expr)
+	l.parsers = append(l.parsers, p)
+	return p, nil
+}
+
+// Release stops keeping a parser up to date, once it is no longer used
+func (l *GrokLibrary) Release(p *GrokParser) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i, other := range l.parsers {
+		if other == p {
+			l.parsers = append(l.parsers[:i], l.parsers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Check reports whether the library and every parser would compile with these runtime patterns
+func (l *GrokLibrary) Check(runtime map[string]string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, err := l.recompile(runtime)
+	return err
+}
+
+// SetRuntimePatterns replaces the runtime patterns and recompiles every parser. Nothing
+// changes when a pattern or parser does not compile.
+func (l *GrokLibrary) SetRuntimePatterns(runtime map[string]string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	exprs, err := l.recompile(runtime)
+	if err != nil {
+		return err
+	}
+	l.runtime = normalizeGrokNames(runtime)
+	for i, p := range l.parsers {
+		p.expr.Store(exprs[i])
+	}
+	return nil
+}
+
+// Patterns lists the effective patterns by name, with where each comes from
+func (l *GrokLibrary) Patterns() []GrokPatternInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	infos := make(map[string]GrokPatternInfo)
+	for name, pattern := range grokBuiltins {
+		infos[name] = GrokPatternInfo{Name: name, Pattern: pattern, Source: GrokBuiltin}
+	}
+	for name, pattern := range l.configured {
+		infos[name] = GrokPatternInfo{Name: name, Pattern: pattern, Source: GrokConfig}
+	}
+	for name, pattern := range l.runtime {
+		infos[name] = GrokPatternInfo{Name: name, Pattern: pattern, Source: GrokRuntime}
+	}
+
+	list := make([]GrokPatternInfo, 0, len(infos))
+	for _, info := range infos {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// recompile compiles the runtime patterns and every parser against them
+func (l *GrokLibrary) recompile(runtime map[string]string) ([]*grokExpr, error) {
+	runtime = normalizeGrokNames(runtime)
+	patterns := l.merged(runtime)
+	if err := l.checkPatterns(patterns, runtime); err != nil {
+		return nil, err
+	}
+
+	exprs := make([]*grokExpr, len(l.parsers))
+	for i, p := range l.parsers {
+		expr, err := compileGrok(p.grok, patterns)
+		if err != nil {
+			return nil, fmt.Errorf("parser %q no longer compiles: %w", p.name, err)
+		}
+		exprs[i] = expr
+	}
+	return exprs, nil
+}
+
+// checkPatterns checks the names of patterns and that each compiles within the library
+func (l *GrokLibrary) checkPatterns(library, patterns map[string]string) error {
+	for name := range patterns {
+		if !grokPatternName.MatchString(name) {
+			return fmt.Errorf("invalid grok pattern name %q: use letters, digits and underscores", name)
+		}
+		if _, err := compileGrok("%{"+name+"}", library); err != nil {
+			return fmt.Errorf("invalid grok pattern %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// merged layers configured and runtime patterns over the built-in ones
+func (l *GrokLibrary) merged(runtime map[string]string) map[string]string {
+	patterns := make(map[string]string, len(grokBuiltins)+len(l.configured)+len(runtime))
+	for _, layer := range []map[string]string{grokBuiltins, l.configured, runtime} {
+		for name, pattern := range layer {
+			patterns[name] = pattern
+		}
+	}
+	return patterns
+}
+
+func normalizeGrokNames(patterns map[string]string) map[string]string {
+	normalized := make(map[string]string, len(patterns))
+	for name, pattern := range patterns {
+		normalized[strings.ToUpper(name)] = pattern
+	}
+	return normalized
+}
+
+// grokField is the log field a capture group fills, with the type to convert it to
+type grokField struct {
+	name string
+	typ  string
+}
+
+// grokExpr is a compiled grok expression; fields is indexed like the regexp's groups
+type grokExpr struct {
+	pattern *regexp.Regexp
+	fields  []grokField
+}
+
+// compileGrok expands the pattern references of a grok expression into a regular expression.
+// Plain (?P<name>...) groups are kept as fields too.
+func compileGrok(grok string, patterns map[string]string) (*grokExpr, error) {
+	var fields []grokField
+	expanded, err := expandGrok(grok, patterns, &fields, 0)
+	if err != nil {
+		return nil, err
+	}
+	pattern, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, err
+	}
+
+	expr := &grokExpr{pattern: pattern, fields: make([]grokField, len(pattern.SubexpNames()))}
+	for i, name := range pattern.SubexpNames() {
+		if index, ok := strings.CutPrefix(name, "grok"); ok {
+			if n, err := strconv.Atoi(index); err == nil && n < len(fields) {
+				expr.fields[i] = fields[n]
+				continue
+			}
+		}
+		expr.fields[i] = grokField{name: name}
+	}
+	return expr, nil
+}
+
+func expandGrok(grok string, patterns map[string]string, fields *[]grokField, depth int) (string, error) {
+	if depth > grokMaxDepth {
+		return "", fmt.Errorf("grok patterns nest too deeply or refer to themselves")
+	}
+
+	var expandErr error
+	expanded := grokReference.ReplaceAllStringFunc(grok, func(ref string) string {
+		if expandErr != nil {
+			return ""
+		}
+		match := grokReference.FindStringSubmatch(ref)
+		name, field, typ := strings.ToUpper(match[1]), match[2], match[3]
+		pattern, ok := patterns[name]
+		if !ok {
+			expandErr = fmt.Errorf("unknown grok pattern %q", match[1])
+			return ""
+		}
+		inner, err := expandGrok(pattern, patterns, fields, depth+1)
+		if err != nil {
+			expandErr = err
+			return ""
+		}
+		if field == "" {
+			return "(?:" + inner + ")"
+		}
+		*fields = append(*fields, grokField{name: field, typ: typ})
+		return fmt.Sprintf("(?P<grok%d>%s)", len(*fields)-1, inner)
+	})
+	return expanded, expandErr
+}
+
+// GrokParser is a declaratively defined parser that extracts fields with a grok expression.
+// The fields "timestamp", "level", "service" and "message" fill the entry; any other field is
+// stored in metadata, converted to a number when its reference has the int or float type.
+type GrokParser struct {
+	name            string
+	grok            string
+	timestampFormat string
+	service         string
+	level           string
+	timestamps      *TimestampParser
+	expr            atomic.Pointer[grokExpr]
+}
+
+// Parse extracts a log entry from a single line
+func (p *GrokParser) Parse(data []byte) (*models.LogEntry, error) {
+	expr := p.expr.Load()
+	line := strings.TrimRight(string(data), "\r\n")
+	match := expr.pattern.FindStringSubmatch(line)
+	if match == nil {
+		return nil, fmt.Errorf("line does not match grok expression")
+	}
+
+	logEntry := models.LogEntry{
+		Timestamp: time.Now(),
+		Service:   p.service,
+		Level:     p.level,
+		Message:   line,
+		Metadata:  make(map[string]interface{}),
+	}
+
+	for i, field := range expr.fields {
+		if field.name == "" || match[i] == "" {
+			continue
+		}
+		value := match[i]
+		switch field.name {
+		case "timestamp":
+			t, err := p.parseTimestamp(value)
+			if err != nil {
+				return nil, err
+			}
+			logEntry.Timestamp = t
+		case "level":
+			logEntry.Level = normalizeLevel(value)
+		case "service":
+			logEntry.Service = value
+		case "message":
+			logEntry.Message = value
+		default:
+			logEntry.Metadata[field.name] = convertGrokValue(value, field.typ)
+		}
+	}
+
+	return &logEntry, nil
+}
+
+func (p *GrokParser) parseTimestamp(value string) (time.Time, error) {
+	if p.timestampFormat != "" {
+		return p.timestamps.ParseLayout(p.timestampFormat, value)
+	}
+	return p.timestamps.ParseString(value)
+}
+
+// convertGrokValue converts a captured value to its declared type, keeping it as a string
+// when it does not convert
+func convertGrokValue(value, typ string) interface{} {
+	switch typ {
+	case "int":
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return n
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"context"
+	"log"
+	"log-ingestion-service/internal/storage"
+	"sync"
+	"time"
+)
+
+// grokSyncInterval bounds how long a pattern changed on another instance takes to apply here
+const grokSyncInterval = 30 * time.Second
+
+// GrokSync keeps the runtime patterns of a grok library in step with the ones stored from the
+// admin API
+type GrokSync struct {
+	repo    *storage.Repository
+	library *GrokLibrary
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu     sync.Mutex
+	loaded map[string]string
+}
+
+// NewGrokSync creates a sync for a library
+func NewGrokSync(repo *storage.Repository, library *GrokLibrary) *GrokSync {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &GrokSync{repo: repo, library: library, ctx: ctx, cancel: cancel}
+}
+
+// Start loads the stored patterns, then reloads them every 30 seconds
+func (s *GrokSync) Start() {
+	s.Reload(s.ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(grokSyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.Reload(s.ctx)
+			}
+		}
+	}()
+}
+
+// Shutdown stops reloading
+func (s *GrokSync) Shutdown() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Reload applies the stored patterns when they changed. Patterns that break a parser are
+// logged once and the library keeps its current ones.
+func (s *GrokSync) Reload(ctx context.Context) {
+	stored, err := s.repo.ListGrokPatterns(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("ERROR: Failed to load grok patterns: %v", err)
+		}
+		return
+	}
+	patterns := make(map[string]string, len(stored))
+	for _, p := range stored {
+		patterns[p.Name] = p.Pattern
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if sameGrokPatterns(s.loaded, patterns) {
+		return
+	}
+	if err := s.library.SetRuntimePatterns(patterns); err != nil {
+		log.Printf("ERROR: Stored grok patterns were not applied: %v", err)
+	}
+	s.loaded = patterns
+}
+
+func sameGrokPatterns(a, b map[string]string) bool {
+	if a == nil || len(a) != len(b) {
+		return false
+	}
+	for name, pattern := range a {
+		if other, ok := b[name]; !ok || other != pattern {
+			return false
+		}
+	}
+	return true
+}
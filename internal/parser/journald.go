@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// NameJournald is the built-in parser for `journalctl -o json` output, also selected by the "journald" source
+const NameJournald = "journald"
+
+// journaldPriorities maps syslog priorities (PRIORITY) to levels
+var journaldPriorities = map[string]string{
+	"0": "FATAL",    // emerg
+	"1": "FATAL",    // alert
+	"2": "CRITICAL", // crit
+	"3": "ERROR",    // err
+	"4": "WARN",     // warning
+	"5": "INFO",     // notice
+	"6": "INFO",     // info
+	"7": "DEBUG",    // debug
+}
+
+// journaldServiceFields are tried in order for the service name
+var journaldServiceFields = []string{"_SYSTEMD_UNIT", "_SYSTEMD_USER_UNIT", "SYSLOG_IDENTIFIER", "_COMM"}
+
+// journaldSkippedFields are cursors and bookkeeping fields not worth storing as metadata
+var journaldSkippedFields = map[string]bool{
+	"MESSAGE":                     true,
+	"PRIORITY":                    true,
+	"__CURSOR":                    true,
+	"__REALTIME_TIMESTAMP":        true,
+	"__MONOTONIC_TIMESTAMP":       true,
+	"__SEQNUM":                    true,
+	"__SEQNUM_ID":                 true,
+	"_SOURCE_REALTIME_TIMESTAMP":  true,
+	"_SOURCE_MONOTONIC_TIMESTAMP": true,
+}
+
+// JournaldParser parses entries in systemd-journald's JSON export format.
+// Field values are strings, arrays of byte values for binary or non-UTF-8 data, arrays of
+// either for fields that occur more than once, or null for values too large to export.
+type JournaldParser struct{}
+
+// NewJournaldParser creates a new journald parser
+func NewJournaldParser() *JournaldParser {
+	return &JournaldParser{}
+}
+
+// Parse parses a single journald JSON entry
+func (p *JournaldParser) Parse(data []byte) (*models.LogEntry, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse journald entry: %w", err)
+	}
+
+	fields := make(map[string]string, len(raw))
+	for key, value := range raw {
+		if s, ok := journaldValue(value); ok {
+			fields[key] = s
+		}
+	}
+
+	logEntry := models.LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Message:   fields["MESSAGE"],
+		Metadata:  make(map[string]interface{}),
+	}
+	if logEntry.Message == "" {
+		return nil, fmt.Errorf("journald entry has no MESSAGE")
+	}
+
+	// Prefer the time the message was logged over the time journald received it
+	for _, key := range []string{"_SOURCE_REALTIME_TIMESTAMP", "__REALTIME_TIMESTAMP"} {
+		if usec, err := strconv.ParseInt(fields[key], 10, 64); err == nil {
+			logEntry.Timestamp = time.UnixMicro(usec).UTC()
+			break
+		}
+	}
+
+	if level, ok := journaldPriorities[fields["PRIORITY"]]; ok {
+		logEntry.Level = level
+	}
+
+	for _, key := range journaldServiceFields {
+		if service := fields[key]; service != "" {
+			logEntry.Service = strings.TrimSuffix(service, ".service")
+			break
+		}
+	}
+	if logEntry.Service == "" {
+		logEntry.Service = UnknownService
+	}
+
+	for key, value := range fields {
+		if !journaldSkippedFields[key] {
+			logEntry.Metadata[strings.ToLower(strings.TrimLeft(key, "_"))] = value
+		}
+	}
+
+	return &logEntry, nil
+}
+
+// journaldValue converts an exported field value to a string. Binary values become UTF-8 text
+// when valid and are quoted otherwise; repeated fields keep their last value.
+func journaldValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []interface{}:
+		if b, ok := journaldBytes(v); ok {
+			if utf8.Valid(b) {
+				return string(b), true
+			}
+			return strconv.QuoteToASCII(string(b)), true
+		}
+		for i := len(v) - 1; i >= 0; i-- {
+			if s, ok := journaldValue(v[i]); ok {
+				return s, true
+			}
+		}
+	}
+	return "", false
+}
+
+// journaldBytes decodes an array of byte values
+func journaldBytes(values []interface{}) ([]byte, bool) {
+	if len(values) == 0 {
+		return nil, false
+	}
+	b := make([]byte, len(values))
+	for i, value := range values {
+		n, ok := value.(float64)
+		if !ok || n < 0 || n > 255 || n != float64(int(n)) {
+			return nil, false
+		}
+		b[i] = byte(n)
+	}
+	return b, true
+}
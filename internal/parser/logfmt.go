@@ -0,0 +1,174 @@
+package parser
+
+import (
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"strings"
+	"time"
+)
+
+// NameLogfmt is the built-in parser for logfmt lines such as `ts=... level=info msg="started"`
+const NameLogfmt = "logfmt"
+
+// logfmtPair is one key=value pair of a logfmt line; bare keys have no value
+type logfmtPair struct {
+	key   string
+	value string
+	bare  bool
+}
+
+// LogfmtParser parses logfmt lines. The level, ts and msg keys (and the time, message and
+// service keys) fill the entry; every other pair is stored in metadata, with bare keys as true.
+type LogfmtParser struct {
+	timestamps *TimestampParser
+}
+
+// NewLogfmtParser creates a new logfmt parser
+func NewLogfmtParser(timestamps *TimestampParser) *LogfmtParser {
+	return &LogfmtParser{timestamps: timestamps}
+}
+
+// Parse parses a logfmt line. The lines following the first, such as stack trace frames of a
+// joined multi-line log, are appended to the message as they are.
+func (p *LogfmtParser) Parse(data []byte) (*models.LogEntry, error) {
+	text := strings.TrimSpace(string(data))
+	if text == "" {
+		return nil, fmt.Errorf("empty log entry")
+	}
+	line, rest, _ := strings.Cut(text, "\n")
+	line = strings.TrimSpace(line)
+
+	pairs, err := decodeLogfmt(line)
+	if err != nil {
+		return nil, err
+	}
+
+	logEntry := models.LogEntry{
+		Timestamp: time.Now(),
+		Level:     "INFO",
+		Service:   UnknownService,
+		Metadata:  make(map[string]interface{}),
+	}
+	for _, pair := range pairs {
+		switch {
+		case pair.bare:
+			logEntry.Metadata[pair.key] = true
+		case pair.key == "level" || pair.key == "lvl":
+			// A blank level keeps the default
+			if level := normalizeLevel(pair.value); level != "" {
+				logEntry.Level = level
+			}
+		case pair.key == "ts" || pair.key == "time":
+			t, err := p.timestamps.ParseString(pair.value)
+			if err != nil {
+				return nil, err
+			}
+			logEntry.Timestamp = t
+		case pair.key == "msg" || pair.key == "message":
+			logEntry.Message = pair.value
+		case pair.key == "service" && pair.value != "":
+			logEntry.Service = pair.value
+		default:
+			logEntry.Metadata[pair.key] = pair.value
+		}
+	}
+
+	if logEntry.Message == "" {
+		logEntry.Message = line
+	}
+	if rest != "" {
+		logEntry.Message += "\n" + rest
+	}
+	return &logEntry, nil
+}
+
+// looksLikeLogfmt reports whether a line is made only of at least two key=value pairs, so that
+// text logs that merely contain a pair, such as "ERROR db: retries=3 exhausted", stay text
+func looksLikeLogfmt(line string) bool {
+	pairs, err := decodeLogfmt(line)
+	if err != nil || len(pairs) < 2 {
+		return false
+	}
+	for _, pair := range pairs {
+		if pair.bare {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeLogfmt splits a line into its pairs. Values may be double-quoted, with backslash escapes.
+func decodeLogfmt(line string) ([]logfmtPair, error) {
+	var pairs []logfmtPair
+	i := 0
+	for i < len(line) {
+		if line[i] == ' ' || line[i] == '\t' {
+			i++
+			continue
+		}
+
+		start := i
+		for i < len(line) && line[i] != '=' && line[i] != ' ' && line[i] != '\t' {
+			if line[i] == '"' {
+				return nil, fmt.Errorf("invalid logfmt key at offset %d", i)
+			}
+			i++
+		}
+		if i == start {
+			return nil, fmt.Errorf("missing logfmt key at offset %d", i)
+		}
+		pair := logfmtPair{key: line[start:i]}
+		if i == len(line) || line[i] != '=' {
+			pair.bare = true
+			pairs = append(pairs, pair)
+			continue
+		}
+		i++
+
+		if i < len(line) && line[i] == '"' {
+			value, next, err := decodeLogfmtQuoted(line, i)
+			if err != nil {
+				return nil, err
+			}
+			pair.value, i = value, next
+		} else {
+			start = i
+			for i < len(line) && line[i] != ' ' && line[i] != '\t' {
+				i++
+			}
+			pair.value = line[start:i]
+		}
+		pairs = append(pairs, pair)
+	}
+	return pairs, nil
+}
+
+// decodeLogfmtQuoted reads the quoted value starting at line[start], returning it unescaped
+// and the offset after its closing quote
+func decodeLogfmtQuoted(line string, start int) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(line); i++ {
+		switch c := line[i]; c {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\\':
+			i++
+			if i == len(line) {
+				break
+			}
+			switch line[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			default:
+				b.WriteByte(line[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated logfmt value at offset %d", start)
+}
@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log-ingestion-service/pkg/config"
+	"regexp"
+	"time"
+)
+
+// maxMultilineBytes ends a joined log once it reaches this size
+const maxMultilineBytes = 1 << 20
+
+// Multiline decides which text lines continue the log before them, so that a stack trace
+// arrives as one log instead of one per frame. Lines that look like JSON are never joined.
+type Multiline struct {
+	start        *regexp.Regexp
+	continuation *regexp.Regexp
+	timeout      time.Duration
+	maxLines     int
+}
+
+// NewMultiline compiles a multi-line configuration. It returns nil when no pattern is set.
+func NewMultiline(cfg config.MultilineConfig) (*Multiline, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	m := &Multiline{timeout: cfg.Timeout, maxLines: cfg.MaxLines}
+	var err error
+	if cfg.StartPattern != "" {
+		if m.start, err = regexp.Compile(cfg.StartPattern); err != nil {
+			return nil, fmt.Errorf("invalid multiline start pattern: %w", err)
+		}
+	}
+	if cfg.ContinuationPattern != "" {
+		if m.continuation, err = regexp.Compile(cfg.ContinuationPattern); err != nil {
+			return nil, fmt.Errorf("invalid multiline continuation pattern: %w", err)
+		}
+	}
+	if m.maxLines < 2 {
+		m.maxLines = 2
+	}
+	return m, nil
+}
+
+// Timeout is how long a streamed log waits for another line before it is complete
+func (m *Multiline) Timeout() time.Duration {
+	return m.timeout
+}
+
+// continues reports whether a line belongs to the log before it
+func (m *Multiline) continues(line []byte) bool {
+	if m.continuation != nil && m.continuation.Match(line) {
+		return true
+	}
+	return m.start != nil && !m.start.Match(line)
+}
+
+// JoinedLog is a log made of one or more lines. First and Last are the tags passed with its
+// first and last line, e.g. line numbers or source positions.
+type JoinedLog[T any] struct {
+	Data  []byte
+	First T
+	Last  T
+}
+
+// LineJoiner joins the lines of one stream into logs. It is not safe for concurrent use.
+type LineJoiner[T any] struct {
+	m       *Multiline
+	pending *JoinedLog[T]
+	lines   int
+	updated time.Time
+}
+
+// NewLineJoiner creates a joiner for one stream of lines
+func NewLineJoiner[T any](m *Multiline) *LineJoiner[T] {
+	return &LineJoiner[T]{m: m}
+}
+
+// Add adds the next line and returns the logs it completed: none while a log may still grow,
+// the previous log when the line starts a new one, or the line itself when it is JSON
+func (j *LineJoiner[T]) Add(line []byte, tag T) []JoinedLog[T] {
+	var done []JoinedLog[T]
+	trimmed := bytes.TrimSpace(line)
+	// Text logs often start with "[timestamp]", so an array must be valid JSON to count
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[' && json.Valid(trimmed)) {
+		if log, ok := j.Flush(); ok {
+			done = append(done, log)
+		}
+		return append(done, JoinedLog[T]{Data: append([]byte(nil), line...), First: tag, Last: tag})
+	}
+
+	if j.pending != nil && j.m.continues(line) && j.lines < j.m.maxLines &&
+		len(j.pending.Data)+1+len(line) <= maxMultilineBytes {
+		j.pending.Data = append(append(j.pending.Data, '\n'), line...)
+		j.pending.Last = tag
+		j.lines++
+		j.updated = time.Now()
+		return nil
+	}
+
+	if log, ok := j.Flush(); ok {
+		done = append(done, log)
+	}
+	j.pending = &JoinedLog[T]{Data: append([]byte(nil), line...), First: tag, Last: tag}
+	j.lines = 1
+	j.updated = time.Now()
+	return done
+}
+
+// Flush returns the log being joined, if any, as complete
+func (j *LineJoiner[T]) Flush() (JoinedLog[T], bool) {
+	if j.pending == nil {
+		return JoinedLog[T]{}, false
+	}
+	log := *j.pending
+	j.pending = nil
+	j.lines = 0
+	return log, true
+}
+
+// Expired returns the log being joined when it has waited longer than the timeout for another line
+func (j *LineJoiner[T]) Expired(now time.Time) (JoinedLog[T], bool) {
+	if j.pending == nil || now.Sub(j.updated) < j.m.timeout {
+		return JoinedLog[T]{}, false
+	}
+	return j.Flush()
+}
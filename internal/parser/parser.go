@@ -3,6 +3,7 @@ package parser
 import (
 	"encoding/json"
 	"fmt"
+	"log-ingestion-service/pkg/config"
 	"log-ingestion-service/pkg/models"
 	"strings"
 	"time"
@@ -13,20 +14,29 @@ type Parser interface {
 	Parse(data []byte) (*models.LogEntry, error)
 }
 
+// UnknownService is assigned to parsed logs that do not name a service
+const UnknownService = "unknown"
+
 // JSONParser parses JSON formatted logs
-type JSONParser struct{}
+type JSONParser struct {
+	mapping FieldMapping
+}
+
+// NewJSONParser creates a new JSON parser that also reads core values from mapped fields
+func NewJSONParser(mapping FieldMapping) *JSONParser {
+	return &JSONParser{mapping: mapping}
+}
 
-// NewJSONParser creates a new JSON parser
-func NewJSONParser() *JSONParser {
-	return &JSONParser{}
+// Decode decodes a JSON log, applying the field mapping but no defaults or required-field checks
+func (p *JSONParser) Decode(data []byte) (*models.LogEntry, error) {
+	return p.mapping.decode(data, nil)
 }
 
 // Parse parses JSON log data
 func (p *JSONParser) Parse(data []byte) (*models.LogEntry, error) {
-	var logEntry models.LogEntry
-	
-	if err := json.Unmarshal(data, &logEntry); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON log: %w", err)
+	logEntry, err := p.Decode(data)
+	if err != nil {
+		return nil, err
 	}
 	
 	// Set default timestamp if not provided
@@ -45,7 +55,7 @@ func (p *JSONParser) Parse(data []byte) (*models.LogEntry, error) {
 		return nil, fmt.Errorf("message field is required")
 	}
 	
-	return &logEntry, nil
+	return logEntry, nil
 }
 
 // TextParser parses plain text formatted logs
@@ -59,12 +69,25 @@ func NewTextParser() *TextParser {
 // Parse parses plain text log data
 // Expected format: [TIMESTAMP] LEVEL SERVICE: MESSAGE
 // Or simpler: LEVEL SERVICE: MESSAGE (timestamp will be set to now)
+// A multi-line log is parsed from its first line; the following lines, such as stack trace
+// frames, are appended to the message as they are.
 func (p *TextParser) Parse(data []byte) (*models.LogEntry, error) {
 	text := strings.TrimSpace(string(data))
 	if text == "" {
 		return nil, fmt.Errorf("empty log entry")
 	}
+	text, rest, _ := strings.Cut(text, "\n")
+	text = strings.TrimSpace(text)
 	
+	logEntry := p.parseLine(text)
+	if rest != "" {
+		logEntry.Message += "\n" + rest
+	}
+	return logEntry, nil
+}
+
+// parseLine parses a single line of text
+func (p *TextParser) parseLine(text string) *models.LogEntry {
 	logEntry := models.LogEntry{
 		Timestamp: time.Now(),
 		Metadata:  make(map[string]interface{}),
@@ -80,8 +103,8 @@ func (p *TextParser) Parse(data []byte) (*models.LogEntry, error) {
 		// Simple format: just use the whole text as message
 		logEntry.Message = text
 		logEntry.Level = "INFO"
-		logEntry.Service = "unknown"
-		return &logEntry, nil
+		logEntry.Service = UnknownService
+		return &logEntry
 	}
 	
 	// Try to detect timestamp in brackets
@@ -134,8 +157,10 @@ func (p *TextParser) Parse(data []byte) (*models.LogEntry, error) {
 		// Remove colon if present
 		servicePart = strings.TrimSuffix(servicePart, ":")
 		logEntry.Service = servicePart
-	} else {
-		logEntry.Service = "unknown"
+	}
+	// A lone colon, as in "INFO : message", names no service
+	if logEntry.Service == "" {
+		logEntry.Service = UnknownService
 	}
 	
 	// Rest is the message
@@ -149,32 +174,89 @@ func (p *TextParser) Parse(data []byte) (*models.LogEntry, error) {
 		logEntry.Message = text
 	}
 	
-	return &logEntry, nil
+	return &logEntry
 }
 
-// AutoParser automatically detects and parses log format
+// AutoParser automatically detects and parses log format. Registered parsers that implement
+// Detector are tried after JSON, and the processors named in parser.processors run on every
+// log it parses or decodes.
 type AutoParser struct {
-	jsonParser *JSONParser
-	textParser *TextParser
+	jsonParser   *JSONParser
+	logfmtParser *LogfmtParser
+	textParser   *TextParser
+	detectors    []Parser
+	processors   []Processor
 }
 
 // NewAutoParser creates a new auto-detecting parser
-func NewAutoParser() *AutoParser {
-	return &AutoParser{
-		jsonParser: NewJSONParser(),
-		textParser: NewTextParser(),
+func NewAutoParser(cfg *config.ParserConfig) (*AutoParser, error) {
+	mapping, err := NewFieldMapping(cfg)
+	if err != nil {
+		return nil, err
+	}
+	
+	processors, err := lookupProcessors(cfg.Processors)
+	if err != nil {
+		return nil, err
 	}
+	
+	return &AutoParser{
+		jsonParser:   NewJSONParser(mapping),
+		logfmtParser: NewLogfmtParser(mapping.timestamps),
+		textParser:   NewTextParser(),
+		detectors:    registeredDetectors(),
+		processors:   processors,
+	}, nil
 }
 
 // Parse automatically detects format and parses the log
 func (p *AutoParser) Parse(data []byte) (*models.LogEntry, error) {
+	logEntry, err := p.detect(data).Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	runProcessors(p.processors, logEntry)
+	return logEntry, nil
+}
+
+// detect chooses the parser for a log
+func (p *AutoParser) detect(data []byte) Parser {
 	// Try JSON first
 	trimmed := strings.TrimSpace(string(data))
-	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
-		return p.jsonParser.Parse(data)
+	// Text logs start with "[timestamp]" too, so an array must be valid JSON
+	if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") && json.Valid([]byte(trimmed)) {
+		return p.jsonParser
+	}
+	
+	// Then registered parsers and logfmt, judged by the first line of a joined multi-line log
+	firstLine, _, _ := strings.Cut(trimmed, "\n")
+	firstLine = strings.TrimSpace(firstLine)
+	for _, detector := range p.detectors {
+		if detector.(Detector).Detect(firstLine) {
+			return detector
+		}
+	}
+	if looksLikeLogfmt(firstLine) {
+		return p.logfmtParser
 	}
 	
 	// Fall back to text parser
-	return p.textParser.Parse(data)
+	return p.textParser
+}
+
+// DecodeJSON decodes a single JSON log object with the configured field mapping
+func (p *AutoParser) DecodeJSON(data []byte) (*models.LogEntry, error) {
+	return p.DecodeJSONWith(data, nil)
+}
+
+// DecodeJSONWith decodes a single JSON log object like DecodeJSON, reading its timestamp with
+// timestamps unless it is nil
+func (p *AutoParser) DecodeJSONWith(data []byte, timestamps *TimestampParser) (*models.LogEntry, error) {
+	logEntry, err := p.jsonParser.mapping.decode(data, timestamps)
+	if err != nil {
+		return nil, err
+	}
+	runProcessors(p.processors, logEntry)
+	return logEntry, nil
 }
 
@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"log-ingestion-service/pkg/config"
+	"testing"
+)
+
+// logSeeds are log lines in each format the auto parser detects, and malformed variants of them
+var logSeeds = []string{
+	`{"timestamp":"2024-01-15T10:30:00Z","service":"checkout","level":"ERROR","message":"payment declined"}`,
+	`{"ts":"2024-01-15T10:30:00Z","app":"checkout","severity":"warn","msg":"retrying","metadata":{"attempt":2}}`,
+	`[{"level":"INFO","message":"array"}]`,
+	`{"level":`,
+	`[2024-01-15T10:30:00Z] ERROR checkout: payment declined`,
+	`INFO api: request served`,
+	`INFO : request served`,
+	`ERROR`,
+	"ERROR worker: job failed\n\tat Worker.run(Worker.java:42)\n\tat Thread.run",
+	`level=info ts=2024-01-15T10:30:00Z service=checkout msg="order placed" order_id=42 cached`,
+	`level= msg="unterminated`,
+	`a=b=c ==`,
+	`127.0.0.1 - - [15/Jan/2024:10:30:00 +0000] "GET / HTTP/1.1" 200 512 "-" "curl/8.0"`,
+	`<13>1 2024-01-15T10:30:00Z host app - - - message`,
+	"",
+	"\n\n",
+}
+
+// FuzzAutoParser checks that no input panics the auto-detecting parser and that every log it
+// accepts has a level and a service
+func FuzzAutoParser(f *testing.F) {
+	for _, seed := range logSeeds {
+		f.Add([]byte(seed))
+	}
+	p, err := NewAutoParser(&config.ParserConfig{})
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		entry, err := p.Parse(data)
+		if err != nil {
+			return
+		}
+		if entry == nil {
+			t.Fatalf("%q: parsed without error into no entry", data)
+		}
+	})
+}
+
+// FuzzTextParser checks that every plain text log is given a level and a service
+func FuzzTextParser(f *testing.F) {
+	for _, seed := range logSeeds {
+		f.Add([]byte(seed))
+	}
+	p := NewTextParser()
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		entry, err := p.Parse(data)
+		if err != nil {
+			return
+		}
+		if entry.Level == "" || entry.Service == "" {
+			t.Errorf("%q: parsed with level %q and service %q", data, entry.Level, entry.Service)
+		}
+	})
+}
+
+// FuzzLogfmtParser checks that no input panics the logfmt parser and that every line it
+// accepts has a level and a service
+func FuzzLogfmtParser(f *testing.F) {
+	for _, seed := range logSeeds {
+		f.Add([]byte(seed))
+	}
+	timestamps, err := NewTimestampParser("UTC")
+	if err != nil {
+		f.Fatal(err)
+	}
+	p := NewLogfmtParser(timestamps)
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		entry, err := p.Parse(data)
+		if err != nil {
+			return
+		}
+		if entry.Level == "" || entry.Service == "" {
+			t.Errorf("%q: parsed with level %q and service %q", data, entry.Level, entry.Service)
+		}
+	})
+}
+
+// FuzzGrokParser checks that no input panics a grok parser with typed fields, whose values
+// are converted as they are extracted
+func FuzzGrokParser(f *testing.F) {
+	for _, seed := range logSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Add([]byte(`2024-01-15T10:30:00Z ERROR checkout 42 0.25 payment declined`))
+	f.Add([]byte(`2024-01-15T10:30:00Z ERROR checkout 99999999999999999999 1e999 overflow`))
+	timestamps, err := NewTimestampParser("UTC")
+	if err != nil {
+		f.Fatal(err)
+	}
+	lib, err := NewGrokLibrary(nil)
+	if err != nil {
+		f.Fatal(err)
+	}
+	p, err := lib.NewParser(config.CustomParserConfig{
+		Name: "fuzz",
+		Grok: `%{TIMESTAMP_ISO8601:timestamp} %{LOGLEVEL:level} %{WORD:service} %{INT:count:int} %{NUMBER:ratio:float} %{GREEDYDATA:message}`,
+	}, timestamps)
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		entry, err := p.Parse(data)
+		if err != nil {
+			return
+		}
+		if entry == nil {
+			t.Fatalf("%q: parsed without error into no entry", data)
+		}
+	})
+}
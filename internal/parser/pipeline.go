@@ -0,0 +1,203 @@
+package parser
+
+import (
+	"fmt"
+	"log-ingestion-service/internal/validator"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"strings"
+)
+
+// Parser pipeline step types
+const (
+	// StepMultiline joins continuation lines onto the line starting their log, as configured
+	// under parser.multiline. It must come first.
+	StepMultiline = "multiline"
+	// StepParse parses each log with a registered parser
+	StepParse = "parse"
+	// StepGrok parses each log with a grok expression
+	StepGrok = "grok"
+	// StepRedact removes sensitive metadata keys and masks sensitive values in metadata and the message
+	StepRedact = "redact"
+	// StepEnrich sets metadata fields and, optionally, the service
+	StepEnrich = "enrich"
+	// StepProcess runs a processor compiled in with RegisterProcessor
+	StepProcess = "process"
+)
+
+// Pipeline parses logs with one parser, then runs its processors on each parsed log in order.
+// Pipelines are defined at runtime and selected like parsers, by name from an API key or by
+// source.
+type Pipeline struct {
+	name       string
+	multiline  bool
+	parser     Parser
+	grok       *GrokParser
+	processors []func(*models.LogEntry)
+}
+
+// Parse parses a log and runs the processors on it
+func (p *Pipeline) Parse(data []byte) (*models.LogEntry, error) {
+	logEntry, err := p.parser.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	p.Process(logEntry)
+	return logEntry, nil
+}
+
+// Process runs the processors on a log decoded elsewhere, such as a structured JSON log
+func (p *Pipeline) Process(logEntry *models.LogEntry) {
+	for _, process := range p.processors {
+		process(logEntry)
+	}
+}
+
+// pipelineSet holds the compiled pipelines by name and the sources that select them
+type pipelineSet struct {
+	byName   map[string]*Pipeline
+	bySource map[string]string
+}
+
+// release detaches the grok parsers of the set from the library
+func (s *pipelineSet) release(library *GrokLibrary) {
+	for _, p := range s.byName {
+		if p.grok != nil {
+			library.Release(p.grok)
+		}
+	}
+}
+
+// compilePipelines builds pipelines from their definitions. Names may not clash with parsers,
+// and a source may select only one pipeline.
+func (r *Registry) compilePipelines(defs []models.ParserPipeline) (*pipelineSet, error) {
+	set := &pipelineSet{byName: make(map[string]*Pipeline), bySource: make(map[string]string)}
+	for _, def := range defs {
+		if _, exists := r.parsers[def.Name]; exists || def.Name == "" {
+			set.release(r.grok)
+			return nil, fmt.Errorf("invalid pipeline name %q: it is empty or names a parser", def.Name)
+		}
+		p, err := r.compilePipeline(def)
+		if err != nil {
+			set.release(r.grok)
+			return nil, fmt.Errorf("pipeline %q: %w", def.Name, err)
+		}
+		set.byName[def.Name] = p
+
+		for _, source := range def.Sources {
+			source = strings.ToLower(source)
+			if other, taken := set.bySource[source]; taken {
+				set.release(r.grok)
+				return nil, fmt.Errorf("source %q is selected by pipelines %q and %q", source, other, def.Name)
+			}
+			set.bySource[source] = def.Name
+		}
+	}
+	return set, nil
+}
+
+// compilePipeline builds one pipeline: an optional multiline step, then one parse or grok step,
+// then any redact, enrich and process steps
+func (r *Registry) compilePipeline(def models.ParserPipeline) (_ *Pipeline, err error) {
+	p := &Pipeline{name: def.Name}
+	defer func() {
+		if err != nil && p.grok != nil {
+			r.grok.Release(p.grok)
+		}
+	}()
+
+	for i, step := range def.Steps {
+		switch step.Type {
+		case StepMultiline:
+			if i != 0 {
+				return nil, fmt.Errorf("step %d: the multiline step must come first", i+1)
+			}
+			if r.multiline == nil {
+				return nil, fmt.Errorf("step %d: multiline joining needs parser.multiline to be configured", i+1)
+			}
+			p.multiline = true
+
+		case StepParse, StepGrok:
+			if p.parser != nil {
+				return nil, fmt.Errorf("step %d: a pipeline has one parse or grok step", i+1)
+			}
+			if step.Type == StepParse {
+				parser, exists := r.parsers[step.Parser]
+				if !exists {
+					return nil, fmt.Errorf("step %d: unknown parser %q", i+1, step.Parser)
+				}
+				p.parser = parser
+				continue
+			}
+			grok, err := r.grok.NewParser(config.CustomParserConfig{
+				Name:            def.Name,
+				Grok:            step.Grok,
+				TimestampFormat: step.TimestampFormat,
+				Service:         UnknownService,
+			}, r.auto.jsonParser.mapping.timestamps)
+			if err != nil {
+				return nil, fmt.Errorf("step %d: %w", i+1, err)
+			}
+			p.parser, p.grok = r.withProcessors(grok), grok
+
+		case StepRedact, StepEnrich, StepProcess:
+			if p.parser == nil {
+				return nil, fmt.Errorf("step %d: %s steps come after the parse or grok step", i+1, step.Type)
+			}
+			process, err := newPipelineProcessor(step)
+			if err != nil {
+				return nil, fmt.Errorf("step %d: %w", i+1, err)
+			}
+			p.processors = append(p.processors, process)
+
+		default:
+			return nil, fmt.Errorf("step %d: unknown step type %q", i+1, step.Type)
+		}
+	}
+
+	if p.parser == nil {
+		return nil, fmt.Errorf("a parse or grok step is required")
+	}
+	return p, nil
+}
+
+// newPipelineProcessor builds the processor of a redact, enrich or process step
+func newPipelineProcessor(step models.PipelineStep) (func(*models.LogEntry), error) {
+	if step.Type == StepProcess {
+		found, err := lookupProcessors([]string{step.Processor})
+		if err != nil {
+			return nil, err
+		}
+		return found[0].Process, nil
+	}
+	if step.Type == StepEnrich {
+		if len(step.Fields) == 0 && step.Service == "" {
+			return nil, fmt.Errorf("an enrich step sets fields or a service")
+		}
+		return func(logEntry *models.LogEntry) {
+			if logEntry.Metadata == nil && len(step.Fields) > 0 {
+				logEntry.Metadata = make(map[string]interface{}, len(step.Fields))
+			}
+			for key, value := range step.Fields {
+				logEntry.Metadata[key] = value
+			}
+			if step.Service != "" {
+				logEntry.Service = step.Service
+			}
+		}, nil
+	}
+
+	if len(step.Keys) == 0 && len(step.Patterns) == 0 {
+		return nil, fmt.Errorf("a redact step has keys or patterns")
+	}
+	scrubber, err := validator.NewScrubber(&config.ScrubConfig{KeyPatterns: step.Keys, ValuePatterns: step.Patterns})
+	if err != nil {
+		return nil, err
+	}
+	return func(logEntry *models.LogEntry) {
+		if logEntry.Metadata != nil {
+			scrubber.Scrub(logEntry.Metadata)
+		}
+		logEntry.Message, _ = scrubber.ScrubText(logEntry.Message)
+	}, nil
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"context"
+	"log"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// pipelineSyncInterval bounds how long a pipeline changed on another instance takes to apply here
+const pipelineSyncInterval = 30 * time.Second
+
+// PipelineSync keeps the pipelines of a registry in step with the ones stored from the admin API
+type PipelineSync struct {
+	repo     *storage.Repository
+	registry *Registry
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+
+	mu     sync.Mutex
+	loaded []models.ParserPipeline
+}
+
+// NewPipelineSync creates a sync for a registry
+func NewPipelineSync(repo *storage.Repository, registry *Registry) *PipelineSync {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &PipelineSync{repo: repo, registry: registry, ctx: ctx, cancel: cancel}
+}
+
+// Start loads the stored pipelines, then reloads them every 30 seconds
+func (s *PipelineSync) Start() {
+	s.Reload(s.ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(pipelineSyncInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.Reload(s.ctx)
+			}
+		}
+	}()
+}
+
+// Shutdown stops reloading
+func (s *PipelineSync) Shutdown() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Reload applies the stored pipelines when they changed. Pipelines that do not compile, for
+// example after a parser was removed from configuration, are logged once and the registry
+// keeps its current ones.
+func (s *PipelineSync) Reload(ctx context.Context) {
+	stored, err := s.repo.ListParserPipelines(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("ERROR: Failed to load parser pipelines: %v", err)
+		}
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loaded != nil && reflect.DeepEqual(s.loaded, stored) {
+		return
+	}
+	if err := s.registry.SetPipelines(stored); err != nil {
+		log.Printf("ERROR: Stored parser pipelines were not applied: %v", err)
+	}
+	s.loaded = stored
+}
@@ -0,0 +1,117 @@
+package parser
+
+import (
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"sort"
+)
+
+// Processor changes logs once they are parsed, e.g. to enrich them or rename fields.
+// Processors are called concurrently and may see a log more than once, as logs parsed by the
+// agent are processed again by the server, so they should be safe for concurrent use and
+// idempotent.
+type Processor interface {
+	Process(logEntry *models.LogEntry)
+}
+
+// ProcessorFunc adapts a function to the Processor interface
+type ProcessorFunc func(logEntry *models.LogEntry)
+
+// Process calls f(logEntry)
+func (f ProcessorFunc) Process(logEntry *models.LogEntry) {
+	f(logEntry)
+}
+
+// Detector is implemented by registered parsers that recognize their own format. The auto
+// parser tries them, in name order, on lines that are not JSON, before logfmt and plain text.
+type Detector interface {
+	// Detect reports whether a line, or the first line of a joined multi-line log, is in the
+	// parser's format
+	Detect(line string) bool
+}
+
+var processors = make(map[string]Processor)
+
+// RegisterProcessor makes a custom processor available to every registry created afterwards,
+// to be run on every log through parser.processors or by pipelines with a process step. Like
+// Register, it is meant to be called from an init function and panics if the name is taken.
+func RegisterProcessor(name string, p Processor) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	if _, exists := processors[name]; exists || name == "" {
+		panic(fmt.Sprintf("parser: RegisterProcessor called twice for %q", name))
+	}
+	processors[name] = p
+}
+
+// lookupProcessors returns the registered processors with these names, in order
+func lookupProcessors(names []string) ([]Processor, error) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	found := make([]Processor, 0, len(names))
+	for _, name := range names {
+		p, exists := processors[name]
+		if !exists {
+			return nil, fmt.Errorf("unknown processor %q", name)
+		}
+		found = append(found, p)
+	}
+	return found, nil
+}
+
+// ProcessorNames lists the registered processors, sorted by name
+func ProcessorNames() []string {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	names := make([]string, 0, len(processors))
+	for name := range processors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registeredDetectors returns the registered parsers that implement Detector, in name order
+func registeredDetectors() []Parser {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	names := make([]string, 0, len(plugins))
+	for name, p := range plugins {
+		if _, ok := p.parser.(Detector); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	detectors := make([]Parser, len(names))
+	for i, name := range names {
+		detectors[i] = plugins[name].parser
+	}
+	return detectors
+}
+
+// processedParser runs processors on the logs of the parser it wraps
+type processedParser struct {
+	Parser
+	processors []Processor
+}
+
+// Parse parses a log and runs the processors on it
+func (p *processedParser) Parse(data []byte) (*models.LogEntry, error) {
+	logEntry, err := p.Parser.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	runProcessors(p.processors, logEntry)
+	return logEntry, nil
+}
+
+func runProcessors(processors []Processor, logEntry *models.LogEntry) {
+	for _, p := range processors {
+		p.Process(logEntry)
+	}
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"fmt"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RegexParser is a declaratively defined parser that extracts fields with a regular expression.
+// Named groups "timestamp", "level", "service" and "message" fill the entry; any other named
+// group is stored in metadata.
+type RegexParser struct {
+	pattern         *regexp.Regexp
+	timestampFormat string
+	service         string
+	level           string
+	timestamps      *TimestampParser
+}
+
+// NewRegexParser compiles a declarative parser definition
+func NewRegexParser(cfg config.CustomParserConfig, timestamps *TimestampParser) (*RegexParser, error) {
+	pattern, err := regexp.Compile(cfg.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern for parser %q: %w", cfg.Name, err)
+	}
+
+	level := cfg.Level
+	if level == "" {
+		level = "INFO"
+	}
+
+	return &RegexParser{
+		pattern:         pattern,
+		timestampFormat: cfg.TimestampFormat,
+		service:         cfg.Service,
+		level:           level,
+		timestamps:      timestamps,
+	}, nil
+}
+
+// Parse extracts a log entry from a single line
+func (p *RegexParser) Parse(data []byte) (*models.LogEntry, error) {
+	line := strings.TrimRight(string(data), "\r\n")
+	match := p.pattern.FindStringSubmatch(line)
+	if match == nil {
+		return nil, fmt.Errorf("line does not match parser pattern")
+	}
+
+	logEntry := models.LogEntry{
+		Timestamp: time.Now(),
+		Service:   p.service,
+		Level:     p.level,
+		Message:   line,
+		Metadata:  make(map[string]interface{}),
+	}
+
+	for i, name := range p.pattern.SubexpNames() {
+		if name == "" || match[i] == "" {
+			continue
+		}
+		value := match[i]
+		switch name {
+		case "timestamp":
+			t, err := p.parseTimestamp(value)
+			if err != nil {
+				return nil, err
+			}
+			logEntry.Timestamp = t
+		case "level":
+			logEntry.Level = normalizeLevel(value)
+		case "service":
+			logEntry.Service = value
+		case "message":
+			logEntry.Message = value
+		default:
+			logEntry.Metadata[name] = value
+		}
+	}
+
+	return &logEntry, nil
+}
+
+func (p *RegexParser) parseTimestamp(value string) (time.Time, error) {
+	if p.timestampFormat != "" {
+		return p.timestamps.ParseLayout(p.timestampFormat, value)
+	}
+	return p.timestamps.ParseString(value)
+}
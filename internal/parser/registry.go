@@ -0,0 +1,300 @@
+package parser
+
+import (
+	"fmt"
+	"log-ingestion-service/pkg/config"
+	"mime"
+	"sort"
+	"log-ingestion-service/pkg/models"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Built-in parser names
+const (
+	NameAuto = "auto"
+	NameJSON = "json"
+	NameText = "text"
+)
+
+// plugin is a parser compiled into the binary via Register
+type plugin struct {
+	parser       Parser
+	contentTypes []string
+}
+
+var (
+	plugins   = make(map[string]plugin)
+	pluginsMu sync.Mutex
+)
+
+// Register makes a custom parser available to every registry created afterwards.
+// It is meant to be called from an init function of a package compiled into the server,
+// and panics if the name is already taken, like database/sql driver registration.
+func Register(name string, p Parser, contentTypes ...string) {
+	pluginsMu.Lock()
+	defer pluginsMu.Unlock()
+
+	if _, exists := plugins[name]; exists || name == NameAuto || name == NameJSON || name == NameText || name == NameLogfmt || name == NameAccessLog || name == NameJournald || name == NameWinEvent {
+		panic(fmt.Sprintf("parser: Register called twice for %q", name))
+	}
+	plugins[name] = plugin{parser: p, contentTypes: contentTypes}
+}
+
+// ParserInfo describes a registered parser
+type ParserInfo struct {
+	Name         string   `json:"name"`
+	ContentTypes []string `json:"content_types,omitempty"`
+	Sources      []string `json:"sources,omitempty"`
+	Pipeline     bool     `json:"pipeline,omitempty"`
+}
+
+// Selection is the request context used to choose a parser, in priority order:
+// an explicit name (e.g. configured on the API key), then the source tag, then the content type
+type Selection struct {
+	Name        string
+	Source      string
+	ContentType string
+}
+
+// Registry holds named parsers and the content types and sources that select them, and the
+// pipelines defined at runtime
+type Registry struct {
+	auto         *AutoParser
+	parsers      map[string]Parser
+	contentTypes map[string]string
+	sources      map[string]string
+	multiline    *Multiline
+	grok         *GrokLibrary
+
+	pipelinesMu sync.Mutex
+	pipelines   atomic.Pointer[pipelineSet]
+
+	// timestampFormats caches the parsers built by Timestamps
+	timestampFormats sync.Map
+}
+
+// NewRegistry creates a registry with the built-in parsers, compiled-in plugins, declarative
+// regex and grok parsers from configuration and the configured source mappings
+func NewRegistry(cfg *config.ParserConfig) (*Registry, error) {
+	auto, err := NewAutoParser(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	multiline, err := NewMultiline(cfg.Multiline)
+	if err != nil {
+		return nil, err
+	}
+
+	grok, err := NewGrokLibrary(cfg.GrokPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Registry{
+		auto:         auto,
+		multiline:    multiline,
+		grok:         grok,
+		parsers:      make(map[string]Parser),
+		contentTypes: make(map[string]string),
+		sources:      make(map[string]string),
+	}
+	r.pipelines.Store(&pipelineSet{byName: map[string]*Pipeline{}, bySource: map[string]string{}})
+
+	r.add(NameAuto, auto)
+	r.add(NameJSON, auto.jsonParser, "application/json", "application/x-ndjson")
+	r.add(NameText, auto.textParser, "text/plain")
+	r.add(NameLogfmt, auto.logfmtParser, "text/x-logfmt")
+	r.add(NameAccessLog, NewAccessLogParser(auto.jsonParser.mapping.timestamps), "text/x-access-log")
+	r.add(NameJournald, NewJournaldParser())
+	r.add(NameWinEvent, NewWinEventParser(auto.jsonParser.mapping.timestamps))
+	r.sources[NameJournald] = NameJournald
+	r.sources[NameWinEvent] = NameWinEvent
+
+	pluginsMu.Lock()
+	for name, p := range plugins {
+		r.add(name, p.parser, p.contentTypes...)
+	}
+	pluginsMu.Unlock()
+
+	for _, custom := range cfg.Custom {
+		if _, exists := r.parsers[custom.Name]; exists || custom.Name == "" {
+			return nil, fmt.Errorf("invalid or duplicate parser name %q", custom.Name)
+		}
+		var p Parser
+		if custom.Grok != "" {
+			p, err = grok.NewParser(custom, auto.jsonParser.mapping.timestamps)
+		} else {
+			p, err = NewRegexParser(custom, auto.jsonParser.mapping.timestamps)
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.add(custom.Name, p, custom.ContentTypes...)
+	}
+
+	for source, name := range cfg.Sources {
+		if _, exists := r.parsers[name]; !exists {
+			return nil, fmt.Errorf("source %q maps to unknown parser %q", source, name)
+		}
+		r.sources[strings.ToLower(source)] = name
+	}
+
+	return r, nil
+}
+
+func (r *Registry) add(name string, p Parser, contentTypes ...string) {
+	if p != Parser(r.auto) {
+		p = r.withProcessors(p)
+	}
+	r.parsers[name] = p
+	for _, ct := range contentTypes {
+		r.contentTypes[normalizeContentType(ct)] = name
+	}
+}
+
+// withProcessors wraps a parser to run the processors of parser.processors on its logs
+func (r *Registry) withProcessors(p Parser) Parser {
+	if len(r.auto.processors) == 0 {
+		return p
+	}
+	return &processedParser{Parser: p, processors: r.auto.processors}
+}
+
+// Auto returns the auto-detecting parser
+func (r *Registry) Auto() *AutoParser {
+	return r.auto
+}
+
+// Grok returns the grok pattern library shared by the grok parsers
+func (r *Registry) Grok() *GrokLibrary {
+	return r.grok
+}
+
+// Timestamps returns a timestamp parser that tries layouts before the usual formats and reads
+// zone-less timestamps in timezone, for sources such as API keys with their own formats. The
+// parsers are cached, so the same formats can be looked up for every request.
+func (r *Registry) Timestamps(layouts []string, timezone string) (*TimestampParser, error) {
+	key := timezone + "\x00" + strings.Join(layouts, "\x00")
+	if p, ok := r.timestampFormats.Load(key); ok {
+		return p.(*TimestampParser), nil
+	}
+	p, err := r.auto.jsonParser.mapping.timestamps.WithFormats(layouts, timezone)
+	if err != nil {
+		return nil, err
+	}
+	r.timestampFormats.Store(key, p)
+	return p, nil
+}
+
+// Multiline returns the multi-line joining applied in front of text parsing, or nil when it is off
+func (r *Registry) Multiline() *Multiline {
+	return r.multiline
+}
+
+// JoinsLines reports whether lines for the named parser are joined into multi-line logs first.
+// Only auto-detected, plain text and logfmt lines are joined, and those of pipelines starting
+// with a multiline step.
+func (r *Registry) JoinsLines(name string) bool {
+	if p, exists := r.pipelines.Load().byName[name]; exists {
+		return p.multiline
+	}
+	return r.multiline != nil && (name == NameAuto || name == NameText || name == NameLogfmt)
+}
+
+// Has reports whether a parser or pipeline with the given name is registered
+func (r *Registry) Has(name string) bool {
+	if _, exists := r.parsers[name]; exists {
+		return true
+	}
+	_, exists := r.pipelines.Load().byName[name]
+	return exists
+}
+
+// CheckPipelines reports whether every pipeline compiles against the registered parsers
+func (r *Registry) CheckPipelines(defs []models.ParserPipeline) error {
+	r.pipelinesMu.Lock()
+	defer r.pipelinesMu.Unlock()
+
+	set, err := r.compilePipelines(defs)
+	if err != nil {
+		return err
+	}
+	set.release(r.grok)
+	return nil
+}
+
+// SetPipelines replaces the pipelines. Nothing changes when one does not compile.
+func (r *Registry) SetPipelines(defs []models.ParserPipeline) error {
+	r.pipelinesMu.Lock()
+	defer r.pipelinesMu.Unlock()
+
+	set, err := r.compilePipelines(defs)
+	if err != nil {
+		return err
+	}
+	r.pipelines.Swap(set).release(r.grok)
+	return nil
+}
+
+// Select returns the parser for a request and its name, falling back to auto-detection.
+// Pipelines are selected like parsers; their sources take precedence over parser.sources.
+func (r *Registry) Select(sel Selection) (Parser, string) {
+	pipelines := r.pipelines.Load()
+	if p, exists := r.parsers[sel.Name]; exists {
+		return p, sel.Name
+	}
+	if p, exists := pipelines.byName[sel.Name]; exists {
+		return p, sel.Name
+	}
+	if name, exists := pipelines.bySource[strings.ToLower(sel.Source)]; exists {
+		return pipelines.byName[name], name
+	}
+	if name, exists := r.sources[strings.ToLower(sel.Source)]; exists {
+		return r.parsers[name], name
+	}
+	if name, exists := r.contentTypes[normalizeContentType(sel.ContentType)]; exists {
+		return r.parsers[name], name
+	}
+	return r.auto, NameAuto
+}
+
+// List describes every registered parser, sorted by name
+func (r *Registry) List() []ParserInfo {
+	infos := make(map[string]*ParserInfo, len(r.parsers))
+	for name := range r.parsers {
+		infos[name] = &ParserInfo{Name: name}
+	}
+	for ct, name := range r.contentTypes {
+		infos[name].ContentTypes = append(infos[name].ContentTypes, ct)
+	}
+	for source, name := range r.sources {
+		infos[name].Sources = append(infos[name].Sources, source)
+	}
+	pipelines := r.pipelines.Load()
+	for name := range pipelines.byName {
+		infos[name] = &ParserInfo{Name: name, Pipeline: true}
+	}
+	for source, name := range pipelines.bySource {
+		infos[name].Sources = append(infos[name].Sources, source)
+	}
+
+	list := make([]ParserInfo, 0, len(infos))
+	for _, info := range infos {
+		sort.Strings(info.ContentTypes)
+		sort.Strings(info.Sources)
+		list = append(list, *info)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// normalizeContentType strips parameters such as charset and lowercases the media type
+func normalizeContentType(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
@@ -3,17 +3,24 @@ package parser
 import (
 	"fmt"
 	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// MaxQueryLength bounds search queries; longer ones are rejected rather than tokenized
+const MaxQueryLength = 2048
+
 // SearchParser parses tokenized search queries
-type SearchParser struct{}
+type SearchParser struct {
+	// sla is the policy sla: tokens are evaluated under; without one they are rejected
+	sla *models.SLAPolicy
+}
 
-// NewSearchParser creates a new search parser
-func NewSearchParser() *SearchParser {
-	return &SearchParser{}
+// NewSearchParser creates a new search parser. sla may be nil when SLA searches are not needed.
+func NewSearchParser(sla *models.SLAPolicy) *SearchParser {
+	return &SearchParser{sla: sla}
 }
 
 // ParseQuery parses a search query string into FaultFilters
@@ -26,6 +33,9 @@ func (p *SearchParser) ParseQuery(query string) (*storage.FaultFilters, error) {
 	if query == "" {
 		return filters, nil
 	}
+	if len(query) > MaxQueryLength {
+		return nil, fmt.Errorf("query is longer than %d bytes", MaxQueryLength)
+	}
 	
 	// Split query into tokens
 	tokens := p.tokenize(query)
@@ -40,47 +50,81 @@ func (p *SearchParser) ParseQuery(query string) (*storage.FaultFilters, error) {
 	return filters, nil
 }
 
-// tokenize splits a query string into tokens
+// ParseLogQuery parses a log search query into LogFilters. service:name and level:error (or a
+// comma-separated list of levels) filter; other tokens are searched for in messages.
+func (p *SearchParser) ParseLogQuery(query string) (*storage.LogFilters, error) {
+	filters := &storage.LogFilters{}
+	if len(query) > MaxQueryLength {
+		return nil, fmt.Errorf("query is longer than %d bytes", MaxQueryLength)
+	}
+	
+	var search []string
+	for _, token := range p.tokenize(query) {
+		key, value, ok := strings.Cut(token, ":")
+		switch strings.ToLower(key) {
+		case "service":
+			if ok {
+				filters.Service = strings.Trim(value, "\"")
+				continue
+			}
+		case "level":
+			if ok {
+				for _, level := range strings.Split(strings.Trim(value, "\""), ",") {
+					if level = strings.TrimSpace(level); level != "" {
+						filters.Levels = append(filters.Levels, level)
+					}
+				}
+				continue
+			}
+		}
+		search = append(search, token)
+	}
+	filters.Search = strings.Join(search, " ")
+	
+	return filters, nil
+}
+
+// tokenize splits a query string into tokens. Tokens are trimmed, and blank ones, such as an
+// empty quoted phrase, are dropped.
 func (p *SearchParser) tokenize(query string) []string {
 	// Split by spaces, but preserve quoted strings
 	var tokens []string
 	var current strings.Builder
 	inQuotes := false
 	
-	for i, char := range query {
+	for _, char := range query {
 		if char == '"' {
 			if inQuotes {
 				// End of quoted string
-				if current.Len() > 0 {
-					tokens = append(tokens, current.String())
-					current.Reset()
-				}
+				tokens = appendToken(tokens, &current)
 				inQuotes = false
 			} else {
-				// Start of quoted string
-				if current.Len() > 0 {
-					tokens = append(tokens, current.String())
-					current.Reset()
+				// Start of quoted string; a quoted value (tag:"a b") stays part of its key
+				if !strings.HasSuffix(current.String(), ":") {
+					tokens = appendToken(tokens, &current)
 				}
 				inQuotes = true
 			}
 		} else if char == ' ' && !inQuotes {
 			// Space outside quotes - end of token
-			if current.Len() > 0 {
-				tokens = append(tokens, current.String())
-				current.Reset()
-			}
+			tokens = appendToken(tokens, &current)
 		} else {
 			current.WriteRune(char)
 		}
-		
-		// Handle last token
-		if i == len(query)-1 && current.Len() > 0 {
-			tokens = append(tokens, current.String())
-		}
 	}
 	
-	return tokens
+	// Handle last token, including an unterminated quote
+	return appendToken(tokens, &current)
+}
+
+// appendToken adds the token being built to tokens unless it is blank, and starts a new one
+func appendToken(tokens []string, current *strings.Builder) []string {
+	token := strings.TrimSpace(current.String())
+	current.Reset()
+	if token == "" {
+		return tokens
+	}
+	return append(tokens, token)
 }
 
 // parseToken parses a single token and updates filters
@@ -95,6 +139,9 @@ func (p *SearchParser) parseToken(token string, filters *storage.FaultFilters) e
 		negated = true
 		token = token[1:]
 	}
+	if token == "" {
+		return nil
+	}
 	
 	// Check for key:value format
 	if strings.Contains(token, ":") {
@@ -112,6 +159,10 @@ func (p *SearchParser) parseToken(token string, filters *storage.FaultFilters) e
 		switch key {
 		case "is":
 			return p.parseIsToken(value, negated, filters)
+		case "state":
+			return p.parseStateToken(value, negated, filters)
+		case "sla":
+			return p.parseSLAToken(value, negated, filters)
 		case "environment", "env":
 			return p.parseEnvironmentToken(value, filters)
 		case "assignee":
@@ -123,6 +174,15 @@ func (p *SearchParser) parseToken(token string, filters *storage.FaultFilters) e
 		case "occurred.before", "before":
 			return p.parseDateToken(value, filters, false)
 		default:
+			// field.<key>:value matches a custom field
+			if fieldKey, ok := strings.CutPrefix(key, "field."); ok && fieldKey != "" {
+				filters.CustomFields = append(filters.CustomFields, storage.CustomFieldFilter{
+					Key:     fieldKey,
+					Value:   value,
+					Negated: negated,
+				})
+				return nil
+			}
 			// Unknown key, treat as search text
 			if filters.Search == "" {
 				filters.Search = token
@@ -160,8 +220,48 @@ func (p *SearchParser) parseIsToken(value string, negated bool, filters *storage
 	return nil
 }
 
+// parseStateToken parses state:acknowledged tokens; a comma-separated list matches any of its states
+func (p *SearchParser) parseStateToken(value string, negated bool, filters *storage.FaultFilters) error {
+	for _, state := range strings.Split(value, ",") {
+		state = strings.TrimSpace(state)
+		if state == "" {
+			continue
+		}
+		if negated {
+			filters.ExcludedStates = append(filters.ExcludedStates, state)
+		} else {
+			filters.States = append(filters.States, state)
+		}
+	}
+	
+	return nil
+}
+
+// parseSLAToken parses sla:breached and sla:at_risk tokens
+func (p *SearchParser) parseSLAToken(value string, negated bool, filters *storage.FaultFilters) error {
+	if p.sla == nil {
+		return fmt.Errorf("SLA search is not available here")
+	}
+	if negated {
+		return fmt.Errorf("sla cannot be negated")
+	}
+	
+	value = strings.ToLower(value)
+	if value != models.SLABreached && value != models.SLAAtRisk {
+		return fmt.Errorf("unknown 'sla' value: %s (allowed: %s, %s)", value, models.SLABreached, models.SLAAtRisk)
+	}
+	filters.SLAStatus = value
+	filters.SLA = p.sla
+	
+	return nil
+}
+
 // parseEnvironmentToken parses environment:production tokens
 func (p *SearchParser) parseEnvironmentToken(value string, filters *storage.FaultFilters) error {
+	// An empty value, as while a search is being typed, does not filter
+	if value == "" {
+		return nil
+	}
 	filters.Environment = &value
 	return nil
 }
@@ -194,6 +294,9 @@ func (p *SearchParser) parseAssigneeToken(value string, filters *storage.FaultFi
 
 // parseTagToken parses tag:value tokens
 func (p *SearchParser) parseTagToken(value string, filters *storage.FaultFilters) error {
+	if value == "" {
+		return nil
+	}
 	if filters.Tags == nil {
 		filters.Tags = []string{}
 	}
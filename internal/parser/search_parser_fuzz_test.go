@@ -0,0 +1,106 @@
+package parser
+
+import (
+	"log-ingestion-service/pkg/models"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// searchQuerySeeds are fault and log searches as users type them, including the malformed
+// ones seen in request logs
+var searchQuerySeeds = []string{
+	"",
+	"NoMethodError",
+	"is:resolved",
+	"-is:ignored environment:production",
+	`tag:"needs triage" assignee:42`,
+	"state:acknowledged,triaged -state:ignored",
+	"sla:breached",
+	"field.customer:acme -field.plan:free",
+	"occurred.after:2d before:1w",
+	`message:"connection refused" env:staging`,
+	`"unterminated quote`,
+	`tag:"`,
+	"-",
+	"- -is:resolved",
+	"a:b:c",
+	"service:checkout level:error,warn timeout",
+	`service:"billing api" level:` + "\"\"",
+	"héllo wörld",
+	"\xff\xfe",
+}
+
+// checkSearch checks the search text left after filters were taken out: terms are separated by
+// single spaces, with none around them
+func checkSearch(t *testing.T, query, search string) {
+	t.Helper()
+	if search != strings.TrimSpace(search) || strings.Contains(search, "  ") {
+		t.Errorf("query %q left search text %q with stray spaces", query, search)
+	}
+}
+
+func FuzzParseQuery(f *testing.F) {
+	for _, seed := range searchQuerySeeds {
+		f.Add(seed)
+	}
+	p := NewSearchParser(&models.SLAPolicy{})
+
+	f.Fuzz(func(t *testing.T, query string) {
+		filters, err := p.ParseQuery(query)
+		if err != nil {
+			if len(query) > MaxQueryLength || strings.Contains(query, ":") {
+				return
+			}
+			t.Fatalf("query %q without filters was rejected: %v", query, err)
+		}
+		if filters == nil {
+			t.Fatalf("query %q returned no filters and no error", query)
+		}
+		checkSearch(t, query, filters.Search)
+		if utf8.ValidString(query) && !utf8.ValidString(filters.Search) {
+			t.Errorf("query %q left invalid UTF-8 search text %q", query, filters.Search)
+		}
+		for _, tag := range filters.Tags {
+			if tag == "" {
+				t.Errorf("query %q matched an empty tag", query)
+			}
+		}
+		for _, state := range append(filters.States, filters.ExcludedStates...) {
+			if state == "" {
+				t.Errorf("query %q matched an empty state", query)
+			}
+		}
+		if filters.Environment != nil && *filters.Environment == "" {
+			t.Errorf("query %q matched an empty environment", query)
+		}
+		for _, field := range filters.CustomFields {
+			if field.Key == "" {
+				t.Errorf("query %q matched a custom field without a key", query)
+			}
+		}
+	})
+}
+
+func FuzzParseLogQuery(f *testing.F) {
+	for _, seed := range searchQuerySeeds {
+		f.Add(seed)
+	}
+	p := NewSearchParser(nil)
+
+	f.Fuzz(func(t *testing.T, query string) {
+		filters, err := p.ParseLogQuery(query)
+		if err != nil {
+			if len(query) > MaxQueryLength {
+				return
+			}
+			t.Fatalf("query %q was rejected: %v", query, err)
+		}
+		checkSearch(t, query, filters.Search)
+		for _, level := range filters.Levels {
+			if level == "" || level != strings.TrimSpace(level) {
+				t.Errorf("query %q matched level %q", query, level)
+			}
+		}
+	})
+}
@@ -0,0 +1,200 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// zonedLayouts carry their own offset and are parsed as-is
+var zonedLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999 -0700",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	"02/Jan/2006:15:04:05 -0700", // Common Log Format
+}
+
+// localLayouts have no zone and are interpreted in the configured default timezone
+var localLayouts = []string{
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// syslogLayouts omit the year, which is inferred
+var syslogLayouts = []string{
+	time.Stamp,
+	"Jan _2 2006 15:04:05",
+}
+
+// layoutCheckTime is formatted and parsed back to check custom layouts; unlike the reference
+// time, no layout formats it as itself
+var layoutCheckTime = time.Date(2019, time.November, 23, 21, 47, 38, 0, time.UTC)
+
+// TimestampParser parses the timestamp formats commonly found in JSON logs
+type TimestampParser struct {
+	location *time.Location
+	// layouts are tried before the usual formats
+	layouts []string
+}
+
+// NewTimestampParser creates a parser that reads zone-less timestamps in the named timezone
+func NewTimestampParser(timezone string) (*TimestampParser, error) {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid default timezone %q: %w", timezone, err)
+	}
+	return &TimestampParser{location: location}, nil
+}
+
+// WithFormats returns a parser that tries layouts, such as "02/Jan/2006:15:04:05", before the
+// usual formats, and reads zone-less timestamps in timezone, or in p's timezone when it is empty
+func (p *TimestampParser) WithFormats(layouts []string, timezone string) (*TimestampParser, error) {
+	location := p.location
+	if timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(timezone); err != nil || timezone == "Local" {
+			return nil, fmt.Errorf("invalid timezone %q", timezone)
+		}
+	}
+	for _, layout := range layouts {
+		if err := checkLayout(layout); err != nil {
+			return nil, err
+		}
+	}
+	return &TimestampParser{location: location, layouts: append([]string(nil), layouts...)}, nil
+}
+
+// checkLayout rejects layouts that cannot read back the times they format, including those
+// without any date or time element
+func checkLayout(layout string) error {
+	formatted := layoutCheckTime.Format(layout)
+	if formatted == layout {
+		return fmt.Errorf("timestamp layout %q has no date or time elements, such as 2006 or 15:04", layout)
+	}
+	if _, err := time.Parse(layout, formatted); err != nil {
+		return fmt.Errorf("invalid timestamp layout %q: %w", layout, err)
+	}
+	return nil
+}
+
+// Parse accepts epoch seconds, milliseconds, microseconds or nanoseconds (as numbers or numeric
+// strings), RFC 3339 and other zoned layouts, ISO timestamps without a zone and syslog timestamps.
+func (p *TimestampParser) Parse(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case float64:
+		return fromEpoch(v), nil
+	case string:
+		return p.ParseString(v)
+	}
+	return time.Time{}, fmt.Errorf("unsupported timestamp value %v", value)
+}
+
+// ParseString parses a textual timestamp
+func (p *TimestampParser) ParseString(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+
+	// Custom layouts come first, as one such as 20060102150405 would otherwise read as an epoch
+	for _, layout := range p.layouts {
+		if t, err := time.ParseInLocation(layout, s, p.location); err == nil {
+			if t.Year() == 0 {
+				t = withInferredYear(t, time.Now().In(p.location))
+			}
+			return t, nil
+		}
+	}
+
+	// Integer strings are parsed exactly so nanosecond epochs keep full precision
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return fromEpochInt(n), nil
+	}
+	if n, err := strconv.ParseFloat(s, 64); err == nil {
+		return fromEpoch(n), nil
+	}
+
+	for _, layout := range zonedLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range localLayouts {
+		if t, err := time.ParseInLocation(layout, s, p.location); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range syslogLayouts {
+		if t, err := time.ParseInLocation(layout, s, p.location); err == nil {
+			if t.Year() == 0 {
+				t = withInferredYear(t, time.Now().In(p.location))
+			}
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized timestamp format %q", s)
+}
+
+// ParseLayout parses a timestamp with an explicit layout, reading zone-less values in the default timezone
+func (p *TimestampParser) ParseLayout(layout, s string) (time.Time, error) {
+	t, err := time.ParseInLocation(layout, strings.TrimSpace(s), p.location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("timestamp %q does not match layout %q", s, layout)
+	}
+	if t.Year() == 0 {
+		t = withInferredYear(t, time.Now().In(p.location))
+	}
+	return t, nil
+}
+
+// fromEpoch converts an epoch value, guessing its unit from its magnitude
+func fromEpoch(n float64) time.Time {
+	abs := math.Abs(n)
+	switch {
+	case abs < 1e11:
+		sec, frac := math.Modf(n)
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+	case abs < 1e14:
+		return time.UnixMilli(int64(n)).UTC()
+	case abs < 1e17:
+		return time.UnixMicro(int64(n)).UTC()
+	}
+	return time.Unix(0, int64(n)).UTC()
+}
+
+// fromEpochInt converts an integer epoch value, guessing its unit from its magnitude
+func fromEpochInt(n int64) time.Time {
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	switch {
+	case abs < 1e11:
+		return time.Unix(n, 0).UTC()
+	case abs < 1e14:
+		return time.UnixMilli(n).UTC()
+	case abs < 1e17:
+		return time.UnixMicro(n).UTC()
+	}
+	return time.Unix(0, n).UTC()
+}
+
+// withInferredYear places a year-less syslog timestamp in the current year, or the previous
+// one if that would put it more than a day in the future (logs from late December read in January)
+func withInferredYear(t, now time.Time) time.Time {
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t
+}
@@ -0,0 +1,140 @@
+package parser
+
+import (
+	"encoding/json"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// NameWinEvent is the built-in parser for Windows Event Log records, also selected by the "winevent" source
+const NameWinEvent = "winevent"
+
+// winEventLevels maps Windows event levels to log levels
+var winEventLevels = map[int]string{
+	0: "INFO", // LogAlways
+	1: "CRITICAL",
+	2: "ERROR",
+	3: "WARN",
+	4: "INFO",
+	5: "DEBUG", // Verbose
+}
+
+// winEventDatePattern matches the "/Date(1700000000000)/" timestamps written by Windows PowerShell 5.1
+var winEventDatePattern = regexp.MustCompile(`^/Date\((-?\d+)[+-]?\d*\)/$`)
+
+// winEventRecord holds the fields of an event as exported by `Get-WinEvent | ConvertTo-Json -Compress`.
+// Alternative names used by other exporters (EventID, Channel, Provider, Computer) are also read.
+type winEventRecord struct {
+	ID               *int64      `json:"Id"`
+	EventID          *int64      `json:"EventID"`
+	Level            *int        `json:"Level"`
+	LevelDisplayName string      `json:"LevelDisplayName"`
+	ProviderName     string      `json:"ProviderName"`
+	Provider         string      `json:"Provider"`
+	LogName          string      `json:"LogName"`
+	Channel          string      `json:"Channel"`
+	MachineName      string      `json:"MachineName"`
+	Computer         string      `json:"Computer"`
+	RecordID         *int64      `json:"RecordId"`
+	TaskDisplayName  string      `json:"TaskDisplayName"`
+	Message          string      `json:"Message"`
+	TimeCreated      interface{} `json:"TimeCreated"`
+}
+
+// WinEventParser parses Windows Event Log records exported as JSON, one per line
+type WinEventParser struct {
+	timestamps *TimestampParser
+}
+
+// NewWinEventParser creates a new Windows Event Log parser
+func NewWinEventParser(timestamps *TimestampParser) *WinEventParser {
+	return &WinEventParser{timestamps: timestamps}
+}
+
+// Parse parses a single Windows event record
+func (p *WinEventParser) Parse(data []byte) (*models.LogEntry, error) {
+	var record winEventRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to parse Windows event: %w", err)
+	}
+
+	logEntry := models.LogEntry{
+		Timestamp: time.Now(),
+		Service:   firstNonEmpty(record.ProviderName, record.Provider, record.LogName, record.Channel, UnknownService),
+		Level:     "INFO",
+		Message:   record.Message,
+		Metadata:  make(map[string]interface{}),
+	}
+
+	if record.Level != nil {
+		if level, ok := winEventLevels[*record.Level]; ok {
+			logEntry.Level = level
+		}
+	} else if record.LevelDisplayName != "" {
+		logEntry.Level = normalizeLevel(record.LevelDisplayName)
+	}
+
+	if record.TimeCreated != nil {
+		t, err := p.parseTime(record.TimeCreated)
+		if err != nil {
+			return nil, err
+		}
+		logEntry.Timestamp = t
+	}
+
+	eventID := record.ID
+	if eventID == nil {
+		eventID = record.EventID
+	}
+	if eventID != nil {
+		logEntry.Metadata["event_id"] = *eventID
+	}
+	if logEntry.Message == "" {
+		if eventID == nil {
+			return nil, fmt.Errorf("Windows event has no Message or Id")
+		}
+		logEntry.Message = fmt.Sprintf("Event %d", *eventID)
+	}
+
+	for key, value := range map[string]string{
+		"channel": firstNonEmpty(record.LogName, record.Channel),
+		"host":    firstNonEmpty(record.MachineName, record.Computer),
+		"task":    record.TaskDisplayName,
+	} {
+		if value != "" {
+			logEntry.Metadata[key] = value
+		}
+	}
+	if record.RecordID != nil {
+		logEntry.Metadata["record_id"] = *record.RecordID
+	}
+
+	return &logEntry, nil
+}
+
+// parseTime accepts ISO timestamps and the legacy "/Date(ms)/" form
+func (p *WinEventParser) parseTime(value interface{}) (time.Time, error) {
+	if s, ok := value.(string); ok {
+		if match := winEventDatePattern.FindStringSubmatch(s); match != nil {
+			ms, err := strconv.ParseInt(match[1], 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("invalid TimeCreated %q: %w", s, err)
+			}
+			return time.UnixMilli(ms).UTC(), nil
+		}
+	}
+	return p.timestamps.Parse(value)
+}
+
+// firstNonEmpty returns the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,181 @@
+// Package pause lets operators stop a pipeline, such as ingestion or outgoing notifications, and
+// start it again. Pauses are stored in the database so they survive restarts and apply to every
+// instance.
+package pause
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"sync"
+	"time"
+)
+
+// Pipelines that can be paused
+const (
+	// Ingestion accepts logs, notices and OTLP exports; while paused they are answered with 503
+	Ingestion = "ingestion"
+	// Notifications delivers alerts for new faults; while paused alerts are dropped
+	Notifications = "notifications"
+)
+
+// pipelines lists every pipeline in display order
+var pipelines = []string{Ingestion, Notifications}
+
+// ErrUnknownPipeline is returned for a pipeline name that is not defined
+var ErrUnknownPipeline = errors.New("unknown pipeline")
+
+// refreshInterval bounds how long another instance's pause or resume takes to apply here
+const refreshInterval = 10 * time.Second
+
+// Status reports whether a pipeline is paused
+type Status struct {
+	Pipeline string     `json:"pipeline"`
+	Paused   bool       `json:"paused"`
+	Reason   string     `json:"reason,omitempty"`
+	PausedBy *int64     `json:"paused_by,omitempty"`
+	PausedAt *time.Time `json:"paused_at,omitempty"`
+}
+
+// Controller tracks paused pipelines
+type Controller struct {
+	repo *storage.Repository
+
+	mu       sync.Mutex
+	paused   map[string]models.PipelinePause
+	loadedAt time.Time
+}
+
+// NewController creates a controller and loads the stored pauses, so a pipeline paused before a
+// restart stays paused from the first request
+func NewController(ctx context.Context, repo *storage.Repository) (*Controller, error) {
+	c := &Controller{repo: repo}
+	if _, err := c.refresh(ctx); err != nil {
+		return nil, err
+	}
+	for _, p := range c.paused {
+		log.Printf("WARN: Pipeline %s is paused since %s: %s", p.Pipeline, p.PausedAt.Format(time.RFC3339), p.Reason)
+	}
+	return c, nil
+}
+
+// Known reports whether name is a defined pipeline
+func Known(name string) bool {
+	for _, p := range pipelines {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Paused reports whether a pipeline is paused. If pauses cannot be reloaded the last known state is used.
+func (c *Controller) Paused(ctx context.Context, pipeline string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if time.Since(c.loadedAt) >= refreshInterval {
+		pauses, err := c.repo.ListPipelinePauses(ctx)
+		if err != nil {
+			log.Printf("WARN: Failed to load pipeline pauses: %v", err)
+			// Retry on a later check rather than on every request
+			c.loadedAt = time.Now()
+		} else {
+			c.store(pauses)
+		}
+	}
+	_, paused := c.paused[pipeline]
+	return paused
+}
+
+// Statuses reports every pipeline, read fresh from the database
+func (c *Controller) Statuses(ctx context.Context) ([]Status, error) {
+	paused, err := c.refresh(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(pipelines))
+	for _, name := range pipelines {
+		status := Status{Pipeline: name}
+		if p, ok := paused[name]; ok {
+			pausedAt := p.PausedAt
+			status.Paused = true
+			status.Reason = p.Reason
+			status.PausedBy = p.PausedBy
+			status.PausedAt = &pausedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// Pause stops a pipeline until it is resumed
+func (c *Controller) Pause(ctx context.Context, pipeline, reason string, actorID *int64) error {
+	if !Known(pipeline) {
+		return fmt.Errorf("%w: %s", ErrUnknownPipeline, pipeline)
+	}
+	p := &models.PipelinePause{Pipeline: pipeline, Reason: reason, PausedBy: actorID}
+	if err := c.repo.PausePipeline(ctx, p); err != nil {
+		return err
+	}
+
+	c.update(func(paused map[string]models.PipelinePause) { paused[pipeline] = *p })
+	log.Printf("WARN: Pipeline %s paused: %s", pipeline, reason)
+	return nil
+}
+
+// Resume starts a paused pipeline again. Resuming a running pipeline does nothing.
+func (c *Controller) Resume(ctx context.Context, pipeline string) error {
+	if !Known(pipeline) {
+		return fmt.Errorf("%w: %s", ErrUnknownPipeline, pipeline)
+	}
+	resumed, err := c.repo.ResumePipeline(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+
+	c.update(func(paused map[string]models.PipelinePause) { delete(paused, pipeline) })
+	if resumed {
+		log.Printf("INFO: Pipeline %s resumed", pipeline)
+	}
+	return nil
+}
+
+// refresh reloads the stored pauses and returns them
+func (c *Controller) refresh(ctx context.Context) (map[string]models.PipelinePause, error) {
+	pauses, err := c.repo.ListPipelinePauses(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.store(pauses)
+	return c.paused, nil
+}
+
+// update changes a copy of the cached pauses, since maps returned by refresh are read unlocked
+func (c *Controller) update(change func(map[string]models.PipelinePause)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	paused := make(map[string]models.PipelinePause, len(c.paused)+1)
+	for name, p := range c.paused {
+		paused[name] = p
+	}
+	change(paused)
+	c.paused = paused
+}
+
+// store replaces the cached pauses; callers hold c.mu
+func (c *Controller) store(pauses []models.PipelinePause) {
+	paused := make(map[string]models.PipelinePause, len(pauses))
+	for _, p := range pauses {
+		paused[p.Pipeline] = p
+	}
+	c.paused = paused
+	c.loadedAt = time.Now()
+}
@@ -0,0 +1,294 @@
+// Package preflight checks at startup that the database can serve the service: the required
+// extensions are installed, the schema is recent enough, the clocks agree, a log can be
+// written and read back, and the data is encrypted as configured. The service reports ready only once every check has passed; failed
+// checks are run again until they do. More checks can be registered alongside the database's.
+package preflight
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SchemaVersion is the latest migration the service needs to have been applied
+const SchemaVersion = 47
+
+// Check is a named check; it fails by returning an error saying why. It may also describe what
+// it found, which is reported whether it passed or not.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) (string, error)
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Status is the outcome of the latest run of the checks
+type Status struct {
+	Passed    bool       `json:"passed"`
+	CheckedAt *time.Time `json:"checked_at,omitempty"`
+	Checks    []Result   `json:"checks"`
+}
+
+// Runner runs the checks, then runs them again until they pass
+type Runner struct {
+	enabled  bool
+	timeout  time.Duration
+	interval time.Duration
+	checks   []Check
+
+	mu     sync.Mutex
+	status Status
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a runner of checks. When preflight is disabled no check is run and the service
+// is ready at once.
+func New(cfg *config.PreflightConfig, checks ...Check) *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Runner{
+		enabled:  cfg.Enabled,
+		timeout:  cfg.Timeout,
+		interval: cfg.RetryInterval,
+		checks:   checks,
+		status:   Status{Checks: []Result{}},
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Register adds a check, run from the next run on
+func (r *Runner) Register(check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check)
+}
+
+// DatabaseChecks returns the checks of the database: extensions, schema version, clock skew,
+// a write/read round trip and encryption
+func DatabaseChecks(cfg *config.PreflightConfig, repo *storage.Repository) []Check {
+	return []Check{
+		{Name: "extensions", Run: withoutDetail(func(ctx context.Context) error { return checkExtensions(ctx, repo, cfg.RequiredExtensions) })},
+		{Name: "schema_version", Run: withoutDetail(func(ctx context.Context) error { return checkSchemaVersion(ctx, repo) })},
+		{Name: "clock", Run: withoutDetail(func(ctx context.Context) error { return checkClock(ctx, repo, cfg.MaxClockSkew) })},
+		{Name: "round_trip", Run: withoutDetail(func(ctx context.Context) error { return checkRoundTrip(ctx, repo) })},
+		{Name: "encryption", Run: func(ctx context.Context) (string, error) { return checkEncryption(ctx, repo, cfg) }},
+	}
+}
+
+// withoutDetail adapts a check that only reports why it failed
+func withoutDetail(run func(ctx context.Context) error) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) { return "", run(ctx) }
+}
+
+// Run runs every check, each within the timeout, and records the outcome
+func (r *Runner) Run(ctx context.Context) Status {
+	r.mu.Lock()
+	checks := append([]Check(nil), r.checks...)
+	r.mu.Unlock()
+
+	now := time.Now()
+	status := Status{Passed: true, CheckedAt: &now, Checks: []Result{}}
+	if r.enabled {
+		for _, check := range checks {
+			result := Result{Name: check.Name, OK: true}
+			checkCtx, cancel := context.WithTimeout(ctx, r.timeout)
+			detail, err := check.Run(checkCtx)
+			result.Detail = detail
+			if err != nil {
+				result.OK = false
+				result.Reason = err.Error()
+				status.Passed = false
+				log.Printf("WARN: Preflight check %s failed: %v", check.Name, err)
+			}
+			cancel()
+			status.Checks = append(status.Checks, result)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+	return status
+}
+
+// Start runs the checks again every retry interval until they pass
+func (r *Runner) Start() {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for !r.Ready() {
+			select {
+			case <-r.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if r.Run(r.ctx).Passed {
+				log.Printf("INFO: Preflight checks passed")
+			}
+		}
+	}()
+}
+
+// Shutdown stops running the checks again, waiting for a run in progress
+func (r *Runner) Shutdown() {
+	r.cancel()
+	r.wg.Wait()
+}
+
+// Status returns the outcome of the latest run
+func (r *Runner) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+// Ready reports whether the latest run passed
+func (r *Runner) Ready() bool {
+	return r.Status().Passed
+}
+
+func checkExtensions(ctx context.Context, repo *storage.Repository, required []string) error {
+	installed, err := repo.InstalledExtensions(ctx)
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool, len(installed))
+	for _, name := range installed {
+		have[name] = true
+	}
+	var missing []string
+	for _, name := range required {
+		if !have[name] {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing extensions: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func checkSchemaVersion(ctx context.Context, repo *storage.Repository) error {
+	version, err := repo.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if version < SchemaVersion {
+		return fmt.Errorf("schema version is %d, %d is needed; apply the migrations", version, SchemaVersion)
+	}
+	return nil
+}
+
+// checkClock compares the database's clock with the midpoint of the query, so the round trip
+// does not count as skew
+func checkClock(ctx context.Context, repo *storage.Repository, maxSkew time.Duration) error {
+	before := time.Now()
+	dbTime, err := repo.DatabaseTime(ctx)
+	if err != nil {
+		return err
+	}
+	after := time.Now()
+	skew := dbTime.Sub(before.Add(after.Sub(before) / 2))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxSkew {
+		return fmt.Errorf("local clock is %s from the database's, more than %s", skew.Round(time.Millisecond), maxSkew)
+	}
+	return nil
+}
+
+// checkRoundTrip writes a log with a random token and reads it back; nothing is kept
+func checkRoundTrip(ctx context.Context, repo *storage.Repository) error {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Errorf("error generating token: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+	written := models.LogEntry{
+		// PostgreSQL keeps microseconds
+		Timestamp: time.Now().UTC().Truncate(time.Microsecond),
+		Service:   "preflight",
+		Level:     "INFO",
+		Message:   "preflight round trip " + token,
+		Metadata:  map[string]interface{}{"token": token},
+	}
+	read, err := repo.RoundTripLog(ctx, written)
+	if err != nil {
+		return err
+	}
+	if read.Message != written.Message || read.Metadata["token"] != token || !read.Timestamp.Equal(written.Timestamp) {
+		return fmt.Errorf("log read back differs from the one written")
+	}
+	return nil
+}
+
+// checkEncryption checks that connections to the database use SSL, when required, and that
+// every relation is in a tablespace asserted to be on encrypted storage, when any are. Neither
+// PostgreSQL nor TimescaleDB encrypts data files, so encryption at rest can only be verified
+// against that assertion. The detail reports what was found either way.
+func checkEncryption(ctx context.Context, repo *storage.Repository, cfg *config.PreflightConfig) (string, error) {
+	ssl, version, err := repo.ConnectionSSL(ctx)
+	if err != nil {
+		return "", err
+	}
+	var details []string
+	if ssl {
+		details = append(details, "connection uses SSL ("+version+")")
+	} else {
+		details = append(details, "connection does not use SSL")
+	}
+
+	if len(cfg.EncryptedTablespaces) == 0 {
+		details = append(details, "encryption at rest not asserted (no encrypted_tablespaces)")
+	} else {
+		counts, err := repo.RelationTablespaces(ctx)
+		if err != nil {
+			return "", err
+		}
+		encrypted := make(map[string]bool, len(cfg.EncryptedTablespaces))
+		for _, name := range cfg.EncryptedTablespaces {
+			encrypted[name] = true
+		}
+		var total int64
+		var unencrypted []string
+		for tablespace, count := range counts {
+			total += count
+			if !encrypted[tablespace] {
+				unencrypted = append(unencrypted, fmt.Sprintf("%s (%d)", tablespace, count))
+			}
+		}
+		if len(unencrypted) > 0 {
+			sort.Strings(unencrypted)
+			details = append(details, "relations outside the encrypted tablespaces")
+			return strings.Join(details, "; "), fmt.Errorf("relations are in tablespaces not asserted to be encrypted: %s", strings.Join(unencrypted, ", "))
+		}
+		details = append(details, fmt.Sprintf("all %d relations in encrypted tablespaces %s", total, strings.Join(cfg.EncryptedTablespaces, ", ")))
+	}
+
+	detail := strings.Join(details, "; ")
+	if cfg.RequireSSL && !ssl {
+		return detail, fmt.Errorf("the database connection does not use SSL; set LOG_INGESTION_DB_SSLMODE to require or stricter")
+	}
+	return detail, nil
+}
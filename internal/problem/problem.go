@@ -0,0 +1,196 @@
+package problem
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log-ingestion-service/internal/i18n"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type for RFC 7807 problem details
+const ContentType = "application/problem+json"
+
+// FormatHeader lets clients opt back into the legacy {"error","details"} shape
+// by sending "X-Error-Format: legacy"
+const FormatHeader = "X-Error-Format"
+
+// Stable machine-readable error codes
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeValidationFailed     = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeConflict             = "conflict"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeRateLimited          = "rate_limited"
+	CodeInternal             = "internal_error"
+	CodeUnavailable          = "service_unavailable"
+	CodeTimeout              = "timeout"
+	CodeQueryTooExpensive    = "query_too_expensive"
+)
+
+// Problem represents an RFC 7807 problem details object
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	// MessageID identifies the title whatever its language, for titles in the message catalog
+	MessageID string `json:"message_id,omitempty"`
+	Tenant    string `json:"tenant,omitempty"`
+	// RetryAfter and Backoff are set on retryable responses (429, 503)
+	RetryAfter int      `json:"retry_after,omitempty"`
+	Backoff    *Backoff `json:"backoff,omitempty"`
+}
+
+// Backoff is a structured hint describing how clients should space out retries
+type Backoff struct {
+	Strategy       string `json:"strategy"`
+	InitialSeconds int    `json:"initial_seconds"`
+	MaxSeconds     int    `json:"max_seconds"`
+	Jitter         bool   `json:"jitter"`
+}
+
+// maxBackoffSeconds caps the suggested exponential backoff
+const maxBackoffSeconds = 60
+
+// New builds a problem for the given status and code
+func New(status int, code, title, detail string) *Problem {
+	return &Problem{
+		Type:   "/problems/" + strings.ReplaceAll(code, "_", "-"),
+		Title:  title,
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Respond writes a problem response and aborts the request chain.
+// If err is non-nil its message is used as the problem detail.
+func Respond(c *gin.Context, status int, code, title string, err error) {
+	detail := ""
+	if err != nil {
+		detail = err.Error()
+	}
+	RespondDetail(c, status, code, title, detail)
+}
+
+// RespondDetail writes a problem response with an explicit detail string
+func RespondDetail(c *gin.Context, status int, code, title, detail string) {
+	p := New(status, code, title, detail)
+	p.Instance = c.Request.URL.Path
+	p.Tenant = tenantFromContext(c)
+	Write(c, p)
+}
+
+// RespondRetry writes a retryable problem with a Retry-After header and backoff hint.
+// retryAfter is rounded up to whole seconds, with a minimum of one.
+func RespondRetry(c *gin.Context, status int, code, title string, err error, retryAfter time.Duration) {
+	detail := ""
+	if err != nil {
+		detail = err.Error()
+	}
+
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	maxSeconds := maxBackoffSeconds
+	if seconds > maxSeconds {
+		maxSeconds = seconds
+	}
+
+	p := New(status, code, title, detail)
+	p.Instance = c.Request.URL.Path
+	p.Tenant = tenantFromContext(c)
+	p.RetryAfter = seconds
+	p.Backoff = &Backoff{
+		Strategy:       "exponential",
+		InitialSeconds: seconds,
+		MaxSeconds:     maxSeconds,
+		Jitter:         true,
+	}
+
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	Write(c, p)
+}
+
+// Write renders p honouring the legacy format header and aborts the request chain. Titles in
+// the message catalog are translated to the language negotiated from Accept-Language, except
+// in the legacy format, whose clients may match on the English text.
+func Write(c *gin.Context, p *Problem) {
+	if wantsLegacy(c) {
+		body := gin.H{"error": p.Title}
+		if p.Detail != "" {
+			body["details"] = p.Detail
+		}
+		if p.RetryAfter > 0 {
+			body["retry_after"] = p.RetryAfter
+		}
+		c.AbortWithStatusJSON(p.Status, body)
+		return
+	}
+
+	if id, ok := i18n.MessageID(p.Title); ok {
+		lang := i18n.Negotiate(c.GetHeader("Accept-Language"))
+		p.MessageID = id
+		p.Title = i18n.Text(lang, id)
+		c.Header("Content-Language", lang)
+	}
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// BadRequest responds with 400 invalid_request
+func BadRequest(c *gin.Context, title string, err error) {
+	Respond(c, http.StatusBadRequest, CodeInvalidRequest, title, err)
+}
+
+// NotFound responds with 404 not_found
+func NotFound(c *gin.Context, title string, err error) {
+	Respond(c, http.StatusNotFound, CodeNotFound, title, err)
+}
+
+// Internal responds with 500 internal_error, or with 503 timeout if err was caused by the
+// request's deadline expiring
+func Internal(c *gin.Context, title string, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		Timeout(c, err)
+		return
+	}
+	Respond(c, http.StatusInternalServerError, CodeInternal, title, err)
+}
+
+// Timeout responds with 503 timeout when a request exceeds its handler deadline
+func Timeout(c *gin.Context, err error) {
+	RespondRetry(c, http.StatusServiceUnavailable, CodeTimeout, "Request timed out", err, time.Second)
+}
+
+// wantsLegacy reports whether the client asked for the legacy error shape
+func wantsLegacy(c *gin.Context) bool {
+	return strings.EqualFold(strings.TrimSpace(c.GetHeader(FormatHeader)), "legacy")
+}
+
+// tenantFromContext identifies the authenticated principal so multi-user
+// deployments can correlate errors without exposing credentials
+func tenantFromContext(c *gin.Context) string {
+	if userID, ok := c.Get("user_id"); ok {
+		if id, ok := userID.(int64); ok && id != 0 {
+			return fmt.Sprintf("user:%d", id)
+		}
+	}
+	if _, ok := c.Get("api_key"); ok {
+		return "api_key"
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+// Package querycost asks the PostgreSQL planner what a search or analytics query will cost
+// before it runs. Queries estimated over the configured limits are rejected, or queued to run
+// a few at a time, and every guarded query is recorded in the query audit log with who ran it.
+package querycost
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"time"
+)
+
+// ErrQueueTimeout is returned when a query over the limits waited too long for a slot
+var ErrQueueTimeout = errors.New("timed out waiting for a slot for expensive queries")
+
+// TooExpensiveError is returned for a query over the limits when they are enforced by rejecting
+type TooExpensiveError struct {
+	Estimate storage.QueryEstimate
+	Duration time.Duration
+	Limits   config.QueryGuardConfig
+}
+
+func (e *TooExpensiveError) Error() string {
+	return fmt.Sprintf("query is estimated to read %.0f rows in %s, over the limits of %.0f rows and %s; narrow the query, for example with a shorter time range",
+		e.Estimate.Rows, e.Duration.Round(time.Millisecond), e.Limits.MaxRows, e.Limits.MaxDuration)
+}
+
+// Query identifies a guarded query and who ran it, for the audit log
+type Query struct {
+	Route    string
+	Query    string
+	UserID   *int64
+	APIKeyID *int64
+}
+
+// Estimator asks the planner what a query will cost
+type Estimator func(ctx context.Context) (*storage.QueryEstimate, error)
+
+// Guard admits queries within the limits and rejects or queues the others
+type Guard struct {
+	cfg   config.QueryGuardConfig
+	repo  *storage.Repository
+	slots chan struct{}
+}
+
+// NewGuard creates a guard enforcing cfg
+func NewGuard(cfg *config.QueryGuardConfig, repo *storage.Repository) *Guard {
+	g := &Guard{cfg: *cfg, repo: repo}
+	if cfg.Action == config.QueryGuardQueue {
+		g.slots = make(chan struct{}, cfg.QueueSlots)
+	}
+	return g
+}
+
+// Admit estimates a query and records it in the audit log. It returns a function to call once
+// the query is done, which frees the slot of a queued query. Over the limits it returns a
+// *TooExpensiveError, or ErrQueueTimeout when no slot frees up in time. A query the planner
+// cannot estimate runs, as it would without the guard.
+func (g *Guard) Admit(ctx context.Context, q Query, estimate Estimator) (func(), error) {
+	done := func() {}
+	if g == nil || !g.cfg.Enabled {
+		return done, nil
+	}
+
+	est, err := estimate(ctx)
+	if err != nil {
+		log.Printf("WARN: Failed to estimate %s query: %v", q.Route, err)
+		g.record(ctx, q, nil, models.QueryAllowed)
+		return done, nil
+	}
+	duration := time.Duration(est.Cost / g.cfg.CostPerSecond * float64(time.Second))
+	if est.Rows <= g.cfg.MaxRows && duration <= g.cfg.MaxDuration {
+		g.record(ctx, q, est, models.QueryAllowed)
+		return done, nil
+	}
+
+	if g.slots == nil {
+		g.record(ctx, q, est, models.QueryRejected)
+		return nil, &TooExpensiveError{Estimate: *est, Duration: duration, Limits: g.cfg}
+	}
+
+	timer := time.NewTimer(g.cfg.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case g.slots <- struct{}{}:
+		g.record(ctx, q, est, models.QueryQueued)
+		return func() { <-g.slots }, nil
+	case <-timer.C:
+		g.record(ctx, q, est, models.QueryRejected)
+		return nil, ErrQueueTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// record adds a query to the audit log. A failure is logged rather than failing the query.
+func (g *Guard) record(ctx context.Context, q Query, est *storage.QueryEstimate, outcome string) {
+	entry := &models.QueryAuditEntry{
+		UserID:   q.UserID,
+		APIKeyID: q.APIKeyID,
+		Route:    q.Route,
+		Query:    q.Query,
+		Outcome:  outcome,
+	}
+	if est != nil {
+		entry.EstimatedRows = &est.Rows
+		entry.EstimatedCost = &est.Cost
+	}
+	if err := g.repo.RecordQueryAudit(ctx, entry); err != nil {
+		log.Printf("WARN: Failed to record %s query in the audit log: %v", q.Route, err)
+	}
+}
@@ -0,0 +1,184 @@
+// Package redact masks sensitive data in logs and notices. The scrub rules from configuration
+// apply everywhere; redaction rules stored in the database add to them, for logs and every
+// project or for one project's notices.
+package redact
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/internal/validator"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// refreshInterval bounds how long a rule changed on another instance takes to apply here
+const refreshInterval = 30 * time.Second
+
+// loadTimeout bounds reloading rules for logs, which are scrubbed without a request context
+const loadTimeout = 5 * time.Second
+
+// Engine keeps a scrubber for logs and one for each project with rules of its own
+type Engine struct {
+	base config.ScrubConfig
+	repo *storage.Repository
+
+	mu       sync.Mutex
+	global   *validator.Scrubber
+	projects map[int64]*validator.Scrubber
+	loadedAt time.Time
+}
+
+// New creates an engine applying the configured scrub rules and loads the stored rules, so
+// they apply from the first log
+func New(ctx context.Context, cfg *config.ScrubConfig, repo *storage.Repository) (*Engine, error) {
+	global, err := validator.NewScrubber(cfg)
+	if err != nil {
+		return nil, err
+	}
+	e := &Engine{base: *cfg, repo: repo, global: global}
+	if err := e.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Check reports whether a rule compiles
+func Check(rule *models.RedactionRule) error {
+	var cfg config.ScrubConfig
+	if err := addRule(&cfg, rule); err != nil {
+		return err
+	}
+	_, err := validator.NewScrubber(&cfg)
+	return err
+}
+
+// LogScrubber returns the scrubber for logs, which have no project
+func (e *Engine) LogScrubber() *validator.Scrubber {
+	ctx, cancel := context.WithTimeout(context.Background(), loadTimeout)
+	defer cancel()
+	return e.Scrubber(ctx, nil)
+}
+
+// Scrubber returns the scrubber for a project's notices, or for logs when projectID is nil. If
+// rules cannot be reloaded the last known rules are used.
+func (e *Engine) Scrubber(ctx context.Context, projectID *int64) *validator.Scrubber {
+	e.mu.Lock()
+	stale := time.Since(e.loadedAt) >= refreshInterval
+	if stale {
+		// One caller reloads; the others carry on with the current rules
+		e.loadedAt = time.Now()
+	}
+	e.mu.Unlock()
+	if stale {
+		if err := e.Reload(ctx); err != nil {
+			log.Printf("WARN: Failed to load redaction rules: %v", err)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if projectID != nil {
+		if scrubber, ok := e.projects[*projectID]; ok {
+			return scrubber
+		}
+	}
+	return e.global
+}
+
+// RedactNotice masks sensitive data in a notice with the rules for its project, returning the
+// dotted paths of everything masked
+func (e *Engine) RedactNotice(ctx context.Context, notice *models.Notice) []string {
+	if e == nil {
+		return nil
+	}
+	return e.Scrubber(ctx, notice.ProjectID).ScrubNotice(notice)
+}
+
+// Reload reads the stored rules and rebuilds the scrubbers. Rules that no longer compile are
+// skipped and logged.
+func (e *Engine) Reload(ctx context.Context) error {
+	rules, err := e.repo.ListRedactionRules(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	var valid []*models.RedactionRule
+	for i := range rules {
+		if err := Check(&rules[i]); err != nil {
+			log.Printf("WARN: Skipping redaction rule %d: %v", rules[i].ID, err)
+			continue
+		}
+		valid = append(valid, &rules[i])
+	}
+
+	globalCfg := copyConfig(&e.base)
+	for _, rule := range valid {
+		if rule.ProjectID == nil {
+			addRule(&globalCfg, rule)
+		}
+	}
+	// Project rules add to the global ones
+	projectCfgs := make(map[int64]*config.ScrubConfig)
+	for _, rule := range valid {
+		if rule.ProjectID == nil {
+			continue
+		}
+		cfg, ok := projectCfgs[*rule.ProjectID]
+		if !ok {
+			projectCfg := copyConfig(&globalCfg)
+			cfg = &projectCfg
+			projectCfgs[*rule.ProjectID] = cfg
+		}
+		addRule(cfg, rule)
+	}
+
+	global, err := validator.NewScrubber(&globalCfg)
+	if err != nil {
+		return err
+	}
+	projects := make(map[int64]*validator.Scrubber, len(projectCfgs))
+	for projectID, cfg := range projectCfgs {
+		if projects[projectID], err = validator.NewScrubber(cfg); err != nil {
+			return err
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.global = global
+	e.projects = projects
+	e.loadedAt = time.Now()
+	return nil
+}
+
+// addRule adds a rule's pattern to a scrub configuration
+func addRule(cfg *config.ScrubConfig, rule *models.RedactionRule) error {
+	if strings.TrimSpace(rule.Pattern) == "" {
+		return fmt.Errorf("pattern is required")
+	}
+	switch rule.Kind {
+	case models.RedactKey:
+		cfg.KeyPatterns = append(cfg.KeyPatterns, rule.Pattern)
+	case models.RedactValue:
+		cfg.ValuePatterns = append(cfg.ValuePatterns, rule.Pattern)
+	case models.RedactPath:
+		cfg.Paths = append(cfg.Paths, rule.Pattern)
+	default:
+		return fmt.Errorf("kind must be %s, %s or %s", models.RedactKey, models.RedactValue, models.RedactPath)
+	}
+	return nil
+}
+
+// copyConfig copies a scrub configuration, so appending to the copy leaves the original alone
+func copyConfig(cfg *config.ScrubConfig) config.ScrubConfig {
+	return config.ScrubConfig{
+		KeyPatterns:   append([]string(nil), cfg.KeyPatterns...),
+		ValuePatterns: append([]string(nil), cfg.ValuePatterns...),
+		Paths:         append([]string(nil), cfg.Paths...),
+	}
+}
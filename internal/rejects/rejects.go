@@ -0,0 +1,195 @@
+// Package rejects counts logs and notices turned away by ingestion, by reason, and keeps an
+// optional scrubbed sample of the rejected payloads for debugging client integrations. Counts and
+// samples are kept in memory per instance.
+package rejects
+
+import (
+	"errors"
+	"log-ingestion-service/internal/validator"
+	"log-ingestion-service/pkg/config"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Kinds of rejected payload
+const (
+	KindLog    = "log"
+	KindNotice = "notice"
+)
+
+// Reasons besides the validator's
+const (
+	// ReasonInvalidBody is a request body that is not valid JSON of the expected shape
+	ReasonInvalidBody = "invalid_body"
+	// ReasonUnparseable is a log no parser could read
+	ReasonUnparseable = "unparseable"
+)
+
+// Source identifies where a rejected payload came from
+type Source struct {
+	// APIKey is empty for unauthenticated listeners
+	APIKey string
+	// Listener names the syslog listener a message arrived on; empty for HTTP
+	Listener string
+	Service  string
+}
+
+// Sample is a rejected payload, scrubbed and truncated
+type Sample struct {
+	ID         int64     `json:"id"`
+	Kind       string    `json:"kind"`
+	Reason     string    `json:"reason"`
+	Error      string    `json:"error,omitempty"`
+	Source     Source    `json:"-"`
+	ReceivedAt time.Time `json:"received_at"`
+	Payload    string    `json:"payload"`
+	// Size is the length of the payload as received
+	Size      int  `json:"size"`
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Store counts rejects and keeps the most recent samples
+type Store struct {
+	cfg       config.RejectsConfig
+	scrubbers validator.ScrubberSource
+	started   time.Time
+
+	mu      sync.Mutex
+	counts  map[string]map[string]int64
+	samples []Sample
+	nextID  int64
+}
+
+// NewStore creates a store sampling rejected payloads as configured. Payloads are scrubbed with
+// the log scrubber of scrubbers before they are kept.
+func NewStore(cfg *config.RejectsConfig, scrubbers validator.ScrubberSource) *Store {
+	return &Store{
+		cfg:       *cfg,
+		scrubbers: scrubbers,
+		started:   time.Now(),
+		counts:    make(map[string]map[string]int64),
+	}
+}
+
+// Reason classifies a rejection error. Errors that are not validation failures mean the payload
+// could not be parsed.
+func Reason(err error) string {
+	var invalid *validator.ValidationError
+	if errors.As(err, &invalid) {
+		return invalid.Reason
+	}
+	return ReasonUnparseable
+}
+
+// Record counts a rejected payload under reason and may keep a sample of it. It does nothing on
+// a nil store.
+func (s *Store) Record(kind, reason string, source Source, payload []byte, err error) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	byReason, ok := s.counts[kind]
+	if !ok {
+		byReason = make(map[string]int64)
+		s.counts[kind] = byReason
+	}
+	byReason[reason]++
+	s.mu.Unlock()
+
+	if s.cfg.SampleRate <= 0 || rand.Float64() >= s.cfg.SampleRate {
+		return
+	}
+
+	sample := Sample{
+		Kind:       kind,
+		Reason:     reason,
+		Source:     source,
+		ReceivedAt: time.Now().UTC(),
+		Size:       len(payload),
+	}
+	if err != nil {
+		sample.Error = err.Error()
+	}
+	if len(payload) > 0 {
+		scrubbed := payload
+		if s.scrubbers != nil {
+			scrubbed = s.scrubbers.LogScrubber().ScrubPayload(payload)
+		}
+		if len(scrubbed) > s.cfg.MaxSampleBytes {
+			scrubbed = truncate(scrubbed, s.cfg.MaxSampleBytes)
+			sample.Truncated = true
+		}
+		sample.Payload = string(scrubbed)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	sample.ID = s.nextID
+	s.samples = append(s.samples, sample)
+	if over := len(s.samples) - s.cfg.MaxSamples; over > 0 {
+		s.samples = append(s.samples[:0:0], s.samples[over:]...)
+	}
+}
+
+// Counts returns the number of rejects by kind and reason
+func (s *Store) Counts() map[string]map[string]int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make(map[string]map[string]int64, len(s.counts))
+	for kind, byReason := range s.counts {
+		copied := make(map[string]int64, len(byReason))
+		for reason, n := range byReason {
+			copied[reason] = n
+		}
+		out[kind] = copied
+	}
+	return out
+}
+
+// Samples returns kept samples, newest first, optionally only those of a kind or reason
+func (s *Store) Samples(kind, reason string) []Sample {
+	s.mu.Lock()
+	out := make([]Sample, 0, len(s.samples))
+	for _, sample := range s.samples {
+		if (kind == "" || sample.Kind == kind) && (reason == "" || sample.Reason == reason) {
+			out = append(out, sample)
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool { return out[i].ID > out[j].ID })
+	return out
+}
+
+// ClearSamples deletes every kept sample and returns how many there were
+func (s *Store) ClearSamples() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.samples)
+	s.samples = nil
+	return n
+}
+
+// Config returns the sampling configuration
+func (s *Store) Config() config.RejectsConfig {
+	return s.cfg
+}
+
+// Started returns when the store began counting
+func (s *Store) Started() time.Time {
+	return s.started
+}
+
+// truncate shortens data to at most n bytes without splitting a UTF-8 sequence
+func truncate(data []byte, n int) []byte {
+	for n > 0 && n < len(data) && !utf8.RuneStart(data[n]) {
+		n--
+	}
+	return data[:n]
+}
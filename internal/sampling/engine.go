@@ -0,0 +1,262 @@
+// Package sampling drops or samples incoming logs before they are buffered for storage, such as
+// keeping one in a hundred DEBUG logs from a noisy service. Rules from configuration are
+// checked first, then the rules stored from the admin API; the first matching rule decides.
+// How many logs each rule matched and dropped is counted in memory per instance.
+package sampling
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// refreshInterval bounds how long a rule changed on another instance takes to apply here
+const refreshInterval = 30 * time.Second
+
+// loadTimeout bounds reloading rules, which are checked without a request context
+const loadTimeout = 5 * time.Second
+
+// Rule sources
+const (
+	SourceConfig = "config"
+	SourceAdmin  = "admin"
+)
+
+// rule is a compiled sampling rule with its counters
+type rule struct {
+	id          int64
+	source      string
+	service     string
+	level       string
+	pattern     *regexp.Regexp
+	rate        float64
+	description string
+
+	matched int64
+	dropped int64
+}
+
+// matches reports whether a log meets every condition of the rule
+func (r *rule) matches(logEntry *models.LogEntry) bool {
+	if r.service != "" && logEntry.Service != r.service {
+		return false
+	}
+	if r.level != "" && !strings.EqualFold(logEntry.Level, r.level) {
+		return false
+	}
+	return r.pattern == nil || r.pattern.MatchString(logEntry.Message)
+}
+
+// RuleStats describes a rule and how many logs it matched and dropped
+type RuleStats struct {
+	// ID is set for rules from the admin API
+	ID             *int64  `json:"id,omitempty"`
+	Source         string  `json:"source"`
+	Service        string  `json:"service,omitempty"`
+	Level          string  `json:"level,omitempty"`
+	MessagePattern string  `json:"message_pattern,omitempty"`
+	SampleRate     float64 `json:"sample_rate"`
+	Description    string  `json:"description,omitempty"`
+	Matched        int64   `json:"matched"`
+	Dropped        int64   `json:"dropped"`
+}
+
+// Stats counts the logs checked and dropped since the instance started
+type Stats struct {
+	Since   time.Time   `json:"since"`
+	Checked int64       `json:"checked"`
+	Dropped int64       `json:"dropped"`
+	Rules   []RuleStats `json:"rules"`
+}
+
+// Engine checks logs against the sampling rules
+type Engine struct {
+	repo    *storage.Repository
+	base    []*rule
+	started time.Time
+
+	checked int64
+	dropped int64
+
+	mu       sync.Mutex
+	rules    []*rule
+	loadedAt time.Time
+}
+
+// New creates an engine applying the configured rules and loads the stored rules, so they apply
+// from the first log
+func New(ctx context.Context, cfg *config.SamplingConfig, repo *storage.Repository) (*Engine, error) {
+	e := &Engine{repo: repo, started: time.Now()}
+	for i, c := range cfg.Rules {
+		r, err := compile(c.Service, c.Level, c.MessagePattern, c.SampleRate)
+		if err != nil {
+			return nil, fmt.Errorf("sampling rule %d: %w", i, err)
+		}
+		r.source = SourceConfig
+		e.base = append(e.base, r)
+	}
+	if err := e.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Check reports whether a rule sets a condition and compiles
+func Check(rule *models.SamplingRule) error {
+	_, err := compile(rule.Service, rule.Level, rule.MessagePattern, rule.SampleRate)
+	return err
+}
+
+func compile(service, level, pattern string, rate float64) (*rule, error) {
+	if service == "" && level == "" && pattern == "" {
+		return nil, fmt.Errorf("service, level or message_pattern is required")
+	}
+	if level != "" {
+		known := false
+		for _, l := range config.CanonicalLevels {
+			if strings.EqualFold(level, l) {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("level must be one of %s", strings.Join(config.CanonicalLevels, ", "))
+		}
+	}
+	if rate < 0 || rate > 1 {
+		return nil, fmt.Errorf("sample_rate must be between 0 and 1")
+	}
+	r := &rule{service: service, level: level, rate: rate}
+	if pattern != "" {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("message_pattern is not a valid regular expression: %w", err)
+		}
+		r.pattern = compiled
+	}
+	return r, nil
+}
+
+// Keep reports whether a log is to be stored: it is when no rule matches it, and otherwise with
+// the matching rule's sample rate. If rules cannot be reloaded the last known rules are used.
+func (e *Engine) Keep(logEntry *models.LogEntry) bool {
+	if e == nil {
+		return true
+	}
+	atomic.AddInt64(&e.checked, 1)
+	for _, r := range e.current() {
+		if !r.matches(logEntry) {
+			continue
+		}
+		atomic.AddInt64(&r.matched, 1)
+		if r.rate >= 1 || (r.rate > 0 && rand.Float64() < r.rate) {
+			return true
+		}
+		atomic.AddInt64(&r.dropped, 1)
+		atomic.AddInt64(&e.dropped, 1)
+		return false
+	}
+	return true
+}
+
+// current returns the rules in the order they are checked, reloading them when stale
+func (e *Engine) current() []*rule {
+	e.mu.Lock()
+	stale := time.Since(e.loadedAt) >= refreshInterval
+	if stale {
+		// One caller reloads; the others carry on with the current rules
+		e.loadedAt = time.Now()
+	}
+	e.mu.Unlock()
+	if stale {
+		ctx, cancel := context.WithTimeout(context.Background(), loadTimeout)
+		defer cancel()
+		if err := e.Reload(ctx); err != nil {
+			log.Printf("WARN: Failed to load sampling rules: %v", err)
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.rules
+}
+
+// Stats returns the rules with their counters, in the order they are checked
+func (e *Engine) Stats() Stats {
+	stats := Stats{
+		Since:   e.started,
+		Checked: atomic.LoadInt64(&e.checked),
+		Dropped: atomic.LoadInt64(&e.dropped),
+		Rules:   []RuleStats{},
+	}
+	for _, r := range e.current() {
+		rs := RuleStats{
+			Source:      r.source,
+			Service:     r.service,
+			Level:       r.level,
+			SampleRate:  r.rate,
+			Description: r.description,
+			Matched:     atomic.LoadInt64(&r.matched),
+			Dropped:     atomic.LoadInt64(&r.dropped),
+		}
+		if r.pattern != nil {
+			rs.MessagePattern = r.pattern.String()
+		}
+		if r.source == SourceAdmin {
+			id := r.id
+			rs.ID = &id
+		}
+		stats.Rules = append(stats.Rules, rs)
+	}
+	return stats
+}
+
+// Reload reads the stored rules and compiles them. Rules that no longer compile are skipped
+// and logged. The counters of rules still stored carry over.
+func (e *Engine) Reload(ctx context.Context) error {
+	stored, err := e.repo.ListSamplingRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	previous := make(map[int64]*rule)
+	for _, r := range e.rules {
+		if r.source == SourceAdmin {
+			previous[r.id] = r
+		}
+	}
+	e.mu.Unlock()
+
+	rules := append([]*rule(nil), e.base...)
+	for _, s := range stored {
+		if r, ok := previous[s.ID]; ok {
+			rules = append(rules, r)
+			continue
+		}
+		r, err := compile(s.Service, s.Level, s.MessagePattern, s.SampleRate)
+		if err != nil {
+			log.Printf("WARN: Skipping sampling rule %d: %v", s.ID, err)
+			continue
+		}
+		r.id = s.ID
+		r.source = SourceAdmin
+		r.description = s.Description
+		rules = append(rules, r)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = rules
+	e.loadedAt = time.Now()
+	return nil
+}
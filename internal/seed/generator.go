@@ -0,0 +1,216 @@
+package seed
+
+import (
+	"fmt"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// faultTemplate describes a family of realistic errors
+type faultTemplate struct {
+	class    string
+	messages []string
+	frames   []string
+}
+
+var faultTemplates = []faultTemplate{
+	{"NoMethodError", []string{"undefined method `%s' for nil:NilClass"}, []string{"app/models/order.rb", "app/services/checkout.rb"}},
+	{"ActiveRecord::RecordNotFound", []string{"Couldn't find User with 'id'=%d"}, []string{"app/controllers/users_controller.rb"}},
+	{"Net::ReadTimeout", []string{"Net::ReadTimeout with #<TCPSocket:(closed)>"}, []string{"app/clients/payment_gateway.rb", "app/jobs/charge_job.rb"}},
+	{"TypeError", []string{"Cannot read properties of undefined (reading '%s')"}, []string{"src/components/Cart.vue", "src/store/cart.js"}},
+	{"KeyError", []string{"'%s'"}, []string{"app/handlers/webhook.py", "app/serializers.py"}},
+	{"psycopg2.OperationalError", []string{"could not connect to server: Connection refused"}, []string{"app/db.py"}},
+	{"java.lang.NullPointerException", []string{"Cannot invoke \"%s()\" because value is null"}, []string{"src/main/java/com/example/InvoiceService.java"}},
+	{"context.DeadlineExceeded", []string{"context deadline exceeded while calling %s"}, []string{"internal/search/client.go", "internal/api/search.go"}},
+	{"RuntimeError", []string{"Redis connection pool exhausted (%d/%d)"}, []string{"lib/cache.rb"}},
+	{"ValueError", []string{"invalid literal for int() with base 10: '%s'"}, []string{"app/forms.py"}},
+}
+
+var appFrames = []string{"orders", "billing", "accounts", "search", "notifications"}
+
+var identifiers = []string{"total", "email", "shipping_address", "currency", "price", "items", "token", "locale"}
+
+var environments = map[string]int{"production": 70, "staging": 20, "development": 10}
+
+var tagPool = []string{"checkout", "payments", "frontend", "backend", "flaky", "regression", "customer-reported", "p1"}
+
+var firstNames = []string{"Ada", "Grace", "Alan", "Linus", "Margaret", "Ken", "Barbara", "Dennis", "Radia", "Frances"}
+
+var lastNames = []string{"Lovelace", "Hopper", "Turing", "Torvalds", "Hamilton", "Thompson", "Liskov", "Ritchie", "Perlman", "Allen"}
+
+var commentTexts = []string{
+	"Looking into this now.",
+	"Started after the last deploy, rolling back.",
+	"Can't reproduce locally, need more context.",
+	"Same root cause as the payment timeout, see linked fault.",
+	"Fixed in the next release.",
+	"Only affects a handful of customers on the old mobile app.",
+}
+
+var services = []string{"api-gateway", "orders", "billing", "worker", "frontend"}
+
+var logLevels = map[string]int{"debug": 15, "info": 60, "warn": 15, "error": 9, "fatal": 1}
+
+var logMessages = []string{
+	"request completed",
+	"cache miss for key %s",
+	"retrying upstream call (attempt %d)",
+	"user %d signed in",
+	"slow query took %dms",
+	"job enqueued: %s",
+	"payment declined for order %d",
+	"connection reset by peer",
+}
+
+var hostnames = []string{"web-1", "web-2", "web-3", "worker-1", "worker-2"}
+
+var revisions = []string{"a1b2c3d", "e4f5a6b", "c7d8e9f", "0a1b2c3"}
+
+// generator produces random but plausible sample records
+type generator struct {
+	rng  *rand.Rand
+	now  time.Time
+	span time.Duration
+}
+
+func (g *generator) pick(values []string) string {
+	return values[g.rng.Intn(len(values))]
+}
+
+func (g *generator) chance(p float64) bool {
+	return g.rng.Float64() < p
+}
+
+// weighted picks a key with probability proportional to its weight
+func (g *generator) weighted(weights map[string]int) string {
+	total := 0
+	for _, w := range weights {
+		total += w
+	}
+	n := g.rng.Intn(total)
+	// Iterate in a stable order so a fixed random seed reproduces the same data
+	keys := make([]string, 0, len(weights))
+	for key := range weights {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		n -= weights[key]
+		if n < 0 {
+			return key
+		}
+	}
+	return ""
+}
+
+// past returns a random time within the seeding window
+func (g *generator) past() time.Time {
+	return g.now.Add(-time.Duration(g.rng.Int63n(int64(g.span))))
+}
+
+// message fills a template message with plausible values
+func (g *generator) message(tmpl faultTemplate) string {
+	msg := g.pick(tmpl.messages)
+	switch {
+	case strings.Contains(msg, "%s"):
+		return fmt.Sprintf(msg, g.pick(identifiers))
+	case strings.Contains(msg, "%d/%d"):
+		return fmt.Sprintf(msg, 50, 50)
+	case strings.Contains(msg, "%d"):
+		return fmt.Sprintf(msg, 1000+g.rng.Intn(90000))
+	}
+	return msg
+}
+
+// backtrace builds a stack whose top frames come from the template and the rest from framework code
+func (g *generator) backtrace(tmpl faultTemplate, app string) []models.BacktraceFrame {
+	var frames []models.BacktraceFrame
+	for _, file := range tmpl.frames {
+		line := 10 + g.rng.Intn(400)
+		frames = append(frames, models.BacktraceFrame{
+			File:     file,
+			Line:     &line,
+			Function: fmt.Sprintf("%s_%s", app, g.pick(identifiers)),
+			Context:  "app",
+		})
+	}
+	for i := 0; i < 3+g.rng.Intn(5); i++ {
+		line := 1 + g.rng.Intn(900)
+		frames = append(frames, models.BacktraceFrame{
+			File:     fmt.Sprintf("vendor/framework/lib/dispatch_%d.rb", i),
+			Line:     &line,
+			Function: "call",
+			Context:  "all",
+		})
+	}
+	return frames
+}
+
+func (g *generator) tags() []string {
+	tags := []string{}
+	for _, tag := range tagPool {
+		if g.chance(0.15) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// notice builds one occurrence of fault between firstSeen and now
+func (g *generator) notice(fault *models.Fault, frames []models.BacktraceFrame, environment string, firstSeen time.Time) *models.Notice {
+	createdAt := firstSeen.Add(time.Duration(g.rng.Int63n(int64(g.now.Sub(firstSeen)) + 1)))
+	hostname := g.pick(hostnames)
+	revision := g.pick(revisions)
+
+	return &models.Notice{
+		ID:        fmt.Sprintf("%010x%016x", createdAt.UnixMilli(), g.rng.Int63()),
+		FaultID:   fault.ID,
+		Message:   fault.Message,
+		Backtrace: frames,
+		Context: map[string]interface{}{
+			"user_id":    1 + g.rng.Intn(5000),
+			"request_id": fmt.Sprintf("%08x", g.rng.Uint32()),
+		},
+		Params: map[string]interface{}{
+			"controller": "orders",
+			"action":     "create",
+		},
+		Environment: map[string]interface{}{
+			"environment_name": environment,
+		},
+		Breadcrumbs: []models.Breadcrumb{
+			{Category: "request", Message: "POST /orders", Time: createdAt.Add(-2 * time.Second)},
+			{Category: "query", Message: "SELECT * FROM orders WHERE id = ?", Time: createdAt.Add(-time.Second)},
+		},
+		Revision:  &revision,
+		Hostname:  &hostname,
+		CreatedAt: createdAt,
+	}
+}
+
+// logEntry builds one sample log tagged with the sandbox project
+func (g *generator) logEntry(projectID int64) models.LogEntry {
+	msg := g.pick(logMessages)
+	switch {
+	case strings.Contains(msg, "%s"):
+		msg = fmt.Sprintf(msg, g.pick(identifiers))
+	case strings.Contains(msg, "%d"):
+		msg = fmt.Sprintf(msg, 1+g.rng.Intn(2000))
+	}
+
+	return models.LogEntry{
+		Timestamp: g.past(),
+		Service:   g.pick(services),
+		Level:     g.weighted(logLevels),
+		Message:   msg,
+		Metadata: map[string]interface{}{
+			storage.SandboxLogKey: projectID,
+			"hostname":            g.pick(hostnames),
+			"duration_ms":         g.rng.Intn(1500),
+		},
+	}
+}
@@ -0,0 +1,286 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"math/rand"
+	"time"
+)
+
+// DefaultProjectID is the sandbox project sample data is written to when none is given
+const DefaultProjectID int64 = 9999
+
+// Limits on a single seeding run
+const (
+	maxFaults          = 500
+	maxNoticesPerFault = 200
+	maxLogs            = 100000
+	maxUsers           = 50
+)
+
+// Options controls how much sample data is generated
+type Options struct {
+	ProjectID       int64 `json:"project_id"`
+	Faults          int   `json:"faults"`
+	NoticesPerFault int   `json:"notices_per_fault"`
+	Logs            int   `json:"logs"`
+	Users           int   `json:"users"`
+	Days            int   `json:"days"`
+	RandomSeed      int64 `json:"random_seed"`
+	Wipe            bool  `json:"wipe"`
+}
+
+// Summary reports what a seeding run generated
+type Summary struct {
+	ProjectID int64                `json:"project_id"`
+	Wiped     *storage.SandboxWipe `json:"wiped,omitempty"`
+	Users     int                  `json:"users"`
+	Faults    int                  `json:"faults"`
+	Notices   int                  `json:"notices"`
+	Comments  int                  `json:"comments"`
+	Logs      int                  `json:"logs"`
+	Duration  string               `json:"duration"`
+}
+
+// DefaultOptions returns a small data set suitable for demos and UI development
+func DefaultOptions() Options {
+	return Options{
+		ProjectID:       DefaultProjectID,
+		Faults:          25,
+		NoticesPerFault: 20,
+		Logs:            2000,
+		Users:           5,
+		Days:            14,
+	}
+}
+
+// Validate checks that options are within the seeding limits
+func (o *Options) Validate() error {
+	switch {
+	case o.ProjectID <= 0:
+		return fmt.Errorf("project_id must be positive")
+	case o.Faults < 0 || o.Faults > maxFaults:
+		return fmt.Errorf("faults must be between 0 and %d", maxFaults)
+	case o.NoticesPerFault < 1 || o.NoticesPerFault > maxNoticesPerFault:
+		return fmt.Errorf("notices_per_fault must be between 1 and %d", maxNoticesPerFault)
+	case o.Logs < 0 || o.Logs > maxLogs:
+		return fmt.Errorf("logs must be between 0 and %d", maxLogs)
+	case o.Users < 0 || o.Users > maxUsers:
+		return fmt.Errorf("users must be between 0 and %d", maxUsers)
+	case o.Days < 1 || o.Days > 90:
+		return fmt.Errorf("days must be between 1 and 90")
+	}
+	return nil
+}
+
+// Seeder writes realistic sample faults, notices, logs and users into a sandbox project
+type Seeder struct {
+	repo *storage.Repository
+}
+
+// NewSeeder creates a new seeder
+func NewSeeder(repo *storage.Repository) *Seeder {
+	return &Seeder{repo: repo}
+}
+
+// Run generates sample data according to opts, wiping the sandbox project first if requested
+func (s *Seeder) Run(ctx context.Context, opts Options) (*Summary, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	seed := opts.RandomSeed
+	if seed == 0 {
+		seed = start.UnixNano()
+	}
+	g := &generator{
+		rng:  rand.New(rand.NewSource(seed)),
+		now:  start,
+		span: time.Duration(opts.Days) * 24 * time.Hour,
+	}
+
+	summary := &Summary{ProjectID: opts.ProjectID}
+
+	// Marked first, so a real project is refused before anything is written to it
+	if err := s.repo.MarkSandbox(ctx, opts.ProjectID); err != nil {
+		return nil, err
+	}
+
+	if opts.Wipe {
+		wipe, err := s.repo.WipeSandbox(ctx, opts.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		summary.Wiped = wipe
+	}
+
+	users, err := s.seedUsers(ctx, g, opts)
+	if err != nil {
+		return nil, err
+	}
+	summary.Users = len(users)
+
+	for i := 0; i < opts.Faults; i++ {
+		notices, comments, ok, err := s.seedFault(ctx, g, opts, users)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			summary.Faults++
+			summary.Notices += notices
+			summary.Comments += comments
+		}
+	}
+
+	if err := s.seedLogs(ctx, g, opts); err != nil {
+		return nil, err
+	}
+	summary.Logs = opts.Logs
+
+	summary.Duration = time.Since(start).Round(time.Millisecond).String()
+	return summary, nil
+}
+
+// Wipe removes all sample data from a sandbox project; other projects are refused with
+// storage.ErrNotSandbox
+func (s *Seeder) Wipe(ctx context.Context, projectID int64) (*storage.SandboxWipe, error) {
+	return s.repo.WipeSandbox(ctx, projectID)
+}
+
+// seedUsers creates sandbox users, reusing any that already exist
+func (s *Seeder) seedUsers(ctx context.Context, g *generator, opts Options) ([]models.User, error) {
+	users := make([]models.User, 0, opts.Users)
+	for i := 0; i < opts.Users; i++ {
+		email := storage.SandboxUserEmail(opts.ProjectID, i+1)
+		if existing, err := s.repo.GetUserByEmail(ctx, email); err == nil {
+			users = append(users, *existing)
+			continue
+		}
+
+		user := models.User{
+			Email: email,
+			Name:  g.pick(firstNames) + " " + g.pick(lastNames),
+		}
+		if err := s.repo.CreateUser(ctx, &user); err != nil {
+			return nil, fmt.Errorf("error creating sandbox user: %w", err)
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// seedFault creates one fault with notices, and sometimes an assignee, tags, comments and a workflow state.
+// It reports false if the fingerprint already belongs to a fault outside the sandbox.
+func (s *Seeder) seedFault(ctx context.Context, g *generator, opts Options, users []models.User) (int, int, bool, error) {
+	tmpl := faultTemplates[g.rng.Intn(len(faultTemplates))]
+	app := g.pick(appFrames)
+	environment := g.weighted(environments)
+	frames := g.backtrace(tmpl, app)
+	location := fmt.Sprintf("%s:%d", frames[0].File, *frames[0].Line)
+	firstSeen := g.past()
+
+	projectID := opts.ProjectID
+	fault, err := s.repo.CreateFault(ctx, &models.Fault{
+		ProjectID:   &projectID,
+		ErrorClass:  tmpl.class,
+		Message:     g.message(tmpl),
+		Location:    &location,
+		Environment: environment,
+		Tags:        g.tags(),
+		FirstSeenAt: firstSeen,
+		LastSeenAt:  firstSeen,
+	})
+	if err != nil {
+		return 0, 0, false, err
+	}
+	if fault.ProjectID == nil || *fault.ProjectID != opts.ProjectID {
+		return 0, 0, false, nil
+	}
+
+	// Skew notice counts so a few faults dominate, as in real projects
+	count := 1 + int(float64(opts.NoticesPerFault)*g.rng.ExpFloat64()/2)
+	if count > maxNoticesPerFault {
+		count = maxNoticesPerFault
+	}
+
+	lastSeen := firstSeen
+	for i := 0; i < count; i++ {
+		notice := g.notice(fault, frames, environment, firstSeen)
+		notice.ProjectID = &projectID
+		if notice.CreatedAt.After(lastSeen) {
+			lastSeen = notice.CreatedAt
+		}
+		if err := s.repo.CreateNotice(ctx, notice); err != nil {
+			return 0, 0, false, fmt.Errorf("error creating sandbox notice: %w", err)
+		}
+	}
+
+	if err := s.repo.SetFaultSeenStats(ctx, fault.ID, int64(count), firstSeen, lastSeen); err != nil {
+		return 0, 0, false, err
+	}
+
+	comments := 0
+	if len(users) > 0 {
+		if g.chance(0.4) {
+			assignee := users[g.rng.Intn(len(users))].ID
+			if err := s.repo.AssignFault(ctx, fault.ID, &assignee, &assignee); err != nil {
+				return 0, 0, false, err
+			}
+		}
+		for n := g.rng.Intn(3); n > 0; n-- {
+			comment := &models.Comment{
+				FaultID: fault.ID,
+				UserID:  users[g.rng.Intn(len(users))].ID,
+				Comment: g.pick(commentTexts),
+			}
+			if err := s.repo.CreateComment(ctx, comment); err != nil {
+				return 0, 0, false, fmt.Errorf("error creating sandbox comment: %w", err)
+			}
+			comments++
+		}
+	}
+
+	// States are those of the default workflow
+	state := ""
+	switch {
+	case g.chance(0.2):
+		state = "resolved"
+	case g.chance(0.05):
+		state = "wontfix"
+	case g.chance(0.2):
+		state = g.pick([]string{"acknowledged", "in_progress"})
+	}
+	if state != "" {
+		move := func(string) (string, bool, bool, error) {
+			return state, state == "resolved", state == "wontfix", nil
+		}
+		if err := s.repo.MoveFault(ctx, fault.ID, "state_changed", move, nil); err != nil {
+			return 0, 0, false, err
+		}
+		if err := s.repo.UpdateFaultSLAClock(ctx, fault.ID, "new", []string{"resolved", "wontfix"}); err != nil {
+			return 0, 0, false, err
+		}
+	}
+
+	return count, comments, true, nil
+}
+
+// seedLogs inserts sample logs in batches, tagged with the sandbox project so they can be wiped
+func (s *Seeder) seedLogs(ctx context.Context, g *generator, opts Options) error {
+	const batchSize = 1000
+
+	batch := make([]models.LogEntry, 0, batchSize)
+	for i := 0; i < opts.Logs; i++ {
+		batch = append(batch, g.logEntry(opts.ProjectID))
+		if len(batch) == batchSize || i == opts.Logs-1 {
+			if err := s.repo.InsertBatch(ctx, batch); err != nil {
+				return fmt.Errorf("error inserting sandbox logs: %w", err)
+			}
+			batch = batch[:0]
+		}
+	}
+	return nil
+}
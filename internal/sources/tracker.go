@@ -0,0 +1,245 @@
+// Package sources tracks ingestion per source, an API key or syslog listener and the service its
+// logs name, so a client sending invalid logs can be found quickly. Statistics are kept in memory
+// and cover the time since this instance started.
+package sources
+
+import (
+	"log-ingestion-service/internal/rejects"
+	"sort"
+	"sync"
+	"time"
+)
+
+// rateWindow is the number of one-minute buckets rates are averaged over
+const rateWindow = 5
+
+// maxSources bounds memory use; sources seen after the limit are counted under OverflowService
+const maxSources = 10000
+
+// OverflowService is the service recorded for new sources once maxSources are tracked
+const OverflowService = "(other)"
+
+// Longer service names and validation errors are truncated
+const (
+	maxServiceLength = 255
+	maxErrorLength   = 500
+)
+
+// Key identifies a source
+type Key struct {
+	// APIKey is empty for unauthenticated listeners
+	APIKey string
+	// Listener names the syslog listener messages arrived on; empty for HTTP
+	Listener string
+	// Service is empty when a log could not be parsed far enough to name one
+	Service string
+}
+
+// Stats summarizes one source
+type Stats struct {
+	Key           `json:"-"`
+	Accepted      int64     `json:"accepted"`
+	Rejected      int64     `json:"rejected"`
+	RejectedBytes int64     `json:"rejected_bytes"`
+	FirstSeen     time.Time `json:"first_seen"`
+	LastSeen      time.Time `json:"last_seen"`
+	LastRejected  *time.Time `json:"last_rejected,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+	// Rates are logs per minute over the last rateWindow minutes
+	AcceptedPerMinute float64 `json:"accepted_per_minute"`
+	RejectedPerMinute float64 `json:"rejected_per_minute"`
+	// ErrorRate is the share of logs rejected since the source was first seen
+	ErrorRate float64 `json:"error_rate"`
+}
+
+type bucket struct {
+	minute   int64
+	accepted int64
+	rejected int64
+}
+
+type source struct {
+	stats   Stats
+	buckets [rateWindow]bucket
+}
+
+// add counts logs in the bucket for minute, reusing the slot of an expired minute
+func (s *source) add(minute, accepted, rejected int64) {
+	b := &s.buckets[minute%rateWindow]
+	if b.minute != minute {
+		*b = bucket{minute: minute}
+	}
+	b.accepted += accepted
+	b.rejected += rejected
+}
+
+// Tracker records ingestion per source
+type Tracker struct {
+	rejects *rejects.Store
+
+	mu      sync.Mutex
+	sources map[Key]*source
+	started time.Time
+}
+
+// NewTracker creates an empty tracker that also passes rejected logs to rejected, if not nil
+func NewTracker(rejected *rejects.Store) *Tracker {
+	return &Tracker{rejects: rejected, sources: make(map[Key]*source), started: time.Now()}
+}
+
+// Started returns when the tracker began counting
+func (t *Tracker) Started() time.Time {
+	return t.started
+}
+
+// Recorder collects the outcome of the logs in one request, so the tracker is locked once per
+// request rather than once per log. A nil Recorder discards everything.
+type Recorder struct {
+	tracker  *Tracker
+	apiKey   string
+	listener string
+	counts   map[string]*Stats
+}
+
+// Begin starts recording a request authenticated with apiKey, or arriving on a syslog listener.
+// It returns nil on a nil tracker.
+func (t *Tracker) Begin(apiKey, listener string) *Recorder {
+	if t == nil {
+		return nil
+	}
+	return &Recorder{tracker: t, apiKey: apiKey, listener: listener, counts: make(map[string]*Stats, 1)}
+}
+
+func (r *Recorder) service(name string) *Stats {
+	name = truncate(name, maxServiceLength)
+	s, ok := r.counts[name]
+	if !ok {
+		s = &Stats{}
+		r.counts[name] = s
+	}
+	return s
+}
+
+// Accept counts a log accepted for service
+func (r *Recorder) Accept(service string) {
+	if r == nil {
+		return
+	}
+	r.service(service).Accepted++
+}
+
+// Reject counts a log rejected for err, classified by rejects.Reason
+func (r *Recorder) Reject(service string, payload []byte, err error) {
+	r.reject(service, rejects.Reason(err), payload, err)
+}
+
+// RejectBody counts a request body that could not be decoded into logs
+func (r *Recorder) RejectBody(payload []byte, err error) {
+	r.reject("", rejects.ReasonInvalidBody, payload, err)
+}
+
+func (r *Recorder) reject(service, reason string, payload []byte, err error) {
+	if r == nil {
+		return
+	}
+	s := r.service(service)
+	s.Rejected++
+	s.RejectedBytes += int64(len(payload))
+	if err != nil {
+		s.LastError = err.Error()
+	}
+	source := rejects.Source{APIKey: r.apiKey, Listener: r.listener, Service: service}
+	r.tracker.rejects.Record(rejects.KindLog, reason, source, payload, err)
+}
+
+// Flush adds the recorded counts to the tracker
+func (r *Recorder) Flush() {
+	if r == nil || len(r.counts) == 0 {
+		return
+	}
+	r.tracker.record(r.apiKey, r.listener, r.counts, time.Now())
+	r.counts = make(map[string]*Stats, 1)
+}
+
+func (t *Tracker) record(apiKey, listener string, counts map[string]*Stats, now time.Time) {
+	minute := now.Unix() / 60
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for service, c := range counts {
+		key := Key{APIKey: apiKey, Listener: listener, Service: service}
+		s, ok := t.sources[key]
+		if !ok {
+			if len(t.sources) >= maxSources {
+				key.Service = OverflowService
+				s, ok = t.sources[key]
+			}
+			if !ok {
+				s = &source{stats: Stats{Key: key, FirstSeen: now}}
+				t.sources[key] = s
+			}
+		}
+
+		s.stats.Accepted += c.Accepted
+		s.stats.Rejected += c.Rejected
+		s.stats.RejectedBytes += c.RejectedBytes
+		s.stats.LastSeen = now
+		if c.Rejected > 0 {
+			rejectedAt := now
+			s.stats.LastRejected = &rejectedAt
+			if c.LastError != "" {
+				s.stats.LastError = truncate(c.LastError, maxErrorLength)
+			}
+		}
+		s.add(minute, c.Accepted, c.Rejected)
+	}
+}
+
+// Snapshot returns every source, those rejecting the most logs per minute first
+func (t *Tracker) Snapshot() []Stats {
+	now := time.Now()
+	minute := now.Unix() / 60
+
+	t.mu.Lock()
+	out := make([]Stats, 0, len(t.sources))
+	for _, s := range t.sources {
+		stats := s.stats
+		var accepted, rejected int64
+		for _, b := range s.buckets {
+			if b.minute > minute-rateWindow {
+				accepted += b.accepted
+				rejected += b.rejected
+			}
+		}
+		stats.AcceptedPerMinute = float64(accepted) / rateWindow
+		stats.RejectedPerMinute = float64(rejected) / rateWindow
+		if total := stats.Accepted + stats.Rejected; total > 0 {
+			stats.ErrorRate = float64(stats.Rejected) / float64(total)
+		}
+		out = append(out, stats)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].RejectedPerMinute != out[j].RejectedPerMinute {
+			return out[i].RejectedPerMinute > out[j].RejectedPerMinute
+		}
+		if out[i].Rejected != out[j].Rejected {
+			return out[i].Rejected > out[j].Rejected
+		}
+		return out[i].LastSeen.After(out[j].LastSeen)
+	})
+	return out
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
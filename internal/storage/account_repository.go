@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+)
+
+// ListFaultAccounts returns the accounts a fault's notices were reported for, most recently
+// affected first
+func (r *Repository) ListFaultAccounts(ctx context.Context, faultID int64, limit, offset int) ([]models.AffectedAccount, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT account_id, COUNT(*), MIN(created_at), MAX(created_at)
+		FROM notices
+		WHERE fault_id = $1 AND account_id IS NOT NULL
+		GROUP BY account_id
+		ORDER BY MAX(created_at) DESC, account_id
+		LIMIT $2 OFFSET $3
+	`, faultID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error listing fault accounts: %w", err)
+	}
+	defer rows.Close()
+
+	accounts := []models.AffectedAccount{}
+	for rows.Next() {
+		var a models.AffectedAccount
+		if err := rows.Scan(&a.AccountID, &a.NoticeCount, &a.FirstSeenAt, &a.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("error scanning fault account: %w", err)
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+// ListAccountFaults returns the faults with notices reported for an account, most recently seen
+// for the account first. With unresolved set, resolved and ignored faults are left out.
+func (r *Repository) ListAccountFaults(ctx context.Context, accountID string, unresolved bool, limit, offset int) ([]models.AccountFault, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT f.id, f.project_id, f.error_class, f.message, f.environment, f.resolved, f.ignored,
+		       a.notice_count, a.first_seen_at, a.last_seen_at
+		FROM (
+			SELECT fault_id, COUNT(*) AS notice_count, MIN(created_at) AS first_seen_at, MAX(created_at) AS last_seen_at
+			FROM notices
+			WHERE account_id = $1
+			GROUP BY fault_id
+		) a
+		JOIN faults f ON f.id = a.fault_id
+		WHERE NOT $2 OR (NOT f.resolved AND NOT f.ignored)
+		ORDER BY a.last_seen_at DESC, f.id DESC
+		LIMIT $3 OFFSET $4
+	`, accountID, unresolved, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error listing account faults: %w", err)
+	}
+	defer rows.Close()
+
+	faults := []models.AccountFault{}
+	for rows.Next() {
+		var f models.AccountFault
+		err := rows.Scan(&f.FaultID, &f.ProjectID, &f.ErrorClass, &f.Message, &f.Environment, &f.Resolved, &f.Ignored,
+			&f.NoticeCount, &f.FirstSeenAt, &f.LastSeenAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning account fault: %w", err)
+		}
+		faults = append(faults, f)
+	}
+	return faults, rows.Err()
+}
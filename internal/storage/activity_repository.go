@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+)
+
+// GetProjectActivity returns a page of a project's activity, newest first.
+// Fault creation, fault history and comments come from their own tables; deploys come from
+// the deploys recorded by the deploy analyzer, one per revision at its first environment.
+// types restricts the feed to the given event types when non-empty.
+func (r *Repository) GetProjectActivity(ctx context.Context, projectID int64, types []string, limit, offset int) ([]models.ActivityEvent, error) {
+	query := `
+		SELECT a.type, a.id, a.fault_id, a.error_class, a.action, a.comment, a.revision, a.user_id, a.created_at,
+		       u.email, u.name, u.avatar_url, u.is_admin, u.created_at
+		FROM (
+			SELECT 'fault_created' AS type, f.id, f.id AS fault_id, f.error_class,
+			       NULL::TEXT AS action, NULL::TEXT AS comment, NULL::TEXT AS revision,
+			       NULL::BIGINT AS user_id, f.first_seen_at AS created_at
+			FROM faults f
+			WHERE f.project_id = $1
+
+			UNION ALL
+
+			SELECT 'fault_history', h.id, h.fault_id, f.error_class,
+			       h.action, NULL, h.revision, h.user_id, h.created_at
+			FROM fault_history h
+			JOIN faults f ON f.id = h.fault_id
+			WHERE f.project_id = $1
+
+			UNION ALL
+
+			SELECT 'comment', c.id, c.fault_id, f.error_class,
+			       NULL, c.comment, NULL, c.user_id, c.created_at
+			FROM fault_comments c
+			JOIN faults f ON f.id = c.fault_id
+			WHERE f.project_id = $1
+
+			UNION ALL
+
+			-- Matches the expression of idx_deploys_revision; project IDs are never -1
+			SELECT 'deploy', NULL, NULL, NULL,
+			       NULL, NULL, d.revision, NULL, MIN(d.deployed_at)
+			FROM deploys d
+			WHERE COALESCE(d.project_id, -1) = $1
+			GROUP BY d.revision
+		) a
+		LEFT JOIN users u ON u.id = a.user_id
+		WHERE cardinality($2::TEXT[]) = 0 OR a.type = ANY($2)
+		ORDER BY a.created_at DESC, a.type, a.id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	if types == nil {
+		types = []string{}
+	}
+
+	rows, err := r.pool.Query(ctx, query, projectID, types, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error getting project activity: %w", err)
+	}
+	defer rows.Close()
+
+	var events []models.ActivityEvent
+	for rows.Next() {
+		var e models.ActivityEvent
+		var userEmail, userName, userAvatarURL sql.NullString
+		var userIsAdmin sql.NullBool
+		var userCreatedAt sql.NullTime
+
+		err := rows.Scan(
+			&e.Type,
+			&e.ID,
+			&e.FaultID,
+			&e.ErrorClass,
+			&e.Action,
+			&e.Comment,
+			&e.Revision,
+			&e.UserID,
+			&e.CreatedAt,
+			&userEmail,
+			&userName,
+			&userAvatarURL,
+			&userIsAdmin,
+			&userCreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning activity: %w", err)
+		}
+
+		if e.UserID != nil && userEmail.Valid {
+			e.User = &models.User{
+				ID:        *e.UserID,
+				Email:     userEmail.String,
+				Name:      userName.String,
+				IsAdmin:   userIsAdmin.Valid && userIsAdmin.Bool,
+				CreatedAt: userCreatedAt.Time,
+			}
+			if userAvatarURL.Valid {
+				e.User.AvatarURL = &userAvatarURL.String
+			}
+		}
+
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// noticeBacktrace selects a notice's backtrace, from the backtraces table or, for notices
+// stored before backtraces were deduplicated, the notice itself. Queries selecting it join
+// noticeBacktraceJoin on the notice alias n.
+const noticeBacktrace = "COALESCE(b.frames, n.backtrace)"
+
+const noticeBacktraceJoin = "LEFT JOIN backtraces b ON b.hash = n.backtrace_hash"
+
+// newBacktraceSet returns the set of distinct backtraces of notices being stored
+func newBacktraceSet() *contentSet {
+	return &contentSet{table: "backtraces", column: "frames"}
+}
+
+// DeleteUnusedBacktraces deletes up to limit backtraces that no notice references any more,
+// once notices were removed by retention or with their fault. Backtraces used within grace are
+// kept, since notices referencing them may still be in flight.
+func (r *Repository) DeleteUnusedBacktraces(ctx context.Context, grace time.Duration, limit int) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM backtraces
+		WHERE hash IN (
+			SELECT b.hash
+			FROM backtraces b
+			WHERE b.last_used_at < $1
+			  AND NOT EXISTS (SELECT 1 FROM notices n WHERE n.backtrace_hash = b.hash)
+			LIMIT $2
+		)
+	`, time.Now().Add(-grace), limit)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting unused backtraces: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ProjectBadgeStats holds the aggregates shown on a project's badges
+type ProjectBadgeStats struct {
+	// OpenFaults counts faults neither resolved nor ignored
+	OpenFaults int64
+	// DayNotices counts notices received in the last 24 hours
+	DayNotices int64
+	// OutageHours counts the hours within the uptime window with a notice of a high or
+	// critical fault; UptimeHours is the length of that window
+	OutageHours int64
+	UptimeHours int64
+}
+
+// GetProjectBadgeStats returns the badge aggregates of a project, with uptime measured over
+// window. It returns nil for a project without faults, as projects exist only through them.
+func (r *Repository) GetProjectBadgeStats(ctx context.Context, projectID int64, window time.Duration) (*ProjectBadgeStats, error) {
+	var exists bool
+	if err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM faults WHERE project_id = $1)`, projectID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("error getting project badge stats: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	stats := ProjectBadgeStats{UptimeHours: int64(window / time.Hour)}
+	err := r.pool.QueryRow(ctx, `
+		SELECT
+			(SELECT COUNT(*) FROM faults
+			 WHERE project_id = $1 AND resolved = FALSE AND ignored = FALSE),
+			(SELECT COUNT(*) FROM notices
+			 WHERE project_id = $1 AND created_at >= NOW() - INTERVAL '1 day'),
+			(SELECT COUNT(DISTINCT date_trunc('hour', n.created_at))
+			 FROM notices n
+			 JOIN faults f ON f.id = n.fault_id
+			 WHERE n.project_id = $1 AND n.created_at >= NOW() - make_interval(hours => $2)
+			   AND f.severity IN ('high', 'critical'))
+	`, projectID, stats.UptimeHours).Scan(&stats.OpenFaults, &stats.DayNotices, &stats.OutageHours)
+	if err != nil {
+		return nil, fmt.Errorf("error getting project badge stats: %w", err)
+	}
+	return &stats, nil
+}
@@ -0,0 +1,43 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetCheckpoints returns the last stored sequence number of each shard a listener has read from stream
+func (r *Repository) GetCheckpoints(ctx context.Context, listener, stream string) (map[string]string, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT shard_id, sequence_number
+		FROM ingest_checkpoints
+		WHERE listener = $1 AND stream = $2
+	`, listener, stream)
+	if err != nil {
+		return nil, fmt.Errorf("error getting ingest checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	checkpoints := make(map[string]string)
+	for rows.Next() {
+		var shard, seq string
+		if err := rows.Scan(&shard, &seq); err != nil {
+			return nil, fmt.Errorf("error scanning ingest checkpoint: %w", err)
+		}
+		checkpoints[shard] = seq
+	}
+	return checkpoints, rows.Err()
+}
+
+// SaveCheckpoint records the last stored sequence number of a shard
+func (r *Repository) SaveCheckpoint(ctx context.Context, listener, stream, shard, sequence string) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO ingest_checkpoints (listener, stream, shard_id, sequence_number)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (listener, stream, shard_id) DO UPDATE
+		SET sequence_number = EXCLUDED.sequence_number, updated_at = NOW()
+	`, listener, stream, shard, sequence)
+	if err != nil {
+		return fmt.Errorf("error saving ingest checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// contentSet collects distinct JSON documents of notices being stored, keyed by content hash,
+// for a content-addressed table with a hash key, a JSONB column and last_used_at
+type contentSet struct {
+	table    string
+	column   string
+	hashes   [][]byte
+	contents []string
+	seen     map[[sha256.Size]byte]struct{}
+}
+
+// add records a document's JSON encoding and returns its hash, or nil for an empty document,
+// which is stored as NULL
+func (s *contentSet) add(content []byte, empty bool) []byte {
+	if empty {
+		return nil
+	}
+	sum := sha256.Sum256(content)
+	if s.seen == nil {
+		s.seen = make(map[[sha256.Size]byte]struct{})
+	}
+	if _, ok := s.seen[sum]; !ok {
+		s.seen[sum] = struct{}{}
+		s.hashes = append(s.hashes, sum[:])
+		s.contents = append(s.contents, string(content))
+	}
+	return sum[:]
+}
+
+// store inserts the documents not stored yet and marks stored ones as used, at most hourly so
+// hot documents are not rewritten by every batch. It runs in the transaction inserting the
+// notices that reference them.
+func (s *contentSet) store(ctx context.Context, tx pgx.Tx) error {
+	if len(s.hashes) == 0 {
+		return nil
+	}
+	_, err := tx.Exec(ctx, fmt.Sprintf(`
+		WITH inserted AS (
+			INSERT INTO %[1]s (hash, %[2]s)
+			SELECT hash, content::jsonb
+			FROM unnest($1::bytea[], $2::text[]) AS t(hash, content)
+			ON CONFLICT (hash) DO NOTHING
+		)
+		UPDATE %[1]s
+		SET last_used_at = NOW()
+		WHERE hash = ANY($1) AND last_used_at < NOW() - INTERVAL '1 hour'
+	`, s.table, s.column), s.hashes, s.contents)
+	if err != nil {
+		return fmt.Errorf("error storing %s: %w", s.table, err)
+	}
+	return nil
+}
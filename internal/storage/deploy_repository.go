@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"time"
+)
+
+const deployColumns = `id, project_id, environment, revision, deployed_at, window_seconds,
+		       notices_before, notices_after, faults_before, faults_after, new_faults,
+		       spike, complete, analyzed_at`
+
+// DeployFilters narrows the deploys listed
+type DeployFilters struct {
+	ProjectID   *int64
+	Environment string
+	SpikesOnly  bool
+}
+
+// RecordDeploys records a deploy for each revision reported in an environment by notices stored
+// since the given time. A deploy already recorded is moved earlier when an older notice with its
+// revision is found, and analyzed again.
+func (r *Repository) RecordDeploys(ctx context.Context, since time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `
+		INSERT INTO deploys (project_id, environment, revision, deployed_at)
+		SELECT n.project_id, f.environment, n.revision, MIN(n.created_at)
+		FROM notices n
+		JOIN faults f ON f.id = n.fault_id
+		WHERE n.created_at >= $1 AND n.revision IS NOT NULL AND n.revision <> ''
+		GROUP BY n.project_id, f.environment, n.revision
+		ON CONFLICT ((COALESCE(project_id, -1)), environment, revision)
+		DO UPDATE SET deployed_at = EXCLUDED.deployed_at, complete = FALSE
+		WHERE EXCLUDED.deployed_at < deploys.deployed_at
+	`, since)
+	if err != nil {
+		return 0, fmt.Errorf("error recording deploys: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// LatestDeployAt returns when the most recent recorded deploy happened, or nil before any deploy
+// was recorded
+func (r *Repository) LatestDeployAt(ctx context.Context) (*time.Time, error) {
+	var latest *time.Time
+	if err := r.pool.QueryRow(ctx, `SELECT MAX(deployed_at) FROM deploys`).Scan(&latest); err != nil {
+		return nil, fmt.Errorf("error getting latest deploy: %w", err)
+	}
+	return latest, nil
+}
+
+// AnalyzeDeploys counts the notices and faults in the window before and after up to limit
+// deploys whose counts are not final, in ID order after afterID. A deploy is flagged as a spike
+// once at least minNotices notices followed it and they exceed the notices before it by
+// spikeFactor. Counts become final for deploys whose window after ended before completeBefore.
+// It returns the number of deploys analyzed and the last one's ID, to continue from.
+func (r *Repository) AnalyzeDeploys(ctx context.Context, window time.Duration, spikeFactor float64, minNotices int64, completeBefore time.Time, afterID int64, limit int) (int, int64, error) {
+	rows, err := r.pool.Query(ctx, `
+		UPDATE deploys d
+		SET window_seconds = $1::int,
+		    notices_before = s.notices_before,
+		    notices_after = s.notices_after,
+		    faults_before = s.faults_before,
+		    faults_after = s.faults_after,
+		    new_faults = s.new_faults,
+		    spike = s.notices_after >= $3 AND s.notices_after >= s.notices_before * $2::float8,
+		    complete = d.deployed_at + make_interval(secs => $1::int) < $4,
+		    analyzed_at = NOW()
+		FROM (
+			SELECT t.id, c.notices_before, c.notices_after, c.faults_before, c.faults_after, nf.new_faults
+			FROM deploys t
+			CROSS JOIN LATERAL (
+				SELECT COUNT(*) FILTER (WHERE n.created_at < t.deployed_at) AS notices_before,
+				       COUNT(*) FILTER (WHERE n.created_at >= t.deployed_at) AS notices_after,
+				       COUNT(DISTINCT n.fault_id) FILTER (WHERE n.created_at < t.deployed_at) AS faults_before,
+				       COUNT(DISTINCT n.fault_id) FILTER (WHERE n.created_at >= t.deployed_at) AS faults_after
+				FROM notices n
+				JOIN faults f ON f.id = n.fault_id
+				WHERE f.environment = t.environment
+				  AND n.project_id IS NOT DISTINCT FROM t.project_id
+				  AND n.created_at >= t.deployed_at - make_interval(secs => $1::int)
+				  AND n.created_at < t.deployed_at + make_interval(secs => $1::int)
+			) c
+			CROSS JOIN LATERAL (
+				SELECT COUNT(*) AS new_faults
+				FROM faults f
+				WHERE f.environment = t.environment
+				  AND f.project_id IS NOT DISTINCT FROM t.project_id
+				  AND f.first_seen_at >= t.deployed_at
+				  AND f.first_seen_at < t.deployed_at + make_interval(secs => $1::int)
+			) nf
+			WHERE NOT t.complete AND t.id > $5
+			ORDER BY t.id
+			LIMIT $6
+		) s
+		WHERE d.id = s.id
+		RETURNING d.id
+	`, int(window.Seconds()), spikeFactor, minNotices, completeBefore, afterID, limit)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error analyzing deploys: %w", err)
+	}
+	defer rows.Close()
+
+	analyzed, lastID := 0, afterID
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return 0, 0, fmt.Errorf("error scanning deploy: %w", err)
+		}
+		analyzed++
+		if id > lastID {
+			lastID = id
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, 0, fmt.Errorf("error analyzing deploys: %w", err)
+	}
+	return analyzed, lastID, nil
+}
+
+// ListDeploys returns a page of deploys, most recent first
+func (r *Repository) ListDeploys(ctx context.Context, filters DeployFilters, limit, offset int) ([]models.Deploy, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM deploys
+		WHERE ($1::BIGINT IS NULL OR project_id = $1)
+		  AND ($2 = '' OR environment = $2)
+		  AND (NOT $3 OR spike)
+		ORDER BY deployed_at DESC, id DESC
+		LIMIT $4 OFFSET $5
+	`, deployColumns)
+
+	rows, err := r.pool.Query(ctx, query, filters.ProjectID, filters.Environment, filters.SpikesOnly, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error listing deploys: %w", err)
+	}
+	defer rows.Close()
+
+	var deploys []models.Deploy
+	for rows.Next() {
+		var d models.Deploy
+		err := rows.Scan(&d.ID, &d.ProjectID, &d.Environment, &d.Revision, &d.DeployedAt, &d.WindowSeconds,
+			&d.NoticesBefore, &d.NoticesAfter, &d.FaultsBefore, &d.FaultsAfter, &d.NewFaults,
+			&d.Spike, &d.Complete, &d.AnalyzedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning deploy: %w", err)
+		}
+		deploys = append(deploys, d)
+	}
+	return deploys, rows.Err()
+}
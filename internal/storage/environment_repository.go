@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const environmentColumns = `id, project_id, name, position, alerting_enabled, retention_days, created_at, updated_at`
+
+// ErrEnvironmentExists is returned when an environment of the same name already exists
+var ErrEnvironmentExists = errors.New("an environment with this name already exists")
+
+// environmentFaults matches the faults of environment e: faults named after it, in its project,
+// or for a global environment, in any project without its own environment of that name
+const environmentFaults = `f.environment = e.name
+		  AND (f.project_id = e.project_id
+		       OR (e.project_id IS NULL AND NOT EXISTS (
+		           SELECT 1 FROM environments o WHERE o.project_id = f.project_id AND o.name = e.name)))`
+
+// CreateEnvironment creates a new environment
+func (r *Repository) CreateEnvironment(ctx context.Context, env *models.Environment) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO environments (project_id, name, position, alerting_enabled, retention_days)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`, env.ProjectID, env.Name, env.Position, env.AlertingEnabled, env.RetentionDays).Scan(&env.ID, &env.CreatedAt, &env.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrEnvironmentExists
+		}
+		return fmt.Errorf("error creating environment: %w", err)
+	}
+	return nil
+}
+
+// ListEnvironments returns environments in promotion order, optionally limited to one project
+// (plus global environments)
+func (r *Repository) ListEnvironments(ctx context.Context, projectID *int64) ([]models.Environment, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM environments
+		WHERE $1::BIGINT IS NULL OR project_id IS NULL OR project_id = $1
+		ORDER BY position, name, id
+	`, environmentColumns)
+
+	rows, err := r.pool.Query(ctx, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing environments: %w", err)
+	}
+	defer rows.Close()
+
+	envs := []models.Environment{}
+	for rows.Next() {
+		env, err := scanEnvironment(rows)
+		if err != nil {
+			return nil, err
+		}
+		envs = append(envs, *env)
+	}
+	return envs, rows.Err()
+}
+
+// GetEnvironment returns an environment by ID
+func (r *Repository) GetEnvironment(ctx context.Context, id int64) (*models.Environment, error) {
+	row := r.pool.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM environments WHERE id = $1`, environmentColumns), id)
+	env, err := scanEnvironment(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return env, err
+}
+
+// UpdateEnvironment saves an environment's name, position, alerting and retention
+func (r *Repository) UpdateEnvironment(ctx context.Context, env *models.Environment) error {
+	err := r.pool.QueryRow(ctx, `
+		UPDATE environments
+		SET name = $2, position = $3, alerting_enabled = $4, retention_days = $5, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`, env.ID, env.Name, env.Position, env.AlertingEnabled, env.RetentionDays).Scan(&env.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrEnvironmentExists
+		}
+		return fmt.Errorf("error updating environment: %w", err)
+	}
+	return nil
+}
+
+// DeleteEnvironment deletes an environment. Its faults are kept.
+func (r *Repository) DeleteEnvironment(ctx context.Context, id int64) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM environments WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting environment: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// NextEnvironment returns the environment a release is promoted to after env: the first one
+// with a higher position that applies to env's project, or nil when env is the last
+func (r *Repository) NextEnvironment(ctx context.Context, env *models.Environment) (*models.Environment, error) {
+	row := r.pool.QueryRow(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM environments
+		WHERE position > $2
+		  AND (project_id IS NULL OR project_id IS NOT DISTINCT FROM $1)
+		ORDER BY position, (project_id IS NULL), name, id
+		LIMIT 1
+	`, environmentColumns), env.ProjectID, env.Position)
+	next, err := scanEnvironment(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return next, err
+}
+
+// ListUnpromotedFaults returns the unresolved faults of environment e, most recently seen first,
+// with no fault of the same error class and location in the target environment: errors that
+// have not reached the target yet, such as regressions seen in staging before a release
+func (r *Repository) ListUnpromotedFaults(ctx context.Context, env, target *models.Environment, limit, offset int) ([]models.Fault, error) {
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
+		SELECT f.id, f.project_id, f.error_class, f.message, f.location, f.environment,
+		       f.resolved, f.ignored, f.state, f.opened_at, f.acknowledged_at, f.closed_at, f.tags, f.severity, f.custom_fields, f.occurrence_count,
+		       f.first_seen_at, f.last_seen_at, f.created_at, f.updated_at
+		FROM faults f
+		JOIN environments e ON e.id = $1
+		WHERE %s
+		  AND NOT f.resolved AND NOT f.ignored
+		  AND NOT EXISTS (
+		      SELECT 1 FROM faults p
+		      WHERE p.environment = $2
+		        AND p.project_id IS NOT DISTINCT FROM f.project_id
+		        AND p.error_class = f.error_class
+		        AND p.location IS NOT DISTINCT FROM f.location)
+		ORDER BY f.last_seen_at DESC, f.id DESC
+		LIMIT $3 OFFSET $4
+	`, environmentFaults), env.ID, target.Name, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error listing unpromoted faults: %w", err)
+	}
+	defer rows.Close()
+
+	var faults []models.Fault
+	for rows.Next() {
+		var f models.Fault
+		err := rows.Scan(&f.ID, &f.ProjectID, &f.ErrorClass, &f.Message, &f.Location, &f.Environment,
+			&f.Resolved, &f.Ignored, &f.State, &f.OpenedAt, &f.AcknowledgedAt, &f.ClosedAt, &f.Tags, &f.Severity, &f.CustomFields, &f.OccurrenceCount,
+			&f.FirstSeenAt, &f.LastSeenAt, &f.CreatedAt, &f.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning fault: %w", err)
+		}
+		faults = append(faults, f)
+	}
+	return faults, rows.Err()
+}
+
+// DeleteExpiredEnvironmentNotices deletes up to limit notices older than the retention of the
+// environment with the given ID. Fault occurrence counts are kept.
+func (r *Repository) DeleteExpiredEnvironmentNotices(ctx context.Context, envID int64, cutoff time.Time, limit int) (int64, error) {
+	tag, err := r.pool.Exec(ctx, fmt.Sprintf(`
+		DELETE FROM notices
+		WHERE id IN (
+			SELECT n.id
+			FROM environments e
+			JOIN faults f ON %s
+			JOIN notices n ON n.fault_id = f.id
+			WHERE e.id = $1 AND n.created_at < $2
+			LIMIT $3
+		)
+	`, environmentFaults), envID, cutoff, limit)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting expired notices: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// scanEnvironment scans an environment selected with environmentColumns
+func scanEnvironment(row pgx.Row) (*models.Environment, error) {
+	var env models.Environment
+	err := row.Scan(&env.ID, &env.ProjectID, &env.Name, &env.Position, &env.AlertingEnabled, &env.RetentionDays,
+		&env.CreatedAt, &env.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error scanning environment: %w", err)
+	}
+	return &env, nil
+}
@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const escalationRuleColumns = `id, project_id, name, min_occurrences, min_affected_users, window_seconds,
+		       severity, enabled, match_count, last_matched_at, created_by, created_at`
+
+// affectedUserExpr identifies the user a notice affected, from its context
+const affectedUserExpr = `COALESCE(n.context->>'user_id', n.context->>'user_email')`
+
+// CreateEscalationRule creates a new severity escalation rule
+func (r *Repository) CreateEscalationRule(ctx context.Context, rule *models.EscalationRule) error {
+	query := `
+		INSERT INTO escalation_rules (project_id, name, min_occurrences, min_affected_users,
+		                              window_seconds, severity, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, match_count, created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		rule.ProjectID,
+		rule.Name,
+		rule.MinOccurrences,
+		rule.MinAffectedUsers,
+		rule.WindowSeconds,
+		rule.Severity,
+		rule.Enabled,
+		rule.CreatedBy,
+	).Scan(&rule.ID, &rule.MatchCount, &rule.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating escalation rule: %w", err)
+	}
+
+	return nil
+}
+
+// ListEscalationRules returns escalation rules, optionally limited to one project (plus global rules)
+func (r *Repository) ListEscalationRules(ctx context.Context, projectID *int64, enabledOnly bool) ([]models.EscalationRule, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM escalation_rules
+		WHERE ($1::BIGINT IS NULL OR project_id IS NULL OR project_id = $1)
+		  AND (NOT $2 OR enabled)
+		ORDER BY id
+	`, escalationRuleColumns)
+
+	rows, err := r.pool.Query(ctx, query, projectID, enabledOnly)
+	if err != nil {
+		return nil, fmt.Errorf("error listing escalation rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.EscalationRule{}
+	for rows.Next() {
+		var rule models.EscalationRule
+		err := rows.Scan(
+			&rule.ID,
+			&rule.ProjectID,
+			&rule.Name,
+			&rule.MinOccurrences,
+			&rule.MinAffectedUsers,
+			&rule.WindowSeconds,
+			&rule.Severity,
+			&rule.Enabled,
+			&rule.MatchCount,
+			&rule.LastMatchedAt,
+			&rule.CreatedBy,
+			&rule.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning escalation rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// DeleteEscalationRule deletes an escalation rule
+func (r *Repository) DeleteEscalationRule(ctx context.Context, id int64) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM escalation_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting escalation rule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("escalation rule %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// FindEscalationCandidates returns up to limit unresolved, unignored faults seen since
+// seenAfter whose severity is below the rule's and which cross all of its thresholds
+func (r *Repository) FindEscalationCandidates(ctx context.Context, rule *models.EscalationRule, seenAfter time.Time, limit int) ([]int64, error) {
+	query := fmt.Sprintf(`
+		SELECT f.id
+		FROM faults f
+		WHERE NOT f.resolved AND NOT f.ignored
+		  AND ($1::BIGINT IS NULL OR f.project_id = $1)
+		  AND COALESCE(array_position($2::TEXT[], f.severity), 0) < array_position($2::TEXT[], $3)
+		  AND f.last_seen_at >= $4
+		  AND ($5::BIGINT IS NULL OR
+		       CASE WHEN $7::INT IS NULL THEN f.occurrence_count
+		            ELSE (SELECT COUNT(*) FROM notices n
+		                  WHERE n.fault_id = f.id AND n.created_at >= NOW() - make_interval(secs => $7))
+		       END >= $5)
+		  AND ($6::BIGINT IS NULL OR
+		       (SELECT COUNT(DISTINCT %s) FROM notices n
+		        WHERE n.fault_id = f.id
+		          AND ($7::INT IS NULL OR n.created_at >= NOW() - make_interval(secs => $7))) >= $6)
+		ORDER BY f.last_seen_at DESC
+		LIMIT $8
+	`, affectedUserExpr)
+
+	rows, err := r.pool.Query(ctx, query, rule.ProjectID, models.Severities, rule.Severity, seenAfter,
+		rule.MinOccurrences, rule.MinAffectedUsers, rule.WindowSeconds, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error finding faults to escalate: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("error scanning fault ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// EscalateFault raises a fault's severity to the rule's, recording the escalation in history
+// and counting it against the rule. It returns the previous severity, or "" when the fault is
+// gone or already at or above the rule's severity, which makes escalation safe to race.
+func (r *Repository) EscalateFault(ctx context.Context, faultID int64, rule *models.EscalationRule) (string, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var previous string
+	err = tx.QueryRow(ctx, `
+		UPDATE faults f
+		SET severity = $3, updated_at = NOW()
+		FROM (SELECT id, severity FROM faults WHERE id = $1 FOR UPDATE) old
+		WHERE f.id = old.id
+		  AND COALESCE(array_position($2::TEXT[], old.severity), 0) < array_position($2::TEXT[], $3)
+		RETURNING old.severity
+	`, faultID, models.Severities, rule.Severity).Scan(&previous)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("error escalating fault: %w", err)
+	}
+
+	changes := []models.FieldChange{
+		{Field: "severity", Old: previous, New: rule.Severity},
+		{Field: "escalation_rule_id", New: rule.ID},
+	}
+	if err := insertFaultHistory(ctx, tx, faultID, "escalated", nil, nil, changes); err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE escalation_rules
+		SET match_count = match_count + 1, last_matched_at = NOW()
+		WHERE id = $1
+	`, rule.ID)
+	if err != nil {
+		return "", fmt.Errorf("error recording escalation rule match: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("error committing escalation: %w", err)
+	}
+	return previous, nil
+}
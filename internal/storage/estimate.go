@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// QueryEstimate is the planner's estimate of a query: the rows it reads and its total cost,
+// in the planner's arbitrary units
+type QueryEstimate struct {
+	Rows float64 `json:"rows"`
+	Cost float64 `json:"cost"`
+}
+
+// EstimateFaults asks the planner how many faults match filters and what finding them costs
+func (r *Repository) EstimateFaults(ctx context.Context, filters FaultFilters) (*QueryEstimate, error) {
+	whereClause, args, _ := faultWhereClause(filters)
+	return r.explain(ctx, `SELECT 1 FROM faults f `+whereClause, args...)
+}
+
+// EstimateLogs asks the planner how many logs match filters and what finding them costs
+func (r *Repository) EstimateLogs(ctx context.Context, filters LogFilters) (*QueryEstimate, error) {
+	where, args := logFiltersWhere(filters)
+	return r.explain(ctx, `SELECT 1 FROM logs `+where, args...)
+}
+
+// explain runs EXPLAIN on a query, without running the query, and reads the estimate of its
+// top plan node
+func (r *Repository) explain(ctx context.Context, query string, args ...interface{}) (*QueryEstimate, error) {
+	var raw []byte
+	if err := r.pool.QueryRow(ctx, `EXPLAIN (FORMAT JSON) `+query, args...).Scan(&raw); err != nil {
+		return nil, fmt.Errorf("error explaining query: %w", err)
+	}
+
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+			Cost float64 `json:"Total Cost"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &plans); err != nil {
+		return nil, fmt.Errorf("error decoding query plan: %w", err)
+	}
+	if len(plans) == 0 {
+		return nil, fmt.Errorf("error explaining query: no plan returned")
+	}
+	return &QueryEstimate{Rows: plans[0].Plan.Rows, Cost: plans[0].Plan.Cost}, nil
+}
@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const faultFieldColumns = `id, project_id, key, name, type, options, created_at, updated_at`
+
+// ErrFaultFieldExists is returned when a custom field with the same key already exists
+var ErrFaultFieldExists = errors.New("a custom field with this key already exists")
+
+// CreateFaultFieldDefinition creates a custom field definition
+func (r *Repository) CreateFaultFieldDefinition(ctx context.Context, def *models.FaultFieldDefinition) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO fault_field_definitions (project_id, key, name, type, options)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`, def.ProjectID, def.Key, def.Name, def.Type, fieldOptions(def.Options)).Scan(&def.ID, &def.CreatedAt, &def.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrFaultFieldExists
+		}
+		return fmt.Errorf("error creating custom field: %w", err)
+	}
+	return nil
+}
+
+// ListFaultFieldDefinitions returns custom field definitions by key, optionally limited to one
+// project (plus global definitions)
+func (r *Repository) ListFaultFieldDefinitions(ctx context.Context, projectID *int64) ([]models.FaultFieldDefinition, error) {
+	return r.queryFaultFieldDefinitions(ctx, fmt.Sprintf(`
+		SELECT %s
+		FROM fault_field_definitions
+		WHERE $1::BIGINT IS NULL OR project_id IS NULL OR project_id = $1
+		ORDER BY key, project_id NULLS FIRST
+	`, faultFieldColumns), projectID)
+}
+
+// FaultFieldDefinitionsFor returns the custom fields that apply to faults of a project: its own
+// definitions, and global definitions of keys it does not define itself
+func (r *Repository) FaultFieldDefinitionsFor(ctx context.Context, projectID *int64) ([]models.FaultFieldDefinition, error) {
+	return r.queryFaultFieldDefinitions(ctx, fmt.Sprintf(`
+		SELECT DISTINCT ON (key) %s
+		FROM fault_field_definitions
+		WHERE project_id IS NULL OR project_id = $1
+		ORDER BY key, project_id NULLS LAST
+	`, faultFieldColumns), projectID)
+}
+
+func (r *Repository) queryFaultFieldDefinitions(ctx context.Context, query string, projectID *int64) ([]models.FaultFieldDefinition, error) {
+	rows, err := r.pool.Query(ctx, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	defs := []models.FaultFieldDefinition{}
+	for rows.Next() {
+		def, err := scanFaultFieldDefinition(rows)
+		if err != nil {
+			return nil, err
+		}
+		defs = append(defs, *def)
+	}
+	return defs, rows.Err()
+}
+
+// GetFaultFieldDefinition returns a custom field definition by ID
+func (r *Repository) GetFaultFieldDefinition(ctx context.Context, id int64) (*models.FaultFieldDefinition, error) {
+	row := r.pool.QueryRow(ctx, fmt.Sprintf(`SELECT %s FROM fault_field_definitions WHERE id = $1`, faultFieldColumns), id)
+	def, err := scanFaultFieldDefinition(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return def, err
+}
+
+// UpdateFaultFieldDefinition saves a custom field's name and options. Values already set on
+// faults are kept even if their option was removed.
+func (r *Repository) UpdateFaultFieldDefinition(ctx context.Context, def *models.FaultFieldDefinition) error {
+	err := r.pool.QueryRow(ctx, `
+		UPDATE fault_field_definitions
+		SET name = $2, options = $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`, def.ID, def.Name, fieldOptions(def.Options)).Scan(&def.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error updating custom field: %w", err)
+	}
+	return nil
+}
+
+// DeleteFaultFieldDefinition deletes a custom field definition and removes its values from the
+// faults it applied to
+func (r *Repository) DeleteFaultFieldDefinition(ctx context.Context, id int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var def models.FaultFieldDefinition
+	err = tx.QueryRow(ctx, `
+		DELETE FROM fault_field_definitions WHERE id = $1 RETURNING project_id, key
+	`, id).Scan(&def.ProjectID, &def.Key)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting custom field: %w", err)
+	}
+
+	// A global definition's values stay on faults of projects with their own definition of the key
+	if _, err := tx.Exec(ctx, `
+		UPDATE faults f
+		SET custom_fields = custom_fields - $2
+		WHERE custom_fields ? $2
+		  AND (f.project_id = $1
+		       OR ($1::BIGINT IS NULL AND NOT EXISTS (
+		           SELECT 1 FROM fault_field_definitions o WHERE o.project_id = f.project_id AND o.key = $2)))
+	`, def.ProjectID, def.Key); err != nil {
+		return fmt.Errorf("error removing custom field values: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("error committing custom field deletion: %w", err)
+	}
+	return nil
+}
+
+// SetFaultCustomFields sets custom field values on a fault and records the change in history.
+// A nil value removes the field.
+func (r *Repository) SetFaultCustomFields(ctx context.Context, id int64, values map[string]interface{}, actorID *int64) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	return r.trackedFaultUpdate(ctx, id, "updated", actorID, []string{"custom_fields"}, func(old map[string]interface{}) map[string]interface{} {
+		fields := make(map[string]interface{})
+		if existing, ok := old["custom_fields"].(map[string]interface{}); ok {
+			for key, value := range existing {
+				fields[key] = value
+			}
+		}
+		for key, value := range values {
+			if value == nil {
+				delete(fields, key)
+			} else {
+				fields[key] = value
+			}
+		}
+		return map[string]interface{}{"custom_fields": fields}
+	})
+}
+
+// fieldOptions stores missing options as an empty array
+func fieldOptions(options []string) []string {
+	if options == nil {
+		return []string{}
+	}
+	return options
+}
+
+// scanFaultFieldDefinition scans a definition selected with faultFieldColumns
+func scanFaultFieldDefinition(row pgx.Row) (*models.FaultFieldDefinition, error) {
+	var def models.FaultFieldDefinition
+	err := row.Scan(&def.ID, &def.ProjectID, &def.Key, &def.Name, &def.Type, &def.Options, &def.CreatedAt, &def.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error scanning custom field: %w", err)
+	}
+	return &def, nil
+}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// trackedFaultUpdate applies an update to a fault inside a transaction and records a
+// history entry with the before/after value of every column that actually changed.
+// apply receives the current values of columns and returns the new values to write.
+// No history is written when nothing changes.
+func (r *Repository) trackedFaultUpdate(ctx context.Context, id int64, action string, actorID *int64, columns []string, apply func(old map[string]interface{}) map[string]interface{}) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	
+	old, err := lockFaultColumns(ctx, tx, id, columns)
+	if err != nil {
+		return err
+	}
+	
+	updates := apply(old)
+	
+	var changes []models.FieldChange
+	var setParts []string
+	var args []interface{}
+	argIndex := 1
+	
+	keys := make([]string, 0, len(updates))
+	for key := range updates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	
+	for _, key := range keys {
+		value := normalizeFaultValue(updates[key])
+		change, changed := diffFaultField(key, old[key], value)
+		if !changed {
+			continue
+		}
+		changes = append(changes, change)
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", key, argIndex))
+		args = append(args, value)
+		argIndex++
+	}
+	
+	if len(changes) == 0 {
+		return tx.Commit(ctx)
+	}
+	
+	args = append(args, id)
+	query := fmt.Sprintf(`
+		UPDATE faults
+		SET %s, updated_at = NOW()
+		WHERE id = $%d
+	`, strings.Join(setParts, ", "), argIndex)
+	
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("error updating fault: %w", err)
+	}
+	
+	if err := insertFaultHistory(ctx, tx, id, action, actorID, nil, changes); err != nil {
+		return err
+	}
+	
+	return tx.Commit(ctx)
+}
+
+// lockFaultColumns reads the current values of columns for a fault and locks the row
+func lockFaultColumns(ctx context.Context, tx pgx.Tx, id int64, columns []string) (map[string]interface{}, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM faults
+		WHERE id = $1
+		FOR UPDATE
+	`, strings.Join(columns, ", "))
+	
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	
+	if err := tx.QueryRow(ctx, query, id).Scan(dest...); err != nil {
+		if IsNotFound(err) {
+			return nil, fmt.Errorf("fault %d: %w", id, ErrNotFound)
+		}
+		return nil, fmt.Errorf("error reading fault: %w", err)
+	}
+	
+	old := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		old[column] = normalizeFaultValue(values[i])
+	}
+	return old, nil
+}
+
+// insertFaultHistory writes a history entry using the given transaction
+func insertFaultHistory(ctx context.Context, tx pgx.Tx, faultID int64, action string, userID *int64, revision *string, changes []models.FieldChange) error {
+	if changes == nil {
+		changes = []models.FieldChange{}
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("error encoding history changes: %w", err)
+	}
+	
+	query := `
+		INSERT INTO fault_history (fault_id, action, user_id, revision, changes)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+	
+	if _, err := tx.Exec(ctx, query, faultID, action, userID, revision, changesJSON); err != nil {
+		return fmt.Errorf("error recording fault history: %w", err)
+	}
+	return nil
+}
+
+// diffFaultField compares the old and new value of a fault column
+func diffFaultField(field string, oldValue, newValue interface{}) (models.FieldChange, bool) {
+	newValue = normalizeFaultValue(newValue)
+	
+	if field == "tags" {
+		oldTags, _ := oldValue.([]string)
+		newTags, _ := newValue.([]string)
+		added, removed := diffTags(oldTags, newTags)
+		change := models.FieldChange{Field: field, Added: added, Removed: removed}
+		return change, len(added) > 0 || len(removed) > 0
+	}
+	
+	oldJSON, _ := json.Marshal(oldValue)
+	newJSON, _ := json.Marshal(newValue)
+	if string(oldJSON) == string(newJSON) {
+		return models.FieldChange{}, false
+	}
+	
+	return models.FieldChange{Field: field, Old: oldValue, New: newValue}, true
+}
+
+// diffTags returns the tags present only in next and only in prev
+func diffTags(prev, next []string) ([]string, []string) {
+	inPrev := make(map[string]bool, len(prev))
+	for _, t := range prev {
+		inPrev[t] = true
+	}
+	inNext := make(map[string]bool, len(next))
+	for _, t := range next {
+		inNext[t] = true
+	}
+	
+	var added, removed []string
+	for _, t := range next {
+		if !inPrev[t] {
+			added = append(added, t)
+			inPrev[t] = true
+		}
+	}
+	for _, t := range prev {
+		if !inNext[t] {
+			removed = append(removed, t)
+			inNext[t] = true
+		}
+	}
+	return added, removed
+}
+
+// normalizeFaultValue converts database and JSON values to comparable Go types
+func normalizeFaultValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case *int64:
+		if val == nil {
+			return nil
+		}
+		return *val
+	case int32:
+		return int64(val)
+	case float64:
+		if val == float64(int64(val)) {
+			return int64(val)
+		}
+		return val
+	case []interface{}:
+		out := make([]string, 0, len(val))
+		for _, item := range val {
+			out = append(out, fmt.Sprint(item))
+		}
+		return out
+	case []string:
+		if val == nil {
+			return []string{}
+		}
+		return val
+	}
+	return v
+}
+
+// mergeTags appends tags to existing, skipping duplicates
+func mergeTags(existing, tags []string) []string {
+	seen := make(map[string]bool, len(existing)+len(tags))
+	merged := make([]string, 0, len(existing)+len(tags))
+	for _, t := range append(append([]string{}, existing...), tags...) {
+		if !seen[t] {
+			seen[t] = true
+			merged = append(merged, t)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrSelfLink is returned when a fault is linked to itself
+var ErrSelfLink = errors.New("cannot link a fault to itself")
+
+// ErrLinkExists is returned when two faults are already linked
+var ErrLinkExists = errors.New("the faults are already linked")
+
+// LinkFaults links a fault to another, which may belong to another project. kind is relates_to,
+// or duplicate_of to mark faultID as a duplicate of linkedFaultID. The link is recorded in the
+// history of both faults and returned as seen from faultID.
+func (r *Repository) LinkFaults(ctx context.Context, faultID, linkedFaultID int64, kind string, actorID *int64) (*models.FaultLink, error) {
+	if faultID == linkedFaultID {
+		return nil, ErrSelfLink
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var found int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM faults WHERE id = ANY($1)`, []int64{faultID, linkedFaultID}).Scan(&found); err != nil {
+		return nil, fmt.Errorf("error checking faults: %w", err)
+	}
+	if found != 2 {
+		return nil, fmt.Errorf("link faults %d and %d: %w", faultID, linkedFaultID, ErrNotFound)
+	}
+
+	var linkID int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO fault_links (fault_id, linked_fault_id, kind, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, faultID, linkedFaultID, kind, actorID).Scan(&linkID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrLinkExists
+		}
+		return nil, fmt.Errorf("error creating fault link: %w", err)
+	}
+
+	if err := recordLinkHistory(ctx, tx, "linked", faultID, linkedFaultID, kind, actorID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error committing fault link: %w", err)
+	}
+
+	links, err := r.GetFaultLinks(ctx, faultID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range links {
+		if links[i].ID == linkID {
+			return &links[i], nil
+		}
+	}
+	return nil, fmt.Errorf("fault link %d: %w", linkID, ErrNotFound)
+}
+
+// UnlinkFaults removes a link of a fault, recording it in the history of both faults
+func (r *Repository) UnlinkFaults(ctx context.Context, faultID, linkID int64, actorID *int64) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var from, to int64
+	var kind string
+	err = tx.QueryRow(ctx, `
+		DELETE FROM fault_links
+		WHERE id = $1 AND (fault_id = $2 OR linked_fault_id = $2)
+		RETURNING fault_id, linked_fault_id, kind
+	`, linkID, faultID).Scan(&from, &to, &kind)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("fault link %d: %w", linkID, ErrNotFound)
+		}
+		return fmt.Errorf("error deleting fault link: %w", err)
+	}
+
+	if err := recordLinkHistory(ctx, tx, "unlinked", from, to, kind, actorID); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// GetFaultLinks returns the links of a fault, newest first, each with a summary of the fault at
+// its other end
+func (r *Repository) GetFaultLinks(ctx context.Context, faultID int64) ([]models.FaultLink, error) {
+	query := `
+		SELECT l.id,
+		       CASE WHEN l.kind = 'duplicate_of' AND l.linked_fault_id = $1 THEN 'duplicated_by' ELSE l.kind END,
+		       f.id, f.project_id, f.error_class, f.message, f.environment, f.resolved,
+		       l.created_by, l.created_at
+		FROM fault_links l
+		JOIN faults f ON f.id = CASE WHEN l.fault_id = $1 THEN l.linked_fault_id ELSE l.fault_id END
+		WHERE l.fault_id = $1 OR l.linked_fault_id = $1
+		ORDER BY l.created_at DESC, l.id DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, faultID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting fault links: %w", err)
+	}
+	defer rows.Close()
+
+	var links []models.FaultLink
+	for rows.Next() {
+		var l models.FaultLink
+		err := rows.Scan(
+			&l.ID,
+			&l.Kind,
+			&l.FaultID,
+			&l.ProjectID,
+			&l.ErrorClass,
+			&l.Message,
+			&l.Environment,
+			&l.Resolved,
+			&l.CreatedBy,
+			&l.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning fault link: %w", err)
+		}
+		links = append(links, l)
+	}
+
+	return links, rows.Err()
+}
+
+// moveFaultLinks moves the links of a fault being merged to the merge target. Links between the
+// two faults, and links to a fault the target is already linked with, are dropped.
+func moveFaultLinks(ctx context.Context, tx pgx.Tx, sourceFaultID, targetFaultID int64) error {
+	steps := []struct {
+		name  string
+		query string
+	}{
+		{
+			name: "dropping links between merged faults",
+			query: `
+				DELETE FROM fault_links
+				WHERE (fault_id = $1 AND linked_fault_id = $2) OR (fault_id = $2 AND linked_fault_id = $1)
+			`,
+		},
+		{
+			name: "dropping links the target already has",
+			query: `
+				DELETE FROM fault_links l
+				WHERE (l.fault_id = $1 OR l.linked_fault_id = $1)
+				  AND EXISTS (
+				      SELECT 1 FROM fault_links t
+				      WHERE (t.fault_id = $2 AND t.linked_fault_id = CASE WHEN l.fault_id = $1 THEN l.linked_fault_id ELSE l.fault_id END)
+				         OR (t.linked_fault_id = $2 AND t.fault_id = CASE WHEN l.fault_id = $1 THEN l.linked_fault_id ELSE l.fault_id END)
+				  )
+			`,
+		},
+		{
+			name:  "moving links",
+			query: `UPDATE fault_links SET fault_id = $2 WHERE fault_id = $1`,
+		},
+		{
+			name:  "moving linked faults",
+			query: `UPDATE fault_links SET linked_fault_id = $2 WHERE linked_fault_id = $1`,
+		},
+	}
+	for _, step := range steps {
+		if _, err := tx.Exec(ctx, step.query, sourceFaultID, targetFaultID); err != nil {
+			return fmt.Errorf("error %s: %w", step.name, err)
+		}
+	}
+	return nil
+}
+
+// recordLinkHistory records a link change in the history of both faults. The change is named by
+// the kind of link as seen from each fault.
+func recordLinkHistory(ctx context.Context, tx pgx.Tx, action string, faultID, linkedFaultID int64, kind string, actorID *int64) error {
+	reverse := kind
+	if kind == models.LinkDuplicateOf {
+		reverse = models.LinkDuplicatedBy
+	}
+	sides := []struct {
+		faultID, other int64
+		field          string
+	}{
+		{faultID, linkedFaultID, kind},
+		{linkedFaultID, faultID, reverse},
+	}
+	for _, side := range sides {
+		change := models.FieldChange{Field: side.field, New: side.other}
+		if action == "unlinked" {
+			change = models.FieldChange{Field: side.field, Old: side.other}
+		}
+		if err := insertFaultHistory(ctx, tx, side.faultID, action, actorID, nil, []models.FieldChange{change}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
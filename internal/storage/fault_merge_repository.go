@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+)
+
+// ErrSelfMerge is returned when a fault is merged into itself
+var ErrSelfMerge = errors.New("cannot merge a fault into itself")
+
+// MergeFaults merges the source fault into the target fault. Notices, links and earlier merge
+// records move to the target, the source's fingerprint and counts are recorded in
+// fault_merges so future notices keep grouping into the target, and the source is deleted.
+func (r *Repository) MergeFaults(ctx context.Context, sourceFaultID, targetFaultID int64, actorID *int64) error {
+	if sourceFaultID == targetFaultID {
+		return ErrSelfMerge
+	}
+	
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	
+	// Lock both faults in ID order to avoid deadlocks with concurrent merges
+	lockQuery := `
+		SELECT id, error_class, message, location, environment,
+		       occurrence_count, first_seen_at, last_seen_at
+		FROM faults
+		WHERE id = ANY($1)
+		ORDER BY id
+		FOR UPDATE
+	`
+	rows, err := tx.Query(ctx, lockQuery, []int64{sourceFaultID, targetFaultID})
+	if err != nil {
+		return fmt.Errorf("error locking faults: %w", err)
+	}
+	
+	var source *models.FaultMerge
+	found := 0
+	for rows.Next() {
+		var m models.FaultMerge
+		var id int64
+		if err := rows.Scan(&id, &m.ErrorClass, &m.Message, &m.Location, &m.Environment,
+			&m.OccurrenceCount, &m.FirstSeenAt, &m.LastSeenAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("error scanning fault: %w", err)
+		}
+		if id == sourceFaultID {
+			m.SourceFaultID = id
+			source = &m
+		}
+		found++
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error locking faults: %w", err)
+	}
+	if found != 2 {
+		return fmt.Errorf("merge faults %d into %d: %w", sourceFaultID, targetFaultID, ErrNotFound)
+	}
+	
+	steps := []struct {
+		name  string
+		query string
+		args  []interface{}
+	}{
+		{
+			name:  "moving notices",
+			query: `UPDATE notices SET fault_id = $1 WHERE fault_id = $2`,
+			args:  []interface{}{targetFaultID, sourceFaultID},
+		},
+		{
+			name:  "moving earlier merges",
+			query: `UPDATE fault_merges SET target_fault_id = $1 WHERE target_fault_id = $2`,
+			args:  []interface{}{targetFaultID, sourceFaultID},
+		},
+		{
+			name: "updating target fault",
+			query: `
+				UPDATE faults
+				SET occurrence_count = occurrence_count + $1,
+				    first_seen_at = LEAST(first_seen_at, $2),
+				    last_seen_at = GREATEST(last_seen_at, $3)
+				WHERE id = $4
+			`,
+			args: []interface{}{source.OccurrenceCount, source.FirstSeenAt, source.LastSeenAt, targetFaultID},
+		},
+		{
+			name: "recording merge",
+			query: `
+				INSERT INTO fault_merges (target_fault_id, source_fault_id, error_class, message, location,
+				                          environment, occurrence_count, first_seen_at, last_seen_at, merged_by)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			`,
+			args: []interface{}{targetFaultID, sourceFaultID, source.ErrorClass, source.Message, source.Location,
+				source.Environment, source.OccurrenceCount, source.FirstSeenAt, source.LastSeenAt, actorID},
+		},
+		{
+			name:  "deleting source fault",
+			query: `DELETE FROM faults WHERE id = $1`,
+			args:  []interface{}{sourceFaultID},
+		},
+	}
+	
+	// Links are moved before the source is deleted, which would drop them
+	if err := moveFaultLinks(ctx, tx, sourceFaultID, targetFaultID); err != nil {
+		return err
+	}
+	
+	for _, step := range steps {
+		if _, err := tx.Exec(ctx, step.query, step.args...); err != nil {
+			return fmt.Errorf("error %s: %w", step.name, err)
+		}
+	}
+	
+	change := models.FieldChange{Field: "merged_fault_id", New: sourceFaultID}
+	if err := insertFaultHistory(ctx, tx, targetFaultID, "merged", actorID, nil, []models.FieldChange{change}); err != nil {
+		return err
+	}
+	
+	return tx.Commit(ctx)
+}
+
+// GetFaultMerges returns the faults merged into a fault, most recent first
+func (r *Repository) GetFaultMerges(ctx context.Context, faultID int64) ([]models.FaultMerge, error) {
+	query := `
+		SELECT id, target_fault_id, source_fault_id, error_class, message, location, environment,
+		       occurrence_count, first_seen_at, last_seen_at, merged_by, merged_at
+		FROM fault_merges
+		WHERE target_fault_id = $1
+		ORDER BY merged_at DESC, id DESC
+	`
+	
+	rows, err := r.pool.Query(ctx, query, faultID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting fault merges: %w", err)
+	}
+	defer rows.Close()
+	
+	var merges []models.FaultMerge
+	for rows.Next() {
+		var m models.FaultMerge
+		err := rows.Scan(
+			&m.ID,
+			&m.TargetFaultID,
+			&m.SourceFaultID,
+			&m.ErrorClass,
+			&m.Message,
+			&m.Location,
+			&m.Environment,
+			&m.OccurrenceCount,
+			&m.FirstSeenAt,
+			&m.LastSeenAt,
+			&m.MergedBy,
+			&m.MergedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning fault merge: %w", err)
+		}
+		merges = append(merges, m)
+	}
+	
+	return merges, nil
+}
@@ -4,22 +4,65 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log-ingestion-service/pkg/models"
+	"sort"
 	"strings"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 // FaultFilters represents filters for listing faults
 type FaultFilters struct {
 	Resolved    *bool
 	Ignored     *bool
+	// States matches faults in any of these workflow states; ExcludedStates leaves faults in them out
+	States         []string
+	ExcludedStates []string
 	Environment *string
 	AssigneeID  *int64
 	Tags        []string
+	// Public matches faults marked public or not; ProjectID matches the faults of one project
+	Public      *bool
+	ProjectID   *int64
+	// CustomFields match faults by custom field value
+	CustomFields []CustomFieldFilter
 	Search      string
+	// SLAStatus matches faults with an SLA timer running in this status, at_risk or breached,
+	// under the SLA policy
+	SLAStatus   string
+	SLA         *models.SLAPolicy
+	// SeenAfter matches faults last seen at or after it
+	SeenAfter   *time.Time
 	Limit       int
 	Offset      int
+	// Sort names the ordering column (see FaultSortColumns); empty means last_seen
+	Sort string
+	// SortAscending reverses the default newest/largest-first ordering
+	SortAscending bool
+	// ExpandAssignee joins the assignee user onto each listed fault
+	ExpandAssignee bool
+	// ExpandLatestNotice joins a summary of each fault's most recent notice
+	ExpandLatestNotice bool
+}
+
+// CustomFieldFilter matches faults whose custom field Key has Value, compared as text and
+// ignoring case, or with Negated, faults where it does not
+type CustomFieldFilter struct {
+	Key     string
+	Value   string
+	Negated bool
+}
+
+// FaultSortColumns maps the sort names accepted by the API to fault columns
+var FaultSortColumns = map[string]string{
+	"last_seen":   "f.last_seen_at",
+	"first_seen":  "f.first_seen_at",
+	"occurrences": "f.occurrence_count",
+	"created":     "f.created_at",
 }
 
 // CreateFault creates a new fault or returns existing one based on grouping
@@ -33,10 +76,10 @@ func (r *Repository) CreateFault(ctx context.Context, fault *models.Fault) (*mod
 	// Fault doesn't exist, create it
 	query := `
 		INSERT INTO faults (project_id, error_class, message, location, environment, 
-		                   first_seen_at, last_seen_at, tags)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		                   first_seen_at, last_seen_at, tags, state)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, COALESCE(NULLIF($9, ''), 'new'))
 		RETURNING id, project_id, error_class, message, location, environment,
-		          resolved, ignored, assignee_id, tags, public, occurrence_count,
+		          resolved, ignored, state, opened_at, acknowledged_at, closed_at, assignee_id, tags, public, severity, custom_fields, occurrence_count,
 		          first_seen_at, last_seen_at, created_at, updated_at
 	`
 	
@@ -50,6 +93,7 @@ func (r *Repository) CreateFault(ctx context.Context, fault *models.Fault) (*mod
 		fault.FirstSeenAt,
 		fault.LastSeenAt,
 		fault.Tags,
+		fault.State,
 	).Scan(
 		&createdFault.ID,
 		&createdFault.ProjectID,
@@ -59,9 +103,15 @@ func (r *Repository) CreateFault(ctx context.Context, fault *models.Fault) (*mod
 		&createdFault.Environment,
 		&createdFault.Resolved,
 		&createdFault.Ignored,
+		&createdFault.State,
+		&createdFault.OpenedAt,
+		&createdFault.AcknowledgedAt,
+		&createdFault.ClosedAt,
 		&createdFault.AssigneeID,
 		&createdFault.Tags,
 		&createdFault.Public,
+		&createdFault.Severity,
+		&createdFault.CustomFields,
 		&createdFault.OccurrenceCount,
 		&createdFault.FirstSeenAt,
 		&createdFault.LastSeenAt,
@@ -76,14 +126,24 @@ func (r *Repository) CreateFault(ctx context.Context, fault *models.Fault) (*mod
 	return &createdFault, nil
 }
 
-// FindFaultByFingerprint finds a fault by its fingerprint (error_class + location + environment)
+// FindFaultByFingerprint finds a fault by its fingerprint (error_class + location + environment).
+// Fingerprints of faults that were merged away resolve to the fault they were merged into.
 func (r *Repository) FindFaultByFingerprint(ctx context.Context, fault *models.Fault) (*models.Fault, error) {
 	query := `
 		SELECT id, project_id, error_class, message, location, environment,
-		       resolved, ignored, assignee_id, tags, public, occurrence_count,
+		       resolved, ignored, state, opened_at, acknowledged_at, closed_at, assignee_id, tags, public, severity, custom_fields, occurrence_count,
 		       first_seen_at, last_seen_at, created_at, updated_at
-		FROM faults
-		WHERE error_class = $1 AND location = $2 AND environment = $3
+		FROM (
+			SELECT f.*, 0 AS priority
+			FROM faults f
+			WHERE f.error_class = $1 AND f.location = $2 AND f.environment = $3
+			UNION ALL
+			SELECT f.*, 1 AS priority
+			FROM fault_merges m
+			JOIN faults f ON f.id = m.target_fault_id
+			WHERE m.error_class = $1 AND m.location = $2 AND m.environment = $3
+		) matched
+		ORDER BY priority
 		LIMIT 1
 	`
 	
@@ -101,9 +161,15 @@ func (r *Repository) FindFaultByFingerprint(ctx context.Context, fault *models.F
 		&foundFault.Environment,
 		&foundFault.Resolved,
 		&foundFault.Ignored,
+		&foundFault.State,
+		&foundFault.OpenedAt,
+		&foundFault.AcknowledgedAt,
+		&foundFault.ClosedAt,
 		&foundFault.AssigneeID,
 		&foundFault.Tags,
 		&foundFault.Public,
+		&foundFault.Severity,
+		&foundFault.CustomFields,
 		&foundFault.OccurrenceCount,
 		&foundFault.FirstSeenAt,
 		&foundFault.LastSeenAt,
@@ -122,7 +188,7 @@ func (r *Repository) FindFaultByFingerprint(ctx context.Context, fault *models.F
 func (r *Repository) GetFault(ctx context.Context, id int64) (*models.Fault, error) {
 	query := `
 		SELECT f.id, f.project_id, f.error_class, f.message, f.location, f.environment,
-		       f.resolved, f.ignored, f.assignee_id, f.tags, f.public, f.occurrence_count,
+		       f.resolved, f.ignored, f.state, f.opened_at, f.acknowledged_at, f.closed_at, f.assignee_id, f.tags, f.public, f.severity, f.custom_fields, f.occurrence_count,
 		       f.first_seen_at, f.last_seen_at, f.created_at, f.updated_at,
 		       u.id, u.email, u.name, u.avatar_url, u.is_admin, u.created_at
 		FROM faults f
@@ -146,9 +212,15 @@ func (r *Repository) GetFault(ctx context.Context, id int64) (*models.Fault, err
 		&fault.Environment,
 		&fault.Resolved,
 		&fault.Ignored,
+		&fault.State,
+		&fault.OpenedAt,
+		&fault.AcknowledgedAt,
+		&fault.ClosedAt,
 		&fault.AssigneeID,
 		&fault.Tags,
 		&fault.Public,
+		&fault.Severity,
+		&fault.CustomFields,
 		&fault.OccurrenceCount,
 		&fault.FirstSeenAt,
 		&fault.LastSeenAt,
@@ -182,8 +254,9 @@ func (r *Repository) GetFault(ctx context.Context, id int64) (*models.Fault, err
 	return &fault, nil
 }
 
-// ListFaults returns a list of faults with filters
-func (r *Repository) ListFaults(ctx context.Context, filters FaultFilters) ([]models.Fault, int64, error) {
+// faultWhereClause builds the WHERE clause and arguments for a set of fault filters.
+// It returns the next free placeholder index for callers that append more arguments.
+func faultWhereClause(filters FaultFilters) (string, []interface{}, int) {
 	var conditions []string
 	var args []interface{}
 	argIndex := 1
@@ -201,6 +274,18 @@ func (r *Repository) ListFaults(ctx context.Context, filters FaultFilters) ([]mo
 		argIndex++
 	}
 	
+	if len(filters.States) > 0 {
+		conditions = append(conditions, fmt.Sprintf("f.state = ANY($%d)", argIndex))
+		args = append(args, filters.States)
+		argIndex++
+	}
+	
+	if len(filters.ExcludedStates) > 0 {
+		conditions = append(conditions, fmt.Sprintf("f.state <> ALL($%d)", argIndex))
+		args = append(args, filters.ExcludedStates)
+		argIndex++
+	}
+	
 	if filters.Environment != nil && *filters.Environment != "" {
 		conditions = append(conditions, fmt.Sprintf("f.environment = $%d", argIndex))
 		args = append(args, *filters.Environment)
@@ -219,6 +304,28 @@ func (r *Repository) ListFaults(ctx context.Context, filters FaultFilters) ([]mo
 		argIndex++
 	}
 	
+	if filters.Public != nil {
+		conditions = append(conditions, fmt.Sprintf("f.public = $%d", argIndex))
+		args = append(args, *filters.Public)
+		argIndex++
+	}
+	
+	if filters.ProjectID != nil {
+		conditions = append(conditions, fmt.Sprintf("f.project_id = $%d", argIndex))
+		args = append(args, *filters.ProjectID)
+		argIndex++
+	}
+	
+	for _, field := range filters.CustomFields {
+		condition := fmt.Sprintf("LOWER(f.custom_fields->>$%d) = LOWER($%d)", argIndex, argIndex+1)
+		if field.Negated {
+			condition = fmt.Sprintf("COALESCE(LOWER(f.custom_fields->>$%d) <> LOWER($%d), TRUE)", argIndex, argIndex+1)
+		}
+		conditions = append(conditions, condition)
+		args = append(args, field.Key, field.Value)
+		argIndex += 2
+	}
+	
 	if filters.Search != "" {
 		searchPattern := "%" + strings.ToLower(filters.Search) + "%"
 		conditions = append(conditions, fmt.Sprintf(
@@ -229,11 +336,44 @@ func (r *Repository) ListFaults(ctx context.Context, filters FaultFilters) ([]mo
 		argIndex++
 	}
 	
+	if filters.SLAStatus != "" && filters.SLA != nil {
+		var condition string
+		var slaArgs []interface{}
+		condition, slaArgs, argIndex = slaStatusCondition(filters.SLA, filters.SLAStatus, argIndex)
+		conditions = append(conditions, condition)
+		args = append(args, slaArgs...)
+	}
+	
+	if filters.SeenAfter != nil {
+		conditions = append(conditions, fmt.Sprintf("f.last_seen_at >= $%d", argIndex))
+		args = append(args, *filters.SeenAfter)
+		argIndex++
+	}
+	
 	whereClause := ""
 	if len(conditions) > 0 {
 		whereClause = "WHERE " + strings.Join(conditions, " AND ")
 	}
 	
+	return whereClause, args, argIndex
+}
+
+// faultOrder returns the sort column and direction for a set of fault filters
+func faultOrder(filters FaultFilters) (string, string) {
+	column, ok := FaultSortColumns[filters.Sort]
+	if !ok {
+		column = FaultSortColumns["last_seen"]
+	}
+	if filters.SortAscending {
+		return column, "ASC"
+	}
+	return column, "DESC"
+}
+
+// ListFaults returns a list of faults with filters
+func (r *Repository) ListFaults(ctx context.Context, filters FaultFilters) ([]models.Fault, int64, error) {
+	whereClause, args, argIndex := faultWhereClause(filters)
+	
 	// Count query
 	countQuery := fmt.Sprintf(`
 		SELECT COUNT(*)
@@ -261,17 +401,47 @@ func (r *Repository) ListFaults(ctx context.Context, filters FaultFilters) ([]mo
 		offset = 0
 	}
 	
+	sortColumn, sortDir := faultOrder(filters)
+	
+	// Only join users when the assignee expansion is requested
+	userColumns := "NULL::bigint, NULL::text, NULL::text, NULL::text, NULL::boolean, NULL::timestamptz"
+	userJoin := ""
+	if filters.ExpandAssignee {
+		userColumns = "u.id, u.email, u.name, u.avatar_url, u.is_admin, u.created_at"
+		userJoin = "LEFT JOIN users u ON f.assignee_id = u.id"
+	}
+	
+	// The lateral join uses idx_notices_fault_created to fetch one row per fault
+	noticeColumns := "NULL::text, NULL::text, NULL::text, NULL::jsonb, NULL::timestamptz"
+	noticeJoin := ""
+	if filters.ExpandLatestNotice {
+		noticeColumns = "ln.id, ln.hostname, ln.revision, ln.backtrace, ln.created_at"
+		noticeJoin = fmt.Sprintf(`
+		LEFT JOIN LATERAL (
+			SELECT n.id, n.hostname, n.revision,
+			       jsonb_path_query_array(%s, '$[0 to %d]') AS backtrace,
+			       n.created_at
+			FROM notices n
+			%s
+			WHERE n.fault_id = f.id
+			ORDER BY n.created_at DESC
+			LIMIT 1
+		) ln ON TRUE`, noticeBacktrace, models.NoticeSummaryFrames-1, noticeBacktraceJoin)
+	}
+	
 	listQuery := fmt.Sprintf(`
 		SELECT f.id, f.project_id, f.error_class, f.message, f.location, f.environment,
-		       f.resolved, f.ignored, f.assignee_id, f.tags, f.public, f.occurrence_count,
+		       f.resolved, f.ignored, f.state, f.opened_at, f.acknowledged_at, f.closed_at, f.assignee_id, f.tags, f.public, f.severity, f.custom_fields, f.occurrence_count,
 		       f.first_seen_at, f.last_seen_at, f.created_at, f.updated_at,
-		       u.id, u.email, u.name, u.avatar_url, u.is_admin, u.created_at
+		       %s,
+		       %s
 		FROM faults f
-		LEFT JOIN users u ON f.assignee_id = u.id
 		%s
-		ORDER BY f.last_seen_at DESC
+		%s
+		%s
+		ORDER BY %s %s, f.id %s
 		LIMIT $%d OFFSET $%d
-	`, whereClause, argIndex, argIndex+1)
+	`, userColumns, noticeColumns, userJoin, noticeJoin, whereClause, sortColumn, sortDir, sortDir, argIndex, argIndex+1)
 	
 	args = append(args, limit, offset)
 	
@@ -289,6 +459,9 @@ func (r *Repository) ListFaults(ctx context.Context, filters FaultFilters) ([]mo
 		var userAvatarURL sql.NullString
 		var userIsAdmin sql.NullBool
 		var userCreatedAt sql.NullTime
+		var noticeID, noticeHostname, noticeRevision sql.NullString
+		var noticeBacktraceJSON []byte
+		var noticeCreatedAt sql.NullTime
 		
 		err := rows.Scan(
 			&fault.ID,
@@ -299,9 +472,15 @@ func (r *Repository) ListFaults(ctx context.Context, filters FaultFilters) ([]mo
 			&fault.Environment,
 			&fault.Resolved,
 			&fault.Ignored,
+			&fault.State,
+			&fault.OpenedAt,
+			&fault.AcknowledgedAt,
+			&fault.ClosedAt,
 			&fault.AssigneeID,
 			&fault.Tags,
 			&fault.Public,
+			&fault.Severity,
+			&fault.CustomFields,
 			&fault.OccurrenceCount,
 			&fault.FirstSeenAt,
 			&fault.LastSeenAt,
@@ -313,11 +492,34 @@ func (r *Repository) ListFaults(ctx context.Context, filters FaultFilters) ([]mo
 			&userAvatarURL,
 			&userIsAdmin,
 			&userCreatedAt,
+			&noticeID,
+			&noticeHostname,
+			&noticeRevision,
+			&noticeBacktraceJSON,
+			&noticeCreatedAt,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("error scanning fault: %w", err)
 		}
 		
+		if noticeID.Valid {
+			fault.LatestNotice = &models.NoticeSummary{
+				ID:        noticeID.String,
+				CreatedAt: noticeCreatedAt.Time,
+			}
+			if noticeHostname.Valid {
+				fault.LatestNotice.Hostname = &noticeHostname.String
+			}
+			if noticeRevision.Valid {
+				fault.LatestNotice.Revision = &noticeRevision.String
+			}
+			if len(noticeBacktraceJSON) > 0 {
+				if err := json.Unmarshal(noticeBacktraceJSON, &fault.LatestNotice.Backtrace); err != nil {
+					return nil, 0, fmt.Errorf("error decoding backtrace of notice %s: %w", noticeID.String, err)
+				}
+			}
+		}
+		
 		if userID.Valid {
 			fault.Assignee = &models.User{
 				ID:        userID.Int64,
@@ -337,6 +539,49 @@ func (r *Repository) ListFaults(ctx context.Context, filters FaultFilters) ([]mo
 	return faults, total, nil
 }
 
+// GetFaultNeighbors returns the IDs of the faults immediately before and after id
+// in the ordering defined by filters. The fault itself need not match the filters,
+// so triage can continue after a fault drops out of the current search.
+func (r *Repository) GetFaultNeighbors(ctx context.Context, id int64, filters FaultFilters) (*int64, *int64, error) {
+	sortColumn, sortDir := faultOrder(filters)
+	
+	whereClause, args, argIndex := faultWhereClause(filters)
+	if whereClause == "" {
+		whereClause = "WHERE TRUE"
+	}
+	
+	// Compare (sort key, id) tuples against the anchor fault so ties stay stable
+	anchor := fmt.Sprintf(`(SELECT %s, f.id FROM faults f WHERE f.id = $%d)`, sortColumn, argIndex)
+	args = append(args, id)
+	
+	before, after := ">", "<"
+	reverse := "ASC"
+	if sortDir == "ASC" {
+		before, after = "<", ">"
+		reverse = "DESC"
+	}
+	
+	query := fmt.Sprintf(`
+		SELECT
+			(SELECT f.id FROM faults f %[1]s AND (%[2]s, f.id) %[3]s %[4]s
+			 ORDER BY %[2]s %[5]s, f.id %[5]s LIMIT 1),
+			(SELECT f.id FROM faults f %[1]s AND (%[2]s, f.id) %[6]s %[4]s
+			 ORDER BY %[2]s %[7]s, f.id %[7]s LIMIT 1),
+			EXISTS (SELECT 1 FROM faults WHERE id = $%[8]d)
+	`, whereClause, sortColumn, before, anchor, reverse, after, sortDir, argIndex)
+	
+	var prevID, nextID *int64
+	var exists bool
+	if err := r.pool.QueryRow(ctx, query, args...).Scan(&prevID, &nextID, &exists); err != nil {
+		return nil, nil, fmt.Errorf("error getting fault neighbors: %w", err)
+	}
+	if !exists {
+		return nil, nil, fmt.Errorf("fault %d: %w", id, ErrNotFound)
+	}
+	
+	return prevID, nextID, nil
+}
+
 // UpdateFault updates a fault
 func (r *Repository) UpdateFault(ctx context.Context, id int64, updates map[string]interface{}) error {
 	if len(updates) == 0 {
@@ -365,113 +610,78 @@ func (r *Repository) UpdateFault(ctx context.Context, id int64, updates map[stri
 	return err
 }
 
-// ResolveFault marks a fault as resolved
-func (r *Repository) ResolveFault(ctx context.Context, id int64, userID *int64) error {
-	query := `
-		UPDATE faults
-		SET resolved = TRUE, updated_at = NOW()
-		WHERE id = $1
-	`
-	
-	_, err := r.pool.Exec(ctx, query, id)
-	if err != nil {
-		return err
-	}
-	
-	// Record history
-	return r.AddFaultHistory(ctx, id, "resolved", userID, nil)
-}
+// FaultMove picks a fault's next workflow state from its current one, along with the legacy
+// resolved and ignored flags for it. Returning an error refuses the move.
+type FaultMove func(from string) (to string, resolved, ignored bool, err error)
 
-// UnresolveFault marks a fault as unresolved
-func (r *Repository) UnresolveFault(ctx context.Context, id int64, userID *int64) error {
-	query := `
-		UPDATE faults
-		SET resolved = FALSE, updated_at = NOW()
-		WHERE id = $1
-	`
-	
-	_, err := r.pool.Exec(ctx, query, id)
+// MoveFault moves a fault to the state move picks and records history when it changes. The
+// fault is locked while move runs, so the move is decided on its current state.
+func (r *Repository) MoveFault(ctx context.Context, id int64, action string, move FaultMove, actorID *int64) error {
+	var moveErr error
+	err := r.trackedFaultUpdate(ctx, id, action, actorID, []string{"state", "resolved", "ignored"}, func(old map[string]interface{}) map[string]interface{} {
+		from, _ := old["state"].(string)
+		to, resolved, ignored, err := move(from)
+		if err != nil {
+			moveErr = err
+			return nil
+		}
+		return map[string]interface{}{"state": to, "resolved": resolved, "ignored": ignored}
+	})
 	if err != nil {
 		return err
 	}
-	
-	return r.AddFaultHistory(ctx, id, "unresolved", userID, nil)
+	return moveErr
 }
 
-// IgnoreFault marks a fault as ignored
-func (r *Repository) IgnoreFault(ctx context.Context, id int64, userID *int64) error {
-	query := `
-		UPDATE faults
-		SET ignored = TRUE, updated_at = NOW()
-		WHERE id = $1
-	`
-	
-	_, err := r.pool.Exec(ctx, query, id)
-	if err != nil {
-		return err
+// AssignFault assigns a fault to a user, or unassigns it when assigneeID is nil.
+// actorID is the user making the change and is recorded in history.
+func (r *Repository) AssignFault(ctx context.Context, id int64, assigneeID *int64, actorID *int64) error {
+	action := "assigned"
+	if assigneeID == nil {
+		action = "unassigned"
 	}
 	
-	return r.AddFaultHistory(ctx, id, "ignored", userID, nil)
+	return r.trackedFaultUpdate(ctx, id, action, actorID, []string{"assignee_id"}, func(map[string]interface{}) map[string]interface{} {
+		if assigneeID == nil {
+			return map[string]interface{}{"assignee_id": nil}
+		}
+		return map[string]interface{}{"assignee_id": *assigneeID}
+	})
 }
 
-// UnignoreFault marks a fault as not ignored
-func (r *Repository) UnignoreFault(ctx context.Context, id int64, userID *int64) error {
-	query := `
-		UPDATE faults
-		SET ignored = FALSE, updated_at = NOW()
-		WHERE id = $1
-	`
-	
-	_, err := r.pool.Exec(ctx, query, id)
-	if err != nil {
-		return err
+// AddFaultTags adds tags to a fault, ignoring tags it already has
+func (r *Repository) AddFaultTags(ctx context.Context, id int64, tags []string, actorID *int64) error {
+	if len(tags) == 0 {
+		return nil
 	}
 	
-	return r.AddFaultHistory(ctx, id, "unignored", userID, nil)
+	return r.trackedFaultUpdate(ctx, id, "tagged", actorID, []string{"tags"}, func(old map[string]interface{}) map[string]interface{} {
+		existing, _ := old["tags"].([]string)
+		return map[string]interface{}{"tags": mergeTags(existing, tags)}
+	})
 }
 
-// AssignFault assigns a fault to a user
-func (r *Repository) AssignFault(ctx context.Context, id int64, userID *int64) error {
-	query := `
-		UPDATE faults
-		SET assignee_id = $1, updated_at = NOW()
-		WHERE id = $2
-	`
-	
-	_, err := r.pool.Exec(ctx, query, userID, id)
-	if err != nil {
-		return err
-	}
-	
-	return r.AddFaultHistory(ctx, id, "assigned", userID, nil)
+// ReplaceFaultTags replaces all tags on a fault
+func (r *Repository) ReplaceFaultTags(ctx context.Context, id int64, tags []string, actorID *int64) error {
+	return r.trackedFaultUpdate(ctx, id, "tagged", actorID, []string{"tags"}, func(map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"tags": mergeTags(nil, tags)}
+	})
 }
 
-// AddFaultTags adds tags to a fault
-func (r *Repository) AddFaultTags(ctx context.Context, id int64, tags []string) error {
-	if len(tags) == 0 {
+// UpdateFaultTracked applies a partial update to a fault and records the changed fields in history
+func (r *Repository) UpdateFaultTracked(ctx context.Context, id int64, updates map[string]interface{}, actorID *int64) error {
+	if len(updates) == 0 {
 		return nil
 	}
 	
-	query := `
-		UPDATE faults
-		SET tags = array_cat(tags, $1), updated_at = NOW()
-		WHERE id = $2
-	`
-	
-	_, err := r.pool.Exec(ctx, query, tags, id)
-	return err
-}
-
-// ReplaceFaultTags replaces all tags on a fault
-func (r *Repository) ReplaceFaultTags(ctx context.Context, id int64, tags []string) error {
-	query := `
-		UPDATE faults
-		SET tags = $1, updated_at = NOW()
-		WHERE id = $2
-	`
+	columns := make([]string, 0, len(updates))
+	for column := range updates {
+		columns = append(columns, column)
+	}
 	
-	_, err := r.pool.Exec(ctx, query, tags, id)
-	return err
+	return r.trackedFaultUpdate(ctx, id, "updated", actorID, columns, func(map[string]interface{}) map[string]interface{} {
+		return updates
+	})
 }
 
 // IncrementFaultOccurrence increments the occurrence count and updates last_seen_at
@@ -493,21 +703,24 @@ func (r *Repository) GetFaultOccurrences(ctx context.Context, faultID int64, lim
 	if limit <= 0 {
 		limit = 50
 	}
-	if limit > 1000 {
-		limit = 1000
+	// Allow one row past the API maximum so callers can look ahead for another page
+	if limit > 1001 {
+		limit = 1001
 	}
 	if offset < 0 {
 		offset = 0
 	}
 	
-	query := `
-		SELECT id, fault_id, project_id, message, backtrace, context, params,
-		       session, cookies, environment, breadcrumbs, revision, hostname, created_at
-		FROM notices
-		WHERE fault_id = $1
-		ORDER BY created_at DESC
+	query := fmt.Sprintf(`
+		SELECT n.id, n.fault_id, n.project_id, n.message, %s, n.context, n.params,
+		       n.session, n.cookies, %s, n.breadcrumbs, n.revision, n.hostname, n.created_at, n.account_id
+		FROM notices n
+		%s
+		%s
+		WHERE n.fault_id = $1
+		ORDER BY n.created_at DESC
 		LIMIT $2 OFFSET $3
-	`
+	`, noticeBacktrace, noticeEnvironment, noticeBacktraceJoin, noticeEnvironmentJoin)
 	
 	rows, err := r.pool.Query(ctx, query, faultID, limit, offset)
 	if err != nil {
@@ -519,7 +732,7 @@ func (r *Repository) GetFaultOccurrences(ctx context.Context, faultID int64, lim
 	for rows.Next() {
 		var notice models.Notice
 		var backtraceJSON, contextJSON, paramsJSON, sessionJSON, cookiesJSON, environmentJSON, breadcrumbsJSON []byte
-		var revision, hostname sql.NullString
+		var revision, hostname, accountID sql.NullString
 		
 		err := rows.Scan(
 			&notice.ID,
@@ -536,6 +749,7 @@ func (r *Repository) GetFaultOccurrences(ctx context.Context, faultID int64, lim
 			&revision,
 			&hostname,
 			&notice.CreatedAt,
+			&accountID,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("error scanning notice: %w", err)
@@ -569,6 +783,7 @@ func (r *Repository) GetFaultOccurrences(ctx context.Context, faultID int64, lim
 		if hostname.Valid {
 			notice.Hostname = &hostname.String
 		}
+		notice.AccountID = accountID.String
 		
 		notices = append(notices, notice)
 	}
@@ -613,50 +828,259 @@ func (r *Repository) GetFaultStats(ctx context.Context, faultID int64) (*FaultSt
 	return &stats, nil
 }
 
-// CreateNotice creates a new notice
+// CreateNotice creates a new notice. Its backtrace is stored once in the backtraces table and
+// referenced by hash, as is its environment data in payload_sections when the payload is shared.
 func (r *Repository) CreateNotice(ctx context.Context, notice *models.Notice) error {
 	query := `
-		INSERT INTO notices (id, fault_id, project_id, message, backtrace, context, params,
-		                    session, cookies, environment, breadcrumbs, revision, hostname, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		INSERT INTO notices (id, fault_id, project_id, message, backtrace_hash, context, params,
+		                    session, cookies, environment, environment_hash, breadcrumbs, revision, hostname,
+		                    created_at, error_class, component, action, account_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 	`
 	
-	backtraceJSON, _ := json.Marshal(notice.Backtrace)
-	contextJSON, _ := json.Marshal(notice.Context)
-	paramsJSON, _ := json.Marshal(notice.Params)
-	sessionJSON, _ := json.Marshal(notice.Session)
-	cookiesJSON, _ := json.Marshal(notice.Cookies)
-	environmentJSON, _ := json.Marshal(notice.Environment)
-	breadcrumbsJSON, _ := json.Marshal(notice.Breadcrumbs)
+	// Encode all JSONB columns into one pooled buffer, which is reused once the insert returns
+	jsonb, release, err := marshalJSONB(notice.Backtrace, notice.Context, notice.Params, notice.Session,
+		notice.Cookies, notice.Environment, notice.Breadcrumbs)
+	if err != nil {
+		return fmt.Errorf("error encoding notice: %w", err)
+	}
+	defer release()
+	
+	backtraces, sections := newBacktraceSet(), newPayloadSectionSet()
+	backtraceHash := backtraces.add(jsonb[0], len(notice.Backtrace) == 0)
+	environment, environmentHash := sharedSection(sections, notice.SharePayload, jsonb[5], len(notice.Environment) == 0)
+	
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
 	
-	_, err := r.pool.Exec(ctx, query,
+	if err := backtraces.store(ctx, tx); err != nil {
+		return err
+	}
+	if err := sections.store(ctx, tx); err != nil {
+		return err
+	}
+	
+	_, err = tx.Exec(ctx, query,
 		notice.ID,
 		notice.FaultID,
 		notice.ProjectID,
 		notice.Message,
-		backtraceJSON,
-		contextJSON,
-		paramsJSON,
-		sessionJSON,
-		cookiesJSON,
-		environmentJSON,
-		breadcrumbsJSON,
+		backtraceHash,
+		jsonb[1], // context
+		jsonb[2], // params
+		jsonb[3], // session
+		jsonb[4], // cookies
+		environment,
+		environmentHash,
+		jsonb[6], // breadcrumbs
 		notice.Revision,
 		notice.Hostname,
 		notice.CreatedAt,
+		nullIfEmpty(notice.ErrorClass),
+		nullIfEmpty(notice.Component),
+		nullIfEmpty(notice.Action),
+		nullIfEmpty(notice.AccountID),
 	)
+	if err != nil {
+		return err
+	}
 	
-	return err
+	return tx.Commit(ctx)
+}
+
+// InsertNoticeBatch stores notices with a single COPY and adds them to their faults'
+// occurrence counts, in one transaction. Each distinct backtrace and shared payload section in
+// the batch is stored once. counted adds occurrences of notices that were not stored, by fault.
+// Notices of faults merged since they were grouped are stored under the fault they were merged
+// into, and those of faults deleted since are dropped rather than failing the whole batch; the
+// number dropped is returned.
+func (r *Repository) InsertNoticeBatch(ctx context.Context, notices []*models.Notice, counted map[int64]int32) (int, error) {
+	if len(notices) == 0 && len(counted) == 0 {
+		return 0, nil
+	}
+	
+	rows := make([][]interface{}, 0, len(notices))
+	counts := make(map[int64]int32, len(counted))
+	for id, n := range counted {
+		counts[id] = n
+	}
+	// The encoded columns stay in use until the last attempt at the COPY has returned
+	releases := make([]func(), 0, len(notices))
+	defer func() {
+		for _, release := range releases {
+			release()
+		}
+	}()
+	backtraces, sections := newBacktraceSet(), newPayloadSectionSet()
+	for _, notice := range notices {
+		jsonb, release, err := marshalJSONB(notice.Backtrace, notice.Context, notice.Params, notice.Session,
+			notice.Cookies, notice.Environment, notice.Breadcrumbs)
+		if err != nil {
+			return 0, fmt.Errorf("error encoding notice %s: %w", notice.ID, err)
+		}
+		releases = append(releases, release)
+		
+		environment, environmentHash := sharedSection(sections, notice.SharePayload, jsonb[5], len(notice.Environment) == 0)
+		rows = append(rows, []interface{}{
+			notice.ID, notice.FaultID, notice.ProjectID, notice.Message,
+			backtraces.add(jsonb[0], len(notice.Backtrace) == 0), jsonb[1], jsonb[2], jsonb[3], jsonb[4],
+			environment, environmentHash, jsonb[6],
+			notice.Revision, notice.Hostname, notice.CreatedAt,
+			nullIfEmpty(notice.ErrorClass), nullIfEmpty(notice.Component), nullIfEmpty(notice.Action),
+			nullIfEmpty(notice.AccountID),
+		})
+		counts[notice.FaultID]++
+	}
+	
+	err := r.writeNoticeBatch(ctx, rows, counts, backtraces, sections)
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23503" {
+		return 0, err
+	}
+	
+	// A fault was merged or deleted after its notices were grouped; move its notices to the
+	// fault it was merged into, or drop them, and try once more
+	rows, counts, dropped, err := r.refaultNoticeRows(ctx, rows, counts)
+	if err != nil {
+		return 0, err
+	}
+	if err := r.writeNoticeBatch(ctx, rows, counts, backtraces, sections); err != nil {
+		return 0, err
+	}
+	return dropped, nil
+}
+
+// writeNoticeBatch writes the rows and occurrence counts built by InsertNoticeBatch
+func (r *Repository) writeNoticeBatch(ctx context.Context, rows [][]interface{}, counts map[int64]int32, backtraces, sections *contentSet) error {
+	// Update faults in ID order so concurrent flushes lock rows consistently
+	faultIDs := make([]int64, 0, len(counts))
+	for id := range counts {
+		faultIDs = append(faultIDs, id)
+	}
+	sort.Slice(faultIDs, func(i, j int) bool { return faultIDs[i] < faultIDs[j] })
+	increments := make([]int32, len(faultIDs))
+	for i, id := range faultIDs {
+		increments[i] = counts[id]
+	}
+	
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	
+	if err := backtraces.store(ctx, tx); err != nil {
+		return err
+	}
+	if err := sections.store(ctx, tx); err != nil {
+		return err
+	}
+	
+	if len(rows) > 0 {
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"notices"},
+			[]string{"id", "fault_id", "project_id", "message", "backtrace_hash", "context", "params",
+				"session", "cookies", "environment", "environment_hash", "breadcrumbs", "revision", "hostname", "created_at",
+				"error_class", "component", "action", "account_id"},
+			pgx.CopyFromRows(rows),
+		)
+		if err != nil {
+			return fmt.Errorf("error copying notices: %w", err)
+		}
+	}
+	
+	_, err = tx.Exec(ctx, `
+		UPDATE faults f
+		SET occurrence_count = f.occurrence_count + c.n,
+		    last_seen_at = NOW(),
+		    updated_at = NOW()
+		FROM unnest($1::bigint[], $2::int[]) AS c(id, n)
+		WHERE f.id = c.id
+	`, faultIDs, increments)
+	if err != nil {
+		return fmt.Errorf("error updating fault occurrences: %w", err)
+	}
+	
+	return tx.Commit(ctx)
+}
+
+// refaultNoticeRows points the rows and counts of faults that no longer exist at the fault each
+// was merged into, following fault_merges, and drops the rows of deleted faults. It returns the
+// number of rows dropped.
+func (r *Repository) refaultNoticeRows(ctx context.Context, rows [][]interface{}, counts map[int64]int32) ([][]interface{}, map[int64]int32, int, error) {
+	faultIDs := make([]int64, 0, len(counts))
+	for id := range counts {
+		faultIDs = append(faultIDs, id)
+	}
+	result, err := r.pool.Query(ctx, `
+		SELECT c.id, COALESCE(f.id, m.target_fault_id)
+		FROM unnest($1::bigint[]) AS c(id)
+		LEFT JOIN faults f ON f.id = c.id
+		LEFT JOIN LATERAL (
+			SELECT target_fault_id FROM fault_merges
+			WHERE source_fault_id = c.id
+			ORDER BY merged_at DESC
+			LIMIT 1
+		) m ON TRUE
+	`, faultIDs)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("error resolving faults of notices: %w", err)
+	}
+	current := make(map[int64]int64, len(faultIDs))
+	for result.Next() {
+		var id int64
+		var target *int64
+		if err := result.Scan(&id, &target); err != nil {
+			result.Close()
+			return nil, nil, 0, fmt.Errorf("error scanning fault of notices: %w", err)
+		}
+		if target != nil {
+			current[id] = *target
+		}
+	}
+	result.Close()
+	if err := result.Err(); err != nil {
+		return nil, nil, 0, fmt.Errorf("error resolving faults of notices: %w", err)
+	}
+	
+	kept := rows[:0]
+	for _, row := range rows {
+		target, ok := current[row[1].(int64)]
+		if !ok {
+			continue
+		}
+		row[1] = target
+		kept = append(kept, row)
+	}
+	refaulted := make(map[int64]int32, len(counts))
+	for id, n := range counts {
+		if target, ok := current[id]; ok {
+			refaulted[target] += n
+		}
+	}
+	return kept, refaulted, len(rows) - len(kept), nil
+}
+
+// nullIfEmpty stores an empty string as NULL
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
 }
 
 // GetNotice returns a notice by ID
 func (r *Repository) GetNotice(ctx context.Context, id string) (*models.Notice, error) {
-	query := `
-		SELECT id, fault_id, project_id, message, backtrace, context, params,
-		       session, cookies, environment, breadcrumbs, revision, hostname, created_at
-		FROM notices
-		WHERE id = $1
-	`
+	query := fmt.Sprintf(`
+		SELECT n.id, n.fault_id, n.project_id, n.message, %s, n.context, n.params,
+		       n.session, n.cookies, %s, n.breadcrumbs, n.revision, n.hostname, n.created_at
+		FROM notices n
+		%s
+		%s
+		WHERE n.id = $1
+	`, noticeBacktrace, noticeEnvironment, noticeBacktraceJoin, noticeEnvironmentJoin)
 	
 	var notice models.Notice
 	var backtraceJSON, contextJSON, paramsJSON, sessionJSON, cookiesJSON, environmentJSON, breadcrumbsJSON []byte
@@ -723,20 +1147,28 @@ func (r *Repository) DeleteFault(ctx context.Context, id int64) error {
 }
 
 // AddFaultHistory adds a history entry for a fault
-func (r *Repository) AddFaultHistory(ctx context.Context, faultID int64, action string, userID *int64, revision *string) error {
+func (r *Repository) AddFaultHistory(ctx context.Context, faultID int64, action string, userID *int64, revision *string, changes ...models.FieldChange) error {
+	if changes == nil {
+		changes = []models.FieldChange{}
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("error encoding history changes: %w", err)
+	}
+	
 	query := `
-		INSERT INTO fault_history (fault_id, action, user_id, revision)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO fault_history (fault_id, action, user_id, revision, changes)
+		VALUES ($1, $2, $3, $4, $5)
 	`
 	
-	_, err := r.pool.Exec(ctx, query, faultID, action, userID, revision)
+	_, err = r.pool.Exec(ctx, query, faultID, action, userID, revision, changesJSON)
 	return err
 }
 
 // GetFaultHistory returns history entries for a fault
 func (r *Repository) GetFaultHistory(ctx context.Context, faultID int64) ([]models.FaultHistory, error) {
 	query := `
-		SELECT h.id, h.fault_id, h.action, h.user_id, h.revision, h.created_at,
+		SELECT h.id, h.fault_id, h.action, h.user_id, h.revision, h.changes, h.created_at,
 		       u.id, u.email, u.name, u.avatar_url, u.is_admin, u.created_at
 		FROM fault_history h
 		LEFT JOIN users u ON h.user_id = u.id
@@ -753,6 +1185,7 @@ func (r *Repository) GetFaultHistory(ctx context.Context, faultID int64) ([]mode
 	var history []models.FaultHistory
 	for rows.Next() {
 		var h models.FaultHistory
+		var changesJSON []byte
 		var userID sql.NullInt64
 		var userEmail, userName sql.NullString
 		var userAvatarURL sql.NullString
@@ -765,6 +1198,7 @@ func (r *Repository) GetFaultHistory(ctx context.Context, faultID int64) ([]mode
 			&h.Action,
 			&h.UserID,
 			&h.Revision,
+			&changesJSON,
 			&h.CreatedAt,
 			&userID,
 			&userEmail,
@@ -777,6 +1211,12 @@ func (r *Repository) GetFaultHistory(ctx context.Context, faultID int64) ([]mode
 			return nil, fmt.Errorf("error scanning history: %w", err)
 		}
 		
+		h.Changes = []models.FieldChange{}
+		if len(changesJSON) > 0 {
+			json.Unmarshal(changesJSON, &h.Changes)
+		}
+		h.Description = h.Describe()
+		
 		if userID.Valid {
 			h.User = &models.User{
 				ID:        userID.Int64,
@@ -811,18 +1251,56 @@ func (r *Repository) CreateComment(ctx context.Context, comment *models.Comment)
 	return err
 }
 
-// GetFaultComments returns comments for a fault
-func (r *Repository) GetFaultComments(ctx context.Context, faultID int64) ([]models.Comment, error) {
+// FindRecentComment returns an identical comment by the same user on the same fault
+// created within window, or ErrNotFound. It is used to suppress double submissions.
+func (r *Repository) FindRecentComment(ctx context.Context, comment *models.Comment, window time.Duration) (*models.Comment, error) {
+	query := `
+		SELECT id, fault_id, user_id, comment, created_at
+		FROM fault_comments
+		WHERE fault_id = $1 AND user_id = $2 AND comment = $3
+		  AND created_at > NOW() - make_interval(secs => $4)
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	
+	var existing models.Comment
+	err := r.pool.QueryRow(ctx, query, comment.FaultID, comment.UserID, comment.Comment, window.Seconds()).Scan(
+		&existing.ID,
+		&existing.FaultID,
+		&existing.UserID,
+		&existing.Comment,
+		&existing.CreatedAt,
+	)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("error finding recent comment: %w", err)
+	}
+	
+	return &existing, nil
+}
+
+// GetFaultComments returns a page of comments for a fault, oldest first; a limit of 0 returns
+// them all
+func (r *Repository) GetFaultComments(ctx context.Context, faultID int64, limit, offset int) ([]models.Comment, error) {
 	query := `
 		SELECT c.id, c.fault_id, c.user_id, c.comment, c.created_at,
 		       u.id, u.email, u.name, u.avatar_url, u.is_admin, u.created_at
 		FROM fault_comments c
 		JOIN users u ON c.user_id = u.id
 		WHERE c.fault_id = $1
-		ORDER BY c.created_at ASC
+		ORDER BY c.created_at ASC, c.id ASC
+		LIMIT $2 OFFSET $3
 	`
 	
-	rows, err := r.pool.Query(ctx, query, faultID)
+	// LIMIT NULL is no limit
+	var pageLimit *int
+	if limit > 0 {
+		pageLimit = &limit
+	}
+	
+	rows, err := r.pool.Query(ctx, query, faultID, pageLimit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("error getting comments: %w", err)
 	}
@@ -862,15 +1340,22 @@ func (r *Repository) GetFaultComments(ctx context.Context, faultID int64) ([]mod
 	return comments, nil
 }
 
-// GetUsers returns all users
-func (r *Repository) GetUsers(ctx context.Context) ([]models.User, error) {
+// GetUsers returns a page of users ordered by name; a limit of 0 returns them all
+func (r *Repository) GetUsers(ctx context.Context, limit, offset int) ([]models.User, error) {
 	query := `
 		SELECT id, email, name, avatar_url, is_admin, created_at
 		FROM users
-		ORDER BY name ASC
+		ORDER BY name ASC, id ASC
+		LIMIT $1 OFFSET $2
 	`
 	
-	rows, err := r.pool.Query(ctx, query)
+	// LIMIT NULL is no limit
+	var pageLimit *int
+	if limit > 0 {
+		pageLimit = &limit
+	}
+	
+	rows, err := r.pool.Query(ctx, query, pageLimit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("error getting users: %w", err)
 	}
@@ -988,47 +1473,123 @@ func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*models.
 	return &user, nil
 }
 
-// MergeFaults merges notices from source fault into target fault
-func (r *Repository) MergeFaults(ctx context.Context, sourceFaultID, targetFaultID int64) error {
-	// Update all notices to point to target fault
-	query := `
-		UPDATE notices
-		SET fault_id = $1
-		WHERE fault_id = $2
-	`
-	
-	_, err := r.pool.Exec(ctx, query, targetFaultID, sourceFaultID)
-	if err != nil {
-		return fmt.Errorf("error updating notices: %w", err)
-	}
-	
-	// Get stats for both faults
-	sourceStats, err := r.GetFaultStats(ctx, sourceFaultID)
-	if err != nil {
-		return fmt.Errorf("error getting source fault stats: %w", err)
-	}
-	
-	targetFault, err := r.GetFault(ctx, targetFaultID)
-	if err != nil {
-		return fmt.Errorf("error getting target fault: %w", err)
-	}
-	
-	// Update target fault with merged data
-	updates := map[string]interface{}{
-		"occurrence_count": targetFault.OccurrenceCount + sourceStats.TotalOccurrences,
-	}
-	
-	if sourceStats.FirstOccurred.Before(targetFault.FirstSeenAt) {
-		updates["first_seen_at"] = sourceStats.FirstOccurred
-	}
-	if sourceStats.LastOccurred.After(targetFault.LastSeenAt) {
-		updates["last_seen_at"] = sourceStats.LastOccurred
+// FacetCount is the number of faults sharing one value of a facet
+type FacetCount struct {
+	Value string  `json:"value"`
+	Label *string `json:"label,omitempty"`
+	Count int64   `json:"count"`
+}
+
+// FaultFacets holds per-facet fault counts for a search
+type FaultFacets struct {
+	Environment []FacetCount `json:"environment"`
+	Status      []FacetCount `json:"status"`
+	State       []FacetCount `json:"state"`
+	Assignee    []FacetCount `json:"assignee"`
+	Tags        []FacetCount `json:"tags"`
+}
+
+// GetFaultFacets returns fault counts grouped by environment, status, state, assignee and
+// the most common tags, for the faults matching filters. Pagination and sort are ignored.
+func (r *Repository) GetFaultFacets(ctx context.Context, filters FaultFilters, topTags int) (*FaultFacets, error) {
+	whereClause, args, argIndex := faultWhereClause(filters)
+	
+	facets := &FaultFacets{}
+	
+	queries := []struct {
+		name  string
+		dest  *[]FacetCount
+		query string
+		args  []interface{}
+	}{
+		{
+			name: "environment",
+			dest: &facets.Environment,
+			query: fmt.Sprintf(`
+				SELECT f.environment, NULL::TEXT, COUNT(*)
+				FROM faults f
+				%s
+				GROUP BY f.environment
+				ORDER BY COUNT(*) DESC, f.environment
+			`, whereClause),
+			args: args,
+		},
+		{
+			name: "status",
+			dest: &facets.Status,
+			query: fmt.Sprintf(`
+				SELECT CASE WHEN f.ignored THEN 'ignored' WHEN f.resolved THEN 'resolved' ELSE 'unresolved' END AS status,
+				       NULL::TEXT, COUNT(*)
+				FROM faults f
+				%s
+				GROUP BY status
+				ORDER BY COUNT(*) DESC, status
+			`, whereClause),
+			args: args,
+		},
+		{
+			name: "state",
+			dest: &facets.State,
+			query: fmt.Sprintf(`
+				SELECT f.state, NULL::TEXT, COUNT(*)
+				FROM faults f
+				%s
+				GROUP BY f.state
+				ORDER BY COUNT(*) DESC, f.state
+			`, whereClause),
+			args: args,
+		},
+		{
+			name: "assignee",
+			dest: &facets.Assignee,
+			query: fmt.Sprintf(`
+				SELECT COALESCE(f.assignee_id::TEXT, 'unassigned'), MAX(u.name), COUNT(*)
+				FROM faults f
+				LEFT JOIN users u ON f.assignee_id = u.id
+				%s
+				GROUP BY f.assignee_id
+				ORDER BY COUNT(*) DESC, f.assignee_id
+			`, whereClause),
+			args: args,
+		},
+		{
+			name: "tags",
+			dest: &facets.Tags,
+			query: fmt.Sprintf(`
+				SELECT t.tag, NULL::TEXT, COUNT(*)
+				FROM faults f
+				CROSS JOIN LATERAL unnest(f.tags) AS t(tag)
+				%s
+				GROUP BY t.tag
+				ORDER BY COUNT(*) DESC, t.tag
+				LIMIT $%d
+			`, whereClause, argIndex),
+			args: append(append([]interface{}{}, args...), topTags),
+		},
 	}
 	
-	if err := r.UpdateFault(ctx, targetFaultID, updates); err != nil {
-		return fmt.Errorf("error updating target fault: %w", err)
+	for _, q := range queries {
+		rows, err := r.pool.Query(ctx, q.query, q.args...)
+		if err != nil {
+			return nil, fmt.Errorf("error getting %s facet: %w", q.name, err)
+		}
+		
+		counts := []FacetCount{}
+		for rows.Next() {
+			var fc FacetCount
+			if err := rows.Scan(&fc.Value, &fc.Label, &fc.Count); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("error scanning %s facet: %w", q.name, err)
+			}
+			counts = append(counts, fc)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error reading %s facet: %w", q.name, err)
+		}
+		
+		*q.dest = counts
 	}
 	
-	// Delete source fault
-	return r.DeleteFault(ctx, sourceFaultID)
+	return facets, nil
 }
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+)
+
+// ListFeatureFlagOverrides returns every feature flag override
+func (r *Repository) ListFeatureFlagOverrides(ctx context.Context) ([]models.FeatureFlagOverride, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT name, project_id, enabled, updated_by, updated_at
+		FROM feature_flags
+		ORDER BY name, project_id NULLS FIRST
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := []models.FeatureFlagOverride{}
+	for rows.Next() {
+		var o models.FeatureFlagOverride
+		if err := rows.Scan(&o.Name, &o.ProjectID, &o.Enabled, &o.UpdatedBy, &o.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning feature flag: %w", err)
+		}
+		overrides = append(overrides, o)
+	}
+	return overrides, rows.Err()
+}
+
+// SetFeatureFlagOverride creates or replaces the override of a flag for a project, or globally when projectID is nil
+func (r *Repository) SetFeatureFlagOverride(ctx context.Context, o *models.FeatureFlagOverride) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO feature_flags (name, project_id, enabled, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name, (COALESCE(project_id, -1)))
+		DO UPDATE SET enabled = EXCLUDED.enabled, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING updated_at
+	`, o.Name, o.ProjectID, o.Enabled, o.UpdatedBy).Scan(&o.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error setting feature flag: %w", err)
+	}
+	return nil
+}
+
+// DeleteFeatureFlagOverride removes an override, returning ErrNotFound if there was none
+func (r *Repository) DeleteFeatureFlagOverride(ctx context.Context, name string, projectID *int64) error {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM feature_flags
+		WHERE name = $1 AND COALESCE(project_id, -1) = COALESCE($2::BIGINT, -1)
+	`, name, projectID)
+	if err != nil {
+		return fmt.Errorf("error deleting feature flag: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
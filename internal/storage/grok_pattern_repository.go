@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+)
+
+// ListGrokPatterns returns every grok pattern defined at runtime
+func (r *Repository) ListGrokPatterns(ctx context.Context) ([]models.GrokPattern, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT name, pattern, updated_by, updated_at
+		FROM grok_patterns
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing grok patterns: %w", err)
+	}
+	defer rows.Close()
+
+	patterns := []models.GrokPattern{}
+	for rows.Next() {
+		var p models.GrokPattern
+		if err := rows.Scan(&p.Name, &p.Pattern, &p.UpdatedBy, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning grok pattern: %w", err)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, rows.Err()
+}
+
+// SetGrokPattern creates or replaces a grok pattern
+func (r *Repository) SetGrokPattern(ctx context.Context, p *models.GrokPattern) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO grok_patterns (name, pattern, updated_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name)
+		DO UPDATE SET pattern = EXCLUDED.pattern, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING updated_at
+	`, p.Name, p.Pattern, p.UpdatedBy).Scan(&p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error setting grok pattern: %w", err)
+	}
+	return nil
+}
+
+// DeleteGrokPattern removes a grok pattern, returning ErrNotFound if there was none
+func (r *Repository) DeleteGrokPattern(ctx context.Context, name string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM grok_patterns WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("error deleting grok pattern: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrJobExists is returned when a job is enqueued with the unique key of a queued or running job
+var ErrJobExists = errors.New("a job with the same key is already queued or running")
+
+// JobFilters narrows ListJobs
+type JobFilters struct {
+	Type      string
+	Status    string
+	CreatedBy *int64
+	Limit     int
+	Offset    int
+}
+
+const jobColumns = `
+	id, type, status, payload, result, error, progress_done, progress_total, progress_message,
+	attempts, max_attempts, unique_key, cancel_requested, run_at, created_by,
+	created_at, started_at, finished_at, updated_at
+`
+
+func scanJob(row pgx.Row) (*models.Job, error) {
+	var j models.Job
+	var payload, result []byte
+	err := row.Scan(&j.ID, &j.Type, &j.Status, &payload, &result, &j.Error, &j.ProgressDone, &j.ProgressTotal,
+		&j.ProgressMessage, &j.Attempts, &j.MaxAttempts, &j.UniqueKey, &j.CancelRequested, &j.RunAt, &j.CreatedBy,
+		&j.CreatedAt, &j.StartedAt, &j.FinishedAt, &j.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	j.Payload = json.RawMessage(payload)
+	if len(result) > 0 {
+		j.Result = json.RawMessage(result)
+	}
+	return &j, nil
+}
+
+// CreateJob queues a job. Type, Payload, MaxAttempts, UniqueKey, RunAt and CreatedBy are read
+// from job, and the stored job is returned.
+func (r *Repository) CreateJob(ctx context.Context, job *models.Job) (*models.Job, error) {
+	payload := job.Payload
+	if len(payload) == 0 {
+		payload = json.RawMessage(`{}`)
+	}
+	runAt := job.RunAt
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+	created, err := scanJob(r.pool.QueryRow(ctx, `
+		INSERT INTO jobs (type, payload, max_attempts, unique_key, run_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+jobColumns,
+		job.Type, []byte(payload), job.MaxAttempts, job.UniqueKey, runAt, job.CreatedBy))
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return nil, ErrJobExists
+		}
+		return nil, fmt.Errorf("error creating job: %w", err)
+	}
+	return created, nil
+}
+
+// GetJob returns a job by ID
+func (r *Repository) GetJob(ctx context.Context, id int64) (*models.Job, error) {
+	job, err := scanJob(r.pool.QueryRow(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("error getting job: %w", err)
+	}
+	return job, nil
+}
+
+// ListJobs returns jobs newest first, with the total matching the filters
+func (r *Repository) ListJobs(ctx context.Context, filters JobFilters) ([]models.Job, int64, error) {
+	where := `
+		WHERE ($1 = '' OR type = $1)
+		  AND ($2 = '' OR status = $2)
+		  AND ($3::BIGINT IS NULL OR created_by = $3)
+	`
+	args := []interface{}{filters.Type, filters.Status, filters.CreatedBy}
+
+	var total int64
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM jobs`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting jobs: %w", err)
+	}
+
+	limit := filters.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := r.pool.Query(ctx, `SELECT `+jobColumns+` FROM jobs`+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4 OFFSET $5
+	`, append(args, limit, filters.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []models.Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error scanning job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, total, rows.Err()
+}
+
+// ClaimJob marks the next due job of one of types as running by worker, leased until lease has
+// passed. Running jobs whose lease expired are claimed again while they have attempts left, and
+// marked failed once they have none. Nil means no job is due.
+func (r *Repository) ClaimJob(ctx context.Context, types []string, worker string, lease time.Duration) (*models.Job, error) {
+	// A worker lost on the last attempt leaves a job nobody will claim again
+	if _, err := r.pool.Exec(ctx, `
+		UPDATE jobs
+		SET status = 'failed', error = 'worker lost and no attempts left',
+		    locked_by = NULL, locked_until = NULL, finished_at = NOW(), updated_at = NOW()
+		WHERE type = ANY($1) AND status = 'running' AND locked_until < NOW()
+		  AND attempts >= max_attempts
+	`, types); err != nil {
+		return nil, fmt.Errorf("error failing exhausted jobs: %w", err)
+	}
+
+	job, err := scanJob(r.pool.QueryRow(ctx, `
+		UPDATE jobs
+		SET status = 'running', attempts = attempts + 1, locked_by = $2,
+		    locked_until = NOW() + $3::INTERVAL,
+		    started_at = COALESCE(started_at, NOW()), updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE type = ANY($1)
+			  AND ((status = 'queued' AND run_at <= NOW())
+			       OR (status = 'running' AND locked_until < NOW() AND attempts < max_attempts))
+			ORDER BY run_at, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+jobColumns,
+		types, worker, lease))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error claiming job: %w", err)
+	}
+	return job, nil
+}
+
+// HeartbeatJob records a running job's progress and extends its lease. It returns whether
+// cancellation was requested, and ErrNotFound when worker no longer holds the job.
+func (r *Repository) HeartbeatJob(ctx context.Context, id int64, worker string, lease time.Duration, done int64, total *int64, message string) (bool, error) {
+	var cancel bool
+	err := r.pool.QueryRow(ctx, `
+		UPDATE jobs
+		SET progress_done = $3, progress_total = $4, progress_message = $5,
+		    locked_until = NOW() + $6::INTERVAL, updated_at = NOW()
+		WHERE id = $1 AND locked_by = $2 AND status = 'running'
+		RETURNING cancel_requested
+	`, id, worker, done, total, message, lease).Scan(&cancel)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, ErrNotFound
+		}
+		return false, fmt.Errorf("error updating job progress: %w", err)
+	}
+	return cancel, nil
+}
+
+// FinishJob records the outcome of a running job held by worker: succeeded with result, failed
+// or cancelled with errMsg, or queued again at retryAt
+func (r *Repository) FinishJob(ctx context.Context, id int64, worker, status string, result []byte, errMsg *string, retryAt *time.Time) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE jobs
+		SET status = $3, result = COALESCE($4, result), error = $5,
+		    run_at = COALESCE($6, run_at), locked_by = NULL, locked_until = NULL,
+		    finished_at = CASE WHEN $3 = 'queued' THEN NULL ELSE NOW() END,
+		    updated_at = NOW()
+		WHERE id = $1 AND locked_by = $2 AND status = 'running'
+	`, id, worker, status, result, errMsg, retryAt)
+	if err != nil {
+		return fmt.Errorf("error finishing job: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CancelJob cancels a queued job at once, or asks the worker running it to stop. Finished jobs
+// are returned unchanged.
+func (r *Repository) CancelJob(ctx context.Context, id int64) (*models.Job, error) {
+	job, err := scanJob(r.pool.QueryRow(ctx, `
+		UPDATE jobs
+		SET status = CASE WHEN status = 'queued' THEN 'cancelled' ELSE status END,
+		    finished_at = CASE WHEN status = 'queued' THEN NOW() ELSE finished_at END,
+		    cancel_requested = status IN ('queued', 'running') OR cancel_requested,
+		    updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+jobColumns, id))
+	if err != nil {
+		return nil, fmt.Errorf("error cancelling job: %w", err)
+	}
+	return job, nil
+}
+
+// DeleteFinishedJobs removes jobs that finished before cutoff, returning how many were deleted
+func (r *Repository) DeleteFinishedJobs(ctx context.Context, cutoff time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM jobs WHERE status IN ('succeeded', 'failed', 'cancelled') AND finished_at < $1
+	`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting finished jobs: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
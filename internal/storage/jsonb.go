@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// maxPooledJSONBBytes keeps buffers grown by unusually large rows out of the pool
+const maxPooledJSONBBytes = 256 << 10
+
+// jsonbEncoder encodes several JSONB column values into one reusable buffer
+type jsonbEncoder struct {
+	buf     bytes.Buffer
+	enc     *json.Encoder
+	columns [][]byte
+}
+
+var jsonbEncoderPool = sync.Pool{
+	New: func() interface{} {
+		e := &jsonbEncoder{}
+		e.enc = json.NewEncoder(&e.buf)
+		return e
+	},
+}
+
+// marshalJSONB encodes values as JSON into a single pooled buffer and returns one slice per value,
+// replacing a json.Marshal allocation per column. The slices are only valid until release is
+// called, which must happen after the query using them has returned.
+func marshalJSONB(values ...interface{}) (columns [][]byte, release func(), err error) {
+	e := jsonbEncoderPool.Get().(*jsonbEncoder)
+	e.buf.Reset()
+	e.columns = e.columns[:0]
+
+	release = func() {
+		if e.buf.Cap() <= maxPooledJSONBBytes {
+			jsonbEncoderPool.Put(e)
+		}
+	}
+
+	ends := make([]int, 0, len(values))
+	for _, v := range values {
+		if err := e.enc.Encode(v); err != nil {
+			release()
+			return nil, nil, err
+		}
+		// Encode terminates each value with a newline, which is excluded from the column
+		ends = append(ends, e.buf.Len()-1)
+	}
+
+	// Slice only after encoding, since the buffer may move while it grows
+	data := e.buf.Bytes()
+	start := 0
+	for _, end := range ends {
+		e.columns = append(e.columns, data[start:end])
+		start = end + 1
+	}
+	return e.columns, release, nil
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"testing"
+	"time"
+)
+
+// benchmarkNotice returns a notice with JSONB columns of the size a typical client reports
+func benchmarkNotice() *models.Notice {
+	notice := &models.Notice{
+		Message:     "undefined method `name' for nil:NilClass",
+		Context:     map[string]interface{}{"user_id": 42, "user_email": "user@example.com"},
+		Params:      map[string]interface{}{"controller": "users", "action": "show", "id": "42"},
+		Session:     map[string]interface{}{"session_id": "3f9a2c"},
+		Cookies:     map[string]interface{}{"locale": "en"},
+		Environment: map[string]interface{}{"HTTP_HOST": "example.com", "REQUEST_METHOD": "GET", "PATH_INFO": "/users/42"},
+	}
+	for i := 0; i < 20; i++ {
+		line := 10 + i
+		notice.Backtrace = append(notice.Backtrace, models.BacktraceFrame{
+			File:     fmt.Sprintf("app/models/model_%d.rb", i),
+			Line:     &line,
+			Function: fmt.Sprintf("method_%d", i),
+		})
+	}
+	for i := 0; i < 10; i++ {
+		notice.Breadcrumbs = append(notice.Breadcrumbs, models.Breadcrumb{
+			Category: "query",
+			Message:  fmt.Sprintf("SELECT * FROM users WHERE id = %d", i),
+			Time:     time.Unix(1700000000+int64(i), 0).UTC(),
+		})
+	}
+	return notice
+}
+
+func noticeJSONBValues(notice *models.Notice) []interface{} {
+	return []interface{}{notice.Backtrace, notice.Context, notice.Params, notice.Session,
+		notice.Cookies, notice.Environment, notice.Breadcrumbs}
+}
+
+func TestMarshalJSONBMatchesMarshal(t *testing.T) {
+	values := noticeJSONBValues(benchmarkNotice())
+	columns, release, err := marshalJSONB(values...)
+	if err != nil {
+		t.Fatalf("marshalJSONB: %v", err)
+	}
+	defer release()
+
+	if len(columns) != len(values) {
+		t.Fatalf("got %d columns, want %d", len(columns), len(values))
+	}
+	for i, v := range values {
+		want, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("json.Marshal: %v", err)
+		}
+		if !bytes.Equal(columns[i], want) {
+			t.Errorf("column %d = %s, want %s", i, columns[i], want)
+		}
+	}
+}
+
+// BenchmarkNoticeJSONBPooled measures the encoding CreateNotice does, into one pooled buffer
+func BenchmarkNoticeJSONBPooled(b *testing.B) {
+	values := noticeJSONBValues(benchmarkNotice())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, release, err := marshalJSONB(values...)
+		if err != nil {
+			b.Fatal(err)
+		}
+		release()
+	}
+}
+
+// BenchmarkNoticeJSONBMarshal measures the json.Marshal per column the pool replaced
+func BenchmarkNoticeJSONBMarshal(b *testing.B) {
+	values := noticeJSONBValues(benchmarkNotice())
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, v := range values {
+			if _, err := json.Marshal(v); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+// BenchmarkNoticeJSONBPooledParallel measures the pool under concurrent inserts
+func BenchmarkNoticeJSONBPooledParallel(b *testing.B) {
+	values := noticeJSONBValues(benchmarkNotice())
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, release, err := marshalJSONB(values...)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			release()
+		}
+	})
+}
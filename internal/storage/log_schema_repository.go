@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrLogSchemaExists is returned when the service or API key already has a schema
+var ErrLogSchemaExists = errors.New("a schema is already registered for this service or API key")
+
+// CreateLogSchema registers a schema for a service or an API key
+func (r *Repository) CreateLogSchema(ctx context.Context, schema *models.LogSchema) error {
+	query := `
+		INSERT INTO log_schemas (service, api_key_id, schema, mode, description, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		schema.Service,
+		schema.APIKeyID,
+		schema.Schema,
+		schema.Mode,
+		schema.Description,
+		schema.CreatedBy,
+	).Scan(&schema.ID, &schema.CreatedAt, &schema.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) {
+			switch pgErr.Code {
+			case "23505":
+				return ErrLogSchemaExists
+			case "23503":
+				return fmt.Errorf("API key %d: %w", *schema.APIKeyID, ErrNotFound)
+			}
+		}
+		return fmt.Errorf("error creating log schema: %w", err)
+	}
+
+	return nil
+}
+
+// ListLogSchemas returns every log schema, with the values of their API keys
+func (r *Repository) ListLogSchemas(ctx context.Context) ([]models.LogSchema, error) {
+	query := `
+		SELECT s.id, s.service, s.api_key_id, COALESCE(k.key, ''), s.schema, s.mode, s.description,
+		       s.created_by, s.created_at, s.updated_at
+		FROM log_schemas s
+		LEFT JOIN api_keys k ON k.id = s.api_key_id
+		ORDER BY s.id
+	`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error listing log schemas: %w", err)
+	}
+	defer rows.Close()
+
+	schemas := []models.LogSchema{}
+	for rows.Next() {
+		schema, err := scanLogSchema(rows)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, *schema)
+	}
+
+	return schemas, rows.Err()
+}
+
+// GetLogSchema returns a log schema by ID
+func (r *Repository) GetLogSchema(ctx context.Context, id int64) (*models.LogSchema, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT s.id, s.service, s.api_key_id, COALESCE(k.key, ''), s.schema, s.mode, s.description,
+		       s.created_by, s.created_at, s.updated_at
+		FROM log_schemas s
+		LEFT JOIN api_keys k ON k.id = s.api_key_id
+		WHERE s.id = $1
+	`, id)
+	schema, err := scanLogSchema(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("log schema %d: %w", id, ErrNotFound)
+	}
+	return schema, err
+}
+
+// UpdateLogSchema saves a log schema's schema, mode and description
+func (r *Repository) UpdateLogSchema(ctx context.Context, schema *models.LogSchema) error {
+	err := r.pool.QueryRow(ctx, `
+		UPDATE log_schemas
+		SET schema = $2, mode = $3, description = $4, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`, schema.ID, schema.Schema, schema.Mode, schema.Description).Scan(&schema.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("log schema %d: %w", schema.ID, ErrNotFound)
+		}
+		return fmt.Errorf("error updating log schema: %w", err)
+	}
+	return nil
+}
+
+// DeleteLogSchema deletes a log schema
+func (r *Repository) DeleteLogSchema(ctx context.Context, id int64) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM log_schemas WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting log schema: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("log schema %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// scanLogSchema scans a log schema row with its API key value
+func scanLogSchema(row pgx.Row) (*models.LogSchema, error) {
+	var schema models.LogSchema
+	err := row.Scan(
+		&schema.ID,
+		&schema.Service,
+		&schema.APIKeyID,
+		&schema.APIKey,
+		&schema.Schema,
+		&schema.Mode,
+		&schema.Description,
+		&schema.CreatedBy,
+		&schema.CreatedAt,
+		&schema.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error scanning log schema: %w", err)
+	}
+	return &schema, nil
+}
@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+)
+
+const mergeRuleColumns = `id, project_id, name, error_class, message_pattern, environment,
+		       target_fault_id, enabled, match_count, last_matched_at, created_by, created_at`
+
+// CreateMergeRule creates a new automatic merge rule
+func (r *Repository) CreateMergeRule(ctx context.Context, rule *models.MergeRule) error {
+	query := `
+		INSERT INTO merge_rules (project_id, name, error_class, message_pattern, environment,
+		                         target_fault_id, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, match_count, created_at
+	`
+	
+	err := r.pool.QueryRow(ctx, query,
+		rule.ProjectID,
+		rule.Name,
+		rule.ErrorClass,
+		rule.MessagePattern,
+		rule.Environment,
+		rule.TargetFaultID,
+		rule.Enabled,
+		rule.CreatedBy,
+	).Scan(&rule.ID, &rule.MatchCount, &rule.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating merge rule: %w", err)
+	}
+	
+	return nil
+}
+
+// ListMergeRules returns merge rules, optionally limited to one project (plus global rules)
+func (r *Repository) ListMergeRules(ctx context.Context, projectID *int64, enabledOnly bool) ([]models.MergeRule, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM merge_rules
+		WHERE ($1::BIGINT IS NULL OR project_id IS NULL OR project_id = $1)
+		  AND (NOT $2 OR enabled)
+		ORDER BY id
+	`, mergeRuleColumns)
+	
+	rows, err := r.pool.Query(ctx, query, projectID, enabledOnly)
+	if err != nil {
+		return nil, fmt.Errorf("error listing merge rules: %w", err)
+	}
+	defer rows.Close()
+	
+	rules := []models.MergeRule{}
+	for rows.Next() {
+		var rule models.MergeRule
+		err := rows.Scan(
+			&rule.ID,
+			&rule.ProjectID,
+			&rule.Name,
+			&rule.ErrorClass,
+			&rule.MessagePattern,
+			&rule.Environment,
+			&rule.TargetFaultID,
+			&rule.Enabled,
+			&rule.MatchCount,
+			&rule.LastMatchedAt,
+			&rule.CreatedBy,
+			&rule.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning merge rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+	
+	return rules, nil
+}
+
+// DeleteMergeRule deletes a merge rule
+func (r *Repository) DeleteMergeRule(ctx context.Context, id int64) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM merge_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting merge rule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("merge rule %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// RecordMergeRuleMatch increments a rule's match counter
+func (r *Repository) RecordMergeRuleMatch(ctx context.Context, id int64) error {
+	query := `
+		UPDATE merge_rules
+		SET match_count = match_count + 1, last_matched_at = NOW()
+		WHERE id = $1
+	`
+	_, err := r.pool.Exec(ctx, query, id)
+	return err
+}
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+)
+
+// ListParserPipelines returns every parser pipeline
+func (r *Repository) ListParserPipelines(ctx context.Context) ([]models.ParserPipeline, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT name, sources, steps, updated_by, updated_at
+		FROM parser_pipelines
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing parser pipelines: %w", err)
+	}
+	defer rows.Close()
+
+	pipelines := []models.ParserPipeline{}
+	for rows.Next() {
+		var p models.ParserPipeline
+		var steps []byte
+		if err := rows.Scan(&p.Name, &p.Sources, &steps, &p.UpdatedBy, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning parser pipeline: %w", err)
+		}
+		if err := json.Unmarshal(steps, &p.Steps); err != nil {
+			return nil, fmt.Errorf("error decoding steps of parser pipeline %s: %w", p.Name, err)
+		}
+		pipelines = append(pipelines, p)
+	}
+	return pipelines, rows.Err()
+}
+
+// SetParserPipeline creates or replaces a parser pipeline
+func (r *Repository) SetParserPipeline(ctx context.Context, p *models.ParserPipeline) error {
+	steps, err := json.Marshal(p.Steps)
+	if err != nil {
+		return fmt.Errorf("error encoding parser pipeline steps: %w", err)
+	}
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO parser_pipelines (name, sources, steps, updated_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name)
+		DO UPDATE SET sources = EXCLUDED.sources, steps = EXCLUDED.steps, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		RETURNING updated_at
+	`, p.Name, p.Sources, steps, p.UpdatedBy).Scan(&p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error setting parser pipeline: %w", err)
+	}
+	return nil
+}
+
+// DeleteParserPipeline removes a parser pipeline, returning ErrNotFound if there was none
+func (r *Repository) DeleteParserPipeline(ctx context.Context, name string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM parser_pipelines WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("error deleting parser pipeline: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// CountAPIKeysWithParser counts the active API keys set to use the named parser
+func (r *Repository) CountAPIKeysWithParser(ctx context.Context, name string) (int64, error) {
+	var count int64
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM api_keys WHERE parser = $1 AND is_active = TRUE`, name).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting API keys with parser: %w", err)
+	}
+	return count, nil
+}
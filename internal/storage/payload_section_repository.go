@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// noticeEnvironment selects a notice's environment data, from the payload_sections table when
+// the notice was stored with a shared payload, or the notice itself. Queries selecting it join
+// noticeEnvironmentJoin on the notice alias n.
+const noticeEnvironment = "COALESCE(e.data, n.environment)"
+
+const noticeEnvironmentJoin = "LEFT JOIN payload_sections e ON e.hash = n.environment_hash"
+
+// newPayloadSectionSet returns the set of distinct payload sections of notices being stored
+func newPayloadSectionSet() *contentSet {
+	return &contentSet{table: "payload_sections", column: "data"}
+}
+
+// DeleteUnusedPayloadSections deletes up to limit payload sections that no notice references any
+// more. Sections used within grace are kept, since notices referencing them may still be in flight.
+func (r *Repository) DeleteUnusedPayloadSections(ctx context.Context, grace time.Duration, limit int) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM payload_sections
+		WHERE hash IN (
+			SELECT s.hash
+			FROM payload_sections s
+			WHERE s.last_used_at < $1
+			  AND NOT EXISTS (SELECT 1 FROM notices n WHERE n.environment_hash = s.hash)
+			LIMIT $2
+		)
+	`, time.Now().Add(-grace), limit)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting unused payload sections: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// sharedSection returns the values stored in a notice's inline column and hash column for a
+// payload section: the section inline when it is not shared, or its hash once added to sections
+func sharedSection(sections *contentSet, share bool, content []byte, empty bool) ([]byte, []byte) {
+	if !share || empty {
+		return content, nil
+	}
+	return nil, sections.add(content, false)
+}
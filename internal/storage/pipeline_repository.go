@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+)
+
+// ListPipelinePauses returns every paused pipeline
+func (r *Repository) ListPipelinePauses(ctx context.Context) ([]models.PipelinePause, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT pipeline, reason, paused_by, paused_at
+		FROM pipeline_pauses
+		ORDER BY pipeline
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing pipeline pauses: %w", err)
+	}
+	defer rows.Close()
+
+	pauses := []models.PipelinePause{}
+	for rows.Next() {
+		var p models.PipelinePause
+		if err := rows.Scan(&p.Pipeline, &p.Reason, &p.PausedBy, &p.PausedAt); err != nil {
+			return nil, fmt.Errorf("error scanning pipeline pause: %w", err)
+		}
+		pauses = append(pauses, p)
+	}
+	return pauses, rows.Err()
+}
+
+// PausePipeline records a pipeline as paused. Pausing a paused pipeline replaces its reason and keeps the original time.
+func (r *Repository) PausePipeline(ctx context.Context, p *models.PipelinePause) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO pipeline_pauses (pipeline, reason, paused_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (pipeline) DO UPDATE SET reason = EXCLUDED.reason, paused_by = EXCLUDED.paused_by
+		RETURNING paused_at
+	`, p.Pipeline, p.Reason, p.PausedBy).Scan(&p.PausedAt)
+	if err != nil {
+		return fmt.Errorf("error pausing pipeline: %w", err)
+	}
+	return nil
+}
+
+// ResumePipeline removes a pipeline's pause, returning false if it was not paused
+func (r *Repository) ResumePipeline(ctx context.Context, pipeline string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM pipeline_pauses WHERE pipeline = $1`, pipeline)
+	if err != nil {
+		return false, fmt.Errorf("error resuming pipeline: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
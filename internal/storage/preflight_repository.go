@@ -0,0 +1,134 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"time"
+)
+
+// InstalledExtensions returns the names of the PostgreSQL extensions installed in the database
+func (r *Repository) InstalledExtensions(ctx context.Context) ([]string, error) {
+	rows, err := r.pool.Query(ctx, `SELECT extname FROM pg_extension ORDER BY extname`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing extensions: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("error scanning extension: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// SchemaVersion returns the latest migration recorded in schema_migrations, or 0 when the
+// table does not exist yet
+func (r *Repository) SchemaVersion(ctx context.Context) (int, error) {
+	var exists bool
+	if err := r.pool.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("error checking schema version: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+	var version int
+	if err := r.pool.QueryRow(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, fmt.Errorf("error checking schema version: %w", err)
+	}
+	return version, nil
+}
+
+// DatabaseTime returns the current time on the database server's clock
+func (r *Repository) DatabaseTime(ctx context.Context) (time.Time, error) {
+	var now time.Time
+	if err := r.pool.QueryRow(ctx, `SELECT clock_timestamp()`).Scan(&now); err != nil {
+		return time.Time{}, fmt.Errorf("error reading database time: %w", err)
+	}
+	return now, nil
+}
+
+// ConnectionSSL reports whether the connection serving the query uses SSL, and its protocol
+// version
+func (r *Repository) ConnectionSSL(ctx context.Context) (bool, string, error) {
+	var ssl bool
+	var version string
+	err := r.pool.QueryRow(ctx, `
+		SELECT ssl, COALESCE(version, '') FROM pg_stat_ssl WHERE pid = pg_backend_pid()
+	`).Scan(&ssl, &version)
+	if err != nil {
+		return false, "", fmt.Errorf("error checking connection SSL: %w", err)
+	}
+	return ssl, version, nil
+}
+
+// RelationTablespaces counts the tables, indexes, materialized views and TOAST tables created
+// in the database, including those of extensions and TimescaleDB chunks, by the tablespace
+// holding them. Relations in the database's default tablespace are counted under its name.
+func (r *Repository) RelationTablespaces(ctx context.Context) (map[string]int64, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT COALESCE(t.spcname, d.spcname), COUNT(*)
+		FROM pg_class c
+		LEFT JOIN pg_tablespace t ON t.oid = c.reltablespace
+		CROSS JOIN (
+			SELECT ts.spcname
+			FROM pg_database db
+			JOIN pg_tablespace ts ON ts.oid = db.dattablespace
+			WHERE db.datname = current_database()
+		) d
+		WHERE c.relkind IN ('r', 'i', 'm', 't')
+		  -- Objects below FirstNormalObjectId belong to the system catalogs
+		  AND c.oid >= 16384
+		GROUP BY 1
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing relation tablespaces: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var tablespace string
+		var count int64
+		if err := rows.Scan(&tablespace, &count); err != nil {
+			return nil, fmt.Errorf("error scanning relation tablespace: %w", err)
+		}
+		counts[tablespace] = count
+	}
+	return counts, rows.Err()
+}
+
+// RoundTripLog writes a log and reads it back, in a transaction that is rolled back so nothing
+// is kept, returning what was read
+func (r *Repository) RoundTripLog(ctx context.Context, logEntry models.LogEntry) (*models.LogEntry, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id int64
+	err = tx.QueryRow(ctx, `
+		INSERT INTO logs (timestamp, service, level, message, metadata)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, logEntry.Timestamp, logEntry.Service, logEntry.Level, logEntry.Message, logEntry.Metadata).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("error writing log: %w", err)
+	}
+
+	read := models.LogEntry{ID: id}
+	err = tx.QueryRow(ctx, `
+		SELECT timestamp, service, level, message, metadata
+		FROM logs
+		WHERE id = $1 AND timestamp = $2
+	`, id, logEntry.Timestamp).Scan(&read.Timestamp, &read.Service, &read.Level, &read.Message, &read.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("error reading log back: %w", err)
+	}
+	return &read, nil
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreatePublicToken stores a new public token by the hash of its value
+func (r *Repository) CreatePublicToken(ctx context.Context, t *models.PublicToken, tokenHash string) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO public_tokens (name, token_hash, project_id, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, t.Name, tokenHash, t.ProjectID, t.CreatedBy).Scan(&t.ID, &t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating public token: %w", err)
+	}
+	return nil
+}
+
+// ListPublicTokens returns public tokens, newest first. If userID is nil every token is
+// returned (admin); otherwise only the tokens that user created.
+func (r *Repository) ListPublicTokens(ctx context.Context, userID *int64) ([]models.PublicToken, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, name, project_id, created_by, created_at, revoked_at
+		FROM public_tokens
+		WHERE $1::BIGINT IS NULL OR created_by = $1
+		ORDER BY created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing public tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []models.PublicToken{}
+	for rows.Next() {
+		var t models.PublicToken
+		if err := rows.Scan(&t.ID, &t.Name, &t.ProjectID, &t.CreatedBy, &t.CreatedAt, &t.RevokedAt); err != nil {
+			return nil, fmt.Errorf("error scanning public token: %w", err)
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// GetPublicTokenByHash returns the unrevoked public token with this hash, or ErrNotFound
+func (r *Repository) GetPublicTokenByHash(ctx context.Context, tokenHash string) (*models.PublicToken, error) {
+	var t models.PublicToken
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, name, project_id, created_by, created_at, revoked_at
+		FROM public_tokens
+		WHERE token_hash = $1 AND revoked_at IS NULL
+	`, tokenHash).Scan(&t.ID, &t.Name, &t.ProjectID, &t.CreatedBy, &t.CreatedAt, &t.RevokedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting public token: %w", err)
+	}
+	return &t, nil
+}
+
+// RevokePublicToken revokes a public token. If userID is provided, only a token that user
+// created is revoked. It returns ErrNotFound if no unrevoked token matched.
+func (r *Repository) RevokePublicToken(ctx context.Context, id int64, userID *int64) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE public_tokens
+		SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL AND ($2::BIGINT IS NULL OR created_by = $2)
+	`, id, userID)
+	if err != nil {
+		return fmt.Errorf("error revoking public token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
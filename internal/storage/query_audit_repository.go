@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+)
+
+// QueryAuditFilters narrows a query audit log listing
+type QueryAuditFilters struct {
+	UserID  *int64
+	Outcome string
+}
+
+// RecordQueryAudit adds an entry to the query audit log
+func (r *Repository) RecordQueryAudit(ctx context.Context, entry *models.QueryAuditEntry) error {
+	query := `
+		INSERT INTO query_audit_log (user_id, api_key_id, route, query, estimated_rows, estimated_cost, outcome)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		entry.UserID,
+		entry.APIKeyID,
+		entry.Route,
+		entry.Query,
+		entry.EstimatedRows,
+		entry.EstimatedCost,
+		entry.Outcome,
+	).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error recording query audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// ListQueryAudit returns query audit log entries, newest first
+func (r *Repository) ListQueryAudit(ctx context.Context, filters QueryAuditFilters, limit, offset int) ([]models.QueryAuditEntry, error) {
+	query := `
+		SELECT id, user_id, api_key_id, route, query, estimated_rows, estimated_cost, outcome, created_at
+		FROM query_audit_log
+		WHERE ($1::BIGINT IS NULL OR user_id = $1)
+		  AND ($2 = '' OR outcome = $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.pool.Query(ctx, query, filters.UserID, filters.Outcome, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("error listing query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.QueryAuditEntry{}
+	for rows.Next() {
+		var entry models.QueryAuditEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.APIKeyID,
+			&entry.Route,
+			&entry.Query,
+			&entry.EstimatedRows,
+			&entry.EstimatedCost,
+			&entry.Outcome,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning query audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
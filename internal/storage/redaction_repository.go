@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+)
+
+// CreateRedactionRule creates a new redaction rule
+func (r *Repository) CreateRedactionRule(ctx context.Context, rule *models.RedactionRule) error {
+	query := `
+		INSERT INTO redaction_rules (project_id, kind, pattern, description, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		rule.ProjectID,
+		rule.Kind,
+		rule.Pattern,
+		rule.Description,
+		rule.CreatedBy,
+	).Scan(&rule.ID, &rule.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating redaction rule: %w", err)
+	}
+
+	return nil
+}
+
+// ListRedactionRules returns redaction rules, optionally limited to one project (plus global rules)
+func (r *Repository) ListRedactionRules(ctx context.Context, projectID *int64) ([]models.RedactionRule, error) {
+	query := `
+		SELECT id, project_id, kind, pattern, description, created_by, created_at
+		FROM redaction_rules
+		WHERE ($1::BIGINT IS NULL OR project_id IS NULL OR project_id = $1)
+		ORDER BY id
+	`
+
+	rows, err := r.pool.Query(ctx, query, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing redaction rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.RedactionRule{}
+	for rows.Next() {
+		var rule models.RedactionRule
+		err := rows.Scan(
+			&rule.ID,
+			&rule.ProjectID,
+			&rule.Kind,
+			&rule.Pattern,
+			&rule.Description,
+			&rule.CreatedBy,
+			&rule.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning redaction rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// DeleteRedactionRule deletes a redaction rule
+func (r *Repository) DeleteRedactionRule(ctx context.Context, id int64) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM redaction_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting redaction rule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("redaction rule %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
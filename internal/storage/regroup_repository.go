@@ -0,0 +1,156 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"time"
+)
+
+// RegroupScope selects the notices to regroup. Exactly one field is set.
+type RegroupScope struct {
+	NoticeID  string `json:"notice_id,omitempty"`
+	FaultID   *int64 `json:"fault_id,omitempty"`
+	ProjectID *int64 `json:"project_id,omitempty"`
+}
+
+// RegroupCursor is the position after the last notice read, in created_at then ID order
+type RegroupCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// ListRegroupNotices returns up to limit notices in scope after cursor, oldest first, with only
+// the fields used for grouping. A nil cursor starts at the oldest notice.
+func (r *Repository) ListRegroupNotices(ctx context.Context, scope RegroupScope, cursor *RegroupCursor, limit int) ([]models.Notice, error) {
+	query := fmt.Sprintf(`
+		SELECT n.id, n.fault_id, n.message, %s, %s, n.created_at,
+		       COALESCE(n.error_class, ''), COALESCE(n.component, ''), COALESCE(n.action, '')
+		FROM notices n
+		%s
+		%s
+		WHERE ($1 = '' OR n.id = $1)
+		  AND ($2::BIGINT IS NULL OR n.fault_id = $2)
+		  AND ($3::BIGINT IS NULL OR n.fault_id IN (SELECT id FROM faults WHERE project_id = $3))
+		  AND ($4::TIMESTAMPTZ IS NULL OR (n.created_at, n.id) > ($4, $5))
+		ORDER BY n.created_at, n.id
+		LIMIT $6
+	`, noticeBacktrace, noticeEnvironment, noticeBacktraceJoin, noticeEnvironmentJoin)
+	var after *time.Time
+	var afterID string
+	if cursor != nil {
+		after = &cursor.CreatedAt
+		afterID = cursor.ID
+	}
+
+	rows, err := r.pool.Query(ctx, query, scope.NoticeID, scope.FaultID, scope.ProjectID, after, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error listing notices to regroup: %w", err)
+	}
+	defer rows.Close()
+
+	var notices []models.Notice
+	for rows.Next() {
+		var n models.Notice
+		var backtraceJSON, environmentJSON []byte
+		if err := rows.Scan(&n.ID, &n.FaultID, &n.Message, &backtraceJSON, &environmentJSON, &n.CreatedAt,
+			&n.ErrorClass, &n.Component, &n.Action); err != nil {
+			return nil, fmt.Errorf("error scanning notice: %w", err)
+		}
+		if len(backtraceJSON) > 0 {
+			json.Unmarshal(backtraceJSON, &n.Backtrace)
+		}
+		if len(environmentJSON) > 0 {
+			json.Unmarshal(environmentJSON, &n.Environment)
+		}
+		notices = append(notices, n)
+	}
+	return notices, rows.Err()
+}
+
+// CountRegroupNotices returns the number of notices in scope
+func (r *Repository) CountRegroupNotices(ctx context.Context, scope RegroupScope) (int64, error) {
+	var count int64
+	err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*)
+		FROM notices n
+		WHERE ($1 = '' OR n.id = $1)
+		  AND ($2::BIGINT IS NULL OR n.fault_id = $2)
+		  AND ($3::BIGINT IS NULL OR n.fault_id IN (SELECT id FROM faults WHERE project_id = $3))
+	`, scope.NoticeID, scope.FaultID, scope.ProjectID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting notices to regroup: %w", err)
+	}
+	return count, nil
+}
+
+// CountFaultNotices returns the number of notices stored for a fault
+func (r *Repository) CountFaultNotices(ctx context.Context, faultID int64) (int64, error) {
+	var count int64
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM notices WHERE fault_id = $1`, faultID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("error counting fault notices: %w", err)
+	}
+	return count, nil
+}
+
+// MoveNotices moves notices from one fault to another, moving their occurrences with them, and
+// records the move in both faults' history. Notices no longer on the source fault are skipped.
+// It returns the number of notices moved.
+func (r *Repository) MoveNotices(ctx context.Context, noticeIDs []string, sourceFaultID, targetFaultID int64, actorID *int64) (int64, error) {
+	if sourceFaultID == targetFaultID {
+		return 0, ErrSelfMerge
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var moved int64
+	var first, last *time.Time
+	err = tx.QueryRow(ctx, `
+		WITH moved AS (
+			UPDATE notices SET fault_id = $1
+			WHERE id = ANY($2) AND fault_id = $3
+			RETURNING created_at
+		)
+		SELECT COUNT(*), MIN(created_at), MAX(created_at) FROM moved
+	`, targetFaultID, noticeIDs, sourceFaultID).Scan(&moved, &first, &last)
+	if err != nil {
+		return 0, fmt.Errorf("error moving notices: %w", err)
+	}
+	if moved == 0 {
+		return 0, nil
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE faults SET occurrence_count = GREATEST(occurrence_count - $1, 0) WHERE id = $2
+	`, moved, sourceFaultID); err != nil {
+		return 0, fmt.Errorf("error updating source fault: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `
+		UPDATE faults
+		SET occurrence_count = occurrence_count + $1,
+		    first_seen_at = LEAST(first_seen_at, $2),
+		    last_seen_at = GREATEST(last_seen_at, $3)
+		WHERE id = $4
+	`, moved, first, last, targetFaultID); err != nil {
+		return 0, fmt.Errorf("error updating target fault: %w", err)
+	}
+
+	change := models.FieldChange{Field: "regrouped_to_fault_id", New: targetFaultID}
+	if err := insertFaultHistory(ctx, tx, sourceFaultID, "regrouped", actorID, nil, []models.FieldChange{change}); err != nil {
+		return 0, err
+	}
+	change = models.FieldChange{Field: "regrouped_from_fault_id", New: sourceFaultID}
+	if err := insertFaultHistory(ctx, tx, targetFaultID, "regrouped", actorID, nil, []models.FieldChange{change}); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("error committing notice move: %w", err)
+	}
+	return moved, nil
+}
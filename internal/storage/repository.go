@@ -2,8 +2,10 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log-ingestion-service/pkg/models"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -15,11 +17,19 @@ type Repository struct {
 	pool *pgxpool.Pool
 }
 
+// ErrNotFound is returned when a requested record does not exist
+var ErrNotFound = errors.New("record not found")
+
 // NewRepository creates a new repository instance
 func NewRepository(pool *pgxpool.Pool) *Repository {
 	return &Repository{pool: pool}
 }
 
+// IsNotFound reports whether err means the requested record does not exist
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, pgx.ErrNoRows)
+}
+
 // InsertLog inserts a single log entry
 func (r *Repository) InsertLog(ctx context.Context, logEntry *models.LogEntry) error {
 	query := `
@@ -176,16 +186,16 @@ func (r *Repository) GetLogStats(ctx context.Context, timeRange time.Duration) (
 	return stats, nil
 }
 
-// GetRecentLogs returns recent log entries
-func (r *Repository) GetRecentLogs(ctx context.Context, limit int) ([]models.LogEntry, error) {
+// GetRecentLogs returns a page of log entries, newest first
+func (r *Repository) GetRecentLogs(ctx context.Context, limit, offset int) ([]models.LogEntry, error) {
 	query := `
 		SELECT id, timestamp, service, level, message, metadata
 		FROM logs
 		ORDER BY timestamp DESC
-		LIMIT $1
+		LIMIT $1 OFFSET $2
 	`
 	
-	rows, err := r.pool.Query(ctx, query, limit)
+	rows, err := r.pool.Query(ctx, query, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("error getting recent logs: %w", err)
 	}
@@ -239,6 +249,173 @@ func (r *Repository) GetErrorLogs(ctx context.Context, limit int, timeRange time
 	return logs, nil
 }
 
+// LogFilters selects logs for SearchLogs. Empty fields match everything.
+type LogFilters struct {
+	Service string
+	// Levels matches any of the levels, case-insensitively
+	Levels []string
+	// Search matches messages containing it, case-insensitively
+	Search string
+	From   time.Time
+	To     time.Time
+	Limit  int
+	// Offset skips the first matches, for SearchLogs
+	Offset int
+	// Fields are the JSON fields of models.LogEntry to read, in any order; empty reads them
+	// all. Columns left out are not selected, so leaving out metadata skips decoding it.
+	Fields []string
+}
+
+// logFields are the JSON fields of models.LogEntry, named after their columns, in select order
+var logFields = []string{"id", "timestamp", "service", "level", "message", "metadata"}
+
+// logSelection returns the columns to select for the requested fields, and the scan
+// destinations of a log for them
+func logSelection(fields []string) (string, func(*models.LogEntry) []interface{}) {
+	selected := logFields
+	if len(fields) > 0 {
+		selected = nil
+		for _, field := range logFields {
+			for _, f := range fields {
+				if f == field {
+					selected = append(selected, field)
+					break
+				}
+			}
+		}
+	}
+	
+	dest := func(log *models.LogEntry) []interface{} {
+		targets := make([]interface{}, len(selected))
+		for i, field := range selected {
+			switch field {
+			case "id":
+				targets[i] = &log.ID
+			case "timestamp":
+				targets[i] = &log.Timestamp
+			case "service":
+				targets[i] = &log.Service
+			case "level":
+				targets[i] = &log.Level
+			case "message":
+				targets[i] = &log.Message
+			case "metadata":
+				targets[i] = &log.Metadata
+			}
+		}
+		return targets
+	}
+	return strings.Join(selected, ", "), dest
+}
+
+// logFiltersWhere returns the WHERE clause selecting logs by filters, and its arguments $1 to $5
+func logFiltersWhere(filters LogFilters) (string, []interface{}) {
+	where := `
+		WHERE ($1 = '' OR service = $1)
+		  AND (CARDINALITY($2::TEXT[]) = 0 OR UPPER(level) = ANY($2))
+		  AND ($3 = '' OR message ILIKE '%' || $3 || '%')
+		  AND ($4::TIMESTAMPTZ IS NULL OR timestamp >= $4)
+		  AND ($5::TIMESTAMPTZ IS NULL OR timestamp < $5)
+	`
+	levels := make([]string, len(filters.Levels))
+	for i, level := range filters.Levels {
+		levels[i] = strings.ToUpper(level)
+	}
+	var from, to *time.Time
+	if !filters.From.IsZero() {
+		from = &filters.From
+	}
+	if !filters.To.IsZero() {
+		to = &filters.To
+	}
+	return where, []interface{}{filters.Service, levels, escapeLike(filters.Search), from, to}
+}
+
+// SearchLogs returns up to filters.Limit matching logs newest first, after skipping
+// filters.Offset of them, with the number of matches
+func (r *Repository) SearchLogs(ctx context.Context, filters LogFilters) ([]models.LogEntry, int64, error) {
+	where, args := logFiltersWhere(filters)
+	
+	var total int64
+	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM logs`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting logs: %w", err)
+	}
+	
+	limit := filters.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+	columns, dest := logSelection(filters.Fields)
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+columns+`
+		FROM logs`+where+`
+		ORDER BY timestamp DESC
+		LIMIT $6 OFFSET $7
+	`, append(args, limit, filters.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error searching logs: %w", err)
+	}
+	defer rows.Close()
+	
+	logs := []models.LogEntry{}
+	for rows.Next() {
+		var log models.LogEntry
+		if err := rows.Scan(dest(&log)...); err != nil {
+			return nil, 0, err
+		}
+		logs = append(logs, log)
+	}
+	
+	return logs, total, rows.Err()
+}
+
+// LogCursor is the position of a log in (timestamp, id) order
+type LogCursor struct {
+	Timestamp time.Time
+	ID        int64
+}
+
+// ExportLogs returns up to limit logs matching filters, oldest first, that come after the
+// cursor when it is not nil. Each batch of an export starts after the last log of the previous
+// one, so it is an index range scan however far into the export it is, and logs arriving
+// meanwhile do not shift it. filters.Limit is ignored; filters.Fields must include id and
+// timestamp for the cursor to be taken from the last log.
+func (r *Repository) ExportLogs(ctx context.Context, filters LogFilters, after *LogCursor, limit int) ([]models.LogEntry, error) {
+	where, args := logFiltersWhere(filters)
+	// The cursor condition is left out rather than made optional, so the planner always sees
+	// a range it can scan the (timestamp, id) index for
+	if after != nil {
+		where += ` AND (timestamp, id) > ($7, $8)`
+	}
+	args = append(args, limit)
+	if after != nil {
+		args = append(args, after.Timestamp, after.ID)
+	}
+	
+	columns, dest := logSelection(filters.Fields)
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+columns+`
+		FROM logs`+where+`
+		ORDER BY timestamp, id
+		LIMIT $6
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error exporting logs: %w", err)
+	}
+	defer rows.Close()
+	
+	logs := make([]models.LogEntry, 0, limit)
+	for rows.Next() {
+		var log models.LogEntry
+		if err := rows.Scan(dest(&log)...); err != nil {
+			return nil, err
+		}
+		logs = append(logs, log)
+	}
+	
+	return logs, rows.Err()
+}
+
 // GetLogByID returns a single log entry by ID
 func (r *Repository) GetLogByID(ctx context.Context, id int64) (*models.LogEntry, error) {
 	query := `
@@ -260,49 +437,124 @@ func (r *Repository) GetLogByID(ctx context.Context, id int64) (*models.LogEntry
 
 // APIKey represents an API key in the database
 type APIKey struct {
-	ID              int64     `json:"id"`
-	Key             string    `json:"key"` // Only returned when creating
-	Name            string    `json:"name"`
-	Description     string    `json:"description"`
-	CreatedAt       time.Time `json:"created_at"`
-	IsActive        bool      `json:"is_active"`
-	CreatedByUserID *int64    `json:"created_by_user_id"`
+	ID               int64     `json:"id"`
+	Key              string    `json:"key"` // Only returned when creating
+	Name             string    `json:"name"`
+	Description      string    `json:"description"`
+	CreatedAt        time.Time `json:"created_at"`
+	IsActive         bool      `json:"is_active"`
+	CreatedByUserID  *int64    `json:"created_by_user_id"`
+	Parser           *string   `json:"parser"`
+	// TimestampLayouts and Timezone declare the timestamps of JSON logs sent with the key
+	TimestampLayouts []string  `json:"timestamp_layouts"`
+	Timezone         *string   `json:"timezone"`
+}
+
+// APIKeyParsing is how raw logs sent with an API key are parsed
+type APIKeyParsing struct {
+	// Parser is empty when the parser is chosen automatically
+	Parser           string
+	TimestampLayouts []string
+	Timezone         string
 }
 
 // TimeSeriesPoint represents a data point for time series charts
 type TimeSeriesPoint struct {
 	Time  time.Time `json:"time"`
 	Count int64     `json:"count"`
+	// ComparedTime is the bucket's own time in a comparison series, whose Time is moved to line
+	// up with the current series
+	ComparedTime *time.Time `json:"compared_time,omitempty"`
 }
 
-// GetTimeSeriesData returns time series data for charts
-func (r *Repository) GetTimeSeriesData(ctx context.Context, timeRange time.Duration, interval string) ([]TimeSeriesPoint, error) {
-	since := time.Now().Add(-timeRange)
+// timeSeriesIntervals are the bucket widths time series accept, narrowest first
+var timeSeriesIntervals = []struct {
+	name   string
+	bucket string
+	width  time.Duration
+}{
+	{"1m", "1 minute", time.Minute},
+	{"5m", "5 minutes", 5 * time.Minute},
+	{"15m", "15 minutes", 15 * time.Minute},
+	{"30m", "30 minutes", 30 * time.Minute},
+	{"1h", "1 hour", time.Hour},
+	{"3h", "3 hours", 3 * time.Hour},
+	{"6h", "6 hours", 6 * time.Hour},
+	{"12h", "12 hours", 12 * time.Hour},
+	{"1d", "1 day", 24 * time.Hour},
+	{"1w", "7 days", 7 * 24 * time.Hour},
+}
+
+// DownsampleInterval returns the narrowest interval, no narrower than the one requested, that
+// splits timeRange into at most maxPoints buckets, or the widest interval when none does.
+// Unknown intervals are taken as 5m, like GetTimeSeriesData does.
+func DownsampleInterval(interval string, timeRange time.Duration, maxPoints int) string {
+	requested := 5 * time.Minute
+	for _, i := range timeSeriesIntervals {
+		if i.name == interval {
+			requested = i.width
+		}
+	}
+	for _, i := range timeSeriesIntervals {
+		if i.width >= requested && int64(timeRange/i.width) <= int64(maxPoints) {
+			return i.name
+		}
+	}
+	return timeSeriesIntervals[len(timeSeriesIntervals)-1].name
+}
+
+// GetTimeSeriesData returns time series data for charts. Buckets start at whole intervals in
+// location, so day buckets begin at local midnight and follow daylight saving changes.
+// Bucket times are returned in location.
+func (r *Repository) GetTimeSeriesData(ctx context.Context, timeRange time.Duration, interval string, location *time.Location) ([]TimeSeriesPoint, error) {
+	now := time.Now()
+	return r.getTimeSeries(ctx, now.Add(-timeRange), now, interval, location)
+}
+
+// GetComparisonTimeSeriesData returns the time series of the same window days earlier, e.g. 7
+// for the week before, to overlay on the current one. Days are counted in location, so the
+// window keeps its local times across daylight saving changes. Each bucket's Time is moved
+// forward by days to line up with the current series, and ComparedTime keeps its own time.
+func (r *Repository) GetComparisonTimeSeriesData(ctx context.Context, timeRange time.Duration, interval string, location *time.Location, days int) ([]TimeSeriesPoint, error) {
+	if location == nil {
+		location = time.UTC
+	}
+	until := time.Now().In(location).AddDate(0, 0, -days)
+	points, err := r.getTimeSeries(ctx, until.Add(-timeRange), until, interval, location)
+	if err != nil {
+		return nil, err
+	}
+	for i := range points {
+		compared := points[i].Time
+		points[i].ComparedTime = &compared
+		points[i].Time = compared.AddDate(0, 0, days)
+	}
+	return points, nil
+}
+
+// getTimeSeries counts logs between since and until in buckets of interval
+func (r *Repository) getTimeSeries(ctx context.Context, since, until time.Time, interval string, location *time.Location) ([]TimeSeriesPoint, error) {
+	if location == nil {
+		location = time.UTC
+	}
 	
 	// Validate interval (1m, 5m, 1h, etc.)
-	var timeBucket string
-	switch interval {
-	case "1m":
-		timeBucket = "1 minute"
-	case "5m":
-		timeBucket = "5 minutes"
-	case "15m":
-		timeBucket = "15 minutes"
-	case "1h":
-		timeBucket = "1 hour"
-	default:
-		timeBucket = "5 minutes"
+	timeBucket := "5 minutes"
+	for _, i := range timeSeriesIntervals {
+		if i.name == interval {
+			timeBucket = i.bucket
+		}
 	}
 	
 	query := fmt.Sprintf(`
-		SELECT time_bucket('%s', timestamp) AS bucket, COUNT(*) as count
+		SELECT time_bucket('%s', timestamp, $3::TEXT) AS bucket, COUNT(*) as count
 		FROM logs
-		WHERE timestamp >= $1
+		WHERE timestamp >= $1 AND timestamp < $2
 		GROUP BY bucket
 		ORDER BY bucket ASC
 	`, timeBucket)
 	
-	rows, err := r.pool.Query(ctx, query, since)
+	rows, err := r.pool.Query(ctx, query, since, until, location.String())
 	if err != nil {
 		return nil, fmt.Errorf("error getting time series data: %w", err)
 	}
@@ -315,6 +567,7 @@ func (r *Repository) GetTimeSeriesData(ctx context.Context, timeRange time.Durat
 		if err != nil {
 			return nil, err
 		}
+		point.Time = point.Time.In(location)
 		points = append(points, point)
 	}
 	
@@ -354,7 +607,8 @@ func (r *Repository) ListAPIKeys(ctx context.Context, userID *int64) ([]APIKey,
 
 	if userID != nil {
 		query = `
-			SELECT id, name, description, created_at, is_active, created_by_user_id
+			SELECT id, name, description, created_at, is_active, created_by_user_id, parser,
+			       COALESCE(timestamp_layouts, '{}'), timezone
 			FROM api_keys
 			WHERE created_by_user_id = $1
 			ORDER BY created_at DESC
@@ -362,7 +616,8 @@ func (r *Repository) ListAPIKeys(ctx context.Context, userID *int64) ([]APIKey,
 		args = append(args, *userID)
 	} else {
 		query = `
-			SELECT id, name, description, created_at, is_active, created_by_user_id
+			SELECT id, name, description, created_at, is_active, created_by_user_id, parser,
+			       COALESCE(timestamp_layouts, '{}'), timezone
 			FROM api_keys
 			ORDER BY created_at DESC
 		`
@@ -384,6 +639,9 @@ func (r *Repository) ListAPIKeys(ctx context.Context, userID *int64) ([]APIKey,
 			&key.CreatedAt,
 			&key.IsActive,
 			&key.CreatedByUserID,
+			&key.Parser,
+			&key.TimestampLayouts,
+			&key.Timezone,
 		)
 		if err != nil {
 			return nil, err
@@ -423,12 +681,82 @@ func (r *Repository) DeleteAPIKey(ctx context.Context, id int64, userID *int64)
 	}
 	
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("API key with id %d not found or not authorized", id)
+		return fmt.Errorf("API key with id %d not found or not authorized: %w", id, ErrNotFound)
+	}
+	
+	return nil
+}
+
+// SetAPIKeyParser sets the parser used for raw logs sent with an API key (nil restores automatic selection).
+// If userID is provided, only updates the key if it belongs to that user.
+func (r *Repository) SetAPIKeyParser(ctx context.Context, id int64, parser *string, userID *int64) error {
+	query := `UPDATE api_keys SET parser = $2 WHERE id = $1`
+	args := []interface{}{id, parser}
+	if userID != nil {
+		query += ` AND created_by_user_id = $3`
+		args = append(args, *userID)
+	}
+	
+	result, err := r.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error setting API key parser: %w", err)
+	}
+	
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("API key with id %d not found or not authorized: %w", id, ErrNotFound)
 	}
 	
 	return nil
 }
 
+// SetAPIKeyTimestamps sets the timestamp layouts and timezone of JSON logs sent with an API key
+// (empty layouts and a nil timezone restore the defaults).
+// If userID is provided, only updates the key if it belongs to that user.
+func (r *Repository) SetAPIKeyTimestamps(ctx context.Context, id int64, layouts []string, timezone *string, userID *int64) error {
+	query := `UPDATE api_keys SET timestamp_layouts = $2, timezone = $3 WHERE id = $1`
+	args := []interface{}{id, layouts, timezone}
+	if userID != nil {
+		query += ` AND created_by_user_id = $4`
+		args = append(args, *userID)
+	}
+	
+	result, err := r.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error setting API key timestamps: %w", err)
+	}
+	
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("API key with id %d not found or not authorized: %w", id, ErrNotFound)
+	}
+	
+	return nil
+}
+
+// GetAPIKeyParsing returns how logs sent with an active API key are parsed; unknown keys get
+// the zero value
+func (r *Repository) GetAPIKeyParsing(ctx context.Context, key string) (APIKeyParsing, error) {
+	var parser, timezone *string
+	var layouts []string
+	query := `SELECT parser, COALESCE(timestamp_layouts, '{}'), timezone FROM api_keys WHERE key = $1 AND is_active = TRUE`
+	
+	err := r.pool.QueryRow(ctx, query, key).Scan(&parser, &layouts, &timezone)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return APIKeyParsing{}, nil
+		}
+		return APIKeyParsing{}, fmt.Errorf("error getting API key parsing: %w", err)
+	}
+	
+	parsing := APIKeyParsing{TimestampLayouts: layouts}
+	if parser != nil {
+		parsing.Parser = *parser
+	}
+	if timezone != nil {
+		parsing.Timezone = *timezone
+	}
+	return parsing, nil
+}
+
 // GetAPIKeyByValue checks if an API key exists and is active
 func (r *Repository) GetAPIKeyByValue(ctx context.Context, key string) (bool, error) {
 	var exists bool
@@ -447,6 +775,34 @@ func (r *Repository) GetAPIKeyByValue(ctx context.Context, key string) (bool, er
 	return exists, nil
 }
 
+// GetAPIKeysByValue looks up API keys by their value, returning them keyed by value. Unknown
+// values are left out; the key values themselves are not returned.
+func (r *Repository) GetAPIKeysByValue(ctx context.Context, values []string) (map[string]APIKey, error) {
+	keys := make(map[string]APIKey, len(values))
+	if len(values) == 0 {
+		return keys, nil
+	}
+	rows, err := r.pool.Query(ctx, `
+		SELECT key, id, name, created_at, is_active, created_by_user_id
+		FROM api_keys
+		WHERE key = ANY($1)
+	`, values)
+	if err != nil {
+		return nil, fmt.Errorf("error looking up API keys: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var value string
+		var key APIKey
+		if err := rows.Scan(&value, &key.ID, &key.Name, &key.CreatedAt, &key.IsActive, &key.CreatedByUserID); err != nil {
+			return nil, fmt.Errorf("error scanning API key: %w", err)
+		}
+		keys[value] = key
+	}
+	return keys, rows.Err()
+}
+
 // GetAllActiveAPIKeys returns all active API key strings (for validation)
 func (r *Repository) GetAllActiveAPIKeys(ctx context.Context) ([]string, error) {
 	query := `
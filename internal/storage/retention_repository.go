@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+)
+
+// RetentionTables maps the tables that can carry a retention policy to their time column
+var RetentionTables = map[string]string{
+	"logs":    "timestamp",
+	"notices": "created_at",
+}
+
+// Retention policy sources
+const (
+	RetentionSourceTimescaleDB = "timescaledb"
+	RetentionSourceRequest     = "request"
+)
+
+// RetentionPreview describes what a retention policy would delete from one table if it ran now
+type RetentionPreview struct {
+	Table string `json:"table"`
+	// Source is timescaledb for a scheduled retention job, or request for a policy given in the query
+	Source           string     `json:"source"`
+	JobID            *int64     `json:"job_id,omitempty"`
+	DropAfter        string     `json:"drop_after"`
+	ScheduleInterval string     `json:"schedule_interval,omitempty"`
+	NextStart        *time.Time `json:"next_start,omitempty"`
+	Cutoff           time.Time  `json:"cutoff"`
+	// Hypertable tables lose whole chunks that end before the cutoff, so some older rows may remain
+	Hypertable     bool       `json:"hypertable"`
+	RowsToDelete   int64      `json:"rows_to_delete"`
+	ChunksToDrop   int64      `json:"chunks_to_drop"`
+	BytesToFree    int64      `json:"bytes_to_free"`
+	OldestRow      *time.Time `json:"oldest_row,omitempty"`
+	OldestRetained *time.Time `json:"oldest_retained,omitempty"`
+}
+
+// HasTimescaleDB reports whether the TimescaleDB extension is installed
+func (r *Repository) HasTimescaleDB(ctx context.Context) (bool, error) {
+	var installed bool
+	err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb')`).Scan(&installed)
+	if err != nil {
+		return false, fmt.Errorf("error checking for TimescaleDB: %w", err)
+	}
+	return installed, nil
+}
+
+// ListRetentionPolicies returns the TimescaleDB retention jobs on retention tables, ordered by table.
+// Policies whose drop_after is not an interval are skipped.
+func (r *Repository) ListRetentionPolicies(ctx context.Context) ([]RetentionPreview, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT j.job_id, j.hypertable_name, j.config->>'drop_after', j.schedule_interval::TEXT, s.next_start
+		FROM timescaledb_information.jobs j
+		LEFT JOIN timescaledb_information.job_stats s ON s.job_id = j.job_id
+		WHERE j.proc_name = 'policy_retention'
+		ORDER BY j.hypertable_name, j.job_id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error listing retention policies: %w", err)
+	}
+
+	policies := []RetentionPreview{}
+	for rows.Next() {
+		var p RetentionPreview
+		var jobID int64
+		var table, dropAfter, schedule *string
+		if err := rows.Scan(&jobID, &table, &dropAfter, &schedule, &p.NextStart); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error scanning retention policy: %w", err)
+		}
+		if table == nil || dropAfter == nil {
+			continue
+		}
+		if _, ok := RetentionTables[*table]; !ok {
+			continue
+		}
+		p.Table = *table
+		p.Source = RetentionSourceTimescaleDB
+		p.JobID = &jobID
+		p.DropAfter = *dropAfter
+		if schedule != nil {
+			p.ScheduleInterval = *schedule
+		}
+		policies = append(policies, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error listing retention policies: %w", err)
+	}
+
+	valid := policies[:0]
+	for _, p := range policies {
+		if err := r.pool.QueryRow(ctx, `SELECT NOW() - $1::TEXT::INTERVAL`, p.DropAfter).Scan(&p.Cutoff); err != nil {
+			log.Printf("WARN: Skipping retention job %d on %s: drop_after %q is not an interval", *p.JobID, p.Table, p.DropAfter)
+			continue
+		}
+		valid = append(valid, p)
+	}
+	sort.SliceStable(valid, func(i, j int) bool { return valid[i].Table < valid[j].Table })
+	return valid, nil
+}
+
+// PreviewRetention fills in what deleting p.Table's rows older than p.Cutoff would remove. On a
+// hypertable that is the chunks ending at or before the cutoff, as drop_chunks does; otherwise
+// every older row.
+func (r *Repository) PreviewRetention(ctx context.Context, p *RetentionPreview, timescale bool) error {
+	column, ok := RetentionTables[p.Table]
+	if !ok {
+		return fmt.Errorf("table %q has no retention policy support", p.Table)
+	}
+
+	if timescale {
+		err := r.pool.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM timescaledb_information.hypertables WHERE hypertable_name = $1)
+		`, p.Table).Scan(&p.Hypertable)
+		if err != nil {
+			return fmt.Errorf("error checking hypertable %s: %w", p.Table, err)
+		}
+	}
+
+	// Rows before boundary are deleted
+	boundary := p.Cutoff
+	if p.Hypertable {
+		var lastEnd *time.Time
+		err := r.pool.QueryRow(ctx, `
+			SELECT COUNT(*),
+				COALESCE(SUM(pg_total_relation_size(format('%I.%I', chunk_schema, chunk_name)::REGCLASS)), 0),
+				MAX(range_end)
+			FROM timescaledb_information.chunks
+			WHERE hypertable_name = $1 AND range_end <= $2
+		`, p.Table, p.Cutoff).Scan(&p.ChunksToDrop, &p.BytesToFree, &lastEnd)
+		if err != nil {
+			return fmt.Errorf("error listing chunks of %s: %w", p.Table, err)
+		}
+		if lastEnd == nil {
+			boundary = time.Time{}
+		} else {
+			boundary = *lastEnd
+		}
+	}
+
+	// Separate subqueries so each can use the time index instead of scanning the table
+	query := fmt.Sprintf(`
+		SELECT
+			(SELECT COUNT(*) FROM %[2]s WHERE %[1]s < $1),
+			(SELECT MIN(%[1]s) FROM %[2]s),
+			(SELECT MIN(%[1]s) FROM %[2]s WHERE %[1]s >= $1)
+	`, column, p.Table)
+	if err := r.pool.QueryRow(ctx, query, boundary).Scan(&p.RowsToDelete, &p.OldestRow, &p.OldestRetained); err != nil {
+		return fmt.Errorf("error counting expired rows in %s: %w", p.Table, err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+)
+
+// CreateSamplingRule creates a new sampling rule
+func (r *Repository) CreateSamplingRule(ctx context.Context, rule *models.SamplingRule) error {
+	query := `
+		INSERT INTO sampling_rules (service, level, message_pattern, sample_rate, description, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		rule.Service,
+		rule.Level,
+		rule.MessagePattern,
+		rule.SampleRate,
+		rule.Description,
+		rule.CreatedBy,
+	).Scan(&rule.ID, &rule.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error creating sampling rule: %w", err)
+	}
+
+	return nil
+}
+
+// ListSamplingRules returns the sampling rules in the order they are checked
+func (r *Repository) ListSamplingRules(ctx context.Context) ([]models.SamplingRule, error) {
+	query := `
+		SELECT id, service, level, message_pattern, sample_rate, description, created_by, created_at
+		FROM sampling_rules
+		ORDER BY id
+	`
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sampling rules: %w", err)
+	}
+	defer rows.Close()
+
+	rules := []models.SamplingRule{}
+	for rows.Next() {
+		var rule models.SamplingRule
+		err := rows.Scan(
+			&rule.ID,
+			&rule.Service,
+			&rule.Level,
+			&rule.MessagePattern,
+			&rule.SampleRate,
+			&rule.Description,
+			&rule.CreatedBy,
+			&rule.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning sampling rule: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// DeleteSamplingRule deletes a sampling rule
+func (r *Repository) DeleteSamplingRule(ctx context.Context, id int64) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM sampling_rules WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting sampling rule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("sampling rule %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
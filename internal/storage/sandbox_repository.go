@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrNotSandbox is returned for sandbox operations on a project that is not a sandbox
+var ErrNotSandbox = errors.New("the project is not a sandbox")
+
+// SandboxLogKey is the log metadata key that marks generated sample logs with their sandbox project
+const SandboxLogKey = "sandbox_project_id"
+
+// SandboxWipe reports how many records were removed from a sandbox project
+type SandboxWipe struct {
+	Faults int64 `json:"faults"`
+	Logs   int64 `json:"logs"`
+	Users  int64 `json:"users"`
+}
+
+// SandboxUserEmail returns the email address used for the i-th generated user of a sandbox project
+func SandboxUserEmail(projectID int64, i int) string {
+	return fmt.Sprintf("demo-%d-%d@sandbox.invalid", projectID, i)
+}
+
+// SetFaultSeenStats overwrites a fault's occurrence count and first/last seen times
+func (r *Repository) SetFaultSeenStats(ctx context.Context, id int64, occurrences int64, firstSeen, lastSeen time.Time) error {
+	query := `
+		UPDATE faults
+		SET occurrence_count = $2, first_seen_at = $3, last_seen_at = $4, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.pool.Exec(ctx, query, id, occurrences, firstSeen, lastSeen); err != nil {
+		return fmt.Errorf("error setting fault stats: %w", err)
+	}
+	return nil
+}
+
+// MarkSandbox records a project as a sandbox before sample data is written to it. A project
+// that is not yet a sandbox but already has faults is real, and is refused with ErrNotSandbox.
+func (r *Repository) MarkSandbox(ctx context.Context, projectID int64) error {
+	query := `
+		INSERT INTO sandbox_projects (project_id)
+		SELECT $1
+		WHERE NOT EXISTS (SELECT 1 FROM faults WHERE project_id = $1)
+		ON CONFLICT (project_id) DO NOTHING
+	`
+
+	if _, err := r.pool.Exec(ctx, query, projectID); err != nil {
+		return fmt.Errorf("error marking sandbox project: %w", err)
+	}
+	isSandbox, err := r.IsSandbox(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	if !isSandbox {
+		return ErrNotSandbox
+	}
+	return nil
+}
+
+// IsSandbox reports whether a project was recorded as a sandbox
+func (r *Repository) IsSandbox(ctx context.Context, projectID int64) (bool, error) {
+	var exists bool
+	err := r.pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM sandbox_projects WHERE project_id = $1)`, projectID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("error checking sandbox project: %w", err)
+	}
+	return exists, nil
+}
+
+// WipeSandbox deletes all faults, generated logs and generated users of a sandbox project.
+// Notices, comments, history and merges are removed with their faults. Projects that are not
+// sandboxes are refused with ErrNotSandbox.
+func (r *Repository) WipeSandbox(ctx context.Context, projectID int64) (*SandboxWipe, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Locked, so the project stays a sandbox until the wipe commits
+	var marked int64
+	err = tx.QueryRow(ctx, `SELECT project_id FROM sandbox_projects WHERE project_id = $1 FOR SHARE`, projectID).Scan(&marked)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotSandbox
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error checking sandbox project: %w", err)
+	}
+
+	var wipe SandboxWipe
+
+	result, err := tx.Exec(ctx, `DELETE FROM faults WHERE project_id = $1`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting sandbox faults: %w", err)
+	}
+	wipe.Faults = result.RowsAffected()
+
+	result, err = tx.Exec(ctx, `DELETE FROM logs WHERE metadata->>'`+SandboxLogKey+`' = $1`, strconv.FormatInt(projectID, 10))
+	if err != nil {
+		return nil, fmt.Errorf("error deleting sandbox logs: %w", err)
+	}
+	wipe.Logs = result.RowsAffected()
+
+	result, err = tx.Exec(ctx, `DELETE FROM users WHERE email LIKE $1`, fmt.Sprintf("demo-%d-%%@sandbox.invalid", projectID))
+	if err != nil {
+		return nil, fmt.Errorf("error deleting sandbox users: %w", err)
+	}
+	wipe.Users = result.RowsAffected()
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("error committing sandbox wipe: %w", err)
+	}
+
+	return &wipe, nil
+}
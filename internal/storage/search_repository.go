@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"strings"
+	"time"
+)
+
+// SearchFilters selects the records a global search matches
+type SearchFilters struct {
+	// Query matches text containing it, case-insensitively
+	Query string
+	// Since bounds the notices and logs searched; faults and comments are searched in full
+	Since time.Time
+	Limit int
+}
+
+// searchQueries select, for each kind of record, its ID, fault, title, text and time, matching
+// $1 as a LIKE pattern, limited to $2. Notices and logs are searched since $3.
+var searchQueries = map[string]string{
+	models.SearchFault: `
+		SELECT id::TEXT, id, error_class, message, last_seen_at
+		FROM faults
+		WHERE error_class ILIKE $1 OR message ILIKE $1
+		ORDER BY last_seen_at DESC
+		LIMIT $2
+	`,
+	models.SearchNotice: `
+		SELECT n.id, n.fault_id, f.error_class, n.message, n.created_at
+		FROM notices n
+		JOIN faults f ON f.id = n.fault_id
+		WHERE n.message ILIKE $1 AND n.created_at >= $3
+		ORDER BY n.created_at DESC
+		LIMIT $2
+	`,
+	models.SearchComment: `
+		SELECT c.id::TEXT, c.fault_id, f.error_class, c.comment, c.created_at
+		FROM fault_comments c
+		JOIN faults f ON f.id = c.fault_id
+		WHERE c.comment ILIKE $1
+		ORDER BY c.created_at DESC
+		LIMIT $2
+	`,
+	models.SearchLog: `
+		SELECT id::TEXT, NULL::BIGINT, service, message, timestamp
+		FROM logs
+		WHERE message ILIKE $1 AND timestamp >= $3
+		ORDER BY timestamp DESC
+		LIMIT $2
+	`,
+}
+
+// SearchRecords returns up to filters.Limit records of a kind matching filters, newest first.
+// Each hit's Snippet holds the record's full text; highlighting is left to the caller.
+func (r *Repository) SearchRecords(ctx context.Context, kind string, filters SearchFilters) ([]models.SearchHit, error) {
+	query, ok := searchQueries[kind]
+	if !ok {
+		return nil, fmt.Errorf("unknown search kind %q", kind)
+	}
+	args := []interface{}{"%" + escapeLike(filters.Query) + "%", filters.Limit}
+	if kind == models.SearchNotice || kind == models.SearchLog {
+		args = append(args, filters.Since)
+	}
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error searching %ss: %w", kind, err)
+	}
+	defer rows.Close()
+
+	hits := []models.SearchHit{}
+	for rows.Next() {
+		hit := models.SearchHit{Type: kind}
+		if err := rows.Scan(&hit.ID, &hit.FaultID, &hit.Title, &hit.Snippet, &hit.Timestamp); err != nil {
+			return nil, err
+		}
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// escapeLike escapes the LIKE wildcards in s, so it matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+)
+
+// CreateSession stores a new session. Sessions of the user that ended over a week ago are
+// pruned at the same time.
+func (r *Repository) CreateSession(ctx context.Context, session *models.UserSession) error {
+	query := `
+		WITH pruned AS (
+			DELETE FROM user_sessions
+			WHERE user_id = $2 AND LEAST(expires_at, COALESCE(revoked_at, expires_at)) < NOW() - INTERVAL '7 days'
+		)
+		INSERT INTO user_sessions (id, user_id, device, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at, last_active_at
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		session.ID,
+		session.UserID,
+		session.Device,
+		session.UserAgent,
+		session.IPAddress,
+		session.ExpiresAt,
+	).Scan(&session.CreatedAt, &session.LastActiveAt)
+	if err != nil {
+		return fmt.Errorf("error creating session: %w", err)
+	}
+	return nil
+}
+
+// TouchSession reports whether a session of the user is active, refreshing its last activity
+// and IP address if they were last recorded over a minute ago
+func (r *Repository) TouchSession(ctx context.Context, id string, userID int64, ipAddress string) (bool, error) {
+	query := `
+		WITH active AS (
+			SELECT id, last_active_at
+			FROM user_sessions
+			WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+		), touched AS (
+			UPDATE user_sessions s
+			SET last_active_at = NOW(), ip_address = $3
+			FROM active a
+			WHERE s.id = a.id AND a.last_active_at < NOW() - INTERVAL '1 minute'
+		)
+		SELECT COUNT(*) FROM active
+	`
+
+	var count int
+	if err := r.pool.QueryRow(ctx, query, id, userID, ipAddress).Scan(&count); err != nil {
+		return false, fmt.Errorf("error checking session: %w", err)
+	}
+	return count > 0, nil
+}
+
+// ListSessions returns a user's active sessions, most recently active first
+func (r *Repository) ListSessions(ctx context.Context, userID int64) ([]models.UserSession, error) {
+	query := `
+		SELECT id, user_id, device, user_agent, ip_address, created_at, last_active_at, expires_at
+		FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_active_at DESC, created_at DESC
+	`
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.UserSession{}
+	for rows.Next() {
+		var s models.UserSession
+		err := rows.Scan(
+			&s.ID,
+			&s.UserID,
+			&s.Device,
+			&s.UserAgent,
+			&s.IPAddress,
+			&s.CreatedAt,
+			&s.LastActiveAt,
+			&s.ExpiresAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning session: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession revokes an active session of a user
+func (r *Repository) RevokeSession(ctx context.Context, userID int64, id string) error {
+	query := `
+		UPDATE user_sessions
+		SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > NOW()
+	`
+
+	tag, err := r.pool.Exec(ctx, query, id, userID)
+	if err != nil {
+		return fmt.Errorf("error revoking session: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("session %s: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// RevokeSessions revokes every active session of a user except keepID, which may be empty, and
+// returns how many were revoked
+func (r *Repository) RevokeSessions(ctx context.Context, userID int64, keepID string) (int64, error) {
+	query := `
+		UPDATE user_sessions
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND id <> $2 AND revoked_at IS NULL AND expires_at > NOW()
+	`
+
+	tag, err := r.pool.Exec(ctx, query, userID, keepID)
+	if err != nil {
+		return 0, fmt.Errorf("error revoking sessions: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
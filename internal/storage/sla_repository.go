@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"sort"
+	"strings"
+)
+
+// SLAWarning names a fault timer that is at risk or breached and has not been notified about
+type SLAWarning struct {
+	FaultID int64
+	Timer   string
+}
+
+// slaWarnedColumns maps each SLA timer to the column recording its notification
+var slaWarnedColumns = map[string]string{
+	models.SLAAcknowledge: "sla_acknowledge_warned_at",
+	models.SLAResolve:     "sla_resolve_warned_at",
+}
+
+// UpdateFaultSLAClock records when a fault was acknowledged, closed or reopened, after a move
+// to its current state. initial is the workflow's first state and closed lists the resolved
+// and ignored states. Running it again without another move changes nothing.
+func (r *Repository) UpdateFaultSLAClock(ctx context.Context, id int64, initial string, closed []string) error {
+	query := `
+		UPDATE faults f
+		SET opened_at = CASE WHEN s.reopened THEN NOW() ELSE f.opened_at END,
+		    acknowledged_at = CASE WHEN f.state = $2 THEN NULL
+		                           WHEN s.reopened THEN NOW()
+		                           ELSE COALESCE(f.acknowledged_at, NOW()) END,
+		    closed_at = CASE WHEN s.closed THEN COALESCE(f.closed_at, NOW()) END,
+		    sla_acknowledge_warned_at = CASE WHEN s.reopened THEN NULL ELSE f.sla_acknowledge_warned_at END,
+		    sla_resolve_warned_at = CASE WHEN s.reopened THEN NULL ELSE f.sla_resolve_warned_at END
+		FROM (
+			SELECT id, state = ANY($3) AS closed, closed_at IS NOT NULL AND state <> ALL($3) AS reopened
+			FROM faults
+			WHERE id = $1
+		) s
+		WHERE f.id = s.id
+	`
+
+	if _, err := r.pool.Exec(ctx, query, id, initial, closed); err != nil {
+		return fmt.Errorf("error updating fault SLA clock: %w", err)
+	}
+	return nil
+}
+
+// FindSLAWarnings returns up to limit fault timers that are at risk or breached and have not
+// been notified about, oldest first
+func (r *Repository) FindSLAWarnings(ctx context.Context, policy *models.SLAPolicy, limit int) ([]SLAWarning, error) {
+	var warnings []SLAWarning
+	for _, timer := range []string{models.SLAAcknowledge, models.SLAResolve} {
+		condition, args, argIndex := slaElapsedCondition(policy, timer, policy.WarnAt, 1)
+		query := fmt.Sprintf(`
+			SELECT f.id
+			FROM faults f
+			WHERE %s AND f.%s IS NULL
+			ORDER BY f.opened_at
+			LIMIT $%d
+		`, condition, slaWarnedColumns[timer], argIndex)
+
+		rows, err := r.pool.Query(ctx, query, append(args, limit)...)
+		if err != nil {
+			return nil, fmt.Errorf("error finding faults at SLA risk: %w", err)
+		}
+		for rows.Next() {
+			warning := SLAWarning{Timer: timer}
+			if err := rows.Scan(&warning.FaultID); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("error scanning fault ID: %w", err)
+			}
+			warnings = append(warnings, warning)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error finding faults at SLA risk: %w", err)
+		}
+	}
+	return warnings, nil
+}
+
+// MarkSLAWarned records that a fault timer was notified about. It returns false when it
+// already was, so only one instance notifies.
+func (r *Repository) MarkSLAWarned(ctx context.Context, warning SLAWarning) (bool, error) {
+	query := fmt.Sprintf(`
+		UPDATE faults
+		SET %[1]s = NOW()
+		WHERE id = $1 AND %[1]s IS NULL
+	`, slaWarnedColumns[warning.Timer])
+
+	result, err := r.pool.Exec(ctx, query, warning.FaultID)
+	if err != nil {
+		return false, fmt.Errorf("error marking SLA warning: %w", err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// slaStatusCondition matches faults with a running timer in status, at_risk or breached
+func slaStatusCondition(policy *models.SLAPolicy, status string, argIndex int) (string, []interface{}, int) {
+	var parts []string
+	var args []interface{}
+	for _, timer := range []string{models.SLAAcknowledge, models.SLAResolve} {
+		breached, breachedArgs, next := slaElapsedCondition(policy, timer, 1, argIndex)
+		argIndex = next
+		args = append(args, breachedArgs...)
+		if status == models.SLABreached {
+			parts = append(parts, breached)
+			continue
+		}
+
+		warned, warnedArgs, next := slaElapsedCondition(policy, timer, policy.WarnAt, argIndex)
+		argIndex = next
+		args = append(args, warnedArgs...)
+		parts = append(parts, fmt.Sprintf("(%s AND NOT %s)", warned, breached))
+	}
+	return "(" + strings.Join(parts, " OR ") + ")", args, argIndex
+}
+
+// slaElapsedCondition matches faults whose timer is running and has used at least fraction of
+// its severity's target
+func slaElapsedCondition(policy *models.SLAPolicy, timer string, fraction float64, argIndex int) (string, []interface{}, int) {
+	severities := make([]string, 0, len(policy.Targets))
+	for severity := range policy.Targets {
+		severities = append(severities, severity)
+	}
+	sort.Strings(severities)
+
+	var parts []string
+	var args []interface{}
+	for _, severity := range severities {
+		target := policy.Targets[severity].For(timer)
+		if target <= 0 {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("(f.severity = $%d AND f.opened_at + make_interval(secs => $%d) <= NOW())", argIndex, argIndex+1))
+		args = append(args, severity, target.Seconds()*fraction)
+		argIndex += 2
+	}
+	if len(parts) == 0 {
+		return "FALSE", nil, argIndex
+	}
+
+	running := "f.closed_at IS NULL"
+	if timer == models.SLAAcknowledge {
+		running = "f.acknowledged_at IS NULL AND f.closed_at IS NULL"
+	}
+	return fmt.Sprintf("(%s AND (%s))", running, strings.Join(parts, " OR ")), args, argIndex
+}
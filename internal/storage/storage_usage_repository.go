@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// growthTables are the time-partitioned tables whose growth is tracked, with their time column
+var growthTables = []struct {
+	name   string
+	column string
+}{
+	{"logs", "timestamp"},
+	{"notices", "created_at"},
+}
+
+// TableUsage is the disk space used by one table, including indexes and TOAST
+type TableUsage struct {
+	Table      string `json:"table"`
+	Bytes      int64  `json:"bytes"`
+	// Rows is the planner's estimate, refreshed by autovacuum
+	Rows       int64 `json:"rows"`
+	Hypertable bool  `json:"hypertable"`
+}
+
+// ProjectUsage is the estimated space used by one project's faults, notices and history
+type ProjectUsage struct {
+	ProjectID      *int64 `json:"project_id"`
+	Faults         int64  `json:"faults"`
+	Notices        int64  `json:"notices"`
+	HistoryEntries int64  `json:"history_entries"`
+	EstimatedBytes int64  `json:"estimated_bytes"`
+}
+
+// WeeklyGrowth is the space taken by data written in one week
+type WeeklyGrowth struct {
+	WeekStart time.Time        `json:"week_start"`
+	Tables    map[string]int64 `json:"tables"`
+	Bytes     int64            `json:"bytes"`
+	// Estimated is set when sizes come from row counts rather than chunk sizes
+	Estimated bool `json:"estimated"`
+}
+
+// GetDatabaseSize returns the size of the current database in bytes
+func (r *Repository) GetDatabaseSize(ctx context.Context) (int64, error) {
+	var size int64
+	if err := r.pool.QueryRow(ctx, `SELECT pg_database_size(current_database())`).Scan(&size); err != nil {
+		return 0, fmt.Errorf("error getting database size: %w", err)
+	}
+	return size, nil
+}
+
+// GetTableUsage returns the size of every table in the public schema, largest first. Hypertable
+// sizes include their chunks.
+func (r *Repository) GetTableUsage(ctx context.Context, timescale bool) ([]TableUsage, error) {
+	query := `
+		SELECT relname, pg_total_relation_size(relid), GREATEST(n_live_tup, 0), FALSE
+		FROM pg_stat_user_tables
+		WHERE schemaname = 'public'
+	`
+	if timescale {
+		query = `
+			SELECT t.relname,
+				CASE WHEN h.hypertable_name IS NULL THEN pg_total_relation_size(t.relid)
+					ELSE hypertable_size(format('%I.%I', h.hypertable_schema, h.hypertable_name)::REGCLASS) END,
+				CASE WHEN h.hypertable_name IS NULL THEN GREATEST(t.n_live_tup, 0)
+					ELSE approximate_row_count(format('%I.%I', h.hypertable_schema, h.hypertable_name)::REGCLASS) END,
+				h.hypertable_name IS NOT NULL
+			FROM pg_stat_user_tables t
+			LEFT JOIN timescaledb_information.hypertables h
+				ON h.hypertable_schema = t.schemaname AND h.hypertable_name = t.relname
+			WHERE t.schemaname = 'public'
+		`
+	}
+	rows, err := r.pool.Query(ctx, query+` ORDER BY 2 DESC, 1`)
+	if err != nil {
+		return nil, fmt.Errorf("error getting table sizes: %w", err)
+	}
+	defer rows.Close()
+
+	tables := []TableUsage{}
+	for rows.Next() {
+		var t TableUsage
+		if err := rows.Scan(&t.Table, &t.Bytes, &t.Rows, &t.Hypertable); err != nil {
+			return nil, fmt.Errorf("error scanning table size: %w", err)
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// GetProjectUsage estimates the space used per project. Notice counts come from the faults'
+// occurrence counts rather than a scan of notices; bytes are the counts times the average row
+// size of each table in tables.
+func (r *Repository) GetProjectUsage(ctx context.Context, tables []TableUsage) ([]ProjectUsage, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT f.project_id, COUNT(*), COALESCE(SUM(f.occurrence_count), 0), COALESCE(SUM(h.entries), 0)
+		FROM faults f
+		LEFT JOIN (
+			SELECT fault_id, COUNT(*) AS entries FROM fault_history GROUP BY fault_id
+		) h ON h.fault_id = f.id
+		GROUP BY f.project_id
+		ORDER BY f.project_id NULLS FIRST
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("error getting project usage: %w", err)
+	}
+	defer rows.Close()
+
+	rowBytes := make(map[string]int64, len(tables))
+	for _, t := range tables {
+		if t.Rows > 0 {
+			rowBytes[t.Table] = t.Bytes / t.Rows
+		}
+	}
+
+	projects := []ProjectUsage{}
+	for rows.Next() {
+		var p ProjectUsage
+		if err := rows.Scan(&p.ProjectID, &p.Faults, &p.Notices, &p.HistoryEntries); err != nil {
+			return nil, fmt.Errorf("error scanning project usage: %w", err)
+		}
+		p.EstimatedBytes = p.Faults*rowBytes["faults"] + p.Notices*rowBytes["notices"] + p.HistoryEntries*rowBytes["fault_history"]
+		projects = append(projects, p)
+	}
+	return projects, rows.Err()
+}
+
+// GetWeeklyGrowth returns the space taken by logs and notices written in each of the weeks since
+// since. Hypertables are measured by the size of their chunks, which is exact when chunks span a
+// week or less; other tables by row counts times their average row size.
+func (r *Repository) GetWeeklyGrowth(ctx context.Context, since time.Time, tables []TableUsage) ([]WeeklyGrowth, error) {
+	usage := make(map[string]TableUsage, len(tables))
+	for _, t := range tables {
+		usage[t.Table] = t
+	}
+
+	weeks := make(map[time.Time]*WeeklyGrowth)
+	add := func(week time.Time, table string, bytes int64, estimated bool) {
+		week = week.UTC()
+		w, ok := weeks[week]
+		if !ok {
+			w = &WeeklyGrowth{WeekStart: week, Tables: make(map[string]int64)}
+			weeks[week] = w
+		}
+		w.Tables[table] += bytes
+		w.Bytes += bytes
+		w.Estimated = w.Estimated || estimated
+	}
+
+	for _, gt := range growthTables {
+		t := usage[gt.name]
+		var query string
+		var args []interface{}
+		if t.Hypertable {
+			query = `
+				SELECT date_trunc('week', range_start AT TIME ZONE 'UTC'), SUM(pg_total_relation_size(format('%I.%I', chunk_schema, chunk_name)::REGCLASS))
+				FROM timescaledb_information.chunks
+				WHERE hypertable_name = $1 AND range_start >= $2
+				GROUP BY 1
+			`
+			args = []interface{}{gt.name, since}
+		} else {
+			if t.Rows == 0 {
+				continue
+			}
+			query = fmt.Sprintf(`
+				SELECT date_trunc('week', %[1]s AT TIME ZONE 'UTC'), COUNT(*) * %[3]d
+				FROM %[2]s
+				WHERE %[1]s >= $1
+				GROUP BY 1
+			`, gt.column, gt.name, t.Bytes/t.Rows)
+			args = []interface{}{since}
+		}
+
+		rows, err := r.pool.Query(ctx, query, args...)
+		if err != nil {
+			return nil, fmt.Errorf("error getting growth of %s: %w", gt.name, err)
+		}
+		for rows.Next() {
+			var week time.Time
+			var bytes int64
+			if err := rows.Scan(&week, &bytes); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("error scanning growth of %s: %w", gt.name, err)
+			}
+			add(week, gt.name, bytes, !t.Hypertable)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("error getting growth of %s: %w", gt.name, err)
+		}
+	}
+
+	growth := make([]WeeklyGrowth, 0, len(weeks))
+	for week := startOfWeek(since); !week.After(time.Now()); week = week.AddDate(0, 0, 7) {
+		if w, ok := weeks[week]; ok {
+			growth = append(growth, *w)
+		} else {
+			growth = append(growth, WeeklyGrowth{WeekStart: week, Tables: map[string]int64{}})
+		}
+	}
+	return growth, nil
+}
+
+// startOfWeek returns the Monday 00:00 UTC on or before t, matching date_trunc('week')
+func startOfWeek(t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const subscriptionColumns = `id, user_id, name, kind, query, frequency, at_time, weekday, timezone,
+		       channel, target, max_results, enabled, next_run_at, last_run_at, last_status,
+		       last_error, last_result_count, created_at, updated_at`
+
+func scanSubscription(row pgx.Row) (*models.Subscription, error) {
+	var s models.Subscription
+	err := row.Scan(&s.ID, &s.UserID, &s.Name, &s.Kind, &s.Query, &s.Schedule.Frequency, &s.Schedule.At,
+		&s.Schedule.Weekday, &s.Schedule.Timezone, &s.Channel, &s.Target, &s.MaxResults, &s.Enabled,
+		&s.NextRunAt, &s.LastRunAt, &s.LastStatus, &s.LastError, &s.LastResultCount, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// CreateSubscription saves a query subscription, filling in its ID and timestamps
+func (r *Repository) CreateSubscription(ctx context.Context, s *models.Subscription) error {
+	created, err := scanSubscription(r.pool.QueryRow(ctx, `
+		INSERT INTO query_subscriptions (user_id, name, kind, query, frequency, at_time, weekday, timezone,
+		                                 channel, target, max_results, enabled, next_run_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING `+subscriptionColumns,
+		s.UserID, s.Name, s.Kind, s.Query, s.Schedule.Frequency, s.Schedule.At, s.Schedule.Weekday,
+		s.Schedule.Timezone, s.Channel, s.Target, s.MaxResults, s.Enabled, s.NextRunAt))
+	if err != nil {
+		return fmt.Errorf("error creating subscription: %w", err)
+	}
+	*s = *created
+	return nil
+}
+
+// GetSubscription returns a subscription by ID
+func (r *Repository) GetSubscription(ctx context.Context, id int64) (*models.Subscription, error) {
+	s, err := scanSubscription(r.pool.QueryRow(ctx, `SELECT `+subscriptionColumns+` FROM query_subscriptions WHERE id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("error getting subscription: %w", err)
+	}
+	return s, nil
+}
+
+// ListSubscriptions returns a user's subscriptions, or everyone's when userID is nil
+func (r *Repository) ListSubscriptions(ctx context.Context, userID *int64) ([]models.Subscription, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+subscriptionColumns+`
+		FROM query_subscriptions
+		WHERE ($1::BIGINT IS NULL OR user_id = $1)
+		ORDER BY id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subscriptions := []models.Subscription{}
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, *s)
+	}
+	return subscriptions, rows.Err()
+}
+
+// UpdateSubscription saves a subscription's settings and next run
+func (r *Repository) UpdateSubscription(ctx context.Context, s *models.Subscription) error {
+	updated, err := scanSubscription(r.pool.QueryRow(ctx, `
+		UPDATE query_subscriptions
+		SET name = $2, kind = $3, query = $4, frequency = $5, at_time = $6, weekday = $7, timezone = $8,
+		    channel = $9, target = $10, max_results = $11, enabled = $12, next_run_at = $13, updated_at = NOW()
+		WHERE id = $1
+		RETURNING `+subscriptionColumns,
+		s.ID, s.Name, s.Kind, s.Query, s.Schedule.Frequency, s.Schedule.At, s.Schedule.Weekday,
+		s.Schedule.Timezone, s.Channel, s.Target, s.MaxResults, s.Enabled, s.NextRunAt))
+	if err != nil {
+		return fmt.Errorf("error updating subscription: %w", err)
+	}
+	*s = *updated
+	return nil
+}
+
+// DeleteSubscription deletes a subscription
+func (r *Repository) DeleteSubscription(ctx context.Context, id int64) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM query_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("error deleting subscription: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ListDueSubscriptions returns enabled subscriptions whose next run is at or before now
+func (r *Repository) ListDueSubscriptions(ctx context.Context, now time.Time) ([]models.Subscription, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+subscriptionColumns+`
+		FROM query_subscriptions
+		WHERE enabled AND next_run_at <= $1
+		ORDER BY next_run_at
+	`, now)
+	if err != nil {
+		return nil, fmt.Errorf("error listing due subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subscriptions []models.Subscription
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, *s)
+	}
+	return subscriptions, rows.Err()
+}
+
+// AdvanceSubscription moves a subscription's next run from due to next. It returns false when
+// another instance advanced it first, so each run is scheduled once.
+func (r *Repository) AdvanceSubscription(ctx context.Context, id int64, due, next time.Time) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE query_subscriptions SET next_run_at = $3 WHERE id = $1 AND next_run_at = $2
+	`, id, due, next)
+	if err != nil {
+		return false, fmt.Errorf("error advancing subscription: %w", err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// RecordSubscriptionRun stores the outcome of a subscription's latest run
+func (r *Repository) RecordSubscriptionRun(ctx context.Context, id int64, status string, errMsg *string, resultCount int) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE query_subscriptions
+		SET last_run_at = NOW(), last_status = $2, last_error = $3, last_result_count = $4
+		WHERE id = $1
+	`, id, status, errMsg, resultCount)
+	if err != nil {
+		return fmt.Errorf("error recording subscription run: %w", err)
+	}
+	return nil
+}
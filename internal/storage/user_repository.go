@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+	"strings"
+)
+
+// ProfileUpdate holds the optional fields a user may change on their own profile
+type ProfileUpdate struct {
+	Name             *string
+	AvatarURL        *string
+	Timezone         *string
+	DefaultProjectID *int64
+	ClearProject     bool
+	Theme            *string
+	Language         *string
+	Notifications    *models.NotificationPreferences
+}
+
+// GetUserProfile returns a user and their preferences by ID
+func (r *Repository) GetUserProfile(ctx context.Context, id int64) (*models.UserProfile, error) {
+	query := `
+		SELECT id, email, name, avatar_url, is_admin, created_at,
+		       timezone, default_project_id, theme, language, notification_preferences
+		FROM users
+		WHERE id = $1
+	`
+
+	var profile models.UserProfile
+	var avatarURL sql.NullString
+	var notificationsJSON []byte
+
+	err := r.pool.QueryRow(ctx, query, id).Scan(
+		&profile.ID,
+		&profile.Email,
+		&profile.Name,
+		&avatarURL,
+		&profile.IsAdmin,
+		&profile.CreatedAt,
+		&profile.Preferences.Timezone,
+		&profile.Preferences.DefaultProjectID,
+		&profile.Preferences.Theme,
+		&profile.Preferences.Language,
+		&notificationsJSON,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error getting user profile: %w", err)
+	}
+
+	if avatarURL.Valid {
+		profile.AvatarURL = &avatarURL.String
+	}
+	if len(notificationsJSON) > 0 {
+		json.Unmarshal(notificationsJSON, &profile.Preferences.Notifications)
+	}
+
+	return &profile, nil
+}
+
+// UpdateUserProfile applies a partial profile update for a user
+func (r *Repository) UpdateUserProfile(ctx context.Context, id int64, update ProfileUpdate) error {
+	var setParts []string
+	var args []interface{}
+	argIndex := 1
+
+	set := func(column string, value interface{}) {
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", column, argIndex))
+		args = append(args, value)
+		argIndex++
+	}
+
+	if update.Name != nil {
+		set("name", *update.Name)
+	}
+	if update.AvatarURL != nil {
+		if *update.AvatarURL == "" {
+			setParts = append(setParts, "avatar_url = NULL")
+		} else {
+			set("avatar_url", *update.AvatarURL)
+		}
+	}
+	if update.Timezone != nil {
+		set("timezone", *update.Timezone)
+	}
+	if update.ClearProject {
+		setParts = append(setParts, "default_project_id = NULL")
+	} else if update.DefaultProjectID != nil {
+		set("default_project_id", *update.DefaultProjectID)
+	}
+	if update.Theme != nil {
+		set("theme", *update.Theme)
+	}
+	if update.Language != nil {
+		set("language", *update.Language)
+	}
+	if update.Notifications != nil {
+		notificationsJSON, err := json.Marshal(update.Notifications)
+		if err != nil {
+			return fmt.Errorf("error encoding notification preferences: %w", err)
+		}
+		set("notification_preferences", notificationsJSON)
+	}
+
+	if len(setParts) == 0 {
+		return nil
+	}
+
+	args = append(args, id)
+	query := fmt.Sprintf(`
+		UPDATE users
+		SET %s
+		WHERE id = $%d
+	`, strings.Join(setParts, ", "), argIndex)
+
+	result, err := r.pool.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("error updating user profile: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("user with id %d: %w", id, ErrNotFound)
+	}
+
+	return nil
+}
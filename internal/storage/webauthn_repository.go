@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log-ingestion-service/pkg/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrCredentialExists is returned when a passkey is registered twice
+var ErrCredentialExists = errors.New("the passkey is already registered")
+
+const webAuthnCredentialColumns = `id, user_id, name, credential_id, public_key, algorithm, sign_count,
+		       transports, COALESCE(aaguid, ''), created_at, last_used_at`
+
+// CreateWebAuthnCredential stores a newly registered passkey
+func (r *Repository) CreateWebAuthnCredential(ctx context.Context, cred *models.WebAuthnCredential) error {
+	if cred.Transports == nil {
+		cred.Transports = []string{}
+	}
+	query := `
+		INSERT INTO webauthn_credentials (user_id, name, credential_id, public_key, algorithm,
+		                                  sign_count, transports, aaguid)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+		RETURNING id, created_at
+	`
+
+	err := r.pool.QueryRow(ctx, query,
+		cred.UserID,
+		cred.Name,
+		cred.CredentialID,
+		cred.PublicKey,
+		cred.Algorithm,
+		cred.SignCount,
+		cred.Transports,
+		cred.AAGUID,
+	).Scan(&cred.ID, &cred.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrCredentialExists
+		}
+		return fmt.Errorf("error creating passkey: %w", err)
+	}
+	return nil
+}
+
+// ListWebAuthnCredentials returns a user's passkeys, oldest first
+func (r *Repository) ListWebAuthnCredentials(ctx context.Context, userID int64) ([]models.WebAuthnCredential, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM webauthn_credentials
+		WHERE user_id = $1
+		ORDER BY id
+	`, webAuthnCredentialColumns)
+
+	rows, err := r.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing passkeys: %w", err)
+	}
+	defer rows.Close()
+
+	creds := []models.WebAuthnCredential{}
+	for rows.Next() {
+		cred, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		creds = append(creds, *cred)
+	}
+	return creds, rows.Err()
+}
+
+// GetWebAuthnCredentialByCredentialID returns the passkey with a WebAuthn credential ID
+func (r *Repository) GetWebAuthnCredentialByCredentialID(ctx context.Context, credentialID []byte) (*models.WebAuthnCredential, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM webauthn_credentials
+		WHERE credential_id = $1
+	`, webAuthnCredentialColumns)
+
+	cred, err := scanWebAuthnCredential(r.pool.QueryRow(ctx, query, credentialID))
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, fmt.Errorf("passkey: %w", ErrNotFound)
+		}
+		return nil, err
+	}
+	return cred, nil
+}
+
+// RecordWebAuthnLogin stores a passkey's new signature counter after a login
+func (r *Repository) RecordWebAuthnLogin(ctx context.Context, id int64, signCount int64) error {
+	query := `
+		UPDATE webauthn_credentials
+		SET sign_count = $2, last_used_at = NOW()
+		WHERE id = $1
+	`
+	if _, err := r.pool.Exec(ctx, query, id, signCount); err != nil {
+		return fmt.Errorf("error recording passkey login: %w", err)
+	}
+	return nil
+}
+
+// RenameWebAuthnCredential renames one of a user's passkeys
+func (r *Repository) RenameWebAuthnCredential(ctx context.Context, userID, id int64, name string) (*models.WebAuthnCredential, error) {
+	query := fmt.Sprintf(`
+		UPDATE webauthn_credentials
+		SET name = $3
+		WHERE id = $1 AND user_id = $2
+		RETURNING %s
+	`, webAuthnCredentialColumns)
+
+	cred, err := scanWebAuthnCredential(r.pool.QueryRow(ctx, query, id, userID, name))
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, fmt.Errorf("passkey %d: %w", id, ErrNotFound)
+		}
+		return nil, err
+	}
+	return cred, nil
+}
+
+// DeleteWebAuthnCredential removes one of a user's passkeys
+func (r *Repository) DeleteWebAuthnCredential(ctx context.Context, userID, id int64) error {
+	result, err := r.pool.Exec(ctx, `DELETE FROM webauthn_credentials WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("error deleting passkey: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("passkey %d: %w", id, ErrNotFound)
+	}
+	return nil
+}
+
+// CreateWebAuthnChallenge stores the challenge of a ceremony being started. Expired challenges
+// are pruned at the same time.
+func (r *Repository) CreateWebAuthnChallenge(ctx context.Context, challenge *models.WebAuthnChallenge) error {
+	query := `
+		WITH pruned AS (
+			DELETE FROM webauthn_challenges WHERE expires_at < NOW()
+		)
+		INSERT INTO webauthn_challenges (id, kind, challenge, user_id, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	_, err := r.pool.Exec(ctx, query,
+		challenge.ID,
+		challenge.Kind,
+		challenge.Challenge,
+		challenge.UserID,
+		challenge.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("error creating passkey challenge: %w", err)
+	}
+	return nil
+}
+
+// TakeWebAuthnChallenge deletes and returns the challenge of a ceremony of kind, so it can be
+// used once only. Nil means there is no such challenge, or it was already used.
+func (r *Repository) TakeWebAuthnChallenge(ctx context.Context, id, kind string) (*models.WebAuthnChallenge, error) {
+	query := `
+		DELETE FROM webauthn_challenges
+		WHERE id = $1 AND kind = $2
+		RETURNING id, kind, challenge, user_id, expires_at
+	`
+
+	var challenge models.WebAuthnChallenge
+	err := r.pool.QueryRow(ctx, query, id, kind).Scan(
+		&challenge.ID,
+		&challenge.Kind,
+		&challenge.Challenge,
+		&challenge.UserID,
+		&challenge.ExpiresAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error taking passkey challenge: %w", err)
+	}
+	return &challenge, nil
+}
+
+func scanWebAuthnCredential(row pgx.Row) (*models.WebAuthnCredential, error) {
+	var cred models.WebAuthnCredential
+	err := row.Scan(
+		&cred.ID,
+		&cred.UserID,
+		&cred.Name,
+		&cred.CredentialID,
+		&cred.PublicKey,
+		&cred.Algorithm,
+		&cred.SignCount,
+		&cred.Transports,
+		&cred.AAGUID,
+		&cred.CreatedAt,
+		&cred.LastUsedAt,
+	)
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error scanning passkey: %w", err)
+	}
+	return &cred, nil
+}
@@ -0,0 +1,57 @@
+package subscription
+
+import (
+	"fmt"
+	"log-ingestion-service/internal/i18n"
+	"strings"
+	"time"
+)
+
+// reportLogFields are the log fields formatReport shows
+var reportLogFields = []string{"timestamp", "level", "service", "message"}
+
+// subject is the email subject of a report in a language
+func subject(report *Report, lang string) string {
+	return i18n.Text(lang, "report_subject", report.Name, report.Total, kindName(report, lang))
+}
+
+// kindName is the name of what a report lists, such as "logs", in a language
+func kindName(report *Report, lang string) string {
+	return i18n.Text(lang, "report_kind_"+report.Kind)
+}
+
+// formatReport renders a report as plain text for email in a language. Log and fault text
+// is shown as stored.
+func formatReport(report *Report, lang string) string {
+	kind := kindName(report, lang)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", report.Name)
+	if report.Query != "" {
+		fmt.Fprintf(&b, "%s\n", i18n.Text(lang, "report_query", report.Query))
+	}
+	fmt.Fprintf(&b, "%s\n\n", i18n.Text(lang, "report_period", report.From.Format(time.RFC3339), report.To.Format(time.RFC3339)))
+
+	shown := len(report.Logs) + len(report.Faults)
+	switch {
+	case report.Total == 0:
+		fmt.Fprintf(&b, "%s\n", i18n.Text(lang, "report_none", kind))
+		return b.String()
+	case int64(shown) < report.Total:
+		fmt.Fprintf(&b, "%s\n\n", i18n.Text(lang, "report_partial", report.Total, kind, shown))
+	default:
+		fmt.Fprintf(&b, "%s\n\n", i18n.Text(lang, "report_all", report.Total, kind))
+	}
+
+	for _, entry := range report.Logs {
+		fmt.Fprintf(&b, "%s [%s] %s: %s\n", entry.Timestamp.UTC().Format(time.RFC3339), entry.Level, entry.Service, entry.Message)
+	}
+	for _, fault := range report.Faults {
+		location := ""
+		if fault.Location != nil {
+			location = i18n.Text(lang, "report_fault_location", *fault.Location)
+		}
+		fmt.Fprintf(&b, "#%d %s: %s%s\n", fault.ID, fault.ErrorClass, fault.Message, location)
+		fmt.Fprintf(&b, "    %s\n", i18n.Text(lang, "report_fault_summary", fault.Environment, fault.OccurrenceCount, fault.LastSeenAt.UTC().Format(time.RFC3339)))
+	}
+	return b.String()
+}
@@ -0,0 +1,352 @@
+package subscription
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log-ingestion-service/internal/i18n"
+	"log-ingestion-service/internal/jobs"
+	"log-ingestion-service/internal/notify"
+	"log-ingestion-service/internal/parser"
+	"log-ingestion-service/internal/storage"
+	"log-ingestion-service/pkg/models"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReportJob is the job type that runs a subscription's query and delivers the results
+const ReportJob = "subscription_report"
+
+// EventReport is the X-Event-Type of reports delivered to webhooks
+const EventReport = "subscription.report"
+
+// MaxResults bounds the results delivered per run
+const MaxResults = 1000
+
+// checkInterval is how often the scheduler looks for due subscriptions
+const checkInterval = time.Minute
+
+// Report run states recorded on a subscription
+const (
+	RunSucceeded = "succeeded"
+	RunFailed    = "failed"
+)
+
+// reportPayload is the payload of a report job: the subscription and the period it covers
+type reportPayload struct {
+	SubscriptionID int64     `json:"subscription_id"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+}
+
+// runResult is the result of a report job
+type runResult struct {
+	Results int    `json:"results"`
+	Total   int64  `json:"total"`
+	Channel string `json:"channel"`
+}
+
+// Report is the result of one subscription run, as delivered to webhooks
+type Report struct {
+	SubscriptionID int64     `json:"subscription_id"`
+	Name           string    `json:"name"`
+	Kind           string    `json:"kind"`
+	Query          string    `json:"query"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	// Total is the number of matches; at most the subscription's max_results are included
+	Total  int64             `json:"total"`
+	Logs   []models.LogEntry `json:"logs,omitempty"`
+	Faults []models.Fault    `json:"faults,omitempty"`
+}
+
+// Scheduler runs saved log and fault queries on their schedules and delivers the results by
+// email or webhook. Each due run is queued as a background job, so runs are spread across
+// instances and retried like other jobs.
+type Scheduler struct {
+	repo         *storage.Repository
+	runner       *jobs.Runner
+	mailer       *notify.Mailer
+	client       *http.Client
+	searchParser *parser.SearchParser
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+}
+
+// NewScheduler creates a scheduler and registers the report job type with runner. Webhooks time
+// out after timeout, and fault queries search SLA timers under sla.
+func NewScheduler(repo *storage.Repository, runner *jobs.Runner, mailer *notify.Mailer, timeout time.Duration, sla *models.SLAPolicy) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Scheduler{
+		repo:         repo,
+		runner:       runner,
+		mailer:       mailer,
+		client:       newWebhookClient(timeout),
+		searchParser: parser.NewSearchParser(sla),
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+	runner.Register(ReportJob, s.run)
+	return s
+}
+
+// Start begins checking for due subscriptions every minute
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+		for {
+			s.enqueueDue(s.ctx, time.Now())
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Shutdown stops the scheduler. Queued report jobs are left to the job runner.
+func (s *Scheduler) Shutdown() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Validate checks a subscription's settings before it is saved. A webhook's host must resolve to
+// public addresses only.
+func (s *Scheduler) Validate(ctx context.Context, sub *models.Subscription) error {
+	if strings.TrimSpace(sub.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	switch sub.Kind {
+	case models.SubscriptionLogs:
+		if _, err := s.searchParser.ParseLogQuery(sub.Query); err != nil {
+			return fmt.Errorf("invalid query: %w", err)
+		}
+	case models.SubscriptionFaults:
+		if _, err := s.searchParser.ParseQuery(sub.Query); err != nil {
+			return fmt.Errorf("invalid query: %w", err)
+		}
+	default:
+		return fmt.Errorf("kind must be %q or %q, got %q", models.SubscriptionLogs, models.SubscriptionFaults, sub.Kind)
+	}
+	if _, err := sub.Schedule.Validate(); err != nil {
+		return fmt.Errorf("invalid schedule: %w", err)
+	}
+	switch sub.Channel {
+	case models.ChannelEmail:
+		if !s.mailer.Enabled() {
+			return fmt.Errorf("email delivery is not configured on this server")
+		}
+		if _, err := mail.ParseAddress(sub.Target); err != nil {
+			return fmt.Errorf("target %q is not an email address", sub.Target)
+		}
+	case models.ChannelWebhook:
+		u, err := url.Parse(sub.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("target %q is not an http(s) URL", sub.Target)
+		}
+		if err := checkWebhookHost(ctx, u); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("channel must be %q or %q, got %q", models.ChannelEmail, models.ChannelWebhook, sub.Channel)
+	}
+	if sub.MaxResults < 1 || sub.MaxResults > MaxResults {
+		return fmt.Errorf("max_results must be between 1 and %d, got %d", MaxResults, sub.MaxResults)
+	}
+	return nil
+}
+
+// RunNow queues a run covering the schedule's last period up to now, outside the schedule
+func (s *Scheduler) RunNow(ctx context.Context, sub *models.Subscription, actorID *int64) (*models.Job, error) {
+	now := time.Now().UTC()
+	return s.enqueue(ctx, sub, now.Add(-sub.Schedule.Period()), now, actorID)
+}
+
+// enqueue queues a report job. Only one run of a subscription may be queued or running.
+func (s *Scheduler) enqueue(ctx context.Context, sub *models.Subscription, from, to time.Time, actorID *int64) (*models.Job, error) {
+	return s.runner.Enqueue(ctx, ReportJob, reportPayload{SubscriptionID: sub.ID, From: from, To: to}, jobs.EnqueueOptions{
+		UniqueKey: fmt.Sprintf("%s:%d", ReportJob, sub.ID),
+		CreatedBy: actorID,
+	})
+}
+
+// enqueueDue queues a run of every due subscription and moves it to its next run. Runs missed
+// while no instance was up are skipped, except the latest.
+func (s *Scheduler) enqueueDue(ctx context.Context, now time.Time) {
+	due, err := s.repo.ListDueSubscriptions(ctx, now)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("ERROR: Failed to list due subscriptions: %v", err)
+		}
+		return
+	}
+	for i := range due {
+		sub := &due[i]
+		advanced, err := s.repo.AdvanceSubscription(ctx, sub.ID, sub.NextRunAt, sub.Schedule.Next(now))
+		if err != nil {
+			log.Printf("ERROR: Failed to schedule subscription %d: %v", sub.ID, err)
+			continue
+		}
+		if !advanced {
+			// Another instance took this run
+			continue
+		}
+		to := sub.NextRunAt.UTC()
+		if _, err := s.enqueue(ctx, sub, to.Add(-sub.Schedule.Period()), to, &sub.UserID); err != nil {
+			if errors.Is(err, storage.ErrJobExists) {
+				log.Printf("WARN: Skipped run of subscription %d, the previous run has not finished", sub.ID)
+				continue
+			}
+			log.Printf("ERROR: Failed to queue run of subscription %d: %v", sub.ID, err)
+		}
+	}
+}
+
+// run is the handler of report jobs
+func (s *Scheduler) run(ctx context.Context, job *jobs.Job) (interface{}, error) {
+	var payload reportPayload
+	if err := job.Decode(&payload); err != nil {
+		return nil, err
+	}
+	sub, err := s.repo.GetSubscription(ctx, payload.SubscriptionID)
+	if err != nil {
+		if storage.IsNotFound(err) {
+			return nil, jobs.Permanent(fmt.Errorf("subscription %d was deleted", payload.SubscriptionID))
+		}
+		return nil, err
+	}
+
+	report, err := s.query(ctx, sub, payload.From, payload.To)
+	if err == nil {
+		job.Progress(0, 1, "delivering")
+		err = s.deliver(ctx, sub, report)
+	}
+
+	count := 0
+	status := RunSucceeded
+	var errMsg *string
+	if report != nil {
+		count = len(report.Logs) + len(report.Faults)
+	}
+	if err != nil {
+		status = RunFailed
+		msg := err.Error()
+		errMsg = &msg
+	}
+	if recErr := s.repo.RecordSubscriptionRun(ctx, sub.ID, status, errMsg, count); recErr != nil {
+		log.Printf("ERROR: Failed to record run of subscription %d: %v", sub.ID, recErr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	job.Progress(1, 1, "delivered")
+	return &runResult{Results: count, Total: report.Total, Channel: sub.Channel}, nil
+}
+
+// query runs a subscription's query over a period. Logs are those logged in the period; faults
+// are those seen since its start.
+func (s *Scheduler) query(ctx context.Context, sub *models.Subscription, from, to time.Time) (*Report, error) {
+	report := &Report{
+		SubscriptionID: sub.ID,
+		Name:           sub.Name,
+		Kind:           sub.Kind,
+		Query:          sub.Query,
+		From:           from,
+		To:             to,
+	}
+	switch sub.Kind {
+	case models.SubscriptionLogs:
+		filters, err := s.searchParser.ParseLogQuery(sub.Query)
+		if err != nil {
+			return nil, jobs.Permanent(fmt.Errorf("invalid query: %w", err))
+		}
+		filters.From = from
+		filters.To = to
+		filters.Limit = sub.MaxResults
+		if sub.Channel == models.ChannelEmail {
+			// Emails show a line per log, so their metadata is not read
+			filters.Fields = reportLogFields
+		}
+		report.Logs, report.Total, err = s.repo.SearchLogs(ctx, *filters)
+		if err != nil {
+			return nil, err
+		}
+	case models.SubscriptionFaults:
+		filters, err := s.searchParser.ParseQuery(sub.Query)
+		if err != nil {
+			return nil, jobs.Permanent(fmt.Errorf("invalid query: %w", err))
+		}
+		filters.SeenAfter = &from
+		filters.Limit = sub.MaxResults
+		filters.Offset = 0
+		report.Faults, report.Total, err = s.repo.ListFaults(ctx, *filters)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, jobs.Permanent(fmt.Errorf("unknown subscription kind %q", sub.Kind))
+	}
+	return report, nil
+}
+
+// deliver sends a report through the subscription's channel
+func (s *Scheduler) deliver(ctx context.Context, sub *models.Subscription, report *Report) error {
+	switch sub.Channel {
+	case models.ChannelEmail:
+		lang := s.language(ctx, sub.UserID)
+		return s.mailer.Send(ctx, []string{sub.Target}, subject(report, lang), formatReport(report, lang))
+	case models.ChannelWebhook:
+		return s.post(ctx, sub.Target, report)
+	}
+	return jobs.Permanent(fmt.Errorf("unknown channel %q", sub.Channel))
+}
+
+// language returns the language a user reads reports in, falling back to the default when
+// their profile cannot be read, so a report is still sent
+func (s *Scheduler) language(ctx context.Context, userID int64) string {
+	profile, err := s.repo.GetUserProfile(ctx, userID)
+	if err != nil {
+		log.Printf("WARN: Failed to get language of user %d, sending report in %s: %v", userID, i18n.Default, err)
+		return i18n.Default
+	}
+	if !i18n.Supported(profile.Preferences.Language) {
+		return i18n.Default
+	}
+	return profile.Preferences.Language
+}
+
+// post sends a report as JSON to a webhook, treating any non-2xx response as a failure
+func (s *Scheduler) post(ctx context.Context, target string, report *Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("error encoding report: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return jobs.Permanent(fmt.Errorf("error creating webhook request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Type", EventReport)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
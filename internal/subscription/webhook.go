@@ -0,0 +1,80 @@
+package subscription
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// errNonPublicAddress is returned when a webhook would connect to an address reports may not be
+// sent to
+var errNonPublicAddress = errors.New("webhook address is not public")
+
+// nonPublicPrefixes are ranges that IsGlobalUnicast and IsPrivate let through but that do not
+// reach the public internet: "this network", carrier-grade NAT and benchmarking
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+}
+
+// publicAddr reports whether reports may be delivered to addr. Any user can set a webhook, so
+// loopback, private, link-local and other internal addresses are refused, keeping the server
+// from being used to reach internal services or cloud metadata endpoints.
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkWebhookHost resolves a webhook's host and refuses it when any of its addresses is not
+// public. The addresses are checked again when connecting, as they may change.
+func checkWebhookHost(ctx context.Context, u *url.URL) error {
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("target host %q does not resolve: %w", u.Hostname(), err)
+	}
+	for _, addr := range addrs {
+		if !publicAddr(addr) {
+			return fmt.Errorf("target host %q resolves to %s: %w", u.Hostname(), addr.Unmap(), errNonPublicAddress)
+		}
+	}
+	return nil
+}
+
+// newWebhookClient returns a client that only connects to public addresses. The address is
+// checked as the connection is made, after resolution, so neither a host whose addresses changed
+// since it was validated nor a redirect can reach an internal one. Proxies are not used, as the
+// check would then apply to the proxy rather than the webhook.
+func newWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddr(addrPort.Addr()) {
+				return fmt.Errorf("%w: %s", errNonPublicAddress, addrPort.Addr().Unmap())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,190 @@
+// Package swr caches the results of expensive queries with stale-while-revalidate: a fresh
+// result is served as is, a stale one is served at once while it is recomputed in the
+// background, and only a missing or expired one makes the caller wait.
+package swr
+
+import (
+	"context"
+	"log"
+	"log-ingestion-service/pkg/config"
+	"sync"
+	"time"
+)
+
+// Statuses of a lookup
+const (
+	// StatusHit served a fresh result
+	StatusHit = "HIT"
+	// StatusStale served a stale result while a refresh runs in the background
+	StatusStale = "STALE"
+	// StatusMiss computed the result, as none was cached or it had expired
+	StatusMiss = "MISS"
+	// StatusBypass computed the result because the caller asked for a fresh one
+	StatusBypass = "BYPASS"
+)
+
+// Loader computes a result
+type Loader func(ctx context.Context) (interface{}, error)
+
+// Status describes how a result was served
+type Status struct {
+	Status string
+	// Age is how long ago the result was computed
+	Age time.Duration
+}
+
+// entry is a cached result with the loader that recomputes it
+type entry struct {
+	value      interface{}
+	loadedAt   time.Time
+	load       Loader
+	refreshing bool
+}
+
+// call is a load in progress, which concurrent misses for the same key wait for
+type call struct {
+	done  chan struct{}
+	value interface{}
+	err   error
+}
+
+// Cache holds results by key. A nil cache computes every result.
+type Cache struct {
+	ttl            time.Duration
+	maxStale       time.Duration
+	maxEntries     int
+	refreshTimeout time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]*entry
+	inflight map[string]*call
+}
+
+// New creates a cache as configured, or returns nil when caching is off. Background refreshes
+// are bounded by refreshTimeout, unless it is zero.
+func New(cfg *config.AnalyticsCacheConfig, refreshTimeout time.Duration) *Cache {
+	if !cfg.Enabled {
+		return nil
+	}
+	return &Cache{
+		ttl:            cfg.TTL,
+		maxStale:       cfg.MaxStale,
+		maxEntries:     cfg.MaxEntries,
+		refreshTimeout: refreshTimeout,
+		entries:        make(map[string]*entry),
+		inflight:       make(map[string]*call),
+	}
+}
+
+// Cached reports whether a result for key can be served without computing it
+func (c *Cache) Cached(key string) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	return ok && time.Since(e.loadedAt) < c.ttl+c.maxStale
+}
+
+// Get returns the result for key: the cached one when fresh, the cached one while a refresh
+// starts when stale, and otherwise the one computed by load. With bypass the result is always
+// computed, and cached for the next caller.
+func (c *Cache) Get(ctx context.Context, key string, bypass bool, load Loader) (interface{}, Status, error) {
+	if c == nil {
+		value, err := load(ctx)
+		return value, Status{Status: StatusBypass}, err
+	}
+
+	if !bypass {
+		c.mu.Lock()
+		if e, ok := c.entries[key]; ok {
+			age := time.Since(e.loadedAt)
+			switch {
+			case age < c.ttl:
+				c.mu.Unlock()
+				return e.value, Status{Status: StatusHit, Age: age}, nil
+			case age < c.ttl+c.maxStale:
+				if !e.refreshing {
+					e.refreshing = true
+					go c.refresh(key, e.load)
+				}
+				c.mu.Unlock()
+				return e.value, Status{Status: StatusStale, Age: age}, nil
+			}
+		}
+		c.mu.Unlock()
+	}
+
+	value, err := c.load(ctx, key, load)
+	status := Status{Status: StatusMiss}
+	if bypass {
+		status.Status = StatusBypass
+	}
+	return value, status, err
+}
+
+// load computes the result for key, sharing the work with concurrent callers for the same key
+func (c *Cache) load(ctx context.Context, key string, load Loader) (interface{}, error) {
+	c.mu.Lock()
+	if inflight, ok := c.inflight[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-inflight.done:
+			return inflight.value, inflight.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	current := &call{done: make(chan struct{})}
+	c.inflight[key] = current
+	c.mu.Unlock()
+
+	current.value, current.err = load(ctx)
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if current.err == nil {
+		c.store(key, current.value, load)
+	}
+	c.mu.Unlock()
+	close(current.done)
+	return current.value, current.err
+}
+
+// refresh recomputes a stale result in the background. On failure the stale result is kept
+// until it expires.
+func (c *Cache) refresh(key string, load Loader) {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if c.refreshTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.refreshTimeout)
+	}
+	defer cancel()
+	value, err := load(ctx)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		log.Printf("WARN: Failed to refresh cached %s: %v", key, err)
+		if e, ok := c.entries[key]; ok {
+			e.refreshing = false
+		}
+		return
+	}
+	c.store(key, value, load)
+}
+
+// store caches a result, evicting the oldest one when the cache is full; callers hold c.mu
+func (c *Cache) store(key string, value interface{}, load Loader) {
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		var oldest string
+		var oldestAt time.Time
+		for k, e := range c.entries {
+			if oldest == "" || e.loadedAt.Before(oldestAt) {
+				oldest, oldestAt = k, e.loadedAt
+			}
+		}
+		delete(c.entries, oldest)
+	}
+	c.entries[key] = &entry{value: value, loadedAt: time.Now(), load: load}
+}
@@ -0,0 +1,146 @@
+// Package useragent recognizes the browser, operating system and kind of device behind a
+// User-Agent header. It looks for well-known tokens rather than fully parsing the header, which
+// is enough to tell the common browsers, platforms and crawlers apart.
+package useragent
+
+import (
+	"strings"
+)
+
+// Device kinds
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+	DeviceOther   = "other"
+)
+
+// Agent is what a user agent string says about its client. Fields that could not be
+// recognized are empty.
+type Agent struct {
+	Browser        string `json:"browser,omitempty"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OS             string `json:"os,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	Device         string `json:"device,omitempty"`
+}
+
+// Bot reports whether the agent is a crawler or other automated client
+func (a Agent) Bot() bool {
+	return a.Device == DeviceBot
+}
+
+// browsers and platforms are matched in order, so more specific tokens come first: crawlers
+// and Edge and Opera also claim to be Chrome, and Chrome claims to be Safari. The version follows the
+// version prefix, or the token when there is none.
+var (
+	browsers = []struct{ token, name, version string }{
+		{"Googlebot/", "Googlebot", ""},
+		{"bingbot/", "Bingbot", ""},
+		{"Edg/", "Edge", ""},
+		{"EdgA/", "Edge", ""},
+		{"EdgiOS/", "Edge", ""},
+		{"OPR/", "Opera", ""},
+		{"SamsungBrowser/", "Samsung Internet", ""},
+		{"Firefox/", "Firefox", ""},
+		{"FxiOS/", "Firefox", ""},
+		{"CriOS/", "Chrome", ""},
+		{"Chrome/", "Chrome", ""},
+		{"Safari/", "Safari", "Version/"},
+		{"curl/", "curl", ""},
+		{"Wget/", "Wget", ""},
+		{"python-requests/", "python-requests", ""},
+		{"Go-http-client/", "Go", ""},
+	}
+	platforms = []struct{ token, name, version string }{
+		{"iPhone", "iOS", "iPhone OS "},
+		{"iPad", "iPadOS", "CPU OS "},
+		{"Android", "Android", "Android "},
+		{"Windows", "Windows", "Windows NT "},
+		{"Mac OS X", "macOS", "Mac OS X "},
+		{"CrOS", "ChromeOS", ""},
+		{"Linux", "Linux", ""},
+	}
+	// windowsVersions maps Windows NT kernel versions to the release names people know
+	windowsVersions = map[string]string{
+		"10.0": "10",
+		"6.3":  "8.1",
+		"6.2":  "8",
+		"6.1":  "7",
+	}
+	// botTokens are matched case-insensitively
+	botTokens = []string{"bot", "crawler", "spider", "slurp", "headless"}
+)
+
+// Parse recognizes the browser, platform and device of a user agent string
+func Parse(userAgent string) Agent {
+	var agent Agent
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.token) {
+			agent.Browser = b.name
+			agent.BrowserVersion = versionAfter(userAgent, b.token, b.version)
+			break
+		}
+	}
+	for _, p := range platforms {
+		if strings.Contains(userAgent, p.token) {
+			agent.OS = p.name
+			agent.OSVersion = versionAfter(userAgent, p.token, p.version)
+			if p.name == "Windows" {
+				if name, known := windowsVersions[agent.OSVersion]; known {
+					agent.OSVersion = name
+				}
+			}
+			break
+		}
+	}
+	agent.Device = device(userAgent, agent)
+	return agent
+}
+
+// device classifies the client, trusting bot tokens over everything else
+func device(userAgent string, agent Agent) string {
+	lower := strings.ToLower(userAgent)
+	for _, token := range botTokens {
+		if strings.Contains(lower, token) {
+			return DeviceBot
+		}
+	}
+	switch {
+	case agent.OS == "iPadOS" || strings.Contains(userAgent, "Tablet") ||
+		(agent.OS == "Android" && !strings.Contains(userAgent, "Mobile")):
+		return DeviceTablet
+	case agent.OS == "iOS" || strings.Contains(userAgent, "Mobi"):
+		return DeviceMobile
+	case agent.OS != "" && agent.Browser != "":
+		return DeviceDesktop
+	case userAgent == "":
+		return ""
+	default:
+		return DeviceOther
+	}
+}
+
+// versionAfter returns the dotted version following prefix, or following token when there is
+// no prefix. Underscores, as in "Mac OS X 10_15_7", become dots.
+func versionAfter(userAgent, token, prefix string) string {
+	if prefix == "" {
+		prefix = token
+	}
+	i := strings.Index(userAgent, prefix)
+	if i < 0 {
+		return ""
+	}
+	i += len(prefix)
+
+	end := i
+	for end < len(userAgent) {
+		c := userAgent[end]
+		if (c < '0' || c > '9') && c != '.' && c != '_' {
+			break
+		}
+		end++
+	}
+	return strings.ReplaceAll(strings.Trim(userAgent[i:end], "._"), "_", ".")
+}
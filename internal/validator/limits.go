@@ -0,0 +1,171 @@
+package validator
+
+import (
+	"encoding/json"
+	"log-ingestion-service/pkg/config"
+	"sort"
+)
+
+// TruncatedValue replaces values nested too deep or too large to keep
+const TruncatedValue = "[TRUNCATED]"
+
+// TruncatedField lists, in truncated metadata, which limits it exceeded
+const TruncatedField = "metadata_truncated"
+
+// Limits exceeded by truncated metadata
+const (
+	LimitKeys  = "keys"
+	LimitDepth = "depth"
+	LimitBytes = "bytes"
+)
+
+// MetadataLimiter truncates metadata exceeding the configured key count, depth and size
+type MetadataLimiter struct {
+	maxKeys  int
+	maxDepth int
+	maxBytes int
+}
+
+// NewMetadataLimiter creates a limiter enforcing cfg
+func NewMetadataLimiter(cfg *config.MetadataLimitConfig) *MetadataLimiter {
+	return &MetadataLimiter{maxKeys: cfg.MaxKeys, maxDepth: cfg.MaxDepth, maxBytes: cfg.MaxBytes}
+}
+
+// Limit truncates metadata in place to the limits, returning the limits it exceeded. Truncated
+// metadata lists them under TruncatedField. Depth is enforced first, then the key count, so
+// the keys nearest the top are kept, then the size, by replacing the largest top-level values
+// and finally dropping keys.
+func (l *MetadataLimiter) Limit(metadata map[string]interface{}) []string {
+	if l == nil || len(metadata) == 0 {
+		return nil
+	}
+	var exceeded []string
+	if l.limitDepth(metadata, 1) {
+		exceeded = append(exceeded, LimitDepth)
+	}
+	if l.limitKeys(metadata) {
+		exceeded = append(exceeded, LimitKeys)
+	}
+	if l.limitBytes(metadata) {
+		exceeded = append(exceeded, LimitBytes)
+	}
+	if len(exceeded) > 0 {
+		metadata[TruncatedField] = exceeded
+	}
+	return exceeded
+}
+
+// limitDepth replaces the objects and arrays nested below the maximum depth, reporting
+// whether any were
+func (l *MetadataLimiter) limitDepth(value interface{}, depth int) bool {
+	truncated := false
+	replace := func(child interface{}) (interface{}, bool) {
+		switch child.(type) {
+		case map[string]interface{}, []interface{}:
+			if depth >= l.maxDepth {
+				return TruncatedValue, true
+			}
+			return child, l.limitDepth(child, depth+1)
+		}
+		return child, false
+	}
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, child := range value {
+			var cut bool
+			if value[key], cut = replace(child); cut {
+				truncated = true
+			}
+		}
+	case []interface{}:
+		for i, child := range value {
+			var cut bool
+			if value[i], cut = replace(child); cut {
+				truncated = true
+			}
+		}
+	}
+	return truncated
+}
+
+// limitKeys drops the keys past the maximum count, visiting objects breadth first and keys in
+// sorted order, reporting whether any were dropped
+func (l *MetadataLimiter) limitKeys(metadata map[string]interface{}) bool {
+	remaining := l.maxKeys
+	truncated := false
+	queue := []interface{}{metadata}
+	for len(queue) > 0 {
+		value := queue[0]
+		queue = queue[1:]
+		switch value := value.(type) {
+		case map[string]interface{}:
+			for _, key := range sortedKeys(value) {
+				if remaining == 0 {
+					delete(value, key)
+					truncated = true
+					continue
+				}
+				remaining--
+				queue = append(queue, value[key])
+			}
+		case []interface{}:
+			queue = append(queue, value...)
+		}
+	}
+	return truncated
+}
+
+// limitBytes replaces the largest top-level values until the metadata's JSON fits the maximum
+// size, dropping keys if that is not enough, and reports whether anything was replaced
+func (l *MetadataLimiter) limitBytes(metadata map[string]interface{}) bool {
+	encoded, err := json.Marshal(metadata)
+	if err != nil || len(encoded) <= l.maxBytes {
+		return false
+	}
+	size := len(encoded)
+
+	sizes := make(map[string]int, len(metadata))
+	keys := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		v, _ := json.Marshal(value)
+		sizes[key] = len(v)
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if sizes[keys[i]] != sizes[keys[j]] {
+			return sizes[keys[i]] > sizes[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	// Room for the marker listing the exceeded limits
+	budget := l.maxBytes - len(`,"`+TruncatedField+`":["depth","keys","bytes"]`)
+	replaced := len(`"` + TruncatedValue + `"`)
+	for _, key := range keys {
+		if size <= budget {
+			break
+		}
+		if sizes[key] > replaced {
+			metadata[key] = TruncatedValue
+			size -= sizes[key] - replaced
+			sizes[key] = replaced
+		}
+	}
+	// Too many keys for even their truncated values to fit; drop the last ones
+	sort.Strings(keys)
+	for i := len(keys) - 1; i >= 0 && size > budget; i-- {
+		k, _ := json.Marshal(keys[i])
+		size -= len(k) + 1 + sizes[keys[i]] + 1
+		delete(metadata, keys[i])
+	}
+	return true
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
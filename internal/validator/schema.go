@@ -0,0 +1,56 @@
+package validator
+
+import (
+	"log-ingestion-service/internal/jsonschema"
+	"log-ingestion-service/pkg/models"
+	"strings"
+)
+
+// SchemaViolationsField lists, in the metadata of a log accepted despite failing a schema in
+// flag mode, how it fails
+const SchemaViolationsField = "schema_violations"
+
+// MetadataSchema is a JSON schema the metadata of logs must satisfy
+type MetadataSchema struct {
+	Schema *jsonschema.Schema
+	// Flag accepts logs failing the schema, listing the violations under
+	// SchemaViolationsField, rather than rejecting them
+	Flag bool
+}
+
+// SchemaSource provides the schemas for the logs of a service sent with an API key, which may
+// change as schemas are edited
+type SchemaSource interface {
+	MetadataSchemas(service, apiKey string) []*MetadataSchema
+}
+
+// checkSchemas checks a log's metadata against the schemas for its service and API key. The
+// first rejecting schema that fails rejects the log; failures of flagging schemas are
+// recorded in its metadata.
+func (v *Validator) checkSchemas(logEntry *models.LogEntry, apiKey string) error {
+	if v.schemas == nil {
+		return nil
+	}
+	var flagged []string
+	for _, schema := range v.schemas.MetadataSchemas(logEntry.Service, apiKey) {
+		var metadata interface{} = logEntry.Metadata
+		if logEntry.Metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+		violations := schema.Schema.Validate(metadata, "metadata")
+		if len(violations) == 0 {
+			continue
+		}
+		if !schema.Flag {
+			return invalid(ReasonSchemaViolation, "metadata does not match the schema: %s", strings.Join(violations, "; "))
+		}
+		flagged = append(flagged, violations...)
+	}
+	if len(flagged) > 0 {
+		if logEntry.Metadata == nil {
+			logEntry.Metadata = make(map[string]interface{})
+		}
+		logEntry.Metadata[SchemaViolationsField] = flagged
+	}
+	return nil
+}
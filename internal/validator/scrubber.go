@@ -0,0 +1,297 @@
+package validator
+
+import (
+	"encoding/json"
+	"fmt"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FilteredValue replaces sensitive substrings found in metadata values
+const FilteredValue = "[FILTERED]"
+
+// maxScrubDepth bounds recursion into deeply nested metadata
+const maxScrubDepth = 32
+
+// wildcard matches any key or array element in a path
+const wildcard = "*"
+
+// Scrubber masks sensitive keys, paths and values in nested metadata
+type Scrubber struct {
+	keyPatterns   []*regexp.Regexp
+	valuePatterns []*regexp.Regexp
+	paths         [][]string
+}
+
+// ScrubberSource provides the scrubber for logs, which may change as redaction rules are edited
+type ScrubberSource interface {
+	LogScrubber() *Scrubber
+}
+
+// LogScrubber returns s, so a fixed scrubber can serve as a ScrubberSource
+func (s *Scrubber) LogScrubber() *Scrubber {
+	return s
+}
+
+// NewScrubber compiles the configured key and value patterns and paths.
+// Key patterns are matched case-insensitively against every key at any depth.
+func NewScrubber(cfg *config.ScrubConfig) (*Scrubber, error) {
+	s := &Scrubber{}
+	for _, path := range cfg.Paths {
+		segments, err := ParsePath(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scrub path %q: %w", path, err)
+		}
+		s.paths = append(s.paths, segments)
+	}
+	for _, pattern := range cfg.KeyPatterns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scrub key pattern %q: %w", pattern, err)
+		}
+		s.keyPatterns = append(s.keyPatterns, re)
+	}
+	for _, pattern := range cfg.ValuePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scrub value pattern %q: %w", pattern, err)
+		}
+		s.valuePatterns = append(s.valuePatterns, re)
+	}
+	return s, nil
+}
+
+// ParsePath splits a JSON path such as "metadata.user.password", "$.params.cards[*].number" or
+// "session.*.token" into its keys, with "*" matching any key or array element and a number in
+// brackets a single element
+func ParsePath(path string) ([]string, error) {
+	path = strings.TrimPrefix(strings.TrimSpace(path), "$.")
+	if path == "" {
+		return nil, fmt.Errorf("path is empty")
+	}
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		key, index, hasIndex := strings.Cut(part, "[")
+		if key == "" && !hasIndex {
+			return nil, fmt.Errorf("path has an empty key")
+		}
+		if key != "" {
+			segments = append(segments, key)
+		}
+		for hasIndex {
+			var rest string
+			index, rest, hasIndex = strings.Cut(index, "]")
+			if !hasIndex {
+				return nil, fmt.Errorf("path has an unclosed [")
+			}
+			if _, err := strconv.Atoi(index); err != nil && index != wildcard {
+				return nil, fmt.Errorf("array index %q is not a number or *", index)
+			}
+			segments = append(segments, index)
+			if rest == "" {
+				break
+			}
+			if !strings.HasPrefix(rest, "[") {
+				return nil, fmt.Errorf("unexpected %q after ]", rest)
+			}
+			index, hasIndex = rest[1:], true
+		}
+	}
+	return segments, nil
+}
+
+// Scrub masks sensitive keys, paths and values in log metadata, recursing into nested maps and
+// arrays. Paths start at "metadata". It returns the dotted paths, within metadata, of
+// everything it masked.
+func (s *Scrubber) Scrub(metadata map[string]interface{}) []string {
+	paths := s.ScrubSections(map[string]interface{}{"metadata": metadata})
+	for i, path := range paths {
+		paths[i] = strings.TrimPrefix(path, "metadata.")
+	}
+	return paths
+}
+
+// ScrubNotice masks sensitive keys, paths and values in a notice's context, params, session,
+// cookies and environment, which paths start at, and in its breadcrumb metadata and backtrace
+// variables. It returns the dotted paths of everything it masked.
+func (s *Scrubber) ScrubNotice(notice *models.Notice) []string {
+	sections := map[string]interface{}{
+		"context":     notice.Context,
+		"params":      notice.Params,
+		"session":     notice.Session,
+		"cookies":     notice.Cookies,
+		"environment": notice.Environment,
+	}
+	paths := s.ScrubSections(sections)
+	// A section may have been masked whole by a path or key rule
+	notice.Context = sectionMap(sections, "context", notice.Context)
+	notice.Params = sectionMap(sections, "params", notice.Params)
+	notice.Session = sectionMap(sections, "session", notice.Session)
+	notice.Cookies = sectionMap(sections, "cookies", notice.Cookies)
+	notice.Environment = sectionMap(sections, "environment", notice.Environment)
+
+	var more []string
+	for i := range notice.Breadcrumbs {
+		s.scrubMap(notice.Breadcrumbs[i].Metadata, "breadcrumbs["+strconv.Itoa(i)+"].metadata", 0, &more)
+	}
+	for i := range notice.Backtrace {
+		s.scrubMap(notice.Backtrace[i].Vars, "backtrace["+strconv.Itoa(i)+"].vars", 0, &more)
+	}
+	sort.Strings(more)
+	return append(paths, more...)
+}
+
+// ScrubSections masks sensitive data in named sections, such as a log's metadata or a notice's
+// params: the values at the configured paths, which start with a section name, then the values
+// of sensitive keys and the sensitive parts of strings anywhere. It returns the dotted paths of
+// everything it masked.
+func (s *Scrubber) ScrubSections(sections map[string]interface{}) []string {
+	var paths []string
+	for _, path := range s.paths {
+		maskPath(sections, path, "", &paths)
+	}
+	s.scrubMap(sections, "", -1, &paths)
+	sort.Strings(paths)
+	return paths
+}
+
+// sectionMap returns a section after scrubbing: the original map, or nil when the section was
+// masked whole, since notice sections must stay objects
+func sectionMap(sections map[string]interface{}, name string, original map[string]interface{}) map[string]interface{} {
+	if m, ok := sections[name].(map[string]interface{}); ok {
+		return m
+	}
+	if original == nil {
+		return nil
+	}
+	return map[string]interface{}{}
+}
+
+// maskPath replaces the values at a path with the filtered placeholder
+func maskPath(value interface{}, path []string, prefix string, paths *[]string) {
+	if len(path) == 0 {
+		return
+	}
+	key, rest := path[0], path[1:]
+	visit := func(child interface{}, childPath string, set func(interface{})) {
+		if len(rest) > 0 {
+			maskPath(child, rest, childPath, paths)
+			return
+		}
+		if child != FilteredValue {
+			set(FilteredValue)
+			*paths = append(*paths, childPath)
+		}
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if key == wildcard || key == k {
+				k := k
+				visit(child, joinPath(prefix, k), func(masked interface{}) { v[k] = masked })
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			if key == wildcard || key == strconv.Itoa(i) {
+				i := i
+				visit(child, prefix+"["+strconv.Itoa(i)+"]", func(masked interface{}) { v[i] = masked })
+			}
+		}
+	}
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// ScrubPayload removes sensitive data from a request payload kept for debugging. JSON payloads
+// are scrubbed like metadata; anything else has sensitive values masked as text.
+func (s *Scrubber) ScrubPayload(data []byte) []byte {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err == nil {
+		var paths []string
+		if scrubbed, changed := s.scrubValue(value, "", 0, &paths); changed {
+			value = scrubbed
+		}
+		if out, err := json.Marshal(value); err == nil {
+			return out
+		}
+	}
+	text := string(data)
+	for _, re := range s.valuePatterns {
+		text = re.ReplaceAllString(text, FilteredValue)
+	}
+	return []byte(text)
+}
+
+// ScrubText masks sensitive values in free text, such as a log message, reporting whether any were found
+func (s *Scrubber) ScrubText(text string) (string, bool) {
+	masked := text
+	for _, re := range s.valuePatterns {
+		masked = re.ReplaceAllString(masked, FilteredValue)
+	}
+	return masked, masked != text
+}
+
+// scrubMap masks the values of sensitive keys and scrubs the others. Sections are scrubbed at
+// depth -1, so their names are not taken for keys.
+func (s *Scrubber) scrubMap(m map[string]interface{}, prefix string, depth int, paths *[]string) {
+	if depth > maxScrubDepth {
+		return
+	}
+	for key, value := range m {
+		path := joinPath(prefix, key)
+		if depth >= 0 && s.sensitiveKey(key) {
+			if value != FilteredValue {
+				m[key] = FilteredValue
+				*paths = append(*paths, path)
+			}
+			continue
+		}
+		if scrubbed, changed := s.scrubValue(value, path, depth, paths); changed {
+			m[key] = scrubbed
+			*paths = append(*paths, path)
+		}
+	}
+}
+
+// scrubValue recurses into containers and masks strings, reporting whether a string was replaced
+func (s *Scrubber) scrubValue(value interface{}, path string, depth int, paths *[]string) (interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		s.scrubMap(v, path, depth+1, paths)
+	case []interface{}:
+		for i, item := range v {
+			itemPath := path + "[" + strconv.Itoa(i) + "]"
+			if scrubbed, changed := s.scrubValue(item, itemPath, depth+1, paths); changed {
+				v[i] = scrubbed
+				*paths = append(*paths, itemPath)
+			}
+		}
+	case string:
+		masked := v
+		for _, re := range s.valuePatterns {
+			masked = re.ReplaceAllString(masked, FilteredValue)
+		}
+		return masked, masked != v
+	}
+	return value, false
+}
+
+func (s *Scrubber) sensitiveKey(key string) bool {
+	for _, re := range s.keyPatterns {
+		if re.MatchString(key) {
+			return true
+		}
+	}
+	return false
+}
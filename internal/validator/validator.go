@@ -2,102 +2,249 @@ package validator
 
 import (
 	"fmt"
+	"log-ingestion-service/pkg/config"
 	"log-ingestion-service/pkg/models"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
+var (
+	// ansiEscapePattern matches CSI sequences (colors, cursor movement) and OSC sequences (titles, hyperlinks)
+	ansiEscapePattern = regexp.MustCompile(`\x1b\[[0-9;?]*[ -/]*[@-~]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[@-Z\\-_]`)
+	controlCharPattern = regexp.MustCompile(`[\x00-\x08\x0B-\x0C\x0E-\x1F]`)
+	serviceNamePattern = regexp.MustCompile(`[^a-zA-Z0-9\-_]`)
+)
+
+// Reasons a log entry fails validation
+const (
+	ReasonTimestampMissing = "timestamp_missing"
+	ReasonTimestampFuture  = "timestamp_future"
+	ReasonTimestampPast    = "timestamp_past"
+	ReasonServiceMissing   = "service_missing"
+	ReasonServiceTooLong   = "service_too_long"
+	ReasonInvalidLevel     = "invalid_level"
+	ReasonMessageMissing   = "message_missing"
+	ReasonSchemaViolation  = "schema_violation"
+)
+
+// ValidationError is returned by Validate, classifying the failure for reject metrics
+type ValidationError struct {
+	Reason  string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+func invalid(reason, format string, args ...interface{}) error {
+	return &ValidationError{Reason: reason, Message: fmt.Sprintf(format, args...)}
+}
+
+// truncationMarker is appended to messages cut to the maximum length
+const truncationMarker = "…"
+
+// Modifications records what Sanitize changed in a log entry
+type Modifications struct {
+	InvalidUTF8     bool     `json:"invalid_utf8,omitempty"`
+	ANSIStripped    bool     `json:"ansi_stripped,omitempty"`
+	ControlStripped bool     `json:"control_chars_stripped,omitempty"`
+	Truncated       bool     `json:"truncated,omitempty"`
+	OriginalLength  int      `json:"original_length,omitempty"`
+	ServiceRenamed  bool     `json:"service_renamed,omitempty"`
+	ScrubbedFields  []string `json:"scrubbed_fields,omitempty"`
+	// MetadataTruncated lists the metadata limits exceeded: keys, depth or bytes
+	MetadataTruncated []string `json:"metadata_truncated,omitempty"`
+}
+
+// Modified reports whether anything was changed
+func (m Modifications) Modified() bool {
+	return m.InvalidUTF8 || m.ANSIStripped || m.ControlStripped || m.Truncated || m.ServiceRenamed || len(m.ScrubbedFields) > 0 || len(m.MetadataTruncated) > 0
+}
+
+// levelAliases maps common non-canonical level names to canonical levels
+var levelAliases = map[string]string{
+	"TRACE":         "DEBUG",
+	"VERBOSE":       "DEBUG",
+	"INFORMATION":   "INFO",
+	"INFORMATIONAL": "INFO",
+	"NOTICE":        "INFO",
+	"ERR":           "ERROR",
+	"CRIT":          "CRITICAL",
+	"ALERT":         "FATAL",
+	"EMERG":         "FATAL",
+	"EMERGENCY":     "FATAL",
+	"PANIC":         "FATAL",
+}
+
 // Validator validates log entries
 type Validator struct {
 	maxMessageLength int
 	maxServiceLength int
-	allowedLevels    map[string]bool
+	// levels maps every accepted level, uppercased, to the canonical level it is stored as
+	levels    map[string]string
+	scrubbers ScrubberSource
+	schemas   SchemaSource
+	limits    *MetadataLimiter
 }
 
-// NewValidator creates a new validator
-func NewValidator() *Validator {
+// NewValidator creates a new validator that masks sensitive metadata with the log scrubber of
+// scrubbers, maps levels as configured, checks metadata against the schemas from schemas and
+// truncates it to limits
+func NewValidator(scrubbers ScrubberSource, cfg *config.LevelConfig, schemas SchemaSource, limits *MetadataLimiter) *Validator {
+	levels := make(map[string]string)
+	for _, level := range config.CanonicalLevels {
+		levels[level] = level
+	}
+	for name, level := range levelAliases {
+		levels[name] = level
+	}
+	for severity, level := range cfg.SyslogSeverities {
+		levels[strconv.Itoa(severity)] = strings.ToUpper(level)
+	}
+	for name, level := range cfg.Aliases {
+		levels[strings.ToUpper(strings.TrimSpace(name))] = strings.ToUpper(level)
+	}
+	
 	return &Validator{
 		maxMessageLength: 10000, // 10KB max message length
 		maxServiceLength: 255,
-		allowedLevels: map[string]bool{
-			"DEBUG":    true,
-			"INFO":     true,
-			"WARN":     true,
-			"WARNING":  true,
-			"ERROR":    true,
-			"FATAL":    true,
-			"CRITICAL": true,
-		},
+		scrubbers:        scrubbers,
+		levels:           levels,
+		schemas:          schemas,
+		limits:           limits,
 	}
 }
 
-// Validate validates a log entry
+// Validate validates a log entry, checking its metadata against its service's schemas
 func (v *Validator) Validate(logEntry *models.LogEntry) error {
+	return v.ValidateFrom(logEntry, "")
+}
+
+// ValidateFrom validates a log entry sent with an API key, checking its metadata against the
+// schemas of its service and of the key
+func (v *Validator) ValidateFrom(logEntry *models.LogEntry, apiKey string) error {
+	if err := v.validateFields(logEntry); err != nil {
+		return err
+	}
+	return v.checkSchemas(logEntry, apiKey)
+}
+
+// validateFields validates the fields every log entry must have
+func (v *Validator) validateFields(logEntry *models.LogEntry) error {
 	// Validate timestamp
 	if logEntry.Timestamp.IsZero() {
-		return fmt.Errorf("timestamp is required")
+		return invalid(ReasonTimestampMissing, "timestamp is required")
 	}
 	
 	// Validate timestamp is not too far in the future (allow 1 hour buffer)
 	maxFutureTime := time.Now().Add(1 * time.Hour)
 	if logEntry.Timestamp.After(maxFutureTime) {
-		return fmt.Errorf("timestamp cannot be more than 1 hour in the future")
+		return invalid(ReasonTimestampFuture, "timestamp cannot be more than 1 hour in the future")
 	}
 	
 	// Validate timestamp is not too far in the past (allow 7 days)
 	maxPastTime := time.Now().Add(-7 * 24 * time.Hour)
 	if logEntry.Timestamp.Before(maxPastTime) {
-		return fmt.Errorf("timestamp cannot be more than 7 days in the past")
+		return invalid(ReasonTimestampPast, "timestamp cannot be more than 7 days in the past")
 	}
 	
 	// Validate service
 	if logEntry.Service == "" {
-		return fmt.Errorf("service is required")
+		return invalid(ReasonServiceMissing, "service is required")
 	}
 	if len(logEntry.Service) > v.maxServiceLength {
-		return fmt.Errorf("service name exceeds maximum length of %d", v.maxServiceLength)
+		return invalid(ReasonServiceTooLong, "service name exceeds maximum length of %d", v.maxServiceLength)
 	}
 	
-	// Validate level
-	upperLevel := strings.ToUpper(logEntry.Level)
-	if !v.allowedLevels[upperLevel] {
-		return fmt.Errorf("invalid log level: %s", logEntry.Level)
+	// Validate level, mapping aliases and syslog severities to canonical levels
+	level, ok := v.levels[strings.ToUpper(strings.TrimSpace(logEntry.Level))]
+	if !ok {
+		return invalid(ReasonInvalidLevel, "invalid log level: %s", logEntry.Level)
 	}
-	logEntry.Level = upperLevel // Normalize to uppercase
+	logEntry.Level = level
 	
 	// Validate message
+	// Overlong messages are truncated by Sanitize rather than rejected
 	if logEntry.Message == "" {
-		return fmt.Errorf("message is required")
-	}
-	if len(logEntry.Message) > v.maxMessageLength {
-		return fmt.Errorf("message exceeds maximum length of %d", v.maxMessageLength)
+		return invalid(ReasonMessageMissing, "message is required")
 	}
 	
 	return nil
 }
 
-// Sanitize sanitizes a log entry by removing sensitive data
-func (v *Validator) Sanitize(logEntry *models.LogEntry) {
+// Sanitize sanitizes a log entry by masking sensitive data and normalizing text.
+// Strings are coerced to valid UTF-8, ANSI escape sequences and control characters are
+// stripped from the message, and overlong messages are truncated on a rune boundary.
+func (v *Validator) Sanitize(logEntry *models.LogEntry) Modifications {
+	var mods Modifications
+	
 	// Sanitize service name (remove special characters, keep alphanumeric, dash, underscore)
-	re := regexp.MustCompile(`[^a-zA-Z0-9\-_]`)
-	logEntry.Service = re.ReplaceAllString(logEntry.Service, "")
+	service := serviceNamePattern.ReplaceAllString(logEntry.Service, "")
+	mods.ServiceRenamed = service != logEntry.Service
+	logEntry.Service = service
 	
 	// Sanitize level (already validated, just ensure uppercase)
 	logEntry.Level = strings.ToUpper(logEntry.Level)
 	
-	// Sanitize message (remove null bytes and control characters except newlines and tabs)
-	re = regexp.MustCompile(`[\x00-\x08\x0B-\x0C\x0E-\x1F]`)
-	logEntry.Message = re.ReplaceAllString(logEntry.Message, "")
+	// Normalize message to valid UTF-8 before pattern matching
+	message := logEntry.Message
+	if !utf8.ValidString(message) {
+		message = strings.ToValidUTF8(message, string(utf8.RuneError))
+		mods.InvalidUTF8 = true
+	}
+	
+	// Strip ANSI escape sequences emitted by colorized container logs
+	if stripped := ansiEscapePattern.ReplaceAllString(message, ""); stripped != message {
+		message = stripped
+		mods.ANSIStripped = true
+	}
+	
+	// Remove null bytes and control characters except newlines and tabs
+	if stripped := controlCharPattern.ReplaceAllString(message, ""); stripped != message {
+		message = stripped
+		mods.ControlStripped = true
+	}
+	
+	if len(message) > v.maxMessageLength {
+		mods.Truncated = true
+		mods.OriginalLength = len(message)
+		message = TruncateUTF8(message, v.maxMessageLength-len(truncationMarker)) + truncationMarker
+	}
+	logEntry.Message = message
 	
-	// Sanitize metadata - remove sensitive fields
+	// Sanitize metadata - mask sensitive fields at any depth
 	if logEntry.Metadata != nil {
-		sensitiveFields := []string{"password", "token", "secret", "api_key", "apikey", "auth", "authorization", "credit_card", "ssn", "social_security"}
-		for _, field := range sensitiveFields {
-			delete(logEntry.Metadata, field)
-			delete(logEntry.Metadata, strings.ToLower(field))
-			delete(logEntry.Metadata, strings.ToUpper(field))
+		mods.ScrubbedFields = v.scrubbers.LogScrubber().Scrub(logEntry.Metadata)
+		
+		for key, value := range logEntry.Metadata {
+			if str, ok := value.(string); ok && !utf8.ValidString(str) {
+				logEntry.Metadata[key] = strings.ToValidUTF8(str, string(utf8.RuneError))
+				mods.InvalidUTF8 = true
+			}
 		}
+		
+		// Truncate oversized metadata once it is scrubbed, so no secret is left half masked
+		mods.MetadataTruncated = v.limits.Limit(logEntry.Metadata)
 	}
+	
+	return mods
 }
 
+// TruncateUTF8 shortens s to at most maxBytes bytes without splitting a multi-byte rune
+func TruncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	if maxBytes <= 0 {
+		return ""
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut]
+}
@@ -0,0 +1,495 @@
+// Package webauthn implements the WebAuthn registration and authentication ceremonies used for
+// passkey login. Ceremony challenges are kept in a ChallengeStore shared by all instances, so
+// any instance can finish a ceremony another began, and each is deleted on first use so a
+// response cannot be replayed. Attestation is not requested, and attestation statements are not
+// verified.
+package webauthn
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"strings"
+	"time"
+)
+
+// ErrVerification wraps every reason a ceremony response is rejected
+var ErrVerification = errors.New("passkey verification failed")
+
+// Timeout is how long a ceremony may take, from its options to the response
+const Timeout = 5 * time.Minute
+
+// challengeSize is the length of ceremony challenges and session IDs in bytes
+const challengeSize = 32
+
+// maxCredentialIDLength is the longest credential ID the specification allows
+const maxCredentialIDLength = 1023
+
+// Ceremony kinds recorded with challenges
+const (
+	ceremonyRegister = "register"
+	ceremonyLogin    = "login"
+)
+
+// Authenticator data flags
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+)
+
+// ChallengeStore keeps the challenges of ceremonies in progress
+type ChallengeStore interface {
+	CreateWebAuthnChallenge(ctx context.Context, challenge *models.WebAuthnChallenge) error
+	// TakeWebAuthnChallenge deletes and returns a challenge, or nil if there is none
+	TakeWebAuthnChallenge(ctx context.Context, id, kind string) (*models.WebAuthnChallenge, error)
+}
+
+// RelyingParty runs ceremonies for one relying party ID
+type RelyingParty struct {
+	id         string
+	name       string
+	origins    map[string]bool
+	challenges ChallengeStore
+}
+
+// New creates a relying party from cfg, keeping ceremony challenges in challenges
+func New(cfg *config.WebAuthnConfig, challenges ChallengeStore) *RelyingParty {
+	origins := make(map[string]bool)
+	for _, origin := range cfg.Origins {
+		origins[strings.TrimSuffix(origin, "/")] = true
+	}
+	if len(origins) == 0 && cfg.RPID != "" {
+		origins["https://"+cfg.RPID] = true
+	}
+	return &RelyingParty{
+		id:         cfg.RPID,
+		name:       cfg.RPName,
+		origins:    origins,
+		challenges: challenges,
+	}
+}
+
+// Enabled reports whether a relying party ID is configured
+func (rp *RelyingParty) Enabled() bool {
+	return rp.id != ""
+}
+
+// User is the account a passkey is registered for
+type User struct {
+	ID          int64
+	Name        string
+	DisplayName string
+}
+
+// CredentialDescriptor identifies a credential in ceremony options
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// CredentialParameter offers a credential algorithm to authenticators
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// CreationOptions are the options of a registration ceremony, in the JSON form accepted by
+// PublicKeyCredential.parseCreationOptionsFromJSON
+type CreationOptions struct {
+	RP struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	Challenge              string                 `json:"challenge"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		ResidentKey      string `json:"residentKey"`
+		UserVerification string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+	Attestation string `json:"attestation"`
+}
+
+// RequestOptions are the options of an authentication ceremony, in the JSON form accepted by
+// PublicKeyCredential.parseRequestOptionsFromJSON
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationResponse is a registration credential as serialized by PublicKeyCredential.toJSON
+type RegistrationResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is an authentication credential as serialized by PublicKeyCredential.toJSON
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// BeginRegistration starts registering a passkey for user. Credentials the user already has
+// are excluded so an authenticator is not registered twice. It returns the options for the
+// browser and the session ID to send back with the response.
+func (rp *RelyingParty) BeginRegistration(ctx context.Context, user User, existing []models.WebAuthnCredential) (*CreationOptions, string, error) {
+	challenge, token, err := rp.newSession(ctx, ceremonyRegister, &user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	opts := &CreationOptions{
+		Challenge:          encode(challenge),
+		Timeout:            Timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(existing),
+		Attestation:        "none",
+	}
+	opts.RP.ID = rp.id
+	opts.RP.Name = rp.name
+	opts.User.ID = encode(userHandle(user.ID))
+	opts.User.Name = user.Name
+	opts.User.DisplayName = user.DisplayName
+	for _, alg := range supportedAlgorithms {
+		opts.PubKeyCredParams = append(opts.PubKeyCredParams, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+	opts.AuthenticatorSelection.ResidentKey = "preferred"
+	opts.AuthenticatorSelection.UserVerification = "required"
+	return opts, token, nil
+}
+
+// FinishRegistration verifies a registration response for the user whose ceremony the session
+// began, and returns the new credential, without a name, ready to be stored. The session ends
+// whatever the outcome.
+func (rp *RelyingParty) FinishRegistration(ctx context.Context, token string, userID int64, resp *RegistrationResponse) (*models.WebAuthnCredential, error) {
+	sess, err := rp.openSession(ctx, token, ceremonyRegister)
+	if err != nil {
+		return nil, err
+	}
+	if sess.UserID == nil || *sess.UserID != userID {
+		return nil, verificationError("the registration was started by another user")
+	}
+	if resp.Type != "public-key" {
+		return nil, verificationError("credential type %q is not public-key", resp.Type)
+	}
+	if err := rp.verifyClientData(resp.Response.ClientDataJSON, "webauthn.create", sess.Challenge); err != nil {
+		return nil, err
+	}
+
+	rawObject, err := decode(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, verificationError("invalid attestationObject: %v", err)
+	}
+	item, _, err := decodeCBOR(rawObject)
+	if err != nil {
+		return nil, verificationError("invalid attestationObject: %v", err)
+	}
+	object, ok := item.(map[interface{}]interface{})
+	if !ok {
+		return nil, verificationError("invalid attestationObject: not a map")
+	}
+	rawAuthData, _ := object["authData"].([]byte)
+	authData, err := rp.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.flags&flagAttested == 0 {
+		return nil, verificationError("authenticator data has no attested credential")
+	}
+
+	key, err := parsePublicKey(authData.publicKey)
+	if err != nil {
+		return nil, verificationError("%v", err)
+	}
+
+	return &models.WebAuthnCredential{
+		UserID:       userID,
+		CredentialID: authData.credentialID,
+		PublicKey:    authData.publicKey,
+		Algorithm:    key.alg,
+		SignCount:    int64(authData.signCount),
+		Transports:   resp.Response.Transports,
+		AAGUID:       formatAAGUID(authData.aaguid),
+	}, nil
+}
+
+// BeginLogin starts a login. With allowed credentials, only those may be used; without, the
+// browser offers any passkey the user has for this relying party.
+func (rp *RelyingParty) BeginLogin(ctx context.Context, allowed []models.WebAuthnCredential) (*RequestOptions, string, error) {
+	challenge, token, err := rp.newSession(ctx, ceremonyLogin, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	return &RequestOptions{
+		Challenge:        encode(challenge),
+		Timeout:          Timeout.Milliseconds(),
+		RPID:             rp.id,
+		AllowCredentials: descriptors(allowed),
+		UserVerification: "required",
+	}, token, nil
+}
+
+// FinishLogin verifies a login response. lookup finds the stored credential by its ID, and its
+// errors are returned as is. The credential is returned with its new signature counter. The
+// session ends whatever the outcome.
+func (rp *RelyingParty) FinishLogin(ctx context.Context, token string, resp *AssertionResponse, lookup func(credentialID []byte) (*models.WebAuthnCredential, error)) (*models.WebAuthnCredential, error) {
+	sess, err := rp.openSession(ctx, token, ceremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Type != "public-key" {
+		return nil, verificationError("credential type %q is not public-key", resp.Type)
+	}
+	credentialID, err := decode(resp.ID)
+	if err != nil || len(credentialID) == 0 {
+		return nil, verificationError("invalid credential id")
+	}
+
+	cred, err := lookup(credentialID)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Response.UserHandle != "" {
+		handle, err := decode(resp.Response.UserHandle)
+		if err != nil || !bytes.Equal(handle, userHandle(cred.UserID)) {
+			return nil, verificationError("the passkey belongs to another user")
+		}
+	}
+
+	if err := rp.verifyClientData(resp.Response.ClientDataJSON, "webauthn.get", sess.Challenge); err != nil {
+		return nil, err
+	}
+	rawAuthData, err := decode(resp.Response.AuthenticatorData)
+	if err != nil {
+		return nil, verificationError("invalid authenticatorData: %v", err)
+	}
+	authData, err := rp.verifyAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := parsePublicKey(cred.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("stored credential %d: %w", cred.ID, err)
+	}
+	signature, err := decode(resp.Response.Signature)
+	if err != nil {
+		return nil, verificationError("invalid signature: %v", err)
+	}
+	clientDataJSON, _ := decode(resp.Response.ClientDataJSON)
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	if err := key.verify(append(rawAuthData, clientDataHash[:]...), signature); err != nil {
+		return nil, verificationError("%v", err)
+	}
+
+	// Authenticators without a counter always report zero; otherwise it must increase
+	count := int64(authData.signCount)
+	if (count != 0 || cred.SignCount != 0) && count <= cred.SignCount {
+		return nil, verificationError("signature counter did not increase, the passkey may have been cloned")
+	}
+	cred.SignCount = count
+	return cred, nil
+}
+
+// clientData is the client data the browser signs over
+type clientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// verifyClientData checks the ceremony type, challenge and origin of a response's client data
+func (rp *RelyingParty) verifyClientData(encoded, ceremonyType string, challenge []byte) error {
+	raw, err := decode(encoded)
+	if err != nil {
+		return verificationError("invalid clientDataJSON: %v", err)
+	}
+	var data clientData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return verificationError("invalid clientDataJSON: %v", err)
+	}
+	if data.Type != ceremonyType {
+		return verificationError("client data type %q is not %s", data.Type, ceremonyType)
+	}
+	got, err := decode(data.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(got, challenge) != 1 {
+		return verificationError("the challenge does not match")
+	}
+	if !rp.origins[data.Origin] {
+		return verificationError("origin %q is not allowed", data.Origin)
+	}
+	if data.CrossOrigin {
+		return verificationError("cross-origin ceremonies are not allowed")
+	}
+	return nil
+}
+
+// authenticatorData is the parsed authenticator data of a response
+type authenticatorData struct {
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// verifyAuthenticatorData parses authenticator data and checks it is for this relying party
+// and that the user was present and verified
+func (rp *RelyingParty) verifyAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, verificationError("authenticator data is too short")
+	}
+	rpIDHash := sha256.Sum256([]byte(rp.id))
+	if !bytes.Equal(raw[:32], rpIDHash[:]) {
+		return nil, verificationError("the passkey is for another relying party")
+	}
+	data := &authenticatorData{
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	if data.flags&flagUserPresent == 0 {
+		return nil, verificationError("the user was not present")
+	}
+	if data.flags&flagUserVerified == 0 {
+		return nil, verificationError("the user was not verified")
+	}
+	if data.flags&flagAttested == 0 {
+		return data, nil
+	}
+
+	rest := raw[37:]
+	if len(rest) < 18 {
+		return nil, verificationError("attested credential data is too short")
+	}
+	data.aaguid = rest[:16]
+	idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if idLength == 0 || idLength > maxCredentialIDLength || len(rest) < idLength {
+		return nil, verificationError("invalid credential ID length %d", idLength)
+	}
+	data.credentialID = append([]byte(nil), rest[:idLength]...)
+	rest = rest[idLength:]
+
+	// The key is followed by extension data, if any
+	_, after, err := decodeCBOR(rest)
+	if err != nil {
+		return nil, verificationError("invalid credential public key: %v", err)
+	}
+	data.publicKey = append([]byte(nil), rest[:len(rest)-len(after)]...)
+	return data, nil
+}
+
+// newSession creates a ceremony challenge and stores it under a new session ID
+func (rp *RelyingParty) newSession(ctx context.Context, kind string, userID *int64) ([]byte, string, error) {
+	challenge := make([]byte, challengeSize)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, "", fmt.Errorf("error generating challenge: %w", err)
+	}
+	id := make([]byte, challengeSize)
+	if _, err := rand.Read(id); err != nil {
+		return nil, "", fmt.Errorf("error generating session ID: %w", err)
+	}
+	token := encode(id)
+	err := rp.challenges.CreateWebAuthnChallenge(ctx, &models.WebAuthnChallenge{
+		ID:        token,
+		Kind:      kind,
+		Challenge: challenge,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(Timeout),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return challenge, token, nil
+}
+
+// openSession takes the challenge of a session, which can therefore be opened once only, and
+// checks it has not expired
+func (rp *RelyingParty) openSession(ctx context.Context, token, kind string) (*models.WebAuthnChallenge, error) {
+	if token == "" {
+		return nil, verificationError("invalid session")
+	}
+	sess, err := rp.challenges.TakeWebAuthnChallenge(ctx, token, kind)
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil {
+		return nil, verificationError("invalid or already used session")
+	}
+	if time.Now().After(sess.ExpiresAt) {
+		return nil, verificationError("the session expired, start again")
+	}
+	return sess, nil
+}
+
+// descriptors lists credentials for ceremony options
+func descriptors(creds []models.WebAuthnCredential) []CredentialDescriptor {
+	list := make([]CredentialDescriptor, 0, len(creds))
+	for _, cred := range creds {
+		list = append(list, CredentialDescriptor{Type: "public-key", ID: encode(cred.CredentialID), Transports: cred.Transports})
+	}
+	return list
+}
+
+// userHandle is the opaque WebAuthn user ID of a user
+func userHandle(userID int64) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(userID))
+	return handle
+}
+
+// formatAAGUID formats an authenticator model ID as a UUID, or "" when the authenticator does
+// not disclose it
+func formatAAGUID(aaguid []byte) string {
+	if len(aaguid) != 16 || bytes.Equal(aaguid, make([]byte, 16)) {
+		return ""
+	}
+	h := hex.EncodeToString(aaguid)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// encode encodes binary ceremony values as unpadded base64url, as WebAuthn JSON does
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decode decodes base64url, with or without padding
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func verificationError(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrVerification, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,671 @@
+package webauthn
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"log-ingestion-service/pkg/config"
+	"log-ingestion-service/pkg/models"
+	"math/big"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+// memoryChallenges is a ChallengeStore for tests
+type memoryChallenges struct {
+	mu         sync.Mutex
+	challenges map[string]models.WebAuthnChallenge
+}
+
+func (m *memoryChallenges) CreateWebAuthnChallenge(ctx context.Context, challenge *models.WebAuthnChallenge) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.challenges == nil {
+		m.challenges = make(map[string]models.WebAuthnChallenge)
+	}
+	m.challenges[challenge.ID] = *challenge
+	return nil
+}
+
+func (m *memoryChallenges) TakeWebAuthnChallenge(ctx context.Context, id, kind string) (*models.WebAuthnChallenge, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	challenge, ok := m.challenges[id]
+	if !ok || challenge.Kind != kind {
+		return nil, nil
+	}
+	delete(m.challenges, id)
+	return &challenge, nil
+}
+
+func newTestRP() (*RelyingParty, *memoryChallenges) {
+	store := &memoryChallenges{}
+	return New(&config.WebAuthnConfig{RPID: testRPID, RPName: "Test", Origins: []string{testOrigin + "/"}}, store), store
+}
+
+// encodeCBOR encodes the values authenticators send: unsigned and negative integers, byte and
+// text strings, and maps with integer or text keys, sorted canonically
+func encodeCBOR(value interface{}) []byte {
+	head := func(major byte, n uint64) []byte {
+		switch {
+		case n < 24:
+			return []byte{major<<5 | byte(n)}
+		case n <= 0xff:
+			return []byte{major<<5 | 24, byte(n)}
+		case n <= 0xffff:
+			return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+		case n <= 0xffffffff:
+			return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+		}
+		return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, n)
+	}
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return head(1, uint64(-1-v))
+		}
+		return head(0, uint64(v))
+	case []byte:
+		return append(head(2, uint64(len(v))), v...)
+	case string:
+		return append(head(3, uint64(len(v))), v...)
+	case map[interface{}]interface{}:
+		entries := make([][2][]byte, 0, len(v))
+		for key, item := range v {
+			entries = append(entries, [2][]byte{encodeCBOR(key), encodeCBOR(item)})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			a, b := entries[i][0], entries[j][0]
+			if len(a) != len(b) {
+				return len(a) < len(b)
+			}
+			return bytes.Compare(a, b) < 0
+		})
+		out := head(5, uint64(len(v)))
+		for _, entry := range entries {
+			out = append(append(out, entry[0]...), entry[1]...)
+		}
+		return out
+	}
+	panic("unsupported CBOR value")
+}
+
+// authenticator is a software authenticator holding one credential
+type authenticator struct {
+	credentialID []byte
+	alg          int
+	signer       crypto.Signer
+	signCount    uint32
+	// flags are set on the authenticator data of every response
+	flags byte
+	// coseKeyOverride replaces the encoded public key when set
+	coseKeyOverride []byte
+}
+
+func newAuthenticator(t testing.TB, alg int) *authenticator {
+	t.Helper()
+	var signer crypto.Signer
+	var err error
+	switch alg {
+	case AlgES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	case AlgRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, minRSABits)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &authenticator{credentialID: id, alg: alg, signer: signer, flags: flagUserPresent | flagUserVerified}
+}
+
+// coseKey encodes the credential public key
+func (a *authenticator) coseKey() []byte {
+	if a.coseKeyOverride != nil {
+		return a.coseKeyOverride
+	}
+	switch key := a.signer.Public().(type) {
+	case *ecdsa.PublicKey:
+		return encodeCBOR(map[interface{}]interface{}{
+			coseKty: ktyEC2, coseAlg: AlgES256, coseCrv: crvP256,
+			coseX: key.X.FillBytes(make([]byte, 32)), coseY: key.Y.FillBytes(make([]byte, 32)),
+		})
+	case ed25519.PublicKey:
+		return encodeCBOR(map[interface{}]interface{}{
+			coseKty: ktyOKP, coseAlg: AlgEdDSA, coseCrv: crvEd25519, coseX: []byte(key),
+		})
+	case *rsa.PublicKey:
+		return encodeCBOR(map[interface{}]interface{}{
+			coseKty: ktyRSA, coseAlg: AlgRS256, coseCrv: key.N.Bytes(), coseX: big.NewInt(int64(key.E)).Bytes(),
+		})
+	}
+	panic("unsupported key")
+}
+
+// authenticatorData builds authenticator data, with the attested credential when attested
+func (a *authenticator) authenticatorData(rpID string, attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	flags := a.flags
+	if attested {
+		flags |= flagAttested
+	}
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey()...)
+	}
+	return data
+}
+
+func clientDataJSON(ceremonyType, challenge, origin string) []byte {
+	data, _ := json.Marshal(clientData{Type: ceremonyType, Challenge: challenge, Origin: origin})
+	return data
+}
+
+// register answers registration options
+func (a *authenticator) register(opts *CreationOptions) *RegistrationResponse {
+	resp := &RegistrationResponse{ID: encode(a.credentialID), Type: "public-key"}
+	resp.Response.ClientDataJSON = encode(clientDataJSON("webauthn.create", opts.Challenge, testOrigin))
+	resp.Response.AttestationObject = encode(encodeCBOR(map[interface{}]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[interface{}]interface{}{},
+		"authData": a.authenticatorData(opts.RP.ID, true),
+	}))
+	resp.Response.Transports = []string{"internal"}
+	return resp
+}
+
+// assert answers login options for the user, signing with the next counter value
+func (a *authenticator) assert(t testing.TB, opts *RequestOptions, userID int64) *AssertionResponse {
+	t.Helper()
+	if a.signCount > 0 {
+		a.signCount++
+	}
+	rawClientData := clientDataJSON("webauthn.get", opts.Challenge, testOrigin)
+	authData := a.authenticatorData(opts.RPID, false)
+	resp := &AssertionResponse{ID: encode(a.credentialID), Type: "public-key"}
+	resp.Response.ClientDataJSON = encode(rawClientData)
+	resp.Response.AuthenticatorData = encode(authData)
+	resp.Response.Signature = encode(a.sign(t, authData, rawClientData))
+	resp.Response.UserHandle = encode(userHandle(userID))
+	return resp
+}
+
+func (a *authenticator) sign(t testing.TB, authData, rawClientData []byte) []byte {
+	t.Helper()
+	clientDataHash := sha256.Sum256(rawClientData)
+	signed := append(append([]byte(nil), authData...), clientDataHash[:]...)
+	var sig []byte
+	var err error
+	if a.alg == AlgEdDSA {
+		sig, err = a.signer.Sign(rand.Reader, signed, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(signed)
+		sig, err = a.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+// registered registers a credential for user 7 and returns it as stored
+func registered(t *testing.T, rp *RelyingParty, a *authenticator) *models.WebAuthnCredential {
+	t.Helper()
+	ctx := context.Background()
+	opts, session, err := rp.BeginRegistration(ctx, User{ID: 7, Name: "ada@example.com"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cred, err := rp.FinishRegistration(ctx, session, 7, a.register(opts))
+	if err != nil {
+		t.Fatalf("registration failed: %v", err)
+	}
+	cred.ID = 1
+	return cred
+}
+
+func lookupCredential(cred *models.WebAuthnCredential) func([]byte) (*models.WebAuthnCredential, error) {
+	return func(id []byte) (*models.WebAuthnCredential, error) {
+		if !bytes.Equal(id, cred.CredentialID) {
+			return nil, errors.New("unknown credential")
+		}
+		stored := *cred
+		return &stored, nil
+	}
+}
+
+func TestCeremonies(t *testing.T) {
+	for _, alg := range supportedAlgorithms {
+		a := newAuthenticator(t, alg)
+		a.signCount = 1
+		rp, _ := newTestRP()
+		ctx := context.Background()
+
+		cred := registered(t, rp, a)
+		if cred.UserID != 7 || cred.Algorithm != alg || !bytes.Equal(cred.CredentialID, a.credentialID) || cred.SignCount != 1 {
+			t.Fatalf("alg %d: registered %+v", alg, cred)
+		}
+
+		opts, session, err := rp.BeginLogin(ctx, []models.WebAuthnCredential{*cred})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(opts.AllowCredentials) != 1 || opts.AllowCredentials[0].ID != encode(a.credentialID) {
+			t.Errorf("alg %d: allowed credentials %+v", alg, opts.AllowCredentials)
+		}
+		loggedIn, err := rp.FinishLogin(ctx, session, a.assert(t, opts, 7), lookupCredential(cred))
+		if err != nil {
+			t.Fatalf("alg %d: login failed: %v", alg, err)
+		}
+		if loggedIn.SignCount != 2 {
+			t.Errorf("alg %d: sign count is %d, want 2", alg, loggedIn.SignCount)
+		}
+	}
+}
+
+// TestSessionsAreSingleUse replays a valid login response and checks that a failed attempt
+// also ends the session
+func TestSessionsAreSingleUse(t *testing.T) {
+	rp, _ := newTestRP()
+	ctx := context.Background()
+	a := newAuthenticator(t, AlgES256)
+	cred := registered(t, rp, a)
+
+	opts, session, err := rp.BeginLogin(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := a.assert(t, opts, 7)
+	if _, err := rp.FinishLogin(ctx, session, resp, lookupCredential(cred)); err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if _, err := rp.FinishLogin(ctx, session, resp, lookupCredential(cred)); !errors.Is(err, ErrVerification) {
+		t.Errorf("replayed login: got %v, want a verification error", err)
+	}
+
+	opts, session, err = rp.BeginLogin(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = a.assert(t, opts, 7)
+	resp.Response.Signature = encode([]byte("not a signature"))
+	if _, err := rp.FinishLogin(ctx, session, resp, lookupCredential(cred)); !errors.Is(err, ErrVerification) {
+		t.Fatalf("bad signature: got %v, want a verification error", err)
+	}
+	if _, err := rp.FinishLogin(ctx, session, a.assert(t, opts, 7), lookupCredential(cred)); !errors.Is(err, ErrVerification) {
+		t.Errorf("retry after a failed login: got %v, want a verification error", err)
+	}
+
+	// A registration session cannot finish a login
+	_, session, err = rp.BeginRegistration(ctx, User{ID: 7}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rp.FinishLogin(ctx, session, a.assert(t, opts, 7), lookupCredential(cred)); !errors.Is(err, ErrVerification) {
+		t.Errorf("login with a registration session: got %v, want a verification error", err)
+	}
+}
+
+func TestExpiredSession(t *testing.T) {
+	rp, store := newTestRP()
+	ctx := context.Background()
+	a := newAuthenticator(t, AlgES256)
+	cred := registered(t, rp, a)
+
+	opts, session, err := rp.BeginLogin(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := store.challenges[session]
+	expired.ExpiresAt = time.Now().Add(-time.Second)
+	store.challenges[session] = expired
+	if _, err := rp.FinishLogin(ctx, session, a.assert(t, opts, 7), lookupCredential(cred)); !errors.Is(err, ErrVerification) {
+		t.Errorf("expired session: got %v, want a verification error", err)
+	}
+}
+
+func TestRegistrationRejectsTamperedResponses(t *testing.T) {
+	cases := []struct {
+		name   string
+		userID int64
+		tamper func(a *authenticator, opts *CreationOptions, resp *RegistrationResponse)
+	}{
+		{name: "another user", userID: 8},
+		{name: "credential type", tamper: func(a *authenticator, opts *CreationOptions, resp *RegistrationResponse) {
+			resp.Type = "password"
+		}},
+		{name: "ceremony type", tamper: func(a *authenticator, opts *CreationOptions, resp *RegistrationResponse) {
+			resp.Response.ClientDataJSON = encode(clientDataJSON("webauthn.get", opts.Challenge, testOrigin))
+		}},
+		{name: "challenge", tamper: func(a *authenticator, opts *CreationOptions, resp *RegistrationResponse) {
+			resp.Response.ClientDataJSON = encode(clientDataJSON("webauthn.create", encode(make([]byte, challengeSize)), testOrigin))
+		}},
+		{name: "origin", tamper: func(a *authenticator, opts *CreationOptions, resp *RegistrationResponse) {
+			resp.Response.ClientDataJSON = encode(clientDataJSON("webauthn.create", opts.Challenge, "https://evil.example"))
+		}},
+		{name: "relying party", tamper: func(a *authenticator, opts *CreationOptions, resp *RegistrationResponse) {
+			opts.RP.ID = "evil.example"
+			*resp = *a.register(opts)
+		}},
+		{name: "user not verified", tamper: func(a *authenticator, opts *CreationOptions, resp *RegistrationResponse) {
+			a.flags = flagUserPresent
+			*resp = *a.register(opts)
+		}},
+		{name: "user not present", tamper: func(a *authenticator, opts *CreationOptions, resp *RegistrationResponse) {
+			a.flags = flagUserVerified
+			*resp = *a.register(opts)
+		}},
+		{name: "attestation object not CBOR", tamper: func(a *authenticator, opts *CreationOptions, resp *RegistrationResponse) {
+			resp.Response.AttestationObject = encode([]byte{0xbf, 0xff})
+		}},
+		{name: "attestation object not a map", tamper: func(a *authenticator, opts *CreationOptions, resp *RegistrationResponse) {
+			resp.Response.AttestationObject = encode(encodeCBOR("none"))
+		}},
+		{name: "no attested credential", tamper: func(a *authenticator, opts *CreationOptions, resp *RegistrationResponse) {
+			resp.Response.AttestationObject = encode(encodeCBOR(map[interface{}]interface{}{
+				"fmt": "none", "attStmt": map[interface{}]interface{}{}, "authData": a.authenticatorData(testRPID, false),
+			}))
+		}},
+		{name: "truncated authenticator data", tamper: func(a *authenticator, opts *CreationOptions, resp *RegistrationResponse) {
+			authData := a.authenticatorData(testRPID, true)
+			resp.Response.AttestationObject = encode(encodeCBOR(map[interface{}]interface{}{
+				"fmt": "none", "attStmt": map[interface{}]interface{}{}, "authData": authData[:len(authData)-5],
+			}))
+		}},
+		{name: "credential ID length", tamper: func(a *authenticator, opts *CreationOptions, resp *RegistrationResponse) {
+			authData := a.authenticatorData(testRPID, true)
+			binary.BigEndian.PutUint16(authData[53:55], 0xffff)
+			resp.Response.AttestationObject = encode(encodeCBOR(map[interface{}]interface{}{
+				"fmt": "none", "attStmt": map[interface{}]interface{}{}, "authData": authData,
+			}))
+		}},
+		{name: "unsupported key", tamper: func(a *authenticator, opts *CreationOptions, resp *RegistrationResponse) {
+			a.coseKeyOverride = encodeCBOR(map[interface{}]interface{}{coseKty: ktyEC2, coseAlg: -35, coseCrv: 2})
+			*resp = *a.register(opts)
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rp, _ := newTestRP()
+			ctx := context.Background()
+			a := newAuthenticator(t, AlgES256)
+			opts, session, err := rp.BeginRegistration(ctx, User{ID: 7}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp := a.register(opts)
+			if tc.tamper != nil {
+				tc.tamper(a, opts, resp)
+			}
+			userID := tc.userID
+			if userID == 0 {
+				userID = 7
+			}
+			if _, err := rp.FinishRegistration(ctx, session, userID, resp); !errors.Is(err, ErrVerification) {
+				t.Errorf("got %v, want a verification error", err)
+			}
+		})
+	}
+}
+
+func TestLoginRejectsTamperedResponses(t *testing.T) {
+	cases := []struct {
+		name   string
+		tamper func(t *testing.T, a *authenticator, opts *RequestOptions, resp *AssertionResponse)
+	}{
+		{name: "signature", tamper: func(t *testing.T, a *authenticator, opts *RequestOptions, resp *AssertionResponse) {
+			sig, _ := decode(resp.Response.Signature)
+			sig[len(sig)-1] ^= 0x01
+			resp.Response.Signature = encode(sig)
+		}},
+		{name: "signed authenticator data", tamper: func(t *testing.T, a *authenticator, opts *RequestOptions, resp *AssertionResponse) {
+			authData, _ := decode(resp.Response.AuthenticatorData)
+			authData[36]++
+			resp.Response.AuthenticatorData = encode(authData)
+		}},
+		{name: "signed client data", tamper: func(t *testing.T, a *authenticator, opts *RequestOptions, resp *AssertionResponse) {
+			raw, _ := decode(resp.Response.ClientDataJSON)
+			resp.Response.ClientDataJSON = encode(append(raw, ' '))
+		}},
+		{name: "challenge", tamper: func(t *testing.T, a *authenticator, opts *RequestOptions, resp *AssertionResponse) {
+			opts.Challenge = encode(make([]byte, challengeSize))
+			*resp = *a.assert(t, opts, 7)
+		}},
+		{name: "relying party", tamper: func(t *testing.T, a *authenticator, opts *RequestOptions, resp *AssertionResponse) {
+			opts.RPID = "evil.example"
+			*resp = *a.assert(t, opts, 7)
+		}},
+		{name: "another user's handle", tamper: func(t *testing.T, a *authenticator, opts *RequestOptions, resp *AssertionResponse) {
+			resp.Response.UserHandle = encode(userHandle(8))
+		}},
+		{name: "user not verified", tamper: func(t *testing.T, a *authenticator, opts *RequestOptions, resp *AssertionResponse) {
+			a.flags = flagUserPresent
+			*resp = *a.assert(t, opts, 7)
+		}},
+		{name: "counter did not increase", tamper: func(t *testing.T, a *authenticator, opts *RequestOptions, resp *AssertionResponse) {
+			a.signCount = 1
+			*resp = *a.assert(t, opts, 7)
+		}},
+		{name: "cross origin", tamper: func(t *testing.T, a *authenticator, opts *RequestOptions, resp *AssertionResponse) {
+			rawClientData, _ := json.Marshal(clientData{Type: "webauthn.get", Challenge: opts.Challenge, Origin: testOrigin, CrossOrigin: true})
+			authData, _ := decode(resp.Response.AuthenticatorData)
+			resp.Response.ClientDataJSON = encode(rawClientData)
+			resp.Response.Signature = encode(a.sign(t, authData, rawClientData))
+		}},
+		{name: "credential ID", tamper: func(t *testing.T, a *authenticator, opts *RequestOptions, resp *AssertionResponse) {
+			resp.ID = "!"
+		}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rp, _ := newTestRP()
+			ctx := context.Background()
+			a := newAuthenticator(t, AlgES256)
+			a.signCount = 4
+			cred := registered(t, rp, a)
+
+			opts, session, err := rp.BeginLogin(ctx, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp := a.assert(t, opts, 7)
+			tc.tamper(t, a, opts, resp)
+			if _, err := rp.FinishLogin(ctx, session, resp, lookupCredential(cred)); !errors.Is(err, ErrVerification) {
+				t.Errorf("got %v, want a verification error", err)
+			}
+		})
+	}
+}
+
+func TestParsePublicKeyRejectsInvalidKeys(t *testing.T) {
+	ec := newAuthenticator(t, AlgES256).signer.Public().(*ecdsa.PublicKey)
+	x, y := ec.X.FillBytes(make([]byte, 32)), ec.Y.FillBytes(make([]byte, 32))
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string][]byte{
+		"not a map":          encodeCBOR("key"),
+		"trailing data":      append(encodeCBOR(map[interface{}]interface{}{coseKty: ktyEC2, coseAlg: AlgES256, coseCrv: crvP256, coseX: x, coseY: y}), 0),
+		"P-384 curve":        encodeCBOR(map[interface{}]interface{}{coseKty: ktyEC2, coseAlg: AlgES256, coseCrv: 2, coseX: x, coseY: y}),
+		"short coordinate":   encodeCBOR(map[interface{}]interface{}{coseKty: ktyEC2, coseAlg: AlgES256, coseCrv: crvP256, coseX: x[1:], coseY: y}),
+		"point not on curve": encodeCBOR(map[interface{}]interface{}{coseKty: ktyEC2, coseAlg: AlgES256, coseCrv: crvP256, coseX: x, coseY: x}),
+		"Ed448 curve":        encodeCBOR(map[interface{}]interface{}{coseKty: ktyOKP, coseAlg: AlgEdDSA, coseCrv: 7, coseX: make([]byte, 57)}),
+		"short Ed25519 key":  encodeCBOR(map[interface{}]interface{}{coseKty: ktyOKP, coseAlg: AlgEdDSA, coseCrv: crvEd25519, coseX: make([]byte, 31)}),
+		"1024-bit RSA key":   encodeCBOR(map[interface{}]interface{}{coseKty: ktyRSA, coseAlg: AlgRS256, coseCrv: small.N.Bytes(), coseX: []byte{1, 0, 1}}),
+		"RSA exponent":       encodeCBOR(map[interface{}]interface{}{coseKty: ktyRSA, coseAlg: AlgRS256, coseCrv: small.N.Bytes(), coseX: make([]byte, 5)}),
+		"key type mismatch":  encodeCBOR(map[interface{}]interface{}{coseKty: ktyOKP, coseAlg: AlgES256, coseCrv: crvP256, coseX: x, coseY: y}),
+	}
+	for name, data := range cases {
+		if _, err := parsePublicKey(data); err == nil {
+			t.Errorf("%s: parsed", name)
+		}
+	}
+}
+
+func TestDecodeCBORRejectsMalformedInput(t *testing.T) {
+	nested := bytes.Repeat([]byte{0x81}, maxCBORDepth+2)
+	cases := map[string][]byte{
+		"empty":             {},
+		"truncated integer": {0x19, 0x01},
+		"truncated bytes":   {0x44, 1, 2},
+		"huge array":        {0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"huge map":          {0xbb, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"indefinite length": {0x5f, 0x41, 0x00, 0xff},
+		"float":             {0xf9, 0x3c, 0x00},
+		"byte string key":   {0xa1, 0x41, 0x00, 0x00},
+		"integer overflow":  {0x3b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		"too deep":          append(nested, 0x00),
+	}
+	for name, data := range cases {
+		if _, _, err := decodeCBOR(data); err == nil {
+			t.Errorf("%s: decoded", name)
+		}
+	}
+}
+
+// Recorded ES256 ceremony for user 7 on example.com: a registration answering the challenge
+// 00 01 .. 1f, then a login answering ff fe .. e0 with signature counter 42
+const (
+	vectorRegistrationClientData = "eyJ0eXBlIjoid2ViYXV0aG4uY3JlYXRlIiwiY2hhbGxlbmdlIjoiQUFFQ0F3UUZCZ2NJQ1FvTERBME9EeEFSRWhNVUZSWVhHQmthR3h3ZEhoOCIsIm9yaWdpbiI6Imh0dHBzOi8vZXhhbXBsZS5jb20iLCJjcm9zc09yaWdpbiI6ZmFsc2V9"
+	vectorAttestationObject      = "o2NmbXRkbm9uZWdhdHRTdG10oGhhdXRoRGF0YViUo3mm9u6vuaVeN4wRgDTidR5oL6ufLTCrE9ISVYbOGUdFAAAAKQAAAAAAAAAAAAAAAAAAAAAAEMDBwsPExcbHyMnKy8zNzs-lAQIDJiABIVggIBsNBXdFxMYV1SuLhfBQvJqN88oH1gNvwE07RDYrIVEiWCDWfueRQ6vCXKrP0H5uOMYq6ZYmvGJw3dclVEJmf7eJKA"
+	vectorCredentialID           = "wMHCw8TFxsfIycrLzM3Ozw"
+	vectorLoginClientData        = "eyJ0eXBlIjoid2ViYXV0aG4uZ2V0IiwiY2hhbGxlbmdlIjoiX183OV9QdjYtZmozOXZYMDhfTHg4T191N2V6cjZ1bm81LWJsNU9QaTRlQSIsIm9yaWdpbiI6Imh0dHBzOi8vZXhhbXBsZS5jb20iLCJjcm9zc09yaWdpbiI6ZmFsc2V9"
+	vectorAuthenticatorData      = "o3mm9u6vuaVeN4wRgDTidR5oL6ufLTCrE9ISVYbOGUcFAAAAKg"
+	vectorSignature              = "MEUCIBKXEIKI3Oivyoi0ggpMxOovcfNt-k36ZCZlWFkNBKvkAiEA8UXhukJ7B2mQp9rIFF1uo3IIs9Vy2C7rNS2TBvOf20M"
+	vectorUserHandle             = "AAAAAAAAAAc"
+)
+
+// vectorSession stores the recorded challenge of kind, counting up or down from start, and
+// returns its session ID
+func vectorSession(store *memoryChallenges, kind string, start, step int) string {
+	challenge := make([]byte, challengeSize)
+	for i := range challenge {
+		challenge[i] = byte(start + step*i)
+	}
+	var userID *int64
+	if kind == ceremonyRegister {
+		id := int64(7)
+		userID = &id
+	}
+	store.CreateWebAuthnChallenge(context.Background(), &models.WebAuthnChallenge{
+		ID: kind + "-vector", Kind: kind, Challenge: challenge, UserID: userID, ExpiresAt: time.Now().Add(Timeout),
+	})
+	return kind + "-vector"
+}
+
+func vectorRegistration() *RegistrationResponse {
+	resp := &RegistrationResponse{ID: vectorCredentialID, Type: "public-key"}
+	resp.Response.ClientDataJSON = vectorRegistrationClientData
+	resp.Response.AttestationObject = vectorAttestationObject
+	return resp
+}
+
+func vectorAssertion() *AssertionResponse {
+	resp := &AssertionResponse{ID: vectorCredentialID, Type: "public-key"}
+	resp.Response.ClientDataJSON = vectorLoginClientData
+	resp.Response.AuthenticatorData = vectorAuthenticatorData
+	resp.Response.Signature = vectorSignature
+	resp.Response.UserHandle = vectorUserHandle
+	return resp
+}
+
+// flipByte returns encoded with one bit of its decoded byte at index changed
+func flipByte(encoded string, index int) string {
+	data, _ := decode(encoded)
+	if index < 0 {
+		index += len(data)
+	}
+	data[index] ^= 0x01
+	return encode(data)
+}
+
+// TestRecordedVectors verifies the recorded ceremony, and rejects it with single bits changed
+// in the signed or parsed data
+func TestRecordedVectors(t *testing.T) {
+	ctx := context.Background()
+	rp, store := newTestRP()
+	cred, err := rp.FinishRegistration(ctx, vectorSession(store, ceremonyRegister, 0, 1), 7, vectorRegistration())
+	if err != nil {
+		t.Fatalf("recorded registration failed: %v", err)
+	}
+	if encode(cred.CredentialID) != vectorCredentialID || cred.Algorithm != AlgES256 || cred.SignCount != 41 || cred.AAGUID != "" {
+		t.Fatalf("recorded registration gave %+v", cred)
+	}
+	cred.ID = 1
+
+	loggedIn, err := rp.FinishLogin(ctx, vectorSession(store, ceremonyLogin, 0xff, -1), vectorAssertion(), lookupCredential(cred))
+	if err != nil {
+		t.Fatalf("recorded login failed: %v", err)
+	}
+	if loggedIn.SignCount != 42 {
+		t.Errorf("sign count is %d, want 42", loggedIn.SignCount)
+	}
+
+	registrations := map[string]func(*RegistrationResponse){
+		"client data": func(r *RegistrationResponse) { r.Response.ClientDataJSON = flipByte(r.Response.ClientDataJSON, 20) },
+		"authenticator data rpID": func(r *RegistrationResponse) {
+			r.Response.AttestationObject = flipByte(r.Response.AttestationObject, 40)
+		},
+		"authenticator data flags": func(r *RegistrationResponse) {
+			r.Response.AttestationObject = flipByte(r.Response.AttestationObject, 62)
+		},
+		"credential public key": func(r *RegistrationResponse) {
+			r.Response.AttestationObject = flipByte(r.Response.AttestationObject, -40)
+		},
+		"attestation object header": func(r *RegistrationResponse) {
+			r.Response.AttestationObject = flipByte(r.Response.AttestationObject, 0)
+		},
+	}
+	for name, tamper := range registrations {
+		rp, store := newTestRP()
+		resp := vectorRegistration()
+		tamper(resp)
+		if _, err := rp.FinishRegistration(ctx, vectorSession(store, ceremonyRegister, 0, 1), 7, resp); !errors.Is(err, ErrVerification) {
+			t.Errorf("registration with changed %s: got %v, want a verification error", name, err)
+		}
+	}
+
+	logins := map[string]func(*AssertionResponse){
+		"client data":        func(r *AssertionResponse) { r.Response.ClientDataJSON = flipByte(r.Response.ClientDataJSON, -3) },
+		"authenticator data": func(r *AssertionResponse) { r.Response.AuthenticatorData = flipByte(r.Response.AuthenticatorData, -1) },
+		"signature":          func(r *AssertionResponse) { r.Response.Signature = flipByte(r.Response.Signature, 10) },
+		"user handle":        func(r *AssertionResponse) { r.Response.UserHandle = flipByte(r.Response.UserHandle, -1) },
+	}
+	for name, tamper := range logins {
+		rp, store := newTestRP()
+		resp := vectorAssertion()
+		tamper(resp)
+		stored := *cred
+		stored.SignCount = 41
+		if _, err := rp.FinishLogin(ctx, vectorSession(store, ceremonyLogin, 0xff, -1), resp, lookupCredential(&stored)); !errors.Is(err, ErrVerification) {
+			t.Errorf("login with changed %s: got %v, want a verification error", name, err)
+		}
+	}
+}
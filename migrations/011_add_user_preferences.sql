@@ -0,0 +1,6 @@
+-- Add self-service profile preferences to users
+-- Defaults keep existing users on UTC with the dark theme and no notifications configured
+ALTER TABLE users ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
+ALTER TABLE users ADD COLUMN default_project_id BIGINT;
+ALTER TABLE users ADD COLUMN theme TEXT NOT NULL DEFAULT 'dark';
+ALTER TABLE users ADD COLUMN notification_preferences JSONB NOT NULL DEFAULT '{}';
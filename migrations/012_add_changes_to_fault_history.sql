@@ -0,0 +1,3 @@
+-- Record structured before/after values for each fault history entry
+-- Each element is {"field", "old", "new"} or, for tags, {"field", "added", "removed"}
+ALTER TABLE fault_history ADD COLUMN changes JSONB NOT NULL DEFAULT '[]';
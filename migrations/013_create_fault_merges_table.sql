@@ -0,0 +1,21 @@
+-- Create fault_merges table - Provenance of faults merged into another fault
+-- The source fault row is deleted on merge, so its fingerprint and counts are copied here.
+-- Fingerprints recorded here keep matching the target fault at ingest so merged errors don't re-split.
+CREATE TABLE IF NOT EXISTS fault_merges (
+    id BIGSERIAL PRIMARY KEY,
+    target_fault_id BIGINT NOT NULL REFERENCES faults(id) ON DELETE CASCADE,
+    source_fault_id BIGINT NOT NULL,
+    error_class TEXT NOT NULL,
+    message TEXT NOT NULL,
+    location TEXT,
+    environment TEXT NOT NULL,
+    occurrence_count BIGINT NOT NULL DEFAULT 0,
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    merged_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    merged_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_fault_merges_target ON fault_merges(target_fault_id);
+CREATE INDEX IF NOT EXISTS idx_fault_merges_fingerprint ON fault_merges(error_class, location, environment);
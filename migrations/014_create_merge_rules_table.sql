@@ -0,0 +1,22 @@
+-- Create merge_rules table - Automatically merge new faults into a canonical fault at ingest
+-- A NULL project_id applies the rule to every project. Criteria left NULL match anything,
+-- but at least one of error_class or message_pattern must be set.
+CREATE TABLE IF NOT EXISTS merge_rules (
+    id BIGSERIAL PRIMARY KEY,
+    project_id BIGINT,
+    name TEXT NOT NULL,
+    error_class TEXT,
+    message_pattern TEXT, -- regular expression matched against the normalized message
+    environment TEXT,
+    target_fault_id BIGINT NOT NULL REFERENCES faults(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    match_count BIGINT NOT NULL DEFAULT 0,
+    last_matched_at TIMESTAMPTZ,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT merge_rules_has_criteria CHECK (error_class IS NOT NULL OR message_pattern IS NOT NULL)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_merge_rules_project_id ON merge_rules(project_id);
+CREATE INDEX IF NOT EXISTS idx_merge_rules_target ON merge_rules(target_fault_id);
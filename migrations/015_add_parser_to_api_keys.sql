@@ -0,0 +1,2 @@
+-- Parser used for raw log bodies sent with this key (NULL = choose by source tag or content type)
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS parser TEXT;
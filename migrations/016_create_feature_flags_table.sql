@@ -0,0 +1,13 @@
+-- Create feature_flags table - Runtime overrides of feature flags set from the admin API
+-- A NULL project_id overrides the flag for every project; a project row takes precedence over it.
+CREATE TABLE IF NOT EXISTS feature_flags (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    project_id BIGINT,
+    enabled BOOLEAN NOT NULL,
+    updated_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One override per flag and scope
+CREATE UNIQUE INDEX IF NOT EXISTS idx_feature_flags_scope ON feature_flags(name, COALESCE(project_id, -1));
@@ -0,0 +1,8 @@
+-- Create pipeline_pauses table - Pipelines paused from the admin API, kept across restarts
+-- A row exists only while its pipeline is paused.
+CREATE TABLE IF NOT EXISTS pipeline_pauses (
+    pipeline TEXT PRIMARY KEY,
+    reason TEXT NOT NULL DEFAULT '',
+    paused_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    paused_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
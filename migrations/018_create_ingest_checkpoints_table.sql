@@ -0,0 +1,9 @@
+-- Create ingest_checkpoints table - Last stored sequence number per Kinesis shard, so listeners resume after restarts
+CREATE TABLE IF NOT EXISTS ingest_checkpoints (
+    listener TEXT NOT NULL,
+    stream TEXT NOT NULL,
+    shard_id TEXT NOT NULL,
+    sequence_number TEXT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (listener, stream, shard_id)
+);
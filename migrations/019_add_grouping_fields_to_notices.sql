@@ -0,0 +1,6 @@
+-- Add grouping inputs to notices - The error class and request component/action a notice was
+-- fingerprinted with, so stored notices can be regrouped after grouping rules change.
+-- Notices stored earlier leave them NULL and are regrouped with their fault's class.
+ALTER TABLE notices ADD COLUMN IF NOT EXISTS error_class TEXT;
+ALTER TABLE notices ADD COLUMN IF NOT EXISTS component TEXT;
+ALTER TABLE notices ADD COLUMN IF NOT EXISTS action TEXT;
@@ -0,0 +1,29 @@
+-- Create query_subscriptions table - Saved log or fault queries whose results are delivered on a schedule
+-- Runs are hourly, daily or weekly at at_time ("HH:MM") in the subscription's time zone; hourly runs
+-- use only its minutes, weekly runs also weekday (0 = Sunday).
+CREATE TABLE IF NOT EXISTS query_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL, -- logs or faults
+    query TEXT NOT NULL DEFAULT '',
+    frequency TEXT NOT NULL, -- hourly, daily or weekly
+    at_time TEXT NOT NULL DEFAULT '00:00',
+    weekday INT,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    channel TEXT NOT NULL, -- email or webhook
+    target TEXT NOT NULL, -- email address or webhook URL
+    max_results INT NOT NULL DEFAULT 100,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_run_at TIMESTAMPTZ,
+    last_status TEXT,
+    last_error TEXT,
+    last_result_count INT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_query_subscriptions_user ON query_subscriptions(user_id);
+CREATE INDEX IF NOT EXISTS idx_query_subscriptions_due ON query_subscriptions(next_run_at) WHERE enabled;
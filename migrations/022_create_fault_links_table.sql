@@ -0,0 +1,18 @@
+-- Create fault_links table - Faults linked as related or duplicate, within or across projects
+-- relates_to links are symmetric; duplicate_of marks fault_id as a duplicate of linked_fault_id.
+-- A pair of faults has at most one link, whichever way round it was created.
+CREATE TABLE IF NOT EXISTS fault_links (
+    id BIGSERIAL PRIMARY KEY,
+    fault_id BIGINT NOT NULL REFERENCES faults(id) ON DELETE CASCADE,
+    linked_fault_id BIGINT NOT NULL REFERENCES faults(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT fault_links_distinct CHECK (fault_id <> linked_fault_id),
+    CONSTRAINT fault_links_kind CHECK (kind IN ('relates_to', 'duplicate_of'))
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fault_links_pair ON fault_links(LEAST(fault_id, linked_fault_id), GREATEST(fault_id, linked_fault_id));
+CREATE INDEX IF NOT EXISTS idx_fault_links_fault ON fault_links(fault_id);
+CREATE INDEX IF NOT EXISTS idx_fault_links_linked_fault ON fault_links(linked_fault_id);
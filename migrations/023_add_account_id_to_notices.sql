@@ -0,0 +1,7 @@
+-- Add account_id to notices - The customer account or tenant a notice affected, extracted at
+-- ingest from the notice context (faults.account_fields). Notices stored earlier leave it NULL.
+ALTER TABLE notices ADD COLUMN IF NOT EXISTS account_id TEXT;
+
+-- Create indexes for "faults affecting an account" and "accounts affected by a fault"
+CREATE INDEX IF NOT EXISTS idx_notices_account_fault ON notices(account_id, fault_id) WHERE account_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_notices_fault_account ON notices(fault_id, account_id) WHERE account_id IS NOT NULL;
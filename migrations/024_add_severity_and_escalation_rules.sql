@@ -0,0 +1,29 @@
+-- Add severity to faults - low, medium, high or critical. Set by hand or raised automatically
+-- by escalation rules; faults created earlier start at low.
+ALTER TABLE faults ADD COLUMN IF NOT EXISTS severity TEXT NOT NULL DEFAULT 'low';
+
+-- Create escalation_rules table - Raise a fault's severity once it crosses a threshold
+-- A NULL project_id applies the rule to every project. Thresholds left NULL are not checked,
+-- but at least one must be set; they count within the last window_seconds, or over the
+-- fault's lifetime when window_seconds is NULL. Affected users are distinct user_id or
+-- user_email values in notice context.
+CREATE TABLE IF NOT EXISTS escalation_rules (
+    id BIGSERIAL PRIMARY KEY,
+    project_id BIGINT,
+    name TEXT NOT NULL,
+    min_occurrences BIGINT,
+    min_affected_users BIGINT,
+    window_seconds INT,
+    severity TEXT NOT NULL, -- medium, high or critical
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    match_count BIGINT NOT NULL DEFAULT 0,
+    last_matched_at TIMESTAMPTZ,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT escalation_rules_has_threshold CHECK (min_occurrences IS NOT NULL OR min_affected_users IS NOT NULL),
+    CONSTRAINT escalation_rules_window CHECK (window_seconds IS NULL OR window_seconds > 0)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_escalation_rules_project_id ON escalation_rules(project_id);
+CREATE INDEX IF NOT EXISTS idx_faults_severity ON faults(severity);
@@ -0,0 +1,17 @@
+-- Create backtraces table - Backtraces stored once and shared by every notice with the same
+-- frames. hash is the SHA-256 of the frames' JSON encoding; last_used_at is refreshed at most
+-- hourly while notices keep using a backtrace, so unused ones can be deleted. Notices stored
+-- before this migration keep their inline backtrace column, which is read when backtrace_hash
+-- is NULL.
+CREATE TABLE IF NOT EXISTS backtraces (
+    hash BYTEA PRIMARY KEY,
+    frames JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE notices ADD COLUMN IF NOT EXISTS backtrace_hash BYTEA REFERENCES backtraces(hash);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_notices_backtrace_hash ON notices(backtrace_hash) WHERE backtrace_hash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_backtraces_last_used_at ON backtraces(last_used_at);
@@ -0,0 +1,18 @@
+-- Create payload_sections table - Large notice payload sections that rarely change between
+-- notices, such as a host's environment data, stored once and shared by every notice sending
+-- them. hash is the SHA-256 of the section's JSON encoding; last_used_at is refreshed at most
+-- hourly while notices keep using a section, so unused ones can be deleted. Sections are only
+-- shared for projects with the notice_payload_dedup feature flag on; other notices keep their
+-- sections inline.
+CREATE TABLE IF NOT EXISTS payload_sections (
+    hash BYTEA PRIMARY KEY,
+    data JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE notices ADD COLUMN IF NOT EXISTS environment_hash BYTEA REFERENCES payload_sections(hash);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_notices_environment_hash ON notices(environment_hash) WHERE environment_hash IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_payload_sections_last_used_at ON payload_sections(last_used_at);
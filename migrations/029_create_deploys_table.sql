@@ -0,0 +1,24 @@
+-- Create deploys table - Deploys inferred from the first notice reported with each revision in
+-- an environment, with notice and fault counts for equal windows before and after the deploy.
+-- Counts are refreshed until the window after the deploy has ended, then kept as they are.
+CREATE TABLE IF NOT EXISTS deploys (
+    id BIGSERIAL PRIMARY KEY,
+    project_id BIGINT,
+    environment TEXT NOT NULL,
+    revision TEXT NOT NULL,
+    deployed_at TIMESTAMPTZ NOT NULL,
+    window_seconds INTEGER NOT NULL DEFAULT 0,
+    notices_before BIGINT NOT NULL DEFAULT 0,
+    notices_after BIGINT NOT NULL DEFAULT 0,
+    faults_before BIGINT NOT NULL DEFAULT 0,
+    faults_after BIGINT NOT NULL DEFAULT 0,
+    new_faults BIGINT NOT NULL DEFAULT 0,
+    spike BOOLEAN NOT NULL DEFAULT FALSE,
+    complete BOOLEAN NOT NULL DEFAULT FALSE,
+    analyzed_at TIMESTAMPTZ
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_deploys_revision ON deploys((COALESCE(project_id, -1)), environment, revision);
+CREATE INDEX IF NOT EXISTS idx_deploys_deployed_at ON deploys(deployed_at DESC);
+CREATE INDEX IF NOT EXISTS idx_deploys_incomplete ON deploys(deployed_at) WHERE NOT complete;
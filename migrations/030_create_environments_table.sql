@@ -0,0 +1,18 @@
+-- Create environments table - Environments faults are reported from, ordered by position in
+-- the order releases are promoted through them (dev, staging, production), with per-environment
+-- alerting and notice retention. Faults name their environment; environments without a row
+-- here alert and keep notices as usual. A row without project_id applies to every project
+-- without its own row of that name.
+CREATE TABLE IF NOT EXISTS environments (
+    id BIGSERIAL PRIMARY KEY,
+    project_id BIGINT,
+    name TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    alerting_enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    retention_days INTEGER,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_environments_name ON environments((COALESCE(project_id, -1)), name);
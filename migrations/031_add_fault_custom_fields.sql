@@ -0,0 +1,20 @@
+-- Add custom fields to faults - Per-project definitions of structured fields teams attach to
+-- faults (incident number, root-cause category), and the values set on each fault, keyed by
+-- field key. A definition without project_id applies to every project without its own
+-- definition of that key.
+CREATE TABLE IF NOT EXISTS fault_field_definitions (
+    id BIGSERIAL PRIMARY KEY,
+    project_id BIGINT,
+    key TEXT NOT NULL,
+    name TEXT NOT NULL,
+    type TEXT NOT NULL,
+    options TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE faults ADD COLUMN IF NOT EXISTS custom_fields JSONB NOT NULL DEFAULT '{}';
+
+-- Create indexes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fault_field_definitions_key ON fault_field_definitions((COALESCE(project_id, -1)), key);
+CREATE INDEX IF NOT EXISTS idx_faults_custom_fields ON faults USING GIN (custom_fields);
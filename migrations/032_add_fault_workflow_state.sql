@@ -0,0 +1,11 @@
+-- Add workflow state to faults - The state a fault is in within the configured workflow
+-- (new, acknowledged, in_progress, resolved, wontfix by default). The resolved and ignored
+-- flags are kept in step with it for older clients and queries.
+ALTER TABLE faults ADD COLUMN IF NOT EXISTS state TEXT NOT NULL DEFAULT 'new';
+
+UPDATE faults
+SET state = CASE WHEN ignored THEN 'wontfix' ELSE 'resolved' END
+WHERE state = 'new' AND (resolved OR ignored);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_faults_state ON faults(state);
@@ -0,0 +1,20 @@
+-- Add SLA timers to faults - When a fault was opened (created or reopened), acknowledged and
+-- closed (resolved or ignored), from which its per-severity acknowledge and resolve timers are
+-- computed, and when an at-risk notification was sent for each timer.
+ALTER TABLE faults ADD COLUMN IF NOT EXISTS opened_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE faults ADD COLUMN IF NOT EXISTS acknowledged_at TIMESTAMPTZ;
+ALTER TABLE faults ADD COLUMN IF NOT EXISTS closed_at TIMESTAMPTZ;
+ALTER TABLE faults ADD COLUMN IF NOT EXISTS sla_acknowledge_warned_at TIMESTAMPTZ;
+ALTER TABLE faults ADD COLUMN IF NOT EXISTS sla_resolve_warned_at TIMESTAMPTZ;
+
+-- Existing faults keep their history; they are not notified about, so upgrading does not send
+-- a notification for every old open fault
+UPDATE faults
+SET opened_at = first_seen_at,
+    acknowledged_at = CASE WHEN state <> 'new' THEN updated_at END,
+    closed_at = CASE WHEN resolved OR ignored THEN updated_at END,
+    sla_acknowledge_warned_at = NOW(),
+    sla_resolve_warned_at = NOW();
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_faults_open_sla ON faults(opened_at) WHERE closed_at IS NULL;
@@ -0,0 +1,8 @@
+-- Create grok_patterns table - Grok patterns defined from the admin API
+-- They are added to the pattern library of every instance, over the built-in and configured patterns.
+CREATE TABLE IF NOT EXISTS grok_patterns (
+    name TEXT PRIMARY KEY,
+    pattern TEXT NOT NULL,
+    updated_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
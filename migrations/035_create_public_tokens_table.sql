@@ -0,0 +1,11 @@
+-- Create public_tokens table - Read-only tokens for embedding wallboard dashboards
+-- Only a SHA-256 hash of each token is stored. A NULL project_id covers every project.
+CREATE TABLE IF NOT EXISTS public_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    project_id BIGINT,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
@@ -0,0 +1,9 @@
+-- Create parser_pipelines table - Chains of parsing and processing steps defined from the admin API
+-- A pipeline is used for logs from its sources and for API keys whose parser names it.
+CREATE TABLE IF NOT EXISTS parser_pipelines (
+    name TEXT PRIMARY KEY,
+    sources TEXT[] NOT NULL DEFAULT '{}',
+    steps JSONB NOT NULL,
+    updated_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
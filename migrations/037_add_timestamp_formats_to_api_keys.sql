@@ -0,0 +1,4 @@
+-- Timestamp layouts tried first for JSON logs sent with this key, and the timezone of their
+-- zone-less timestamps (NULL = parser.default_timezone)
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS timestamp_layouts TEXT[];
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS timezone TEXT;
@@ -0,0 +1,2 @@
+-- Log exports page through logs in (timestamp, id) order
+CREATE INDEX IF NOT EXISTS idx_logs_timestamp_id ON logs (timestamp, id);
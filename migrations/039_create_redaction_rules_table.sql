@@ -0,0 +1,18 @@
+-- Create redaction_rules table - Rules masking sensitive data in logs and notices, in addition
+-- to the scrub patterns from configuration. A NULL project_id applies the rule to logs and to
+-- the notices of every project. A key rule masks the values of keys matching its regular
+-- expression, a value rule masks the parts of strings matching it, and a path rule masks the
+-- value at a JSON path such as params.user.password.
+CREATE TABLE IF NOT EXISTS redaction_rules (
+    id BIGSERIAL PRIMARY KEY,
+    project_id BIGINT,
+    kind TEXT NOT NULL, -- key, value or path
+    pattern TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT redaction_rules_kind CHECK (kind IN ('key', 'value', 'path'))
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_redaction_rules_project_id ON redaction_rules(project_id);
@@ -0,0 +1,19 @@
+-- Create query_audit_log table - Who ran which guarded search and analytics queries, with the
+-- planner's estimate of their cost and whether they were allowed, queued or rejected.
+-- Estimates are NULL when the planner could not be asked.
+CREATE TABLE IF NOT EXISTS query_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    api_key_id BIGINT REFERENCES api_keys(id) ON DELETE SET NULL,
+    route TEXT NOT NULL,
+    query TEXT NOT NULL DEFAULT '',
+    estimated_rows DOUBLE PRECISION,
+    estimated_cost DOUBLE PRECISION,
+    outcome TEXT NOT NULL, -- allowed, queued or rejected
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT query_audit_log_outcome CHECK (outcome IN ('allowed', 'queued', 'rejected'))
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_query_audit_log_created_at ON query_audit_log(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_query_audit_log_user_id ON query_audit_log(user_id);
@@ -0,0 +1,20 @@
+-- Create log_schemas table - JSON schemas the metadata of incoming logs must satisfy, registered
+-- for a service or for the logs sent with an API key. In reject mode logs failing the schema
+-- are rejected; in flag mode they are stored with the violations listed in their metadata.
+CREATE TABLE IF NOT EXISTS log_schemas (
+    id BIGSERIAL PRIMARY KEY,
+    service TEXT,
+    api_key_id BIGINT REFERENCES api_keys(id) ON DELETE CASCADE,
+    schema JSONB NOT NULL,
+    mode TEXT NOT NULL DEFAULT 'reject', -- reject or flag
+    description TEXT NOT NULL DEFAULT '',
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT log_schemas_mode CHECK (mode IN ('reject', 'flag')),
+    CONSTRAINT log_schemas_target CHECK ((service IS NULL) <> (api_key_id IS NULL))
+);
+
+-- One schema per service and per API key
+CREATE UNIQUE INDEX IF NOT EXISTS idx_log_schemas_service ON log_schemas(service) WHERE service IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_log_schemas_api_key_id ON log_schemas(api_key_id) WHERE api_key_id IS NOT NULL;
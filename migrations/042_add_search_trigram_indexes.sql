@@ -0,0 +1,20 @@
+-- Trigram indexes for global search, which matches text anywhere in a value with ILIKE.
+-- Faults and comments are searched in full; notices and logs only over a recent window, which
+-- their time indexes already narrow, so they are left without one. Without the pg_trgm
+-- extension, or the privilege to create it, search still works by scanning.
+DO $$
+BEGIN
+    BEGIN
+        CREATE EXTENSION IF NOT EXISTS pg_trgm;
+    EXCEPTION WHEN OTHERS THEN
+        RAISE NOTICE 'Could not create pg_trgm extension: %', SQLERRM;
+    END;
+
+    IF EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'pg_trgm') THEN
+        CREATE INDEX IF NOT EXISTS idx_faults_error_class_trgm ON faults USING GIN (error_class gin_trgm_ops);
+        CREATE INDEX IF NOT EXISTS idx_faults_message_trgm ON faults USING GIN (message gin_trgm_ops);
+        CREATE INDEX IF NOT EXISTS idx_fault_comments_comment_trgm ON fault_comments USING GIN (comment gin_trgm_ops);
+    ELSE
+        RAISE NOTICE 'pg_trgm extension not found, global search will scan faults and comments';
+    END IF;
+END $$;
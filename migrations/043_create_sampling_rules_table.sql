@@ -0,0 +1,16 @@
+-- Create sampling_rules table - Rules dropping or sampling incoming logs before they are
+-- buffered, checked after the rules from configuration. A rule matches logs meeting every
+-- condition it sets: service, level and a regular expression matched against the message. It
+-- keeps sample_rate of them, so 0 drops them all and 0.01 keeps one in a hundred.
+CREATE TABLE IF NOT EXISTS sampling_rules (
+    id BIGSERIAL PRIMARY KEY,
+    service TEXT NOT NULL DEFAULT '',
+    level TEXT NOT NULL DEFAULT '',
+    message_pattern TEXT NOT NULL DEFAULT '',
+    sample_rate DOUBLE PRECISION NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT sampling_rules_condition CHECK (service <> '' OR level <> '' OR message_pattern <> ''),
+    CONSTRAINT sampling_rules_sample_rate CHECK (sample_rate >= 0 AND sample_rate <= 1)
+);
@@ -0,0 +1,3 @@
+-- Add a language preference to users, used for the report emails they receive
+-- Existing users keep receiving English reports
+ALTER TABLE users ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT 'en';
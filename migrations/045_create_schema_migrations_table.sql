@@ -0,0 +1,11 @@
+-- Create schema_migrations table - The migrations applied, so the service can check at startup
+-- that the schema is as recent as it needs. Every later migration records its version at its
+-- end. The migrations before this one are recorded here, as they ran before the table existed.
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_migrations (version)
+SELECT generate_series(1, 45)
+ON CONFLICT (version) DO NOTHING;
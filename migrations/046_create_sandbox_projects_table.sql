@@ -0,0 +1,15 @@
+-- Create sandbox_projects table - Projects holding generated sample data. Only these can be wiped,
+-- so a wipe never deletes a real project's faults.
+CREATE TABLE IF NOT EXISTS sandbox_projects (
+    project_id BIGINT PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Projects seeded before this table existed are recognized by their generated users
+INSERT INTO sandbox_projects (project_id)
+SELECT DISTINCT substring(email FROM '^demo-([0-9]+)-[0-9]+@sandbox\.invalid$')::BIGINT
+FROM users
+WHERE email ~ '^demo-[0-9]+-[0-9]+@sandbox\.invalid$'
+ON CONFLICT (project_id) DO NOTHING;
+
+INSERT INTO schema_migrations (version) VALUES (46) ON CONFLICT (version) DO NOTHING;
@@ -0,0 +1,16 @@
+-- Create webauthn_challenges table - Challenges of passkey ceremonies in progress
+-- A challenge is deleted when its ceremony is finished, successfully or not, so a response
+-- cannot be replayed. Expired challenges are pruned as new ones are created.
+CREATE TABLE IF NOT EXISTS webauthn_challenges (
+    id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL,
+    challenge BYTEA NOT NULL,
+    user_id BIGINT REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_webauthn_challenges_expires_at ON webauthn_challenges(expires_at);
+
+INSERT INTO schema_migrations (version) VALUES (47) ON CONFLICT (version) DO NOTHING;
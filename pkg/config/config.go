@@ -14,8 +14,34 @@ type Config struct {
 	Server   ServerConfig   `mapstructure:"server"`
 	Database DatabaseConfig `mapstructure:"database"`
 	Batch    BatchConfig    `mapstructure:"batch"`
+	NoticeBatch BatchConfig `mapstructure:"notice_batch"`
 	RateLimit RateLimitConfig `mapstructure:"ratelimit"`
 	Auth     AuthConfig     `mapstructure:"auth"`
+	Avatars  AvatarConfig   `mapstructure:"avatars"`
+	Idempotency IdempotencyConfig `mapstructure:"idempotency"`
+	Notifications NotificationConfig `mapstructure:"notifications"`
+	Scrub    ScrubConfig    `mapstructure:"scrub"`
+	Rejects  RejectsConfig  `mapstructure:"rejects"`
+	Jobs     JobsConfig     `mapstructure:"jobs"`
+	Faults   FaultConfig    `mapstructure:"faults"`
+	Parser   ParserConfig   `mapstructure:"parser"`
+	Enrich   EnrichConfig   `mapstructure:"enrich"`
+	Levels   LevelConfig    `mapstructure:"levels"`
+	MetadataLimits MetadataLimitConfig `mapstructure:"metadata_limits"`
+	Sampling SamplingConfig `mapstructure:"sampling"`
+	DeadLetter DeadLetterConfig `mapstructure:"dead_letter"`
+	WAL      WALConfig      `mapstructure:"wal"`
+	Preflight PreflightConfig `mapstructure:"preflight"`
+	Listeners []ListenerConfig `mapstructure:"listeners"`
+	Concurrency ConcurrencyConfig `mapstructure:"concurrency"`
+	Timeouts TimeoutConfig `mapstructure:"timeouts"`
+	Export   ExportConfig   `mapstructure:"export"`
+	QueryGuard QueryGuardConfig `mapstructure:"query_guard"`
+	AnalyticsCache AnalyticsCacheConfig `mapstructure:"analytics_cache"`
+	// Features turns feature flags on or off by name; runtime overrides from the admin API take precedence
+	Features map[string]bool `mapstructure:"features"`
+	// Source is where the YAML configuration was read from: a file, LOG_INGESTION_CONFIG_YAML, or none
+	Source string `mapstructure:"-"`
 }
 
 // ServerConfig holds server configuration
@@ -24,6 +50,65 @@ type ServerConfig struct {
 	Host         string        `mapstructure:"host"`
 	ReadTimeout  time.Duration `mapstructure:"read_timeout"`
 	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+	TLSCertFile  string        `mapstructure:"tls_cert_file"`
+	TLSKeyFile   string        `mapstructure:"tls_key_file"`
+}
+
+// MainListener is the name of the listener serving the full API on server.host:server.port
+const MainListener = "api"
+
+// Listener types
+const (
+	ListenerIngest  = "ingest"
+	ListenerSyslog  = "syslog"
+	ListenerOTLP    = "otlp"
+	ListenerSQS     = "sqs"
+	ListenerKinesis = "kinesis"
+)
+
+// Kinesis starting positions for shards without a checkpoint
+const (
+	StartingPositionLatest      = "LATEST"
+	StartingPositionTrimHorizon = "TRIM_HORIZON"
+)
+
+// Listener auth modes
+const (
+	ListenerAuthAPIKey = "api_key"
+	ListenerAuthNone   = "none"
+)
+
+// ListenerConfig declares an additional input alongside the main API server
+type ListenerConfig struct {
+	Name        string `mapstructure:"name"`
+	Type        string `mapstructure:"type"`
+	Host        string `mapstructure:"host"`
+	Port        int    `mapstructure:"port"`
+	TLSCertFile string `mapstructure:"tls_cert_file"`
+	TLSKeyFile  string `mapstructure:"tls_key_file"`
+	// Auth is api_key (default) or none for trusted networks
+	Auth string `mapstructure:"auth"`
+	// Parser is used for requests whose API key does not select one
+	Parser string `mapstructure:"parser"`
+	// Network is udp, tcp, or empty for both; syslog listeners only
+	Network string `mapstructure:"network"`
+	// QueueURL is the SQS queue polled by sqs listeners
+	QueueURL string `mapstructure:"queue_url"`
+	// Stream is the Kinesis data stream read by kinesis listeners
+	Stream string `mapstructure:"stream"`
+	// StartingPosition is LATEST (default) or TRIM_HORIZON for shards without a checkpoint
+	StartingPosition string `mapstructure:"starting_position"`
+	// DLQURL is an SQS queue receiving payloads that hold no valid logs; without it they are dropped
+	DLQURL string `mapstructure:"dlq_url"`
+	// Region defaults to AWS_REGION or AWS_DEFAULT_REGION
+	Region string `mapstructure:"region"`
+	// Endpoint overrides the AWS endpoint, for example for LocalStack
+	Endpoint string `mapstructure:"endpoint"`
+}
+
+// Polled reports whether the listener reads from AWS rather than accepting connections
+func (l ListenerConfig) Polled() bool {
+	return l.Type == ListenerSQS || l.Type == ListenerKinesis
 }
 
 // DatabaseConfig holds database configuration
@@ -31,15 +116,19 @@ type DatabaseConfig struct {
 	Host     string `mapstructure:"host"`
 	Port     int    `mapstructure:"port"`
 	User     string `mapstructure:"user"`
-	Password string `mapstructure:"password"`
+	Password string `mapstructure:"password" secret:"true"`
 	DBName   string `mapstructure:"dbname"`
 	SSLMode  string `mapstructure:"sslmode"`
+	// CapacityBytes is the disk space available to the database, used to project when it fills; 0 if unknown
+	CapacityBytes int64 `mapstructure:"capacity_bytes"`
 }
 
 // BatchConfig holds batch processing configuration
 type BatchConfig struct {
 	Size         int           `mapstructure:"size"`
 	FlushInterval time.Duration `mapstructure:"flush_interval"`
+	// Shards is the number of independently locked buffers; 0 uses GOMAXPROCS
+	Shards int `mapstructure:"shards"`
 }
 
 // RateLimitConfig holds rate limiting configuration
@@ -51,11 +140,399 @@ type RateLimitConfig struct {
 
 // AuthConfig holds authentication configuration
 type AuthConfig struct {
-	AdminAPIKeys []string `mapstructure:"admin_api_keys"`
-	JWTSecret    string   `mapstructure:"jwt_secret"`
+	AdminAPIKeys []string       `mapstructure:"admin_api_keys" secret:"true"`
+	JWTSecret    string         `mapstructure:"jwt_secret" secret:"true"`
+	WebAuthn     WebAuthnConfig `mapstructure:"webauthn"`
+}
+
+// WebAuthnConfig holds passkey settings. Passkeys are disabled while RPID is empty.
+type WebAuthnConfig struct {
+	// RPID is the relying party ID: the domain the UI is served from, such as logs.example.com
+	RPID   string `mapstructure:"rp_id"`
+	RPName string `mapstructure:"rp_name"`
+	// Origins are the origins ceremonies may come from; empty means https://RPID
+	Origins []string `mapstructure:"origins"`
+}
+
+// AvatarConfig holds avatar upload and storage configuration
+type AvatarConfig struct {
+	Dir            string `mapstructure:"dir"`
+	Size           int    `mapstructure:"size"`
+	MaxUploadBytes int64  `mapstructure:"max_upload_bytes"`
+}
+
+// ConcurrencyConfig holds limits on requests handled at once, shared by all listeners.
+// Each priority class may use up to its own limit of MaxInFlight.
+type ConcurrencyConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`
+	MaxInFlight    int           `mapstructure:"max_in_flight"`
+	IngestLimit    int           `mapstructure:"ingest_limit"`
+	QueryLimit     int           `mapstructure:"query_limit"`
+	AnalyticsLimit int           `mapstructure:"analytics_limit"`
+	QueueSize      int           `mapstructure:"queue_size"`
+	QueueTimeout   time.Duration `mapstructure:"queue_timeout"`
+}
+
+// TimeoutConfig holds handler deadlines per priority class, with overrides for individual
+// routes keyed as "METHOD /route/pattern" (e.g. "GET /api/v1/faults/:id"). Zero disables the deadline.
+type TimeoutConfig struct {
+	Ingest    time.Duration            `mapstructure:"ingest"`
+	Query     time.Duration            `mapstructure:"query"`
+	Analytics time.Duration            `mapstructure:"analytics"`
+	Routes    map[string]time.Duration `mapstructure:"routes"`
+}
+
+// ExportConfig bounds streaming log exports
+type ExportConfig struct {
+	// MaxRows caps the logs one export request returns; clients resume after the last one
+	MaxRows int `mapstructure:"max_rows"`
+	// BatchSize is the number of logs read per query and written between flushes
+	BatchSize int `mapstructure:"batch_size"`
+	// WriteTimeout bounds writing one batch to the client, replacing server.write_timeout,
+	// which would cut long exports short
+	WriteTimeout time.Duration `mapstructure:"write_timeout"`
+}
+
+// Actions taken on queries over the query guard limits
+const (
+	QueryGuardReject = "reject"
+	QueryGuardQueue  = "queue"
+)
+
+// QueryGuardConfig bounds fault searches, facets and log exports by the planner's estimate of
+// their cost, taken with EXPLAIN before they run
+type QueryGuardConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxRows is the most rows a query may be estimated to read
+	MaxRows float64 `mapstructure:"max_rows"`
+	// MaxDuration is the longest a query may be estimated to run, its cost divided by
+	// CostPerSecond
+	MaxDuration time.Duration `mapstructure:"max_duration"`
+	// CostPerSecond is how many units of planner cost this database gets through in a second
+	CostPerSecond float64 `mapstructure:"cost_per_second"`
+	// Action is reject, to refuse queries over the limits, or queue, to run them one slot at
+	// a time
+	Action string `mapstructure:"action"`
+	// QueueSlots is how many queries over the limits may run at once when queueing
+	QueueSlots int `mapstructure:"queue_slots"`
+	// QueueTimeout bounds the wait for a slot before the query is refused
+	QueueTimeout time.Duration `mapstructure:"queue_timeout"`
+}
+
+// AnalyticsCacheConfig holds stale-while-revalidate caching of analytics results: statistics,
+// fault facets and fault stats, and storage usage
+type AnalyticsCacheConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// TTL is how long a result is served without being recomputed
+	TTL time.Duration `mapstructure:"ttl"`
+	// MaxStale is how long after TTL a result is still served while it is recomputed in the
+	// background; older results are recomputed before responding
+	MaxStale time.Duration `mapstructure:"max_stale"`
+	// MaxEntries caps the results kept, the oldest being dropped first
+	MaxEntries int `mapstructure:"max_entries"`
+}
+
+// IdempotencyConfig holds Idempotency-Key handling configuration
+type IdempotencyConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	TTL     time.Duration `mapstructure:"ttl"`
+	// MaxBodyBytes bounds the bodies read to fingerprint requests with a key; larger ones are refused
+	MaxBodyBytes int64 `mapstructure:"max_body_bytes"`
+	// MaxEntries bounds the keys kept; the oldest are forgotten first
+	MaxEntries int `mapstructure:"max_entries"`
+	// MaxStoredBytes bounds the response bodies kept for replay; the oldest are forgotten first
+	MaxStoredBytes int64 `mapstructure:"max_stored_bytes"`
+}
+
+// RejectsConfig holds sampling of rejected payloads for debugging client integrations
+type RejectsConfig struct {
+	// SampleRate is the fraction of rejected payloads kept, from 0 (none) to 1 (all)
+	SampleRate     float64 `mapstructure:"sample_rate"`
+	MaxSamples     int     `mapstructure:"max_samples"`
+	MaxSampleBytes int     `mapstructure:"max_sample_bytes"`
+}
+
+// DeadLetterConfig holds where log batches that could not be written are kept for retrying
+type DeadLetterConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Dir     string `mapstructure:"dir"`
+	// MaxBytes bounds the disk used; batches failing once it is reached are lost
+	MaxBytes int64 `mapstructure:"max_bytes"`
+}
+
+// WALConfig holds the write-ahead log, which keeps buffered log entries on disk until they are
+// flushed so that a crash does not lose them
+type WALConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Dir     string `mapstructure:"dir"`
+	// SegmentBytes is the size at which a new segment file is started
+	SegmentBytes int64 `mapstructure:"segment_bytes"`
+	// Sync flushes each append to the disk, so entries also survive a crash of the machine
+	Sync bool `mapstructure:"sync"`
 }
 
-// Load reads configuration from environment variables and config files
+// PreflightConfig holds the checks made against the database before the service reports ready
+type PreflightConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// RequiredExtensions are the PostgreSQL extensions that must be installed
+	RequiredExtensions []string `mapstructure:"required_extensions"`
+	// MaxClockSkew is how far the local clock may be from the database's
+	MaxClockSkew time.Duration `mapstructure:"max_clock_skew"`
+	// Timeout bounds each check
+	Timeout time.Duration `mapstructure:"timeout"`
+	// RetryInterval is how often failed checks are run again
+	RetryInterval time.Duration `mapstructure:"retry_interval"`
+	// RequireSSL fails the encryption check unless connections to the database use SSL
+	RequireSSL bool `mapstructure:"require_ssl"`
+	// EncryptedTablespaces are the tablespaces on encrypted storage; when set, the encryption
+	// check fails unless every table and index is in one of them
+	EncryptedTablespaces []string `mapstructure:"encrypted_tablespaces"`
+}
+
+// JobsConfig holds background job worker configuration
+type JobsConfig struct {
+	// Workers is the number of jobs run at once by this instance; 0 runs none here
+	Workers      int           `mapstructure:"workers"`
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+	// Lease is how long a running job stays claimed without a heartbeat before another worker retries it
+	Lease       time.Duration `mapstructure:"lease"`
+	MaxAttempts int           `mapstructure:"max_attempts"`
+	// Retention is how long finished jobs are kept
+	Retention time.Duration `mapstructure:"retention"`
+}
+
+// NotificationConfig holds outgoing notification configuration
+type NotificationConfig struct {
+	WebhookURLs []string      `mapstructure:"webhook_urls" secret:"true"`
+	Timeout     time.Duration `mapstructure:"timeout"`
+	SMTP        SMTPConfig    `mapstructure:"smtp"`
+}
+
+// SMTPConfig holds the mail server used for email delivery; email is disabled without a host
+type SMTPConfig struct {
+	Host     string `mapstructure:"host"`
+	Port     int    `mapstructure:"port"`
+	Username string `mapstructure:"username"`
+	Password string `mapstructure:"password" secret:"true"`
+	From     string `mapstructure:"from"`
+}
+
+// FaultConfig holds error tracking configuration
+type FaultConfig struct {
+	// AccountFields are notice context paths tried in order for the affected account or tenant;
+	// dotted paths reach into nested objects
+	AccountFields []string `mapstructure:"account_fields"`
+	// Deploys configures the error rate comparison made around each deploy
+	Deploys DeployConfig `mapstructure:"deploys"`
+	// Workflow declares the states faults move through
+	Workflow WorkflowConfig `mapstructure:"workflow"`
+	// SLA sets how soon faults of each severity must be acknowledged and resolved
+	SLA SLAConfig `mapstructure:"sla"`
+	// Sampling limits the notices stored for a fault during an error storm
+	Sampling NoticeSamplingConfig `mapstructure:"sampling"`
+}
+
+// NoticeSamplingConfig limits the notices stored for a fault. Once a fault has had Threshold
+// notices in a minute, only SampleRate of its further notices that minute are stored; the rest
+// only count towards its occurrences.
+type NoticeSamplingConfig struct {
+	// Threshold is the notices per fault per minute stored in full; 0 stores every notice
+	Threshold int `mapstructure:"threshold"`
+	// SampleRate is the fraction of notices over the threshold that are stored
+	SampleRate float64 `mapstructure:"sample_rate"`
+}
+
+// SLAConfig holds per-severity acknowledge and resolve targets for faults
+type SLAConfig struct {
+	// Targets maps a severity to its targets; severities without targets have no SLA
+	Targets map[string]SLATarget `mapstructure:"targets"`
+	// WarnAt is the fraction of a target that has elapsed when its timer is at risk
+	WarnAt float64 `mapstructure:"warn_at"`
+}
+
+// SLATarget is how long after a fault opens it must be acknowledged and resolved; zero means
+// no target
+type SLATarget struct {
+	Acknowledge time.Duration `mapstructure:"acknowledge"`
+	Resolve     time.Duration `mapstructure:"resolve"`
+}
+
+// slaSeverities are the severities SLA targets can be set for from the environment
+var slaSeverities = []string{"low", "medium", "high", "critical"}
+
+// WorkflowConfig declares the states faults move through and the moves allowed between them
+type WorkflowConfig struct {
+	// States lists the workflow states; the first is given to new and reopened faults
+	States []string `mapstructure:"states"`
+	// Transitions maps each state to the states it may move to. With no transitions, any
+	// state may move to any other.
+	Transitions map[string][]string `mapstructure:"transitions"`
+	// ResolvedState and IgnoredState are the states the legacy resolved and ignored flags map to
+	ResolvedState string `mapstructure:"resolved_state"`
+	IgnoredState  string `mapstructure:"ignored_state"`
+}
+
+// defaultWorkflowTransitions are the moves allowed by the default workflow states
+var defaultWorkflowTransitions = map[string][]string{
+	"new":          {"acknowledged", "in_progress", "resolved", "wontfix"},
+	"acknowledged": {"new", "in_progress", "resolved", "wontfix"},
+	"in_progress":  {"acknowledged", "resolved", "wontfix"},
+	"resolved":     {"new"},
+	"wontfix":      {"new"},
+}
+
+// DeployConfig holds the error rate comparison made around each deploy
+type DeployConfig struct {
+	// Window is the length of the periods before and after a deploy whose notices are compared
+	Window time.Duration `mapstructure:"window"`
+	// SpikeFactor is how many times more notices must follow a deploy than preceded it for a spike
+	SpikeFactor float64 `mapstructure:"spike_factor"`
+	// MinNotices is the fewest notices after a deploy that can make a spike
+	MinNotices int64 `mapstructure:"min_notices"`
+}
+
+// ScrubConfig holds patterns for masking sensitive data in log metadata and notices
+type ScrubConfig struct {
+	KeyPatterns   []string `mapstructure:"key_patterns"`
+	ValuePatterns []string `mapstructure:"value_patterns"`
+	// Paths are JSON paths whose values are masked, starting at a log's metadata or a notice's
+	// context, params, session, cookies or environment, e.g. "params.user.password"
+	Paths []string `mapstructure:"paths"`
+}
+
+// ParserConfig holds alternative JSON fields for core log values, tried in order
+type ParserConfig struct {
+	LevelFields     []string `mapstructure:"level_fields"`
+	ServiceFields   []string `mapstructure:"service_fields"`
+	TimestampFields []string `mapstructure:"timestamp_fields"`
+	MessageFields   []string `mapstructure:"message_fields"`
+	DefaultTimezone string   `mapstructure:"default_timezone"`
+	// ServiceTimestamps adds timestamp layouts and a default timezone for the JSON logs of
+	// each named service
+	ServiceTimestamps map[string]TimestampFormatConfig `mapstructure:"service_timestamps"`
+	// Custom declares regex and grok parsers; Sources maps source tags to parser names
+	Custom  []CustomParserConfig `mapstructure:"custom"`
+	Sources map[string]string    `mapstructure:"sources"`
+	// GrokPatterns adds named patterns to the grok library, or replaces built-in ones.
+	// Names are case-insensitive.
+	GrokPatterns map[string]string `mapstructure:"grok_patterns"`
+	// Multiline joins the lines of one log, such as a stack trace, before text parsing
+	Multiline MultilineConfig `mapstructure:"multiline"`
+	// Processors names compiled-in processors run, in order, on every parsed log
+	Processors []string `mapstructure:"processors"`
+}
+
+// TimestampFormatConfig declares the timestamps of one source, such as a legacy system
+// writing "02/Jan/2006 15:04:05"
+type TimestampFormatConfig struct {
+	// Layouts are Go time layouts tried before the usual formats
+	Layouts []string `mapstructure:"layouts"`
+	// Timezone is the IANA timezone of timestamps without an offset; empty keeps
+	// parser.default_timezone
+	Timezone string `mapstructure:"timezone"`
+}
+
+// MultilineConfig decides which text lines continue the log before them. It is off unless a
+// pattern is set. A line is joined onto the previous one when it matches ContinuationPattern,
+// or when StartPattern is set and it does not match it.
+type MultilineConfig struct {
+	StartPattern        string `mapstructure:"start_pattern"`
+	ContinuationPattern string `mapstructure:"continuation_pattern"`
+	// Timeout ends a log that received no line for this long, for sources read as a stream
+	Timeout time.Duration `mapstructure:"timeout"`
+	// MaxLines ends a log at this many lines
+	MaxLines int `mapstructure:"max_lines"`
+}
+
+// Enabled reports whether multi-line joining is configured
+func (m MultilineConfig) Enabled() bool {
+	return m.StartPattern != "" || m.ContinuationPattern != ""
+}
+
+// CustomParserConfig declares a regex or grok parser in configuration; exactly one of
+// Pattern and Grok is set
+type CustomParserConfig struct {
+	Name            string   `mapstructure:"name"`
+	Pattern         string   `mapstructure:"pattern"`
+	// Grok is a grok expression such as "%{IP:client} %{WORD:method} %{GREEDYDATA:message}"
+	Grok            string   `mapstructure:"grok"`
+	// TimestampFormat is a Go time layout for the timestamp group; empty accepts the usual formats
+	TimestampFormat string   `mapstructure:"timestamp_format"`
+	ContentTypes    []string `mapstructure:"content_types"`
+	Service         string   `mapstructure:"service"`
+	Level           string   `mapstructure:"level"`
+}
+
+// EnrichConfig configures the geoip and user_agent processors, which add geo and browser
+// fields to logs that name them in parser.processors or a pipeline, and the same enrichment
+// of notices
+type EnrichConfig struct {
+	// GeoIPDatabase is the path of a MaxMind GeoIP2 or GeoLite2 City or Country database; the
+	// geoip processor is only available when it is set
+	GeoIPDatabase string `mapstructure:"geoip_database"`
+	// IPFields and UserAgentFields are metadata, or notice context, paths tried in order for the
+	// client IP and user agent; dotted paths reach into nested objects
+	IPFields        []string `mapstructure:"ip_fields"`
+	UserAgentFields []string `mapstructure:"user_agent_fields"`
+	// Notices enriches notices from their request context and CGI data
+	Notices bool `mapstructure:"notices"`
+}
+
+// CanonicalLevels are the levels logs are stored with
+var CanonicalLevels = []string{"DEBUG", "INFO", "WARN", "WARNING", "ERROR", "FATAL", "CRITICAL"}
+
+// LevelConfig maps the levels logs are sent with to canonical levels. Common names such as
+// TRACE and NOTICE are mapped without configuration; anything else that is not canonical is
+// rejected.
+type LevelConfig struct {
+	// Aliases maps further level names, case-insensitively, to canonical levels. They take
+	// precedence over the built-in aliases.
+	Aliases map[string]string `mapstructure:"aliases"`
+	// SyslogSeverities are the canonical levels of the numeric syslog severities 0 (emerg) to
+	// 7 (debug), used for syslog messages and logs whose level is such a number
+	SyslogSeverities []string `mapstructure:"syslog_severities"`
+}
+
+// MetadataLimitConfig bounds the metadata of logs and the context, params, session, cookies
+// and environment data of notices. Data over a limit is truncated, and marked as such, rather
+// than rejected.
+type MetadataLimitConfig struct {
+	// MaxKeys caps the keys at all depths; keys past it, nearest the top first, are dropped
+	MaxKeys int `mapstructure:"max_keys"`
+	// MaxDepth caps nesting, the top-level object being depth 1; deeper objects and arrays
+	// are replaced
+	MaxDepth int `mapstructure:"max_depth"`
+	// MaxBytes caps the JSON size; the largest values are replaced until it fits
+	MaxBytes int `mapstructure:"max_bytes"`
+}
+
+// SamplingConfig holds rules dropping or sampling logs before they are buffered for storage.
+// Rules added from the admin API are checked after these.
+type SamplingConfig struct {
+	Rules []SamplingRuleConfig `mapstructure:"rules"`
+}
+
+// SamplingRuleConfig keeps a fraction of the logs matching every condition it sets: the
+// service, the level and a regular expression matched against the message. The first
+// matching rule decides.
+type SamplingRuleConfig struct {
+	Service        string `mapstructure:"service"`
+	Level          string `mapstructure:"level"`
+	MessagePattern string `mapstructure:"message_pattern"`
+	// SampleRate is the fraction of matching logs kept, from 0, which drops them all, to 1
+	SampleRate float64 `mapstructure:"sample_rate"`
+}
+
+// ConfigYAMLEnv holds a complete YAML configuration, e.g. from a Kubernetes secret
+const ConfigYAMLEnv = "LOG_INGESTION_CONFIG_YAML"
+
+// ConfigFileEnv points to a YAML configuration file outside the default search paths, e.g. a mounted secret
+const ConfigFileEnv = "LOG_INGESTION_CONFIG_FILE"
+
+// Load reads configuration from environment variables and config files.
+// The YAML source is, in order of preference, LOG_INGESTION_CONFIG_YAML, the file named by
+// LOG_INGESTION_CONFIG_FILE, or config.yaml in the working directory or ./config.
+// Individual environment variables override values from YAML.
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
@@ -72,8 +549,18 @@ func Load() (*Config, error) {
 	// Bind environment variables
 	bindEnvVars()
 	
-	// Try to read config file (optional)
-	if err := viper.ReadInConfig(); err != nil {
+	// Read YAML configuration from the environment, an explicit file or the search paths
+	if blob := os.Getenv(ConfigYAMLEnv); blob != "" {
+		if err := viper.ReadConfig(strings.NewReader(blob)); err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", ConfigYAMLEnv, err)
+		}
+	} else if path := os.Getenv(ConfigFileEnv); path != "" {
+		viper.SetConfigFile(path)
+		if err := viper.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("error reading config file %s: %w", path, err)
+		}
+	} else if err := viper.ReadInConfig(); err != nil {
+		// The default config file is optional
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
 			return nil, fmt.Errorf("error reading config file: %w", err)
 		}
@@ -83,6 +570,27 @@ func Load() (*Config, error) {
 	if err := viper.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("error unmarshaling config: %w", err)
 	}
+	if os.Getenv(ConfigYAMLEnv) != "" {
+		config.Source = ConfigYAMLEnv
+	} else {
+		config.Source = viper.ConfigFileUsed()
+	}
+	
+	// Transitions are not a viper default, since viper would merge them into configured ones
+	if len(config.Faults.Workflow.Transitions) == 0 && !viper.InConfig("faults.workflow.states") && os.Getenv("LOG_INGESTION_FAULTS_WORKFLOW_STATES") == "" {
+		config.Faults.Workflow.Transitions = defaultWorkflowTransitions
+	}
+	
+	// AWS listeners default to the region the AWS tooling uses
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	for i := range config.Listeners {
+		if config.Listeners[i].Polled() && config.Listeners[i].Region == "" {
+			config.Listeners[i].Region = region
+		}
+	}
 	
 	return &config, nil
 }
@@ -99,33 +607,248 @@ func setDefaults() {
 	viper.SetDefault("database.password", "postgres")
 	viper.SetDefault("database.dbname", "logs")
 	viper.SetDefault("database.sslmode", "disable")
+	viper.SetDefault("database.capacity_bytes", 0)
 	
 	viper.SetDefault("batch.size", 1000)
 	viper.SetDefault("batch.flush_interval", "5s")
+	viper.SetDefault("batch.shards", 0)
+	
+	// Notices are flushed sooner than logs so new faults show up promptly
+	viper.SetDefault("notice_batch.size", 200)
+	viper.SetDefault("notice_batch.flush_interval", "250ms")
 	
 	viper.SetDefault("ratelimit.enabled", true)
 	viper.SetDefault("ratelimit.default_rps", 100)
 	viper.SetDefault("ratelimit.burst", 200)
 	
 	viper.SetDefault("auth.jwt_secret", "dev-secret-change-me-in-production")
+	viper.SetDefault("auth.webauthn.rp_name", "cmd-log")
+	
+	viper.SetDefault("avatars.dir", "./data/avatars")
+	viper.SetDefault("avatars.size", 256)
+	viper.SetDefault("avatars.max_upload_bytes", 5<<20)
+	
+	viper.SetDefault("concurrency.enabled", true)
+	viper.SetDefault("concurrency.max_in_flight", 64)
+	viper.SetDefault("concurrency.ingest_limit", 64)
+	viper.SetDefault("concurrency.query_limit", 32)
+	viper.SetDefault("concurrency.analytics_limit", 8)
+	viper.SetDefault("concurrency.queue_size", 100)
+	viper.SetDefault("concurrency.queue_timeout", "2s")
+	
+	viper.SetDefault("timeouts.ingest", "2s")
+	viper.SetDefault("timeouts.query", "10s")
+	viper.SetDefault("timeouts.analytics", "30s")
+	viper.SetDefault("timeouts.routes", map[string]string{
+		"post /admin/sandbox/seed": "2m",
+		"post /api/v1/logs/ndjson": "5m",
+	})
+	
+	viper.SetDefault("idempotency.enabled", true)
+	viper.SetDefault("idempotency.ttl", "10m")
+	viper.SetDefault("idempotency.max_body_bytes", 10<<20)
+	viper.SetDefault("idempotency.max_entries", 10000)
+	viper.SetDefault("idempotency.max_stored_bytes", 64<<20)
+	
+	viper.SetDefault("notifications.timeout", "10s")
+	viper.SetDefault("notifications.smtp.port", 587)
+	
+	viper.SetDefault("rejects.sample_rate", 0)
+	viper.SetDefault("rejects.max_samples", 100)
+	viper.SetDefault("rejects.max_sample_bytes", 4096)
+	viper.SetDefault("dead_letter.enabled", true)
+	viper.SetDefault("dead_letter.dir", "./data/dead-letter")
+	viper.SetDefault("dead_letter.max_bytes", 1<<30)
+	viper.SetDefault("wal.enabled", false)
+	viper.SetDefault("wal.dir", "./data/wal")
+	viper.SetDefault("wal.segment_bytes", 64<<20)
+	viper.SetDefault("wal.sync", false)
+	viper.SetDefault("preflight.enabled", true)
+	viper.SetDefault("preflight.required_extensions", []string{"timescaledb", "pg_trgm"})
+	viper.SetDefault("preflight.max_clock_skew", "5s")
+	viper.SetDefault("preflight.timeout", "5s")
+	viper.SetDefault("preflight.retry_interval", "30s")
+	viper.SetDefault("preflight.require_ssl", false)
+	viper.SetDefault("preflight.encrypted_tablespaces", []string{})
+	
+	viper.SetDefault("jobs.workers", 2)
+	viper.SetDefault("jobs.poll_interval", "2s")
+	viper.SetDefault("jobs.lease", "1m")
+	viper.SetDefault("jobs.max_attempts", 3)
+	viper.SetDefault("jobs.retention", "168h")
+	
+	viper.SetDefault("faults.deploys.window", "1h")
+	viper.SetDefault("faults.deploys.spike_factor", 2.0)
+	viper.SetDefault("faults.deploys.min_notices", 10)
+	viper.SetDefault("faults.workflow.states", []string{"new", "acknowledged", "in_progress", "resolved", "wontfix"})
+	viper.SetDefault("faults.workflow.resolved_state", "resolved")
+	viper.SetDefault("faults.workflow.ignored_state", "wontfix")
+	viper.SetDefault("faults.sla.targets.critical.acknowledge", "30m")
+	viper.SetDefault("faults.sla.targets.critical.resolve", "24h")
+	viper.SetDefault("faults.sla.targets.high.acknowledge", "4h")
+	viper.SetDefault("faults.sla.targets.high.resolve", "72h")
+	viper.SetDefault("faults.sla.targets.medium.acknowledge", "24h")
+	viper.SetDefault("faults.sla.targets.medium.resolve", "168h")
+	viper.SetDefault("faults.sla.warn_at", 0.8)
+	viper.SetDefault("faults.sampling.threshold", 0)
+	viper.SetDefault("faults.sampling.sample_rate", 0.01)
+	viper.SetDefault("faults.account_fields", []string{"account_id", "tenant_id", "account.id", "tenant.id", "organization_id", "org_id"})
+	
+	viper.SetDefault("scrub.key_patterns", []string{
+		`^(password|passwd|token|secret|api_?key|auth|authorization|cookie|credit_card|ssn|social_security)$`,
+	})
+	viper.SetDefault("scrub.value_patterns", []string{
+		`(?i)bearer\s+[a-z0-9._~+/=-]+`,
+		`eyJ[a-zA-Z0-9_-]+\.[a-zA-Z0-9_-]+\.[a-zA-Z0-9_-]+`,
+		`\b\d{4}[ -]?\d{4}[ -]?\d{4}[ -]?\d{4}\b`,
+	})
+	
+	viper.SetDefault("parser.level_fields", []string{"severity", "lvl", "log.level", "levelname", "loglevel", "severity_text"})
+	viper.SetDefault("parser.service_fields", []string{"service.name", "app", "application", "logger", "component"})
+	viper.SetDefault("parser.timestamp_fields", []string{"@timestamp", "time", "ts", "datetime"})
+	viper.SetDefault("parser.message_fields", []string{"msg", "@message", "log", "event"})
+	viper.SetDefault("parser.default_timezone", "UTC")
+	viper.SetDefault("parser.multiline.timeout", "2s")
+	viper.SetDefault("parser.multiline.max_lines", 500)
+	
+	viper.SetDefault("export.max_rows", 1000000)
+	viper.SetDefault("export.batch_size", 1000)
+	viper.SetDefault("export.write_timeout", "30s")
+	
+	viper.SetDefault("query_guard.enabled", true)
+	viper.SetDefault("query_guard.max_rows", 10000000)
+	viper.SetDefault("query_guard.max_duration", "30s")
+	viper.SetDefault("query_guard.cost_per_second", 100000)
+	viper.SetDefault("query_guard.action", QueryGuardReject)
+	viper.SetDefault("query_guard.queue_slots", 1)
+	viper.SetDefault("query_guard.queue_timeout", "30s")
+	
+	viper.SetDefault("analytics_cache.enabled", true)
+	viper.SetDefault("analytics_cache.ttl", "30s")
+	viper.SetDefault("analytics_cache.max_stale", "10m")
+	viper.SetDefault("analytics_cache.max_entries", 1000)
+	
+	viper.SetDefault("levels.syslog_severities", []string{"FATAL", "FATAL", "CRITICAL", "ERROR", "WARN", "INFO", "INFO", "DEBUG"})
+	
+	viper.SetDefault("metadata_limits.max_keys", 1000)
+	viper.SetDefault("metadata_limits.max_depth", 10)
+	viper.SetDefault("metadata_limits.max_bytes", 65536)
+	
+	viper.SetDefault("enrich.ip_fields", []string{"client_ip", "ip", "remote_addr", "http.client_ip", "REMOTE_ADDR"})
+	viper.SetDefault("enrich.user_agent_fields", []string{"user_agent", "http.user_agent", "userAgent", "HTTP_USER_AGENT"})
 }
 
 func bindEnvVars() {
 	viper.BindEnv("server.port", "LOG_INGESTION_SERVER_PORT")
 	viper.BindEnv("server.host", "LOG_INGESTION_SERVER_HOST")
+	viper.BindEnv("server.tls_cert_file", "LOG_INGESTION_SERVER_TLS_CERT_FILE")
+	viper.BindEnv("server.tls_key_file", "LOG_INGESTION_SERVER_TLS_KEY_FILE")
 	viper.BindEnv("database.host", "LOG_INGESTION_DB_HOST")
 	viper.BindEnv("database.port", "LOG_INGESTION_DB_PORT")
 	viper.BindEnv("database.user", "LOG_INGESTION_DB_USER")
 	viper.BindEnv("database.password", "LOG_INGESTION_DB_PASSWORD")
 	viper.BindEnv("database.dbname", "LOG_INGESTION_DB_NAME")
 	viper.BindEnv("database.sslmode", "LOG_INGESTION_DB_SSLMODE")
+	viper.BindEnv("database.capacity_bytes", "LOG_INGESTION_DB_CAPACITY_BYTES")
 	viper.BindEnv("batch.size", "LOG_INGESTION_BATCH_SIZE")
 	viper.BindEnv("batch.flush_interval", "LOG_INGESTION_BATCH_FLUSH_INTERVAL")
+	viper.BindEnv("batch.shards", "LOG_INGESTION_BATCH_SHARDS")
+	viper.BindEnv("notice_batch.size", "LOG_INGESTION_NOTICE_BATCH_SIZE")
+	viper.BindEnv("notice_batch.flush_interval", "LOG_INGESTION_NOTICE_BATCH_FLUSH_INTERVAL")
 	viper.BindEnv("ratelimit.enabled", "LOG_INGESTION_RATELIMIT_ENABLED")
 	viper.BindEnv("ratelimit.default_rps", "LOG_INGESTION_RATELIMIT_DEFAULT_RPS")
 	viper.BindEnv("ratelimit.burst", "LOG_INGESTION_RATELIMIT_BURST")
 	
 	viper.BindEnv("auth.jwt_secret", "LOG_INGESTION_JWT_SECRET")
+	viper.BindEnv("auth.webauthn.rp_id", "LOG_INGESTION_WEBAUTHN_RP_ID")
+	viper.BindEnv("auth.webauthn.rp_name", "LOG_INGESTION_WEBAUTHN_RP_NAME")
+	
+	viper.BindEnv("avatars.dir", "LOG_INGESTION_AVATARS_DIR")
+	viper.BindEnv("avatars.size", "LOG_INGESTION_AVATARS_SIZE")
+	viper.BindEnv("avatars.max_upload_bytes", "LOG_INGESTION_AVATARS_MAX_UPLOAD_BYTES")
+	
+	viper.BindEnv("idempotency.enabled", "LOG_INGESTION_IDEMPOTENCY_ENABLED")
+	viper.BindEnv("idempotency.ttl", "LOG_INGESTION_IDEMPOTENCY_TTL")
+	viper.BindEnv("idempotency.max_body_bytes", "LOG_INGESTION_IDEMPOTENCY_MAX_BODY_BYTES")
+	viper.BindEnv("idempotency.max_entries", "LOG_INGESTION_IDEMPOTENCY_MAX_ENTRIES")
+	viper.BindEnv("idempotency.max_stored_bytes", "LOG_INGESTION_IDEMPOTENCY_MAX_STORED_BYTES")
+	viper.BindEnv("rejects.sample_rate", "LOG_INGESTION_REJECTS_SAMPLE_RATE")
+	viper.BindEnv("rejects.max_samples", "LOG_INGESTION_REJECTS_MAX_SAMPLES")
+	viper.BindEnv("rejects.max_sample_bytes", "LOG_INGESTION_REJECTS_MAX_SAMPLE_BYTES")
+	viper.BindEnv("dead_letter.enabled", "LOG_INGESTION_DEAD_LETTER_ENABLED")
+	viper.BindEnv("dead_letter.dir", "LOG_INGESTION_DEAD_LETTER_DIR")
+	viper.BindEnv("dead_letter.max_bytes", "LOG_INGESTION_DEAD_LETTER_MAX_BYTES")
+	viper.BindEnv("wal.enabled", "LOG_INGESTION_WAL_ENABLED")
+	viper.BindEnv("wal.dir", "LOG_INGESTION_WAL_DIR")
+	viper.BindEnv("wal.segment_bytes", "LOG_INGESTION_WAL_SEGMENT_BYTES")
+	viper.BindEnv("wal.sync", "LOG_INGESTION_WAL_SYNC")
+	viper.BindEnv("preflight.enabled", "LOG_INGESTION_PREFLIGHT_ENABLED")
+	viper.BindEnv("preflight.max_clock_skew", "LOG_INGESTION_PREFLIGHT_MAX_CLOCK_SKEW")
+	viper.BindEnv("preflight.timeout", "LOG_INGESTION_PREFLIGHT_TIMEOUT")
+	viper.BindEnv("preflight.retry_interval", "LOG_INGESTION_PREFLIGHT_RETRY_INTERVAL")
+	viper.BindEnv("preflight.require_ssl", "LOG_INGESTION_PREFLIGHT_REQUIRE_SSL")
+	viper.BindEnv("jobs.workers", "LOG_INGESTION_JOBS_WORKERS")
+	viper.BindEnv("jobs.poll_interval", "LOG_INGESTION_JOBS_POLL_INTERVAL")
+	viper.BindEnv("jobs.lease", "LOG_INGESTION_JOBS_LEASE")
+	viper.BindEnv("jobs.max_attempts", "LOG_INGESTION_JOBS_MAX_ATTEMPTS")
+	viper.BindEnv("jobs.retention", "LOG_INGESTION_JOBS_RETENTION")
+	
+	viper.BindEnv("faults.deploys.window", "LOG_INGESTION_FAULTS_DEPLOYS_WINDOW")
+	viper.BindEnv("faults.deploys.spike_factor", "LOG_INGESTION_FAULTS_DEPLOYS_SPIKE_FACTOR")
+	viper.BindEnv("faults.deploys.min_notices", "LOG_INGESTION_FAULTS_DEPLOYS_MIN_NOTICES")
+	viper.BindEnv("faults.workflow.resolved_state", "LOG_INGESTION_FAULTS_WORKFLOW_RESOLVED_STATE")
+	viper.BindEnv("faults.workflow.ignored_state", "LOG_INGESTION_FAULTS_WORKFLOW_IGNORED_STATE")
+	viper.BindEnv("faults.sla.warn_at", "LOG_INGESTION_FAULTS_SLA_WARN_AT")
+	viper.BindEnv("faults.sampling.threshold", "LOG_INGESTION_FAULTS_SAMPLING_THRESHOLD")
+	viper.BindEnv("faults.sampling.sample_rate", "LOG_INGESTION_FAULTS_SAMPLING_SAMPLE_RATE")
+	for _, severity := range slaSeverities {
+		for _, timer := range []string{"acknowledge", "resolve"} {
+			viper.BindEnv("faults.sla.targets."+severity+"."+timer,
+				"LOG_INGESTION_FAULTS_SLA_"+strings.ToUpper(severity+"_"+timer))
+		}
+	}
+	
+	viper.BindEnv("notifications.timeout", "LOG_INGESTION_NOTIFICATIONS_TIMEOUT")
+	viper.BindEnv("notifications.smtp.host", "LOG_INGESTION_NOTIFICATIONS_SMTP_HOST")
+	viper.BindEnv("notifications.smtp.port", "LOG_INGESTION_NOTIFICATIONS_SMTP_PORT")
+	viper.BindEnv("notifications.smtp.username", "LOG_INGESTION_NOTIFICATIONS_SMTP_USERNAME")
+	viper.BindEnv("notifications.smtp.password", "LOG_INGESTION_NOTIFICATIONS_SMTP_PASSWORD")
+	viper.BindEnv("notifications.smtp.from", "LOG_INGESTION_NOTIFICATIONS_SMTP_FROM")
+	
+	viper.BindEnv("parser.default_timezone", "LOG_INGESTION_PARSER_DEFAULT_TIMEZONE")
+	viper.BindEnv("parser.multiline.start_pattern", "LOG_INGESTION_PARSER_MULTILINE_START_PATTERN")
+	viper.BindEnv("parser.multiline.continuation_pattern", "LOG_INGESTION_PARSER_MULTILINE_CONTINUATION_PATTERN")
+	viper.BindEnv("parser.multiline.timeout", "LOG_INGESTION_PARSER_MULTILINE_TIMEOUT")
+	viper.BindEnv("parser.multiline.max_lines", "LOG_INGESTION_PARSER_MULTILINE_MAX_LINES")
+	viper.BindEnv("enrich.geoip_database", "LOG_INGESTION_ENRICH_GEOIP_DATABASE")
+	viper.BindEnv("enrich.notices", "LOG_INGESTION_ENRICH_NOTICES")
+	viper.BindEnv("concurrency.enabled", "LOG_INGESTION_CONCURRENCY_ENABLED")
+	viper.BindEnv("concurrency.max_in_flight", "LOG_INGESTION_CONCURRENCY_MAX_IN_FLIGHT")
+	viper.BindEnv("concurrency.ingest_limit", "LOG_INGESTION_CONCURRENCY_INGEST_LIMIT")
+	viper.BindEnv("concurrency.query_limit", "LOG_INGESTION_CONCURRENCY_QUERY_LIMIT")
+	viper.BindEnv("concurrency.analytics_limit", "LOG_INGESTION_CONCURRENCY_ANALYTICS_LIMIT")
+	viper.BindEnv("concurrency.queue_size", "LOG_INGESTION_CONCURRENCY_QUEUE_SIZE")
+	viper.BindEnv("concurrency.queue_timeout", "LOG_INGESTION_CONCURRENCY_QUEUE_TIMEOUT")
+	viper.BindEnv("timeouts.ingest", "LOG_INGESTION_TIMEOUT_INGEST")
+	viper.BindEnv("timeouts.query", "LOG_INGESTION_TIMEOUT_QUERY")
+	viper.BindEnv("timeouts.analytics", "LOG_INGESTION_TIMEOUT_ANALYTICS")
+	viper.BindEnv("export.max_rows", "LOG_INGESTION_EXPORT_MAX_ROWS")
+	viper.BindEnv("export.batch_size", "LOG_INGESTION_EXPORT_BATCH_SIZE")
+	viper.BindEnv("export.write_timeout", "LOG_INGESTION_EXPORT_WRITE_TIMEOUT")
+	viper.BindEnv("query_guard.enabled", "LOG_INGESTION_QUERY_GUARD_ENABLED")
+	viper.BindEnv("query_guard.max_rows", "LOG_INGESTION_QUERY_GUARD_MAX_ROWS")
+	viper.BindEnv("query_guard.max_duration", "LOG_INGESTION_QUERY_GUARD_MAX_DURATION")
+	viper.BindEnv("query_guard.cost_per_second", "LOG_INGESTION_QUERY_GUARD_COST_PER_SECOND")
+	viper.BindEnv("query_guard.action", "LOG_INGESTION_QUERY_GUARD_ACTION")
+	viper.BindEnv("query_guard.queue_slots", "LOG_INGESTION_QUERY_GUARD_QUEUE_SLOTS")
+	viper.BindEnv("query_guard.queue_timeout", "LOG_INGESTION_QUERY_GUARD_QUEUE_TIMEOUT")
+	viper.BindEnv("analytics_cache.enabled", "LOG_INGESTION_ANALYTICS_CACHE_ENABLED")
+	viper.BindEnv("analytics_cache.ttl", "LOG_INGESTION_ANALYTICS_CACHE_TTL")
+	viper.BindEnv("analytics_cache.max_stale", "LOG_INGESTION_ANALYTICS_CACHE_MAX_STALE")
+	viper.BindEnv("analytics_cache.max_entries", "LOG_INGESTION_ANALYTICS_CACHE_MAX_ENTRIES")
+	viper.BindEnv("metadata_limits.max_keys", "LOG_INGESTION_METADATA_MAX_KEYS")
+	viper.BindEnv("metadata_limits.max_depth", "LOG_INGESTION_METADATA_MAX_DEPTH")
+	viper.BindEnv("metadata_limits.max_bytes", "LOG_INGESTION_METADATA_MAX_BYTES")
 	
 	// Admin API keys from environment (comma-separated)
 	// Check LOG_INGESTION_ADMIN_API_KEYS first, fallback to LOG_INGESTION_API_KEYS
@@ -143,5 +866,95 @@ func bindEnvVars() {
 		}
 		viper.Set("auth.admin_api_keys", trimmedKeys)
 	}
+	
+	// Notification webhook URLs from environment (comma-separated)
+	if webhookURLs := os.Getenv("LOG_INGESTION_NOTIFICATIONS_WEBHOOK_URLS"); webhookURLs != "" {
+		var urls []string
+		for _, u := range strings.Split(webhookURLs, ",") {
+			if trimmed := strings.TrimSpace(u); trimmed != "" {
+				urls = append(urls, trimmed)
+			}
+		}
+		viper.Set("notifications.webhook_urls", urls)
+	}
+	
+	// Scrub patterns and field mappings from environment (comma-separated)
+	for key, env := range map[string]string{
+		"scrub.key_patterns":              "LOG_INGESTION_SCRUB_KEY_PATTERNS",
+		"scrub.value_patterns":            "LOG_INGESTION_SCRUB_VALUE_PATTERNS",
+		"scrub.paths":                     "LOG_INGESTION_SCRUB_PATHS",
+		"parser.level_fields":             "LOG_INGESTION_PARSER_LEVEL_FIELDS",
+		"parser.service_fields":           "LOG_INGESTION_PARSER_SERVICE_FIELDS",
+		"parser.timestamp_fields":         "LOG_INGESTION_PARSER_TIMESTAMP_FIELDS",
+		"parser.message_fields":           "LOG_INGESTION_PARSER_MESSAGE_FIELDS",
+		"parser.processors":               "LOG_INGESTION_PARSER_PROCESSORS",
+		"enrich.ip_fields":                "LOG_INGESTION_ENRICH_IP_FIELDS",
+		"enrich.user_agent_fields":        "LOG_INGESTION_ENRICH_USER_AGENT_FIELDS",
+		"levels.syslog_severities":        "LOG_INGESTION_LEVELS_SYSLOG_SEVERITIES",
+		"faults.account_fields":           "LOG_INGESTION_FAULTS_ACCOUNT_FIELDS",
+		"faults.workflow.states":          "LOG_INGESTION_FAULTS_WORKFLOW_STATES",
+		"auth.webauthn.origins":           "LOG_INGESTION_WEBAUTHN_ORIGINS",
+		"preflight.required_extensions":   "LOG_INGESTION_PREFLIGHT_REQUIRED_EXTENSIONS",
+		"preflight.encrypted_tablespaces": "LOG_INGESTION_PREFLIGHT_ENCRYPTED_TABLESPACES",
+	} {
+		if value := os.Getenv(env); value != "" {
+			var patterns []string
+			for _, p := range strings.Split(value, ",") {
+				if trimmed := strings.TrimSpace(p); trimmed != "" {
+					patterns = append(patterns, trimmed)
+				}
+			}
+			viper.Set(key, patterns)
+		}
+	}
+	
+	// Parser source mappings from environment (comma-separated source=parser pairs)
+	if sources := os.Getenv("LOG_INGESTION_PARSER_SOURCES"); sources != "" {
+		mapping := make(map[string]string)
+		for _, pair := range strings.Split(sources, ",") {
+			if source, name, ok := strings.Cut(pair, "="); ok {
+				mapping[strings.TrimSpace(source)] = strings.TrimSpace(name)
+			}
+		}
+		viper.Set("parser.sources", mapping)
+	}
+	
+	// Level aliases from environment (comma-separated name=level pairs)
+	if aliases := os.Getenv("LOG_INGESTION_LEVELS_ALIASES"); aliases != "" {
+		mapping := make(map[string]string)
+		for _, pair := range strings.Split(aliases, ",") {
+			if name, level, ok := strings.Cut(pair, "="); ok {
+				mapping[strings.TrimSpace(name)] = strings.TrimSpace(level)
+			}
+		}
+		viper.Set("levels.aliases", mapping)
+	}
+	
+	// Workflow transitions from environment (comma-separated from=to|to pairs)
+	if transitions := os.Getenv("LOG_INGESTION_FAULTS_WORKFLOW_TRANSITIONS"); transitions != "" {
+		mapping := make(map[string][]string)
+		for _, pair := range strings.Split(transitions, ",") {
+			if from, to, ok := strings.Cut(pair, "="); ok {
+				from = strings.TrimSpace(from)
+				for _, state := range strings.Split(to, "|") {
+					if trimmed := strings.TrimSpace(state); trimmed != "" {
+						mapping[from] = append(mapping[from], trimmed)
+					}
+				}
+			}
+		}
+		viper.Set("faults.workflow.transitions", mapping)
+	}
+	
+	// Feature flags from environment (comma-separated name=true|false pairs)
+	if features := os.Getenv("LOG_INGESTION_FEATURES"); features != "" {
+		flags := make(map[string]string)
+		for _, pair := range strings.Split(features, ",") {
+			if name, enabled, ok := strings.Cut(pair, "="); ok {
+				flags[strings.TrimSpace(name)] = strings.TrimSpace(enabled)
+			}
+		}
+		viper.Set("features", flags)
+	}
 }
 
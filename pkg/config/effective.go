@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Redacted replaces the values of secret settings, which are tagged secret:"true"
+const Redacted = "[REDACTED]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Effective returns the settings in effect, keyed as in YAML, once defaults, the YAML source and
+// environment variables are merged. Secret settings that are set show as Redacted, and
+// durations are written as in YAML, such as "5s".
+func (c *Config) Effective() map[string]interface{} {
+	return effectiveValue(reflect.ValueOf(*c), false).(map[string]interface{})
+}
+
+func effectiveValue(v reflect.Value, secret bool) interface{} {
+	switch {
+	case v.Kind() == reflect.Struct:
+		settings := make(map[string]interface{}, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			key := field.Tag.Get("mapstructure")
+			if key == "" || key == "-" || !field.IsExported() {
+				continue
+			}
+			settings[key] = effectiveValue(v.Field(i), field.Tag.Get("secret") == "true")
+		}
+		return settings
+	case v.Kind() == reflect.Map:
+		settings := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			settings[fmt.Sprint(iter.Key().Interface())] = effectiveValue(iter.Value(), secret)
+		}
+		return settings
+	case v.Kind() == reflect.Slice:
+		// Each element of a secret list is redacted, so the number of them still shows
+		values := make([]interface{}, v.Len())
+		for i := range values {
+			values[i] = effectiveValue(v.Index(i), secret)
+		}
+		return values
+	case v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return effectiveValue(v.Elem(), secret)
+	case secret && !v.IsZero():
+		return Redacted
+	case v.Type() == durationType:
+		return time.Duration(v.Int()).String()
+	}
+	return v.Interface()
+}
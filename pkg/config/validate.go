@@ -0,0 +1,552 @@
+package config
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sslModes are the sslmode values accepted by PostgreSQL
+var sslModes = map[string]bool{
+	"disable":     true,
+	"allow":       true,
+	"prefer":      true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+// ValidationErrors lists every problem found in a configuration
+type ValidationErrors []string
+
+func (e ValidationErrors) Error() string {
+	return "invalid configuration: " + strings.Join(e, "; ")
+}
+
+// Validate checks the configuration for values that are out of range or inconsistent with each other.
+// It reports all problems at once, each naming the offending key.
+func (c *Config) Validate() error {
+	var errs ValidationErrors
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		add("server.port must be between 1 and 65535, got %d", c.Server.Port)
+	}
+	if c.Server.ReadTimeout <= 0 {
+		add("server.read_timeout must be positive, got %s", c.Server.ReadTimeout)
+	}
+	if c.Server.WriteTimeout <= 0 {
+		add("server.write_timeout must be positive, got %s", c.Server.WriteTimeout)
+	}
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		add("server.tls_cert_file and server.tls_key_file must be set together")
+	}
+
+	listenerNames := map[string]bool{MainListener: true}
+	ports := map[int]string{c.Server.Port: MainListener}
+	for i, l := range c.Listeners {
+		key := fmt.Sprintf("listeners[%d]", i)
+		if l.Name == "" {
+			add("%s.name is required", key)
+		} else if listenerNames[l.Name] {
+			add("%s.name %q is used more than once (%q is reserved for the main server)", key, l.Name, MainListener)
+		}
+		listenerNames[l.Name] = true
+		switch l.Type {
+		case ListenerIngest, ListenerSyslog, ListenerOTLP, ListenerSQS, ListenerKinesis:
+		default:
+			add("%s.type %q is not one of %s, %s, %s, %s, %s", key, l.Type,
+				ListenerIngest, ListenerSyslog, ListenerOTLP, ListenerSQS, ListenerKinesis)
+		}
+		if l.Polled() {
+			if l.Type == ListenerSQS && l.QueueURL == "" {
+				add("%s.queue_url is required for sqs listeners", key)
+			}
+			if l.Type == ListenerKinesis && l.Stream == "" {
+				add("%s.stream is required for kinesis listeners", key)
+			}
+			if l.Region == "" {
+				add("%s.region is required (or set AWS_REGION)", key)
+			}
+			if l.StartingPosition != "" && l.StartingPosition != StartingPositionLatest && l.StartingPosition != StartingPositionTrimHorizon {
+				add("%s.starting_position %q is not one of %s, %s", key, l.StartingPosition,
+					StartingPositionLatest, StartingPositionTrimHorizon)
+			}
+			if l.Port != 0 || l.TLSCertFile != "" || l.Auth != "" {
+				add("%s.port, tls and auth do not apply to %s listeners", key, l.Type)
+			}
+		} else if l.QueueURL != "" || l.Stream != "" || l.DLQURL != "" || l.Region != "" || l.Endpoint != "" {
+			add("%s.queue_url, stream, dlq_url, region and endpoint only apply to sqs and kinesis listeners", key)
+		}
+		if l.Type != ListenerKinesis && l.StartingPosition != "" {
+			add("%s.starting_position only applies to kinesis listeners", key)
+		}
+		if !l.Polled() {
+			if l.Port < 1 || l.Port > 65535 {
+				add("%s.port must be between 1 and 65535, got %d", key, l.Port)
+			} else if other, taken := ports[l.Port]; taken {
+				add("%s.port %d is already used by listener %q", key, l.Port, other)
+			}
+			ports[l.Port] = l.Name
+		}
+		if (l.TLSCertFile == "") != (l.TLSKeyFile == "") {
+			add("%s.tls_cert_file and %s.tls_key_file must be set together", key, key)
+		}
+		if l.Auth != "" && l.Auth != ListenerAuthAPIKey && l.Auth != ListenerAuthNone {
+			add("%s.auth %q is not one of %s, %s", key, l.Auth, ListenerAuthAPIKey, ListenerAuthNone)
+		}
+		if l.Type == ListenerSyslog {
+			if l.Network != "" && l.Network != "udp" && l.Network != "tcp" {
+				add("%s.network %q is not one of udp, tcp (or empty for both)", key, l.Network)
+			}
+			if l.TLSCertFile != "" && l.Network != "tcp" {
+				add("%s.network must be tcp when TLS is configured", key)
+			}
+			if l.Auth == ListenerAuthAPIKey {
+				add("%s.auth %q is not supported by syslog listeners", key, l.Auth)
+			}
+			if l.Parser != "" {
+				add("%s.parser is not used by syslog listeners", key)
+			}
+		} else if l.Network != "" {
+			add("%s.network only applies to syslog listeners", key)
+		}
+		if l.Type == ListenerOTLP && l.Parser != "" {
+			add("%s.parser is not used by otlp listeners", key)
+		}
+	}
+
+	if c.Database.Host == "" {
+		add("database.host is required")
+	}
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		add("database.port must be between 1 and 65535, got %d", c.Database.Port)
+	}
+	if c.Database.User == "" {
+		add("database.user is required")
+	}
+	if c.Database.DBName == "" {
+		add("database.dbname is required")
+	}
+	if !sslModes[c.Database.SSLMode] {
+		add("database.sslmode %q is not one of disable, allow, prefer, require, verify-ca, verify-full", c.Database.SSLMode)
+	}
+	if c.Database.CapacityBytes < 0 {
+		add("database.capacity_bytes must not be negative, got %d", c.Database.CapacityBytes)
+	}
+
+	if c.Batch.Size <= 0 {
+		add("batch.size must be positive, got %d", c.Batch.Size)
+	}
+	if c.Batch.FlushInterval <= 0 {
+		add("batch.flush_interval must be positive, got %s", c.Batch.FlushInterval)
+	}
+	if c.Batch.Shards < 0 {
+		add("batch.shards must not be negative, got %d", c.Batch.Shards)
+	}
+	if c.NoticeBatch.Size <= 0 {
+		add("notice_batch.size must be positive, got %d", c.NoticeBatch.Size)
+	}
+	if c.NoticeBatch.FlushInterval <= 0 {
+		add("notice_batch.flush_interval must be positive, got %s", c.NoticeBatch.FlushInterval)
+	}
+
+	if c.RateLimit.Enabled {
+		if c.RateLimit.DefaultRPS <= 0 {
+			add("ratelimit.default_rps must be positive when rate limiting is enabled, got %d", c.RateLimit.DefaultRPS)
+		}
+		if c.RateLimit.Burst < c.RateLimit.DefaultRPS {
+			add("ratelimit.burst (%d) must be at least ratelimit.default_rps (%d)", c.RateLimit.Burst, c.RateLimit.DefaultRPS)
+		}
+	}
+
+	if c.Concurrency.Enabled {
+		if c.Concurrency.MaxInFlight <= 0 {
+			add("concurrency.max_in_flight must be positive, got %d", c.Concurrency.MaxInFlight)
+		}
+		for _, class := range []struct {
+			key   string
+			limit int
+		}{
+			{"concurrency.ingest_limit", c.Concurrency.IngestLimit},
+			{"concurrency.query_limit", c.Concurrency.QueryLimit},
+			{"concurrency.analytics_limit", c.Concurrency.AnalyticsLimit},
+		} {
+			if class.limit <= 0 || class.limit > c.Concurrency.MaxInFlight {
+				add("%s must be between 1 and concurrency.max_in_flight (%d), got %d", class.key, c.Concurrency.MaxInFlight, class.limit)
+			}
+		}
+		if c.Concurrency.QueueSize < 0 {
+			add("concurrency.queue_size must not be negative, got %d", c.Concurrency.QueueSize)
+		}
+		if c.Concurrency.QueueTimeout < 0 {
+			add("concurrency.queue_timeout must not be negative, got %s", c.Concurrency.QueueTimeout)
+		}
+	}
+
+	for _, timeout := range []struct {
+		key string
+		d   time.Duration
+	}{
+		{"timeouts.ingest", c.Timeouts.Ingest},
+		{"timeouts.query", c.Timeouts.Query},
+		{"timeouts.analytics", c.Timeouts.Analytics},
+	} {
+		if timeout.d < 0 {
+			add("%s must not be negative, got %s", timeout.key, timeout.d)
+		}
+	}
+	routes := make([]string, 0, len(c.Timeouts.Routes))
+	for route := range c.Timeouts.Routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		d := c.Timeouts.Routes[route]
+		if method, path, ok := strings.Cut(route, " "); !ok || method == "" || !strings.HasPrefix(path, "/") {
+			add("timeouts.routes key %q must be \"METHOD /route\"", route)
+		} else if d < 0 {
+			add("timeouts.routes[%q] must not be negative, got %s", route, d)
+		}
+	}
+
+	if c.Export.MaxRows <= 0 {
+		add("export.max_rows must be positive, got %d", c.Export.MaxRows)
+	}
+	if c.Export.BatchSize <= 0 || c.Export.BatchSize > 100000 {
+		add("export.batch_size must be between 1 and 100000, got %d", c.Export.BatchSize)
+	}
+	if c.Export.WriteTimeout <= 0 {
+		add("export.write_timeout must be positive, got %s", c.Export.WriteTimeout)
+	}
+
+	if c.QueryGuard.Enabled {
+		g := c.QueryGuard
+		if g.MaxRows <= 0 {
+			add("query_guard.max_rows must be positive, got %g", g.MaxRows)
+		}
+		if g.MaxDuration <= 0 {
+			add("query_guard.max_duration must be positive, got %s", g.MaxDuration)
+		}
+		if g.CostPerSecond <= 0 {
+			add("query_guard.cost_per_second must be positive, got %g", g.CostPerSecond)
+		}
+		switch g.Action {
+		case QueryGuardReject:
+		case QueryGuardQueue:
+			if g.QueueSlots <= 0 {
+				add("query_guard.queue_slots must be positive, got %d", g.QueueSlots)
+			}
+			if g.QueueTimeout <= 0 {
+				add("query_guard.queue_timeout must be positive, got %s", g.QueueTimeout)
+			}
+		default:
+			add("query_guard.action must be %s or %s, got %q", QueryGuardReject, QueryGuardQueue, g.Action)
+		}
+	}
+
+	if c.AnalyticsCache.Enabled {
+		if c.AnalyticsCache.TTL <= 0 {
+			add("analytics_cache.ttl must be positive, got %s", c.AnalyticsCache.TTL)
+		}
+		if c.AnalyticsCache.MaxStale < 0 {
+			add("analytics_cache.max_stale must not be negative, got %s", c.AnalyticsCache.MaxStale)
+		}
+		if c.AnalyticsCache.MaxEntries <= 0 {
+			add("analytics_cache.max_entries must be positive, got %d", c.AnalyticsCache.MaxEntries)
+		}
+	}
+
+	if c.Auth.JWTSecret == "" {
+		add("auth.jwt_secret is required")
+	}
+	if c.Auth.WebAuthn.RPID != "" {
+		if strings.ContainsAny(c.Auth.WebAuthn.RPID, ":/") {
+			add("auth.webauthn.rp_id must be a domain without scheme or port, got %q", c.Auth.WebAuthn.RPID)
+		}
+		for _, origin := range c.Auth.WebAuthn.Origins {
+			if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" {
+				add("auth.webauthn.origins entry %q must be a scheme and host, such as https://%s", origin, c.Auth.WebAuthn.RPID)
+			}
+		}
+	}
+
+	if c.Avatars.Dir == "" {
+		add("avatars.dir is required")
+	}
+	if c.Avatars.Size <= 0 {
+		add("avatars.size must be positive, got %d", c.Avatars.Size)
+	}
+	if c.Avatars.MaxUploadBytes <= 0 {
+		add("avatars.max_upload_bytes must be positive, got %d", c.Avatars.MaxUploadBytes)
+	}
+
+	if c.Idempotency.Enabled && c.Idempotency.TTL <= 0 {
+		add("idempotency.ttl must be positive when idempotency is enabled, got %s", c.Idempotency.TTL)
+	}
+	if c.Idempotency.Enabled && c.Idempotency.MaxBodyBytes <= 0 {
+		add("idempotency.max_body_bytes must be positive when idempotency is enabled, got %d", c.Idempotency.MaxBodyBytes)
+	}
+
+	if c.Rejects.SampleRate < 0 || c.Rejects.SampleRate > 1 {
+		add("rejects.sample_rate must be between 0 and 1, got %g", c.Rejects.SampleRate)
+	}
+	if c.Rejects.SampleRate > 0 {
+		if c.Rejects.MaxSamples <= 0 {
+			add("rejects.max_samples must be positive when sampling is enabled, got %d", c.Rejects.MaxSamples)
+		}
+		if c.Rejects.MaxSampleBytes <= 0 {
+			add("rejects.max_sample_bytes must be positive when sampling is enabled, got %d", c.Rejects.MaxSampleBytes)
+		}
+	}
+	if c.DeadLetter.Enabled {
+		if c.DeadLetter.Dir == "" {
+			add("dead_letter.dir is required when the dead-letter queue is enabled")
+		}
+		if c.DeadLetter.MaxBytes <= 0 {
+			add("dead_letter.max_bytes must be positive, got %d", c.DeadLetter.MaxBytes)
+		}
+	}
+	if c.WAL.Enabled {
+		if c.WAL.Dir == "" {
+			add("wal.dir is required when the write-ahead log is enabled")
+		}
+		if c.WAL.SegmentBytes <= 0 {
+			add("wal.segment_bytes must be positive, got %d", c.WAL.SegmentBytes)
+		}
+		if c.WAL.Dir != "" && c.WAL.Dir == c.DeadLetter.Dir {
+			add("wal.dir must differ from dead_letter.dir")
+		}
+	}
+	if c.Preflight.Enabled {
+		if c.Preflight.MaxClockSkew <= 0 {
+			add("preflight.max_clock_skew must be positive, got %s", c.Preflight.MaxClockSkew)
+		}
+		if c.Preflight.Timeout <= 0 {
+			add("preflight.timeout must be positive, got %s", c.Preflight.Timeout)
+		}
+		if c.Preflight.RetryInterval <= 0 {
+			add("preflight.retry_interval must be positive, got %s", c.Preflight.RetryInterval)
+		}
+	}
+
+	if c.Jobs.Workers < 0 {
+		add("jobs.workers must not be negative, got %d", c.Jobs.Workers)
+	}
+	if c.Jobs.PollInterval <= 0 {
+		add("jobs.poll_interval must be positive, got %s", c.Jobs.PollInterval)
+	}
+	if c.Jobs.Lease < 10*time.Second {
+		add("jobs.lease must be at least 10s, got %s", c.Jobs.Lease)
+	}
+	if c.Jobs.MaxAttempts < 1 {
+		add("jobs.max_attempts must be at least 1, got %d", c.Jobs.MaxAttempts)
+	}
+	if c.Jobs.Retention <= 0 {
+		add("jobs.retention must be positive, got %s", c.Jobs.Retention)
+	}
+	if c.Faults.Deploys.Window < time.Minute {
+		add("faults.deploys.window must be at least 1m, got %s", c.Faults.Deploys.Window)
+	}
+	if c.Faults.Deploys.SpikeFactor < 1 {
+		add("faults.deploys.spike_factor must be at least 1, got %g", c.Faults.Deploys.SpikeFactor)
+	}
+	if c.Faults.Deploys.MinNotices < 1 {
+		add("faults.deploys.min_notices must be at least 1, got %d", c.Faults.Deploys.MinNotices)
+	}
+	for severity, target := range c.Faults.SLA.Targets {
+		known := false
+		for _, s := range slaSeverities {
+			known = known || s == severity
+		}
+		if !known {
+			add("faults.sla.targets.%s is not a severity (allowed: %s)", severity, strings.Join(slaSeverities, ", "))
+		}
+		if target.Acknowledge < 0 || target.Resolve < 0 {
+			add("faults.sla.targets.%s must not be negative", severity)
+		}
+	}
+	if c.Faults.SLA.WarnAt <= 0 || c.Faults.SLA.WarnAt >= 1 {
+		add("faults.sla.warn_at must be between 0 and 1, got %g", c.Faults.SLA.WarnAt)
+	}
+	if c.Faults.Sampling.Threshold < 0 {
+		add("faults.sampling.threshold must not be negative, got %d", c.Faults.Sampling.Threshold)
+	}
+	if c.Faults.Sampling.SampleRate < 0 || c.Faults.Sampling.SampleRate > 1 {
+		add("faults.sampling.sample_rate must be between 0 and 1, got %g", c.Faults.Sampling.SampleRate)
+	}
+	workflow := c.Faults.Workflow
+	states := make(map[string]bool, len(workflow.States))
+	for i, state := range workflow.States {
+		if state == "" {
+			add("faults.workflow.states[%d] must not be empty", i)
+		} else if states[state] {
+			add("faults.workflow.states[%d] %q is listed more than once", i, state)
+		}
+		states[state] = true
+	}
+	if len(workflow.States) < 2 {
+		add("faults.workflow.states must list at least 2 states")
+	}
+	for from, targets := range workflow.Transitions {
+		if !states[from] {
+			add("faults.workflow.transitions.%s is not a workflow state", from)
+		}
+		for _, to := range targets {
+			if !states[to] {
+				add("faults.workflow.transitions.%s: %q is not a workflow state", from, to)
+			}
+		}
+	}
+	if !states[workflow.ResolvedState] || !states[workflow.IgnoredState] || workflow.ResolvedState == workflow.IgnoredState {
+		add("faults.workflow.resolved_state and ignored_state must be two different workflow states, got %q and %q",
+			workflow.ResolvedState, workflow.IgnoredState)
+	} else if len(workflow.States) > 0 && (workflow.States[0] == workflow.ResolvedState || workflow.States[0] == workflow.IgnoredState) {
+		add("faults.workflow.states must not start with the resolved or ignored state")
+	}
+
+	if c.Notifications.Timeout <= 0 {
+		add("notifications.timeout must be positive, got %s", c.Notifications.Timeout)
+	}
+	for i, webhook := range c.Notifications.WebhookURLs {
+		if u, err := url.Parse(webhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("notifications.webhook_urls[%d] %q is not an http(s) URL", i, webhook)
+		}
+	}
+	if smtp := c.Notifications.SMTP; smtp.Host != "" {
+		if smtp.Port < 1 || smtp.Port > 65535 {
+			add("notifications.smtp.port must be between 1 and 65535, got %d", smtp.Port)
+		}
+		if _, err := mail.ParseAddress(smtp.From); err != nil {
+			add("notifications.smtp.from %q is not an email address", smtp.From)
+		}
+	}
+
+	for i, pattern := range c.Scrub.KeyPatterns {
+		if _, err := regexp.Compile("(?i)" + pattern); err != nil {
+			add("scrub.key_patterns[%d] is not a valid regular expression: %v", i, err)
+		}
+	}
+	for i, pattern := range c.Scrub.ValuePatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			add("scrub.value_patterns[%d] is not a valid regular expression: %v", i, err)
+		}
+	}
+
+	if _, err := time.LoadLocation(c.Parser.DefaultTimezone); err != nil {
+		add("parser.default_timezone %q is not a known timezone", c.Parser.DefaultTimezone)
+	}
+	names := make(map[string]bool)
+	for i, custom := range c.Parser.Custom {
+		if custom.Name == "" {
+			add("parser.custom[%d].name is required", i)
+		} else if names[custom.Name] {
+			add("parser.custom[%d].name %q is used more than once", i, custom.Name)
+		}
+		names[custom.Name] = true
+		switch {
+		case custom.Pattern != "" && custom.Grok != "":
+			add("parser.custom[%d] sets both pattern and grok", i)
+		case custom.Pattern == "" && custom.Grok == "":
+			add("parser.custom[%d] needs a pattern or grok expression", i)
+		case custom.Pattern != "":
+			if _, err := regexp.Compile(custom.Pattern); err != nil {
+				add("parser.custom[%d].pattern is not a valid regular expression: %v", i, err)
+			}
+		}
+	}
+	for service, formats := range c.Parser.ServiceTimestamps {
+		if len(formats.Layouts) == 0 && formats.Timezone == "" {
+			add("parser.service_timestamps.%s needs layouts or a timezone", service)
+		}
+		if _, err := time.LoadLocation(formats.Timezone); err != nil || formats.Timezone == "Local" {
+			add("parser.service_timestamps.%s.timezone %q is not a known timezone", service, formats.Timezone)
+		}
+	}
+	for source, name := range c.Parser.Sources {
+		if name == "" {
+			add("parser.sources.%s must name a parser", source)
+		}
+	}
+	if _, err := regexp.Compile(c.Parser.Multiline.StartPattern); err != nil {
+		add("parser.multiline.start_pattern is not a valid regular expression: %v", err)
+	}
+	if _, err := regexp.Compile(c.Parser.Multiline.ContinuationPattern); err != nil {
+		add("parser.multiline.continuation_pattern is not a valid regular expression: %v", err)
+	}
+	if c.Parser.Multiline.Enabled() {
+		if c.Parser.Multiline.Timeout <= 0 {
+			add("parser.multiline.timeout must be positive")
+		}
+		if c.Parser.Multiline.MaxLines < 2 {
+			add("parser.multiline.max_lines must be at least 2")
+		}
+	}
+
+	for _, name := range c.Parser.Processors {
+		if name == "geoip" && c.Enrich.GeoIPDatabase == "" {
+			add("parser.processors lists geoip, which needs enrich.geoip_database")
+		}
+	}
+	if c.Enrich.GeoIPDatabase != "" && len(c.Enrich.IPFields) == 0 {
+		add("enrich.ip_fields must not be empty when enrich.geoip_database is set")
+	}
+
+	canonical := make(map[string]bool, len(CanonicalLevels))
+	for _, level := range CanonicalLevels {
+		canonical[level] = true
+	}
+	for name, level := range c.Levels.Aliases {
+		if strings.TrimSpace(name) == "" {
+			add("levels.aliases has an empty level name")
+		}
+		if !canonical[strings.ToUpper(level)] {
+			add("levels.aliases.%s must be one of %s, got %q", name, strings.Join(CanonicalLevels, ", "), level)
+		}
+	}
+	if len(c.Levels.SyslogSeverities) != 8 {
+		add("levels.syslog_severities must list 8 levels, for severities 0 to 7, got %d", len(c.Levels.SyslogSeverities))
+	}
+	for i, level := range c.Levels.SyslogSeverities {
+		if !canonical[strings.ToUpper(level)] {
+			add("levels.syslog_severities[%d] must be one of %s, got %q", i, strings.Join(CanonicalLevels, ", "), level)
+		}
+	}
+
+	for i, rule := range c.Sampling.Rules {
+		key := fmt.Sprintf("sampling.rules[%d]", i)
+		if rule.Service == "" && rule.Level == "" && rule.MessagePattern == "" {
+			add("%s must set service, level or message_pattern", key)
+		}
+		if rule.Level != "" && !canonical[strings.ToUpper(rule.Level)] {
+			add("%s.level must be one of %s, got %q", key, strings.Join(CanonicalLevels, ", "), rule.Level)
+		}
+		if _, err := regexp.Compile(rule.MessagePattern); err != nil {
+			add("%s.message_pattern is not a valid regular expression: %v", key, err)
+		}
+		if rule.SampleRate < 0 || rule.SampleRate > 1 {
+			add("%s.sample_rate must be between 0 and 1, got %g", key, rule.SampleRate)
+		}
+	}
+
+	if c.MetadataLimits.MaxKeys <= 0 {
+		add("metadata_limits.max_keys must be positive, got %d", c.MetadataLimits.MaxKeys)
+	}
+	if c.MetadataLimits.MaxDepth <= 0 {
+		add("metadata_limits.max_depth must be positive, got %d", c.MetadataLimits.MaxDepth)
+	}
+	if c.MetadataLimits.MaxBytes < 1024 {
+		add("metadata_limits.max_bytes must be at least 1024, got %d", c.MetadataLimits.MaxBytes)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
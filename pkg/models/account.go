@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// AffectedAccount is a customer account or tenant that a fault's notices were reported for
+type AffectedAccount struct {
+	AccountID   string    `json:"account_id"`
+	NoticeCount int64     `json:"notice_count"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
+
+// AccountFault is a fault affecting an account, with the notices reported for that account
+type AccountFault struct {
+	FaultID     int64     `json:"fault_id"`
+	ProjectID   *int64    `json:"project_id,omitempty"`
+	ErrorClass  string    `json:"error_class"`
+	Message     string    `json:"message"`
+	Environment string    `json:"environment"`
+	Resolved    bool      `json:"resolved"`
+	Ignored     bool      `json:"ignored"`
+	NoticeCount int64     `json:"notice_count"`
+	FirstSeenAt time.Time `json:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Activity event types returned by the project activity feed
+const (
+	ActivityFaultCreated = "fault_created"
+	ActivityFaultHistory = "fault_history"
+	ActivityComment      = "comment"
+	ActivityDeploy       = "deploy"
+)
+
+// ActivityTypes lists every activity event type in feed order of precedence
+var ActivityTypes = []string{ActivityFaultCreated, ActivityFaultHistory, ActivityComment, ActivityDeploy}
+
+// ActivityEvent is a single entry in a project's chronological activity feed
+type ActivityEvent struct {
+	Type       string    `json:"type"`
+	ID         *int64    `json:"id,omitempty"`
+	FaultID    *int64    `json:"fault_id,omitempty"`
+	ErrorClass *string   `json:"error_class,omitempty"`
+	Action     *string   `json:"action,omitempty"`
+	Comment    *string   `json:"comment,omitempty"`
+	Revision   *string   `json:"revision,omitempty"`
+	UserID     *int64    `json:"user_id,omitempty"`
+	User       *User     `json:"user,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Deploy is a revision's first appearance in an environment, with the error rates in the window
+// before and after it
+type Deploy struct {
+	ID          int64     `json:"id" db:"id"`
+	ProjectID   *int64    `json:"project_id,omitempty" db:"project_id"`
+	Environment string    `json:"environment" db:"environment"`
+	Revision    string    `json:"revision" db:"revision"`
+	DeployedAt  time.Time `json:"deployed_at" db:"deployed_at"`
+	// WindowSeconds is the length of the windows compared before and after the deploy
+	WindowSeconds int   `json:"window_seconds" db:"window_seconds"`
+	NoticesBefore int64 `json:"notices_before" db:"notices_before"`
+	NoticesAfter  int64 `json:"notices_after" db:"notices_after"`
+	FaultsBefore  int64 `json:"faults_before" db:"faults_before"`
+	FaultsAfter   int64 `json:"faults_after" db:"faults_after"`
+	// NewFaults counts faults first seen in the window after the deploy
+	NewFaults int64 `json:"new_faults" db:"new_faults"`
+	// Spike is set when the notices after the deploy exceed those before it by the spike factor
+	Spike bool `json:"spike" db:"spike"`
+	// Complete is set once the window after the deploy has ended and its counts are final
+	Complete   bool       `json:"complete" db:"complete"`
+	AnalyzedAt *time.Time `json:"analyzed_at,omitempty" db:"analyzed_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Environment is a deployment environment that faults are reported from, such as staging or
+// production. Environments are ordered by position, in the order releases are promoted
+// through them.
+type Environment struct {
+	ID        int64  `json:"id" db:"id"`
+	ProjectID *int64 `json:"project_id,omitempty" db:"project_id"`
+	Name      string `json:"name" db:"name"`
+	Position  int    `json:"position" db:"position"`
+	// AlertingEnabled turns off fault notifications for the environment when false
+	AlertingEnabled bool `json:"alerting_enabled" db:"alerting_enabled"`
+	// RetentionDays is how long the environment's notices are kept; nil keeps them as long as
+	// global retention does
+	RetentionDays *int      `json:"retention_days,omitempty" db:"retention_days"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+}
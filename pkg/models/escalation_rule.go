@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Fault severities, lowest first
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Severities lists the fault severities, lowest first
+var Severities = []string{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// SeverityRank returns the position of a severity in Severities, or -1 for an unknown one
+func SeverityRank(severity string) int {
+	for i, s := range Severities {
+		if s == severity {
+			return i
+		}
+	}
+	return -1
+}
+
+// EscalationRule automatically raises the severity of faults that cross its thresholds
+type EscalationRule struct {
+	ID               int64      `json:"id" db:"id"`
+	ProjectID        *int64     `json:"project_id,omitempty" db:"project_id"`
+	Name             string     `json:"name" db:"name"`
+	MinOccurrences   *int64     `json:"min_occurrences,omitempty" db:"min_occurrences"`
+	MinAffectedUsers *int64     `json:"min_affected_users,omitempty" db:"min_affected_users"`
+	WindowSeconds    *int       `json:"window_seconds,omitempty" db:"window_seconds"`
+	Severity         string     `json:"severity" db:"severity"`
+	Enabled          bool       `json:"enabled" db:"enabled"`
+	MatchCount       int64      `json:"match_count" db:"match_count"`
+	LastMatchedAt    *time.Time `json:"last_matched_at,omitempty" db:"last_matched_at"`
+	CreatedBy        *int64     `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+}
@@ -16,10 +16,28 @@ type Fault struct {
 	Environment     string     `json:"environment" db:"environment"`
 	Resolved        bool       `json:"resolved" db:"resolved"`
 	Ignored         bool       `json:"ignored" db:"ignored"`
+	// State is the fault's workflow state; Resolved and Ignored follow from it
+	State           string     `json:"state" db:"state"`
+	// OpenedAt is when the fault was created or last reopened; SLA timers run from it
+	OpenedAt        time.Time  `json:"opened_at" db:"opened_at"`
+	// AcknowledgedAt is when the fault left the workflow's first state
+	AcknowledgedAt  *time.Time `json:"acknowledged_at,omitempty" db:"acknowledged_at"`
+	// ClosedAt is when the fault was resolved or ignored
+	ClosedAt        *time.Time `json:"closed_at,omitempty" db:"closed_at"`
+	// SLA holds the fault's SLA timers, when its severity has targets
+	SLA             *FaultSLA  `json:"sla,omitempty"`
+	// Highlights marks where the text of a search matches the error class, message and location
+	Highlights      []Highlight `json:"highlights,omitempty"`
 	AssigneeID      *int64     `json:"assignee_id,omitempty" db:"assignee_id"`
 	Assignee        *User      `json:"assignee,omitempty"`
+	LatestNotice    *NoticeSummary `json:"latest_notice,omitempty"`
+	Merges          []FaultMerge `json:"merges,omitempty"`
+	Links           []FaultLink `json:"links,omitempty"`
 	Tags            []string   `json:"tags" db:"tags"`
 	Public          bool       `json:"public" db:"public"`
+	Severity        string     `json:"severity" db:"severity"`
+	// CustomFields holds values of the project's custom field definitions, keyed by field key
+	CustomFields    map[string]interface{} `json:"custom_fields" db:"custom_fields"`
 	OccurrenceCount int64      `json:"occurrence_count" db:"occurrence_count"`
 	FirstSeenAt     time.Time  `json:"first_seen_at" db:"first_seen_at"`
 	LastSeenAt      time.Time  `json:"last_seen_at" db:"last_seen_at"`
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Fault field types
+const (
+	FieldTypeText    = "text"
+	FieldTypeNumber  = "number"
+	FieldTypeBoolean = "boolean"
+	FieldTypeDate    = "date"
+	FieldTypeEnum    = "enum"
+)
+
+// FieldTypes lists the custom field types
+var FieldTypes = []string{FieldTypeText, FieldTypeNumber, FieldTypeBoolean, FieldTypeDate, FieldTypeEnum}
+
+// MaxFieldTextLength bounds text field values
+const MaxFieldTextLength = 1000
+
+// fieldKeyPattern restricts keys to what a field.<key>:value search token can name
+var fieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// FaultFieldDefinition declares a custom field that can be set on faults, such as an incident
+// number or a root-cause category
+type FaultFieldDefinition struct {
+	ID        int64  `json:"id" db:"id"`
+	ProjectID *int64 `json:"project_id,omitempty" db:"project_id"`
+	// Key names the field in fault custom_fields and in field.<key>: search tokens
+	Key  string `json:"key" db:"key"`
+	Name string `json:"name" db:"name"`
+	Type string `json:"type" db:"type"`
+	// Options are the allowed values of an enum field
+	Options   []string  `json:"options" db:"options"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Validate checks a definition before it is saved
+func (d *FaultFieldDefinition) Validate() error {
+	if !fieldKeyPattern.MatchString(d.Key) {
+		return fmt.Errorf("key must start with a lowercase letter and contain only lowercase letters, digits and underscores (at most 63)")
+	}
+	if d.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	known := false
+	for _, t := range FieldTypes {
+		known = known || t == d.Type
+	}
+	if !known {
+		return fmt.Errorf("type must be one of text, number, boolean, date, enum")
+	}
+	if d.Type == FieldTypeEnum && len(d.Options) == 0 {
+		return fmt.Errorf("enum fields need at least one option")
+	}
+	if d.Type != FieldTypeEnum && len(d.Options) > 0 {
+		return fmt.Errorf("options are only allowed on enum fields")
+	}
+	seen := make(map[string]bool, len(d.Options))
+	for _, option := range d.Options {
+		if option == "" || seen[option] {
+			return fmt.Errorf("options must be non-empty and unique")
+		}
+		seen[option] = true
+	}
+	return nil
+}
+
+// NormalizeValue checks a value decoded from JSON against the field's type and returns it in
+// the form stored on faults. Dates are stored as YYYY-MM-DD.
+func (d *FaultFieldDefinition) NormalizeValue(value interface{}) (interface{}, error) {
+	switch d.Type {
+	case FieldTypeText:
+		if s, ok := value.(string); ok && len(s) <= MaxFieldTextLength {
+			return s, nil
+		}
+		return nil, fmt.Errorf("field %q must be a string of at most %d bytes", d.Key, MaxFieldTextLength)
+	case FieldTypeNumber:
+		if n, ok := value.(float64); ok {
+			return n, nil
+		}
+		return nil, fmt.Errorf("field %q must be a number", d.Key)
+	case FieldTypeBoolean:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("field %q must be true or false", d.Key)
+	case FieldTypeDate:
+		if s, ok := value.(string); ok {
+			if t, err := time.Parse("2006-01-02", s); err == nil {
+				return t.Format("2006-01-02"), nil
+			}
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return t.UTC().Format("2006-01-02"), nil
+			}
+		}
+		return nil, fmt.Errorf("field %q must be a date (YYYY-MM-DD)", d.Key)
+	case FieldTypeEnum:
+		if s, ok := value.(string); ok {
+			for _, option := range d.Options {
+				if s == option {
+					return s, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("field %q must be one of %v", d.Key, d.Options)
+	}
+	return nil, fmt.Errorf("field %q has unknown type %q", d.Key, d.Type)
+}
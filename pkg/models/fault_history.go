@@ -1,14 +1,132 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
 
 // FaultHistory represents an audit trail entry for fault changes
 type FaultHistory struct {
-	ID        int64     `json:"id" db:"id"`
-	FaultID   int64     `json:"fault_id" db:"fault_id"`
-	Action    string    `json:"action" db:"action"` // resolved, assigned, tagged, etc.
-	UserID    *int64    `json:"user_id,omitempty" db:"user_id"`
-	User      *User     `json:"user,omitempty"`
-	Revision  *string   `json:"revision,omitempty" db:"revision"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID          int64         `json:"id" db:"id"`
+	FaultID     int64         `json:"fault_id" db:"fault_id"`
+	Action      string        `json:"action" db:"action"` // resolved, assigned, tagged, etc.
+	UserID      *int64        `json:"user_id,omitempty" db:"user_id"`
+	User        *User         `json:"user,omitempty"`
+	Revision    *string       `json:"revision,omitempty" db:"revision"`
+	Changes     []FieldChange `json:"changes" db:"changes"`
+	Description string        `json:"description"`
+	CreatedAt   time.Time     `json:"created_at" db:"created_at"`
+}
+
+// FieldChange records how a single fault field changed.
+// List fields such as tags use Added/Removed instead of Old/New.
+type FieldChange struct {
+	Field   string      `json:"field"`
+	Old     interface{} `json:"old,omitempty"`
+	New     interface{} `json:"new,omitempty"`
+	Added   []string    `json:"added,omitempty"`
+	Removed []string    `json:"removed,omitempty"`
+}
+
+// Describe returns a human-readable summary of the entry for display
+func (h *FaultHistory) Describe() string {
+	// A state change also flips the legacy flags; the state alone describes it
+	movedState := false
+	for _, change := range h.Changes {
+		movedState = movedState || change.Field == "state"
+	}
+
+	var parts []string
+	for _, change := range h.Changes {
+		if movedState && (change.Field == "resolved" || change.Field == "ignored") {
+			continue
+		}
+		if d := change.Describe(); d != "" {
+			parts = append(parts, d)
+		}
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, "; ")
+	}
+
+	switch h.Action {
+	case "resolved":
+		return "Marked resolved"
+	case "unresolved":
+		return "Marked unresolved"
+	case "ignored":
+		return "Ignored"
+	case "unignored":
+		return "Stopped ignoring"
+	case "assigned":
+		return "Assignment changed"
+	case "":
+		return ""
+	}
+	return strings.ToUpper(h.Action[:1]) + strings.ReplaceAll(h.Action[1:], "_", " ")
+}
+
+// Describe returns a human-readable summary of a single field change
+func (c FieldChange) Describe() string {
+	switch c.Field {
+	case "tags":
+		var parts []string
+		if len(c.Added) > 0 {
+			parts = append(parts, "added tags "+strings.Join(c.Added, ", "))
+		}
+		if len(c.Removed) > 0 {
+			parts = append(parts, "removed tags "+strings.Join(c.Removed, ", "))
+		}
+		if len(parts) == 0 {
+			return ""
+		}
+		d := strings.Join(parts, " and ")
+		return strings.ToUpper(d[:1]) + d[1:]
+	case "resolved":
+		if c.New == true {
+			return "Marked resolved"
+		}
+		return "Marked unresolved"
+	case "ignored":
+		if c.New == true {
+			return "Ignored"
+		}
+		return "Stopped ignoring"
+	case "state":
+		return fmt.Sprintf("Moved from %s to %s", formatChangeValue(c.Old), formatChangeValue(c.New))
+	case "merged_fault_id":
+		return fmt.Sprintf("Merged fault #%s into this fault", formatChangeValue(c.New))
+	case "regrouped_to_fault_id":
+		return fmt.Sprintf("Regrouped notices into fault #%s", formatChangeValue(c.New))
+	case "escalation_rule_id":
+		return fmt.Sprintf("Escalated automatically by rule #%s", formatChangeValue(c.New))
+	case "regrouped_from_fault_id":
+		return fmt.Sprintf("Regrouped notices from fault #%s into this fault", formatChangeValue(c.New))
+	case "assignee_id":
+		switch {
+		case c.New == nil:
+			return fmt.Sprintf("Unassigned (was user %s)", formatChangeValue(c.Old))
+		case c.Old == nil:
+			return fmt.Sprintf("Assigned to user %s", formatChangeValue(c.New))
+		default:
+			return fmt.Sprintf("Reassigned from user %s to user %s", formatChangeValue(c.Old), formatChangeValue(c.New))
+		}
+	}
+	return fmt.Sprintf("Changed %s from %s to %s", strings.ReplaceAll(c.Field, "_", " "), formatChangeValue(c.Old), formatChangeValue(c.New))
+}
+
+func formatChangeValue(v interface{}) string {
+	if v == nil {
+		return "none"
+	}
+	if s, ok := v.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(raw)
 }
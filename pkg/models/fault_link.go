@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Fault link kinds. A duplicate_of link reads as duplicated_by from the other fault.
+const (
+	LinkRelatesTo    = "relates_to"
+	LinkDuplicateOf  = "duplicate_of"
+	LinkDuplicatedBy = "duplicated_by"
+)
+
+// FaultLink is a link from one fault to another, possibly in a different project, as seen from
+// the fault it is listed on. The linked fault's summary is included.
+type FaultLink struct {
+	ID          int64     `json:"id" db:"id"`
+	Kind        string    `json:"kind" db:"kind"`
+	FaultID     int64     `json:"fault_id" db:"fault_id"`
+	ProjectID   *int64    `json:"project_id,omitempty" db:"project_id"`
+	ErrorClass  string    `json:"error_class" db:"error_class"`
+	Message     string    `json:"message" db:"message"`
+	Environment string    `json:"environment" db:"environment"`
+	Resolved    bool      `json:"resolved" db:"resolved"`
+	CreatedBy   *int64    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// FaultMerge records a fault that was merged into another, preserving its fingerprint
+type FaultMerge struct {
+	ID              int64     `json:"id" db:"id"`
+	TargetFaultID   int64     `json:"target_fault_id" db:"target_fault_id"`
+	SourceFaultID   int64     `json:"source_fault_id" db:"source_fault_id"`
+	ErrorClass      string    `json:"error_class" db:"error_class"`
+	Message         string    `json:"message" db:"message"`
+	Location        *string   `json:"location,omitempty" db:"location"`
+	Environment     string    `json:"environment" db:"environment"`
+	OccurrenceCount int64     `json:"occurrence_count" db:"occurrence_count"`
+	FirstSeenAt     time.Time `json:"first_seen_at" db:"first_seen_at"`
+	LastSeenAt      time.Time `json:"last_seen_at" db:"last_seen_at"`
+	MergedBy        *int64    `json:"merged_by,omitempty" db:"merged_by"`
+	MergedAt        time.Time `json:"merged_at" db:"merged_at"`
+}
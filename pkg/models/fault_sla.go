@@ -0,0 +1,98 @@
+package models
+
+import "time"
+
+// SLA timer statuses
+const (
+	// SLAPending timers are running with time to spare
+	SLAPending = "pending"
+	// SLAAtRisk timers are running and past the policy's warning point
+	SLAAtRisk = "at_risk"
+	// SLABreached timers are running past their deadline
+	SLABreached = "breached"
+	// SLAMet timers stopped by their deadline
+	SLAMet = "met"
+	// SLAMissed timers stopped after their deadline
+	SLAMissed = "missed"
+)
+
+// SLA timers
+const (
+	SLAAcknowledge = "acknowledge"
+	SLAResolve     = "resolve"
+)
+
+// SLATarget is how long after a fault opens it must be acknowledged and resolved; zero means
+// no target
+type SLATarget struct {
+	Acknowledge time.Duration
+	Resolve     time.Duration
+}
+
+// For returns the target of a timer
+func (t SLATarget) For(timer string) time.Duration {
+	if timer == SLAAcknowledge {
+		return t.Acknowledge
+	}
+	return t.Resolve
+}
+
+// SLAPolicy holds the SLA targets of each severity
+type SLAPolicy struct {
+	Targets map[string]SLATarget
+	// WarnAt is the fraction of a target that has elapsed when its timer is at risk
+	WarnAt float64
+}
+
+// FaultSLA holds a fault's SLA timers; a timer is nil when its severity has no target
+type FaultSLA struct {
+	Acknowledge *SLATimer `json:"acknowledge,omitempty"`
+	Resolve     *SLATimer `json:"resolve,omitempty"`
+}
+
+// SLATimer is the state of one SLA timer of a fault
+type SLATimer struct {
+	DueAt  time.Time  `json:"due_at"`
+	MetAt  *time.Time `json:"met_at,omitempty"`
+	Status string     `json:"status"`
+}
+
+// Status computes a fault's SLA timers at now. Timers run from when the fault was opened: the
+// acknowledge timer until it leaves the workflow's first state, and the resolve timer until it
+// is resolved or ignored. It returns nil when the fault's severity has no targets.
+func (p *SLAPolicy) Status(f *Fault, now time.Time) *FaultSLA {
+	target, ok := p.Targets[f.Severity]
+	if !ok || (target.Acknowledge <= 0 && target.Resolve <= 0) {
+		return nil
+	}
+
+	acknowledgedAt := f.AcknowledgedAt
+	if acknowledgedAt == nil {
+		acknowledgedAt = f.ClosedAt
+	}
+	return &FaultSLA{
+		Acknowledge: p.timer(f.OpenedAt, target.Acknowledge, acknowledgedAt, now),
+		Resolve:     p.timer(f.OpenedAt, target.Resolve, f.ClosedAt, now),
+	}
+}
+
+func (p *SLAPolicy) timer(openedAt time.Time, target time.Duration, metAt *time.Time, now time.Time) *SLATimer {
+	if target <= 0 {
+		return nil
+	}
+
+	timer := &SLATimer{DueAt: openedAt.Add(target), MetAt: metAt}
+	switch {
+	case metAt != nil && metAt.After(timer.DueAt):
+		timer.Status = SLAMissed
+	case metAt != nil:
+		timer.Status = SLAMet
+	case now.After(timer.DueAt):
+		timer.Status = SLABreached
+	case !now.Before(openedAt.Add(time.Duration(float64(target) * p.WarnAt))):
+		timer.Status = SLAAtRisk
+	default:
+		timer.Status = SLAPending
+	}
+	return timer
+}
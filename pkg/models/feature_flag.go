@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// FeatureFlagOverride is a runtime setting of a feature flag, globally or for one project
+type FeatureFlagOverride struct {
+	Name      string    `json:"name" db:"name"`
+	ProjectID *int64    `json:"project_id,omitempty" db:"project_id"`
+	Enabled   bool      `json:"enabled" db:"enabled"`
+	UpdatedBy *int64    `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// GrokPattern is a named grok pattern defined at runtime from the admin API
+type GrokPattern struct {
+	Name      string    `json:"name" db:"name"`
+	Pattern   string    `json:"pattern" db:"pattern"`
+	UpdatedBy *int64    `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
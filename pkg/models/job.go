@@ -0,0 +1,43 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job states
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+	JobCancelled = "cancelled"
+)
+
+// Job is a background operation queued in the database
+type Job struct {
+	ID              int64           `json:"id" db:"id"`
+	Type            string          `json:"type" db:"type"`
+	Status          string          `json:"status" db:"status"`
+	Payload         json.RawMessage `json:"payload" db:"payload"`
+	Result          json.RawMessage `json:"result,omitempty" db:"result"`
+	Error           *string         `json:"error,omitempty" db:"error"`
+	ProgressDone    int64           `json:"progress_done" db:"progress_done"`
+	ProgressTotal   *int64          `json:"progress_total,omitempty" db:"progress_total"`
+	ProgressMessage string          `json:"progress_message,omitempty" db:"progress_message"`
+	Attempts        int             `json:"attempts" db:"attempts"`
+	MaxAttempts     int             `json:"max_attempts" db:"max_attempts"`
+	UniqueKey       *string         `json:"unique_key,omitempty" db:"unique_key"`
+	CancelRequested bool            `json:"cancel_requested" db:"cancel_requested"`
+	RunAt           time.Time       `json:"run_at" db:"run_at"`
+	CreatedBy       *int64          `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+	StartedAt       *time.Time      `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt      *time.Time      `json:"finished_at,omitempty" db:"finished_at"`
+	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// Finished reports whether the job will not run again
+func (j *Job) Finished() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCancelled
+}
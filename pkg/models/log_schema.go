@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Log schema modes
+const (
+	// SchemaReject rejects logs whose metadata fails the schema
+	SchemaReject = "reject"
+	// SchemaFlag stores logs whose metadata fails the schema, listing the violations in it
+	SchemaFlag = "flag"
+)
+
+// LogSchema is a JSON schema the metadata of a service's logs, or of the logs sent with an
+// API key, must satisfy
+type LogSchema struct {
+	ID       int64   `json:"id" db:"id"`
+	Service  *string `json:"service,omitempty" db:"service"`
+	APIKeyID *int64  `json:"api_key_id,omitempty" db:"api_key_id"`
+	// APIKey is the value of the key, for matching requests; it is never returned
+	APIKey      string          `json:"-" db:"-"`
+	Schema      json.RawMessage `json:"schema" db:"schema"`
+	Mode        string          `json:"mode" db:"mode"`
+	Description string          `json:"description,omitempty" db:"description"`
+	CreatedBy   *int64          `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+}
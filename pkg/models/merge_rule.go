@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// MergeRule automatically groups new faults matching its criteria into a canonical fault
+type MergeRule struct {
+	ID             int64      `json:"id" db:"id"`
+	ProjectID      *int64     `json:"project_id,omitempty" db:"project_id"`
+	Name           string     `json:"name" db:"name"`
+	ErrorClass     *string    `json:"error_class,omitempty" db:"error_class"`
+	MessagePattern *string    `json:"message_pattern,omitempty" db:"message_pattern"`
+	Environment    *string    `json:"environment,omitempty" db:"environment"`
+	TargetFaultID  int64      `json:"target_fault_id" db:"target_fault_id"`
+	Enabled        bool       `json:"enabled" db:"enabled"`
+	MatchCount     int64      `json:"match_count" db:"match_count"`
+	LastMatchedAt  *time.Time `json:"last_matched_at,omitempty" db:"last_matched_at"`
+	CreatedBy      *int64     `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
@@ -1,6 +1,10 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
 
 // Notice represents an individual error occurrence
 type Notice struct {
@@ -17,7 +21,26 @@ type Notice struct {
 	Breadcrumbs []Breadcrumb           `json:"breadcrumbs,omitempty" db:"breadcrumbs"`
 	Revision    *string                `json:"revision,omitempty" db:"revision"`
 	Hostname    *string                `json:"hostname,omitempty" db:"hostname"`
+	AccountID   string                 `json:"account_id,omitempty" db:"account_id"`
 	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
+	// Grouping inputs kept for regrouping; empty for notices stored before they were recorded
+	ErrorClass string `json:"-" db:"error_class"`
+	Component  string `json:"-" db:"component"`
+	Action     string `json:"-" db:"action"`
+	// SharePayload stores the environment data once, shared with every notice sending the same data
+	SharePayload bool `json:"-" db:"-"`
+}
+
+// NoticeSummaryFrames is the number of backtrace frames kept in a NoticeSummary
+const NoticeSummaryFrames = 3
+
+// NoticeSummary is a compact view of a notice used when listing faults
+type NoticeSummary struct {
+	ID        string           `json:"id"`
+	Hostname  *string          `json:"hostname,omitempty"`
+	Revision  *string          `json:"revision,omitempty"`
+	Backtrace []BacktraceFrame `json:"backtrace,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
 }
 
 // BacktraceFrame represents a single stack frame in a backtrace
@@ -30,6 +53,39 @@ type BacktraceFrame struct {
 	Vars       map[string]interface{} `json:"vars,omitempty"`
 }
 
+// UnmarshalJSON also accepts the frame fields sent by Honeybadger SDKs: "number" (a string from
+// the Ruby SDK, a number from the JavaScript and PHP SDKs) for the line and "method" for the function
+func (f *BacktraceFrame) UnmarshalJSON(data []byte) error {
+	type plain BacktraceFrame
+	var frame struct {
+		plain
+		Number json.RawMessage `json:"number"`
+		Method string          `json:"method"`
+	}
+	if err := json.Unmarshal(data, &frame); err != nil {
+		return err
+	}
+	
+	*f = BacktraceFrame(frame.plain)
+	if f.Line == nil && len(frame.Number) > 0 {
+		var number json.Number
+		if err := json.Unmarshal(frame.Number, &number); err != nil {
+			// Quoted, as the Ruby SDK sends it
+			var quoted string
+			if json.Unmarshal(frame.Number, &quoted) == nil {
+				number = json.Number(quoted)
+			}
+		}
+		if line, err := strconv.Atoi(string(number)); err == nil {
+			f.Line = &line
+		}
+	}
+	if f.Function == "" {
+		f.Function = frame.Method
+	}
+	return nil
+}
+
 // Breadcrumb represents an event in the breadcrumb trail
 type Breadcrumb struct {
 	Category string                 `json:"category"`
@@ -58,6 +114,9 @@ type NoticeRequest struct {
 		Session    map[string]interface{} `json:"session,omitempty"`
 		Cookies    map[string]interface{} `json:"cookies,omitempty"`
 		Context    map[string]interface{} `json:"context,omitempty"`
+		// CGIData holds the request headers and server variables, such as REMOTE_ADDR; it is
+		// only read for enrichment
+		CGIData    map[string]interface{} `json:"cgi_data,omitempty"`
 	} `json:"request,omitempty"`
 	Server struct {
 		EnvironmentName string                 `json:"environment_name,omitempty"`
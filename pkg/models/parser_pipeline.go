@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// ParserPipeline is an ordered chain of steps that parses and processes the logs of its
+// sources and of the API keys set to use it, defined at runtime from the admin API
+type ParserPipeline struct {
+	Name      string         `json:"name" db:"name"`
+	Sources   []string       `json:"sources" db:"sources"`
+	Steps     []PipelineStep `json:"steps" db:"steps"`
+	UpdatedBy *int64         `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// PipelineStep is one step of a parser pipeline. Which fields apply depends on Type.
+type PipelineStep struct {
+	Type string `json:"type"`
+	// Parser names the registered parser of a parse step
+	Parser string `json:"parser,omitempty"`
+	// Grok and TimestampFormat define the expression of a grok step
+	Grok            string `json:"grok,omitempty"`
+	TimestampFormat string `json:"timestamp_format,omitempty"`
+	// Keys and Patterns are the metadata key and value patterns of a redact step
+	Keys     []string `json:"keys,omitempty"`
+	Patterns []string `json:"patterns,omitempty"`
+	// Fields and Service are set on every log by an enrich step
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+	Service string                 `json:"service,omitempty"`
+	// Processor names the compiled-in processor of a process step
+	Processor string `json:"processor,omitempty"`
+}
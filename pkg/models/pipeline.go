@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// PipelinePause records that a pipeline was paused, by whom and why
+type PipelinePause struct {
+	Pipeline string    `json:"pipeline" db:"pipeline"`
+	Reason   string    `json:"reason" db:"reason"`
+	PausedBy *int64    `json:"paused_by,omitempty" db:"paused_by"`
+	PausedAt time.Time `json:"paused_at" db:"paused_at"`
+}
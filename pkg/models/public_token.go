@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// PublicToken is a read-only token for wallboard dashboards. It reads aggregated analytics and
+// faults marked public, of one project or of every project when ProjectID is nil.
+type PublicToken struct {
+	ID        int64      `json:"id" db:"id"`
+	Name      string     `json:"name" db:"name"`
+	ProjectID *int64     `json:"project_id,omitempty" db:"project_id"`
+	CreatedBy *int64     `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Outcomes of a guarded query
+const (
+	// QueryAllowed ran at once, within the cost limits
+	QueryAllowed = "allowed"
+	// QueryQueued was over the limits and ran once an expensive query slot was free
+	QueryQueued = "queued"
+	// QueryRejected was over the limits and did not run
+	QueryRejected = "rejected"
+)
+
+// QueryAuditEntry records who ran a guarded search or analytics query, what the planner
+// estimated it would cost and whether it ran
+type QueryAuditEntry struct {
+	ID       int64  `json:"id" db:"id"`
+	UserID   *int64 `json:"user_id,omitempty" db:"user_id"`
+	APIKeyID *int64 `json:"api_key_id,omitempty" db:"api_key_id"`
+	Route    string `json:"route" db:"route"`
+	Query    string `json:"query" db:"query"`
+	// EstimatedRows and EstimatedCost are nil when the planner could not be asked
+	EstimatedRows *float64  `json:"estimated_rows" db:"estimated_rows"`
+	EstimatedCost *float64  `json:"estimated_cost" db:"estimated_cost"`
+	Outcome       string    `json:"outcome" db:"outcome"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
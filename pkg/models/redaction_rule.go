@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Redaction rule kinds
+const (
+	// RedactKey masks the values of keys matching the pattern, case-insensitively
+	RedactKey = "key"
+	// RedactValue masks the parts of string values matching the pattern
+	RedactValue = "value"
+	// RedactPath masks the value at the JSON path given as the pattern
+	RedactPath = "path"
+)
+
+// RedactionRule masks sensitive data in logs and notices, in addition to the scrub patterns
+// from configuration. Rules without a project apply to logs and to every project's notices.
+type RedactionRule struct {
+	ID          int64     `json:"id" db:"id"`
+	ProjectID   *int64    `json:"project_id,omitempty" db:"project_id"`
+	Kind        string    `json:"kind" db:"kind"`
+	Pattern     string    `json:"pattern" db:"pattern"`
+	Description string    `json:"description,omitempty" db:"description"`
+	CreatedBy   *int64    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
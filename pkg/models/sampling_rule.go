@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// SamplingRule drops or samples incoming logs, in addition to the rules from configuration. It
+// matches logs meeting every condition it sets and keeps SampleRate of them.
+type SamplingRule struct {
+	ID      int64  `json:"id" db:"id"`
+	Service string `json:"service,omitempty" db:"service"`
+	Level   string `json:"level,omitempty" db:"level"`
+	// MessagePattern is a regular expression matched against messages
+	MessagePattern string `json:"message_pattern,omitempty" db:"message_pattern"`
+	// SampleRate is the fraction of matching logs kept, from 0, which drops them all, to 1
+	SampleRate  float64   `json:"sample_rate" db:"sample_rate"`
+	Description string    `json:"description,omitempty" db:"description"`
+	CreatedBy   *int64    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
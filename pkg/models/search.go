@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Kinds of records a global search matches
+const (
+	SearchFault   = "fault"
+	SearchNotice  = "notice"
+	SearchComment = "comment"
+	SearchLog     = "log"
+)
+
+// SearchKinds are the kinds of records a global search matches, in the order they are grouped
+var SearchKinds = []string{SearchFault, SearchNotice, SearchComment, SearchLog}
+
+// Highlight marks a match in a field of a search hit, as character offsets with End exclusive
+type Highlight struct {
+	Field string `json:"field"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+// SearchHit is a record matched by a global search. Title names it: the error class for
+// faults, notices and comments and the service for logs. Snippet is the matching text, cut
+// around the first match when long.
+type SearchHit struct {
+	Type string `json:"type"`
+	// ID is the record's ID, a string since notice IDs are ULIDs
+	ID string `json:"id"`
+	// FaultID is the fault a notice or comment belongs to, or the fault itself
+	FaultID    *int64      `json:"fault_id,omitempty"`
+	Title      string      `json:"title"`
+	Snippet    string      `json:"snippet"`
+	Highlights []Highlight `json:"highlights"`
+	Timestamp  time.Time   `json:"timestamp"`
+}
+
+// SearchGroup holds the hits of one kind
+type SearchGroup struct {
+	Type    string      `json:"type"`
+	Hits    []SearchHit `json:"hits"`
+	HasMore bool        `json:"has_more"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// UserSession is a signed-in session of a user, created at login. Its token stops working as
+// soon as the session is revoked.
+type UserSession struct {
+	ID           string    `json:"id" db:"id"`
+	UserID       int64     `json:"user_id" db:"user_id"`
+	Device       string    `json:"device" db:"device"`
+	UserAgent    string    `json:"user_agent" db:"user_agent"`
+	IPAddress    string    `json:"ip_address" db:"ip_address"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	LastActiveAt time.Time `json:"last_active_at" db:"last_active_at"`
+	ExpiresAt    time.Time `json:"expires_at" db:"expires_at"`
+	Current      bool      `json:"current"`
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Subscription kinds, the data a subscription queries
+const (
+	SubscriptionLogs   = "logs"
+	SubscriptionFaults = "faults"
+)
+
+// Subscription delivery channels
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+)
+
+// Schedule frequencies
+const (
+	FrequencyHourly = "hourly"
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+)
+
+// Subscription is a saved log or fault query whose results are delivered on a schedule
+type Subscription struct {
+	ID              int64      `json:"id" db:"id"`
+	UserID          int64      `json:"user_id" db:"user_id"`
+	Name            string     `json:"name" db:"name"`
+	Kind            string     `json:"kind" db:"kind"`
+	Query           string     `json:"query" db:"query"`
+	Schedule        Schedule   `json:"schedule"`
+	Channel         string     `json:"channel" db:"channel"`
+	Target          string     `json:"target" db:"target"`
+	MaxResults      int        `json:"max_results" db:"max_results"`
+	Enabled         bool       `json:"enabled" db:"enabled"`
+	NextRunAt       time.Time  `json:"next_run_at" db:"next_run_at"`
+	LastRunAt       *time.Time `json:"last_run_at,omitempty" db:"last_run_at"`
+	LastStatus      *string    `json:"last_status,omitempty" db:"last_status"`
+	LastError       *string    `json:"last_error,omitempty" db:"last_error"`
+	LastResultCount *int       `json:"last_result_count,omitempty" db:"last_result_count"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// Schedule says when a subscription runs: every hour at the minutes of At, every day at At, or
+// every week on Weekday at At, in Timezone
+type Schedule struct {
+	Frequency string `json:"frequency"`
+	// At is a "HH:MM" time of day
+	At string `json:"at"`
+	// Weekday is the day of weekly runs, 0 (Sunday) to 6
+	Weekday  *int   `json:"weekday,omitempty"`
+	Timezone string `json:"timezone"`
+}
+
+// Validate checks the schedule and returns its time zone
+func (s Schedule) Validate() (*time.Location, error) {
+	switch s.Frequency {
+	case FrequencyHourly, FrequencyDaily:
+		if s.Weekday != nil {
+			return nil, fmt.Errorf("weekday is only used by weekly schedules")
+		}
+	case FrequencyWeekly:
+		if s.Weekday == nil || *s.Weekday < 0 || *s.Weekday > 6 {
+			return nil, fmt.Errorf("weekly schedules need a weekday from 0 (Sunday) to 6")
+		}
+	default:
+		return nil, fmt.Errorf("frequency must be hourly, daily or weekly, got %q", s.Frequency)
+	}
+	if _, _, err := s.clock(); err != nil {
+		return nil, err
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q", s.Timezone)
+	}
+	return loc, nil
+}
+
+// clock returns the hour and minute of At
+func (s Schedule) clock() (int, int, error) {
+	t, err := time.Parse("15:04", s.At)
+	if err != nil {
+		return 0, 0, fmt.Errorf("at must be a HH:MM time, got %q", s.At)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// Period is the time between two runs
+func (s Schedule) Period() time.Duration {
+	switch s.Frequency {
+	case FrequencyHourly:
+		return time.Hour
+	case FrequencyWeekly:
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// Next returns the first run strictly after t. The schedule must be valid.
+func (s Schedule) Next(t time.Time) time.Time {
+	loc, err := s.Validate()
+	if err != nil {
+		return t.Add(s.Period())
+	}
+	hour, minute, _ := s.clock()
+	local := t.In(loc)
+
+	switch s.Frequency {
+	case FrequencyHourly:
+		next := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), minute, 0, 0, loc)
+		if !next.After(t) {
+			next = next.Add(time.Hour)
+		}
+		return next
+	case FrequencyWeekly:
+		days := (*s.Weekday - int(local.Weekday()) + 7) % 7
+		next := time.Date(local.Year(), local.Month(), local.Day()+days, hour, minute, 0, 0, loc)
+		if !next.After(t) {
+			next = time.Date(local.Year(), local.Month(), local.Day()+days+7, hour, minute, 0, 0, loc)
+		}
+		return next
+	}
+	next := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+	if !next.After(t) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, hour, minute, 0, 0, loc)
+	}
+	return next
+}
@@ -12,3 +12,27 @@ type User struct {
 	IsAdmin      bool      `json:"is_admin" db:"is_admin"`
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 }
+
+// UserPreferences holds the self-service settings for a user account
+type UserPreferences struct {
+	Timezone         string                  `json:"timezone" db:"timezone"`
+	DefaultProjectID *int64                  `json:"default_project_id,omitempty" db:"default_project_id"`
+	Theme            string                  `json:"theme" db:"theme"`
+	Language         string                  `json:"language" db:"language"`
+	Notifications    NotificationPreferences `json:"notifications" db:"notification_preferences"`
+}
+
+// NotificationPreferences controls which events a user is notified about
+type NotificationPreferences struct {
+	NewFault    bool `json:"new_fault"`
+	Assignment  bool `json:"assignment"`
+	Comment     bool `json:"comment"`
+	Reopened    bool `json:"reopened"`
+	DailyDigest bool `json:"daily_digest"`
+}
+
+// UserProfile is a user together with their preferences, as returned by /api/v1/me
+type UserProfile struct {
+	User
+	Preferences UserPreferences `json:"preferences"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// WebAuthnCredential is a passkey registered to a user. The credential ID and public key are
+// only used to verify logins and are not returned by the API.
+type WebAuthnCredential struct {
+	ID           int64      `json:"id" db:"id"`
+	UserID       int64      `json:"user_id" db:"user_id"`
+	Name         string     `json:"name" db:"name"`
+	CredentialID []byte     `json:"-" db:"credential_id"`
+	PublicKey    []byte     `json:"-" db:"public_key"`
+	Algorithm    int        `json:"algorithm" db:"algorithm"`
+	SignCount    int64      `json:"sign_count" db:"sign_count"`
+	Transports   []string   `json:"transports" db:"transports"`
+	AAGUID       string     `json:"aaguid,omitempty" db:"aaguid"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+}
+
+// WebAuthnChallenge is the state of a passkey ceremony in progress, kept until the ceremony is
+// finished or expires. UserID is set for registrations.
+type WebAuthnChallenge struct {
+	ID        string    `json:"id" db:"id"`
+	Kind      string    `json:"kind" db:"kind"`
+	Challenge []byte    `json:"-" db:"challenge"`
+	UserID    *int64    `json:"user_id,omitempty" db:"user_id"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+}
@@ -71,7 +71,7 @@ async function fetchWithAuth(url, options = {}) {
     const contentType = response.headers.get('content-type') || ''
     let error = { error: `Request failed with status ${response.status}` }
 
-    if (contentType.includes('application/json')) {
+    if (contentType.includes('application/json') || contentType.includes('application/problem+json')) {
       try {
         const errorData = await response.json()
         error = errorData
@@ -100,8 +100,10 @@ async function fetchWithAuth(url, options = {}) {
       }
     }
 
-    const errorMessage = error.error || error.details || `Request failed with status ${response.status}`
-    const fullError = error.details && error.error ? `${error.error}: ${error.details}` : errorMessage
+    const title = error.title || error.error
+    const detail = error.detail || error.details
+    const errorMessage = title || detail || `Request failed with status ${response.status}`
+    const fullError = detail && title ? `${title}: ${detail}` : errorMessage
     throw new Error(fullError)
   }
 
@@ -140,7 +142,7 @@ export async function register(name, email, password) {
 
   const data = await response.json()
   if (!response.ok) {
-    return { success: false, error: data.error || 'Registration failed' }
+    return { success: false, error: data.title || data.error || 'Registration failed' }
   }
   return data
 }
@@ -165,11 +167,16 @@ export async function login(email, password) {
     return data
   }
 
-  return { success: false, error: data.error || 'Login failed' }
+  return { success: false, error: data.title || data.error || 'Login failed' }
 }
 
-// Auth: Logout
-export function logout() {
+// Auth: Logout (the session cookie is HttpOnly, so the server must clear it)
+export async function logout() {
+  try {
+    await fetch(`${API_BASE}/auth/logout`, { method: 'POST', credentials: 'same-origin' })
+  } catch (err) {
+    console.error('Logout request failed:', err)
+  }
   removeAuthToken()
 }
 
@@ -178,9 +185,9 @@ export async function getHealth() {
   return fetchWithAuth('/admin/health?format=json')
 }
 
-// Get metrics
-export async function getMetrics(range = '24h', interval = '5m') {
-  return fetchWithAuth(`/admin/metrics?range=${range}&interval=${interval}`)
+// Get metrics, with time series bucketed in the browser's timezone
+export async function getMetrics(range = '24h', interval = '5m', tz = Intl.DateTimeFormat().resolvedOptions().timeZone) {
+  return fetchWithAuth(`/admin/metrics?range=${range}&interval=${interval}&tz=${encodeURIComponent(tz || 'UTC')}&max_points=500`)
 }
 
 // Get recent logs
@@ -218,4 +225,48 @@ export async function deleteAPIKey(id) {
   })
 }
 
+// Get the current user's profile and preferences
+export async function getProfile() {
+  return fetchWithAuth('/api/v1/me')
+}
+
+// Update the current user's profile and preferences
+export async function updateProfile(changes) {
+  const profile = await fetchWithAuth('/api/v1/me', {
+    method: 'PATCH',
+    body: JSON.stringify(changes)
+  })
+  const info = getUserInfo()
+  if (info) {
+    localStorage.setItem('user_info', JSON.stringify({
+      ...info,
+      name: profile.name,
+      avatar_url: profile.avatar_url,
+      preferences: profile.preferences
+    }))
+  }
+  return profile
+}
+
+// Upload a new avatar image for the current user
+export async function uploadAvatar(file) {
+  const form = new FormData()
+  form.append('avatar', file)
+  const token = getAuthToken()
+  const response = await fetch(`${API_BASE}/api/v1/me/avatar`, {
+    method: 'POST',
+    headers: token ? { 'Authorization': `Bearer ${token}` } : {},
+    body: form
+  })
+  const data = await response.json()
+  if (!response.ok) {
+    throw new Error(data.detail || data.title || data.error || 'Avatar upload failed')
+  }
+  const info = getUserInfo()
+  if (info) {
+    localStorage.setItem('user_info', JSON.stringify({ ...info, avatar_url: data.avatar_url }))
+  }
+  return data
+}
+
 export { getAuthToken, setAuthToken, removeAuthToken, isAuthenticated, isAdmin, getUserInfo, fetchWithAuth }